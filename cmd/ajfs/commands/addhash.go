@@ -0,0 +1,91 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"github.com/andrejacobs/ajfs/internal/app/addhash"
+	"github.com/spf13/cobra"
+)
+
+// ajfs add-hash.
+var addHashCmd = &cobra.Command{
+	Use:   "add-hash",
+	Short: "Add a hash table that uses another hashing algorithm.",
+	Long: `Calculate the file signature hashes using another hashing algorithm and store
+them as an additional hash table in the database.
+
+A database can carry more than one hash table, for example SHA-1 for quick
+change detection and SHA-512 for strong integrity checking. "ajfs diff"
+compares the hashes using the strongest algorithm that both databases have in
+common.
+
+Only the files that have not been hashed using the algorithm yet are hashed,
+thus running the command again adds the hashes for files that failed or that
+were added by "ajfs update". The hashes calculated thus far are kept when
+interrupted.
+
+Hashes are reused from the hash cache for files that have not changed (see
+"ajfs cache"). Use "--no-cache" to not use the cache.
+
+Any signature (see "ajfs sign") is removed. A sealed database (see
+"ajfs seal") will not be modified unless "--force" is used.
+
+NOTE: The database must have been created using the "--hash" option.`,
+	Example: `  # add SHA-512 hashes to the default ./db.ajfs database
+  ajfs add-hash --algo=sha512
+
+  # add SHA-1 hashes to the specific database and display a progress bar
+  ajfs add-hash --algo=sha1 --progress /path/to/database.ajfs`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		algo, err := algoFromFlag(addHashAlgo)
+		if err != nil {
+			exitOnError(err, 1)
+		}
+
+		commonConfig.Progress = showProgress
+		cfg := addhash.Config{
+			CommonConfig:  commonConfig,
+			Algo:          algo,
+			Force:         addHashForce,
+			HashCachePath: hashCachePath(addHashNoCache),
+		}
+		cfg.DbPath = dbPathFromArgs(args)
+
+		if err := addhash.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(addHashCmd)
+
+	addHashCmd.Flags().StringVarP(&addHashAlgo, "algo", "a", "sha512", "Hashing algorithm to use. Valid values are 'sha1', 'sha256', 'sha512' and 'md5' (legacy).")
+	addHashCmd.Flags().BoolVarP(&showProgress, "progress", "p", false, "Display progress information.")
+	addHashCmd.Flags().BoolVar(&addHashNoCache, "no-cache", false, "Do not reuse or store file signature hashes using the hash cache.")
+	addHashCmd.Flags().BoolVar(&addHashForce, "force", false, "Add the hash table even if the database has been sealed.")
+}
+
+var (
+	addHashAlgo    string
+	addHashForce   bool
+	addHashNoCache bool
+)
@@ -0,0 +1,114 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"fmt"
+
+	"github.com/andrejacobs/ajfs/internal/app/annotate"
+	"github.com/spf13/cobra"
+)
+
+// ajfs annotate.
+var annotateCmd = &cobra.Command{
+	Use:   "annotate",
+	Short: "Record review decisions for duplicate groups and paths.",
+	Long: `Record review decisions for duplicate groups and paths.
+
+The decisions are stored in the database so that reviewing a large number of
+duplicates can be done over multiple sessions without losing track of what has
+already been decided.
+
+A label is one of: reviewed, keep or delete-candidate.
+
+Use "--group" with the file signature hash displayed by "ajfs dupes" to label
+a whole group of duplicate files, or with the "Group:" identifier displayed for
+potential duplicates. Use "--path" with a path relative to the root to label a
+single entry. Both can be repeated.
+
+Use "--clear" to remove the labels of the groups and paths instead.
+
+Use "--list" to display all the labels stored in the database.
+
+Labels are displayed by "ajfs dupes" and groups that have been labelled can be
+hidden using "ajfs dupes --unreviewed".
+
+A sealed database (see "ajfs seal") will not be annotated unless "--force" is
+used. Annotating removes any signature (see "ajfs sign").`,
+	Example: `  # mark a duplicate group as reviewed
+  ajfs annotate --label reviewed --group 9c1185a5c5e9fc54612808977ee8f548b2258d31
+
+  # mark which copies to keep and which to delete
+  ajfs annotate --label keep --path photos/2019/img_001.jpg /path/to/database.ajfs
+  ajfs annotate --label delete-candidate --path backup/img_001.jpg /path/to/database.ajfs
+
+  # remove the label of a path
+  ajfs annotate --clear --path backup/img_001.jpg /path/to/database.ajfs
+
+  # display all the labels
+  ajfs annotate --list /path/to/database.ajfs`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if annotateClear && annotateLabel != "" {
+			exitOnError(fmt.Errorf("--clear can't be used with --label"), 1)
+		}
+		if annotateList && (annotateClear || annotateLabel != "" || len(annotatePaths) > 0 || len(annotateGroups) > 0) {
+			exitOnError(fmt.Errorf("--list can't be used with --label, --clear, --path or --group"), 1)
+		}
+		if !annotateList && !annotateClear && annotateLabel == "" {
+			exitOnError(fmt.Errorf("--label is required"), 1)
+		}
+
+		cfg := annotate.Config{
+			CommonConfig: commonConfig,
+			Label:        annotateLabel,
+			Paths:        annotatePaths,
+			Groups:       annotateGroups,
+			Clear:        annotateClear,
+			List:         annotateList,
+			Force:        annotateForce,
+		}
+		cfg.DbPath = dbPathFromArgs(args)
+
+		if err := annotate.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(annotateCmd)
+
+	annotateCmd.Flags().StringVarP(&annotateLabel, "label", "l", "", "Label to give [reviewed, keep, delete-candidate].")
+	annotateCmd.Flags().StringArrayVarP(&annotatePaths, "path", "p", nil, "Path (relative to the root) of the entry to label.")
+	annotateCmd.Flags().StringArrayVarP(&annotateGroups, "group", "g", nil, "File signature hash (or potential duplicates identifier) of the duplicate group to label.")
+	annotateCmd.Flags().BoolVar(&annotateClear, "clear", false, "Remove the labels of the paths and groups.")
+	annotateCmd.Flags().BoolVar(&annotateList, "list", false, "Display all the labels.")
+	annotateCmd.Flags().BoolVar(&annotateForce, "force", false, "Annotate the database even if it has been sealed.")
+}
+
+var (
+	annotateLabel  string
+	annotatePaths  []string
+	annotateGroups []string
+	annotateClear  bool
+	annotateList   bool
+	annotateForce  bool
+)
@@ -0,0 +1,122 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"context"
+
+	"github.com/andrejacobs/ajfs/internal/app/cache"
+	"github.com/andrejacobs/ajfs/internal/hashcache"
+	"github.com/spf13/cobra"
+)
+
+// ajfs cache.
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the file signature hash cache.",
+	Long: `Manage the local cache of file signature hashes.
+
+While calculating file signature hashes (e.g. "ajfs scan --hash", "ajfs resume"
+and "ajfs update") each hash is also stored in a cache that is shared by all
+databases. The cache maps a file's absolute path, size, last modification time
+and hashing algorithm to the hash. When a new snapshot is created of a mostly
+unchanged file hierarchy then the hashes are reused from the cache instead of
+having to read all of the files again.
+
+NOTE: A file is assumed to be unchanged when its size and last modification
+time are the same. Use "--no-cache" on the commands that calculate hashes to
+not use the cache.
+
+The cache is stored in the user's cache directory (e.g. ~/.cache/ajfs on Linux).`,
+	Example: `  # display the location and number of hashes in the cache
+  ajfs cache info
+
+  # remove the hashes for files that no longer exist or that have changed
+  ajfs cache prune
+
+  # remove all the hashes
+  ajfs cache clear`,
+}
+
+// ajfs cache info.
+var cacheInfoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Display information about the cache.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runCacheCommand(cmd.Context(), cache.Info)
+	},
+}
+
+// ajfs cache prune.
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove the hashes for files that no longer exist or that have changed.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runCacheCommand(cmd.Context(), cache.Prune)
+	},
+}
+
+// ajfs cache clear.
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all the hashes from the cache.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runCacheCommand(cmd.Context(), cache.Clear)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheInfoCmd, cachePruneCmd, cacheClearCmd)
+}
+
+func runCacheCommand(ctx context.Context, fn func(ctx context.Context, cfg cache.Config) error) {
+	cachePath, err := hashcache.DefaultPath()
+	if err != nil {
+		exitOnError(err, 1)
+	}
+
+	cfg := cache.Config{
+		CommonConfig: commonConfig,
+		CachePath:    cachePath,
+	}
+
+	if err := fn(ctx, cfg); err != nil {
+		exitOnError(err, 1)
+	}
+}
+
+// Path to the hash cache to be used while calculating file signature hashes.
+// An empty path (cache disabled) is returned if noCache is true or the default path can't be determined.
+func hashCachePath(noCache bool) string {
+	if noCache {
+		return ""
+	}
+
+	cachePath, err := hashcache.DefaultPath()
+	if err != nil {
+		commonConfig.Errorln("WARNING: the hash cache will not be used. " + err.Error())
+		return ""
+	}
+	return cachePath
+}
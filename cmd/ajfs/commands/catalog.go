@@ -0,0 +1,152 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"github.com/andrejacobs/ajfs/internal/app/catalog"
+	"github.com/spf13/cobra"
+)
+
+// ajfs catalog.
+var catalogCmd = &cobra.Command{
+	Use:   "catalog",
+	Short: "Manage a catalog of databases.",
+	Long: `Manage a catalog that groups multiple databases together as a single set.
+
+A catalog is a small JSON file (e.g. backups.ajfscat) that lists the databases
+(also known as volumes) that belong to the set. Each volume has a unique name
+that is used to indicate which database a result came from.
+
+Databases that are located below the directory containing the catalog are
+stored as relative paths, which means the catalog and databases can be moved
+together.
+
+Commands that support catalogs (e.g. "ajfs search" and "ajfs dupes") accept the
+catalog file in place of a database and will operate across all the volumes.`,
+	Example: `  # create a new catalog from two databases
+  ajfs catalog create backups.ajfscat laptop.ajfs nas.ajfs
+
+  # add another database using a specific volume name
+  ajfs catalog add --name offsite backups.ajfscat /media/usb/backup.ajfs
+
+  # list the volumes in the catalog
+  ajfs catalog list backups.ajfscat
+
+  # remove a volume from the catalog
+  ajfs catalog remove backups.ajfscat offsite
+
+  # search across all the volumes
+  ajfs search backups.ajfscat --iname '*.pdf'`,
+}
+
+// ajfs catalog create.
+var catalogCreateCmd = &cobra.Command{
+	Use:   "create catalog db...",
+	Short: "Create a new catalog.",
+	Long: `Create a new catalog containing the specified databases.
+The volume name defaults to the database filename without the extension.`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := catalogConfigFromArgs(args)
+		cfg.ForceOverride = catalogForceOverride
+
+		if err := catalog.Create(cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+// ajfs catalog add.
+var catalogAddCmd = &cobra.Command{
+	Use:   "add catalog db...",
+	Short: "Add databases to an existing catalog.",
+	Long: `Add databases to an existing catalog.
+The volume name defaults to the database filename without the extension.`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := catalogConfigFromArgs(args)
+
+		if err := catalog.Add(cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+// ajfs catalog remove.
+var catalogRemoveCmd = &cobra.Command{
+	Use:   "remove catalog name...",
+	Short: "Remove volumes from a catalog.",
+	Long: `Remove volumes from a catalog.
+The databases themselves are not deleted.`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := catalog.Config{
+			CommonConfig: commonConfig,
+			Names:        args[1:],
+		}
+		cfg.DbPath = args[0]
+
+		if err := catalog.Remove(cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+// ajfs catalog list.
+var catalogListCmd = &cobra.Command{
+	Use:   "list catalog",
+	Short: "Display the volumes in a catalog.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := catalog.Config{
+			CommonConfig: commonConfig,
+		}
+		cfg.DbPath = args[0]
+
+		if err := catalog.List(cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(catalogCmd)
+	catalogCmd.AddCommand(catalogCreateCmd, catalogAddCmd, catalogRemoveCmd, catalogListCmd)
+
+	catalogCreateCmd.Flags().BoolVar(&catalogForceOverride, "force", false, "Override any existing catalog.")
+	catalogCreateCmd.Flags().StringVar(&catalogName, "name", "", "Name of the volume. Only valid when a single database is specified.")
+	catalogAddCmd.Flags().StringVar(&catalogName, "name", "", "Name of the volume. Only valid when a single database is specified.")
+}
+
+// Config for the create and add sub commands.
+func catalogConfigFromArgs(args []string) catalog.Config {
+	cfg := catalog.Config{
+		CommonConfig: commonConfig,
+		DbPaths:      args[1:],
+		Name:         catalogName,
+	}
+	cfg.DbPath = args[0]
+	return cfg
+}
+
+var (
+	catalogForceOverride bool
+	catalogName          string
+)
@@ -21,7 +21,9 @@
 package commands
 
 import (
-	"github.com/andrejacobs/ajfs/internal/app/fix"
+	"errors"
+
+	"github.com/andrejacobs/ajfs/internal/app/check"
 	"github.com/spf13/cobra"
 )
 
@@ -30,23 +32,43 @@ var checkCmd = &cobra.Command{
 	Use:   "check",
 	Short: "Check the integrity of a database.",
 	Long: `Check the integrity of a database.
-This is just a convenient way for running: ajfs fix --dry-run
+
+Intended for automated validation jobs (e.g. after a backup has been made).
+The following checks are performed in order and the first failure stops the
+check:
+* Signature and version: The file is an ajfs database that can be fully
+  processed by this version of ajfs.
+* Checksum: The stored checksums match the database contents.
+* Structure: The same analysis as "ajfs fix --dry-run". Use --verbose to
+  display the analysis.
+* Hash table: Every file has exactly one hash table entry and a sorted hash
+  table is in fact sorted.
+
+Exit codes:
+  0  All the checks passed.
+  1  Any other error (e.g. the database file does not exist).
+  2  Not a valid or supported ajfs database.
+  3  The checksum verification failed.
+  4  The database structure needs to be fixed (see "ajfs fix").
+  5  The hash table is inconsistent.
 `,
 	Example: `  # using the default ./db.ajfs database
   ajfs check
 
   # using a specific database
-  ajfs check /path/to/database.ajfs`,
+  ajfs check /path/to/database.ajfs
+
+  # also display the structural analysis
+  ajfs check --verbose /path/to/database.ajfs`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		cfg := fix.Config{
+		cfg := check.Config{
 			CommonConfig: commonConfig,
-			DryRun:       true,
 		}
 		cfg.DbPath = dbPathFromArgs(args)
 
-		if err := fix.Run(cfg); err != nil {
-			exitOnError(err, 1)
+		if err := check.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, checkExitCode(err))
 		}
 	},
 }
@@ -54,3 +76,26 @@ This is just a convenient way for running: ajfs fix --dry-run
 func init() {
 	rootCmd.AddCommand(checkCmd)
 }
+
+// Exit codes used by ajfs check for each class of failure.
+const (
+	checkExitFormat    = 2
+	checkExitChecksum  = 3
+	checkExitStructure = 4
+	checkExitHashTable = 5
+)
+
+func checkExitCode(err error) int {
+	switch {
+	case errors.Is(err, check.ErrFormat):
+		return checkExitFormat
+	case errors.Is(err, check.ErrChecksum):
+		return checkExitChecksum
+	case errors.Is(err, check.ErrStructure):
+		return checkExitStructure
+	case errors.Is(err, check.ErrHashTable):
+		return checkExitHashTable
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"github.com/andrejacobs/ajfs/internal/app/cleanup"
+	"github.com/spf13/cobra"
+)
+
+// ajfs cleanup.
+var cleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "Report files that are candidates to be cleaned up.",
+	Long: `Report the files that are candidates to be cleaned up by combining the
+search criteria (e.g. size and last modification time) with duplicate
+information.
+
+Criteria include:
+* Any of the search flags (see "ajfs search --help").
+* The file has a duplicate inside the same database (--dupes).
+* The file also exists in a backup database (--backup).
+
+Files are matched against the backup database using the file signature hashes
+when both databases were hashed using the same algorithm, regardless of the
+filename or location. Otherwise files are matched by their relative path and
+size.
+
+Only the paths of the candidates are written to STDOUT, one per line, so that
+the output can be used by scripts. Use "--print0" to separate the paths with a
+NUL character (e.g. to be used with "xargs -0"). A summary is written to STDERR.
+
+NOTE: No files will be deleted by this command.`,
+	Example: `  # files bigger than 1GB, not modified in 5 years, that also exist in the backup
+  ajfs cleanup --size +1G --before 5Y --backup backup.ajfs /path/to/database.ajfs
+
+  # files bigger than 100MB that have a duplicate somewhere else
+  ajfs cleanup --size +100M --dupes /path/to/database.ajfs
+
+  # pass the full paths of the candidates to another tool
+  ajfs cleanup --full --print0 --before 10Y /path/to/database.ajfs | xargs -0 ls -l`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := cleanup.Config{
+			CommonConfig:   commonConfig,
+			OnlyDuplicates: cleanupDupes,
+			BackupPath:     cleanupBackupPath,
+			NullSeparated:  cleanupPrint0,
+		}
+		cfg.DbPath = dbPathFromArgs(args)
+		cfg.UnderConfig = parseUnderConfig(cfg.DbPath)
+		cfg.DisplayFullPaths = parseFullPaths(cfg.DbPath)
+
+		exp, _, err := parseSearchExpression()
+		if err != nil {
+			exitOnError(err, 1)
+		}
+		cfg.Expression = exp
+
+		if err := cleanup.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cleanupCmd)
+	addUnderFlag(cleanupCmd)
+
+	cleanupCmd.Flags().BoolVar(&cleanupDupes, "dupes", false, "Only files that have a duplicate inside the same database.")
+	cleanupCmd.Flags().StringVar(&cleanupBackupPath, "backup", "", "Only files that also exist in this backup database.")
+	addFullPathsFlags(cleanupCmd)
+	cleanupCmd.Flags().BoolVarP(&cleanupPrint0, "print0", "0", false, "Separate the paths with a NUL character instead of a newline.")
+
+	addSearchFlags(cleanupCmd)
+}
+
+var (
+	cleanupDupes      bool
+	cleanupBackupPath string
+	cleanupPrint0     bool
+)
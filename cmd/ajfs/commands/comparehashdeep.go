@@ -0,0 +1,84 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"errors"
+	"os"
+
+	"github.com/andrejacobs/ajfs/internal/app/hashdeep"
+	"github.com/spf13/cobra"
+)
+
+// ajfs compare-hashdeep.
+var compareHashdeepCmd = &cobra.Command{
+	Use:   "compare-hashdeep",
+	Short: "Audit a database against a hashdeep manifest.",
+	Long: `Audit the files in a database against a hashdeep manifest using the same
+semantics as "hashdeep -a".
+
+The database must contain file signature hashes and the manifest must contain
+hashes calculated with the same algorithm.
+
+Each file is reported as one of the following:
+* Matched: The path, size and hash are the same as in the manifest.
+* Changed: The path is in the manifest but the size or hash differs.
+* Moved:   The hash is in the manifest but under a different path.
+* New:     Neither the path nor the hash is in the manifest.
+* Missing: A manifest entry for which neither the path nor the hash was found.
+
+Matched files are only displayed when using "--verbose". The command exits
+with status 1 when the audit fails.
+`,
+	Example: `  # audit the default ./db.ajfs database against the manifest
+  ajfs compare-hashdeep manifest.hashdeep
+
+  # audit the database against the manifest
+  ajfs compare-hashdeep /path/to/database.ajfs manifest.hashdeep`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := hashdeep.Config{
+			CommonConfig: commonConfig,
+		}
+
+		switch len(args) {
+		case 1:
+			cfg.DbPath = defaultDBPath
+			cfg.ManifestPath = args[0]
+		case 2:
+			cfg.DbPath = args[0]
+			cfg.ManifestPath = args[1]
+		default:
+			panic("invalid args")
+		}
+
+		if err := hashdeep.Run(cmd.Context(), cfg); err != nil {
+			// The report has already been displayed
+			if errors.Is(err, hashdeep.ErrAuditFailed) {
+				os.Exit(1)
+			}
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(compareHashdeepCmd)
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package commands
+
+import (
+	"github.com/andrejacobs/ajfs/internal/app/convert"
+	"github.com/spf13/cobra"
+)
+
+// ajfs convert.
+var convertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "Convert a database to a different format version or hashing algorithm.",
+	Long: `Convert an existing database into a new database using a different file
+format version and/or file signature hashing algorithm.
+
+The path entries are copied as is from the existing database.
+
+File format version 2 stores each path relative to the path of the entry
+before it, which makes databases of deep directory trees a lot smaller. Use
+"--to-version=1" to create a database that can be read by older versions of ajfs.
+
+If the hashing algorithm stays the same then the existing file signature
+hashes are copied over. When a different hashing algorithm is specified
+then the file signature hashes need to be calculated again and thus the
+root path stored in the database must be accessible.
+
+The hash calculation process can be safely interrupted using Ctrl+C (SIGTERM)
+and be resumed at another time using "ajfs resume" on the new database.
+
+Supported file signature hash algorithms are: sha1, sha256 and sha512.
+
+The database integrity checksum algorithm is kept the same unless "--checksum"
+is specified. Valid values are: crc32 and sha256.`,
+	Example: `  # copy the database into the current file format version
+  ajfs convert /path/to/old.ajfs /path/to/new.ajfs
+
+  # convert the database to use SHA-1 for the file signature hashes
+  ajfs convert --algo=sha1 --progress /path/to/old.ajfs /path/to/new.ajfs
+
+  # convert the database to also use a SHA-256 integrity checksum
+  ajfs convert --checksum=sha256 /path/to/old.ajfs /path/to/new.ajfs
+
+  # convert the database to file format version 1
+  ajfs convert --to-version=1 /path/to/old.ajfs /path/to/new.ajfs`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		commonConfig.Progress = showProgress
+
+		cfg := convert.Config{
+			CommonConfig: commonConfig,
+			OutPath:      args[1],
+			ToVersion:    convertToVersion,
+		}
+		cfg.DbPath = args[0]
+
+		if cmd.Flags().Changed("algo") {
+			algo, err := algoFromFlag(convertHashAlgo)
+			if err != nil {
+				exitOnError(err, 1)
+			}
+
+			cfg.ChangeAlgo = true
+			cfg.Algo = algo
+		}
+
+		if cmd.Flags().Changed("checksum") {
+			checksumAlgo, err := checksumAlgoFromFlag(convertChecksumAlgo)
+			if err != nil {
+				exitOnError(err, 1)
+			}
+
+			cfg.ChangeChecksum = true
+			cfg.ChecksumAlgo = checksumAlgo
+		}
+
+		if err := convert.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+
+	convertCmd.Flags().StringVarP(&convertHashAlgo, "algo", "a", "sha256", "Hashing algorithm to use. Valid values are 'sha1', 'sha256', 'sha512' and 'md5' (legacy).")
+	convertCmd.Flags().StringVar(&convertChecksumAlgo, "checksum", "crc32", "Database integrity checksum algorithm. Valid values are 'crc32' and 'sha256'.")
+	convertCmd.Flags().IntVar(&convertToVersion, "to-version", 0, "File format version of the new database. Defaults to the current version.")
+	convertCmd.Flags().BoolVarP(&showProgress, "progress", "p", false, "Display progress information.")
+}
+
+var (
+	convertHashAlgo     string
+	convertChecksumAlgo string
+	convertToVersion    int
+)
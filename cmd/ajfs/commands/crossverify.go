@@ -0,0 +1,87 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package commands
+
+import (
+	"errors"
+	"os"
+
+	"github.com/andrejacobs/ajfs/internal/app/crossverify"
+	"github.com/spf13/cobra"
+)
+
+// ajfs cross-verify.
+var crossVerifyCmd = &cobra.Command{
+	Use:   "cross-verify",
+	Short: "Verify the hashes of files present in two databases.",
+	Long: `Verify that the files which exist at the same path in both databases have the
+same file signature hash. Useful for detecting silent corruption between a
+source and its backup.
+
+Both databases must contain file signature hashes calculated with the same
+algorithm. Files that only exist in one of the databases are ignored (see
+"ajfs diff").
+
+Each file that differs is reported as one of the following:
+* Corrupted: The size and last modification time are the same but the hash differs.
+* Changed:   The hash differs along with the size or last modification time.
+
+Matched files are only displayed when using "--verbose". The command exits
+with status 1 when any file differs.
+
+When the databases were created using "ajfs scan --hash-times", each reported
+file also states when its hash was first computed and when it was re-verified.
+`,
+	Example: `  # verify the default ./db.ajfs database against the backup
+  ajfs cross-verify /path/to/backup.ajfs
+
+  # verify the source database against the backup database
+  ajfs cross-verify /path/to/source.ajfs /path/to/backup.ajfs`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := crossverify.Config{
+			CommonConfig: commonConfig,
+		}
+
+		switch len(args) {
+		case 1:
+			cfg.DbPath = defaultDBPath
+			cfg.OtherPath = args[0]
+		case 2:
+			cfg.DbPath = args[0]
+			cfg.OtherPath = args[1]
+		default:
+			panic("invalid args")
+		}
+
+		if err := crossverify.Run(cmd.Context(), cfg); err != nil {
+			// The report has already been displayed
+			if errors.Is(err, crossverify.ErrVerifyFailed) {
+				os.Exit(1)
+			}
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(crossVerifyCmd)
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"github.com/andrejacobs/ajfs/internal/app/daemon"
+	"github.com/spf13/cobra"
+)
+
+// ajfs daemon.
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Serve search, diff and dupes queries to other processes.",
+	Long: `Keep one or more databases open and answer search, diff and dupes queries
+from other processes until interrupted (e.g. Ctrl+C).
+
+This avoids the cost of opening and reading the databases for every query,
+which makes it useful when another tool needs to run many queries.
+
+The requests and responses are JSON documents sent over HTTP, so any HTTP
+client can be used. The daemon listens on either a unix domain socket
+(unix:///path/to/socket) or a TCP address (tcp://host:port). There is no
+authentication, so prefer a unix socket or only listen on localhost.
+
+HTTP with JSON is used instead of gRPC so that ajfs does not depend on the
+protobuf and gRPC libraries and no generated client code is needed. The
+databases are not memory mapped. They are kept open and every query reads the
+entries through the normal file path.
+
+The databases are addressed by their path or their name, which is the file
+name without the extension (e.g. "nas" for /backups/nas.ajfs). The name can be
+omitted when only a single database is being served.
+
+Endpoints:
+  GET  /databases  List the databases being served.
+  POST /search     Search a database. The body uses the same criteria as
+                   "ajfs search": regex, iregex, name, iname, path, ipath,
+                   size (a list), size_blocks, type, hash, id, before, after
+                   and between. Use limit to return at most n entries.
+                   e.g. {"db": "nas", "iname": ["*.pdf"], "size": ["+1M"]}
+  POST /dupes      Find duplicate files. e.g. {"db": "nas"}
+  POST /diff       Compare two databases. e.g. {"lhs": "laptop", "rhs": "nas"}
+
+Errors are returned with a non 2xx status code and a body of {"error": "..."}.`,
+	Example: `  # serve the default ./db.ajfs database on a unix socket
+  ajfs daemon --listen unix:///tmp/ajfs.sock
+
+  # search for all PDF files using curl
+  curl --unix-socket /tmp/ajfs.sock -d '{"iname": ["*.pdf"]}' http://ajfs/search
+
+  # serve two databases on localhost
+  ajfs daemon --listen tcp://127.0.0.1:7070 /path/to/laptop.ajfs /path/to/nas.ajfs
+
+  # compare them
+  curl -d '{"lhs": "laptop", "rhs": "nas"}' http://127.0.0.1:7070/diff`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := daemon.Config{
+			CommonConfig: commonConfig,
+			Listen:       daemonListen,
+			DbPaths:      args,
+		}
+		if len(cfg.DbPaths) == 0 {
+			cfg.DbPaths = []string{defaultDBPath}
+		}
+
+		if err := daemon.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+
+	daemonCmd.Flags().StringVar(&daemonListen, "listen", "unix:///tmp/ajfs.sock", "Address to listen on. Either unix:///path/to/socket or tcp://host:port.")
+}
+
+var (
+	daemonListen string
+)
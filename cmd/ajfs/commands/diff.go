@@ -22,8 +22,10 @@ package commands
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/andrejacobs/ajfs/internal/app/diff"
+	"github.com/andrejacobs/ajfs/internal/style"
 	"github.com/spf13/cobra"
 )
 
@@ -43,6 +45,9 @@ You can compare:
 * A database against another file system hierarchy.
 * One file system hierarchy against another one.
 
+When a database was scanned with "--archives", the members of the archives in
+the file system hierarchy it is compared against are also recorded.
+
 Differences are displayed in the following format:
 
 * If the file or directory only exists in the left hand side (as in removed 
@@ -65,6 +70,9 @@ Differences are displayed in the following format:
    * s: size has changed.
    * l: last modification date has changed.
    * x: file signature hash has changed.
+   * c: (directories only, shown in place of x) the number of entries inside the
+     directory has changed. Requires both databases to have been created using
+     "ajfs scan --dir-stats".
    * ~: this property has not changed.
 
    For example a file that has changed in size and its last modification date:
@@ -78,11 +86,47 @@ Differences are displayed in the following order:
 * Items that exist on both sides and have changed.
 
 You can also filter on items to be included or excluded from the diff output.
-The filter uses the same f, d, m, s, l and x notation.
+The filter uses the same f, d, m, s, l, x and c notation.
 The filter can also include - for LHS, + for RHS or ~ for something has changed.
 Include filters are checked first and at least one need to be matched for the item to appear in the output.
 Exclude filters are checked after any include filters and an item need to not match any exclude filter to be kept
-in the output.`,
+in the output.
+
+Ignore rules are applied before the filters:
+* --ignore: Differences for paths matching the regular expression are ignored
+  (e.g. log directories that always differ).
+* --ignore-changes: The listed classes of changes are ignored. Valid values are
+  mode, size, mtime, hash and content. An item that only has ignored changes is
+  treated as unchanged (e.g. environments where mtimes are routinely rewritten).
+* --mtime-precision: Modification times that differ by at most the duration
+  are treated as unchanged (e.g. 2s when one side is stored on a FAT file
+  system). Modification times are always compared as instants in time, thus
+  databases created in different time zones can be compared.
+
+Use --summarize-depth N to roll up the file differences below a depth of N
+into a single line per directory with the counts and total size of the files,
+e.g. "photos/2021: 134 added, 2 changed, 1.2 GB". Files directly inside the
+root are summarized as ".".
+
+Entries are paired up using the path identifier stored in each database. Use
+--relative to pair them up purely by their path relative to the root instead,
+regardless of the root paths and of how the identifiers were derived (e.g. a
+database created on Windows compared against one created on Linux). This is
+the common case when comparing a drive against its copy.
+
+Use --report out.html to write the differences as a standalone HTML report
+instead of displaying them. The report displays the differences as a
+collapsible tree with the rolled up file differences of each directory and can
+be filtered by the type of difference and by path. It can be opened in any
+browser and shared with people that don't use ajfs.
+
+Use --check-trash when the right hand side is a file system hierarchy (e.g. a
+database against its root path) to check if the items that are missing from
+disk were moved into a trash instead of being deleted. Each missing item is
+reported as "(found in trash: path)" or as "(gone)". The ajfs trash (see
+"ajfs undo"), the Linux (freedesktop.org) trash, the Windows Recycle Bin and the
+macOS Trash are checked. The macOS Trash does not record where an item came
+from and thus items are matched by name and size.`,
 	Example: `  # differences between the default ./db.ajfs database and the root path
   ajfs diff
 
@@ -107,6 +151,24 @@ in the output.`,
   # ignore differences where a directory's size or a file's mode has changed (e.g. copying files from a Mac to a NAS)
   ajfs diff -e=ds -e=fm /path/to/lhs /path/to/rhs
 
+  # focus on content changes while ignoring the log directory
+  ajfs diff --ignore-changes mtime,mode --ignore '^var/log/' /path/to/lhs /path/to/rhs
+
+  # compare a drive against its copy on a FAT formatted USB drive
+  ajfs diff --mtime-precision 2s /path/to/drive.ajfs /path/to/usb.ajfs
+
+  # compare a drive against its copy by relative path only
+  ajfs diff --relative /path/to/drive.ajfs /path/to/copy.ajfs
+
+  # check if the files that are missing from disk are still in the trash
+  ajfs diff --check-trash --include=- /path/to/database.ajfs
+
+  # write an HTML report that can be shared with colleagues
+  ajfs diff --report changes.html /path/to/lhs.ajfs /path/to/rhs.ajfs
+
+  # one line per top level directory and its sub directories
+  ajfs diff --summarize-depth 2 /path/to/lhs.ajfs /path/to/rhs.ajfs
+
   # only show differences for files on LHS or RHS and exclude if the size or last modification time has been changed
   ajfs diff -i=f- -i=f+ -e=s -e=l /path/to/lhs /path/to/rhs`,
 	Args: cobra.MaximumNArgs(2),
@@ -114,6 +176,10 @@ in the output.`,
 		cfg := diff.Config{
 			CommonConfig: commonConfig,
 		}
+		if diffRelative {
+			cfg.Match = diff.MatchByRelativePath
+		}
+		cfg.CheckTrash = diffCheckTrash
 
 		switch len(args) {
 		case 0:
@@ -125,15 +191,33 @@ in the output.`,
 			cfg.RhsPath = args[1]
 		}
 
+		var summary *diff.DepthSummary
+		displayFn := printDiff
+		if diffSummarizeDepth > 0 {
+			summary = diff.NewDepthSummary(diffSummarizeDepth)
+			displayFn = summary.Compare
+		} else if diffSummarizeDepth < 0 {
+			exitOnError(fmt.Errorf("--summarize-depth must be 1 or more"), 1)
+		}
+
+		var report *diff.Report
+		if diffReport != "" {
+			if summary != nil {
+				exitOnError(fmt.Errorf("--report can not be used with --summarize-depth"), 1)
+			}
+			report = diff.NewReport()
+			displayFn = report.Compare
+		}
+
 		stats := diff.DiffStats{}
 		if showStats {
-			stats.Fn = printDiff
+			stats.Fn = displayFn
 			cfg.Fn = stats.Compare
 		} else if showOnlyStats {
 			stats.Fn = func(d diff.Diff) error { return nil }
 			cfg.Fn = stats.Compare
 		} else {
-			cfg.Fn = printDiff
+			cfg.Fn = displayFn
 		}
 
 		var err error
@@ -145,11 +229,40 @@ in the output.`,
 		if err != nil {
 			exitOnError(err, 1)
 		}
+		cfg.Ignore.Paths, err = diff.ParseIgnorePaths(diffIgnorePaths)
+		if err != nil {
+			exitOnError(err, 1)
+		}
+		cfg.Ignore.Changes, err = diff.ParseIgnoreChanges(diffIgnoreChanges)
+		if err != nil {
+			exitOnError(err, 1)
+		}
+		if diffMTimePrecision < 0 {
+			exitOnError(fmt.Errorf("--mtime-precision can't be negative"), 1)
+		}
+		cfg.Ignore.ModTimePrecision = diffMTimePrecision
 
-		if err := diff.Run(cfg); err != nil {
+		if err := diff.Run(cmd.Context(), cfg); err != nil {
 			exitOnError(err, 1)
 		}
 
+		if report != nil {
+			rhs := cfg.RhsPath
+			if rhs == "" {
+				rhs = fmt.Sprintf("root path of %s", cfg.LhsPath)
+			}
+			if err := report.WriteFile(diffReport, cfg.LhsPath, rhs, commonConfig.DisplayTime(time.Now())); err != nil {
+				exitOnError(err, 1)
+			}
+			commonConfig.VerbosePrintln(fmt.Sprintf("Report written to %q", diffReport))
+		}
+
+		if summary != nil && !showOnlyStats {
+			for _, s := range summary.Summaries() {
+				fmt.Println(s.String())
+			}
+		}
+
 		if showStats || showOnlyStats {
 			fmt.Println()
 			fmt.Println("Statistics:")
@@ -164,6 +277,11 @@ in the output.`,
 			fmt.Printf("Size changed:                   %d\n", stats.SizeChanged)
 			fmt.Printf("Last modification time changed: %d\n", stats.ModTimeChanged)
 			fmt.Printf("File signature hash changed:    %d\n", stats.HashChanged)
+			fmt.Printf("Directory content changed:      %d\n", stats.ContentChanged)
+			if diffCheckTrash {
+				fmt.Printf("Found in trash:                 %d\n", stats.InTrash)
+				fmt.Printf("Gone:                           %d\n", stats.Gone)
+			}
 		}
 	},
 }
@@ -175,13 +293,27 @@ func init() {
 	diffCmd.Flags().StringArrayVarP(&excludeFilters, "exclude", "e", nil, "Exclude filter")
 	diffCmd.Flags().BoolVarP(&showStats, "stats", "s", false, "Display diffs and statistics")
 	diffCmd.Flags().BoolVarP(&showOnlyStats, "only-stats", "o", false, "Display only statistics")
+	diffCmd.Flags().StringArrayVar(&diffIgnorePaths, "ignore", nil, "Ignore differences for paths matching this regular expression")
+	diffCmd.Flags().IntVar(&diffSummarizeDepth, "summarize-depth", 0, "Roll up the file differences below this depth into one line per directory")
+	diffCmd.Flags().StringSliceVar(&diffIgnoreChanges, "ignore-changes", nil, "Ignore these classes of changes [mode, size, mtime, hash, content]")
+	diffCmd.Flags().DurationVar(&diffMTimePrecision, "mtime-precision", 0, "Treat modification times that differ by at most this duration as unchanged (e.g. 2s for FAT)")
+	diffCmd.Flags().BoolVar(&diffRelative, "relative", false, "Pair up the entries purely by their path relative to the root")
+	diffCmd.Flags().StringVar(&diffReport, "report", "", "Write the differences as a standalone HTML report to this file")
+	diffCmd.Flags().BoolVar(&diffCheckTrash, "check-trash", false, "Check if the items missing from the file system were moved into a trash")
 }
 
 var (
-	includeFilters []string
-	excludeFilters []string
-	showStats      bool
-	showOnlyStats  bool
+	includeFilters     []string
+	excludeFilters     []string
+	showStats          bool
+	showOnlyStats      bool
+	diffIgnorePaths    []string
+	diffIgnoreChanges  []string
+	diffSummarizeDepth int
+	diffRelative       bool
+	diffMTimePrecision time.Duration
+	diffReport         string
+	diffCheckTrash     bool
 )
 
 func printDiff(d diff.Diff) error {
@@ -189,6 +321,10 @@ func printDiff(d diff.Diff) error {
 		return nil
 	}
 
+	if commonConfig.Styled {
+		fmt.Println(style.Paint(d.Color(), d.String()))
+		return nil
+	}
 	fmt.Println(d.String())
 	return nil
 }
@@ -0,0 +1,75 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"github.com/andrejacobs/ajfs/internal/app/doctor"
+	"github.com/spf13/cobra"
+)
+
+// ajfs doctor.
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [database] [root]",
+	Short: "Display environment and performance diagnostics.",
+	Long: `Display information about the environment that affects long running scans
+and suggestions on how to configure them.
+
+The report contains the number of CPUs, the available memory, the file
+system type of the database and the scan root, whether the database is stored
+on the same device as the scan root (the writes then compete with reading the
+files) and the known pitfalls for the environment, such as the coarse
+modification time of FAT file systems and the limited hashing throughput of
+network file systems.
+
+The database and scan root don't need to exist yet.`,
+	Example: `  # diagnose the environment for the default ./db.ajfs database
+  ajfs doctor
+
+  # diagnose scanning /media/backups into the default ./db.ajfs database
+  ajfs doctor /media/backups
+
+  # diagnose scanning /media/backups into a specific database
+  ajfs doctor /path/to/database.ajfs /media/backups`,
+	Args: cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := doctor.Config{
+			CommonConfig: commonConfig,
+		}
+
+		switch len(args) {
+		case 0:
+			cfg.DbPath = defaultDBPath
+		case 1:
+			cfg.DbPath = defaultDBPath
+			cfg.Root = args[0]
+		case 2:
+			cfg.DbPath = args[0]
+			cfg.Root = args[1]
+		}
+
+		if err := doctor.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
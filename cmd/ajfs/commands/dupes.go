@@ -21,6 +21,9 @@
 package commands
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/andrejacobs/ajfs/internal/app/dupes"
 	"github.com/spf13/cobra"
 )
@@ -47,7 +50,68 @@ Count: 2
 Total Size: 22334814 [22 MB]
 <<<
 ` + "```\n" +
-		`To find all duplicate subtrees use the "-d, --dirs" option.
+		`Known-acceptable duplicates can be skipped using the "--ignore-file" option.
+Each line in the ignore file is either a file signature hash, which ignores the
+whole group, or two paths separated by a tab that should not be considered
+duplicates of each other. Empty lines and lines starting with # are skipped.
+Use "--ignore-append" to append the displayed groups to the ignore file.
+
+Files for which the file signature hash has not been calculated (e.g. due to
+errors while scanning) can't be compared and are reported as skipped. Use
+"--potential" to also display these files grouped by the same size and name.
+
+The file signature hash identifies a group of duplicates and stays the same
+across runs, which allows scripts, the ignore file and "ajfs annotate" to refer
+to a group. A group of potential duplicates is identified by the "Group:"
+digest of its size and name instead.
+
+Use "--extensions" to only display the duplicate files that exist under
+different file extensions (e.g. .jpeg and .jpg), which helps to normalize the
+naming of files. Extensions are compared case insensitive.
+
+Use "--by-extension" to display the number and total size of the redundant
+copies per file extension, ordered from the most to the least bytes that could
+be freed, instead of each group. The first file of each group is counted as the
+original that is kept, so only the other copies are counted. This helps to
+decide which types of files to clean up first.
+
+Labels recorded using "ajfs annotate" are displayed with each group and path.
+Use "--unreviewed" to skip the groups that have been labelled, either as a whole
+or by labelling each of the files in the group.
+
+Use "--confirm-bytes" to compare the bytes of the files on disk before a group
+is displayed, for when a matching hash is not certain enough. The files are
+read in chunks and the comparison stops at the first difference. Each group
+displays "Bytes: identical", "Bytes: differ" when a file has different bytes
+than the first one or "Bytes: unconfirmable" when a file could not be read.
+
+Use "--format fdupes" (or "jdupes") to write the full paths of each group on a
+line each, followed by a blank line, as output by fdupes and jdupes. Use
+"--format rmlint" to write the JSON document of rmlint, in which the first file
+of each group is the original, and that can be used with "rmlint --replay".
+This allows existing deletion tooling to act on the duplicates. Only the groups
+are written and with "--confirm-bytes" only the groups with identical bytes.
+
+Use "--full" to display the paths of the duplicate files prefixed with the root
+path of the database (see "ajfs set-paths" to make this the default). The
+subtrees and the duplicates across the volumes of a catalog are always displayed
+relative to the root path.
+
+Databases with more than 5 million files are sorted in runs that are written to
+temporary files (see $TMPDIR) to limit the memory used while finding duplicates.
+
+A catalog (see "ajfs catalog") can be specified instead of a database to find
+duplicate files across all of its volumes. Each path is prefixed with the
+volume name. Only volumes hashed with the same algorithm can be compared.
+
+A directory can be specified after the database to display which of its files
+already exist in the database, for example to triage a downloads folder against
+an archive. Only the files with the same size as a file in the database are
+hashed, using at most "--jobs" files at the same time. The previously calculated
+hashes are reused from the hash cache (see "ajfs cache"). Use "--no-cache" to
+not use the cache. Empty files are never considered to be duplicates.
+
+To find all duplicate subtrees use the "-d, --dirs" option.
 Each parent of a subtree in the hierarchy is given a unique signature that is 
 calculated based on each of its children's signatures. Thus it can be used
 to find subtrees in the hierarchy that share the same children regardless
@@ -89,18 +153,77 @@ For example: We have 2 copies of the Day1 directory.
   # display duplicate files from the specified database
   ajfs dupes /path/to/database.ajfs
 
+  # display files in photos that already exist somewhere in archive
+  ajfs dupes --within photos --against archive /path/to/database.ajfs
+
+  # skip known-acceptable duplicates and add the displayed groups to the ignore file
+  ajfs dupes --ignore-file dupes.ignore --ignore-append /path/to/database.ajfs
+
+  # display identical files that were saved with different extensions
+  ajfs dupes --extensions /path/to/database.ajfs
+
+  # display which file types use the most space in duplicates
+  ajfs dupes --by-extension /path/to/database.ajfs
+
+  # display the duplicate groups that still need to be reviewed
+  ajfs dupes --unreviewed /path/to/database.ajfs
+
+  # confirm the duplicates by comparing the bytes of the files on disk
+  ajfs dupes --confirm-bytes /path/to/database.ajfs
+
+  # write the duplicates for the tooling of fdupes or rmlint
+  ajfs dupes --format fdupes /path/to/database.ajfs > dupes.txt
+  ajfs dupes --format rmlint /path/to/database.ajfs > rmlint.json
+
+  # display duplicate files across all the volumes in a catalog
+  ajfs dupes backups.ajfscat
+
+  # display the downloaded files that already exist in the database
+  ajfs dupes /path/to/database.ajfs ~/Downloads
+
   # display duplicate subtrees in the tree format
   ajfs dupes --dirs --tree /path/to/database.ajfs`,
-	Args: cobra.MaximumNArgs(1),
+	Args: cobra.MaximumNArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg := dupes.Config{
 			CommonConfig: commonConfig,
 			Subtrees:     dupesDirs,
 			PrintTree:    dupesDirsPrintTree,
+			Within:       dupesWithin,
+			Against:      dupesAgainst,
+			IgnoreFile:   dupesIgnoreFile,
+			AppendIgnore: dupesIgnoreAppend,
+			Potential:    dupesPotential,
+
+			MixedExtensions: dupesExtensions,
+			ByExtension:     dupesByExtension,
+			Unreviewed:      dupesUnreviewed,
+			ConfirmBytes:    dupesConfirmBytes,
+
+			Jobs: dupesJobs,
 		}
 		cfg.DbPath = dbPathFromArgs(args)
+		cfg.UnderConfig = parseUnderConfig(cfg.DbPath)
+		cfg.DisplayFullPaths = parseFullPaths(cfg.DbPath)
+		if len(args) > 1 {
+			cfg.LivePath = args[1]
+			cfg.HashCachePath = hashCachePath(dupesNoCache)
+		} else if dupesNoCache {
+			exitOnError(fmt.Errorf("--no-cache can only be used with a directory"), 1)
+		}
+
+		switch strings.ToLower(dupesFormat) {
+		case "ajfs":
+			cfg.Format = dupes.FormatDefault
+		case "fdupes", "jdupes":
+			cfg.Format = dupes.FormatFdupes
+		case "rmlint":
+			cfg.Format = dupes.FormatRmlint
+		default:
+			exitOnError(fmt.Errorf("invalid duplicates format %q", dupesFormat), 1)
+		}
 
-		if err := dupes.Run(cfg); err != nil {
+		if err := dupes.Run(cmd.Context(), cfg); err != nil {
 			exitOnError(err, 1)
 		}
 
@@ -109,12 +232,38 @@ For example: We have 2 copies of the Day1 directory.
 
 func init() {
 	rootCmd.AddCommand(dupesCmd)
+	addUnderFlag(dupesCmd)
+	addFullPathsFlags(dupesCmd)
 
 	dupesCmd.Flags().BoolVarP(&dupesDirs, "dirs", "d", false, "Display duplicate subtree directories.")
 	dupesCmd.Flags().BoolVarP(&dupesDirsPrintTree, "tree", "t", false, "Display the tree hierarchy of duplicate subtrees.")
+	dupesCmd.Flags().StringVar(&dupesWithin, "within", "", "Only display duplicate files that have a copy at or below this path (relative to the database root or an absolute path inside the root).")
+	dupesCmd.Flags().StringVar(&dupesAgainst, "against", "", "Only display duplicate files that also have a copy at or below this path (relative to the database root or an absolute path inside the root).")
+	dupesCmd.Flags().StringVar(&dupesIgnoreFile, "ignore-file", "", "Skip the known-acceptable duplicates listed in the file.")
+	dupesCmd.Flags().BoolVar(&dupesIgnoreAppend, "ignore-append", false, "Append the displayed groups to the ignore file.")
+	dupesCmd.Flags().BoolVar(&dupesPotential, "potential", false, "Display files without a hash that share the same size and name.")
+	dupesCmd.Flags().BoolVar(&dupesExtensions, "extensions", false, "Only display duplicate files that have different file extensions.")
+	dupesCmd.Flags().BoolVar(&dupesByExtension, "by-extension", false, "Display the number and total size of the redundant copies per file extension.")
+	dupesCmd.Flags().BoolVar(&dupesUnreviewed, "unreviewed", false, "Skip the duplicate groups that have been labelled using ajfs annotate.")
+	dupesCmd.Flags().BoolVar(&dupesConfirmBytes, "confirm-bytes", false, "Compare the bytes of the files on disk before a group is displayed.")
+	dupesCmd.Flags().StringVar(&dupesFormat, "format", "ajfs", "Output format: ajfs, fdupes, jdupes or rmlint.")
+	dupesCmd.Flags().IntVarP(&dupesJobs, "jobs", "j", 0, "Maximum number of files in the directory to hash at the same time. 0 means the number of CPUs.")
+	dupesCmd.Flags().BoolVar(&dupesNoCache, "no-cache", false, "Do not reuse or store file signature hashes using the hash cache.")
 }
 
 var (
 	dupesDirs          = false
 	dupesDirsPrintTree = false
+	dupesWithin        = ""
+	dupesAgainst       = ""
+	dupesIgnoreFile    = ""
+	dupesIgnoreAppend  = false
+	dupesPotential     = false
+	dupesExtensions    = false
+	dupesByExtension   = false
+	dupesUnreviewed    = false
+	dupesConfirmBytes  = false
+	dupesFormat        = "ajfs"
+	dupesJobs          = 0
+	dupesNoCache       = false
 )
@@ -32,16 +32,43 @@ import (
 var exportCmd = &cobra.Command{
 	Use:   "export",
 	Short: "Export a database.",
-	Long:  `Export a database into one of the following formats: CSV, JSON or Hashdeep`,
+	Long: `Export a database into one of the following formats: CSV, JSON, NDJSON or Hashdeep
+
+The CSV export starts with comment lines (prefixed with #) containing the root
+path and the hashing algorithm used.
+
+The NDJSON (newline delimited JSON) export writes one JSON object per line. The
+first line is the header record containing the "database" information and each
+line after that is an entry. Useful for stream processors like "jq -c".
+
+Use "--compress" to gzip the CSV or NDJSON output.
+
+Paths that are not valid UTF-8 (possible on Linux) are escaped in the CSV, JSON
+and NDJSON exports: each invalid byte is written as \xNN and each backslash as
+\\. The JSON and NDJSON entries of these paths also contain the original bytes
+encoded as base64 in "pathBase64". The Hashdeep export writes the paths as is,
+the same as hashdeep itself.
+
+Only a subset of the entries can be exported by using the same matching flags
+as the search command (e.g. --type f --size +100M). See "ajfs search --help".`,
 	Example: `  # export the default ./db.ajfs to a CSV file
   ajfs export /path/to/export.csv
 
   # export a database to a CSV file
   ajfs export /path/to/database.ajfs /path/to/export.csv
 
+  # export a database to a gzip compressed CSV file
+  ajfs export --compress /path/to/database.ajfs /path/to/export.csv.gz
+
   # export with full path information to a JSON file
   ajfs export --full --format=json /path/to/database.ajfs /path/to/export.json
 
+  # export one JSON object per line
+  ajfs export --format=ndjson /path/to/database.ajfs /path/to/export.ndjson
+
+  # export only the files bigger than 100MB
+  ajfs export --type f --size +100M /path/to/database.ajfs /path/to/export.csv
+
   # export to a hashdeep file. NOTE: the database must contain file signature hashes
   ajfs export --format=hashdeep /path/to/export.sha256`,
 	Args: cobra.RangeArgs(1, 2),
@@ -49,6 +76,7 @@ var exportCmd = &cobra.Command{
 		cfg := export.Config{
 			CommonConfig: commonConfig,
 			FullPaths:    exportFullPaths,
+			Compress:     exportCompress,
 		}
 
 		switch len(args) {
@@ -61,6 +89,7 @@ var exportCmd = &cobra.Command{
 		default:
 			panic("invalid args")
 		}
+		cfg.UnderConfig = parseUnderConfig(cfg.DbPath)
 
 		switch strings.ToLower(exportFormat) {
 		case "csv":
@@ -69,11 +98,19 @@ var exportCmd = &cobra.Command{
 			cfg.Format = export.FormatJSON
 		case "hashdeep":
 			cfg.Format = export.FormatHashdeep
+		case "ndjson":
+			cfg.Format = export.FormatNDJSON
 		default:
 			exitOnError(fmt.Errorf("invalid export format %q", exportFormat), 1)
 		}
 
-		if err := export.Run(cfg); err != nil {
+		exp, _, err := parseSearchExpression()
+		if err != nil {
+			exitOnError(err, 1)
+		}
+		cfg.Expression = exp
+
+		if err := export.Run(cmd.Context(), cfg); err != nil {
 			exitOnError(err, 1)
 		}
 	},
@@ -81,12 +118,16 @@ var exportCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(exportCmd)
+	addUnderFlag(exportCmd)
+	addSearchFlags(exportCmd)
 
-	exportCmd.Flags().StringVar(&exportFormat, "format", "csv", "Export format: csv, json or hashdeep.")
+	exportCmd.Flags().StringVar(&exportFormat, "format", "csv", "Export format: csv, json, ndjson or hashdeep.")
 	exportCmd.Flags().BoolVarP(&exportFullPaths, "full", "f", false, "Export full paths for entries.")
+	exportCmd.Flags().BoolVar(&exportCompress, "compress", false, "Gzip compress the CSV or NDJSON output. Adds .gz to the export path if needed.")
 }
 
 var (
 	exportFormat    string
 	exportFullPaths bool
+	exportCompress  bool
 )
@@ -22,9 +22,13 @@ package commands
 
 import (
 	"fmt"
+	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/search"
+	"github.com/andrejacobs/ajfs/internal/db"
 	"github.com/andrejacobs/ajfs/internal/filter"
 	"github.com/andrejacobs/go-aj/file"
 	"github.com/spf13/cobra"
@@ -33,12 +37,81 @@ import (
 var (
 	includePathRegex []string // Regexes for path inclusion filtering
 	excludePathRegex []string // Regexes for path exclusion filtering
+
+	minSizeExpr   string // Exclude files smaller than this size
+	maxSizeExpr   string // Exclude files larger than this size
+	newerThanExpr string // Exclude files last modified at or before this date/time
+	olderThanExpr string // Exclude files last modified at or after this date/time
+
+	specialPolicy string // Whether symlinks, FIFOs, sockets and device files are recorded or ignored
+)
+
+const (
+	specialPolicyRecord = "record"
+	specialPolicyIgnore = "ignore"
 )
 
 // Add the path filtering flags to the cobra command.
 func addPathFilteringFlags(c *cobra.Command) {
 	c.Flags().StringArrayVarP(&includePathRegex, "include", "i", nil, "Include path regex filter")
 	c.Flags().StringArrayVarP(&excludePathRegex, "exclude", "e", nil, "Exclude path regex filter")
+	c.Flags().StringVar(&minSizeExpr, "min-size", "", "Exclude files smaller than this size. e.g. 1k, 10M")
+	c.Flags().StringVar(&maxSizeExpr, "max-size", "", "Exclude files larger than this size. e.g. 500M, 2G")
+	c.Flags().StringVar(&newerThanExpr, "newer-than", "", "Only include files modified after this date/time. e.g. 2024-01-31, 30D")
+	c.Flags().StringVar(&olderThanExpr, "older-than", "", "Only include files modified before this date/time. e.g. 2024-01-31, 5Y")
+	c.Flags().StringVar(&specialPolicy, "special", specialPolicyRecord, "Whether symlinks, FIFOs, sockets and device files are recorded. [record, ignore]")
+}
+
+// Parse the size and age flags into file limits.
+func parseFileLimits() (filter.FileLimits, error) {
+	var limits filter.FileLimits
+	var err error
+
+	if limits.MinSize, err = parseSizeLimit("min-size", minSizeExpr); err != nil {
+		return limits, err
+	}
+	if limits.MaxSize, err = parseSizeLimit("max-size", maxSizeExpr); err != nil {
+		return limits, err
+	}
+	if limits.MaxSize > 0 && limits.MinSize > limits.MaxSize {
+		return limits, fmt.Errorf("--min-size %q is larger than --max-size %q", minSizeExpr, maxSizeExpr)
+	}
+
+	if limits.NewerThan, err = parseTimeLimit("newer-than", newerThanExpr); err != nil {
+		return limits, err
+	}
+	if limits.OlderThan, err = parseTimeLimit("older-than", olderThanExpr); err != nil {
+		return limits, err
+	}
+
+	return limits, nil
+}
+
+func parseSizeLimit(flag string, expression string) (uint64, error) {
+	if expression == "" {
+		return 0, nil
+	}
+
+	s, err := search.NewSize(expression)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse --%s. %w", flag, err)
+	}
+	if !s.IsExact() {
+		return 0, fmt.Errorf("failed to parse --%s. only a single size is allowed %q", flag, expression)
+	}
+	return s.Size(), nil
+}
+
+func parseTimeLimit(flag string, expression string) (time.Time, error) {
+	if expression == "" {
+		return time.Time{}, nil
+	}
+
+	s, err := search.NewModTimeBefore(expression)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse --%s. %w", flag, err)
+	}
+	return s.Reference(), nil
 }
 
 // Parse the include path regexes into file and dir path matchers.
@@ -68,7 +141,20 @@ func parseFilterConfig() (*config.FilterConfig, error) {
 		return nil, fmt.Errorf("failed to parse the exclude filtering flags. %w", err)
 	}
 
-	result.FileExcluder = file.MatchAppleDSStore(exclF)
+	limits, err := parseFileLimits()
+	if err != nil {
+		return nil, err
+	}
+
+	result.FileExcluder = file.MatchAppleDSStore(filter.MatchOutsideLimits(limits)(exclF))
+
+	switch specialPolicy {
+	case specialPolicyRecord:
+	case specialPolicyIgnore:
+		result.FileExcluder = filter.MatchSpecialFiles(result.FileExcluder)
+	default:
+		return nil, fmt.Errorf("invalid --special value %q. expected %q or %q", specialPolicy, specialPolicyRecord, specialPolicyIgnore)
+	}
 	result.DirExcluder = exclD
 
 	if runtime.GOOS == "darwin" {
@@ -78,3 +164,32 @@ func parseFilterConfig() (*config.FilterConfig, error) {
 
 	return result, nil
 }
+
+var (
+	underPath string // Relative path inside the database used to restrict the processed entries
+)
+
+// Add the flag used to restrict processing to a subpath inside the database.
+func addUnderFlag(c *cobra.Command) {
+	c.Flags().StringVar(&underPath, "under", "", "Only process entries at or below this path (relative to the database root or an absolute path inside the root).")
+}
+
+// Parse the under config that can be used by commands.
+// An absolute path is made relative to the root path of the database.
+func parseUnderConfig(dbPath string) config.UnderConfig {
+	var rootPath string
+	if filepath.IsAbs(underPath) {
+		dbf, err := db.OpenDatabase(dbPath)
+		if err != nil {
+			exitOnError(err, 1)
+		}
+		rootPath = dbf.RootPath()
+		dbf.Close()
+	}
+
+	result, err := config.NewUnderConfig(underPath, rootPath)
+	if err != nil {
+		exitOnError(fmt.Errorf("invalid --under path. %w", err), 1)
+	}
+	return result
+}
@@ -34,7 +34,7 @@ var fixCmd = &cobra.Command{
 	Long: `Attempts to repair a damaged database.
 
 Use '--dry-run' to check the integrity of a database without making changes to
-the database. This is equavalent to running 'ajfs check'.
+the database. This check is also performed by 'ajfs check'.
 
 A backup of the database header will be made before applying any changes.
 The backup will be created in the current working directory using the same
@@ -42,6 +42,21 @@ filename as the database with the extension '.bak' added.
 
 Use '--restore /path/to/___.bak' to restore a backup header to a database. 
 
+A database that was not closed cleanly (e.g. the process was killed while
+scanning or resuming) is marked as dirty and can't be opened by the other
+commands until it has been repaired using this command. The file signature
+hashes that were being written at the time are recorded in a journal file
+(the database path with ".journal" added). Hashes that were not written
+completely are cleared so that "ajfs resume" calculates them again.
+
+A database that is damaged beyond repair, e.g. the entries were truncated by a
+scan that crashed, can be salvaged using '--salvage /path/to/new.ajfs'. All the
+entries that can be read completely (and any complete file signature hashes)
+are copied into a new database. The damaged database is not modified.
+
+A sealed database (see "ajfs seal") will not be modified unless "--force" is
+used.
+
 >> Is used to display database errors that were found and that can be corrected.
 !! Is used when an error happened during the process.
 
@@ -53,7 +68,10 @@ Use '--restore /path/to/___.bak' to restore a backup header to a database.
   ajfs fix /path/to/database.ajfs
 
   # restore a backup header file
-  ajfs fix --restore /path/to/header.ajfs.bak /path/to/database.ajfs`,
+  ajfs fix --restore /path/to/header.ajfs.bak /path/to/database.ajfs
+
+  # copy what can still be read from a truncated database into a new one
+  ajfs fix --salvage /path/to/salvaged.ajfs /path/to/database.ajfs`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg := fix.Config{
@@ -61,10 +79,12 @@ Use '--restore /path/to/___.bak' to restore a backup header to a database.
 			Stdin:        os.Stdin,
 			DryRun:       fixDryRun,
 			RestorePath:  fixRestorePath,
+			Force:        fixForce,
+			SalvagePath:  fixSalvagePath,
 		}
 		cfg.DbPath = dbPathFromArgs(args)
 
-		if err := fix.Run(cfg); err != nil {
+		if err := fix.Run(cmd.Context(), cfg); err != nil {
 			exitOnError(err, 1)
 		}
 	},
@@ -75,10 +95,14 @@ func init() {
 
 	fixCmd.Flags().BoolVar(&fixDryRun, "dry-run", false, "Only display the repairs that will need to be performed.")
 	fixCmd.Flags().StringVar(&fixRestorePath, "restore", "", "Path to a backup header to be restored.")
+	fixCmd.Flags().BoolVar(&fixForce, "force", false, "Make changes even if the database has been sealed.")
+	fixCmd.Flags().StringVar(&fixSalvagePath, "salvage", "", "Path to a new database into which the readable entries will be copied.")
 
 }
 
 var (
 	fixDryRun      bool
 	fixRestorePath string
+	fixForce       bool
+	fixSalvagePath string
 )
@@ -0,0 +1,102 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package commands
+
+import (
+	"errors"
+	"os"
+
+	"github.com/andrejacobs/ajfs/internal/app/grep"
+	"github.com/spf13/cobra"
+)
+
+// ajfs grep.
+var grepCmd = &cobra.Command{
+	Use:   "grep PATTERN [database]",
+	Short: "Search the contents of files using the database to find them.",
+	Long: `Search the contents of the files on disk for lines that match the regular
+expression PATTERN. The database is used to find the files to be searched
+instead of walking the file system, which means the files can be narrowed
+down using "--under" and the search criteria (see "ajfs search --help").
+
+Only regular files are searched and multiple files are searched at the same
+time ("--jobs"). The results are displayed in the order stored in the database.
+Files that can't be read are reported and skipped. A warning is displayed when a
+matching file's size or last modification time differs from the database.
+
+The exit code is 1 when none of the files contain a match.`,
+	Example: `  # display the lines containing TODO in all the go files
+  ajfs grep TODO --name '*.go'
+
+  # list the files below the documents directory that contain "invoice"
+  ajfs grep --ignore-case -l invoice --under documents /path/to/database.ajfs
+
+  # search for a literal string in text files modified within the last week
+  ajfs grep -F 'a.b(c)' --iname '*.txt' --after 7D /path/to/database.ajfs`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := grep.Config{
+			CommonConfig:     commonConfig,
+			Pattern:          args[0],
+			FixedString:      grepFixedString,
+			IgnoreCase:       grepIgnoreCase,
+			FilesWithMatches: grepFilesWithMatches,
+			Jobs:             grepJobs,
+		}
+		cfg.DbPath = dbPathFromArgs(args[1:])
+		cfg.UnderConfig = parseUnderConfig(cfg.DbPath)
+		cfg.DisplayFullPaths = parseFullPaths(cfg.DbPath)
+
+		exp, alsoHashes, err := parseSearchExpression()
+		if err != nil {
+			exitOnError(err, 1)
+		}
+		cfg.Expression = exp
+		cfg.AlsoHashes = alsoHashes
+
+		if err := grep.Run(cmd.Context(), cfg); err != nil {
+			if errors.Is(err, grep.ErrNoMatch) {
+				os.Exit(1)
+			}
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(grepCmd)
+	addUnderFlag(grepCmd)
+
+	grepCmd.Flags().BoolVarP(&grepFixedString, "fixed-strings", "F", false, "Match the pattern as a literal string instead of a regular expression.")
+	grepCmd.Flags().BoolVar(&grepIgnoreCase, "ignore-case", false, "Match the pattern case insensitive.")
+	grepCmd.Flags().BoolVarP(&grepFilesWithMatches, "files-with-matches", "l", false, "Only display the entries of the files that contain a match.")
+	grepCmd.Flags().IntVarP(&grepJobs, "jobs", "j", 0, "Maximum number of files to search at the same time. 0 means the number of CPUs.")
+	addFullPathsFlags(grepCmd)
+
+	addSearchFlags(grepCmd)
+}
+
+var (
+	grepFixedString      bool
+	grepIgnoreCase       bool
+	grepFilesWithMatches bool
+	grepJobs             int
+)
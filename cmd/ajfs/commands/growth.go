@@ -0,0 +1,74 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"github.com/andrejacobs/ajfs/internal/app/growth"
+	"github.com/spf13/cobra"
+)
+
+// ajfs growth.
+var growthCmd = &cobra.Command{
+	Use:   "growth",
+	Short: "Display how much each directory grew or shrunk between two databases.",
+	Long: `Display how much the files inside each top level directory grew or shrunk
+between an older and a newer database, together with the number of files that
+were added (New) and removed. This answers "what grew by 300GB since last
+month?" without having to go through the full diff.
+
+The directories are displayed from the largest growth to the largest
+shrinkage. Files that are not inside a directory are displayed on their own and
+directories in which nothing changed are not displayed. Use "--under" to
+display the directories directly below a subpath instead.
+
+Files are paired up using their path relative to the root of each database. A
+file that changed in size is included in the growth but is not counted as added
+or removed.`,
+	Example: `  # display the growth per top level directory
+  ajfs growth last-month.ajfs today.ajfs
+
+  # display the growth of the directories directly below photos with human friendly sizes
+  ajfs growth --human --under photos last-month.ajfs today.ajfs`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := growth.Config{
+			CommonConfig: commonConfig,
+			OldPath:      args[0],
+			NewPath:      args[1],
+			HumanSizes:   growthHumanSizes,
+		}
+		cfg.UnderConfig = parseUnderConfig(cfg.NewPath)
+
+		if err := growth.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(growthCmd)
+	addUnderFlag(growthCmd)
+
+	growthCmd.Flags().BoolVar(&growthHumanSizes, "human", false, "Display sizes in a human friendly format.")
+}
+
+var (
+	growthHumanSizes bool
+)
@@ -30,20 +30,32 @@ var infoCmd = &cobra.Command{
 	Use:   "info",
 	Short: "Display information about a database.",
 	Long: `Display information about a database such as the path it was created from, meta, features and statistics.
-Info will also validate the integrity of the database.`,
+Info will also validate the integrity of the database.
+
+The size of each section of the database file is displayed along with the
+average size of an entry and the overhead of the database compared to only
+storing the path strings.
+
+Use --layout to only display the layout of the database file: the format
+version, the revision of the entries lookup table and the offset and size of
+each section. A database with an unknown lookup table revision is rejected.`,
 	Example: `  # using the default ./db.ajfs database
   ajfs info
 
   # using a specific database
-  ajfs info /path/to/database.ajfs`,
+  ajfs info /path/to/database.ajfs
+
+  # display the layout of the database file
+  ajfs info --layout /path/to/database.ajfs`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg := info.Config{
 			CommonConfig: commonConfig,
+			Layout:       infoLayout,
 		}
 		cfg.DbPath = dbPathFromArgs(args)
 
-		if err := info.Run(cfg); err != nil {
+		if err := info.Run(cmd.Context(), cfg); err != nil {
 			exitOnError(err, 1)
 		}
 
@@ -52,4 +64,10 @@ Info will also validate the integrity of the database.`,
 
 func init() {
 	rootCmd.AddCommand(infoCmd)
+
+	infoCmd.Flags().BoolVar(&infoLayout, "layout", false, "Only display the layout of the database file.")
 }
+
+var (
+	infoLayout bool
+)
@@ -21,6 +21,8 @@
 package commands
 
 import (
+	"fmt"
+
 	"github.com/andrejacobs/ajfs/internal/app/list"
 	"github.com/spf13/cobra"
 )
@@ -29,7 +31,29 @@ import (
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "Display the database path entries.",
-	Long:  `Display all the path entries stored inside a database.`,
+	Long: `Display all the path entries stored inside a database.
+
+Use "--offset" and "--limit" (or "--head" and "--tail") to display only a range
+of the entries. When no other filtering or sorting is requested then the range
+is read directly from the database without reading the preceding entries.
+
+Use "--hash-status" to display the status of the file signature hash of each
+entry, e.g. to see what still remains after an interrupted scan:
+* hashed:  The hash has been calculated.
+* pending: The hash still needs to be calculated (see "ajfs resume").
+* failed:  Calculating the hash failed and the file is recorded in the error log.
+* skipped: The entry is not a regular file and is thus never hashed.
+
+Use "-l" (or "--long") to display the entries in the same columns as "ls -l":
+permissions, number of links, owner, group, size, modification date and name.
+The number of links, owner, group and the target of a symbolic link are not
+stored in the database and are thus displayed as "-", which keeps the positions
+of the columns the same for scripts that parse the output of ls.
+
+Use "--full" to display the paths prefixed with the root path of the database.
+To not have to remember "--full" every time, store the preference in the
+database using "ajfs set-paths" or set AJFS_FULL_PATHS=1 in the environment.
+"--relative" overrides either.`,
 	Example: `  # using the default ./db.ajfs database
   ajfs list
 
@@ -37,18 +61,64 @@ var listCmd = &cobra.Command{
   ajfs list /path/to/database.ajfs
 
   # display full paths, file signature hashes and more information for each entry
-  ajfs list --full --hash --more /path/to/database.ajfs`,
+  ajfs list --full --hash --more /path/to/database.ajfs
+
+  # display the entries in the long format of ls
+  ajfs list -l /path/to/database.ajfs
+
+  # display which files still need to be hashed
+  ajfs list --hash-status /path/to/database.ajfs
+
+  # display the entries sorted in Swedish order
+  ajfs list --locale sv_SE /path/to/database.ajfs
+
+  # display the first 20 entries
+  ajfs list --head 20 /path/to/database.ajfs
+
+  # display 100 entries starting at the 5000th entry
+  ajfs list --offset 5000 --limit 100 /path/to/database.ajfs
+
+  # display the last 20 entries
+  ajfs list --tail 20 /path/to/database.ajfs`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg := list.Config{
-			CommonConfig:     commonConfig,
-			DisplayFullPaths: listDisplayFullPaths,
-			DisplayHashes:    listDisplayHashes,
-			DisplayMinimal:   !listDisplayMore,
+			CommonConfig:      commonConfig,
+			DisplayHashes:     listDisplayHashes,
+			DisplayMinimal:    !listDisplayMore,
+			DisplayLong:       listDisplayLong,
+			DisplayHashStatus: listDisplayHashStatus,
+			Offset:            listOffset,
+			Limit:             listLimit,
+			Collator:          parseLocale(),
 		}
 		cfg.DbPath = dbPathFromArgs(args)
+		cfg.UnderConfig = parseUnderConfig(cfg.DbPath)
+		cfg.DisplayFullPaths = parseFullPaths(cfg.DbPath)
+
+		if listDisplayLong && listDisplayMore {
+			exitOnError(fmt.Errorf("--long and --more can't be used together"), 1)
+		}
+
+		for _, name := range []string{"head", "tail"} {
+			if n, _ := cmd.Flags().GetInt(name); cmd.Flags().Changed(name) && n < 1 {
+				exitOnError(fmt.Errorf("invalid value %d for --%s, expected at least 1", n, name), 1)
+			}
+		}
+
+		switch {
+		case listHead > 0 && listTail > 0:
+			exitOnError(fmt.Errorf("--head and --tail can't be used together"), 1)
+		case (listHead > 0 || listTail > 0) && listLimit > 0:
+			exitOnError(fmt.Errorf("--limit can't be used together with --head or --tail"), 1)
+		case listHead > 0:
+			cfg.Limit = listHead
+		case listTail > 0:
+			cfg.Limit = listTail
+			cfg.Tail = true
+		}
 
-		if err := list.Run(cfg); err != nil {
+		if err := list.Run(cmd.Context(), cfg); err != nil {
 			exitOnError(err, 1)
 		}
 	},
@@ -56,14 +126,27 @@ var listCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(listCmd)
+	addUnderFlag(listCmd)
+	addLocaleFlag(listCmd)
 
-	listCmd.Flags().BoolVarP(&listDisplayFullPaths, "full", "f", false, "Display full paths for entries.")
+	addFullPathsFlags(listCmd)
 	listCmd.Flags().BoolVarP(&listDisplayHashes, "hash", "s", false, "Display file signature hashes if available.")
 	listCmd.Flags().BoolVarP(&listDisplayMore, "more", "m", false, "Display more information about the paths.")
+	listCmd.Flags().BoolVarP(&listDisplayLong, "long", "l", false, "Display the entries in the same columns as ls -l.")
+	listCmd.Flags().BoolVar(&listDisplayHashStatus, "hash-status", false, "Display whether each entry is hashed, pending, failed or skipped.")
+	listCmd.Flags().IntVar(&listOffset, "offset", 0, "Number of entries to skip before displaying.")
+	listCmd.Flags().IntVar(&listLimit, "limit", 0, "Maximum number of entries to display.")
+	listCmd.Flags().IntVar(&listHead, "head", 0, "Display only the first N entries.")
+	listCmd.Flags().IntVar(&listTail, "tail", 0, "Display only the last N entries (--offset then counts from the end).")
 }
 
 var (
-	listDisplayFullPaths bool
-	listDisplayHashes    bool
-	listDisplayMore      bool
+	listDisplayHashes     bool
+	listDisplayMore       bool
+	listDisplayLong       bool
+	listDisplayHashStatus bool
+	listOffset            int
+	listLimit             int
+	listHead              int
+	listTail              int
 )
@@ -0,0 +1,47 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package commands
+
+import (
+	"github.com/andrejacobs/ajfs/internal/collate"
+	"github.com/spf13/cobra"
+)
+
+// Add the --locale flag to the command.
+func addLocaleFlag(c *cobra.Command) {
+	c.Flags().StringVar(&localeName, "locale", "", `Sort the entries in the order of a language instead of the stored order.
+  e.g. --locale sv_SE or --locale en
+  Accented letters sort next to their base letter unless the language treats them as separate letters.`)
+}
+
+// Create the collator requested with the --locale flag.
+// Returns nil when no locale was specified.
+func parseLocale() *collate.Collator {
+	c, err := collate.New(localeName)
+	if err != nil {
+		exitOnError(err, 1)
+	}
+	return c
+}
+
+var (
+	localeName string
+)
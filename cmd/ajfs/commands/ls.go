@@ -0,0 +1,98 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package commands
+
+import (
+	"github.com/andrejacobs/ajfs/internal/app/ls"
+	"github.com/andrejacobs/go-aj/file"
+	"github.com/spf13/cobra"
+)
+
+// ajfs ls.
+var lsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "Display a single directory level from the database.",
+	Long: `Display the entries of a single directory level stored inside a database in
+a similar way that "ls -l" does.
+
+The path is relative to the root path of the database. If the path is a
+directory then the direct children will be displayed. If the path is a file
+then only that entry will be displayed.
+
+The path can also be a shell pattern (e.g. * ?) in which case all the entries
+that match the pattern will be displayed. NOTE: Remember to quote the pattern
+so that your shell does not expand it.
+
+Entries are displayed in the following format:
+
+  mode size last-modification-time name`,
+	Example: `  # display the top level of the default ./db.ajfs database
+  ajfs ls
+
+  # display the top level of the specified database
+  ajfs ls /path/to/database.ajfs
+
+  # display the entries inside a directory from the default ./db.ajfs
+  ajfs ls photos/2023
+
+  # display all entries matching the shell pattern
+  ajfs ls /path/to/database.ajfs 'photos/2023/*.jpg'`,
+	Args: cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := ls.Config{
+			CommonConfig: commonConfig,
+			Collator:     parseLocale(),
+		}
+
+		switch len(args) {
+		case 0:
+			cfg.DbPath = defaultDBPath
+		case 1:
+			exists, err := file.FileExists(args[0])
+			if err != nil {
+				exitOnError(err, 1)
+			}
+
+			if exists {
+				cfg.DbPath = args[0]
+			} else {
+				cfg.DbPath = defaultDBPath
+				cfg.Pattern = args[0]
+			}
+		case 2:
+			cfg.DbPath = args[0]
+			cfg.Pattern = args[1]
+		default:
+			panic("invalid args")
+		}
+		cfg.DisplayFullPaths = parseFullPaths(cfg.DbPath)
+
+		if err := ls.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lsCmd)
+	addLocaleFlag(lsCmd)
+	addFullPathsFlags(lsCmd)
+}
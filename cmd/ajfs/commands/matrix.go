@@ -0,0 +1,64 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package commands
+
+import (
+	"github.com/andrejacobs/ajfs/internal/app/matrix"
+	"github.com/spf13/cobra"
+)
+
+// ajfs matrix.
+var matrixCmd = &cobra.Command{
+	Use:   "matrix",
+	Short: "Summarize the differences between every pair of databases.",
+	Long: `Compare every database with every other database and display an N x N matrix
+that summarizes the differences between each pair. Useful for triaging a shelf
+of archive disks at a glance before looking at the full differences using
+"ajfs diff".
+
+Each cell shows the number of paths that differ and the total size of the files
+that differ. Changed files are counted using the size in the database of the
+row.
+
+A root digest is calculated for each database from the path, type, permissions,
+size and last modification time of every entry, as well as the file signature
+hashes when present. Two databases with the same root digest are displayed as
+identical. The hashes are only taken into account when both databases have
+hashes that were calculated using the same algorithm.
+`,
+	Example: `  # compare the databases of three archive disks
+  ajfs matrix disk1.ajfs disk2.ajfs disk3.ajfs`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := matrix.Config{
+			CommonConfig: commonConfig,
+			Paths:        args,
+		}
+
+		if err := matrix.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(matrixCmd)
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/spf13/cobra"
+)
+
+// Add the --metrics flag to the command.
+func addMetricsFlag(c *cobra.Command) {
+	c.Flags().StringVar(&metricsAddress, "metrics", "", `Serve Prometheus metrics at http://<address>/metrics while running.
+  e.g. --metrics :9090 or --metrics localhost:9090`)
+}
+
+// Start serving the Prometheus metrics if requested with the --metrics flag.
+// The returned function stops serving them.
+func startMetrics() func() {
+	if metricsAddress == "" {
+		return func() {}
+	}
+
+	m := &config.Metrics{}
+	stop, err := m.Serve(metricsAddress)
+	if err != nil {
+		exitOnError(err, 1)
+	}
+
+	commonConfig.Metrics = m
+	return stop
+}
+
+var (
+	metricsAddress string
+)
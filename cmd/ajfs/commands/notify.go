@@ -0,0 +1,83 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/notify"
+	"github.com/spf13/cobra"
+)
+
+// Add the --notify-webhook and --on-complete flags to the command.
+func addNotifyFlags(c *cobra.Command) {
+	c.Flags().StringVar(&notifyWebhookURL, "notify-webhook", "", "POST a JSON summary to the URL when the command finishes, fails or is interrupted.")
+	c.Flags().StringVar(&notifyOnComplete, "on-complete", "", `Run the shell command when the command finishes, fails or is interrupted.
+  The JSON summary is passed as standard input and the environment variables
+  AJFS_COMMAND, AJFS_DATABASE, AJFS_STATUS and AJFS_ERROR are set.`)
+}
+
+// Start recording the information needed for the --notify-webhook and --on-complete flags.
+// The returned function sends the notifications and must be called with the result of the command.
+func startNotify(cmd *cobra.Command) func(dbPath string, err error) {
+	if notifyWebhookURL == "" && notifyOnComplete == "" {
+		return func(string, error) {}
+	}
+
+	// Used to include the number of entries scanned and files hashed in the summary
+	if commonConfig.Metrics == nil {
+		commonConfig.Metrics = &config.Metrics{}
+	}
+	metrics := commonConfig.Metrics
+	started := time.Now()
+
+	return func(dbPath string, err error) {
+		s := notify.NewSummary(cmd.Context(), cmd.Name(), dbPath, started, err)
+		totals := metrics.Totals()
+		s.EntriesScanned = totals.EntriesScanned
+		s.FilesHashed = totals.FilesHashed
+		s.BytesHashed = totals.BytesHashed
+		s.Errors = totals.Errors
+
+		// The command's context is cancelled when interrupted and the notifications still need to be sent
+		ctx := context.Background()
+
+		if notifyWebhookURL != "" {
+			if err := notify.Webhook(ctx, notifyWebhookURL, s); err != nil {
+				fmt.Fprintf(os.Stderr, "WARNING: %v\n", err)
+			}
+		}
+
+		if notifyOnComplete != "" {
+			if err := notify.Command(ctx, notifyOnComplete, s, os.Stdout, os.Stderr); err != nil {
+				fmt.Fprintf(os.Stderr, "WARNING: %v\n", err)
+			}
+		}
+	}
+}
+
+var (
+	notifyWebhookURL string
+	notifyOnComplete string
+)
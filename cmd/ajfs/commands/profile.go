@@ -0,0 +1,79 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+var cpuProfileFile *os.File
+
+// Start the CPU profiling if requested with the --profile-cpu flag.
+func startProfiling() error {
+	if profileCPUPath == "" {
+		return nil
+	}
+
+	f, err := os.Create(profileCPUPath)
+	if err != nil {
+		return fmt.Errorf("failed to create the CPU profile file %q. %w", profileCPUPath, err)
+	}
+
+	if err := pprof.StartCPUProfile(f); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to start CPU profiling. %w", err)
+	}
+
+	cpuProfileFile = f
+	return nil
+}
+
+// Stop the CPU profiling and write the heap profile if requested with the --profile-mem flag.
+// Safe to be called more than once.
+func stopProfiling() {
+	if cpuProfileFile != nil {
+		pprof.StopCPUProfile()
+		if err := cpuProfileFile.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: failed to write the CPU profile %q. %v\n", profileCPUPath, err)
+		}
+		cpuProfileFile = nil
+	}
+
+	if profileMemPath != "" {
+		path := profileMemPath
+		profileMemPath = ""
+
+		f, err := os.Create(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: failed to create the memory profile file %q. %v\n", path, err)
+			return
+		}
+		defer f.Close()
+
+		// Get up-to-date statistics
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: failed to write the memory profile %q. %v\n", path, err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"fmt"
+
+	"github.com/andrejacobs/ajfs/internal/app/prune"
+	"github.com/spf13/cobra"
+)
+
+// ajfs prune.
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove entries matching a search expression from the database.",
+	Long: `Remove the entries that match the search criteria (see "ajfs search --help")
+from the database along with their file signature hashes.
+
+When a directory matches then everything below it will also be removed. The
+database is rewritten to a temporary file first which then replaces the
+original.
+
+Use "--dry-run" to only display the entries that would be removed.
+
+A sealed database (see "ajfs seal") will not be modified unless "--force" is
+used.
+
+NOTE: Only the database is modified, no files on disk will be deleted.`,
+	Example: `  # remove all files with "cache" in the path
+  ajfs prune --path '*cache*' --type f /path/to/database.ajfs
+
+  # remove all node_modules directories and their contents
+  ajfs prune --name node_modules --type d /path/to/database.ajfs
+
+  # display what would be removed under the photos directory
+  ajfs prune --dry-run --under photos --name '*.tmp' /path/to/database.ajfs`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := prune.Config{
+			CommonConfig: commonConfig,
+			DryRun:       pruneDryRun,
+			Force:        pruneForce,
+		}
+		cfg.DbPath = dbPathFromArgs(args)
+		cfg.UnderConfig = parseUnderConfig(cfg.DbPath)
+
+		exp, _, err := parseSearchExpression()
+		if err != nil {
+			exitOnError(err, 1)
+		}
+		if exp == nil {
+			exitOnError(fmt.Errorf("expected at least one search flag to select the entries to be removed"), 1)
+		}
+		cfg.Expression = exp
+
+		if err := prune.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+	addUnderFlag(pruneCmd)
+
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "Only display the entries that would be removed.")
+	pruneCmd.Flags().BoolVar(&pruneForce, "force", false, "Remove the entries even if the database has been sealed.")
+
+	addSearchFlags(pruneCmd)
+}
+
+var (
+	pruneDryRun bool
+	pruneForce  bool
+)
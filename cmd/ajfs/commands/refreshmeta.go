@@ -0,0 +1,81 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"github.com/andrejacobs/ajfs/internal/app/refreshmeta"
+	"github.com/spf13/cobra"
+)
+
+// ajfs refresh-meta.
+var refreshMetaCmd = &cobra.Command{
+	Use:   "refresh-meta",
+	Short: "Update the size, mode and modification time of the entries without re-hashing.",
+	Long: `Update the size, mode (type and permissions) and last modification time of
+each entry from the file system without calculating any file signature hashes.
+
+This is a cheap way to fold routine modification time and permission changes
+into the snapshot between full verifications. The file signature hash of a
+file is kept unless its size has changed, in which case the hash is removed
+and needs to be calculated again using "ajfs resume".
+
+Paths that no longer exist are left unchanged and new paths are not added,
+use "ajfs update" for that. The database is rewritten to a temporary file
+first which then replaces the original. The database is left untouched when
+nothing has changed.
+
+Use "--dry-run" to only display the entries that would be refreshed.
+
+A sealed database (see "ajfs seal") will not be modified unless "--force" is
+used.`,
+	Example: `  # refresh the entries of the default ./db.ajfs database
+  ajfs refresh-meta
+
+  # display which entries would be refreshed
+  ajfs refresh-meta --dry-run /path/to/database.ajfs
+
+  # refresh the entries and calculate the hashes of the files that changed in size
+  ajfs refresh-meta /path/to/database.ajfs && ajfs resume /path/to/database.ajfs`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := refreshmeta.Config{
+			CommonConfig: commonConfig,
+			DryRun:       refreshMetaDryRun,
+			Force:        refreshMetaForce,
+		}
+		cfg.DbPath = dbPathFromArgs(args)
+
+		if err := refreshmeta.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(refreshMetaCmd)
+
+	refreshMetaCmd.Flags().BoolVar(&refreshMetaDryRun, "dry-run", false, "Only display the entries that would be refreshed.")
+	refreshMetaCmd.Flags().BoolVar(&refreshMetaForce, "force", false, "Refresh the entries even if the database has been sealed.")
+}
+
+var (
+	refreshMetaDryRun bool
+	refreshMetaForce  bool
+)
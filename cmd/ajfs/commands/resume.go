@@ -31,22 +31,77 @@ var resumeCmd = &cobra.Command{
 	Short: "Resume calculating file signature hashes.",
 	Long: `Resume calculating file signature hashes for a previously interrupted scan.
 
+Files for which the hash could not be calculated are recorded in an error log
+next to the database (e.g. db.ajfs.errors.jsonl) along with the error, time and
+number of attempts. These files are skipped when resuming, unless
+"--retry-errors" is used.
+
+Hashes are reused from the hash cache for files that have not changed (see
+"ajfs cache"). Use "--no-cache" to not use the cache.
+
+Before resuming, a random sample of the files that still need to be hashed is
+checked to see if they still exist below the root path. Resuming is aborted
+when most of them are missing, since the root was likely remounted elsewhere
+(see "ajfs set-root"). Use "--skip-root-check" to resume anyway.
+
+A sealed database (see "ajfs seal") will not be modified unless "--force" is
+used.
+
+When the database header does not match the contents of the file (e.g. the
+database was not closed cleanly), "--auto-fix" first fixes the database the
+same way as "ajfs fix" and then resumes. The original headers are backed up to
+a ".header.bak" file next to the database and can be restored using
+"ajfs fix --restore".
+
+When a scan or resume crashed or was killed, the checkpoint file it left next
+to the database (e.g. db.ajfs.checkpoint.json) is reported. If it stopped
+while hashing, the hashes written after the checkpoint are calculated again
+and replaced when they don't match, since they might not have been fully
+written. Use "--checkpoint-interval" to change how often the checkpoint is
+written or 0 to disable it.
+
 NOTE: The database must have been created using the "--hash" option.`,
 	Example: `  # resume using the default ./db.ajfs database
   ajfs resume
 
   # resume the specific database and display a progress bar
-  ajfs resume --progress /path/to/database.ajfs`,
+  ajfs resume --progress /path/to/database.ajfs
+
+  # resume and also retry the files that failed before
+  ajfs resume --retry-errors /path/to/database.ajfs
+
+  # fix the database if needed and resume in one step
+  ajfs resume --auto-fix /path/to/database.ajfs`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		syncPolicy, err := syncPolicyFromFlag(hashSyncPolicy)
+		if err != nil {
+			exitOnError(err, 1)
+		}
+
 		commonConfig.Progress = showProgress
+		stopMetrics := startMetrics()
+		defer stopMetrics()
+		notifyCompletion := startNotify(cmd)
 
 		cfg := resume.Config{
-			CommonConfig: commonConfig,
+			CommonConfig:  commonConfig,
+			RetryErrors:   resumeRetryErrors,
+			Force:         resumeForce,
+			HashCachePath: hashCachePath(resumeNoCache),
+			SkipRootCheck: resumeSkipRootCheck,
+			SortHashes:    resumeSortHashes,
+			AutoFix:       resumeAutoFix,
+			FlushInterval: hashFlushInterval,
+			SyncPolicy:    syncPolicy,
+
+			CheckpointInterval: checkpointInterval,
 		}
 		cfg.DbPath = dbPathFromArgs(args)
 
-		if err := resume.Run(cfg); err != nil {
+		err = resume.Run(cmd.Context(), cfg)
+		notifyCompletion(cfg.DbPath, err)
+		if err != nil {
 			exitOnError(err, 1)
 		}
 	},
@@ -54,6 +109,25 @@ NOTE: The database must have been created using the "--hash" option.`,
 
 func init() {
 	rootCmd.AddCommand(resumeCmd)
+	addMetricsFlag(resumeCmd)
+	addNotifyFlags(resumeCmd)
+	addHashWriteFlags(resumeCmd)
+	addCheckpointFlag(resumeCmd)
 
 	resumeCmd.Flags().BoolVarP(&showProgress, "progress", "p", false, "Display progress information.")
+	resumeCmd.Flags().BoolVar(&resumeRetryErrors, "retry-errors", false, "Also retry the files recorded in the error log.")
+	resumeCmd.Flags().BoolVar(&resumeNoCache, "no-cache", false, "Do not reuse or store file signature hashes using the hash cache.")
+	resumeCmd.Flags().BoolVar(&resumeForce, "force", false, "Resume even if the database has been sealed.")
+	resumeCmd.Flags().BoolVar(&resumeSortHashes, "sort-hashes", false, "Store the hash table sorted by hash (faster duplicate and hash lookups).")
+	resumeCmd.Flags().BoolVar(&resumeAutoFix, "auto-fix", false, "Fix the database (after backing up the headers) when the header does not match the contents.")
+	resumeCmd.Flags().BoolVar(&resumeSkipRootCheck, "skip-root-check", false, "Resume without checking if the files still exist below the root path.")
 }
+
+var (
+	resumeRetryErrors   bool
+	resumeForce         bool
+	resumeNoCache       bool
+	resumeSkipRootCheck bool
+	resumeSortHashes    bool
+	resumeAutoFix       bool
+)
@@ -22,11 +22,17 @@
 package commands
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/style"
 	"github.com/andrejacobs/go-aj/buildinfo"
 	"github.com/andrejacobs/go-aj/stats"
 	"github.com/spf13/cobra"
@@ -52,7 +58,11 @@ Which can then be used in an offline and independent way to do the following:
 
 // Main entry point for ajfs CLI.
 func Execute() {
-	err := rootCmd.Execute()
+	// Cancelled on Ctrl+C (SIGINT) or SIGTERM so that long running commands can stop promptly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := rootCmd.ExecuteContext(ctx)
 	if err != nil {
 		fmt.Fprintf(os.Stdout, "%v\n", err)
 		os.Exit(1)
@@ -69,6 +79,19 @@ func init() {
 
 	// Persistent flags that are available to every subcommand
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Display verbose information.")
+	// Named --perf-stats because "ajfs diff" already uses --stats
+	rootCmd.PersistentFlags().BoolVar(&showPerfStats, "perf-stats", false, "Display the time taken by each phase and the peak memory usage.")
+	rootCmd.PersistentFlags().BoolVar(&displayUTC, "utc", false, "Display and export times in UTC instead of the time zone in which they were recorded.")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Do not use colors and aligned columns, even when the output is a terminal.")
+	rootCmd.PersistentFlags().StringVar(&ioBufferExpr, "io-buffer", "",
+		"Size of the read and write buffers used for database files. E.g. 4M or 1MiB. Larger buffers help on spinning disks and network storage.")
+	rootCmd.PersistentFlags().BoolVar(&indexCache, "index-cache", false,
+		"Keep the derived indexes of a database in a sidecar file (e.g. db.ajfs.idx) so that they are only built once per snapshot.")
+
+	rootCmd.PersistentFlags().StringVar(&profileCPUPath, "profile-cpu", "", "Write a pprof CPU profile to the file.")
+	rootCmd.PersistentFlags().StringVar(&profileMemPath, "profile-mem", "", "Write a pprof heap profile to the file.")
+	_ = rootCmd.PersistentFlags().MarkHidden("profile-cpu")
+	_ = rootCmd.PersistentFlags().MarkHidden("profile-mem")
 
 	customHelp()
 }
@@ -77,23 +100,51 @@ func init() {
 func initApp() {
 	commonConfig.Init()
 	commonConfig.Verbose = verbose
+	commonConfig.UTC = displayUTC
+	commonConfig.Styled = !noColor && style.Supported(os.Stdout)
+	startTime = time.Now()
 
-	if commonConfig.Verbose {
-		startTime = time.Now()
+	if showPerfStats {
+		commonConfig.Stats = &config.Stats{}
+	}
+
+	ioBufferSize, err := parseSizeLimit("io-buffer", ioBufferExpr)
+	if err != nil {
+		exitOnError(err, 1)
+	}
+	db.SetIOBufferSize(int(min(ioBufferSize, maxIOBufferSize)))
+	db.SetIndexCacheEnabled(indexCache)
+
+	if err := startProfiling(); err != nil {
+		exitOnError(err, 1)
 	}
 }
 
 // Run after a command is finished.
 func cleanupApplication() {
+	stopProfiling()
+
 	if commonConfig.Verbose {
 		commonConfig.VerbosePrintln("")
 		stats.PrintTimeTaken(commonConfig.Stdout, "ajfs", startTime, time.Now())
 	}
+
+	if commonConfig.Stats != nil {
+		commonConfig.Stats.Print(os.Stderr, time.Since(startTime))
+	}
 }
 
 // Log error message to STDERR and exit the program with the specified exit code.
 func exitOnError(err error, code int) {
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, "Interrupted.")
+		stopProfiling()
+		os.Exit(exitInterrupted)
+	}
+
 	fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+	// Profiles are still useful when investigating a failure
+	stopProfiling()
 	os.Exit(code)
 }
 
@@ -119,15 +170,15 @@ func customHelp() {
 	}{
 		{
 			Title:    "Creation commands",
-			Commands: []string{"scan", "resume", "update", "fix"},
+			Commands: []string{"scan", "scan-image", "test-filter", "resume", "add-hash", "update", "refresh-meta", "fix", "convert", "split", "set-root", "set-paths", "prune", "seal", "sign", "catalog", "cache"},
 		},
 		{
 			Title:    "Information commands",
-			Commands: []string{"info", "check", "list", "export", "tree", "search"},
+			Commands: []string{"info", "doctor", "check", "verify-signature", "list", "ls", "export", "tree", "search", "grep", "sample", "top", "shell", "daemon"},
 		},
 		{
 			Title:    "Comparison commands",
-			Commands: []string{"diff", "tosync", "dupes"},
+			Commands: []string{"diff", "annotate", "matrix", "growth", "tosync", "dupes", "cleanup", "compare-hashdeep", "cross-verify", "undo"},
 		},
 	}
 
@@ -145,15 +196,17 @@ func customHelp() {
 		fmt.Println("Available commands:")
 		cmds := cmd.Commands()
 		cmdMap := make(map[string]*cobra.Command)
+		width := 12
 		for _, c := range cmds {
 			cmdMap[c.Name()] = c
+			width = max(width, len(c.Name()))
 		}
 
 		for _, group := range groups {
 			fmt.Printf("  %s:\n", group.Title)
 			for _, name := range group.Commands {
 				if c, ok := cmdMap[name]; ok {
-					fmt.Printf("    %-12s %s\n", c.Name(), c.Short)
+					fmt.Printf("    %-*s %s\n", width, c.Name(), c.Short)
 				}
 			}
 			fmt.Println()
@@ -165,11 +218,23 @@ func customHelp() {
 
 const (
 	defaultDBPath = "./db.ajfs"
+
+	exitInterrupted = 130 // 128 + SIGINT
+
+	maxIOBufferSize = 1024 * 1024 * 1024 // --io-buffer is capped at 1 GiB
 )
 
 var (
-	verbose      bool
-	showProgress bool
+	verbose       bool
+	showProgress  bool
+	showPerfStats bool
+	ioBufferExpr  string
+	displayUTC    bool
+	indexCache    bool
+	noColor       bool
+
+	profileCPUPath string
+	profileMemPath string
 
 	commonConfig config.CommonConfig
 
@@ -0,0 +1,96 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package commands
+
+import (
+	"github.com/andrejacobs/ajfs/internal/app/sample"
+	"github.com/spf13/cobra"
+)
+
+// ajfs sample.
+var sampleCmd = &cobra.Command{
+	Use:   "sample",
+	Short: "Display a random selection of entries.",
+	Long: `Display a uniformly random selection of the path entries stored inside a
+database. Useful for spot-checking a snapshot without going through all of the
+entries.
+
+The selection can be restricted to only files ("--files"), only files for which
+a file signature hash has been calculated ("--hashed") or to the entries that
+match the search criteria (see "ajfs search --help").
+
+The selected entries are displayed in the order stored in the database. Use
+"--seed" to get the same selection again.
+
+The number of entries is set with "-n" (or "--count"), which is why the search
+criteria "--name" has no shorthand for this command.`,
+	Example: `  # display 100 random entries from the default ./db.ajfs database
+  ajfs sample -n 100
+
+  # display 20 random files that have been hashed
+  ajfs sample -n 20 --hashed /path/to/database.ajfs
+
+  # display 10 random PDF files below the documents directory
+  ajfs sample -n 10 --under documents --iname '*.pdf' /path/to/database.ajfs`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := sample.Config{
+			CommonConfig: commonConfig,
+			Count:        sampleCount,
+			OnlyFiles:    sampleOnlyFiles,
+			OnlyHashed:   sampleOnlyHashed,
+			Seed:         sampleSeed,
+		}
+		cfg.DbPath = dbPathFromArgs(args)
+		cfg.UnderConfig = parseUnderConfig(cfg.DbPath)
+		cfg.DisplayFullPaths = parseFullPaths(cfg.DbPath)
+
+		exp, _, err := parseSearchExpression()
+		if err != nil {
+			exitOnError(err, 1)
+		}
+		cfg.Expression = exp
+
+		if err := sample.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sampleCmd)
+	addUnderFlag(sampleCmd)
+
+	sampleCmd.Flags().IntVarP(&sampleCount, "count", "n", 10, "Number of entries to select.")
+	sampleCmd.Flags().BoolVar(&sampleOnlyFiles, "files", false, "Only select regular files.")
+	sampleCmd.Flags().BoolVar(&sampleOnlyHashed, "hashed", false, "Only select files for which a file signature hash has been calculated.")
+	sampleCmd.Flags().Uint64Var(&sampleSeed, "seed", 0, "Seed used for the random selection. 0 means a different selection every time.")
+	addFullPathsFlags(sampleCmd)
+
+	addSearchFlags(sampleCmd)
+}
+
+var (
+	sampleCount      int
+	sampleOnlyFiles  bool
+	sampleOnlyHashed bool
+	sampleSeed       uint64
+)
@@ -23,8 +23,11 @@ package commands
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/checkpoint"
+	"github.com/andrejacobs/ajfs/internal/db"
 	"github.com/andrejacobs/go-aj/ajhash"
 	"github.com/spf13/cobra"
 )
@@ -45,16 +48,87 @@ the database. This can be very valuable for later finding duplicates or
 differences. Calculating the file signature hashes can be a long running
 process depending on the number of files and sizes.
 
+Calculated hashes are also stored in a local cache that is shared by all
+databases and hashes are reused from the cache for files that have not changed
+(see "ajfs cache"). Use "--no-cache" to not use the cache.
+
+Use "--exclude-from-db" to take a new snapshot of a file hierarchy that was
+scanned before. Files with the same path, size and last modification time as
+in the older snapshot are not hashed again and their hashes are copied from it
+instead. The result is still a full snapshot. The older snapshot must have been
+hashed using the same algorithm.
+
+Files for which the hash could not be calculated are recorded in an error log
+next to the database (e.g. db.ajfs.errors.jsonl).
+
+By default the scan is aborted when a directory or file can't be read because
+of a permission error. Use "--skip-unreadable" to continue scanning instead.
+The unreadable paths are still recorded in the database, added to the error
+log and listed in a summary once the scan has finished.
+
+Use "--require-complete" to guarantee that a snapshot is not missing anything,
+e.g. when scanning as a user that might not have access to all the paths. Each
+file is also checked to be readable and the scan fails, without creating the
+database, when any directory or file could not be read. The paths are still
+listed in the summary and recorded in the error log.
+
 The file signature hash calculation process can be safely interrupted using
 Ctrl+C (SIGTERM) and be resumed at another time using "ajfs resume".
 However it is not safe to interrupt the initial database creation, 
 use "--verbose" or "--progress" to know when the calculation process has
 started.
 
+While scanning and hashing, the last path that was processed is recorded in a
+checkpoint file next to the database (e.g. db.ajfs.checkpoint.json) every 30
+seconds. The file is removed when the scan exits cleanly, so a checkpoint file
+that is left behind shows where a crashed or killed scan was when it stopped.
+Use "--checkpoint-interval" to change how often it is written or 0 to disable
+it.
+
+By default all the entries are first written to the database and then each
+file is visited again to calculate the hashes. Use "--single-pass" to
+calculate the hashes as the files are discovered instead, which can be a lot
+faster on slow network file systems. The calculated hashes are kept in memory
+until the walk has finished and interrupting a single pass scan means the
+database file will be deleted.
+
 Supported file signature hash algorithms are: sha1, sha256 and sha512.
 You can determine the fastest algorithm to use by running this command:
   openssl speed sha1 sha256 sha512
 
+The legacy md5 algorithm is also supported, but only to be able to verify and
+compare against old md5 based manifests (e.g. using "ajfs compare-hashdeep").
+
+Use "--exec" to run a shell command for each file as it is discovered, which
+avoids having to walk the file hierarchy again for external processing. Each
+"{}" in the command is replaced by the quoted full path of the file, which is
+also available in the AJFS_PATH environment variable. Files inside archives are
+skipped and a failing command does not abort the scan.
+
+The integrity of the database file is protected by a CRC-32 checksum. Use
+"--checksum=sha256" to also store a SHA-256 checksum, which is recommended for
+very large databases.
+
+Use "--sorted" to store the entries in lexicographic path order instead of the
+order in which they were found while walking. The walk order can differ
+between platforms and runs, while sorted entries make the databases of two
+identical file hierarchies comparable byte for byte (apart from the creation
+meta data such as the time and tool version).
+
+Each entry is identified by a hash of its path relative to the root, which means
+that the databases of two file hierarchies with the same layout share the same
+identifiers. This is what "ajfs diff" relies on to pair up the entries. Use
+"--id-mode=root" (host name and root path) or "--id-mode=uuid" (random) to also
+include a namespace in the identifiers, which makes them unique across
+databases that will be merged or catalogued together. The namespace is kept by
+"ajfs update" and databases with different namespaces are compared by relative
+path.
+
+Use "--dir-stats" to also store the number of children, the number of files
+and the combined size of the files below each directory. This allows commands
+like "ajfs tree --sizes" to display directory sizes without having to walk all
+the entries.
+
 Path filtering:
 
 Used to check whether a file or directory should be included or if it should
@@ -74,7 +148,28 @@ any directories that end with temp, you could use this on the CLI
 If the prefix (f: or d:) is not specified then the regular expression will be
 applied to both files and directories.
 
-See https://pkg.go.dev/regexp/syntax for the syntax.`,
+See https://pkg.go.dev/regexp/syntax for the syntax.
+
+Size and age filtering:
+
+Files can also be excluded based on their size or last modification time so
+that they never enter the database.
+
+  "--min-size {size}"     Exclude files smaller than size.
+  "--max-size {size}"     Exclude files larger than size.
+  "--newer-than {date}"   Only include files modified after date.
+  "--older-than {date}"   Only include files modified before date.
+
+Size can use the suffixes k, M, G, T and P (e.g. 100M is 100 * 1000 * 1000
+bytes). Date can be specified as YYYY-MM-DD, "YYYY-MM-DD HH:mm:ss" or relative
+to now using the suffixes s, m, h, D, M and Y (e.g. 30D means 30 days ago).
+
+Special files:
+
+By default symlinks, named pipes (FIFOs), sockets and device files are
+recorded in the database. Use "--special ignore" to skip them, which is useful
+for /dev like trees or directories containing application sockets that come
+and go and would otherwise show up as differences later on.`,
 	Example: `  # create the default ./db.ajfs database from the specified path
   ajfs scan /path/to/be/scanned
 
@@ -84,6 +179,18 @@ See https://pkg.go.dev/regexp/syntax for the syntax.`,
   # see which paths will be included without creating the database
   ajfs scan --dry-run -i "f:\.pdf$" /path/to/be/scanned
 
+  # skip files larger than 2GB or not modified in the last year
+  ajfs scan --max-size 2G --newer-than 1Y /path/to/be/scanned
+
+  # do not record symlinks, sockets, FIFOs and device files
+  ajfs scan --special ignore /path/to/be/scanned
+
+  # scan a system disk without aborting on directories that can't be read
+  ajfs scan --skip-unreadable /path/to/database.ajfs /
+
+  # fail instead of creating a snapshot that is missing paths that could not be read
+  ajfs scan --require-complete /path/to/database.ajfs /path/to/be/scanned
+
   # override the existing database if it exists
   ajfs scan --force /path/to/database.ajfs /path/to/be/scanned
 
@@ -93,11 +200,44 @@ See https://pkg.go.dev/regexp/syntax for the syntax.`,
   # create a new database and calculate the file signature hashes using SHA-1 while showing a progress bar
   ajfs scan --hash --algo=sha1 --progress /path/to/database.ajfs /path/to/be/scanned
 
+  # take a new snapshot and only hash the files that changed since the previous one
+  ajfs scan --hash --exclude-from-db previous.ajfs /path/to/database.ajfs /path/to/be/scanned
+
+  # calculate the file signature hashes while walking a network share
+  ajfs scan --hash --single-pass /path/to/database.ajfs /Volumes/share
+
+  # record when each file signature hash was calculated (shown by ajfs cross-verify)
+  ajfs scan --hash --hash-times /path/to/database.ajfs /path/to/be/scanned
+
+  # create a new database that uses a SHA-256 integrity checksum
+  ajfs scan --checksum=sha256 /path/to/database.ajfs /path/to/be/scanned
+
+  # create a new database with identifiers that are unique across databases
+  ajfs scan --id-mode=uuid /path/to/database.ajfs /path/to/be/scanned
+
+  # create a new database that also stores the directory statistics
+  ajfs scan --dir-stats /path/to/database.ajfs /path/to/be/scanned
+
   # create a new database and only include PDF and EPUB files
   ajfs scan -i "f:\.pdf$" -i "f:\.epub$" /path/to/be/scanned
 
   # create a new database and exclude all directories that contain the word "temp"
-  ajfs scan -e "d:temp" /path/to/be/scanned`,
+  ajfs scan -e "d:temp" /path/to/be/scanned
+
+  # monitor a long running scan using Prometheus
+  ajfs scan --hash --metrics :9090 /path/to/database.ajfs /path/to/be/scanned
+
+  # include the files stored inside tar and zip archives
+  ajfs scan --hash --archives /path/to/database.ajfs /path/to/be/scanned
+
+  # create thumbnails of the photos while the snapshot is taken
+  ajfs scan --exec 'thumbnail --out ~/thumbs {}' -i "f:\.jpg$" /path/to/database.ajfs /path/to/photos
+
+  # record the progress in the checkpoint file every 5 seconds
+  ajfs scan --hash --checkpoint-interval 5s /path/to/database.ajfs /path/to/be/scanned
+
+  # send an email once an unattended scan has finished or failed
+  ajfs scan --hash --on-complete 'mail -s "ajfs $AJFS_STATUS" me@example.com' /path/to/be/scanned`,
 	Args: cobra.RangeArgs(1, 2),
 	Run: func(cmd *cobra.Command, args []string) {
 		filterCfg, err := parseFilterConfig()
@@ -106,12 +246,36 @@ See https://pkg.go.dev/regexp/syntax for the syntax.`,
 		}
 
 		commonConfig.Progress = showProgress
+		stopMetrics := startMetrics()
+		defer stopMetrics()
+		notifyCompletion := startNotify(cmd)
 
 		cfg := scan.Config{
 			CommonConfig:  commonConfig,
 			FilterConfig:  *filterCfg,
 			ForceOverride: scanForceOverride,
 			DryRun:        scanDryRun,
+			DirStats:      scanDirStats,
+			FlushInterval: hashFlushInterval,
+
+			CheckpointInterval: checkpointInterval,
+
+			SkipUnreadable: scanSkipUnreadable,
+			Sorted:         scanSorted,
+			Archives:       scanArchives,
+
+			RequireComplete: scanRequireComplete,
+		}
+
+		checksumAlgo, err := checksumAlgoFromFlag(scanChecksumAlgo)
+		if err != nil {
+			exitOnError(err, 1)
+		}
+		cfg.ChecksumAlgo = checksumAlgo
+
+		cfg.SyncPolicy, err = syncPolicyFromFlag(hashSyncPolicy)
+		if err != nil {
+			exitOnError(err, 1)
 		}
 
 		switch len(args) {
@@ -125,6 +289,26 @@ See https://pkg.go.dev/regexp/syntax for the syntax.`,
 			panic("invalid args")
 		}
 
+		idMode, err := idModeFromFlag(scanIdMode)
+		if err != nil {
+			exitOnError(err, 1)
+		}
+		cfg.IdNamespace, err = db.NewIdNamespace(idMode, cfg.Root)
+		if err != nil {
+			exitOnError(err, 1)
+		}
+
+		if scanExec != "" {
+			if scanDryRun {
+				exitOnError(fmt.Errorf("--exec can not be used with --dry-run"), 1)
+			}
+			cfg.Hooks = &scan.ExecHook{
+				Command: scanExec,
+				Stdout:  commonConfig.Stdout,
+				Stderr:  commonConfig.Stderr,
+			}
+		}
+
 		if scanCalculateHashes {
 			algo, err := algoFromFlag(scanHashAlgo)
 			if err != nil {
@@ -133,9 +317,24 @@ See https://pkg.go.dev/regexp/syntax for the syntax.`,
 
 			cfg.CalculateHashes = true
 			cfg.Algo = algo
+			cfg.HashCachePath = hashCachePath(scanNoCache)
+			cfg.SinglePass = scanSinglePass
+			cfg.SortHashes = scanSortHashes
+			cfg.HashTimes = scanHashTimes
+			cfg.PreviousDbPath = scanExcludeFromDb
+		} else if scanExcludeFromDb != "" {
+			exitOnError(fmt.Errorf("--exclude-from-db can only be used with --hash"), 1)
+		} else if scanSinglePass {
+			exitOnError(fmt.Errorf("--single-pass can only be used with --hash"), 1)
+		} else if scanSortHashes {
+			exitOnError(fmt.Errorf("--sort-hashes can only be used with --hash"), 1)
+		} else if scanHashTimes {
+			exitOnError(fmt.Errorf("--hash-times can only be used with --hash"), 1)
 		}
 
-		if err := scan.Run(cfg); err != nil {
+		err = scan.Run(cmd.Context(), cfg)
+		notifyCompletion(cfg.DbPath, err)
+		if err != nil {
 			exitOnError(err, 1)
 		}
 	},
@@ -143,11 +342,29 @@ See https://pkg.go.dev/regexp/syntax for the syntax.`,
 
 func init() {
 	rootCmd.AddCommand(scanCmd)
+	addMetricsFlag(scanCmd)
+	addNotifyFlags(scanCmd)
+	addHashWriteFlags(scanCmd)
+	addCheckpointFlag(scanCmd)
 
 	scanCmd.Flags().BoolVar(&scanForceOverride, "force", false, "Override any existing database.")
 	scanCmd.Flags().BoolVarP(&scanCalculateHashes, "hash", "s", false, "Calculate file signature hashes.")
 	scanCmd.Flags().BoolVar(&scanDryRun, "dry-run", false, "Only display files and directories that would be stored in the database.")
-	scanCmd.Flags().StringVarP(&scanHashAlgo, "algo", "a", "sha256", "Hashing algorithm to use. Valid values are 'sha1', 'sha256' and 'sha512'.")
+	scanCmd.Flags().StringVarP(&scanHashAlgo, "algo", "a", "sha256", "Hashing algorithm to use. Valid values are 'sha1', 'sha256', 'sha512' and 'md5' (legacy).")
+	scanCmd.Flags().BoolVar(&scanSinglePass, "single-pass", false, "Calculate the file signature hashes while walking the file hierarchy.")
+	scanCmd.Flags().BoolVar(&scanNoCache, "no-cache", false, "Do not reuse or store file signature hashes using the hash cache.")
+	scanCmd.Flags().StringVar(&scanExcludeFromDb, "exclude-from-db", "", "Copy the hashes of unchanged files (same path, size and modification time) from an older snapshot instead of hashing them.")
+	scanCmd.Flags().StringVar(&scanChecksumAlgo, "checksum", "crc32", "Database integrity checksum algorithm. Valid values are 'crc32' and 'sha256'.")
+	scanCmd.Flags().StringVar(&scanIdMode, "id-mode", "path", `How the entry identifiers are derived.
+  'path' from the relative path only, 'root' also from the host name and root path or 'uuid' also from a random UUID.`)
+	scanCmd.Flags().BoolVar(&scanDirStats, "dir-stats", false, "Store the child counts and cumulative sizes for each directory.")
+	scanCmd.Flags().BoolVar(&scanSkipUnreadable, "skip-unreadable", false, "Record directories and files that can't be read due to permissions and continue scanning.")
+	scanCmd.Flags().BoolVar(&scanRequireComplete, "require-complete", false, "Fail the scan when any directory or file could not be read.")
+	scanCmd.Flags().BoolVar(&scanSorted, "sorted", false, "Store the entries sorted by path instead of the order in which they were found.")
+	scanCmd.Flags().BoolVar(&scanArchives, "archives", false, "Record the members of .tar, .tar.gz and .zip archives and .iso images as virtual entries (e.g. backup.tar!/dir/file).")
+	scanCmd.Flags().BoolVar(&scanSortHashes, "sort-hashes", false, "Store the hash table sorted by hash (faster duplicate and hash lookups).")
+	scanCmd.Flags().BoolVar(&scanHashTimes, "hash-times", false, "Record the time at which each file signature hash was calculated.")
+	scanCmd.Flags().StringVar(&scanExec, "exec", "", "Shell command to run for each file that is found. {} is replaced by the full path of the file.")
 	scanCmd.Flags().BoolVarP(&showProgress, "progress", "p", false, "Display progress information.")
 
 	addPathFilteringFlags(scanCmd)
@@ -157,9 +374,40 @@ var (
 	scanForceOverride   bool
 	scanCalculateHashes bool
 	scanHashAlgo        string
+	scanChecksumAlgo    string
+	scanIdMode          string
 	scanDryRun          bool
+	scanDirStats        bool
+	scanNoCache         bool
+	scanSkipUnreadable  bool
+	scanSinglePass      bool
+	scanSorted          bool
+	scanArchives        bool
+	scanSortHashes      bool
+	scanHashTimes       bool
+	scanExec            string
+	scanExcludeFromDb   string
+	scanRequireComplete bool
+
+	hashFlushInterval  time.Duration
+	hashSyncPolicy     string
+	checkpointInterval time.Duration
 )
 
+// Add the flags that control how calculated hashes are written to the database.
+func addHashWriteFlags(c *cobra.Command) {
+	c.Flags().DurationVar(&hashFlushInterval, "flush-interval", db.DefaultHashFlushInterval,
+		"Interval at which calculated hashes are written to the database in batches. 0 writes each hash immediately.")
+	c.Flags().StringVar(&hashSyncPolicy, "fsync", "periodic", `How often the database is synced to disk while writing hashes.
+  'always' after every write, 'periodic' every 1024 hashes or 'close' only when finished.`)
+}
+
+// Add the flag that controls how often the checkpoint file is written.
+func addCheckpointFlag(c *cobra.Command) {
+	c.Flags().DurationVar(&checkpointInterval, "checkpoint-interval", checkpoint.DefaultInterval,
+		"Interval at which the progress is recorded in a checkpoint file next to the database. 0 disables it.")
+}
+
 // Determine the hashing algorithm to use based on the flag that was passed.
 func algoFromFlag(flag string) (ajhash.Algo, error) {
 	switch strings.ToLower(flag) {
@@ -169,7 +417,49 @@ func algoFromFlag(flag string) (ajhash.Algo, error) {
 		return ajhash.AlgoSHA256, nil
 	case "sha512":
 		return ajhash.AlgoSHA512, nil
+	case "md5":
+		return db.AlgoMD5, nil
 	}
 
 	return ajhash.DefaultAlgo, fmt.Errorf("invalid hashing algorithm '%s'", flag)
 }
+
+// Determine the database integrity checksum algorithm to use based on the flag that was passed.
+func checksumAlgoFromFlag(flag string) (db.ChecksumAlgo, error) {
+	switch strings.ToLower(flag) {
+	case "crc32":
+		return db.ChecksumCRC32, nil
+	case "sha256":
+		return db.ChecksumSHA256, nil
+	}
+
+	return db.ChecksumCRC32, fmt.Errorf("invalid checksum algorithm '%s'", flag)
+}
+
+// Determine how the identifiers are derived based on the flag that was passed.
+func idModeFromFlag(flag string) (db.IdMode, error) {
+	switch strings.ToLower(flag) {
+	case "path":
+		return db.IdModePath, nil
+	case "root":
+		return db.IdModeRoot, nil
+	case "uuid":
+		return db.IdModeUUID, nil
+	}
+
+	return db.IdModePath, fmt.Errorf("invalid identifier mode '%s'", flag)
+}
+
+// Determine the fsync policy to use based on the flag that was passed.
+func syncPolicyFromFlag(flag string) (db.SyncPolicy, error) {
+	switch strings.ToLower(flag) {
+	case "always":
+		return db.SyncAlways, nil
+	case "periodic":
+		return db.SyncPeriodic, nil
+	case "close":
+		return db.SyncOnClose, nil
+	}
+
+	return db.SyncPeriodic, fmt.Errorf("invalid fsync policy '%s'", flag)
+}
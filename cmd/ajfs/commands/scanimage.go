@@ -0,0 +1,116 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"fmt"
+
+	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/spf13/cobra"
+)
+
+// ajfs scan-image.
+var scanImageCmd = &cobra.Command{
+	Use:   "scan-image",
+	Short: "Create a new database from the contents of a disk image.",
+	Long: `Create a new database from the contents of a disk image without having to
+mount it, so that old backup images can be searched and compared alongside
+regular file hierarchies.
+
+Supported images are ISO9660 (.iso, using the Joliet names when present) as
+well as .tar, .tar.gz and .zip archives. The image is only read and never
+modified.
+
+The root path stored in the database is the path of the image followed by "!"
+(e.g. /media/backups/2009.iso!). The path entries are relative to the root of
+the image, file signature hashes can be calculated using "ajfs resume" as
+long as the image stays at the same location. Since there is no file hierarchy
+to compare against, "ajfs update" and "ajfs diff" against the file system can't
+be used with these databases, however diff between two databases can.
+
+If the database path is not specified then a database named "db.ajfs" will be
+created in the current working directory.`,
+	Example: `  # create the default ./db.ajfs database from an ISO image
+  ajfs scan-image /media/backups/2009.iso
+
+  # specify where the database should be stored and calculate file signature hashes
+  ajfs scan-image --hash /media/backups/2009.iso ~/2009.ajfs`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		commonConfig.Progress = showProgress
+
+		cfg := scan.Config{
+			CommonConfig:  commonConfig,
+			Root:          args[0],
+			Image:         true,
+			ForceOverride: scanImageForceOverride,
+			DirStats:      scanImageDirStats,
+		}
+
+		cfg.DbPath = defaultDBPath
+		if len(args) == 2 {
+			cfg.DbPath = args[1]
+		}
+
+		checksumAlgo, err := checksumAlgoFromFlag(scanImageChecksumAlgo)
+		if err != nil {
+			exitOnError(err, 1)
+		}
+		cfg.ChecksumAlgo = checksumAlgo
+
+		if scanImageCalculateHashes {
+			algo, err := algoFromFlag(scanImageHashAlgo)
+			if err != nil {
+				exitOnError(err, 1)
+			}
+
+			cfg.CalculateHashes = true
+			cfg.Algo = algo
+			cfg.HashCachePath = hashCachePath(scanImageNoCache)
+		} else if scanImageNoCache {
+			exitOnError(fmt.Errorf("--no-cache can only be used with --hash"), 1)
+		}
+
+		if err = scan.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(scanImageCmd)
+
+	scanImageCmd.Flags().BoolVar(&scanImageForceOverride, "force", false, "Override any existing database.")
+	scanImageCmd.Flags().BoolVarP(&scanImageCalculateHashes, "hash", "s", false, "Calculate file signature hashes.")
+	scanImageCmd.Flags().StringVarP(&scanImageHashAlgo, "algo", "a", "sha256", "Hashing algorithm to use. Valid values are 'sha1', 'sha256', 'sha512' and 'md5' (legacy).")
+	scanImageCmd.Flags().BoolVar(&scanImageNoCache, "no-cache", false, "Do not reuse or store file signature hashes using the hash cache.")
+	scanImageCmd.Flags().StringVar(&scanImageChecksumAlgo, "checksum", "crc32", "Database integrity checksum algorithm. Valid values are 'crc32' and 'sha256'.")
+	scanImageCmd.Flags().BoolVar(&scanImageDirStats, "dir-stats", false, "Store the child counts and cumulative sizes for each directory.")
+	scanImageCmd.Flags().BoolVarP(&showProgress, "progress", "p", false, "Display progress information.")
+}
+
+var (
+	scanImageForceOverride   bool
+	scanImageCalculateHashes bool
+	scanImageHashAlgo        string
+	scanImageChecksumAlgo    string
+	scanImageDirStats        bool
+	scanImageNoCache         bool
+)
@@ -0,0 +1,69 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"github.com/andrejacobs/ajfs/internal/app/seal"
+	"github.com/spf13/cobra"
+)
+
+// ajfs seal.
+var sealCmd = &cobra.Command{
+	Use:   "seal",
+	Short: "Mark a database as read-only.",
+	Long: `Mark a database as read-only (sealed).
+
+A sealed database is typically an archival reference snapshot that should not
+change. Commands that modify the database (resume, update, fix, set-root and
+prune) will refuse to do so unless "--force" is used.
+
+Only a flag in the database header is changed and thus the integrity checksum
+is not affected. Use "ajfs info" to see if a database is sealed.`,
+	Example: `  # seal the default ./db.ajfs database
+  ajfs seal
+
+  # seal the specified database
+  ajfs seal /path/to/database.ajfs
+
+  # remove the seal
+  ajfs seal --unseal /path/to/database.ajfs`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := seal.Config{
+			CommonConfig: commonConfig,
+			Unseal:       sealUnseal,
+		}
+		cfg.DbPath = dbPathFromArgs(args)
+
+		if err := seal.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sealCmd)
+
+	sealCmd.Flags().BoolVar(&sealUnseal, "unseal", false, "Remove the seal so that the database can be modified again.")
+}
+
+var (
+	sealUnseal bool
+)
@@ -40,8 +40,30 @@ Criteria include:
 * Matching the type of entry (e.g. a directory, file etc.).
 * Matching the path identifier against a prefix.
 * Matching the file signature hash against a prefix.
-* Matching if the size is exactly, greater or less than a value.
+* Matching if the size is exactly, greater or less than a value or within a range.
 * Matching if the last modification date is before or after a value.
+* Matching the number of other files with the same file signature hash.
+* Matching paths that are not valid UTF-8.
+
+Use "--sort" to display the matching entries sorted by path, size or last
+modification time and "--limit" to only display the first n entries. When both
+are used only the n best matching entries are kept in memory while searching,
+which makes it cheap to find for example the 10 largest PDF files in a very big
+database. Use "--count" to only display the number of matching entries.
+
+A catalog (see "ajfs catalog") can be specified instead of a database in which
+case all the volumes are searched and each result is prefixed with the volume
+name (e.g. "[nas] path/to/file.txt").
+
+Use "--save <name>" to save the search criteria under a name instead of
+searching and "--saved <name>" to search using the saved criteria, which avoids
+having to re-type complex searches for recurring audits. The searches are saved
+in "ajfs/searches.json" inside the user's config directory.
+
+Paths are stored exactly as they were returned by the file system, which on
+Linux does not have to be valid UTF-8. When displayed, the bytes of such a path
+that are not valid UTF-8 are escaped as \xNN and backslashes as \\. Use
+"--invalid-utf8" to find these paths.
 `,
 	Example: `  # search for all .txt files in the default ./db.ajfs database
   ajfs search -i "\.txt$"
@@ -55,29 +77,73 @@ Criteria include:
   # display all files smaller than 1GB
   ajfs search --type f --size -1G
 
+  # display all files between 1 MiB and 50 MiB (inclusive)
+  ajfs search --type f --size 1MiB..50MiB
+
   # display all entries with a last modification date before the date
   ajfs search --before 2019-03-01
 
   # display all entries with a last modification date before 30 days ago
   ajfs search --before 30D
 
+  # display all entries that changed in the last month
+  ajfs search --after 1M
+
+  # display all entries that changed between 30 and 7 days ago
+  ajfs search --mtime-between 30D,7D
+
   # display all entries with a last modification date after the date
   ajfs search --after 1999-03-24
+
+  # display the 10 largest .pdf files
+  ajfs search --iname "*.pdf" --sort size --limit 10
+
+  # display the files larger than 1MB of which more than 2 copies exist
+  ajfs search --dupes +2 --size +1M
+
+  # find the paths that are not valid UTF-8 so that they can be renamed
+  ajfs search --invalid-utf8
+
+  # display the number of files modified in the last week
+  ajfs search --type f --after 7D --count
+
+  # search for all .pdf files across all the volumes in a catalog
+  ajfs search --iname "*.pdf" backups.ajfscat
+
+  # save a search for large video files and run it later against a database
+  ajfs search --save big-media --iname "*.mp4" --iname "*.mkv" --size +1G
+  ajfs search --saved big-media /path/to/database.ajfs
 `,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		if searchSave != "" {
+			if err := saveSearch(searchSave); err != nil {
+				exitOnError(err, 1)
+			}
+			return
+		}
+
 		cfg := search.Config{
-			CommonConfig:     commonConfig,
-			DisplayFullPaths: searchDisplayFullPaths,
-			DisplayMinimal:   !searchDisplayMore,
+			CommonConfig:   commonConfig,
+			DisplayMinimal: !searchDisplayMore,
+			Limit:          searchLimit,
+			CountOnly:      searchCountOnly,
 		}
 		cfg.DbPath = dbPathFromArgs(args)
+		cfg.UnderConfig = parseUnderConfig(cfg.DbPath)
+		cfg.DisplayFullPaths = parseFullPaths(cfg.DbPath)
+
+		sortOrder, err := search.ParseSortOrder(searchSortOrder)
+		if err != nil {
+			exitOnError(err, 1)
+		}
+		cfg.Sort = sortOrder
 
 		if err := buildSearchExpression(&cfg); err != nil {
 			exitOnError(err, 1)
 		}
 
-		if err := search.Run(cfg); err != nil {
+		if err := search.Run(cmd.Context(), cfg); err != nil {
 			exitOnError(err, 1)
 		}
 
@@ -86,30 +152,81 @@ Criteria include:
 
 func init() {
 	rootCmd.AddCommand(searchCmd)
+	addUnderFlag(searchCmd)
 
-	searchCmd.Flags().BoolVarP(&searchDisplayFullPaths, "full", "f", false, "Display full paths for entries.")
+	addFullPathsFlags(searchCmd)
 	searchCmd.Flags().BoolVarP(&searchDisplayMore, "more", "m", false, "Display more information about the matching paths.")
+	searchCmd.Flags().IntVar(&searchLimit, "limit", 0, "Display at most this number of matching entries.")
+	searchCmd.Flags().BoolVar(&searchCountOnly, "count", false, "Only display the number of matching entries.")
+	searchCmd.Flags().StringVar(&searchSortOrder, "sort", "", `Sort the matching entries by one of the following:
+  path   alphabetically
+  size   from the largest to the smallest
+  mtime  from the most to the least recently modified`)
+	searchCmd.Flags().StringVar(&searchSave, "save", "", "Save the search criteria under this name instead of searching.")
+	searchCmd.Flags().StringVar(&searchSaved, "saved", "", "Search using the criteria that was saved under this name.")
+
+	addSearchFlags(searchCmd)
+}
+
+var (
+	searchRegex            []string
+	searchRegexInsensitive []string
 
-	searchCmd.Flags().StringArrayVarP(&searchRegex, "exp", "e", nil, "Match path against the regular expression.")
-	searchCmd.Flags().StringArrayVarP(&searchRegexInsensitive, "iexp", "i", nil, "Case insensitive match path against the regular expression.")
+	searchName            []string
+	searchNameInsensitive []string
 
-	searchCmd.Flags().StringArrayVarP(&searchName, "name", "n", nil, "Match base name against the shell pattern (e.g. * ?).")
-	searchCmd.Flags().StringArrayVar(&searchNameInsensitive, "iname", nil, "Case insensitive match base name against the shell pattern (e.g. * ?).")
+	searchPath            []string
+	searchPathInsensitive []string
 
-	searchCmd.Flags().StringArrayVarP(&searchPath, "path", "p", nil, "Match path against the shell pattern (e.g. * ?).")
-	searchCmd.Flags().StringArrayVar(&searchPathInsensitive, "ipath", nil, "Case insensitive match path against the shell pattern (e.g. * ?).")
+	searchSize           []string
+	searchSizeBlocks     bool
+	searchType           string
+	searchHash           string
+	searchModTimeBefore  string
+	searchModTimeAfter   string
+	searchModTimeBetween string
+	searchId             string
+	searchDupes          string
+	searchInvalidUTF8    bool
+	searchDisplayMore    bool
+	searchLimit          int
+	searchCountOnly      bool
+	searchSortOrder      string
+	searchSave           string
+	searchSaved          string
+)
 
-	searchCmd.Flags().StringVarP(&searchType, "type", "t", "", `Match if the type is one of the following:
+// Add the search expression flags to the cobra command.
+// A shorthand is left out when the command already uses it for one of its own flags (e.g. -n for sample --count).
+func addSearchFlags(c *cobra.Command) {
+	shorthand := func(s string) string {
+		if c.Flags().ShorthandLookup(s) != nil {
+			return ""
+		}
+		return s
+	}
+
+	c.Flags().StringArrayVarP(&searchRegex, "exp", shorthand("e"), nil, "Match path against the regular expression.")
+	c.Flags().StringArrayVarP(&searchRegexInsensitive, "iexp", shorthand("i"), nil, "Case insensitive match path against the regular expression.")
+
+	c.Flags().StringArrayVarP(&searchName, "name", shorthand("n"), nil, "Match base name against the shell pattern (e.g. * ?).")
+	c.Flags().StringArrayVar(&searchNameInsensitive, "iname", nil, "Case insensitive match base name against the shell pattern (e.g. * ?).")
+
+	c.Flags().StringArrayVarP(&searchPath, "path", shorthand("p"), nil, "Match path against the shell pattern (e.g. * ?).")
+	c.Flags().StringArrayVar(&searchPathInsensitive, "ipath", nil, "Case insensitive match path against the shell pattern (e.g. * ?).")
+
+	c.Flags().StringVarP(&searchType, "type", shorthand("t"), "", `Match if the type is one of the following:
   d  directory
   f  regular file
   l  symbolic link
   p  named pipe (FIFO)
   s  socket`)
 
-	searchCmd.Flags().StringVarP(&searchHash, "hash", "s", "", "Match if the file signature hash starts with this prefix.")
-	searchCmd.Flags().StringVar(&searchId, "id", "", "Match if the entry's identifier starts with this prefix.")
+	c.Flags().StringVarP(&searchHash, "hash", shorthand("s"), "", "Match if the file signature hash starts with this prefix.")
+	c.Flags().StringVar(&searchId, "id", "", "Match if the entry's identifier starts with this prefix.")
+	c.Flags().BoolVar(&searchInvalidUTF8, "invalid-utf8", false, "Match if the path is not valid UTF-8 (e.g. names created with a legacy encoding).")
 
-	searchCmd.Flags().StringArrayVar(&searchSize, "size", nil, `Match the file size according to:
+	c.Flags().StringArrayVar(&searchSize, "size", nil, `Match the file size according to:
   <n> with no suffix means exactly <n> bytes. e.g. --size 100
 
   With one of the following scaling suffixes:
@@ -118,12 +235,23 @@ func init() {
   g/G   Gigabytes (1 GB = 1000 MB). e.g. --size 1g
   t/T   Terrabytes (1 TB = 1000 GB). e.g. --size 1t
   p/P   Petabytes (1 PB = 1000 TB). e.g. --size 1p
+  KiB   Kibibytes (1 KiB = 1024 bytes). e.g. --size 1KiB
+  MiB   Mebibytes (1 MiB = 1024 KiB). e.g. --size 1MiB
+  GiB   Gibibytes (1 GiB = 1024 MiB). e.g. --size 1GiB
+  TiB   Tebibytes (1 TiB = 1024 GiB). e.g. --size 1TiB
+  PiB   Pebibytes (1 PiB = 1024 TiB). e.g. --size 1PiB
 
   With one of the following operation prefixes:
   +   Greater than. e.g. --size +1k
-  -   Less than. e.g. --size -1k`)
+  -   Less than. e.g. --size -1k
 
-	searchCmd.Flags().StringVarP(&searchModTimeBefore, "before", "b", "", `Match if the entry's last modification time is before this time.
+  Or a range (inclusive) where either bound can be omitted:
+  <n>..<n>  e.g. --size 1m..50m, --size 1GiB..`)
+
+	c.Flags().BoolVar(&searchSizeBlocks, "size-blocks", false, `Round up the file size to the unit used in --size before comparing (like find).
+  e.g. --size -1M will then only match empty files.`)
+
+	c.Flags().StringVarP(&searchModTimeBefore, "before", "b", "", `Match if the entry's last modification time is before this time.
   The following formats are allowed:
   YYYY-MM-DD
   YYYY-MM-DD HH:mm:ss   Also supports YYYY-MM-DDTHH:mm:ss
@@ -132,172 +260,122 @@ func init() {
   <n>Y  n Years before now
 `)
 
-	searchCmd.Flags().StringVarP(&searchModTimeAfter, "after", "a", "", `Match if the entry's last modification time is after this time.
+	c.Flags().StringVarP(&searchModTimeAfter, "after", "a", "", `Match if the entry's last modification time is after this time.
   The following formats are allowed:
   YYYY-MM-DD
   YYYY-MM-DD HH:mm:ss   Also supports YYYY-MM-DDTHH:mm:ss
+  <n>D  n Days before now (e.g. 7D means within the last week)
+  <n>M  n Months before now
+  <n>Y  n Years before now
 `)
-}
-
-var (
-	searchRegex            []string
-	searchRegexInsensitive []string
-
-	searchName            []string
-	searchNameInsensitive []string
-
-	searchPath            []string
-	searchPathInsensitive []string
-
-	searchSize             []string
-	searchType             string
-	searchHash             string
-	searchModTimeBefore    string
-	searchModTimeAfter     string
-	searchId               string
-	searchDisplayFullPaths bool
-	searchDisplayMore      bool
-)
-
-func buildSearchExpression(cfg *search.Config) error {
-
-	var prev search.Expression
-	var and search.Expression
 
-	// Regex
-	prev = &search.Always{}
-	for _, regexStr := range searchRegex {
-		exp, err := search.NewRegex(regexStr)
-		if err != nil {
-			return fmt.Errorf("failed to parse regular expression %q. %v", regexStr, err)
-		}
+	c.Flags().StringVar(&searchModTimeBetween, "mtime-between", "", `Match if the entry's last modification time is between two times.
+  The format is A,B where A and B can use any of the --before formats.
+  e.g. --mtime-between 2024-01-01,2024-06-30 or --mtime-between 30D,7D
+`)
 
-		and = search.NewAnd(prev, exp)
-		prev = and
+	// "ajfs cleanup" has its own --dupes flag
+	if c.Flags().Lookup("dupes") != nil {
+		return
 	}
+	c.Flags().StringVar(&searchDupes, "dupes", "", `Match files by the number of other files with the same file signature hash.
+  <n>       exactly n duplicates. e.g. --dupes 0 for files without copies
+  +<n>      more than n duplicates. e.g. --dupes +2
+  -<n>      fewer than n duplicates
+  <n>..<n>  a range (inclusive) where either bound can be omitted. e.g. --dupes 2..5
+  Only files with a calculated hash can match and empty files have no duplicates.`)
+}
 
-	// Case insensitive regex
-	for _, regexStr := range searchRegexInsensitive {
-		exp, err := search.NewRegex("(?i)" + regexStr)
-		if err != nil {
-			return fmt.Errorf("failed to parse regular expression '(?i)%s'. %v", regexStr, err)
+func buildSearchExpression(cfg *search.Config) error {
+	c := searchCriteria()
+	if searchSaved != "" {
+		if exp, _, err := c.Build(); err != nil || exp != nil {
+			return fmt.Errorf("--saved can't be combined with other search criteria")
 		}
 
-		and = search.NewAnd(prev, exp)
-		prev = and
-	}
-
-	// Name (base name only)
-	for _, pattern := range searchName {
-		exp, err := search.NewShellPattern(pattern, true, false)
+		var err error
+		c, err = loadSavedSearch(searchSaved)
 		if err != nil {
-			return fmt.Errorf("failed to parse shell pattern %q. %v", pattern, err)
+			return err
 		}
-
-		and = search.NewAnd(prev, exp)
-		prev = and
 	}
 
-	// Case insensitive name (base name only)
-	for _, pattern := range searchNameInsensitive {
-		exp, err := search.NewShellPattern(pattern, true, true)
-		if err != nil {
-			return fmt.Errorf("failed to parse shell pattern %q. %v", pattern, err)
-		}
-
-		and = search.NewAnd(prev, exp)
-		prev = and
+	exp, alsoHashes, err := c.Build()
+	if err != nil {
+		return err
 	}
 
-	// Path
-	for _, pattern := range searchPath {
-		exp, err := search.NewShellPattern(pattern, false, false)
-		if err != nil {
-			return fmt.Errorf("failed to parse shell pattern %q. %v", pattern, err)
-		}
-
-		and = search.NewAnd(prev, exp)
-		prev = and
+	// If no flags then match nothing
+	if exp == nil {
+		exp = &search.Never{}
 	}
 
-	// Case insensitive path
-	for _, pattern := range searchPathInsensitive {
-		exp, err := search.NewShellPattern(pattern, false, true)
-		if err != nil {
-			return fmt.Errorf("failed to parse shell pattern %q. %v", pattern, err)
-		}
-
-		and = search.NewAnd(prev, exp)
-		prev = and
-	}
+	cfg.Expresion = exp
+	cfg.AlsoHashes = alsoHashes
+	return nil
+}
 
-	// Size
-	for _, sizeStr := range searchSize {
-		exp, err := search.NewSize(sizeStr)
-		if err != nil {
-			return fmt.Errorf("failed to parse size expression from %q'. %v", sizeStr, err)
-		}
+// Parse the search expression flags.
+// Returns nil as the expression when none of the flags were specified.
+// alsoHashes will be true when the expression requires the file signature hashes.
+func parseSearchExpression() (exp search.Expression, alsoHashes bool, err error) {
+	return searchCriteria().Build()
+}
 
-		and = search.NewAnd(prev, exp)
-		prev = and
+// The search criteria specified by the search expression flags.
+func searchCriteria() search.Criteria {
+	return search.Criteria{
+		Regex:            searchRegex,
+		RegexInsensitive: searchRegexInsensitive,
+		Name:             searchName,
+		NameInsensitive:  searchNameInsensitive,
+		Path:             searchPath,
+		PathInsensitive:  searchPathInsensitive,
+		Size:             searchSize,
+		SizeBlocks:       searchSizeBlocks,
+		Type:             searchType,
+		Hash:             searchHash,
+		Id:               searchId,
+		Before:           searchModTimeBefore,
+		After:            searchModTimeAfter,
+		Between:          searchModTimeBetween,
+		Dupes:            searchDupes,
+		InvalidUTF8:      searchInvalidUTF8,
 	}
+}
 
-	// Type
-	if searchType != "" {
-		exp, err := search.NewType(searchType)
-		if err != nil {
-			return err
-		}
-
-		and = search.NewAnd(prev, exp)
-		prev = and
+// Save the search criteria specified by the flags under the name.
+func saveSearch(name string) error {
+	path, err := search.DefaultSavedSearchesPath()
+	if err != nil {
+		return err
 	}
 
-	// Hash
-	if searchHash != "" {
-		exp := &search.Hash{Prefix: searchHash}
-		and = search.NewAnd(prev, exp)
-		prev = and
-
-		cfg.AlsoHashes = true
+	saved, err := search.LoadSavedSearches(path)
+	if err != nil {
+		return err
 	}
-
-	// Id
-	if searchId != "" {
-		exp := &search.Id{Prefix: searchId}
-		and = search.NewAnd(prev, exp)
-		prev = and
+	if err := saved.Save(name, searchCriteria()); err != nil {
+		return err
 	}
-
-	// Before date/time
-	if searchModTimeBefore != "" {
-		exp, err := search.NewModTimeBefore(searchModTimeBefore)
-		if err != nil {
-			return err
-		}
-
-		and = search.NewAnd(prev, exp)
-		prev = and
+	if err := saved.Write(path); err != nil {
+		return err
 	}
 
-	// After date/time
-	if searchModTimeAfter != "" {
-		exp, err := search.NewModTimeAfter(searchModTimeAfter)
-		if err != nil {
-			return err
-		}
+	commonConfig.Println(fmt.Sprintf("Saved the search %q to %q", name, path))
+	return nil
+}
 
-		and = search.NewAnd(prev, exp)
-		prev = and
+// Load the criteria of the saved search.
+func loadSavedSearch(name string) (search.Criteria, error) {
+	path, err := search.DefaultSavedSearchesPath()
+	if err != nil {
+		return search.Criteria{}, err
 	}
 
-	_ = prev
-
-	// If no flags then match nothing
-	if and == nil {
-		and = &search.Never{}
+	saved, err := search.LoadSavedSearches(path)
+	if err != nil {
+		return search.Criteria{}, err
 	}
-
-	cfg.Expresion = and
-	return nil
+	return saved.Get(name)
 }
@@ -0,0 +1,124 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/andrejacobs/ajfs/internal/app/setpaths"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/spf13/cobra"
+)
+
+// ajfs set-paths.
+var setPathsCmd = &cobra.Command{
+	Use:   "set-paths",
+	Short: "Store whether full or relative paths are displayed by default.",
+	Long: `Store whether the commands that display paths (list, ls, search, grep, dupes,
+sample, top and cleanup) display full paths (prefixed with the root path) or
+paths relative to the root path by default. Use "default" to remove the stored
+preference.
+
+Which paths are displayed is decided in the following order:
+  1. The "--full" or "--relative" option.
+  2. The preference stored in the database using this command.
+  3. The AJFS_FULL_PATHS environment variable (e.g. AJFS_FULL_PATHS=1 in your
+     shell profile displays full paths for all databases).
+  4. Relative paths.
+
+Only a flag in the database header is changed and thus the integrity checksum
+is not affected. The preference can also be stored in a sealed database. Use
+"ajfs info" to see the stored preference.`,
+	Example: `  # always display full paths for the default ./db.ajfs database
+  ajfs set-paths full
+
+  # always display relative paths for the specified database
+  ajfs set-paths /path/to/database.ajfs relative
+
+  # remove the stored preference
+  ajfs set-paths /path/to/database.ajfs default`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := setpaths.Config{
+			CommonConfig: commonConfig,
+		}
+
+		value := args[0]
+		cfg.DbPath = defaultDBPath
+		if len(args) == 2 {
+			cfg.DbPath = args[0]
+			value = args[1]
+		}
+
+		var err error
+		if cfg.PathDisplay, err = db.ParsePathDisplay(value); err != nil {
+			exitOnError(err, 1)
+		}
+
+		if err := setpaths.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(setPathsCmd)
+}
+
+// Add the --full and --relative flags used by the commands that display paths.
+func addFullPathsFlags(c *cobra.Command) {
+	c.Flags().BoolVarP(&fullPaths, "full", "f", false, "Display full paths for entries.")
+	c.Flags().BoolVar(&relativePaths, "relative", false, "Display paths relative to the root path, even if full paths are the default.")
+}
+
+// Returns true if full paths should be displayed for the database.
+// The flags take precedence over the preference stored in the database (see ajfs set-paths), which takes precedence
+// over the AJFS_FULL_PATHS environment variable.
+func parseFullPaths(dbPath string) bool {
+	if fullPaths && relativePaths {
+		exitOnError(fmt.Errorf("--full and --relative can't be used together"), 1)
+	}
+	if fullPaths || relativePaths {
+		return fullPaths
+	}
+
+	// The command reports the database that can't be opened (or is a catalog) itself
+	if pd, err := db.ReadPathDisplay(dbPath); err == nil && pd != db.PathDisplayDefault {
+		return pd == db.PathDisplayFull
+	}
+
+	if value := os.Getenv(fullPathsEnv); value != "" {
+		full, err := strconv.ParseBool(value)
+		if err != nil {
+			exitOnError(fmt.Errorf("invalid value %q for %s, expected true or false", value, fullPathsEnv), 1)
+		}
+		return full
+	}
+	return false
+}
+
+const fullPathsEnv = "AJFS_FULL_PATHS"
+
+var (
+	fullPaths     bool
+	relativePaths bool
+)
@@ -0,0 +1,80 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"github.com/andrejacobs/ajfs/internal/app/setroot"
+	"github.com/spf13/cobra"
+)
+
+// ajfs set-root.
+var setRootCmd = &cobra.Command{
+	Use:   "set-root",
+	Short: "Change the root path stored in the database.",
+	Long: `Change the root path stored in the database, for example when a drive has been
+mounted at a different location.
+
+The path entries are stored relative to the root path and thus the "--full"
+paths, diff against the file system and tosync will use the new root path.
+
+The database is rewritten (using the current file format version) and the
+integrity checksum is recalculated. The path entries, file signature hashes
+and creation information are kept the same.
+
+A sealed database (see "ajfs seal") will not be modified unless "--force" is
+used.`,
+	Example: `  # change the root path of the default ./db.ajfs database
+  ajfs set-root /new/mount/point
+
+  # change the root path of the specified database
+  ajfs set-root /path/to/database.ajfs /new/mount/point`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := setroot.Config{
+			CommonConfig: commonConfig,
+			Force:        setRootForce,
+		}
+
+		switch len(args) {
+		case 1:
+			cfg.DbPath = defaultDBPath
+			cfg.Root = args[0]
+		case 2:
+			cfg.DbPath = args[0]
+			cfg.Root = args[1]
+		default:
+			panic("invalid args")
+		}
+
+		if err := setroot.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(setRootCmd)
+
+	setRootCmd.Flags().BoolVar(&setRootForce, "force", false, "Change the root path even if the database has been sealed.")
+}
+
+var (
+	setRootForce bool
+)
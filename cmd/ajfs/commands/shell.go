@@ -0,0 +1,80 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package commands
+
+import (
+	"github.com/andrejacobs/ajfs/internal/app/shell"
+	"github.com/spf13/cobra"
+)
+
+// ajfs shell.
+var shellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "Explore one or more databases interactively.",
+	Long: `Start an interactive session for exploring one or more databases.
+
+The databases are only read once when the session starts, which makes running
+many queries against a very large database a lot quicker than running the
+individual ajfs commands.
+
+The following commands are available inside the session:
+  ls [path|pattern]  List a directory or the entries matching a shell pattern.
+  cd [path]          Change the current directory. No path changes to the root.
+  pwd                Display the current directory.
+  find <pattern>     Find the entries at or below the current directory whose name matches the shell pattern.
+  hash <path>        Display the file signature hash of a file.
+  info               Display information about the current database.
+  dbs                List the databases that have been loaded.
+  use <name|number>  Switch to another database.
+  help               Display the available commands.
+  exit, quit         End the session.
+
+Paths starting with / are relative to the root path of the database, all other
+paths are relative to the current directory.
+
+When run from a terminal, the previous commands can be recalled using the up
+and down arrow keys. Commands can also be piped into the session, one per line.`,
+	Example: `  # explore the default ./db.ajfs database
+  ajfs shell
+
+  # explore two databases and switch between them using "use"
+  ajfs shell /path/to/laptop.ajfs /path/to/nas.ajfs
+
+  # run commands from a script
+  printf "cd photos\nfind *.jpg\n" | ajfs shell /path/to/database.ajfs`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := shell.Config{
+			CommonConfig: commonConfig,
+			DbPaths:      args,
+		}
+		if len(cfg.DbPaths) == 0 {
+			cfg.DbPaths = []string{defaultDBPath}
+		}
+
+		if err := shell.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(shellCmd)
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"github.com/andrejacobs/ajfs/internal/app/sign"
+	"github.com/spf13/cobra"
+)
+
+// ajfs sign.
+var signCmd = &cobra.Command{
+	Use:   "sign",
+	Short: "Sign a database using an Ed25519 key.",
+	Long: `Sign a database using an Ed25519 private key so that it can later be proven
+that the database has not been modified (see "ajfs verify-signature").
+
+The signature and the public key are stored in the database as a new feature
+section. Signing a database that was already signed replaces the signature.
+
+The key must be a PEM encoded (PKCS #8) Ed25519 private key, which can be
+created and its public key extracted using openssl:
+  openssl genpkey -algorithm ed25519 -out key.pem
+  openssl pkey -in key.pem -pubout -out pub.pem
+
+NOTE: Any changes made to the database after it was signed (e.g. using
+"ajfs resume") will invalidate the signature. Commands that rewrite the
+database (e.g. "ajfs update", "ajfs prune" and "ajfs set-root") will remove
+the signature. Sealing the database (see "ajfs seal") does not affect the
+signature.`,
+	Example: `  # sign the default ./db.ajfs database
+  ajfs sign --key key.pem
+
+  # sign the specified database
+  ajfs sign --key key.pem /path/to/database.ajfs`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := sign.Config{
+			CommonConfig: commonConfig,
+			KeyPath:      signKeyPath,
+		}
+		cfg.DbPath = dbPathFromArgs(args)
+
+		if err := sign.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+// ajfs verify-signature.
+var verifySignatureCmd = &cobra.Command{
+	Use:   "verify-signature",
+	Short: "Verify the signature of a database.",
+	Long: `Verify that a signed database (see "ajfs sign") has not been modified since it
+was signed.
+
+Use "--key" to specify the public key (or the private key) that is expected to
+have been used to sign the database. Without a key only the integrity of the
+database is verified using the public key stored along with the signature,
+which does not prove who signed the database.
+
+The exit code will be 1 if the signature is not valid.`,
+	Example: `  # verify the default ./db.ajfs database was signed using the expected key
+  ajfs verify-signature --key pub.pem
+
+  # only verify the integrity of the specified database
+  ajfs verify-signature /path/to/database.ajfs`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := sign.Config{
+			CommonConfig: commonConfig,
+			KeyPath:      verifySignatureKeyPath,
+		}
+		cfg.DbPath = dbPathFromArgs(args)
+
+		if err := sign.Verify(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(signCmd)
+	rootCmd.AddCommand(verifySignatureCmd)
+
+	signCmd.Flags().StringVar(&signKeyPath, "key", "", "Path to the PEM encoded Ed25519 private key.")
+	_ = signCmd.MarkFlagRequired("key")
+
+	verifySignatureCmd.Flags().StringVar(&verifySignatureKeyPath, "key", "", "Path to the PEM encoded Ed25519 public (or private) key that is expected to have signed the database.")
+}
+
+var (
+	signKeyPath            string
+	verifySignatureKeyPath string
+)
@@ -0,0 +1,58 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"github.com/andrejacobs/ajfs/internal/app/split"
+	"github.com/spf13/cobra"
+)
+
+// ajfs split.
+var splitCmd = &cobra.Command{
+	Use:   "split",
+	Short: "Extract a subpath of a database into a new database.",
+	Long: `Extract all the entries at or below a subpath of an existing database into a
+new standalone database without having to scan the file system again.
+
+The root path of the new database is the subpath joined to the root path of the
+existing database and the entries are stored relative to this new root path.
+The existing file signature hashes are copied over.`,
+	Example: `  # extract the photos directory into a new database
+  ajfs split --under photos/ /path/to/drive.ajfs /path/to/photos.ajfs`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := split.Config{
+			CommonConfig: commonConfig,
+			OutPath:      args[1],
+		}
+		cfg.DbPath = args[0]
+		cfg.UnderConfig = parseUnderConfig(cfg.DbPath)
+
+		if err := split.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(splitCmd)
+	addUnderFlag(splitCmd)
+	_ = splitCmd.MarkFlagRequired("under")
+}
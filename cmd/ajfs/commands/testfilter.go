@@ -0,0 +1,100 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"fmt"
+
+	"github.com/andrejacobs/ajfs/internal/app/testfilter"
+	"github.com/spf13/cobra"
+)
+
+// ajfs test-filter.
+var testFilterCmd = &cobra.Command{
+	Use:   "test-filter",
+	Short: "Display which paths the filters would include or exclude.",
+	Long: `Display which paths the path filtering flags would include or exclude
+without creating or touching any database. This makes it quick to iterate on
+the filter rules before launching a large scan.
+
+The same filters as "ajfs scan" are supported (see "ajfs scan --help"). Each
+path is displayed with one of the following decisions:
+
+* included:        The path would be stored in the database.
+* not included:    The path did not match any of the include filters.
+* excluded:        The path matched an exclude filter (or a size, age or
+                   special file filter).
+* parent excluded: A parent directory would not be walked.
+
+A directory that is not included is displayed once and its contents are not
+walked, the same as a scan would do.
+
+When the path is a single file or a directory inside a larger hierarchy, use
+"--root" to specify the path that will be scanned so that the filters are
+applied to the same relative paths as the scan would.`,
+	Example: `  # test which files would be included when only PDF files are scanned
+  ajfs test-filter -i "f:\.pdf$" /path/to/be/scanned
+
+  # only display what would be excluded
+  ajfs test-filter --excluded -e "d:temp$" --max-size 2G /path/to/be/scanned
+
+  # test a single file relative to the root that will be scanned
+  ajfs test-filter -e "d:temp$" --root /path/to/be/scanned /path/to/be/scanned/temp/file.txt`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if testFilterIncluded && testFilterExcluded {
+			exitOnError(fmt.Errorf("--included and --excluded can't be used together"), 1)
+		}
+
+		filterCfg, err := parseFilterConfig()
+		if err != nil {
+			exitOnError(err, 1)
+		}
+
+		cfg := testfilter.Config{
+			CommonConfig: commonConfig,
+			FilterConfig: *filterCfg,
+			Path:         args[0],
+			Root:         testFilterRoot,
+			OnlyIncluded: testFilterIncluded,
+			OnlyExcluded: testFilterExcluded,
+		}
+
+		if err := testfilter.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(testFilterCmd)
+
+	testFilterCmd.Flags().StringVar(&testFilterRoot, "root", "", "Path that will be scanned. Defaults to the path itself, or its parent when it is a file.")
+	testFilterCmd.Flags().BoolVar(&testFilterIncluded, "included", false, "Only display the paths that would be included.")
+	testFilterCmd.Flags().BoolVar(&testFilterExcluded, "excluded", false, "Only display the paths that would not be included.")
+
+	addPathFilteringFlags(testFilterCmd)
+}
+
+var (
+	testFilterRoot     string
+	testFilterIncluded bool
+	testFilterExcluded bool
+)
@@ -0,0 +1,75 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"github.com/andrejacobs/ajfs/internal/app/top"
+	"github.com/spf13/cobra"
+)
+
+// ajfs top.
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Display the largest files and directories.",
+	Long: `Display the largest files and directories stored in the database.
+
+The size of a directory is the total size of all the files at or below it.
+Entries with the same size are displayed in alphabetical order.`,
+	Example: `  # display the 10 largest files and directories in the default ./db.ajfs database
+  ajfs top
+
+  # display the 20 largest files and directories with human friendly sizes
+  ajfs top --files 20 --dirs 20 --human /path/to/database.ajfs
+
+  # display only the 5 largest directories below a subpath
+  ajfs top --files 0 --dirs 5 --under photos/2023 /path/to/database.ajfs`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := top.Config{
+			CommonConfig: commonConfig,
+			Files:        topFiles,
+			Dirs:         topDirs,
+			HumanSizes:   topHumanSizes,
+		}
+		cfg.DbPath = dbPathFromArgs(args)
+		cfg.UnderConfig = parseUnderConfig(cfg.DbPath)
+		cfg.DisplayFullPaths = parseFullPaths(cfg.DbPath)
+
+		if err := top.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(topCmd)
+
+	topCmd.Flags().IntVar(&topFiles, "files", 10, "Number of the largest files to display.")
+	topCmd.Flags().IntVar(&topDirs, "dirs", 10, "Number of the largest directories to display.")
+	addFullPathsFlags(topCmd)
+	topCmd.Flags().BoolVar(&topHumanSizes, "human", false, "Display sizes in a human friendly format.")
+	addUnderFlag(topCmd)
+}
+
+var (
+	topFiles      int
+	topDirs       int
+	topHumanSizes bool
+)
@@ -55,6 +55,16 @@ system (e.g. Linux server). In which case the file locations are different
 between the systems. In order to do this you need to perform a scan with
 file signature hash calculations on both systems and the use:
   ajfs tosync lhs.ajfs rhs.ajfs
+
+Use "--summary" to display the total number of files and bytes that need to
+be synced. Use "--bandwidth" to also estimate how long the transfer would take,
+e.g. to decide between shipping a drive and syncing over the network. The
+bandwidth is the number of bytes per second and can use the suffixes k, M, G
+(powers of 1000) or KiB, MiB, GiB (powers of 1024), e.g. 100M for 100 MB/s.
+
+Use "--relative" to pair up the files purely by their path relative to the
+root, regardless of the root paths and of how the path identifiers were
+derived (see "ajfs diff --help").
 `,
 	Example: `  # compares the default database ./db.ajfs as the LHS against the RHS database
   ajfs tosync /path/to/rhs.ajf
@@ -64,6 +74,9 @@ file signature hash calculations on both systems and the use:
 
   # only compare the file signature hashes. Useful when the files are in different locations
   ajfs tosync --hash lhs.ajfs rhs.ajfs
+
+  # display the totals and estimate how long it would take to transfer at 50 MB/s
+  ajfs tosync --bandwidth 50M lhs.ajfs rhs.ajfs
 `,
 	Args: cobra.RangeArgs(1, 2),
 	Run: func(cmd *cobra.Command, args []string) {
@@ -71,7 +84,17 @@ file signature hash calculations on both systems and the use:
 			CommonConfig: commonConfig,
 			OnlyHashes:   tosyncHashesOnly,
 			FullPaths:    tosyncFullPaths,
+			Summary:      tosyncSummary,
+		}
+		if tosyncRelative {
+			cfg.Match = diff.MatchByRelativePath
+		}
+
+		bandwidth, err := parseSizeLimit("bandwidth", tosyncBandwidth)
+		if err != nil {
+			exitOnError(err, 1)
 		}
+		cfg.Bandwidth = bandwidth
 
 		switch len(args) {
 		case 1:
@@ -84,7 +107,7 @@ file signature hash calculations on both systems and the use:
 
 		cfg.Fn = printToSync
 
-		if err := tosync.Run(cfg); err != nil {
+		if err := tosync.Run(cmd.Context(), cfg); err != nil {
 			exitOnError(err, 1)
 		}
 	},
@@ -95,11 +118,17 @@ func init() {
 
 	tosyncCmd.Flags().BoolVarP(&tosyncHashesOnly, "hash", "s", false, "Compare only the file signature hashes.")
 	tosyncCmd.Flags().BoolVarP(&tosyncFullPaths, "full", "f", false, "Display full paths for entries.")
+	tosyncCmd.Flags().BoolVar(&tosyncSummary, "summary", false, "Display the total number of files and bytes that need to be synced.")
+	tosyncCmd.Flags().BoolVar(&tosyncRelative, "relative", false, "Pair up the files purely by their path relative to the root.")
+	tosyncCmd.Flags().StringVar(&tosyncBandwidth, "bandwidth", "", "Estimate the transfer time using this many bytes per second (e.g. 100M). Implies --summary.")
 }
 
 var (
 	tosyncHashesOnly bool
 	tosyncFullPaths  bool
+	tosyncSummary    bool
+	tosyncBandwidth  string
+	tosyncRelative   bool
 )
 
 func printToSync(d diff.Diff) error {
@@ -30,7 +30,11 @@ import (
 var treeCmd = &cobra.Command{
 	Use:   "tree",
 	Short: "Display the file hiearchy tree.",
-	Long:  `Display the file hiearchy as a tree in a similar way the popular tree command does.`,
+	Long: `Display the file hiearchy as a tree in a similar way the popular tree command does.
+
+Use "--sizes" to display the size of each entry. The size of a directory is the
+combined size of all the files below it and requires the database to have been
+created using "ajfs scan --dir-stats".`,
 	Example: `  # display the entire hierarchy from the default ./db.ajfs
   ajfs tree
 
@@ -43,6 +47,9 @@ var treeCmd = &cobra.Command{
   # display only directories
   ajfs tree --dirs /path/to/database.ajfs
 
+  # display only directories along with their sizes
+  ajfs tree --dirs --sizes /path/to/database.ajfs
+
   # display only directories and limit the depth to 3 layers starting at the subtree
   ajfs tree --dirs --limit 3 /path/to/database.ajfs /sub/tree/path/inside`,
 	Args: cobra.MaximumNArgs(2),
@@ -51,6 +58,8 @@ var treeCmd = &cobra.Command{
 			CommonConfig: commonConfig,
 			OnlyDirs:     treeOnlyDirs,
 			Limit:        treeLimit,
+			Sizes:        treeSizes,
+			Collator:     parseLocale(),
 		}
 
 		switch len(args) {
@@ -74,8 +83,9 @@ var treeCmd = &cobra.Command{
 		default:
 			panic("invalid args")
 		}
+		cfg.UnderConfig = parseUnderConfig(cfg.DbPath)
 
-		if err := tree.Run(cfg); err != nil {
+		if err := tree.Run(cmd.Context(), cfg); err != nil {
 			exitOnError(err, 1)
 		}
 	},
@@ -83,12 +93,16 @@ var treeCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(treeCmd)
+	addUnderFlag(treeCmd)
+	addLocaleFlag(treeCmd)
 
 	treeCmd.Flags().BoolVarP(&treeOnlyDirs, "dirs", "d", false, "Display only directories.")
 	treeCmd.Flags().IntVarP(&treeLimit, "limit", "l", 0, "Limit the tree depth.")
+	treeCmd.Flags().BoolVar(&treeSizes, "sizes", false, "Display sizes (directories require the database to contain directory statistics).")
 }
 
 var (
 	treeOnlyDirs bool
 	treeLimit    int
+	treeSizes    bool
 )
@@ -0,0 +1,70 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package commands
+
+import (
+	"github.com/andrejacobs/ajfs/internal/app/undo"
+	"github.com/spf13/cobra"
+)
+
+// ajfs undo.
+var undoCmd = &cobra.Command{
+	Use:   "undo <manifest>",
+	Short: "Restore the files that were moved into the trash.",
+	Long: `Restore the files that were moved into the trash by a destructive action.
+
+Instead of deleting files, ajfs moves them into a .ajfs-trash directory at the
+top of the file system they live on and records each move in an undo manifest.
+This command replays the manifest in reverse and moves each file back to its
+original path.
+
+A file is not restored when its original path exists again. These files are
+kept in the manifest so that the undo can be retried once the conflict has been
+resolved. The manifest is removed once all the files have been restored.
+
+To permanently delete the files, remove the .ajfs-trash directories.`,
+	Example: `  # see which files will be restored
+  ajfs undo --dry-run ajfs-undo-20250101-120000.jsonl
+
+  # restore the files
+  ajfs undo ajfs-undo-20250101-120000.jsonl`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := undo.Config{
+			CommonConfig: commonConfig,
+			ManifestPath: args[0],
+			DryRun:       undoDryRun,
+		}
+
+		if err := undo.Run(cmd.Context(), cfg); err != nil {
+			exitOnError(err, 1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+
+	undoCmd.Flags().BoolVar(&undoDryRun, "dry-run", false, "Only display which files would be restored.")
+}
+
+var (
+	undoDryRun bool
+)
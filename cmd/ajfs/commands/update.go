@@ -41,6 +41,18 @@ file entries will be calculated.
 
 A backup of the existing database will first be created (with .bak suffix)
 and if any error occurred then the database will be restored.
+
+Hashes for new or changed files are reused from the hash cache when possible
+(see "ajfs cache"). Use "--no-cache" to not use the cache.
+
+The members of archives are recorded again when the database was scanned with
+"--archives", since this is stored in the database.
+
+A sealed database (see "ajfs seal") will not be updated unless "--force" is
+used, in which case the updated database will remain sealed.
+
+The same path, size, age and special file filtering flags as "ajfs scan" can
+be used.
 `,
 	Example: `  # update the existing default ./db.ajfs database
   ajfs update
@@ -53,17 +65,35 @@ and if any error occurred then the database will be restored.
 		if err != nil {
 			exitOnError(err, 1)
 		}
+		syncPolicy, err := syncPolicyFromFlag(hashSyncPolicy)
+		if err != nil {
+			exitOnError(err, 1)
+		}
 
 		commonConfig.Progress = showProgress
+		stopMetrics := startMetrics()
+		defer stopMetrics()
+		notifyCompletion := startNotify(cmd)
 
 		cfg := update.Config{
-			CommonConfig: commonConfig,
-			FilterConfig: *filterCfg,
-			KeepCopyPath: keepCopyPath,
+			CommonConfig:  commonConfig,
+			FilterConfig:  *filterCfg,
+			KeepCopyPath:  keepCopyPath,
+			Force:         updateForce,
+			HashCachePath: hashCachePath(updateNoCache),
+			FlushInterval: hashFlushInterval,
+			SyncPolicy:    syncPolicy,
+
+			SkipUnreadable: updateSkipUnreadable,
+			Sorted:         updateSorted,
+			SortHashes:     updateSortHashes,
+			Archives:       updateArchives,
 		}
 		cfg.DbPath = dbPathFromArgs(args)
 
-		if err := update.Run(cfg); err != nil {
+		err = update.Run(cmd.Context(), cfg)
+		notifyCompletion(cfg.DbPath, err)
+		if err != nil {
 			exitOnError(err, 1)
 		}
 	},
@@ -71,13 +101,29 @@ and if any error occurred then the database will be restored.
 
 func init() {
 	rootCmd.AddCommand(updateCmd)
+	addMetricsFlag(updateCmd)
+	addNotifyFlags(updateCmd)
+	addHashWriteFlags(updateCmd)
 
 	updateCmd.Flags().BoolVarP(&showProgress, "progress", "p", false, "Display progress information.")
 	updateCmd.Flags().StringVarP(&keepCopyPath, "keep-copy", "k", "", "Path to where to keep a copy of the existing database before the update.")
+	updateCmd.Flags().BoolVar(&updateNoCache, "no-cache", false, "Do not reuse or store file signature hashes using the hash cache.")
+	updateCmd.Flags().BoolVar(&updateForce, "force", false, "Update even if the database has been sealed.")
+	updateCmd.Flags().BoolVar(&updateSorted, "sorted", false, "Store the entries sorted by path instead of the order in which they were found.")
+	updateCmd.Flags().BoolVar(&updateSortHashes, "sort-hashes", false, "Store the hash table sorted by hash (faster duplicate and hash lookups).")
+	updateCmd.Flags().BoolVar(&updateArchives, "archives", false, "Record the members of .tar, .tar.gz and .zip archives and .iso images as virtual entries (e.g. backup.tar!/dir/file).")
+	updateCmd.Flags().BoolVar(&updateSkipUnreadable, "skip-unreadable", false, "Record directories and files that can't be read due to permissions and continue scanning.")
 
 	addPathFilteringFlags(updateCmd)
 }
 
 var (
-	keepCopyPath string
+	keepCopyPath  string
+	updateForce   bool
+	updateNoCache bool
+
+	updateSkipUnreadable bool
+	updateSorted         bool
+	updateSortHashes     bool
+	updateArchives       bool
 )
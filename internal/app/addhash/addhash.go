@@ -0,0 +1,196 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package addhash provides the functionality for ajfs add-hash command.
+package addhash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"path/filepath"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/archive"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/schollz/progressbar/v3"
+)
+
+// Config for the ajfs add-hash command.
+type Config struct {
+	config.CommonConfig
+
+	Algo          ajhash.Algo // Hashing algorithm of the additional hash table.
+	Force         bool        // Add the hash table even if the database has been sealed.
+	HashCachePath string      // Path to the cache used to reuse previously calculated hashes. Empty disables the cache.
+
+	hashFn hashFn // Hashing function
+}
+
+// The hashing function to be used for calculating file signature hashes.
+type hashFn func(ctx context.Context, path string, hasher hash.Hash, w io.Writer) ([]byte, uint64, error)
+
+// Process the ajfs add-hash command.
+func Run(ctx context.Context, cfg Config) error {
+	if cfg.hashFn == nil {
+		hasher := archive.NewHasher()
+		defer hasher.Close()
+		cfg.hashFn = hasher.Hash
+	}
+
+	if err := db.EnsureNotSealed(cfg.DbPath, cfg.Force); err != nil {
+		return err
+	}
+
+	dbf, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+	defer dbf.Close()
+
+	if !dbf.Features().HasHashTable() {
+		return fmt.Errorf("the database %q does not contain a hash table. use \"ajfs scan --hash\" or \"ajfs convert --hash\" first", cfg.DbPath)
+	}
+
+	primaryAlgo, err := dbf.HashTableAlgo()
+	if err != nil {
+		return err
+	}
+	if cfg.Algo == primaryAlgo {
+		return fmt.Errorf("the hash table of %q already uses %s", cfg.DbPath, db.AlgoString(cfg.Algo))
+	}
+
+	// Only the files that have not been hashed using the algorithm yet need to be hashed
+	table, err := dbf.ReadHashTableWithAlgo(ctx, cfg.Algo)
+	if err != nil {
+		if !errors.Is(err, db.ErrHashTableNotFound) {
+			return err
+		}
+		table = make(db.HashTable, dbf.EntriesCount())
+	}
+
+	todo := make([]int, 0, 512)
+	err = dbf.ReadHashTableEntries(ctx, func(idx int, hash []byte) error {
+		if _, exists := table[idx]; !exists {
+			todo = append(todo, idx)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	cfg.VerbosePrintln(fmt.Sprintf("Adding the %s hash table to %q", db.AlgoString(cfg.Algo), cfg.DbPath))
+	cfg.VerbosePrintln(fmt.Sprintf("Still need to process %d files", len(todo)))
+
+	err = calculateHashes(ctx, cfg, dbf, todo, table)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	interrupted := (err != nil)
+
+	if err = dbf.Close(); err != nil {
+		return err
+	}
+
+	// The hashes calculated thus far are kept when interrupted so that the next run only needs to do the rest
+	if err = db.AddHashTable(context.WithoutCancel(ctx), cfg.DbPath, cfg.Algo, table); err != nil {
+		return err
+	}
+
+	if interrupted {
+		cfg.VerbosePrintln("App was interrupted.")
+		return nil
+	}
+
+	cfg.VerbosePrintln("Done!")
+	return nil
+}
+
+// Calculate the hashes of the files at the indices and add them to the table.
+func calculateHashes(ctx context.Context, cfg Config, dbf *db.DatabaseFile, indices []int, table db.HashTable) error {
+	defer cfg.StartPhase(fmt.Sprintf("calculating %s file signatures", db.AlgoString(cfg.Algo)))()
+
+	cache, err := cfg.OpenHashCache(cfg.HashCachePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := cache.Close(); err != nil {
+			cfg.Errorln(err)
+		}
+	}()
+
+	var progress *progressbar.ProgressBar
+	if cfg.Progress {
+		progress = progressbar.Default(int64(len(indices)))
+	}
+
+	failed := 0
+	for _, idx := range indices {
+		if err := ctx.Err(); err != nil {
+			if progress != nil {
+				_ = progress.Exit()
+			}
+			return err
+		}
+
+		pi, err := dbf.ReadEntryAtIndex(idx)
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dbf.RootPath(), pi.Path)
+		if progress == nil {
+			cfg.VerbosePrintln(fmt.Sprintf("Hashing %q", pi.Path))
+		}
+
+		hash, ok := cache.Lookup(path, pi.Size, pi.ModTime, cfg.Algo)
+		if !ok {
+			hash, _, err = cfg.hashFn(ctx, path, db.AlgoHasher(cfg.Algo), nil)
+			if err != nil {
+				if errors.Is(err, context.Canceled) {
+					return err
+				}
+
+				// Continue hashing, the file will be tried again the next time
+				fmt.Fprintf(cfg.Stderr, "failed to calculate the hash for %q. %v\n", path, err)
+				failed++
+			} else if err = cache.Add(path, pi.Size, pi.ModTime, cfg.Algo, hash); err != nil {
+				return err
+			}
+		}
+
+		if err == nil {
+			table[idx] = hash
+		}
+		if progress != nil {
+			_ = progress.Add(1)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(cfg.Stderr, "Failed to calculate the hash for %d files, run \"ajfs add-hash\" again to retry them\n", failed)
+	}
+
+	return nil
+}
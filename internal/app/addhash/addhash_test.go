@@ -0,0 +1,118 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package addhash_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/addhash"
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "1.txt"), []byte("1"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "2.txt"), []byte("22"), 0644))
+
+	commonConfig := config.CommonConfig{
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+		DbPath: filepath.Join(t.TempDir(), "unit-test.ajfs"),
+	}
+	require.NoError(t, scan.Run(context.Background(), scan.Config{
+		CommonConfig:    commonConfig,
+		Root:            root,
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}))
+
+	cfg := addhash.Config{
+		CommonConfig: commonConfig,
+		Algo:         ajhash.AlgoSHA1,
+	}
+
+	// The hash table already uses SHA-1
+	assert.Error(t, addhash.Run(context.Background(), cfg))
+
+	cfg.Algo = ajhash.AlgoSHA256
+	require.NoError(t, addhash.Run(context.Background(), cfg))
+
+	// Running it again keeps the hashes
+	require.NoError(t, addhash.Run(context.Background(), cfg))
+
+	dbf, err := db.OpenDatabase(commonConfig.DbPath)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	algos, err := dbf.HashTableAlgos(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []ajhash.Algo{ajhash.AlgoSHA1, ajhash.AlgoSHA256}, algos)
+
+	table, err := dbf.ReadHashTableWithAlgo(context.Background(), ajhash.AlgoSHA256)
+	require.NoError(t, err)
+	assert.Len(t, table, 2)
+
+	err = dbf.ReadAllEntries(context.Background(), func(idx int, pi path.Info) error {
+		if !pi.IsFile() {
+			return nil
+		}
+		data, err := os.ReadFile(filepath.Join(root, pi.Path))
+		require.NoError(t, err)
+		exp := sha256.Sum256(data)
+		assert.Equal(t, exp[:], table[idx])
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestRunSealed(t *testing.T) {
+	commonConfig := config.CommonConfig{
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+		DbPath: filepath.Join(t.TempDir(), "unit-test.ajfs"),
+	}
+	require.NoError(t, scan.Run(context.Background(), scan.Config{
+		CommonConfig:    commonConfig,
+		Root:            t.TempDir(),
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}))
+	require.NoError(t, db.SetSealed(commonConfig.DbPath, true))
+
+	cfg := addhash.Config{
+		CommonConfig: commonConfig,
+		Algo:         ajhash.AlgoSHA256,
+	}
+	assert.Error(t, addhash.Run(context.Background(), cfg))
+
+	cfg.Force = true
+	assert.NoError(t, addhash.Run(context.Background(), cfg))
+}
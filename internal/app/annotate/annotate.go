@@ -0,0 +1,222 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package annotate provides the functionality for ajfs annotate command.
+package annotate
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+)
+
+// Config for the ajfs annotate command.
+type Config struct {
+	config.CommonConfig
+
+	Label  string   // The label to give, one of reviewed, keep or delete-candidate.
+	Paths  []string // Paths of the entries (relative to the root) to annotate.
+	Groups []string // File signature hashes (hex) of the duplicate groups to annotate.
+	Clear  bool     // Remove the annotations of the paths and groups instead.
+	List   bool     // Display all the annotations.
+	Force  bool     // Annotate the database even if it has been sealed.
+}
+
+// Process the ajfs annotate command.
+func Run(ctx context.Context, cfg Config) error {
+	if cfg.List {
+		return list(cfg)
+	}
+
+	if len(cfg.Paths) == 0 && len(cfg.Groups) == 0 {
+		return fmt.Errorf("at least one path or duplicate group is required to annotate")
+	}
+
+	var label db.Label
+	if !cfg.Clear {
+		var err error
+		if label, err = db.ParseLabel(cfg.Label); err != nil {
+			return err
+		}
+	}
+
+	if err := db.EnsureNotSealed(cfg.DbPath, cfg.Force); err != nil {
+		return err
+	}
+
+	dbf, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+	defer dbf.Close()
+
+	annotations, err := dbf.ReadAnnotations()
+	if err != nil {
+		return err
+	}
+
+	ids := make([]path.Id, 0, len(cfg.Paths))
+	for _, p := range cfg.Paths {
+		id := dbf.IdFromPath(filepath.Clean(p))
+		if _, err := dbf.FindEntryIndexAndOffset(id); err != nil {
+			return fmt.Errorf("failed to find the path %q in the database %q. %w", p, cfg.DbPath, err)
+		}
+		ids = append(ids, id)
+	}
+
+	groups := make([]string, 0, len(cfg.Groups))
+	if len(cfg.Groups) > 0 {
+		groups, err = findGroups(ctx, dbf, cfg.Groups)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = dbf.Close(); err != nil {
+		return err
+	}
+
+	a := db.Annotation{Label: label, Time: time.Now()}
+
+	for _, id := range ids {
+		if cfg.Clear {
+			delete(annotations.Entries, id)
+		} else {
+			annotations.Entries[id] = a
+		}
+	}
+	for _, hash := range groups {
+		if cfg.Clear {
+			delete(annotations.Groups, hash)
+		} else {
+			annotations.Groups[hash] = a
+		}
+	}
+
+	if err = db.SetAnnotations(cfg.DbPath, annotations); err != nil {
+		return err
+	}
+
+	if cfg.Clear {
+		cfg.VerbosePrintln(fmt.Sprintf("Cleared the annotations of %d paths and %d groups", len(ids), len(groups)))
+	} else {
+		cfg.VerbosePrintln(fmt.Sprintf("Annotated %d paths and %d groups as %s", len(ids), len(groups), label))
+	}
+	return nil
+}
+
+// Return the hashes (lowercase hex) after checking that each is a file signature hash in the hash table or the
+// identifier of a group of potential duplicates (see [db.PotentialGroupId]).
+func findGroups(ctx context.Context, dbf *db.DatabaseFile, hashes []string) ([]string, error) {
+	if !dbf.Features().HasHashTable() {
+		return nil, fmt.Errorf("require file signature hashes to be present in the database %q", dbf.Path())
+	}
+
+	table, err := dbf.ReadHashTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]struct{}, len(table))
+	for _, hash := range table {
+		known[hex.EncodeToString(hash)] = struct{}{}
+	}
+
+	// Only calculated when a hash is not found in the hash table
+	var potential map[string]struct{}
+
+	result := make([]string, 0, len(hashes))
+	for _, h := range hashes {
+		hash := strings.ToLower(h)
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("invalid file signature hash %q. %w", h, err)
+		}
+		if _, exists := known[hash]; !exists {
+			if potential == nil {
+				if potential, err = potentialGroups(ctx, dbf); err != nil {
+					return nil, err
+				}
+			}
+			if _, exists = potential[hash]; !exists {
+				return nil, fmt.Errorf("failed to find the file signature hash %q in the database %q", h, dbf.Path())
+			}
+		}
+		result = append(result, hash)
+	}
+	return result, nil
+}
+
+// Return the identifiers of the groups of potential duplicates.
+func potentialGroups(ctx context.Context, dbf *db.DatabaseFile) (map[string]struct{}, error) {
+	result := make(map[string]struct{}, 16)
+	err := dbf.FindPotentialDuplicates(ctx, func(group string, idx int, pi path.Info, hash string) error {
+		result[group] = struct{}{}
+		return nil
+	})
+	return result, err
+}
+
+// Display all the annotations, the groups first followed by the paths (sorted).
+func list(cfg Config) error {
+	dbf, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+	defer dbf.Close()
+	cfg.WarnIfLimited(dbf)
+
+	annotations, err := dbf.ReadAnnotations()
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range slices.Sorted(maps.Keys(annotations.Groups)) {
+		a := annotations.Groups[hash]
+		cfg.Println(fmt.Sprintf("%-16s %s group %s", a.Label, a.Time.Format(time.DateTime), hash))
+	}
+
+	type entry struct {
+		path string
+		a    db.Annotation
+	}
+	entries := make([]entry, 0, len(annotations.Entries))
+	for id, a := range annotations.Entries {
+		pi, err := dbf.ReadEntryById(id)
+		if err != nil {
+			return fmt.Errorf("failed to find the annotated path with identifier %x. %w", id, err)
+		}
+		entries = append(entries, entry{path: pi.Path, a: a})
+	}
+	slices.SortFunc(entries, func(x, y entry) int {
+		return strings.Compare(x.path, y.path)
+	})
+
+	for _, e := range entries {
+		cfg.Println(fmt.Sprintf("%-16s %s path  %s", e.a.Label, e.a.Time.Format(time.DateTime), e.path))
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package annotate_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/annotate"
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/app/seal"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.ajfs")
+
+	commonCfg := config.CommonConfig{
+		DbPath: dbPath,
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+
+	scanCfg := scan.Config{
+		CommonConfig:    commonCfg,
+		Root:            "../../testdata/scan",
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	const hash = "e3d157020b35944b552ba9987eb668228c073d30"
+
+	cfg := annotate.Config{
+		CommonConfig: commonCfg,
+		Label:        "reviewed",
+		Groups:       []string{"E3D157020B35944B552BA9987EB668228C073D30"},
+	}
+	require.NoError(t, annotate.Run(context.Background(), cfg))
+
+	cfg.Label = "keep"
+	cfg.Groups = nil
+	cfg.Paths = []string{"a/a2/same-as-1.txt"}
+	require.NoError(t, annotate.Run(context.Background(), cfg))
+
+	cfg.Label = "delete-candidate"
+	cfg.Paths = []string{"b/b1/b1a/same-as-1.txt", "./1.txt"}
+	require.NoError(t, annotate.Run(context.Background(), cfg))
+
+	list := func() string {
+		var outBuffer bytes.Buffer
+		listCfg := annotate.Config{
+			CommonConfig: commonCfg,
+			List:         true,
+		}
+		listCfg.Stdout = &outBuffer
+		require.NoError(t, annotate.Run(context.Background(), listCfg))
+
+		// The times at which the annotations were made are not compared
+		return regexp.MustCompile(`\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}`).ReplaceAllString(outBuffer.String(), "<time>")
+	}
+
+	expected := `reviewed         <time> group ` + hash + `
+delete-candidate <time> path  1.txt
+keep             <time> path  a/a2/same-as-1.txt
+delete-candidate <time> path  b/b1/b1a/same-as-1.txt
+`
+	assert.Equal(t, expected, list())
+
+	// Clear
+	cfg.Label = ""
+	cfg.Clear = true
+	cfg.Paths = []string{"1.txt"}
+	cfg.Groups = []string{hash}
+	require.NoError(t, annotate.Run(context.Background(), cfg))
+
+	expected = `keep             <time> path  a/a2/same-as-1.txt
+delete-candidate <time> path  b/b1/b1a/same-as-1.txt
+`
+	assert.Equal(t, expected, list())
+
+	// Unknown paths, groups and labels
+	cfg.Clear = false
+	cfg.Label = "keep"
+	cfg.Groups = nil
+	cfg.Paths = []string{"not-found.txt"}
+	assert.ErrorIs(t, annotate.Run(context.Background(), cfg), db.ErrNotFound)
+
+	cfg.Paths = nil
+	cfg.Groups = []string{"0000000000000000000000000000000000000000"}
+	assert.Error(t, annotate.Run(context.Background(), cfg))
+
+	cfg.Groups = []string{"not-a-hash"}
+	assert.Error(t, annotate.Run(context.Background(), cfg))
+
+	cfg.Groups = []string{hash}
+	cfg.Label = "maybe"
+	assert.Error(t, annotate.Run(context.Background(), cfg))
+
+	cfg.Groups = nil
+	cfg.Label = "keep"
+	assert.Error(t, annotate.Run(context.Background(), cfg))
+
+	// Sealed
+	require.NoError(t, seal.Run(context.Background(), seal.Config{CommonConfig: commonCfg}))
+	cfg.Paths = []string{"1.txt"}
+	assert.ErrorIs(t, annotate.Run(context.Background(), cfg), db.ErrSealed)
+
+	cfg.Force = true
+	require.NoError(t, annotate.Run(context.Background(), cfg))
+	assert.Contains(t, list(), "keep             <time> path  1.txt\n")
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package cache provides the functionality for ajfs cache command.
+package cache
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/hashcache"
+)
+
+// Config for the ajfs cache command.
+type Config struct {
+	config.CommonConfig
+
+	CachePath string // Path to the hash cache file.
+}
+
+// Display information about the hash cache.
+func Info(ctx context.Context, cfg Config) error {
+	c, err := cfg.OpenHashCache(cfg.CachePath)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	cfg.Println(fmt.Sprintf("Cache path: %s", cfg.CachePath))
+	cfg.Println(fmt.Sprintf("Hashes:     %d", c.Count()))
+	return nil
+}
+
+// Remove the hashes for files that no longer exist or that have changed.
+func Prune(ctx context.Context, cfg Config) error {
+	c, err := cfg.OpenHashCache(cfg.CachePath)
+	if err != nil {
+		return err
+	}
+
+	removed, err := c.Prune(ctx)
+	if err != nil {
+		_ = c.Close()
+		return err
+	}
+
+	if err = c.Close(); err != nil {
+		return err
+	}
+
+	cfg.Println(fmt.Sprintf("Removed %d hashes, %d remaining", removed, c.Count()))
+	return nil
+}
+
+// Remove all the hashes from the cache.
+func Clear(ctx context.Context, cfg Config) error {
+	if err := hashcache.Remove(cfg.CachePath); err != nil {
+		return err
+	}
+
+	cfg.VerbosePrintln(fmt.Sprintf("Removed %q", cfg.CachePath))
+	return nil
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package catalog provides the functionality for ajfs catalog command.
+package catalog
+
+import (
+	"fmt"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/catalog"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/go-aj/file"
+)
+
+// Config for the ajfs catalog command.
+// DbPath is the path to the catalog file.
+type Config struct {
+	config.CommonConfig
+
+	DbPaths []string // Databases to be added to the catalog.
+	Name    string   // Name of the volume, only valid when adding a single database.
+	Names   []string // Names of the volumes to be removed.
+
+	ForceOverride bool // Override any existing catalog file.
+}
+
+// Create a new catalog containing the databases.
+func Create(cfg Config) error {
+	exists, err := file.FileExists(cfg.DbPath)
+	if err != nil {
+		return fmt.Errorf("failed to create the catalog. %w", err)
+	}
+	if exists && !cfg.ForceOverride {
+		return fmt.Errorf("failed to create the catalog because a file already exists at %q", cfg.DbPath)
+	}
+
+	c := catalog.New(cfg.DbPath)
+	if err := addDatabases(cfg, c); err != nil {
+		return err
+	}
+
+	return c.Save()
+}
+
+// Add the databases to an existing catalog.
+func Add(cfg Config) error {
+	c, err := catalog.Load(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+
+	if err := addDatabases(cfg, c); err != nil {
+		return err
+	}
+
+	return c.Save()
+}
+
+// Remove the volumes from an existing catalog.
+// NOTE: The databases themselves are not deleted.
+func Remove(cfg Config) error {
+	c, err := catalog.Load(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range cfg.Names {
+		if err := c.Remove(name); err != nil {
+			return err
+		}
+		cfg.VerbosePrintln(fmt.Sprintf("Removed %q", name))
+	}
+
+	return c.Save()
+}
+
+// Display the volumes in the catalog.
+func List(cfg Config) error {
+	c, err := catalog.Load(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range c.Volumes {
+		dbPath := c.DatabasePath(v)
+
+		dbf, err := db.OpenDatabase(dbPath)
+		if err != nil {
+			cfg.Println(fmt.Sprintf("%s: %s [unavailable]", v.Name, dbPath))
+			cfg.VerbosePrintln(fmt.Sprintf("  %v", err))
+			continue
+		}
+
+		cfg.Println(fmt.Sprintf("%s: %s [%d entries, root %q]", v.Name, dbPath, dbf.EntriesCount(), dbf.RootPath()))
+		if err = dbf.Close(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func addDatabases(cfg Config, c *catalog.Catalog) error {
+	if len(cfg.DbPaths) == 0 {
+		return fmt.Errorf("expected at least one database to be added to the catalog")
+	}
+	if (cfg.Name != "") && (len(cfg.DbPaths) > 1) {
+		return fmt.Errorf("a name can only be specified when adding a single database")
+	}
+
+	for _, dbPath := range cfg.DbPaths {
+		// Ensure it is a valid database
+		dbf, err := db.OpenDatabase(dbPath)
+		if err != nil {
+			return err
+		}
+		if err = dbf.Close(); err != nil {
+			return err
+		}
+
+		if err = c.Add(cfg.Name, dbPath); err != nil {
+			return err
+		}
+
+		v := c.Volumes[len(c.Volumes)-1]
+		cfg.VerbosePrintln(fmt.Sprintf("Added %q as %q", dbPath, v.Name))
+	}
+
+	return nil
+}
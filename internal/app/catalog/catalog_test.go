@@ -0,0 +1,85 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package catalog_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/catalog"
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalog(t *testing.T) {
+	tempDir := t.TempDir()
+
+	dbPath := filepath.Join(tempDir, "laptop.ajfs")
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: dbPath,
+		},
+		Root: "../../testdata/scan/a",
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	var outBuffer bytes.Buffer
+	cfg := catalog.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+			DbPath: filepath.Join(tempDir, "backups.ajfscat"),
+		},
+		DbPaths: []string{dbPath},
+	}
+
+	require.NoError(t, catalog.Create(cfg))
+	assert.Error(t, catalog.Create(cfg))
+
+	// Only valid databases can be added
+	cfg.DbPaths = []string{filepath.Join(tempDir, "missing.ajfs")}
+	assert.Error(t, catalog.Add(cfg))
+
+	cfg.DbPaths = []string{dbPath}
+	cfg.Name = "offsite"
+	require.NoError(t, catalog.Add(cfg))
+
+	// Database can't be found
+	require.NoError(t, os.Rename(dbPath, filepath.Join(tempDir, "moved.ajfs")))
+	require.NoError(t, catalog.List(cfg))
+	assert.Equal(t, "laptop: "+dbPath+" [unavailable]\noffsite: "+dbPath+" [unavailable]\n", outBuffer.String())
+
+	cfg.Names = []string{"laptop"}
+	require.NoError(t, catalog.Remove(cfg))
+	assert.Error(t, catalog.Remove(cfg))
+
+	require.NoError(t, os.Rename(filepath.Join(tempDir, "moved.ajfs"), dbPath))
+	outBuffer.Reset()
+	require.NoError(t, catalog.List(cfg))
+	assert.Contains(t, outBuffer.String(), "offsite: "+dbPath+" [14 entries")
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package check provides the functionality for ajfs check command.
+package check
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+)
+
+// Each class of failure has its own error so that automated jobs can tell them apart.
+var (
+	ErrFormat    = errors.New("not a valid or supported ajfs database")
+	ErrChecksum  = errors.New("the database checksum verification failed")
+	ErrStructure = errors.New("the database structure needs to be fixed")
+	ErrHashTable = errors.New("the database hash table is inconsistent")
+)
+
+// Config for the ajfs check command.
+type Config struct {
+	config.CommonConfig
+}
+
+// Process the ajfs check command.
+// The checks are performed in order and the first failure is returned.
+func Run(ctx context.Context, cfg Config) error {
+	// The database file not existing is not a failure of the database itself
+	if _, err := os.Stat(cfg.DbPath); err != nil {
+		return err
+	}
+
+	// Signature and version ----------------------------------------
+	dbf, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		// A database that was not closed cleanly can't be opened but is a structural problem
+		if !errors.Is(err, db.ErrDirty) {
+			cfg.Println("Signature and version: FAILED")
+			return fmt.Errorf("%w. %w", ErrFormat, err)
+		}
+		cfg.Println("Signature and version: OK")
+		cfg.Println("Checksum: SKIPPED (database was not closed cleanly)")
+		return checkStructure(cfg)
+	}
+	defer dbf.Close()
+
+	if dbf.Limited() {
+		cfg.Println("Signature and version: FAILED")
+		return fmt.Errorf("%w. %s", ErrFormat, dbf.Warnings()[0])
+	}
+	cfg.Println(fmt.Sprintf("Signature and version: OK (version %d)", dbf.Version()))
+
+	// Checksum -----------------------------------------------------
+	if err := dbf.VerifyChecksums(); err != nil {
+		cfg.Println("Checksum: FAILED")
+		return fmt.Errorf("%w. %w", ErrChecksum, err)
+	}
+	cfg.Println(fmt.Sprintf("Checksum: OK (%s)", dbf.ChecksumAlgo()))
+
+	// Structure ----------------------------------------------------
+	if err := checkStructure(cfg); err != nil {
+		return err
+	}
+
+	// Hash table ---------------------------------------------------
+	if !dbf.Features().HasHashTable() {
+		cfg.Println("Hash table: SKIPPED (not present)")
+		return nil
+	}
+
+	hashed, err := checkHashTable(ctx, dbf)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+		cfg.Println("Hash table: FAILED")
+		return fmt.Errorf("%w. %w", ErrHashTable, err)
+	}
+	cfg.Println(fmt.Sprintf("Hash table: OK (%d of %d files hashed)", hashed, dbf.FileEntriesCount()))
+
+	return nil
+}
+
+// Perform the same structural analysis as "ajfs fix --dry-run".
+// The analysis is only displayed in verbose mode.
+func checkStructure(cfg Config) error {
+	var out io.Writer = io.Discard
+	if cfg.Verbose {
+		out = cfg.Stdout
+	}
+
+	if err := db.FixDatabase(out, cfg.DbPath, true, ""); err != nil {
+		cfg.Println("Structure: FAILED")
+		return fmt.Errorf("%w. %w", ErrStructure, err)
+	}
+	cfg.Println("Structure: OK")
+	return nil
+}
+
+// Check that every file has exactly one hash table entry and that a sorted hash table is in fact sorted.
+// Returns the number of files that have a calculated hash.
+func checkHashTable(ctx context.Context, dbf *db.DatabaseFile) (int, error) {
+	algo, err := dbf.HashTableAlgo()
+	if err != nil {
+		return 0, err
+	}
+
+	files := make(map[int]bool, dbf.FileEntriesCount())
+	err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		if pi.IsFile() {
+			files[idx] = false
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	zeroHash := db.AlgoZeroValue(algo)
+	sorted := dbf.HashTableSorted()
+	var prevHash []byte
+	hashed := 0
+
+	err = dbf.ReadHashTableEntries(ctx, func(idx int, hash []byte) error {
+		seen, isFile := files[idx]
+		if !isFile {
+			return fmt.Errorf("hash table entry for index %d is not a file", idx)
+		}
+		if seen {
+			return fmt.Errorf("hash table contains more than one entry for index %d", idx)
+		}
+		files[idx] = true
+
+		if len(hash) != len(zeroHash) {
+			return fmt.Errorf("hash table entry for index %d has %d bytes, expected %d for %s", idx, len(hash), len(zeroHash), db.AlgoString(algo))
+		}
+		if !bytes.Equal(hash, zeroHash) {
+			hashed++
+		}
+
+		if sorted && prevHash != nil && bytes.Compare(prevHash, hash) > 0 {
+			return fmt.Errorf("hash table is marked as sorted but the entry for index %d is out of order", idx)
+		}
+		prevHash = append(prevHash[:0], hash...)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for idx, seen := range files {
+		if !seen {
+			return 0, fmt.Errorf("file with index %d does not have a hash table entry", idx)
+		}
+	}
+
+	return hashed, nil
+}
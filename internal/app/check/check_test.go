@@ -0,0 +1,111 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package check_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/check"
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/testshared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "unit-test.ajfs")
+	testshared.ScanDatabase(t, dbPath, "../../testdata/scan", true)
+
+	var outBuffer bytes.Buffer
+	cfg := check.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+			DbPath: dbPath,
+		},
+	}
+	require.NoError(t, check.Run(context.Background(), cfg))
+
+	out := outBuffer.String()
+	assert.Contains(t, out, "Signature and version: OK")
+	assert.Contains(t, out, "Checksum: OK")
+	assert.Contains(t, out, "Structure: OK\n")
+	assert.Contains(t, out, "Hash table: OK")
+}
+
+func TestCheckInvalidFormat(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "not-ajfs")
+	require.NoError(t, os.WriteFile(dbPath, bytes.Repeat([]byte("not a database"), 100), 0644))
+
+	cfg := check.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: dbPath,
+		},
+	}
+	assert.ErrorIs(t, check.Run(context.Background(), cfg), check.ErrFormat)
+
+	// A missing file is not a failure of the database
+	cfg.DbPath = filepath.Join(t.TempDir(), "does-not-exist.ajfs")
+	err := check.Run(context.Background(), cfg)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, check.ErrFormat)
+}
+
+func TestCheckInvalidChecksum(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "unit-test.ajfs")
+	testshared.ScanDatabase(t, dbPath, "../../testdata/scan", true)
+
+	dbf, err := db.OpenDatabase(dbPath)
+	require.NoError(t, err)
+	sections, err := dbf.Sections()
+	require.NoError(t, err)
+	require.NoError(t, dbf.Close())
+
+	var entries db.Section
+	for _, s := range sections {
+		if s.Name == "entries" {
+			entries = s
+		}
+	}
+	require.NotZero(t, entries.Size)
+
+	// Corrupt a byte in the middle of the entries
+	data, err := os.ReadFile(dbPath)
+	require.NoError(t, err)
+	data[entries.Offset+entries.Size/2] ^= 0xFF
+	require.NoError(t, os.WriteFile(dbPath, data, 0644))
+
+	cfg := check.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: dbPath,
+		},
+	}
+	assert.ErrorIs(t, check.Run(context.Background(), cfg), check.ErrChecksum)
+}
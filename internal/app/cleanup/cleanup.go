@@ -0,0 +1,204 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package cleanup provides the functionality for ajfs cleanup command.
+package cleanup
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/search"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/human"
+)
+
+// Config for the ajfs cleanup command.
+type Config struct {
+	config.CommonConfig
+	config.UnderConfig
+
+	Expression     search.Expression // [optional] Only files that match the expression (e.g. size and last modification time).
+	OnlyDuplicates bool              // Only files that have a duplicate inside the same database.
+	BackupPath     string            // [optional] Only files that also exist in this database.
+
+	DisplayFullPaths bool // If true then each path entry will be prefixed with the root path of the database.
+	NullSeparated    bool // Separate the paths with a NUL character instead of a newline.
+}
+
+// Process the ajfs cleanup command.
+func Run(ctx context.Context, cfg Config) error {
+	if cfg.Expression == nil && !cfg.OnlyDuplicates && cfg.BackupPath == "" {
+		return fmt.Errorf("at least one criteria is required to find cleanup candidates")
+	}
+
+	dbf, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+	defer dbf.Close()
+	cfg.WarnIfLimited(dbf)
+	if err = search.Prepare(ctx, cfg.Expression, dbf); err != nil {
+		return err
+	}
+
+	if cfg.OnlyDuplicates && !dbf.Features().HasHashTable() {
+		return fmt.Errorf("require file signature hashes to be present in the database %q", cfg.DbPath)
+	}
+
+	var hashTable db.HashTable
+	if dbf.Features().HasHashTable() {
+		hashTable, err = dbf.ReadHashTable(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	var dupes db.DuplicateHashes
+	if cfg.OnlyDuplicates {
+		dupes, err = dbf.FindDuplicateHashes(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	var inBackup func(pi path.Info, hash []byte) bool
+	if cfg.BackupPath != "" {
+		inBackup, err = backupMatcher(ctx, dbf, cfg.BackupPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	count := 0
+	totalSize := uint64(0)
+
+	err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		if !pi.IsFile() || !cfg.IsUnder(pi.Path) {
+			return nil
+		}
+
+		hash := hashTable[idx]
+
+		if cfg.Expression != nil {
+			matched, err := cfg.Expression.Match(pi, hash)
+			if err != nil {
+				return err
+			}
+			if !matched {
+				return nil
+			}
+		}
+
+		// Empty files are not considered to be duplicates of each other
+		if cfg.OnlyDuplicates && (hash == nil || pi.Size == 0 || len(dupes[hex.EncodeToString(hash)]) < 2) {
+			return nil
+		}
+
+		if inBackup != nil && !inBackup(pi, hash) {
+			return nil
+		}
+
+		count++
+		totalSize += pi.Size
+
+		p := pi.Path
+		if cfg.DisplayFullPaths {
+			p = filepath.Join(dbf.RootPath(), p)
+		}
+
+		if cfg.NullSeparated {
+			fmt.Fprintf(cfg.Stdout, "%s\x00", p)
+		} else {
+			cfg.Println(p)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Stdout only contains the paths so that it can be used by scripts
+	fmt.Fprintf(cfg.Stderr, "Found %d cleanup candidates [%s]\n", count, human.Bytes(totalSize))
+	return nil
+}
+
+// Return a function that checks if a file also exists in the backup database.
+// Files are matched by their file signature hash when both databases were hashed with the same algorithm,
+// otherwise files are matched by their relative path and size.
+func backupMatcher(ctx context.Context, dbf *db.DatabaseFile, backupPath string) (func(pi path.Info, hash []byte) bool, error) {
+	backup, err := db.OpenDatabase(backupPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the backup database. %w", err)
+	}
+	defer backup.Close()
+
+	byHash, err := sameHashAlgo(dbf, backup)
+	if err != nil {
+		return nil, err
+	}
+
+	if byHash {
+		hashes, err := backup.BuildHashStrToIndexMap(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the backup database's hash table. %w", err)
+		}
+
+		return func(pi path.Info, hash []byte) bool {
+			if hash == nil {
+				return false
+			}
+			_, exists := hashes[hex.EncodeToString(hash)]
+			return exists
+		}, nil
+	}
+
+	entries, err := backup.BuildIdToInfoMap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the backup database's entries. %w", err)
+	}
+
+	return func(pi path.Info, hash []byte) bool {
+		other, exists := entries[backup.IdOf(dbf, pi)]
+		return exists && other.IsFile() && (other.Size == pi.Size)
+	}, nil
+}
+
+// Return true if both databases have file signature hashes calculated using the same algorithm.
+func sameHashAlgo(lhs *db.DatabaseFile, rhs *db.DatabaseFile) (bool, error) {
+	if !lhs.Features().HasHashTable() || !rhs.Features().HasHashTable() {
+		return false, nil
+	}
+
+	lhsAlgo, err := lhs.HashTableAlgo()
+	if err != nil {
+		return false, fmt.Errorf("failed to get the hashing algorithm of %q. %w", lhs.Path(), err)
+	}
+
+	rhsAlgo, err := rhs.HashTableAlgo()
+	if err != nil {
+		return false, fmt.Errorf("failed to get the hashing algorithm of %q. %w", rhs.Path(), err)
+	}
+
+	return lhsAlgo == rhsAlgo, nil
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package cleanup_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/cleanup"
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/search"
+	"github.com/andrejacobs/ajfs/internal/testshared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := testshared.ScanDatabase(t, filepath.Join(tempDir, "db.ajfs"), "../../testdata/scan", true)
+	backupHashesPath := testshared.ScanDatabase(t, filepath.Join(tempDir, "backup-hashes.ajfs"), "../../testdata/scan/a", true)
+	backupNoHashesPath := testshared.ScanDatabase(t, filepath.Join(tempDir, "backup-no-hashes.ajfs"), "../../testdata/scan", false)
+
+	testCases := []struct {
+		desc       string
+		size       string
+		dupes      bool
+		backupPath string
+		expected   []string
+	}{
+		{
+			desc:  "duplicates",
+			dupes: true,
+			expected: []string{
+				"1.txt",
+				"a/a1/a1a/a1a1/1.txt",
+				"a/a2/same-as-1.txt",
+				"b/b1/b1a/1.txt",
+				"b/b1/b1a/same-as-1.txt",
+			},
+		},
+		{
+			desc: "size",
+			size: "+600",
+			expected: []string{
+				"a/2.txt",
+				"a/a2/6.txt",
+				"b/b1/b1a/7.txt",
+				"c/c.txt",
+			},
+		},
+		{
+			desc:       "size and in backup by hash",
+			size:       "+490",
+			backupPath: backupHashesPath,
+			expected: []string{
+				"a/2.txt",
+				"a/a1/a1a/a1a1/4.txt",
+				"a/a2/6.txt",
+			},
+		},
+		{
+			desc:       "size and in backup by path",
+			size:       "+900",
+			backupPath: backupNoHashesPath,
+			expected: []string{
+				"b/b1/b1a/7.txt",
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			cfg := cleanup.Config{
+				CommonConfig: config.CommonConfig{
+					DbPath: dbPath,
+					Stdout: &buf,
+					Stderr: io.Discard,
+				},
+				OnlyDuplicates: tC.dupes,
+				BackupPath:     tC.backupPath,
+			}
+
+			if tC.size != "" {
+				exp, err := search.NewSize(tC.size)
+				require.NoError(t, err)
+				cfg.Expression = exp
+			}
+
+			require.NoError(t, cleanup.Run(context.Background(), cfg))
+
+			result := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			slices.Sort(result)
+			assert.Equal(t, tC.expected, result)
+		})
+	}
+}
+
+func TestRunNullSeparated(t *testing.T) {
+	dbPath := testshared.ScanDatabase(t, filepath.Join(t.TempDir(), "db.ajfs"), "../../testdata/scan", false)
+
+	exp, err := search.NewSize("+900")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	cfg := cleanup.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: dbPath,
+			Stdout: &buf,
+			Stderr: io.Discard,
+		},
+		Expression:       exp,
+		DisplayFullPaths: true,
+		NullSeparated:    true,
+	}
+
+	require.NoError(t, cleanup.Run(context.Background(), cfg))
+
+	root, err := filepath.Abs("../../testdata/scan")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "b/b1/b1a/7.txt")+"\x00", buf.String())
+}
+
+func TestRunRequiresCriteria(t *testing.T) {
+	dbPath := testshared.ScanDatabase(t, filepath.Join(t.TempDir(), "db.ajfs"), "../../testdata/scan", false)
+
+	cfg := cleanup.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: dbPath,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+	}
+	assert.Error(t, cleanup.Run(context.Background(), cfg))
+
+	cfg.OnlyDuplicates = true
+	assert.ErrorContains(t, cleanup.Run(context.Background(), cfg), "require file signature hashes")
+}
@@ -25,7 +25,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/hashcache"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/ajfs/internal/style"
 	"github.com/andrejacobs/go-aj/file"
 )
 
@@ -34,9 +40,14 @@ type CommonConfig struct {
 	DbPath   string // Path to the database file.
 	Verbose  bool   // Output verbose information to Stdout.
 	Progress bool   // Output progression information to Stdout.
+	UTC      bool   // Display and export times in UTC instead of the time zone in which they were recorded.
+	Styled   bool   // Use colors and aligned columns because Stdout is an interactive terminal (see the style package).
 
 	Stdout io.Writer // Writer used for standard out
 	Stderr io.Writer // Writer used for standard error
+
+	Stats   *Stats   // [optional] Records the timing of each phase when not nil.
+	Metrics *Metrics // [optional] Records the progress of scanning and hashing when not nil.
 }
 
 // Initialize with defaults.
@@ -62,6 +73,42 @@ func (c *CommonConfig) Errorln(a ...any) {
 	fmt.Fprintln(c.Stderr, a...)
 }
 
+// Write a warning to Stderr for each reason why the database can only be processed in a limited way.
+func (c *CommonConfig) WarnIfLimited(dbf *db.DatabaseFile) {
+	for _, w := range dbf.Warnings() {
+		fmt.Fprintf(c.Stderr, "WARNING: %s\n", w)
+	}
+}
+
+// Open the hash cache (see [hashcache.Open]) and write a warning to Stderr for each problem found while loading it.
+func (c *CommonConfig) OpenHashCache(path string) (*hashcache.Cache, error) {
+	cache, err := hashcache.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range cache.Warnings() {
+		fmt.Fprintf(c.Stderr, "WARNING: %s\n", w)
+	}
+	return cache, nil
+}
+
+// Return the time as it should be displayed or exported.
+// Times are kept in the time zone in which they were recorded unless UTC is enabled.
+func (c *CommonConfig) DisplayTime(t time.Time) time.Time {
+	if c.UTC {
+		return t.UTC()
+	}
+	return t
+}
+
+// Return the text in the color when styled output is used, else the text is returned as is.
+func (c *CommonConfig) Paint(color style.Color, s string) string {
+	if !c.Styled {
+		return s
+	}
+	return style.Paint(color, s)
+}
+
 // If Progress is enabled then output to Stdout else output using VerbosePrintln.
 func (c *CommonConfig) ProgressPrintln(a ...any) {
 	if c.Progress {
@@ -81,3 +128,51 @@ type FilterConfig struct {
 	DirExcluder  file.MatchPathFn // Determine which directories should not be walked
 	FileExcluder file.MatchPathFn // Determine which files should not be walked
 }
+
+//-----------------------------------------------------------------------------
+
+// Config used to restrict processing to the entries at or below a subpath inside the database.
+type UnderConfig struct {
+	Under string // Relative path inside the database. Empty means all entries will be processed.
+}
+
+// Return true if the path entry is at or below the Under path or if no Under path was specified.
+func (c *UnderConfig) IsUnder(p string) bool {
+	if c.Under == "" {
+		return true
+	}
+	return path.IsUnder(p, filepath.Clean(c.Under))
+}
+
+// Create the config used to restrict processing to the entries at or below the under path.
+// The under path can either be relative to the root of the database or be an absolute path
+// at or below the root path of the database (which will be made relative to the root).
+// An error is returned if the under path is outside of the root.
+func NewUnderConfig(under string, rootPath string) (UnderConfig, error) {
+	if under == "" {
+		return UnderConfig{}, nil
+	}
+
+	var clean string
+	var err error
+	if filepath.IsAbs(under) {
+		var rel string
+		rel, err = filepath.Rel(rootPath, under)
+		if err == nil {
+			clean, err = path.Normalize(rel)
+		}
+		if err != nil {
+			return UnderConfig{}, fmt.Errorf("%w %q. the path is not inside the root %q of the database", path.ErrInvalidPath, under, rootPath)
+		}
+	} else {
+		clean, err = path.Normalize(under)
+		if err != nil {
+			return UnderConfig{}, err
+		}
+	}
+	if clean == "." {
+		clean = ""
+	}
+
+	return UnderConfig{Under: clean}, nil
+}
@@ -22,10 +22,14 @@ package config_test
 
 import (
 	"bytes"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/path"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPrintln(t *testing.T) {
@@ -85,3 +89,87 @@ func TestProgressPrintln(t *testing.T) {
 	cfg.ProgressPrintln(expected)
 	assert.Equal(t, expected+"\n", buffer.String())
 }
+
+func TestDisplayTime(t *testing.T) {
+	tm := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("SAST", 2*60*60))
+
+	cfg := config.CommonConfig{}
+	assert.Equal(t, "2024-01-02T03:04:05+02:00", cfg.DisplayTime(tm).Format(time.RFC3339))
+
+	cfg.UTC = true
+	assert.Equal(t, "2024-01-02T01:04:05Z", cfg.DisplayTime(tm).Format(time.RFC3339))
+}
+
+func TestUnderConfig(t *testing.T) {
+	cfg := config.UnderConfig{}
+	assert.True(t, cfg.IsUnder("a/b"))
+
+	cfg.Under = "./a/"
+	assert.True(t, cfg.IsUnder("a"))
+	assert.True(t, cfg.IsUnder("a/b"))
+	assert.False(t, cfg.IsUnder("."))
+	assert.False(t, cfg.IsUnder("ab"))
+}
+
+func TestNewUnderConfig(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "data")
+
+	testCases := []struct {
+		desc     string
+		under    string
+		expected string
+	}{
+		{desc: "Empty", under: "", expected: ""},
+		{desc: "Relative", under: "./photos/2024/", expected: filepath.Join("photos", "2024")},
+		{desc: "Root", under: ".", expected: ""},
+		{desc: "Absolute", under: filepath.Join(root, "photos"), expected: "photos"},
+		{desc: "Absolute root", under: root + string(filepath.Separator), expected: ""},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			cfg, err := config.NewUnderConfig(tC.under, root)
+			require.NoError(t, err)
+			assert.Equal(t, tC.expected, cfg.Under)
+		})
+	}
+
+	for _, under := range []string{
+		filepath.Join(string(filepath.Separator), "photos"),
+		filepath.Join(root+"2", "photos"),
+	} {
+		_, err := config.NewUnderConfig(under, root)
+		require.ErrorIs(t, err, path.ErrInvalidPath, under)
+		assert.ErrorContains(t, err, "is not inside the root")
+	}
+
+	for _, under := range []string{
+		filepath.Join("..", "x"),
+		filepath.Join("a", "..", "..", "x"),
+	} {
+		_, err := config.NewUnderConfig(under, root)
+		require.ErrorIs(t, err, path.ErrInvalidPath, under)
+		assert.ErrorContains(t, err, "outside of the root")
+	}
+}
+
+func TestStartPhase(t *testing.T) {
+	cfg := config.CommonConfig{}
+
+	// Not recording
+	cfg.StartPhase("ignored")()
+
+	cfg.Stats = &config.Stats{}
+	done := cfg.StartPhase("first")
+	done()
+	cfg.StartPhase("second")()
+
+	var buffer bytes.Buffer
+	cfg.Stats.Print(&buffer, time.Second)
+
+	out := buffer.String()
+	assert.NotContains(t, out, "ignored")
+	assert.Contains(t, out, "  first:")
+	assert.Contains(t, out, "  second:")
+	assert.Contains(t, out, "  total:                         1s\n")
+	assert.Contains(t, out, "  peak RSS:")
+}
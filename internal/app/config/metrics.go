@@ -0,0 +1,195 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics exposes the progress of long running commands in the Prometheus
+// text format (see the --metrics flag).
+// All methods are safe to be called on a nil *Metrics, in which case nothing is recorded.
+type Metrics struct {
+	entriesScanned atomic.Uint64
+	filesHashed    atomic.Uint64
+	bytesHashed    atomic.Uint64
+	errors         atomic.Uint64
+
+	filesToHash  atomic.Uint64
+	bytesToHash  atomic.Uint64
+	bytesDone    atomic.Uint64 // Includes the files that failed or were found in the cache
+	hashingStart atomic.Int64  // Unix time in nanoseconds, zero until hashing started
+}
+
+// Record that an entry was scanned.
+func (m *Metrics) EntryScanned() {
+	if m == nil {
+		return
+	}
+	m.entriesScanned.Add(1)
+}
+
+// Record the amount of work that needs to be done while calculating the file signature hashes.
+func (m *Metrics) StartHashing(files uint64, bytes uint64) {
+	if m == nil {
+		return
+	}
+	m.filesToHash.Store(files)
+	m.bytesToHash.Store(bytes)
+	m.bytesDone.Store(0)
+	m.hashingStart.Store(time.Now().UnixNano())
+}
+
+// Record that the file signature hash was calculated (or reused from the cache) for a file.
+func (m *Metrics) FileHashed(size uint64) {
+	if m == nil {
+		return
+	}
+	m.filesHashed.Add(1)
+	m.bytesHashed.Add(size)
+	m.bytesDone.Add(size)
+}
+
+// Record that the file signature hash could not be calculated for a file.
+func (m *Metrics) FileFailed(size uint64) {
+	if m == nil {
+		return
+	}
+	m.errors.Add(1)
+	m.bytesDone.Add(size)
+}
+
+// Record errors that are not related to calculating a file signature hash (e.g. unreadable paths).
+func (m *Metrics) AddErrors(n int) {
+	if m == nil || n <= 0 {
+		return
+	}
+	m.errors.Add(uint64(n))
+}
+
+// Totals recorded so far by [Metrics].
+type MetricsTotals struct {
+	EntriesScanned uint64
+	FilesHashed    uint64
+	BytesHashed    uint64
+	Errors         uint64
+}
+
+// Return the totals recorded so far.
+func (m *Metrics) Totals() MetricsTotals {
+	if m == nil {
+		return MetricsTotals{}
+	}
+	return MetricsTotals{
+		EntriesScanned: m.entriesScanned.Load(),
+		FilesHashed:    m.filesHashed.Load(),
+		BytesHashed:    m.bytesHashed.Load(),
+		Errors:         m.errors.Load(),
+	}
+}
+
+// Estimate the number of seconds remaining until all the file signature hashes have been calculated.
+// Returns false when there is not yet enough information for an estimate.
+func (m *Metrics) eta(now time.Time) (float64, bool) {
+	start := m.hashingStart.Load()
+	done := m.bytesDone.Load()
+	total := m.bytesToHash.Load()
+	if start == 0 || done == 0 {
+		return 0, false
+	}
+	if done >= total {
+		return 0, true
+	}
+
+	elapsed := now.Sub(time.Unix(0, start)).Seconds()
+	return elapsed * float64(total-done) / float64(done), true
+}
+
+// Write the metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+
+	write := func(name string, kind string, help string, value any) {
+		fmt.Fprintf(cw, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+	}
+
+	write("ajfs_entries_scanned_total", "counter", "Number of path entries scanned.", m.entriesScanned.Load())
+	write("ajfs_files_hashed_total", "counter", "Number of files for which the file signature hash was calculated.", m.filesHashed.Load())
+	write("ajfs_bytes_hashed_total", "counter", "Number of bytes for which the file signature hash was calculated.", m.bytesHashed.Load())
+	write("ajfs_errors_total", "counter", "Number of paths that could not be read or hashed.", m.errors.Load())
+	write("ajfs_files_to_hash", "gauge", "Number of files that need to be hashed.", m.filesToHash.Load())
+	write("ajfs_bytes_to_hash", "gauge", "Number of bytes that need to be hashed.", m.bytesToHash.Load())
+	if eta, ok := m.eta(time.Now()); ok {
+		write("ajfs_eta_seconds", "gauge", "Estimated number of seconds until all the files have been hashed.", fmt.Sprintf("%.0f", eta))
+	}
+
+	return cw.n, cw.err
+}
+
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+// Serve the metrics over HTTP at /metrics on the address (e.g. ":9090" or "localhost:9090").
+// The returned function stops the server.
+func (m *Metrics) Serve(address string) (func(), error) {
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serve the metrics on %q. %w", address, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = m.WriteTo(w)
+	})
+
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		_ = srv.Serve(ln)
+	}()
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	}, nil
+}
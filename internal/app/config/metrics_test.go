@@ -0,0 +1,109 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package config_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsNil(t *testing.T) {
+	var m *config.Metrics
+	assert.NotPanics(t, func() {
+		m.EntryScanned()
+		m.StartHashing(1, 2)
+		m.FileHashed(1)
+		m.FileFailed(1)
+		m.AddErrors(1)
+	})
+}
+
+func TestMetricsWriteTo(t *testing.T) {
+	m := &config.Metrics{}
+
+	var buffer bytes.Buffer
+	_, err := m.WriteTo(&buffer)
+	require.NoError(t, err)
+	assert.Contains(t, buffer.String(), "# TYPE ajfs_entries_scanned_total counter\najfs_entries_scanned_total 0\n")
+	assert.NotContains(t, buffer.String(), "ajfs_eta_seconds")
+
+	m.EntryScanned()
+	m.EntryScanned()
+	m.StartHashing(3, 300)
+	m.FileHashed(100)
+	m.FileFailed(50)
+	m.AddErrors(2)
+
+	buffer.Reset()
+	n, err := m.WriteTo(&buffer)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buffer.Len()), n)
+
+	out := buffer.String()
+	assert.Contains(t, out, "ajfs_entries_scanned_total 2\n")
+	assert.Contains(t, out, "ajfs_files_hashed_total 1\n")
+	assert.Contains(t, out, "ajfs_bytes_hashed_total 100\n")
+	assert.Contains(t, out, "ajfs_errors_total 3\n")
+	assert.Contains(t, out, "ajfs_files_to_hash 3\n")
+	assert.Contains(t, out, "ajfs_bytes_to_hash 300\n")
+	assert.Contains(t, out, "# TYPE ajfs_eta_seconds gauge\n")
+
+	m.FileHashed(150)
+	buffer.Reset()
+	_, err = m.WriteTo(&buffer)
+	require.NoError(t, err)
+	assert.Contains(t, buffer.String(), "ajfs_eta_seconds 0\n")
+}
+
+func TestMetricsServe(t *testing.T) {
+	m := &config.Metrics{}
+	m.EntryScanned()
+
+	// Find a free port
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	stop, err := m.Serve(address)
+	require.NoError(t, err)
+	defer stop()
+
+	resp, err := http.Get("http://" + address + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "ajfs_entries_scanned_total 1\n")
+
+	// Already in use
+	_, err = m.Serve(address)
+	require.Error(t, err)
+
+}
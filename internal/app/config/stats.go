@@ -0,0 +1,67 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package config
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/andrejacobs/go-aj/human"
+)
+
+// Stats records how long each phase of a command took.
+// Used to report actionable performance data (see the --stats flag).
+type Stats struct {
+	phases []phaseTiming
+}
+
+type phaseTiming struct {
+	name    string
+	elapsed time.Duration
+}
+
+// Start timing the named phase and return the function that must be called when the phase is done.
+// Does nothing if Stats is not being recorded.
+func (c *CommonConfig) StartPhase(name string) func() {
+	if c.Stats == nil {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() {
+		c.Stats.phases = append(c.Stats.phases, phaseTiming{name: name, elapsed: time.Since(start)})
+	}
+}
+
+// Print the timing of each phase, the total time and the peak resident set size.
+func (s *Stats) Print(w io.Writer, total time.Duration) {
+	fmt.Fprintln(w, "Stats:")
+	for _, p := range s.phases {
+		fmt.Fprintf(w, "  %-30s %s\n", p.name+":", p.elapsed)
+	}
+	fmt.Fprintf(w, "  %-30s %s\n", "total:", total)
+
+	if rss, ok := peakRSS(); ok {
+		fmt.Fprintf(w, "  %-30s %d [%s]\n", "peak RSS:", rss, human.Bytes(rss))
+	} else {
+		fmt.Fprintf(w, "  %-30s %s\n", "peak RSS:", "not available")
+	}
+}
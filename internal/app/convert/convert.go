@@ -0,0 +1,181 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package convert provides the functionality for ajfs convert command.
+package convert
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/resume"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
+)
+
+// Config for the ajfs convert command.
+type Config struct {
+	config.CommonConfig
+
+	OutPath string // Path to the new database that will be created.
+
+	ChangeAlgo bool        // Use a different hashing algorithm for the file signature hashes.
+	Algo       ajhash.Algo // The hashing algorithm to use when ChangeAlgo is true.
+
+	ChangeChecksum bool            // Use a different algorithm for the database file integrity checksum.
+	ChecksumAlgo   db.ChecksumAlgo // The checksum algorithm to use when ChangeChecksum is true.
+
+	ToVersion int // The file format version of the new database. 0 means the current version.
+}
+
+// Process the ajfs convert command.
+func Run(ctx context.Context, cfg Config) error {
+	if cfg.ToVersion == 0 {
+		cfg.ToVersion = db.CurrentVersion()
+	}
+	if cfg.ToVersion < 1 || cfg.ToVersion > db.CurrentVersion() {
+		return fmt.Errorf("unsupported file format version %d (supported versions are 1 to %d)", cfg.ToVersion, db.CurrentVersion())
+	}
+
+	cfg.VerbosePrintln(fmt.Sprintf("Converting database %q to %q", cfg.DbPath, cfg.OutPath))
+
+	inDbf, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+	defer inDbf.Close()
+	cfg.WarnIfLimited(inDbf)
+
+	// Determine how the file signature hashes will be created
+	features := db.FeatureFlags(db.FeatureJustEntries)
+	copyHashes := false
+	algo := cfg.Algo
+
+	if inDbf.Features().HasHashTable() {
+		features |= db.FeatureHashTable
+
+		inAlgo, err := inDbf.HashTableAlgo()
+		if err != nil {
+			if !cfg.ChangeAlgo {
+				return err
+			}
+			// The existing hashes can't be read and will be calculated again
+			cfg.Errorln(fmt.Sprintf("WARNING: %v", err))
+		} else {
+			if !cfg.ChangeAlgo {
+				algo = inAlgo
+			}
+			copyHashes = (algo == inAlgo)
+		}
+	} else if cfg.ChangeAlgo {
+		features |= db.FeatureHashTable
+	}
+
+	if inDbf.Features().HasDirStats() {
+		features |= db.FeatureDirStats
+	}
+
+	if inDbf.Features().HasHashTimes() {
+		features |= db.FeatureHashTimes
+	}
+
+	checksumAlgo := inDbf.ChecksumAlgo()
+	if cfg.ChangeChecksum {
+		checksumAlgo = cfg.ChecksumAlgo
+	}
+
+	outDbf, err := db.CreateDatabaseWithIdNamespace(cfg.OutPath, inDbf.RootPath(), features, checksumAlgo, cfg.ToVersion, inDbf.IdNamespace())
+	if err != nil {
+		return err
+	}
+
+	// Called when an error happened and the incomplete database needs to be removed
+	errFn := func(rcvErr error) error {
+		if err := outDbf.Interrupted(); err != nil {
+			return fmt.Errorf("failed to remove the incomplete database %q with error (%w). original error: %w", cfg.OutPath, err, rcvErr)
+		}
+		return rcvErr
+	}
+
+	// Copy the path entries
+	err = inDbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		return outDbf.WriteEntry(&pi)
+	})
+	if err != nil {
+		return errFn(fmt.Errorf("failed to copy the entries from %q. %w", cfg.DbPath, err))
+	}
+
+	if err = outDbf.FinishEntries(); err != nil {
+		return errFn(err)
+	}
+
+	if features.HasHashTable() {
+		cfg.VerbosePrintln(fmt.Sprintf("Creating the hash table (algorithm: %s)", db.AlgoString(algo)))
+
+		if err = outDbf.StartHashTable(algo); err != nil {
+			return errFn(err)
+		}
+
+		if err = outDbf.FinishHashTable(); err != nil {
+			return errFn(err)
+		}
+
+		// The entries were written in the same order and thus the indices map 1:1
+		if copyHashes {
+			cfg.VerbosePrintln("Copying the existing file signature hashes")
+
+			var hashTimes db.HashTimes
+			if inDbf.Features().HasHashTimes() {
+				hashTimes, err = inDbf.ReadHashTimes(ctx)
+				if err != nil {
+					return errFn(err)
+				}
+			}
+
+			err = inDbf.ReadAllEntriesWithHashes(ctx, func(idx int, pi path.Info, hash []byte) error {
+				return outDbf.WriteHashEntryAt(idx, hash, hashTimes[idx])
+			})
+			if err != nil {
+				return errFn(fmt.Errorf("failed to copy the file signature hashes from %q. %w", cfg.DbPath, err))
+			}
+		}
+	}
+
+	if err = outDbf.Close(); err != nil {
+		return err
+	}
+
+	// Calculate the file signature hashes that could not be copied
+	if features.HasHashTable() {
+		resumeCfg := resume.Config{
+			CommonConfig: cfg.CommonConfig,
+		}
+		resumeCfg.DbPath = cfg.OutPath
+
+		if err = resume.Run(ctx, resumeCfg); err != nil {
+			return err
+		}
+	}
+
+	cfg.VerbosePrintln("Done!")
+	return nil
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package convert_test
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/convert"
+	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/andrejacobs/go-aj/file"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvert(t *testing.T) {
+	tempDir := t.TempDir()
+	inFile := filepath.Join(tempDir, "in.ajfs")
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: inFile,
+		},
+		Root:            "../../testdata/scan",
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	testCases := []struct {
+		desc       string
+		changeAlgo bool
+		algo       ajhash.Algo
+		expAlgo    ajhash.Algo
+		version    int
+	}{
+		{desc: "same algo", expAlgo: ajhash.AlgoSHA1},
+		{desc: "version 1", expAlgo: ajhash.AlgoSHA1, version: 1},
+		{desc: "explicit same algo", changeAlgo: true, algo: ajhash.AlgoSHA1, expAlgo: ajhash.AlgoSHA1},
+		{desc: "different algo", changeAlgo: true, algo: ajhash.AlgoSHA256, expAlgo: ajhash.AlgoSHA256},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			outFile := filepath.Join(t.TempDir(), "out.ajfs")
+
+			cfg := convert.Config{
+				CommonConfig: config.CommonConfig{
+					Stdout: io.Discard,
+					Stderr: io.Discard,
+					DbPath: inFile,
+				},
+				OutPath:    outFile,
+				ChangeAlgo: tC.changeAlgo,
+				Algo:       tC.algo,
+				ToVersion:  tC.version,
+			}
+			require.NoError(t, convert.Run(context.Background(), cfg))
+
+			expected := entriesWithHashes(t, inFile)
+
+			dbf, err := db.OpenDatabase(outFile)
+			require.NoError(t, err)
+			defer dbf.Close()
+
+			require.NoError(t, dbf.VerifyChecksums())
+			if tC.version != 0 {
+				assert.Equal(t, tC.version, dbf.Version())
+			} else {
+				assert.Equal(t, db.CurrentVersion(), dbf.Version())
+			}
+
+			algo, err := dbf.HashTableAlgo()
+			require.NoError(t, err)
+			assert.Equal(t, tC.expAlgo, algo)
+
+			absRoot, err := filepath.Abs(scanCfg.Root)
+			require.NoError(t, err)
+
+			count := 0
+			err = dbf.ReadAllEntriesWithHashes(context.Background(), func(idx int, pi path.Info, hash []byte) error {
+				exp, ok := expected[pi.Id]
+				require.True(t, ok)
+				assert.Equal(t, exp.Path, pi.Path)
+				assert.Equal(t, exp.Size, pi.Size)
+
+				if tC.expAlgo == ajhash.AlgoSHA1 {
+					assert.Equal(t, exp.hash, hash)
+				} else {
+					expHash, _, err := file.HashSHA256(t.Context(), filepath.Join(absRoot, pi.Path), nil)
+					require.NoError(t, err)
+					assert.Equal(t, expHash, hash)
+				}
+
+				count++
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, len(expected), count)
+		})
+	}
+}
+
+func TestConvertInvalidVersion(t *testing.T) {
+	cfg := convert.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: "does-not-matter",
+		},
+		OutPath:   filepath.Join(t.TempDir(), "out.ajfs"),
+		ToVersion: 42,
+	}
+	assert.ErrorContains(t, convert.Run(context.Background(), cfg), "unsupported file format version 42")
+}
+
+type entryWithHash struct {
+	path.Info
+	hash []byte
+}
+
+func entriesWithHashes(t *testing.T, dbPath string) map[path.Id]entryWithHash {
+	dbf, err := db.OpenDatabase(dbPath)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	result := make(map[path.Id]entryWithHash)
+	err = dbf.ReadAllEntriesWithHashes(context.Background(), func(idx int, pi path.Info, hash []byte) error {
+		result[pi.Id] = entryWithHash{Info: pi, hash: hash}
+		return nil
+	})
+	require.NoError(t, err)
+	return result
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package crossverify provides the functionality for ajfs cross-verify command.
+package crossverify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+)
+
+// ErrVerifyFailed is returned when a file present in both databases has different file signature hashes.
+var ErrVerifyFailed = errors.New("cross verification failed")
+
+// Config for the ajfs cross-verify command.
+type Config struct {
+	config.CommonConfig
+
+	OtherPath string // Path to the database to verify against.
+}
+
+// Result contains the outcome of verifying the files present in both databases.
+type Result struct {
+	Matched   []string // Same path with the same hash.
+	Corrupted []string // Same path, size and modification time but the hash differs.
+	Changed   []string // Same path but the hash differs along with the size or modification time.
+	Skipped   []string // Files without a calculated hash in either of the databases.
+
+	// Times at which the hashes were calculated, keyed by path.
+	// Only contains the files for which at least one of the databases recorded the hash times.
+	Times map[string]HashTimes
+}
+
+// HashTimes are the times at which the file signature hash of a file was calculated in each database.
+// The zero time means the database did not record the time.
+type HashTimes struct {
+	Lhs time.Time
+	Rhs time.Time
+}
+
+// String describes when the hash was first computed and when it was calculated again.
+func (t HashTimes) String() string {
+	first, second := t.Lhs, t.Rhs
+	if first.IsZero() || (!second.IsZero() && second.Before(first)) {
+		first, second = second, first
+	}
+
+	const layout = "2006-01-02"
+	if second.IsZero() {
+		return fmt.Sprintf("hash computed %s", first.Format(layout))
+	}
+	return fmt.Sprintf("hash computed %s, re-verified %s", first.Format(layout), second.Format(layout))
+}
+
+// Passed returns true if every file present in both databases has the same hash.
+func (r *Result) Passed() bool {
+	return len(r.Corrupted) == 0 && len(r.Changed) == 0
+}
+
+// Process the ajfs cross-verify command.
+func Run(ctx context.Context, cfg Config) error {
+	lhs, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+	defer lhs.Close()
+	cfg.WarnIfLimited(lhs)
+
+	rhs, err := db.OpenDatabase(cfg.OtherPath)
+	if err != nil {
+		return err
+	}
+	defer rhs.Close()
+	cfg.WarnIfLimited(rhs)
+
+	result, err := Verify(ctx, lhs, rhs)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Verbose {
+		for _, p := range result.Matched {
+			cfg.Println(fmt.Sprintf("Matched:   %s%s", p, result.timesSuffix(p)))
+		}
+	}
+	for _, p := range result.Corrupted {
+		cfg.Println(fmt.Sprintf("Corrupted: %s%s", p, result.timesSuffix(p)))
+	}
+	for _, p := range result.Changed {
+		cfg.Println(fmt.Sprintf("Changed:   %s%s", p, result.timesSuffix(p)))
+	}
+	for _, p := range result.Skipped {
+		cfg.Errorln(fmt.Sprintf("WARNING: no file signature hash for %q", p))
+	}
+
+	if result.Passed() {
+		cfg.Println("ajfs: Cross verification passed")
+	} else {
+		cfg.Println("ajfs: Cross verification failed")
+	}
+	cfg.Println(fmt.Sprintf("    Files matched: %d", len(result.Matched)))
+	cfg.Println(fmt.Sprintf("  Files corrupted: %d", len(result.Corrupted)))
+	cfg.Println(fmt.Sprintf("    Files changed: %d", len(result.Changed)))
+	cfg.Println(fmt.Sprintf("    Files skipped: %d", len(result.Skipped)))
+
+	if !result.Passed() {
+		return ErrVerifyFailed
+	}
+	return nil
+}
+
+// Verify compares the file signature hashes of the files that exist at the same path in both databases.
+// Files that only exist in one of the databases are ignored (see "ajfs diff").
+func Verify(ctx context.Context, lhs *db.DatabaseFile, rhs *db.DatabaseFile) (Result, error) {
+	result := Result{}
+
+	for _, dbf := range []*db.DatabaseFile{lhs, rhs} {
+		if !dbf.Features().HasHashTable() {
+			return result, fmt.Errorf("require file signature hashes to be present in the database %q", dbf.Path())
+		}
+	}
+
+	lhsAlgo, err := lhs.HashTableAlgo()
+	if err != nil {
+		return result, err
+	}
+	rhsAlgo, err := rhs.HashTableAlgo()
+	if err != nil {
+		return result, err
+	}
+	if lhsAlgo != rhsAlgo {
+		return result, fmt.Errorf("the databases use different hashing algorithms (%s and %s)", lhsAlgo, rhsAlgo)
+	}
+
+	lhsHashes, err := lhs.ReadHashTable(ctx)
+	if err != nil {
+		return result, err
+	}
+	rhsHashes, err := rhs.ReadHashTable(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	lhsTimes, err := readHashTimes(ctx, lhs)
+	if err != nil {
+		return result, err
+	}
+	rhsTimes, err := readHashTimes(ctx, rhs)
+	if err != nil {
+		return result, err
+	}
+	if lhsTimes != nil || rhsTimes != nil {
+		result.Times = make(map[string]HashTimes)
+	}
+
+	err = lhs.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		if !pi.IsFile() {
+			return nil
+		}
+
+		v, err := rhs.FindEntryIndexAndOffset(rhs.IdOf(lhs, pi))
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		other, err := rhs.ReadEntryAtIndex(int(v.Index))
+		if err != nil {
+			return err
+		}
+		if !other.IsFile() || other.Path != pi.Path {
+			return nil
+		}
+
+		lhsHash, lhsOk := lhsHashes[idx]
+		rhsHash, rhsOk := rhsHashes[int(v.Index)]
+		if !lhsOk || !rhsOk {
+			result.Skipped = append(result.Skipped, pi.Path)
+			return nil
+		}
+
+		times := HashTimes{Lhs: lhsTimes[idx], Rhs: rhsTimes[int(v.Index)]}
+		if !times.Lhs.IsZero() || !times.Rhs.IsZero() {
+			result.Times[pi.Path] = times
+		}
+
+		switch {
+		case bytes.Equal(lhsHash, rhsHash):
+			result.Matched = append(result.Matched, pi.Path)
+		case pi.Size == other.Size && pi.ModTime.Equal(other.ModTime):
+			result.Corrupted = append(result.Corrupted, pi.Path)
+		default:
+			result.Changed = append(result.Changed, pi.Path)
+		}
+		return nil
+	})
+
+	return result, err
+}
+
+// Read the hash times if the database recorded them, otherwise nil is returned.
+func readHashTimes(ctx context.Context, dbf *db.DatabaseFile) (db.HashTimes, error) {
+	if !dbf.Features().HasHashTimes() {
+		return nil, nil
+	}
+	return dbf.ReadHashTimes(ctx)
+}
+
+// Describe the hash times of the path for the report, empty if the times are not known.
+func (r *Result) timesSuffix(p string) string {
+	t, ok := r.Times[p]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", t)
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package crossverify_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/crossverify"
+	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/testshared"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCrossVerify(t *testing.T) {
+	root := t.TempDir()
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for name, content := range map[string]string{
+		"same.txt":      "unchanged",
+		"corrupted.txt": "original",
+		"changed.txt":   "original",
+		"left.txt":      "only on the left",
+	} {
+		p := filepath.Join(root, name)
+		require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+		require.NoError(t, os.Chtimes(p, modTime, modTime))
+	}
+
+	lhsPath := filepath.Join(t.TempDir(), "lhs.ajfs")
+	rhsPath := filepath.Join(t.TempDir(), "rhs.ajfs")
+	testshared.ScanDatabase(t, lhsPath, root, true)
+
+	// Same size and modification time but different content
+	p := filepath.Join(root, "corrupted.txt")
+	require.NoError(t, os.WriteFile(p, []byte("0riginal"), 0644))
+	require.NoError(t, os.Chtimes(p, modTime, modTime))
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "changed.txt"), []byte("changed content"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(root, "left.txt")))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "right.txt"), []byte("only on the right"), 0644))
+	testshared.ScanDatabase(t, rhsPath, root, true)
+
+	var outBuffer bytes.Buffer
+	cfg := crossverify.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+			DbPath: lhsPath,
+		},
+		OtherPath: rhsPath,
+	}
+
+	err := crossverify.Run(context.Background(), cfg)
+	assert.ErrorIs(t, err, crossverify.ErrVerifyFailed)
+	assert.Contains(t, outBuffer.String(), "Corrupted: corrupted.txt\n")
+	assert.Contains(t, outBuffer.String(), "Changed:   changed.txt\n")
+	assert.NotContains(t, outBuffer.String(), "left.txt")
+	assert.NotContains(t, outBuffer.String(), "right.txt")
+	assert.Contains(t, outBuffer.String(), "Files matched: 1\n")
+
+	// Against itself
+	outBuffer.Reset()
+	cfg.OtherPath = lhsPath
+	require.NoError(t, crossverify.Run(context.Background(), cfg))
+	assert.Contains(t, outBuffer.String(), "ajfs: Cross verification passed\n")
+	assert.Contains(t, outBuffer.String(), "Files matched: 4\n")
+}
+
+func TestCrossVerifyRequiresHashes(t *testing.T) {
+	lhsPath := filepath.Join(t.TempDir(), "lhs.ajfs")
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: lhsPath,
+		},
+		Root: "../../testdata/scan",
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	cfg := crossverify.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: lhsPath,
+		},
+		OtherPath: lhsPath,
+	}
+	assert.ErrorContains(t, crossverify.Run(context.Background(), cfg), "require file signature hashes")
+}
+
+func TestCrossVerifyHashTimes(t *testing.T) {
+	lhsPath := filepath.Join(t.TempDir(), "lhs.ajfs")
+	rhsPath := filepath.Join(t.TempDir(), "rhs.ajfs")
+	for _, dbPath := range []string{lhsPath, rhsPath} {
+		scanCfg := scan.Config{
+			CommonConfig: config.CommonConfig{
+				Stdout: io.Discard,
+				Stderr: io.Discard,
+				DbPath: dbPath,
+			},
+			Root:            "../../testdata/scan",
+			CalculateHashes: true,
+			Algo:            ajhash.AlgoSHA1,
+			HashTimes:       true,
+		}
+		require.NoError(t, scan.Run(context.Background(), scanCfg))
+	}
+
+	var outBuffer bytes.Buffer
+	cfg := crossverify.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout:  &outBuffer,
+			Stderr:  io.Discard,
+			DbPath:  lhsPath,
+			Verbose: true,
+		},
+		OtherPath: rhsPath,
+	}
+	require.NoError(t, crossverify.Run(context.Background(), cfg))
+
+	today := time.Now().Format("2006-01-02")
+	assert.Contains(t, outBuffer.String(), "(hash computed "+today+", re-verified "+today+")\n")
+}
+
+func TestHashTimesString(t *testing.T) {
+	computed := time.Date(2024, time.March, 2, 12, 0, 0, 0, time.Local)
+	verified := time.Date(2025, time.January, 10, 12, 0, 0, 0, time.Local)
+
+	assert.Equal(t, "hash computed 2024-03-02, re-verified 2025-01-10", crossverify.HashTimes{Lhs: computed, Rhs: verified}.String())
+	assert.Equal(t, "hash computed 2024-03-02, re-verified 2025-01-10", crossverify.HashTimes{Lhs: verified, Rhs: computed}.String())
+	assert.Equal(t, "hash computed 2025-01-10", crossverify.HashTimes{Rhs: verified}.String())
+	assert.Equal(t, "hash computed 2024-03-02", crossverify.HashTimes{Lhs: computed}.String())
+}
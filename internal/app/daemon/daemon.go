@@ -0,0 +1,541 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package daemon provides the functionality for ajfs daemon command.
+//
+// The daemon keeps a set of databases open and answers search, diff and dupes
+// queries from other processes. Requests and responses are JSON documents sent
+// over HTTP, which means any HTTP client (e.g. curl --unix-socket) can be used.
+package daemon
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/diff"
+	"github.com/andrejacobs/ajfs/internal/app/search"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+)
+
+// Config for the ajfs daemon command.
+type Config struct {
+	config.CommonConfig
+	Listen  string   // Address to listen on. Either unix:///path/to/socket or tcp://host:port.
+	DbPaths []string // Paths to the databases that can be queried.
+}
+
+// Process the ajfs daemon command.
+// The daemon serves requests until the context is cancelled.
+func Run(ctx context.Context, cfg Config) error {
+	srv, err := NewServer(cfg)
+	if err != nil {
+		return err
+	}
+	defer srv.Close()
+
+	ln, err := listen(cfg.Listen)
+	if err != nil {
+		return err
+	}
+
+	httpSrv := &http.Server{
+		Handler:           srv,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = httpSrv.Shutdown(shutdownCtx)
+		case <-done:
+		}
+	}()
+
+	cfg.Println(fmt.Sprintf("Listening on %s", cfg.Listen))
+	err = httpSrv.Serve(ln)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Create the listener from an address of the form unix:///path or tcp://host:port.
+func listen(address string) (net.Listener, error) {
+	network, addr, found := strings.Cut(address, "://")
+	if !found || addr == "" {
+		return nil, fmt.Errorf("invalid listen address %q. expected unix:///path/to/socket or tcp://host:port", address)
+	}
+
+	switch network {
+	case "unix":
+		if err := removeStaleSocket(addr); err != nil {
+			return nil, err
+		}
+	case "tcp":
+	default:
+		return nil, fmt.Errorf("unsupported network %q in the listen address %q", network, address)
+	}
+
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %q. %w", address, err)
+	}
+	return ln, nil
+}
+
+// Remove the socket file left behind by a previous daemon that did not shut down cleanly.
+// Anything other than a socket that nobody is listening on is left alone.
+func removeStaleSocket(socketPath string) error {
+	info, err := os.Lstat(socketPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to check the socket %q. %w", socketPath, err)
+	}
+
+	if info.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("%q already exists and is not a socket", socketPath)
+	}
+
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("another process is already listening on %q", socketPath)
+	}
+
+	if err := os.Remove(socketPath); err != nil {
+		return fmt.Errorf("failed to remove the stale socket %q. %w", socketPath, err)
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// Server answers the queries for a set of open databases.
+type Server struct {
+	cfg     Config
+	dbs     []*database
+	handler http.Handler
+}
+
+type database struct {
+	name string
+	path string
+
+	mu  sync.Mutex // Guards dbf, since reading all the entries is not safe for concurrent use
+	dbf *db.DatabaseFile
+}
+
+// Create a new server and open all the databases.
+// The databases are addressed by their path or by their name, which is the
+// base name of the file without the extension (e.g. "nas" for /backups/nas.ajfs).
+func NewServer(cfg Config) (*Server, error) {
+	if len(cfg.DbPaths) == 0 {
+		return nil, fmt.Errorf("expected at least one database")
+	}
+
+	s := &Server{
+		cfg: cfg,
+		dbs: make([]*database, 0, len(cfg.DbPaths)),
+	}
+
+	for _, dbPath := range cfg.DbPaths {
+		name := strings.TrimSuffix(filepath.Base(dbPath), filepath.Ext(dbPath))
+		if slices.ContainsFunc(s.dbs, func(d *database) bool { return d.name == name }) {
+			s.Close()
+			return nil, fmt.Errorf("more than one database is named %q", name)
+		}
+
+		dbf, err := db.OpenDatabase(dbPath)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		cfg.WarnIfLimited(dbf)
+
+		s.dbs = append(s.dbs, &database{name: name, path: dbPath, dbf: dbf})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /databases", s.handleDatabases)
+	mux.HandleFunc("POST /search", s.handleSearch)
+	mux.HandleFunc("POST /dupes", s.handleDupes)
+	mux.HandleFunc("POST /diff", s.handleDiff)
+	s.handler = mux
+
+	return s, nil
+}
+
+// Close all the databases.
+func (s *Server) Close() {
+	for _, d := range s.dbs {
+		d.mu.Lock()
+		d.dbf.Close()
+		d.mu.Unlock()
+	}
+}
+
+// ServeHTTP implements [http.Handler].
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.cfg.VerbosePrintln(fmt.Sprintf("%s %s", r.Method, r.URL.Path))
+	s.handler.ServeHTTP(w, r)
+}
+
+// Find the database by name or path.
+// The name can be left empty when only a single database is being served.
+func (s *Server) database(nameOrPath string) (*database, error) {
+	if nameOrPath == "" {
+		if len(s.dbs) == 1 {
+			return s.dbs[0], nil
+		}
+		return nil, fmt.Errorf("expected the name of the database to query")
+	}
+
+	for _, d := range s.dbs {
+		if d.name == nameOrPath || d.path == nameOrPath {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", errUnknownDatabase, nameOrPath)
+}
+
+var errUnknownDatabase = errors.New("unknown database")
+
+//-----------------------------------------------------------------------------
+
+// Database describes one of the databases being served.
+type Database struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Root    string `json:"root"`
+	Entries int    `json:"entries"`
+	Hashes  bool   `json:"hashes"`
+}
+
+// Entry is a path entry returned by the search and dupes queries.
+type Entry struct {
+	Id      string    `json:"id"`
+	Path    string    `json:"path"`
+	Size    uint64    `json:"size"`
+	Mode    string    `json:"mode"`
+	ModTime time.Time `json:"mtime"`
+	Hash    string    `json:"hash,omitempty"`
+}
+
+func newEntry(pi path.Info, hash string) Entry {
+	return Entry{
+		Id:      hex.EncodeToString(pi.Id[:]),
+		Path:    pi.Path,
+		Size:    pi.Size,
+		Mode:    pi.Mode.String(),
+		ModTime: pi.ModTime,
+		Hash:    hash,
+	}
+}
+
+// SearchRequest is the body of a search query.
+type SearchRequest struct {
+	Db string `json:"db,omitempty"` // Name or path of the database. Can be omitted when only one is being served.
+	search.Criteria
+	Limit int `json:"limit,omitempty"` // Maximum number of entries to return. Zero means no limit.
+}
+
+// SearchResponse is the result of a search query.
+type SearchResponse struct {
+	Entries []Entry `json:"entries"`
+}
+
+// DupesRequest is the body of a dupes query.
+type DupesRequest struct {
+	Db string `json:"db,omitempty"` // Name or path of the database. Can be omitted when only one is being served.
+}
+
+// DupesGroup is a set of files that share the same file signature hash.
+type DupesGroup struct {
+	Hash    string  `json:"hash"`
+	Entries []Entry `json:"entries"`
+}
+
+// DupesResponse is the result of a dupes query.
+type DupesResponse struct {
+	Groups []DupesGroup `json:"groups"`
+}
+
+// DiffRequest is the body of a diff query.
+type DiffRequest struct {
+	Lhs string `json:"lhs"` // Name or path of the left hand side database.
+	Rhs string `json:"rhs"` // Name or path of the right hand side database.
+}
+
+// Difference between the left and right hand side databases.
+type Difference struct {
+	Type string `json:"type"` // One of: left, right or changed.
+	Path string `json:"path"`
+	Dir  bool   `json:"dir"`
+	Line string `json:"line"` // Same as displayed by ajfs diff.
+}
+
+// DiffResponse is the result of a diff query.
+type DiffResponse struct {
+	Differences []Difference `json:"differences"`
+}
+
+//-----------------------------------------------------------------------------
+
+func (s *Server) handleDatabases(w http.ResponseWriter, r *http.Request) {
+	result := make([]Database, 0, len(s.dbs))
+	for _, d := range s.dbs {
+		d.mu.Lock()
+		result = append(result, Database{
+			Name:    d.name,
+			Path:    d.path,
+			Root:    d.dbf.RootPath(),
+			Entries: d.dbf.EntriesCount(),
+			Hashes:  d.dbf.Features().HasHashTable(),
+		})
+		d.mu.Unlock()
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	var req SearchRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	if req.Limit < 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("the limit can't be negative"))
+		return
+	}
+
+	d, err := s.database(req.Db)
+	if err != nil {
+		writeLookupError(w, err)
+		return
+	}
+
+	exp, alsoHashes, err := req.Criteria.Build()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	// Same as the search command, no criteria matches nothing
+	if exp == nil {
+		exp = &search.Never{}
+	}
+
+	resp := SearchResponse{Entries: make([]Entry, 0)}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err = search.Prepare(r.Context(), exp, d.dbf); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	fn := func(pi path.Info, hash []byte) error {
+		matched, err := exp.Match(pi, hash)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			return nil
+		}
+
+		resp.Entries = append(resp.Entries, newEntry(pi, hex.EncodeToString(hash)))
+		if req.Limit > 0 && len(resp.Entries) >= req.Limit {
+			return db.SkipAll
+		}
+		return nil
+	}
+
+	if alsoHashes && d.dbf.Features().HasHashTable() {
+		err = d.dbf.ReadAllEntriesWithHashes(r.Context(), func(idx int, pi path.Info, hash []byte) error {
+			return fn(pi, hash)
+		})
+	} else {
+		err = d.dbf.ReadAllEntries(r.Context(), func(idx int, pi path.Info) error {
+			return fn(pi, nil)
+		})
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleDupes(w http.ResponseWriter, r *http.Request) {
+	var req DupesRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	d, err := s.database(req.Db)
+	if err != nil {
+		writeLookupError(w, err)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.dbf.Features().HasHashTable() {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("require file signature hashes to be present in the database %q", d.name))
+		return
+	}
+
+	resp := DupesResponse{Groups: make([]DupesGroup, 0)}
+	lastGroup := ""
+
+	err = d.dbf.FindDuplicates(r.Context(), func(group string, idx int, pi path.Info, hash string) error {
+		if group != lastGroup {
+			resp.Groups = append(resp.Groups, DupesGroup{Hash: hash})
+			lastGroup = group
+		}
+		g := &resp.Groups[len(resp.Groups)-1]
+		g.Entries = append(g.Entries, newEntry(pi, ""))
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleDiff(w http.ResponseWriter, r *http.Request) {
+	var req DiffRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	lhs, err := s.database(req.Lhs)
+	if err != nil {
+		writeLookupError(w, err)
+		return
+	}
+	rhs, err := s.database(req.Rhs)
+	if err != nil {
+		writeLookupError(w, err)
+		return
+	}
+	if lhs == rhs {
+		writeJSON(w, http.StatusOK, DiffResponse{Differences: make([]Difference, 0)})
+		return
+	}
+
+	// Always lock in the same order to prevent two opposite diffs from deadlocking
+	first, second := lhs, rhs
+	if first.name > second.name {
+		first, second = second, first
+	}
+	first.mu.Lock()
+	defer first.mu.Unlock()
+	second.mu.Lock()
+	defer second.mu.Unlock()
+
+	resp := DiffResponse{Differences: make([]Difference, 0)}
+
+	err = diff.CompareDatabases(r.Context(), lhs.dbf, rhs.dbf, false, func(d diff.Diff) error {
+		resp.Differences = append(resp.Differences, Difference{
+			Type: diffTypeString(d.Type),
+			Path: d.Path,
+			Dir:  d.IsDir,
+			Line: d.String(),
+		})
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func diffTypeString(t diff.Type) string {
+	switch t {
+	case diff.TypeLeftOnly:
+		return "left"
+	case diff.TypeRightOnly:
+		return "right"
+	case diff.TypeChanged:
+		return "changed"
+	default:
+		return ""
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// Maximum size of a request body.
+const maxRequestSize = 1 << 20
+
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("failed to decode the request. %w", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// Unknown databases are reported as not found and a missing name as a bad request.
+func writeLookupError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, errUnknownDatabase) {
+		status = http.StatusNotFound
+	}
+	writeError(w, status, err)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package daemon_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/daemon"
+	"github.com/andrejacobs/ajfs/internal/testshared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	tempDir := t.TempDir()
+	all := testshared.ScanDatabase(t, filepath.Join(tempDir, "all.ajfs"), "../../testdata/scan", true)
+	plain := testshared.ScanDatabase(t, filepath.Join(tempDir, "plain.ajfs"), "../../testdata/scan/b", false)
+
+	srv, err := daemon.NewServer(daemon.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		DbPaths: []string{all, plain},
+	})
+	require.NoError(t, err)
+	defer srv.Close()
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	// Databases
+	resp, err := http.Get(ts.URL + "/databases")
+	require.NoError(t, err)
+	var dbs []daemon.Database
+	decode(t, resp, http.StatusOK, &dbs)
+	require.Len(t, dbs, 2)
+	assert.Equal(t, "all", dbs[0].Name)
+	assert.Equal(t, 26, dbs[0].Entries)
+	assert.True(t, dbs[0].Hashes)
+	assert.Equal(t, "plain", dbs[1].Name)
+	assert.False(t, dbs[1].Hashes)
+
+	// Search
+	var found daemon.SearchResponse
+	decode(t, post(t, ts.URL+"/search", `{"db":"all","iname":["same-as-*"],"type":"f"}`), http.StatusOK, &found)
+	require.Len(t, found.Entries, 2)
+	assert.Equal(t, "a/a2/same-as-1.txt", found.Entries[0].Path)
+	assert.Equal(t, "b/b1/b1a/same-as-1.txt", found.Entries[1].Path)
+
+	decode(t, post(t, ts.URL+"/search", `{"db":"all","type":"f","limit":3}`), http.StatusOK, &found)
+	assert.Len(t, found.Entries, 3)
+
+	decode(t, post(t, ts.URL+"/search", `{"db":"all","hash":"c7389462"}`), http.StatusOK, &found)
+	require.Len(t, found.Entries, 1)
+	assert.Equal(t, "a/a2/6.txt", found.Entries[0].Path)
+	assert.Equal(t, "c7389462ca5ccb62c4ffe7a8d62d1da92c10cd27", found.Entries[0].Hash)
+
+	// Dupes
+	var dupes daemon.DupesResponse
+	decode(t, post(t, ts.URL+"/dupes", `{"db":"all"}`), http.StatusOK, &dupes)
+	require.NotEmpty(t, dupes.Groups)
+	for _, g := range dupes.Groups {
+		assert.GreaterOrEqual(t, len(g.Entries), 2)
+	}
+
+	// Diff
+	var diffs daemon.DiffResponse
+	decode(t, post(t, ts.URL+"/diff", `{"lhs":"all","rhs":"all"}`), http.StatusOK, &diffs)
+	assert.Empty(t, diffs.Differences)
+
+	decode(t, post(t, ts.URL+"/diff", `{"lhs":"all","rhs":"`+plain+`"}`), http.StatusOK, &diffs)
+	assert.NotEmpty(t, diffs.Differences)
+
+	// Errors
+	var failed map[string]string
+	decode(t, post(t, ts.URL+"/search", `{"type":"f"}`), http.StatusBadRequest, &failed)
+	assert.Contains(t, failed["error"], "expected the name of the database")
+
+	decode(t, post(t, ts.URL+"/search", `{"db":"nope"}`), http.StatusNotFound, &failed)
+	assert.Contains(t, failed["error"], "unknown database")
+
+	decode(t, post(t, ts.URL+"/search", `{"db":"all","size":["bogus"]}`), http.StatusBadRequest, &failed)
+	decode(t, post(t, ts.URL+"/search", `{"db":"all","bogus":true}`), http.StatusBadRequest, &failed)
+
+	decode(t, post(t, ts.URL+"/dupes", `{"db":"plain"}`), http.StatusBadRequest, &failed)
+	assert.Contains(t, failed["error"], "require file signature hashes")
+}
+
+func TestRunUnixSocket(t *testing.T) {
+	// Unix socket paths are limited in length, so avoid the long test temp dir paths
+	tempDir, err := os.MkdirTemp("", "ajfs")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	dbPath := testshared.ScanDatabase(t, filepath.Join(tempDir, "db.ajfs"), "../../testdata/scan", false)
+	socketPath := filepath.Join(tempDir, "ajfs.sock")
+
+	// A stale socket left behind should be replaced
+	ln, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, ln.Close())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- daemon.Run(ctx, daemon.Config{
+			CommonConfig: config.CommonConfig{
+				Stdout: io.Discard,
+				Stderr: io.Discard,
+			},
+			Listen:  "unix://" + socketPath,
+			DbPaths: []string{dbPath},
+		})
+	}()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("unix", socketPath)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+
+	resp, err := client.Post("http://ajfs/search", "application/json", bytes.NewBufferString(`{"name":["6.txt"]}`))
+	require.NoError(t, err)
+	var found daemon.SearchResponse
+	decode(t, resp, http.StatusOK, &found)
+	require.Len(t, found.Entries, 1)
+	assert.Equal(t, "a/a2/6.txt", found.Entries[0].Path)
+
+	cancel()
+	require.NoError(t, <-errCh)
+}
+
+func TestRunInvalidListen(t *testing.T) {
+	dbPath := testshared.ScanDatabase(t, filepath.Join(t.TempDir(), "db.ajfs"), "../../testdata/scan", false)
+	cfg := daemon.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		DbPaths: []string{dbPath},
+	}
+
+	for _, listen := range []string{"", "/tmp/ajfs.sock", "udp://localhost:1234"} {
+		cfg.Listen = listen
+		assert.Error(t, daemon.Run(context.Background(), cfg), listen)
+	}
+
+	// Never remove a file that isn't a socket
+	cfg.Listen = "unix://" + dbPath
+	assert.ErrorContains(t, daemon.Run(context.Background(), cfg), "is not a socket")
+	assert.FileExists(t, dbPath)
+}
+
+func post(t *testing.T, url string, body string) *http.Response {
+	resp, err := http.Post(url, "application/json", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	return resp
+}
+
+func decode(t *testing.T, resp *http.Response, status int, v any) {
+	defer resp.Body.Close()
+	require.Equal(t, status, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+}
@@ -22,18 +22,24 @@
 package diff
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/andrejacobs/ajfs/internal/app/config"
 	"github.com/andrejacobs/ajfs/internal/app/scan"
 	"github.com/andrejacobs/ajfs/internal/db"
 	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/ajfs/internal/style"
+	"github.com/andrejacobs/go-aj/ajhash"
 	"github.com/andrejacobs/go-aj/file"
+	"github.com/andrejacobs/go-aj/human"
 	"github.com/andrejacobs/go-collection/collection"
 )
 
@@ -49,23 +55,36 @@ type Config struct {
 
 	IncludeFilters []FilterFlags
 	ExcludeFilters []FilterFlags
+	Ignore         IgnoreRules
+
+	Match MatchMode // How the entries of both sides are paired up.
+
+	// Check if the items that are missing from the RHS file system hierarchy were moved into a trash.
+	CheckTrash bool
 
 	Fn CompareFn
 }
 
 // Process the ajfs diff command.
-func Run(cfg Config) error {
+func Run(ctx context.Context, cfg Config) error {
 	if cfg.Fn == nil {
 		panic("expected a compare function")
 	}
 
+	// The members of archives are also recorded for a file hierarchy when the database it is compared with
+	// recorded them, otherwise every member would be reported as only existing in the database
+	archives, err := recordsArchives(cfg.LhsPath, cfg.RhsPath)
+	if err != nil {
+		return err
+	}
+
 	lhsExists, err := file.FileExists(cfg.LhsPath)
 	if err != nil {
 		return err
 	}
 	if !lhsExists {
 		cfg.VerbosePrintln(fmt.Sprintf("Creating temporary database for LHS: %q", cfg.LhsPath))
-		dbPath, err := makeTempDatabase(cfg, cfg.LhsPath)
+		dbPath, err := makeTempDatabase(ctx, cfg, cfg.LhsPath, archives)
 		if err != nil {
 			return fmt.Errorf("failed to create temporary database for left hand side. %w", err)
 		}
@@ -86,9 +105,17 @@ func Run(cfg Config) error {
 	if err != nil {
 		return err
 	}
+	if cfg.CheckTrash {
+		if rhsExists {
+			return fmt.Errorf("the trash can only be checked when the right hand side is a file system hierarchy")
+		}
+		if cfg.Fn, err = checkTrashFn(cfg.RhsPath, cfg.Fn); err != nil {
+			return err
+		}
+	}
 	if !rhsExists {
 		cfg.VerbosePrintln(fmt.Sprintf("Creating temporary database for RHS: %q", cfg.RhsPath))
-		dbPath, err := makeTempDatabase(cfg, cfg.RhsPath)
+		dbPath, err := makeTempDatabase(ctx, cfg, cfg.RhsPath, archives)
 		if err != nil {
 			return fmt.Errorf("failed to create temporary database for right hand side. %w", err)
 		}
@@ -103,8 +130,8 @@ func Run(cfg Config) error {
 		cfg.ExcludeFilters = []FilterFlags{}
 	}
 
-	cfg.VerbosePrintln("Checking differences ...")
-	err = Compare(cfg.LhsPath, cfg.RhsPath, cfg.IncludeFilters, cfg.ExcludeFilters, cfg.Fn)
+	cfg.VerbosePrintln(fmt.Sprintf("Checking differences (pairing entries by %s) ...", cfg.Match))
+	err = CompareWithMode(ctx, cfg.LhsPath, cfg.RhsPath, cfg.IncludeFilters, cfg.ExcludeFilters, cfg.Ignore, cfg.Match, cfg.Fn)
 	if err != nil {
 		return err
 	}
@@ -133,6 +160,7 @@ const (
 	ChangedSize                // The size has changed
 	ChangedModTime             // The last modification time has changed
 	ChangedHash                // The hash is different
+	ChangedContent             // The number of entries inside the directory has changed
 )
 
 func (f ChangedFlags) ModeChanged() bool {
@@ -151,6 +179,10 @@ func (f ChangedFlags) HashChanged() bool {
 	return (f & ChangedHash) != 0
 }
 
+func (f ChangedFlags) ContentChanged() bool {
+	return (f & ChangedContent) != 0
+}
+
 func (f ChangedFlags) FilterFlagsMask() FilterFlags {
 	var result FilterFlags = FilterNoOp
 
@@ -174,6 +206,10 @@ func (f ChangedFlags) FilterFlagsMask() FilterFlags {
 		result |= FilterChangedHash
 	}
 
+	if f.ContentChanged() {
+		result |= FilterChangedContent
+	}
+
 	return result
 }
 
@@ -191,8 +227,9 @@ const (
 	FilterChangedSize                // The size has changed
 	FilterChangedModTime             // The last modification time has changed
 	FilterChangedHash                // The hash is different
+	FilterChangedContent             // The number of entries inside the directory has changed
 
-	FilterChangedMask = FilterChangedMode | FilterChangedSize | FilterChangedModTime | FilterChangedHash
+	FilterChangedMask = FilterChangedMode | FilterChangedSize | FilterChangedModTime | FilterChangedHash | FilterChangedContent
 )
 
 func (f FilterFlags) Validate() error {
@@ -230,6 +267,10 @@ func (f FilterFlags) ChangedFlagsMask() ChangedFlags {
 		result |= ChangedHash
 	}
 
+	if f&FilterChangedContent != 0 {
+		result |= ChangedContent
+	}
+
 	return result
 }
 
@@ -268,6 +309,10 @@ func (f FilterFlags) String() string {
 		sb.WriteRune('x')
 	}
 
+	if f&FilterChangedContent != 0 {
+		sb.WriteRune('c')
+	}
+
 	return sb.String()
 }
 
@@ -293,6 +338,8 @@ func ParseFilterFlags(input string) (FilterFlags, error) {
 			result |= FilterChangedModTime
 		case 'x':
 			result |= FilterChangedHash
+		case 'c':
+			result |= FilterChangedContent
 		default:
 			return 0, fmt.Errorf("invalid filter: %s. unknown filter property: %c", input, c)
 		}
@@ -301,6 +348,97 @@ func ParseFilterFlags(input string) (FilterFlags, error) {
 	return result, nil
 }
 
+// IgnoreRules describe the differences that are not relevant and should be ignored.
+// The rules are applied before any include and exclude filters.
+type IgnoreRules struct {
+	Paths   []*regexp.Regexp // Differences for paths that match any of these are ignored
+	Changes ChangedFlags     // Changes that are ignored. Items with only these changes are treated as unchanged
+
+	// Modification times that differ by at most this duration are treated as unchanged (e.g. 2s for FAT file systems).
+	ModTimePrecision time.Duration
+}
+
+// Apply the ignore rules to the difference.
+// Returns false if the difference should be ignored completely.
+func (r IgnoreRules) apply(d *Diff) bool {
+	for _, re := range r.Paths {
+		if re.MatchString(d.Path) {
+			return false
+		}
+	}
+
+	if d.Type == TypeChanged {
+		d.Changed &^= r.Changes
+		if d.Changed.ModTimeChanged() && (d.ModTimeDelta.Abs() <= r.ModTimePrecision) {
+			d.Changed &^= ChangedModTime
+		}
+		if d.Changed == ChangedNothing {
+			d.Type = TypeNothing
+		}
+	}
+	return true
+}
+
+// Parse the path regular expressions of the paths to be ignored.
+func ParseIgnorePaths(input []string) ([]*regexp.Regexp, error) {
+	result := make([]*regexp.Regexp, 0, len(input))
+	for _, expr := range input {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore path pattern %q. %w", expr, err)
+		}
+		result = append(result, re)
+	}
+	return result, nil
+}
+
+// Parse the names of the change classes to be ignored.
+// Valid names are mode, size, mtime, hash and content.
+func ParseIgnoreChanges(input []string) (ChangedFlags, error) {
+	var result ChangedFlags = ChangedNothing
+	for _, name := range input {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "":
+			continue
+		case "mode":
+			result |= ChangedMode
+		case "size":
+			result |= ChangedSize
+		case "mtime":
+			result |= ChangedModTime
+		case "hash":
+			result |= ChangedHash
+		case "content":
+			result |= ChangedContent
+		default:
+			return 0, fmt.Errorf("invalid change class %q to ignore. valid values are mode, size, mtime, hash and content", name)
+		}
+	}
+	return result, nil
+}
+
+// A registered path field that is compared by diff.
+type compareField struct {
+	path.Field
+	flag ChangedFlags // The change reported when the field is different
+}
+
+// The registered path fields that are compared for the items that exist on both sides.
+var compareFields = func() []compareField {
+	var result []compareField
+	for _, f := range path.Fields() {
+		if f.Differs == nil {
+			continue
+		}
+		flag, err := ParseIgnoreChanges([]string{f.Change})
+		if err != nil || flag == ChangedNothing {
+			panic(fmt.Sprintf("the path field %q reports an unknown change %q", f.Name, f.Change))
+		}
+		result = append(result, compareField{Field: f, flag: flag})
+	}
+	return result
+}()
+
 func ParseFilterFlagsArray(input []string) ([]FilterFlags, error) {
 	result := make([]FilterFlags, 0, len(input))
 
@@ -317,18 +455,105 @@ func ParseFilterFlagsArray(input []string) ([]FilterFlags, error) {
 	return result, nil
 }
 
+// MatchMode determines how the entries of the LHS are paired up with the entries of the RHS.
+type MatchMode int
+
+const (
+	MatchById           MatchMode = iota // Pair entries using the identifier stored in each database.
+	MatchByRelativePath                  // Pair entries using only their path relative to the root.
+)
+
+// Stringer implementation.
+func (m MatchMode) String() string {
+	switch m {
+	case MatchById:
+		return "identifier"
+	case MatchByRelativePath:
+		return "relative path"
+	}
+	return fmt.Sprintf("MatchMode(%d)", int(m))
+}
+
+// Return the key used to pair up the entry with an entry from the other side.
+// The relative path key does not depend on how the stored identifier was derived or on the path separator.
+func (m MatchMode) key(pi path.Info) path.Id {
+	if m == MatchByRelativePath {
+		return path.IdFromPath(filepath.ToSlash(filepath.Clean(pi.Path)))
+	}
+	return pi.Id
+}
+
+// Build a map from the pairing key to the path info entry.
+func (m MatchMode) buildInfoMap(ctx context.Context, dbf *db.DatabaseFile) (db.IdToInfoMap, error) {
+	if m == MatchById {
+		return dbf.BuildIdToInfoMap(ctx)
+	}
+
+	result := make(db.IdToInfoMap, dbf.EntriesCount())
+	err := dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		result[m.key(pi)] = pi
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Build a map from the pairing key to the file signature hash calculated using the hashing algorithm.
+func (m MatchMode) buildHashMap(ctx context.Context, dbf *db.DatabaseFile, algo ajhash.Algo) (db.IdToHashMap, error) {
+	if m == MatchById {
+		return dbf.BuildIdToHashMapWithAlgo(ctx, algo)
+	}
+
+	hashTable, err := dbf.ReadHashTableWithAlgo(ctx, algo)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(db.IdToHashMap, len(hashTable))
+	err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		if hash, ok := hashTable[idx]; ok {
+			result[m.key(pi)] = hash
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 // Describe a difference between the LHS and RHS databases.
 type Diff struct {
 	Type    Type         // Type of difference
-	Id      path.Id      // Identifier of the path info item
+	Id      path.Id      // Key used to pair up the item on both sides (the path identifier unless matched by relative path)
 	Path    string       // Path of the item
 	IsDir   bool         // Is this a directory
 	Changed ChangedFlags // What was changed
 	Size    uint64       // Size of the item. If the item exists on both sides, then this would be the size of the LHS item
+
+	ModTimeDelta time.Duration // How much later the RHS item was last modified than the LHS item. Only set if the item exists on both sides
+
+	Trash     TrashState // Whether an item that only exists on the LHS was found in a trash. Only set when checking the trash
+	TrashPath string     // Where the item was found in the trash
 }
 
 // Stringer implementation.
 func (d *Diff) String() string {
+	switch {
+	case d.Type == TypeNothing:
+		return ""
+	case d.Trash == TrashFound:
+		return fmt.Sprintf("%s %s (found in trash: %s)", d.marker(), d.Path, d.TrashPath)
+	case d.Trash == TrashGone:
+		return fmt.Sprintf("%s %s (gone)", d.marker(), d.Path)
+	}
+	return fmt.Sprintf("%s %s", d.marker(), d.Path)
+}
+
+// The marker that describes the type of difference, e.g. "f++++" or "f~sl~".
+func (d *Diff) marker() string {
 	var typeChar rune
 	if d.IsDir {
 		typeChar = 'd'
@@ -338,9 +563,9 @@ func (d *Diff) String() string {
 
 	switch d.Type {
 	case TypeLeftOnly:
-		return fmt.Sprintf("%c---- %s", typeChar, d.Path)
+		return fmt.Sprintf("%c----", typeChar)
 	case TypeRightOnly:
-		return fmt.Sprintf("%c++++ %s", typeChar, d.Path)
+		return fmt.Sprintf("%c++++", typeChar)
 	case TypeChanged:
 		// Mode, Size, ModTime
 		sb := strings.Builder{}
@@ -362,15 +587,33 @@ func (d *Diff) String() string {
 		}
 		if d.Changed.HashChanged() {
 			sb.WriteString("x") // Hash has changed
+		} else if d.Changed.ContentChanged() {
+			sb.WriteString("c") // Number of entries inside the directory has changed
 		} else {
 			sb.WriteString("~") // Data unchanged
 		}
-		return fmt.Sprintf("%s %s", sb.String(), d.Path)
+		return sb.String()
 	default:
 		return ""
 	}
 }
 
+// Color used to display the difference on a terminal.
+// Items that only exist on the LHS are displayed as removed (red), items that only exist on the RHS as
+// added (green) and changed items in yellow.
+func (d *Diff) Color() style.Color {
+	switch d.Type {
+	case TypeLeftOnly:
+		return style.Red
+	case TypeRightOnly:
+		return style.Green
+	case TypeChanged:
+		return style.Yellow
+	default:
+		return style.Default
+	}
+}
+
 func (d Diff) FilterFlagsMask() FilterFlags {
 	var result FilterFlags = FilterNoOp
 
@@ -406,9 +649,24 @@ type CompareFn func(d Diff) error
 // Compare the differences between two ajfs database files.
 // fn Will be called for each difference that is found.
 // If fn returns [SkipAll] then the process will be stopped and nil will be returned as the error.
-func Compare(lhsPath string, rhsPath string,
+// The comparison stops with the context's error once the context is cancelled.
+func Compare(ctx context.Context, lhsPath string, rhsPath string,
 	includeFilters []FilterFlags, excludeFilters []FilterFlags,
 	fn CompareFn) error {
+	return CompareWithIgnore(ctx, lhsPath, rhsPath, includeFilters, excludeFilters, IgnoreRules{}, fn)
+}
+
+// Same as [Compare] but first applies the ignore rules to each difference.
+func CompareWithIgnore(ctx context.Context, lhsPath string, rhsPath string,
+	includeFilters []FilterFlags, excludeFilters []FilterFlags, ignore IgnoreRules,
+	fn CompareFn) error {
+	return CompareWithMode(ctx, lhsPath, rhsPath, includeFilters, excludeFilters, ignore, MatchById, fn)
+}
+
+// Same as [CompareWithIgnore] but the entries are paired up using the match mode.
+func CompareWithMode(ctx context.Context, lhsPath string, rhsPath string,
+	includeFilters []FilterFlags, excludeFilters []FilterFlags, ignore IgnoreRules, mode MatchMode,
+	fn CompareFn) error {
 
 	for _, f := range includeFilters {
 		if err := f.Validate(); err != nil {
@@ -434,6 +692,11 @@ func Compare(lhsPath string, rhsPath string,
 	}
 	defer rhs.Close()
 
+	// Identifiers derived using different namespaces never match
+	if (mode == MatchById) && (lhs.IdNamespace() != rhs.IdNamespace()) {
+		mode = MatchByRelativePath
+	}
+
 	var compFn = fn
 
 	hasIncludeFilters := len(includeFilters) > 0
@@ -472,10 +735,20 @@ func Compare(lhsPath string, rhsPath string,
 		}
 	}
 
+	if len(ignore.Paths) > 0 || ignore.Changes != ChangedNothing || ignore.ModTimePrecision > 0 {
+		filterFn := compFn
+		compFn = func(d Diff) error {
+			if !ignore.apply(&d) {
+				return nil
+			}
+			return filterFn(d)
+		}
+	}
+
 	onlyLHS := false
 
 	if lhs.Features().HasHashTable() && rhs.Features().HasHashTable() {
-		err = compareWithHashes(lhs, rhs, onlyLHS, compFn)
+		err = compareWithHashes(ctx, lhs, rhs, onlyLHS, mode, compFn)
 		if err != nil {
 			if err != SkipAll {
 				return err
@@ -483,7 +756,7 @@ func Compare(lhsPath string, rhsPath string,
 			return nil
 		}
 	} else {
-		err = CompareDatabases(lhs, rhs, onlyLHS, compFn)
+		err = CompareDatabasesWithMode(ctx, lhs, rhs, onlyLHS, mode, compFn)
 		if err != nil {
 			if err != SkipAll {
 				return err
@@ -495,17 +768,37 @@ func Compare(lhsPath string, rhsPath string,
 	return nil
 }
 
-func CompareDatabases(lhs *db.DatabaseFile, rhs *db.DatabaseFile, onlyLHS bool, fn CompareFn) error {
-	lhsMap, err := lhs.BuildIdToInfoMap()
+func CompareDatabases(ctx context.Context, lhs *db.DatabaseFile, rhs *db.DatabaseFile, onlyLHS bool, fn CompareFn) error {
+	return CompareDatabasesWithMode(ctx, lhs, rhs, onlyLHS, MatchById, fn)
+}
+
+// Same as [CompareDatabases] but the entries are paired up using the match mode.
+func CompareDatabasesWithMode(ctx context.Context, lhs *db.DatabaseFile, rhs *db.DatabaseFile, onlyLHS bool,
+	mode MatchMode, fn CompareFn) error {
+	lhsMap, err := mode.buildInfoMap(ctx, lhs)
 	if err != nil {
 		return fmt.Errorf("left hand side error. %w", err)
 	}
 
-	rhsMap, err := rhs.BuildIdToInfoMap()
+	rhsMap, err := mode.buildInfoMap(ctx, rhs)
 	if err != nil {
 		return fmt.Errorf("right hand side error. %w", err)
 	}
 
+	// Directory content changes can only be detected when both sides have the directory statistics
+	var lhsDirStats, rhsDirStats db.DirStatsTable
+	if hasDirStats(lhs) && hasDirStats(rhs) {
+		lhsDirStats, err = lhs.ReadDirStats(ctx)
+		if err != nil {
+			return fmt.Errorf("left hand side error. %w", err)
+		}
+
+		rhsDirStats, err = rhs.ReadDirStats(ctx)
+		if err != nil {
+			return fmt.Errorf("right hand side error. %w", err)
+		}
+	}
+
 	lessFn := func(lhs path.Info, rhs path.Info) bool {
 		return lhs.Path < rhs.Path
 	}
@@ -515,9 +808,13 @@ func CompareDatabases(lhs *db.DatabaseFile, rhs *db.DatabaseFile, onlyLHS bool,
 	sortedLhsOnly := collection.MapSortedByValueFunc(lhsOnly, lessFn)
 
 	for _, kv := range sortedLhsOnly {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		err = fn(Diff{
 			Type:  TypeLeftOnly,
-			Id:    kv.Value.Id,
+			Id:    kv.Key,
 			Path:  kv.Value.Path,
 			IsDir: kv.Value.IsDir(),
 			Size:  kv.Value.Size,
@@ -534,9 +831,13 @@ func CompareDatabases(lhs *db.DatabaseFile, rhs *db.DatabaseFile, onlyLHS bool,
 		sortedRhsOnly := collection.MapSortedByValueFunc(rhsOnly, lessFn)
 
 		for _, kv := range sortedRhsOnly {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
 			err = fn(Diff{
 				Type:  TypeRightOnly,
-				Id:    kv.Value.Id,
+				Id:    kv.Key,
 				Path:  kv.Value.Path,
 				IsDir: kv.Value.IsDir(),
 				Size:  kv.Value.Size,
@@ -551,19 +852,28 @@ func CompareDatabases(lhs *db.DatabaseFile, rhs *db.DatabaseFile, onlyLHS bool,
 	// What exists in both
 	both := collection.MapIntersection(lhsMap, rhsMap)
 	for k := range both {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		lv := lhsMap[k]
 		rv := rhsMap[k]
 
 		// Check what has changed
 		var changed ChangedFlags
-		if lv.Mode != rv.Mode {
-			changed |= ChangedMode
-		}
-		if lv.Size != rv.Size {
-			changed |= ChangedSize
+		for _, f := range compareFields {
+			if f.Differs(&lv, &rv) {
+				changed |= f.flag
+			}
 		}
-		if lv.ModTime != rv.ModTime {
-			changed |= ChangedModTime
+		if (lhsDirStats != nil) && lv.IsDir() && rv.IsDir() {
+			contentChanged, err := dirContentChanged(lhs, lhsDirStats, lv.Id, rhs, rhsDirStats, rv.Id)
+			if err != nil {
+				return err
+			}
+			if contentChanged {
+				changed |= ChangedContent
+			}
 		}
 
 		var diffType Type
@@ -575,11 +885,13 @@ func CompareDatabases(lhs *db.DatabaseFile, rhs *db.DatabaseFile, onlyLHS bool,
 
 		err = fn(Diff{
 			Type:    diffType,
-			Id:      lv.Id,
+			Id:      k,
 			Path:    lv.Path,
 			Changed: changed,
 			IsDir:   lv.IsDir(),
 			Size:    lv.Size,
+
+			ModTimeDelta: rv.ModTime.Sub(lv.ModTime),
 		})
 		if err != nil {
 			return err
@@ -589,33 +901,60 @@ func CompareDatabases(lhs *db.DatabaseFile, rhs *db.DatabaseFile, onlyLHS bool,
 	return nil
 }
 
-func compareWithHashes(lhs *db.DatabaseFile, rhs *db.DatabaseFile, onlyLHS bool, fn CompareFn) error {
-	lhsAlgo, err := lhs.HashTableAlgo()
+// Return true if the database has directory statistics that can be read.
+func hasDirStats(dbf *db.DatabaseFile) bool {
+	return dbf.Features().HasDirStats() && !dbf.Limited()
+}
+
+// Return true if the number of children or files inside the directory are different.
+func dirContentChanged(lhs *db.DatabaseFile, lhsDirStats db.DirStatsTable, lhsId path.Id,
+	rhs *db.DatabaseFile, rhsDirStats db.DirStatsTable, rhsId path.Id) (bool, error) {
+
+	lv, err := lhs.FindEntryIndexAndOffset(lhsId)
 	if err != nil {
-		return fmt.Errorf("failed to get the left hand side hashing algorithm. %w", err)
+		return false, fmt.Errorf("left hand side error. %w", err)
 	}
 
-	rhsAlgo, err := rhs.HashTableAlgo()
+	rv, err := rhs.FindEntryIndexAndOffset(rhsId)
 	if err != nil {
-		return fmt.Errorf("failed to get the right hand side hashing algorithm. %w", err)
+		return false, fmt.Errorf("right hand side error. %w", err)
 	}
 
-	if lhsAlgo != rhsAlgo {
+	ls := lhsDirStats[int(lv.Index)]
+	rs := rhsDirStats[int(rv.Index)]
+	return (ls.Children != rs.Children) || (ls.Files != rs.Files), nil
+}
+
+func compareWithHashes(ctx context.Context, lhs *db.DatabaseFile, rhs *db.DatabaseFile, onlyLHS bool,
+	mode MatchMode, fn CompareFn) error {
+	lhsAlgos, err := lhs.HashTableAlgos(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the left hand side hashing algorithms. %w", err)
+	}
+
+	rhsAlgos, err := rhs.HashTableAlgos(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the right hand side hashing algorithms. %w", err)
+	}
+
+	// Either database can carry additional hash tables, use the strongest algorithm they have in common
+	algo, ok := db.StrongestCommonAlgo(lhsAlgos, rhsAlgos)
+	if !ok {
 		// Can't compare hashes so just do normal compare
-		return CompareDatabases(lhs, rhs, onlyLHS, fn)
+		return CompareDatabasesWithMode(ctx, lhs, rhs, onlyLHS, mode, fn)
 	}
 
-	lhsMap, err := lhs.BuildIdToHashMap()
+	lhsMap, err := mode.buildHashMap(ctx, lhs, algo)
 	if err != nil {
 		return fmt.Errorf("failed to build the left hand side hash map. %w", err)
 	}
 
-	rhsMap, err := rhs.BuildIdToHashMap()
+	rhsMap, err := mode.buildHashMap(ctx, rhs, algo)
 	if err != nil {
 		return fmt.Errorf("failed to build the right hand side hash map. %w", err)
 	}
 
-	err = CompareDatabases(lhs, rhs, onlyLHS, func(d Diff) error {
+	err = CompareDatabasesWithMode(ctx, lhs, rhs, onlyLHS, mode, func(d Diff) error {
 		// Check if the hashes are different if this diff is for a file (!dir)
 		// and the diff thus far indicates nothing or meta has changed
 		if !d.IsDir && ((d.Type == TypeNothing) || (d.Type == TypeChanged)) {
@@ -636,19 +975,47 @@ func compareWithHashes(lhs *db.DatabaseFile, rhs *db.DatabaseFile, onlyLHS bool,
 	return nil
 }
 
+// Returns true if any of the paths is a database that recorded the members of archives as virtual entries.
+// Paths that are not files (e.g. a file hierarchy) are skipped.
+func recordsArchives(paths ...string) (bool, error) {
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		exists, err := file.FileExists(p)
+		if err != nil {
+			return false, err
+		}
+		if !exists {
+			continue
+		}
+		archives, err := db.ReadArchives(p)
+		if err != nil {
+			return false, fmt.Errorf("failed to read the ajfs header of %q. %w", p, err)
+		}
+		if archives {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // Create a temporary database by scanning the path.
+// When archives is true the members of tar and zip archives are also recorded.
 // Returns the path of the temporary database.
-func makeTempDatabase(cfg Config, path string) (string, error) {
+func makeTempDatabase(ctx context.Context, cfg Config, path string, archives bool) (string, error) {
 	dbPath := filepath.Join(os.TempDir(), filepath.Base(path)+".ajfs")
 
 	scanCfg := scan.Config{
 		CommonConfig: cfg.CommonConfig,
 		Root:         path,
+		DirStats:     true,
+		Archives:     archives,
 	}
 	scanCfg.DbPath = dbPath
 	scanCfg.ForceOverride = true
 
-	err := scan.Run(scanCfg)
+	err := scan.Run(ctx, scanCfg)
 	if err != nil {
 		return "", err
 	}
@@ -672,6 +1039,10 @@ type DiffStats struct {
 	SizeChanged    int // Count of items where the size has changed
 	ModTimeChanged int // Count of items where the last modification time changed
 	HashChanged    int // Count of items where the hash has changed
+	ContentChanged int // Count of directories where the number of entries inside has changed
+
+	InTrash int // Count of left hand side only items that were found in a trash
+	Gone    int // Count of left hand side only items that were not found in any trash
 
 	Fn CompareFn // The compare function to be called
 }
@@ -712,7 +1083,121 @@ func (ds *DiffStats) Compare(d Diff) error {
 		if flags&FilterChangedHash != 0 {
 			ds.HashChanged++
 		}
+
+		if flags&FilterChangedContent != 0 {
+			ds.ContentChanged++
+		}
+
+		switch d.Trash {
+		case TrashFound:
+			ds.InTrash++
+		case TrashGone:
+			ds.Gone++
+		}
 	}
 
 	return ds.Fn(d)
 }
+
+//-----------------------------------------------------------------------------
+
+// DepthSummary rolls up the file differences below a depth into a single summary per directory.
+type DepthSummary struct {
+	depth  int
+	groups map[string]*DirSummary
+}
+
+// DirSummary is the rolled up file differences for a directory.
+type DirSummary struct {
+	Path    string // Path of the directory ("." for the files directly inside the root)
+	Removed int    // Count of files that only exist on the left hand side
+	Added   int    // Count of files that only exist on the right hand side
+	Changed int    // Count of files that have changed
+	Size    uint64 // Total size of the files
+}
+
+// Create a new summary that rolls up the differences below depth (1 or more).
+func NewDepthSummary(depth int) *DepthSummary {
+	if depth < 1 {
+		panic("expected a depth of 1 or more")
+	}
+	return &DepthSummary{
+		depth:  depth,
+		groups: make(map[string]*DirSummary),
+	}
+}
+
+// Compare function that will add the file difference to the summary of its directory.
+// Directories are not counted since their changes are the result of the files inside them.
+func (s *DepthSummary) Compare(d Diff) error {
+	if d.Type == TypeNothing || d.IsDir {
+		return nil
+	}
+
+	key := s.dirAtDepth(d.Path)
+	group, exists := s.groups[key]
+	if !exists {
+		group = &DirSummary{Path: key}
+		s.groups[key] = group
+	}
+
+	switch d.Type {
+	case TypeLeftOnly:
+		group.Removed++
+	case TypeRightOnly:
+		group.Added++
+	case TypeChanged:
+		group.Changed++
+	}
+	group.Size += d.Size
+
+	return nil
+}
+
+// Summaries returns the directory summaries sorted by path.
+func (s *DepthSummary) Summaries() []DirSummary {
+	result := make([]DirSummary, 0, len(s.groups))
+	for _, group := range s.groups {
+		result = append(result, *group)
+	}
+	slices.SortFunc(result, func(a, b DirSummary) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return result
+}
+
+// The directory containing the path, limited to the first depth components.
+func (s *DepthSummary) dirAtDepth(p string) string {
+	dir := filepath.Dir(p)
+	if dir == "." {
+		return dir
+	}
+
+	components := strings.Split(dir, string(filepath.Separator))
+	if len(components) > s.depth {
+		components = components[:s.depth]
+	}
+	return filepath.Join(components...)
+}
+
+// Stringer implementation.
+func (s DirSummary) String() string {
+	return fmt.Sprintf("%s: %s", s.Path, s.counts())
+}
+
+// The counts and total size of the files, e.g. "134 added, 2 changed, 1.2 GB".
+func (s DirSummary) counts() string {
+	parts := make([]string, 0, 4)
+	if s.Added > 0 {
+		parts = append(parts, fmt.Sprintf("%d added", s.Added))
+	}
+	if s.Removed > 0 {
+		parts = append(parts, fmt.Sprintf("%d removed", s.Removed))
+	}
+	if s.Changed > 0 {
+		parts = append(parts, fmt.Sprintf("%d changed", s.Changed))
+	}
+	parts = append(parts, human.Bytes(s.Size))
+
+	return strings.Join(parts, ", ")
+}
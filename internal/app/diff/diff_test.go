@@ -21,16 +21,24 @@
 package diff_test
 
 import (
+	"bytes"
+	"context"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/andrejacobs/ajfs/internal/app/addhash"
 	"github.com/andrejacobs/ajfs/internal/app/config"
 	"github.com/andrejacobs/ajfs/internal/app/diff"
 	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/db"
 	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/ajfs/internal/testshared"
 	"github.com/andrejacobs/go-aj/ajhash"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -107,6 +115,13 @@ func TestDiffString(t *testing.T) {
 			flags: diff.ChangedSize | diff.ChangedMode | diff.ChangedModTime | diff.ChangedHash,
 			exp:   "fmslx a.txt",
 		},
+		{
+			typ:   diff.TypeChanged,
+			path:  "dirA",
+			isDir: true,
+			flags: diff.ChangedModTime | diff.ChangedContent,
+			exp:   "d~~lc dirA",
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.exp, func(t *testing.T) {
@@ -140,7 +155,7 @@ func TestDiffCompare(t *testing.T) {
 		},
 		Root: "../../testdata/diff/a",
 	}
-	require.NoError(t, scan.Run(cfg))
+	require.NoError(t, scan.Run(context.Background(), cfg))
 
 	rhsPath := filepath.Join(t.TempDir(), "unit-testing-rhs")
 	_ = os.Remove(rhsPath)
@@ -148,13 +163,13 @@ func TestDiffCompare(t *testing.T) {
 
 	cfg.DbPath = rhsPath
 	cfg.Root = "../../testdata/diff/b"
-	require.NoError(t, scan.Run(cfg))
+	require.NoError(t, scan.Run(context.Background(), cfg))
 
 	lhs := make([]string, 0, 10)
 	rhs := make([]string, 0, 10)
 	changed := make([]string, 0, 10)
 
-	err := diff.Compare(lhsPath, rhsPath, []diff.FilterFlags{}, []diff.FilterFlags{}, func(d diff.Diff) error {
+	err := diff.Compare(context.Background(), lhsPath, rhsPath, []diff.FilterFlags{}, []diff.FilterFlags{}, func(d diff.Diff) error {
 		if d.Path == "." {
 			return nil
 		}
@@ -209,6 +224,262 @@ func TestDiffCompare(t *testing.T) {
 	assert.Equal(t, expectedChanged, changed)
 }
 
+func TestDiffCompareWithMode(t *testing.T) {
+	// Create databases for the same tree where the identifiers were derived differently
+	createDb := func(dbPath string, root string, idFn func(p string) path.Id) {
+		dbf, err := db.CreateDatabase(dbPath, root, db.FeatureJustEntries)
+		require.NoError(t, err)
+		modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		for _, p := range []string{".", "a", "a/1.txt", "2.txt"} {
+			pi := path.Info{Id: idFn(p), Path: filepath.FromSlash(p), Size: 1, ModTime: modTime}
+			if p == "." || p == "a" {
+				pi.Mode = fs.ModeDir | 0o755
+				pi.Size = 0
+			}
+			if p == "2.txt" && root == "/rhs" {
+				pi.Size = 2
+			}
+			require.NoError(t, dbf.WriteEntry(&pi))
+		}
+		require.NoError(t, dbf.FinishEntries())
+		require.NoError(t, dbf.Close())
+	}
+
+	lhsPath := filepath.Join(t.TempDir(), "unit-testing-lhs")
+	rhsPath := filepath.Join(t.TempDir(), "unit-testing-rhs")
+	createDb(lhsPath, "/lhs", func(p string) path.Id { return path.IdFromPath(p) })
+	createDb(rhsPath, "/rhs", func(p string) path.Id { return path.IdFromPath("/rhs/" + p) })
+
+	compare := func(mode diff.MatchMode) []string {
+		result := make([]string, 0, 10)
+		err := diff.CompareWithMode(context.Background(), lhsPath, rhsPath, []diff.FilterFlags{}, []diff.FilterFlags{},
+			diff.IgnoreRules{}, mode, func(d diff.Diff) error {
+				if d.Type != diff.TypeNothing {
+					result = append(result, d.String())
+				}
+				return nil
+			})
+		require.NoError(t, err)
+		slices.Sort(result)
+		return result
+	}
+
+	// Nothing pairs up by identifier
+	assert.Len(t, compare(diff.MatchById), 8)
+
+	assert.Equal(t, []string{"f~s~~ 2.txt"}, compare(diff.MatchByRelativePath))
+}
+
+func TestDiffCompareIdNamespaces(t *testing.T) {
+	createDb := func(dbPath string, namespace string) {
+		dbf, err := db.CreateDatabaseWithIdNamespace(dbPath, "/test", db.FeatureJustEntries, db.ChecksumCRC32,
+			db.CurrentVersion(), namespace)
+		require.NoError(t, err)
+		modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		for _, p := range []string{"1.txt", "2.txt"} {
+			pi := path.Info{Id: path.IdFromPath(p), Path: p, Size: 1, ModTime: modTime}
+			require.NoError(t, dbf.WriteEntry(&pi))
+		}
+		require.NoError(t, dbf.FinishEntries())
+		require.NoError(t, dbf.Close())
+	}
+
+	lhsPath := filepath.Join(t.TempDir(), "unit-testing-lhs")
+	rhsPath := filepath.Join(t.TempDir(), "unit-testing-rhs")
+	createDb(lhsPath, "")
+	createDb(rhsPath, "uuid:unit-testing")
+
+	// The entries are paired up by relative path since the identifiers differ
+	count := 0
+	err := diff.Compare(context.Background(), lhsPath, rhsPath, nil, nil, func(d diff.Diff) error {
+		assert.Equal(t, diff.Type(diff.TypeNothing), d.Type, d.String())
+		count++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestDiffCompareModTimes(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	createDb := func(dbPath string, shift map[string]time.Duration, loc *time.Location) {
+		dbf, err := db.CreateDatabase(dbPath, "/test", db.FeatureJustEntries)
+		require.NoError(t, err)
+		for _, p := range []string{"same.txt", "zone.txt", "fat.txt", "touched.txt"} {
+			pi := path.Info{Id: path.IdFromPath(p), Path: p, Size: 1, ModTime: modTime.Add(shift[p])}
+			if p == "zone.txt" {
+				pi.ModTime = pi.ModTime.In(loc)
+			}
+			require.NoError(t, dbf.WriteEntry(&pi))
+		}
+		require.NoError(t, dbf.FinishEntries())
+		require.NoError(t, dbf.Close())
+	}
+
+	lhsPath := filepath.Join(t.TempDir(), "unit-testing-lhs")
+	rhsPath := filepath.Join(t.TempDir(), "unit-testing-rhs")
+	createDb(lhsPath, nil, time.UTC)
+	createDb(rhsPath, map[string]time.Duration{"fat.txt": 1500 * time.Millisecond, "touched.txt": time.Hour},
+		time.FixedZone("SAST", 2*60*60))
+
+	compare := func(ignore diff.IgnoreRules) []string {
+		result := make([]string, 0, 4)
+		err := diff.CompareWithIgnore(context.Background(), lhsPath, rhsPath, []diff.FilterFlags{}, []diff.FilterFlags{}, ignore,
+			func(d diff.Diff) error {
+				if d.Type != diff.TypeNothing {
+					result = append(result, d.String())
+				}
+				return nil
+			})
+		require.NoError(t, err)
+		slices.Sort(result)
+		return result
+	}
+
+	// The same instant recorded in another time zone is not a change
+	assert.Equal(t, []string{"f~~l~ fat.txt", "f~~l~ touched.txt"}, compare(diff.IgnoreRules{}))
+
+	assert.Equal(t, []string{"f~~l~ touched.txt"}, compare(diff.IgnoreRules{ModTimePrecision: 2 * time.Second}))
+}
+
+func TestDiffCompareWithIgnore(t *testing.T) {
+	lhsPath := filepath.Join(t.TempDir(), "unit-testing-lhs")
+	rhsPath := filepath.Join(t.TempDir(), "unit-testing-rhs")
+
+	cfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: lhsPath,
+		},
+		Root: "../../testdata/diff/a",
+	}
+	require.NoError(t, scan.Run(context.Background(), cfg))
+
+	cfg.DbPath = rhsPath
+	cfg.Root = "../../testdata/diff/b"
+	require.NoError(t, scan.Run(context.Background(), cfg))
+
+	paths, err := diff.ParseIgnorePaths([]string{"^quick(/|$)", "^fox(/|$)"})
+	require.NoError(t, err)
+	changes, err := diff.ParseIgnoreChanges([]string{"mode", "mtime"})
+	require.NoError(t, err)
+	ignore := diff.IgnoreRules{Paths: paths, Changes: changes}
+
+	result := make([]string, 0, 10)
+	unchanged := make([]string, 0, 10)
+
+	err = diff.CompareWithIgnore(context.Background(), lhsPath, rhsPath, []diff.FilterFlags{}, []diff.FilterFlags{}, ignore, func(d diff.Diff) error {
+		if d.Path == "." {
+			return nil
+		}
+		if d.Type == diff.TypeNothing {
+			unchanged = append(unchanged, d.Path)
+		} else {
+			result = append(result, d.String())
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	expected := []string{
+		"d---- dir1",
+		"f---- dir1/lhs-only",
+		"d++++ hole",
+		"f++++ hole/4.txt",
+		"d++++ dir2",
+		"f++++ dir2/rhs-only",
+		"f~s~~ both/6.txt",
+	}
+	slices.Sort(expected)
+	slices.Sort(result)
+	assert.Equal(t, expected, result)
+
+	assert.Contains(t, unchanged, "both/7.txt")
+	assert.Contains(t, unchanged, "both/8.txt")
+}
+
+func TestParseIgnoreChanges(t *testing.T) {
+	changes, err := diff.ParseIgnoreChanges([]string{"MTime", " mode", "size", "hash", "content", ""})
+	require.NoError(t, err)
+	assert.Equal(t, diff.ChangedFlags(diff.ChangedModTime|diff.ChangedMode|diff.ChangedSize|diff.ChangedHash|diff.ChangedContent), changes)
+
+	_, err = diff.ParseIgnoreChanges([]string{"perms"})
+	assert.ErrorContains(t, err, "invalid change class")
+
+	_, err = diff.ParseIgnorePaths([]string{"("})
+	assert.ErrorContains(t, err, "invalid ignore path pattern")
+}
+
+func TestDepthSummary(t *testing.T) {
+	summary := diff.NewDepthSummary(2)
+
+	diffs := []diff.Diff{
+		{Type: diff.TypeRightOnly, Path: "photos/2021/a.jpg", Size: 100},
+		{Type: diff.TypeRightOnly, Path: "photos/2021/trip/b.jpg", Size: 200},
+		{Type: diff.TypeChanged, Path: "photos/2021/c.jpg", Size: 50, Changed: diff.ChangedSize},
+		{Type: diff.TypeRightOnly, Path: "photos/2021", IsDir: true},
+		{Type: diff.TypeLeftOnly, Path: "photos/old.jpg", Size: 10},
+		{Type: diff.TypeNothing, Path: "photos/same.jpg", Size: 1000},
+		{Type: diff.TypeChanged, Path: "top.txt", Size: 5, Changed: diff.ChangedModTime},
+	}
+	for _, d := range diffs {
+		require.NoError(t, summary.Compare(d))
+	}
+
+	expected := []diff.DirSummary{
+		{Path: ".", Changed: 1, Size: 5},
+		{Path: "photos", Removed: 1, Size: 10},
+		{Path: filepath.Join("photos", "2021"), Added: 2, Changed: 1, Size: 350},
+	}
+	assert.Equal(t, expected, summary.Summaries())
+
+	assert.Equal(t, "photos/2021: 2 added, 1 changed, 350 B", expected[2].String())
+	assert.Equal(t, "photos: 1 removed, 10 B", expected[1].String())
+}
+
+func TestReport(t *testing.T) {
+	report := diff.NewReport()
+
+	diffs := []diff.Diff{
+		{Type: diff.TypeRightOnly, Path: filepath.Join("photos", "2021", "a.jpg"), Size: 100},
+		{Type: diff.TypeChanged, Path: filepath.Join("photos", "2021", "c.jpg"), Size: 50, Changed: diff.ChangedSize},
+		{Type: diff.TypeRightOnly, Path: filepath.Join("photos", "2021"), IsDir: true},
+		{Type: diff.TypeLeftOnly, Path: filepath.Join("photos", "<old>.jpg"), Size: 10},
+		{Type: diff.TypeNothing, Path: filepath.Join("photos", "same.jpg"), Size: 1000},
+		{Type: diff.TypeChanged, Path: "top.txt", Size: 5, Changed: diff.ChangedModTime},
+	}
+	for _, d := range diffs {
+		require.NoError(t, report.Compare(d))
+	}
+
+	var buf bytes.Buffer
+	created := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, report.WriteHTML(&buf, "lhs.ajfs", "rhs.ajfs", created))
+	out := buf.String()
+
+	assert.Contains(t, out, "<code>lhs.ajfs</code>")
+	assert.Contains(t, out, "<code>rhs.ajfs</code>")
+	assert.Contains(t, out, "2025-06-01 12:00:00 UTC")
+	assert.Contains(t, out, "<td>1 added, 1 removed, 2 changed, 165 B</td>")
+
+	// Directories with the rolled up differences of the files below them
+	assert.Contains(t, out, `<span class="marker"></span>photos/<span class="counts">1 added, 1 removed, 1 changed, 160 B</span>`)
+	assert.Contains(t, out, `<li class="added" data-path="photos/2021"><details open><summary><span class="marker">d&#43;&#43;&#43;&#43;</span>2021/<span class="counts">1 added, 1 changed, 150 B</span>`)
+
+	// Items
+	assert.Contains(t, out, `<li class="added" data-path="photos/2021/a.jpg"><span class="marker">f&#43;&#43;&#43;&#43;</span>a.jpg<span class="size">100 B</span></li>`)
+	assert.Contains(t, out, `<li class="changed" data-path="photos/2021/c.jpg"><span class="marker">f~s~~</span>c.jpg<span class="size">50 B</span></li>`)
+	assert.Contains(t, out, `<span class="marker">f----</span>&lt;old&gt;.jpg`)
+	assert.Contains(t, out, `<li class="changed" data-path="top.txt"><span class="marker">f~~l~</span>top.txt`)
+	assert.NotContains(t, out, "same.jpg")
+
+	// Children are sorted by name
+	assert.Less(t, strings.Index(out, "2021/"), strings.Index(out, "&lt;old&gt;.jpg"))
+	assert.Less(t, strings.Index(out, "photos/"), strings.Index(out, "top.txt"))
+}
+
 func TestDiffCompareWithHashes(t *testing.T) {
 	lhsPath := filepath.Join(t.TempDir(), "unit-testing-lhs")
 	_ = os.Remove(lhsPath)
@@ -224,7 +495,7 @@ func TestDiffCompareWithHashes(t *testing.T) {
 		CalculateHashes: true,
 		Algo:            ajhash.AlgoSHA1,
 	}
-	require.NoError(t, scan.Run(cfg))
+	require.NoError(t, scan.Run(context.Background(), cfg))
 
 	rhsPath := filepath.Join(t.TempDir(), "unit-testing-rhs")
 	_ = os.Remove(rhsPath)
@@ -232,11 +503,11 @@ func TestDiffCompareWithHashes(t *testing.T) {
 
 	cfg.DbPath = rhsPath
 	cfg.Root = "../../testdata/diff/d"
-	require.NoError(t, scan.Run(cfg))
+	require.NoError(t, scan.Run(context.Background(), cfg))
 
 	changed := make([]string, 0, 10)
 
-	err := diff.Compare(lhsPath, rhsPath, []diff.FilterFlags{}, []diff.FilterFlags{}, func(d diff.Diff) error {
+	err := diff.Compare(context.Background(), lhsPath, rhsPath, []diff.FilterFlags{}, []diff.FilterFlags{}, func(d diff.Diff) error {
 		if d.Path == "." {
 			return nil
 		}
@@ -262,6 +533,53 @@ func TestDiffCompareWithHashes(t *testing.T) {
 	assert.Equal(t, expectedChanged, changed)
 }
 
+func TestDiffCompareWithExtraHashTable(t *testing.T) {
+	lhsPath := filepath.Join(t.TempDir(), "unit-testing-lhs")
+	cfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: lhsPath,
+		},
+		Root:            "../../testdata/diff/c",
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}
+	require.NoError(t, scan.Run(context.Background(), cfg))
+
+	rhsPath := filepath.Join(t.TempDir(), "unit-testing-rhs")
+	cfg.DbPath = rhsPath
+	cfg.Root = "../../testdata/diff/d"
+	cfg.Algo = ajhash.AlgoSHA256
+	require.NoError(t, scan.Run(context.Background(), cfg))
+
+	compare := func() []string {
+		changed := make([]string, 0, 10)
+		err := diff.Compare(context.Background(), lhsPath, rhsPath, []diff.FilterFlags{}, []diff.FilterFlags{}, func(d diff.Diff) error {
+			if d.Changed.HashChanged() {
+				changed = append(changed, d.String())
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		return changed
+	}
+
+	// No algorithm in common
+	assert.Empty(t, compare())
+
+	// The left hand side now also has SHA-256 hashes
+	require.NoError(t, addhash.Run(context.Background(), addhash.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: lhsPath,
+		},
+		Algo: ajhash.AlgoSHA256,
+	}))
+	assert.Equal(t, []string{"f~~~x changed.txt"}, compare())
+}
+
 func TestDiffCompareSame(t *testing.T) {
 	lhsPath := filepath.Join(t.TempDir(), "unit-testing-lhs")
 	_ = os.Remove(lhsPath)
@@ -275,9 +593,9 @@ func TestDiffCompareSame(t *testing.T) {
 		},
 		Root: "../../testdata/diff/a",
 	}
-	require.NoError(t, scan.Run(cfg))
+	require.NoError(t, scan.Run(context.Background(), cfg))
 
-	err := diff.Compare(lhsPath, lhsPath, []diff.FilterFlags{}, []diff.FilterFlags{}, func(d diff.Diff) error {
+	err := diff.Compare(context.Background(), lhsPath, lhsPath, []diff.FilterFlags{}, []diff.FilterFlags{}, func(d diff.Diff) error {
 		switch d.Type {
 		case diff.TypeNothing:
 			// nothing changed
@@ -308,7 +626,7 @@ func TestDiffCompareOrder(t *testing.T) {
 		},
 		Root: "../../testdata/diff/a",
 	}
-	require.NoError(t, scan.Run(cfg))
+	require.NoError(t, scan.Run(context.Background(), cfg))
 
 	rhsPath := filepath.Join(t.TempDir(), "unit-testing-rhs")
 	_ = os.Remove(rhsPath)
@@ -316,13 +634,13 @@ func TestDiffCompareOrder(t *testing.T) {
 
 	cfg.DbPath = rhsPath
 	cfg.Root = "../../testdata/diff/b"
-	require.NoError(t, scan.Run(cfg))
+	require.NoError(t, scan.Run(context.Background(), cfg))
 
 	// We are testing that the order of diffs are always, LHS only, followed by RHS only, lastly followed by Changed.
 	// 0 = LHS, 1 = RHS, 2 == Changed
 	state := 0
 
-	err := diff.Compare(lhsPath, rhsPath, []diff.FilterFlags{}, []diff.FilterFlags{}, func(d diff.Diff) error {
+	err := diff.Compare(context.Background(), lhsPath, rhsPath, []diff.FilterFlags{}, []diff.FilterFlags{}, func(d diff.Diff) error {
 		if d.Path == "." {
 			return nil
 		}
@@ -371,6 +689,7 @@ func TestFilterFlagsString(t *testing.T) {
 		{exp: "s", flags: diff.FilterChangedSize},
 		{exp: "l", flags: diff.FilterChangedModTime},
 		{exp: "x", flags: diff.FilterChangedHash},
+		{exp: "c", flags: diff.FilterChangedContent},
 		{exp: "fmslx", flags: diff.FilterFiles | diff.FilterChangedMode | diff.FilterChangedSize | diff.FilterChangedModTime | diff.FilterChangedHash},
 		{exp: "~fmslx", flags: diff.FilterTypeChanged | diff.FilterFiles | diff.FilterChangedMode | diff.FilterChangedSize | diff.FilterChangedModTime | diff.FilterChangedHash},
 	}
@@ -426,6 +745,10 @@ func TestParseFilterFlags(t *testing.T) {
 			exp:   diff.FilterChangedHash,
 			input: "x",
 		},
+		{
+			exp:   diff.FilterChangedContent,
+			input: "c",
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.exp.String(), func(t *testing.T) {
@@ -601,7 +924,7 @@ func TestDiffCompareIncludeFilter(t *testing.T) {
 		},
 		Root: "../../testdata/diff/a",
 	}
-	require.NoError(t, scan.Run(cfg))
+	require.NoError(t, scan.Run(context.Background(), cfg))
 
 	rhsPath := filepath.Join(t.TempDir(), "unit-testing-rhs")
 	_ = os.Remove(rhsPath)
@@ -609,7 +932,7 @@ func TestDiffCompareIncludeFilter(t *testing.T) {
 
 	cfg.DbPath = rhsPath
 	cfg.Root = "../../testdata/diff/b"
-	require.NoError(t, scan.Run(cfg))
+	require.NoError(t, scan.Run(context.Background(), cfg))
 
 	testCases := []struct {
 		desc    string
@@ -705,7 +1028,7 @@ func TestDiffCompareIncludeFilter(t *testing.T) {
 		t.Run(tC.desc, func(t *testing.T) {
 			result := make([]string, 0, 10)
 
-			err := diff.Compare(lhsPath, rhsPath, tC.filters, []diff.FilterFlags{}, func(d diff.Diff) error {
+			err := diff.Compare(context.Background(), lhsPath, rhsPath, tC.filters, []diff.FilterFlags{}, func(d diff.Diff) error {
 				if d.Path == "." {
 					return nil
 				}
@@ -740,7 +1063,7 @@ func TestDiffCompareExcludeFilter(t *testing.T) {
 		},
 		Root: "../../testdata/diff/a",
 	}
-	require.NoError(t, scan.Run(cfg))
+	require.NoError(t, scan.Run(context.Background(), cfg))
 
 	rhsPath := filepath.Join(t.TempDir(), "unit-testing-rhs")
 	_ = os.Remove(rhsPath)
@@ -748,7 +1071,7 @@ func TestDiffCompareExcludeFilter(t *testing.T) {
 
 	cfg.DbPath = rhsPath
 	cfg.Root = "../../testdata/diff/b"
-	require.NoError(t, scan.Run(cfg))
+	require.NoError(t, scan.Run(context.Background(), cfg))
 
 	testCases := []struct {
 		desc    string
@@ -897,7 +1220,7 @@ func TestDiffCompareExcludeFilter(t *testing.T) {
 		t.Run(tC.desc, func(t *testing.T) {
 			result := make([]string, 0, 10)
 
-			err := diff.Compare(lhsPath, rhsPath, []diff.FilterFlags{}, tC.filters, func(d diff.Diff) error {
+			err := diff.Compare(context.Background(), lhsPath, rhsPath, []diff.FilterFlags{}, tC.filters, func(d diff.Diff) error {
 				if d.Path == "." {
 					return nil
 				}
@@ -929,7 +1252,7 @@ func TestDiffCompareIncludeFilterWithHashes(t *testing.T) {
 		CalculateHashes: true,
 		Algo:            ajhash.AlgoSHA1,
 	}
-	require.NoError(t, scan.Run(cfg))
+	require.NoError(t, scan.Run(context.Background(), cfg))
 
 	rhsPath := filepath.Join(t.TempDir(), "unit-testing-rhs")
 	_ = os.Remove(rhsPath)
@@ -937,11 +1260,11 @@ func TestDiffCompareIncludeFilterWithHashes(t *testing.T) {
 
 	cfg.DbPath = rhsPath
 	cfg.Root = "../../testdata/diff/d"
-	require.NoError(t, scan.Run(cfg))
+	require.NoError(t, scan.Run(context.Background(), cfg))
 
 	changed := make([]string, 0, 10)
 
-	err := diff.Compare(lhsPath, rhsPath, []diff.FilterFlags{diff.FilterChangedHash}, []diff.FilterFlags{}, func(d diff.Diff) error {
+	err := diff.Compare(context.Background(), lhsPath, rhsPath, []diff.FilterFlags{diff.FilterChangedHash}, []diff.FilterFlags{}, func(d diff.Diff) error {
 		if d.Path == "." {
 			return nil
 		}
@@ -978,7 +1301,7 @@ func TestDiffCompareExcludeFilterWithHashes(t *testing.T) {
 		CalculateHashes: true,
 		Algo:            ajhash.AlgoSHA1,
 	}
-	require.NoError(t, scan.Run(cfg))
+	require.NoError(t, scan.Run(context.Background(), cfg))
 
 	rhsPath := filepath.Join(t.TempDir(), "unit-testing-rhs")
 	_ = os.Remove(rhsPath)
@@ -986,11 +1309,11 @@ func TestDiffCompareExcludeFilterWithHashes(t *testing.T) {
 
 	cfg.DbPath = rhsPath
 	cfg.Root = "../../testdata/diff/d"
-	require.NoError(t, scan.Run(cfg))
+	require.NoError(t, scan.Run(context.Background(), cfg))
 
 	changed := make([]string, 0, 10)
 
-	err := diff.Compare(lhsPath, rhsPath, []diff.FilterFlags{diff.FilterNoOp}, []diff.FilterFlags{diff.FilterChangedHash}, func(d diff.Diff) error {
+	err := diff.Compare(context.Background(), lhsPath, rhsPath, []diff.FilterFlags{diff.FilterNoOp}, []diff.FilterFlags{diff.FilterChangedHash}, func(d diff.Diff) error {
 		if d.Path == "." {
 			return nil
 		}
@@ -1024,7 +1347,7 @@ func TestDiffCompareIncludeAndExcludeFilter(t *testing.T) {
 		},
 		Root: "../../testdata/diff/a",
 	}
-	require.NoError(t, scan.Run(cfg))
+	require.NoError(t, scan.Run(context.Background(), cfg))
 
 	rhsPath := filepath.Join(t.TempDir(), "unit-testing-rhs")
 	_ = os.Remove(rhsPath)
@@ -1032,7 +1355,7 @@ func TestDiffCompareIncludeAndExcludeFilter(t *testing.T) {
 
 	cfg.DbPath = rhsPath
 	cfg.Root = "../../testdata/diff/b"
-	require.NoError(t, scan.Run(cfg))
+	require.NoError(t, scan.Run(context.Background(), cfg))
 
 	testCases := []struct {
 		desc    string
@@ -1059,7 +1382,7 @@ func TestDiffCompareIncludeAndExcludeFilter(t *testing.T) {
 		t.Run(tC.desc, func(t *testing.T) {
 			result := make([]string, 0, 10)
 
-			err := diff.Compare(lhsPath, rhsPath, []diff.FilterFlags{tC.include}, []diff.FilterFlags{tC.exclude}, func(d diff.Diff) error {
+			err := diff.Compare(context.Background(), lhsPath, rhsPath, []diff.FilterFlags{tC.include}, []diff.FilterFlags{tC.exclude}, func(d diff.Diff) error {
 				if d.Path == "." {
 					return nil
 				}
@@ -1097,7 +1420,7 @@ func TestDiffStats(t *testing.T) {
 		},
 		Root: "../../testdata/diff/a",
 	}
-	require.NoError(t, scan.Run(cfg))
+	require.NoError(t, scan.Run(context.Background(), cfg))
 
 	rhsPath := filepath.Join(t.TempDir(), "unit-testing-rhs")
 	_ = os.Remove(rhsPath)
@@ -1105,7 +1428,7 @@ func TestDiffStats(t *testing.T) {
 
 	cfg.DbPath = rhsPath
 	cfg.Root = "../../testdata/diff/b"
-	require.NoError(t, scan.Run(cfg))
+	require.NoError(t, scan.Run(context.Background(), cfg))
 
 	testCases := []struct {
 		desc    string
@@ -1195,7 +1518,7 @@ func TestDiffStats(t *testing.T) {
 				},
 			}
 
-			err := diff.Compare(lhsPath, rhsPath, []diff.FilterFlags{tC.include}, []diff.FilterFlags{tC.exclude}, result.Compare)
+			err := diff.Compare(context.Background(), lhsPath, rhsPath, []diff.FilterFlags{tC.include}, []diff.FilterFlags{tC.exclude}, result.Compare)
 			require.NoError(t, err)
 
 			result.Fn = nil
@@ -1207,6 +1530,52 @@ func TestDiffStats(t *testing.T) {
 //-----------------------------------------------------------------------------
 // Run tests
 
+func TestDiffCompareDirContent(t *testing.T) {
+	lhsRoot := t.TempDir()
+	rhsRoot := t.TempDir()
+
+	for _, p := range []string{"same/1.txt", "deep/sub/2.txt"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(lhsRoot, filepath.Dir(p)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(lhsRoot, p), []byte("hello"), 0644))
+		require.NoError(t, os.MkdirAll(filepath.Join(rhsRoot, filepath.Dir(p)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(rhsRoot, p), []byte("hello"), 0644))
+	}
+
+	// Only the number of files below "deep" changes, the direct children stay the same
+	require.NoError(t, os.WriteFile(filepath.Join(rhsRoot, "deep/sub/3.txt"), []byte("world"), 0644))
+
+	lhsPath := filepath.Join(t.TempDir(), "lhs.ajfs")
+	rhsPath := filepath.Join(t.TempDir(), "rhs.ajfs")
+
+	cfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: lhsPath,
+		},
+		Root:     lhsRoot,
+		DirStats: true,
+	}
+	require.NoError(t, scan.Run(context.Background(), cfg))
+
+	cfg.DbPath = rhsPath
+	cfg.Root = rhsRoot
+	require.NoError(t, scan.Run(context.Background(), cfg))
+
+	contentChanged := make([]string, 0, 4)
+	err := diff.Compare(context.Background(), lhsPath, rhsPath, []diff.FilterFlags{}, []diff.FilterFlags{}, func(d diff.Diff) error {
+		if d.Changed.ContentChanged() {
+			assert.True(t, d.IsDir)
+			contentChanged = append(contentChanged, d.Path)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	slices.Sort(contentChanged)
+	assert.Equal(t, []string{".", "deep", "deep/sub"}, contentChanged)
+}
+
 func TestRunTwoDirs(t *testing.T) {
 	if os.Getenv("SKIP_TEST") == "1" {
 		t.Skip("Skipping DiffCompare test")
@@ -1247,7 +1616,7 @@ func TestRunTwoDirs(t *testing.T) {
 		Fn:      fn,
 	}
 
-	err := diff.Run(cfg)
+	err := diff.Run(context.Background(), cfg)
 	require.NoError(t, err)
 
 	expectedLHSOnly := []string{
@@ -1302,7 +1671,7 @@ func TestRunTwoDatabases(t *testing.T) {
 		},
 		Root: "../../testdata/diff/a",
 	}
-	require.NoError(t, scan.Run(scanCfg))
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
 
 	rhsPath := filepath.Join(t.TempDir(), "unit-testing-rhs")
 	_ = os.Remove(rhsPath)
@@ -1310,7 +1679,7 @@ func TestRunTwoDatabases(t *testing.T) {
 
 	scanCfg.DbPath = rhsPath
 	scanCfg.Root = "../../testdata/diff/b"
-	require.NoError(t, scan.Run(scanCfg))
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
 
 	lhs := make([]string, 0, 10)
 	rhs := make([]string, 0, 10)
@@ -1346,7 +1715,7 @@ func TestRunTwoDatabases(t *testing.T) {
 		Fn:      fn,
 	}
 
-	err := diff.Run(cfg)
+	err := diff.Run(context.Background(), cfg)
 	require.NoError(t, err)
 
 	expectedLHSOnly := []string{
@@ -1396,7 +1765,7 @@ func TestRunSingleDatabases(t *testing.T) {
 		},
 		Root: "../../testdata/diff/a",
 	}
-	require.NoError(t, scan.Run(scanCfg))
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
 
 	fn := func(d diff.Diff) error {
 		switch d.Type {
@@ -1418,10 +1787,39 @@ func TestRunSingleDatabases(t *testing.T) {
 		Fn:      fn,
 	}
 
-	err := diff.Run(cfg)
+	err := diff.Run(context.Background(), cfg)
 	require.NoError(t, err)
 }
 
+func TestRunArchives(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "1.txt"), []byte("1"), 0o644))
+	testshared.CreateTar(t, filepath.Join(root, "backup.tar"), map[string]string{"2.txt": "2", "3.txt": "3"})
+
+	lhsPath := filepath.Join(t.TempDir(), "unit-testing-lhs")
+	scanCfg := testshared.ScanConfig(lhsPath, root, false)
+	scanCfg.Archives = true
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	// The members of the archive are also recorded for the file hierarchy
+	compared := 0
+	cfg := diff.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		LhsPath: lhsPath,
+		RhsPath: root,
+		Fn: func(d diff.Diff) error {
+			assert.True(t, d.Type == diff.TypeNothing, "%s changed", d.Path)
+			compared++
+			return nil
+		},
+	}
+	require.NoError(t, diff.Run(context.Background(), cfg))
+	assert.Equal(t, 5, compared)
+}
+
 func TestSkipAll(t *testing.T) {
 	lhsPath := filepath.Join(t.TempDir(), "unit-testing-lhs")
 	_ = os.Remove(lhsPath)
@@ -1435,7 +1833,7 @@ func TestSkipAll(t *testing.T) {
 		},
 		Root: "../../testdata/diff/a",
 	}
-	require.NoError(t, scan.Run(scanCfg))
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
 
 	count := 0
 	fn := func(d diff.Diff) error {
@@ -1452,7 +1850,7 @@ func TestSkipAll(t *testing.T) {
 		Fn:      fn,
 	}
 
-	err := diff.Run(cfg)
+	err := diff.Run(context.Background(), cfg)
 	require.NoError(t, err)
 
 	assert.Equal(t, 1, count)
@@ -1473,7 +1871,7 @@ func TestRunTwoDatabasesWithDifferentHashAlgos(t *testing.T) {
 		CalculateHashes: true,
 		Algo:            ajhash.AlgoSHA1,
 	}
-	require.NoError(t, scan.Run(scanCfg))
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
 
 	rhsPath := filepath.Join(t.TempDir(), "unit-testing-rhs")
 	_ = os.Remove(rhsPath)
@@ -1482,7 +1880,7 @@ func TestRunTwoDatabasesWithDifferentHashAlgos(t *testing.T) {
 	scanCfg.DbPath = rhsPath
 	scanCfg.Root = "../../testdata/diff/b"
 	scanCfg.Algo = ajhash.AlgoSHA256
-	require.NoError(t, scan.Run(scanCfg))
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
 
 	fn := func(d diff.Diff) error {
 		require.False(t, d.Changed.HashChanged())
@@ -1499,6 +1897,71 @@ func TestRunTwoDatabasesWithDifferentHashAlgos(t *testing.T) {
 		Fn:      fn,
 	}
 
-	err := diff.Run(cfg)
+	err := diff.Run(context.Background(), cfg)
 	require.NoError(t, err)
 }
+
+func TestRunCheckTrash(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", filepath.Join(tempDir, "home"))
+	t.Setenv("XDG_DATA_HOME", "")
+
+	root := filepath.Join(tempDir, "root")
+	for _, p := range []string{"keep.txt", "trashed.txt", "gone.txt"} {
+		require.NoError(t, os.MkdirAll(root, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, p), []byte(p), 0644))
+	}
+
+	lhsPath := filepath.Join(tempDir, "unit-testing-lhs")
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: lhsPath,
+		},
+		Root: root,
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	// Soft delete one file by moving it into the freedesktop.org trash and delete the other
+	desktopTrash := filepath.Join(tempDir, "home", ".local", "share", "Trash")
+	require.NoError(t, os.MkdirAll(filepath.Join(desktopTrash, "files"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(desktopTrash, "info"), 0755))
+	trashed := filepath.Join(desktopTrash, "files", "trashed.txt")
+	require.NoError(t, os.Rename(filepath.Join(root, "trashed.txt"), trashed))
+	info := "[Trash Info]\nPath=" + filepath.Join(root, "trashed.txt") + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(desktopTrash, "info", "trashed.txt.trashinfo"), []byte(info), 0644))
+	require.NoError(t, os.Remove(filepath.Join(root, "gone.txt")))
+
+	var result []string
+	stats := diff.DiffStats{
+		Fn: func(d diff.Diff) error {
+			if d.Type != diff.TypeNothing && !d.IsDir {
+				result = append(result, d.String())
+			}
+			return nil
+		},
+	}
+
+	cfg := diff.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		LhsPath:    lhsPath,
+		CheckTrash: true,
+		Fn:         stats.Compare,
+	}
+	require.NoError(t, diff.Run(context.Background(), cfg))
+
+	assert.Equal(t, []string{
+		"f---- gone.txt (gone)",
+		"f---- trashed.txt (found in trash: " + trashed + ")",
+	}, result)
+	assert.Equal(t, 1, stats.InTrash)
+	assert.Equal(t, 1, stats.Gone)
+
+	// The RHS has to be a file system hierarchy
+	cfg.RhsPath = lhsPath
+	assert.Error(t, diff.Run(context.Background(), cfg))
+}
@@ -0,0 +1,228 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package diff
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/andrejacobs/go-aj/human"
+)
+
+//go:embed report.html
+var reportTemplate string
+
+// Report collects the differences to be written as a standalone HTML report.
+// The report displays the differences as a collapsible tree with the rolled up file differences for each directory
+// and can be filtered by the type of difference and by path. It does not depend on any external resources so that it
+// can be shared with people that do not use ajfs.
+type Report struct {
+	root *reportNode
+}
+
+// A directory or item in the tree of differences.
+type reportNode struct {
+	Name     string
+	Path     string
+	Diff     *Diff         // nil for a directory that did not change itself but contains differences
+	Summary  DirSummary    // Rolled up file differences of all the files below a directory
+	Children []*reportNode // Sorted by name once the report is written
+
+	children map[string]*reportNode
+}
+
+// The data used to render the report template.
+type reportData struct {
+	Lhs     string
+	Rhs     string
+	Created string
+	Root    *reportNode
+}
+
+// Create a new report.
+func NewReport() *Report {
+	return &Report{
+		root: newReportNode(".", "."),
+	}
+}
+
+func newReportNode(name string, p string) *reportNode {
+	return &reportNode{
+		Name:     name,
+		Path:     p,
+		Summary:  DirSummary{Path: p},
+		children: make(map[string]*reportNode),
+	}
+}
+
+// Compare function that will add the difference to the report.
+func (r *Report) Compare(d Diff) error {
+	if d.Type == TypeNothing {
+		return nil
+	}
+
+	node := r.root
+	if d.Path == "." {
+		node.Diff = &d
+		return nil
+	}
+	node.add(d)
+
+	p := ""
+	for _, name := range strings.Split(d.Path, string(filepath.Separator)) {
+		p = filepath.Join(p, name)
+		child, exists := node.children[name]
+		if !exists {
+			child = newReportNode(name, p)
+			node.children[name] = child
+		}
+		node = child
+
+		if p != d.Path {
+			node.add(d)
+		}
+	}
+
+	node.Diff = &d
+	return nil
+}
+
+// Add the file difference to the rolled up summary of the directory.
+func (n *reportNode) add(d Diff) {
+	if d.IsDir {
+		return
+	}
+
+	switch d.Type {
+	case TypeLeftOnly:
+		n.Summary.Removed++
+	case TypeRightOnly:
+		n.Summary.Added++
+	case TypeChanged:
+		n.Summary.Changed++
+	}
+	n.Summary.Size += d.Size
+}
+
+// Sort the children by name, recursively.
+func (n *reportNode) sort() {
+	n.Children = n.Children[:0]
+	for _, child := range n.children {
+		child.sort()
+		n.Children = append(n.Children, child)
+	}
+	slices.SortFunc(n.Children, func(a, b *reportNode) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+// Return true if the node is displayed as a directory that can be collapsed.
+func (n *reportNode) IsDir() bool {
+	return len(n.children) > 0 || ((n.Diff != nil) && n.Diff.IsDir)
+}
+
+// The marker that describes the type of difference, e.g. "f++++". Empty when the node did not change itself.
+func (n *reportNode) Marker() string {
+	if n.Diff == nil {
+		return ""
+	}
+	return n.Diff.marker()
+}
+
+// The class used to style and filter the node.
+func (n *reportNode) Class() string {
+	if n.Diff == nil {
+		return "unchanged"
+	}
+
+	switch n.Diff.Type {
+	case TypeLeftOnly:
+		return "removed"
+	case TypeRightOnly:
+		return "added"
+	default:
+		return "changed"
+	}
+}
+
+// The human readable size of an item.
+func (n *reportNode) Size() string {
+	if n.Diff == nil || n.Diff.IsDir {
+		return ""
+	}
+	return human.Bytes(n.Diff.Size)
+}
+
+// The rolled up file differences of a directory, e.g. "134 added, 2 changed, 1.2 GB".
+func (n *reportNode) Counts() string {
+	if n.Summary.Added+n.Summary.Removed+n.Summary.Changed == 0 {
+		return ""
+	}
+	return n.Summary.counts()
+}
+
+// Write the report as a standalone HTML document.
+// lhs and rhs describe the two sides that were compared.
+func (r *Report) WriteHTML(w io.Writer, lhs string, rhs string, created time.Time) error {
+	tmpl, err := template.New("report").Parse(reportTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse the report template. %w", err)
+	}
+
+	r.root.sort()
+
+	data := reportData{
+		Lhs:     lhs,
+		Rhs:     rhs,
+		Created: created.Format(time.DateTime + " MST"),
+		Root:    r.root,
+	}
+
+	if err = tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to write the report. %w", err)
+	}
+	return nil
+}
+
+// Write the report as a standalone HTML document to the file at path.
+// See [Report.WriteHTML] for the other parameters.
+func (r *Report) WriteFile(path string, lhs string, rhs string, created time.Time) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create the report file %q. %w", path, err)
+	}
+
+	if err = r.WriteHTML(f, lhs, rhs, created); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("failed to close the report file %q. %w", path, err)
+	}
+	return nil
+}
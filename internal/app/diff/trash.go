@@ -0,0 +1,57 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package diff
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/andrejacobs/ajfs/internal/trash"
+)
+
+// TrashState describes whether an item that is missing from the file system was found in a trash.
+type TrashState int
+
+const (
+	TrashNotChecked TrashState = iota // The trash was not checked
+	TrashGone                         // The item was not found in any trash
+	TrashFound                        // The item was found in a trash (see Diff.TrashPath)
+)
+
+// Return a compare function that checks if the items that only exist on the LHS (i.e. missing from the file
+// system hierarchy at root) were moved into a trash before calling fn.
+func checkTrashFn(root string, fn CompareFn) (CompareFn, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the absolute path for %q. %w", root, err)
+	}
+
+	locator := trash.NewLocator()
+	return func(d Diff) error {
+		if d.Type == TypeLeftOnly {
+			d.Trash = TrashGone
+			if p, ok := locator.Find(filepath.Join(absRoot, d.Path), d.Size, d.IsDir); ok {
+				d.Trash = TrashFound
+				d.TrashPath = p
+			}
+		}
+		return fn(d)
+	}, nil
+}
@@ -0,0 +1,245 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package doctor provides the functionality for ajfs doctor command.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/go-aj/human"
+)
+
+// Config for the ajfs doctor command.
+type Config struct {
+	config.CommonConfig
+
+	Root string // [optional] Path that will be scanned.
+}
+
+// Report of the environment in which ajfs will be run.
+type Report struct {
+	OS   string // Operating system
+	Arch string // Architecture
+	CPUs int    // Number of logical CPUs
+
+	TotalMemory     uint64 // Total physical memory in bytes. 0 if it could not be determined.
+	AvailableMemory uint64 // Memory in bytes that is available without swapping. 0 if it could not be determined.
+
+	Root       PathReport // The path that will be scanned. Path is empty when not specified.
+	Db         PathReport // The database.
+	SameDevice bool       // The database and the scan root are stored on the same device.
+
+	SuggestedJobs int      // Suggested number of files to be processed at the same time (e.g. "ajfs grep --jobs").
+	Pitfalls      []string // Known issues that affect long runs in this environment.
+}
+
+// PathReport describes where a path is stored.
+type PathReport struct {
+	Path       string // Absolute path
+	FileSystem string // Name of the file system type. Empty if it could not be determined.
+
+	device   uint64
+	deviceOk bool
+}
+
+// Process the ajfs doctor command.
+func Run(ctx context.Context, cfg Config) error {
+	r, err := Diagnose(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	unknown := func(s string) string {
+		if s == "" {
+			return "unknown"
+		}
+		return s
+	}
+
+	cfg.Println("System")
+	cfg.Println(fmt.Sprintf("  OS:            %s/%s", r.OS, r.Arch))
+	cfg.Println(fmt.Sprintf("  CPUs:          %d", r.CPUs))
+	if r.TotalMemory > 0 {
+		cfg.Println(fmt.Sprintf("  Memory:        %s", human.Bytes(r.TotalMemory)))
+	} else {
+		cfg.Println("  Memory:        unknown")
+	}
+	if r.AvailableMemory > 0 {
+		cfg.Println(fmt.Sprintf("  Available:     %s", human.Bytes(r.AvailableMemory)))
+	} else {
+		cfg.Println("  Available:     unknown")
+	}
+
+	cfg.Println("Database")
+	cfg.Println(fmt.Sprintf("  Path:          %s", r.Db.Path))
+	cfg.Println(fmt.Sprintf("  File system:   %s", unknown(r.Db.FileSystem)))
+
+	if r.Root.Path != "" {
+		cfg.Println("Scan root")
+		cfg.Println(fmt.Sprintf("  Path:          %s", r.Root.Path))
+		cfg.Println(fmt.Sprintf("  File system:   %s", unknown(r.Root.FileSystem)))
+		cfg.Println(fmt.Sprintf("  Same device:   %t", r.SameDevice))
+	}
+
+	cfg.Println("Suggestions")
+	cfg.Println(fmt.Sprintf("  Jobs:          %d (e.g. ajfs grep --jobs)", r.SuggestedJobs))
+
+	if len(r.Pitfalls) > 0 {
+		cfg.Println("Pitfalls")
+		for _, p := range r.Pitfalls {
+			cfg.Println("  - " + p)
+		}
+	}
+
+	return nil
+}
+
+// Diagnose the environment in which the database will be created or updated.
+func Diagnose(ctx context.Context, cfg Config) (Report, error) {
+	r := Report{
+		OS:   runtime.GOOS,
+		Arch: runtime.GOARCH,
+		CPUs: runtime.NumCPU(),
+	}
+	r.TotalMemory, r.AvailableMemory = memory()
+
+	var err error
+	r.Db, err = inspectPath(cfg.DbPath)
+	if err != nil {
+		return r, err
+	}
+
+	if cfg.Root != "" {
+		if _, err := os.Stat(cfg.Root); err != nil {
+			return r, fmt.Errorf("failed to diagnose the scan root %q. %w", cfg.Root, err)
+		}
+
+		r.Root, err = inspectPath(cfg.Root)
+		if err != nil {
+			return r, err
+		}
+		r.SameDevice = r.Root.deviceOk && r.Db.deviceOk && (r.Root.device == r.Db.device)
+	}
+
+	r.Evaluate()
+	return r, nil
+}
+
+// Evaluate the suggestions and pitfalls based on the environment described by the report.
+func (r *Report) Evaluate() {
+	r.SuggestedJobs = max(r.CPUs, 1)
+	r.Pitfalls = make([]string, 0, 4)
+
+	root := fileSystemKind(r.Root.FileSystem)
+	dbKind := fileSystemKind(r.Db.FileSystem)
+
+	switch root {
+	case kindNetwork:
+		// Reading many files at the same time over the network mostly competes for the same link
+		r.SuggestedJobs = min(r.SuggestedJobs, 4)
+		r.Pitfalls = append(r.Pitfalls, fmt.Sprintf("The scan root is on a network file system (%s). Hashing throughput is limited by the network, "+
+			"interrupted runs can be continued using \"ajfs resume\" and the hash cache avoids hashing unchanged files again.", r.Root.FileSystem))
+	case kindFAT:
+		r.SuggestedJobs = min(r.SuggestedJobs, 2)
+		r.Pitfalls = append(r.Pitfalls, fmt.Sprintf("The scan root is on a FAT file system (%s) which stores the modification time with a coarse granularity "+
+			"(2 seconds for FAT). Use \"ajfs diff --mtime-precision 2s\" when comparing against a copy on another file system.", r.Root.FileSystem))
+	}
+
+	if dbKind == kindNetwork {
+		r.Pitfalls = append(r.Pitfalls, fmt.Sprintf("The database is on a network file system (%s). Consider creating it locally "+
+			"and using a larger --flush-interval with --fsync=close to reduce the number of round trips.", r.Db.FileSystem))
+	}
+	if dbKind == kindMemory {
+		r.Pitfalls = append(r.Pitfalls, fmt.Sprintf("The database is stored in memory (%s) and will be lost when the system is restarted.", r.Db.FileSystem))
+	}
+
+	if r.SameDevice {
+		r.Pitfalls = append(r.Pitfalls, "The database is on the same device as the scan root. Writing the hashes competes with reading the files, "+
+			"use a larger --flush-interval or store the database on another device.")
+	}
+
+	if (r.AvailableMemory > 0) && (r.AvailableMemory < lowMemory) {
+		r.Pitfalls = append(r.Pitfalls, fmt.Sprintf("Only %s of memory is available. Commands that compare or sort large databases "+
+			"(e.g. diff, dupes) keep the entries in memory and can become slow.", human.Bytes(r.AvailableMemory)))
+	}
+}
+
+//-----------------------------------------------------------------------------
+// Helpers
+
+// Memory below which comparing large databases is likely to cause swapping.
+const lowMemory = 1 << 30
+
+type fsKind int
+
+const (
+	kindLocal   fsKind = iota // Local disk (or unknown)
+	kindNetwork               // Network file system
+	kindFAT                   // FAT family with a coarse modification time
+	kindMemory                // Stored in memory
+)
+
+// Classify the file system type.
+func fileSystemKind(name string) fsKind {
+	switch strings.ToLower(name) {
+	case "nfs", "smb", "smb2", "smbfs", "cifs", "afpfs", "webdav", "9p", "ncpfs", "coda", "afs", "ceph":
+		return kindNetwork
+	case "msdos", "vfat", "fat", "fat32", "exfat":
+		return kindFAT
+	case "tmpfs", "ramfs":
+		return kindMemory
+	}
+	return kindLocal
+}
+
+// Determine where the path is stored.
+// The path does not need to exist yet (e.g. a new database), the nearest existing parent is used instead.
+func inspectPath(p string) (PathReport, error) {
+	absPath, err := filepath.Abs(p)
+	if err != nil {
+		return PathReport{}, fmt.Errorf("failed to get the absolute path from %q. %w", p, err)
+	}
+
+	result := PathReport{
+		Path: absPath,
+	}
+
+	existing := absPath
+	for {
+		if _, err := os.Stat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return result, nil
+		}
+		existing = parent
+	}
+
+	result.FileSystem = fileSystemType(existing)
+	result.device, result.deviceOk = device(existing)
+	return result, nil
+}
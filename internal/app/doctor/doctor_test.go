@@ -0,0 +1,119 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package doctor_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/doctor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnose(t *testing.T) {
+	root := t.TempDir()
+	cfg := doctor.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: filepath.Join(root, "does-not-exist-yet", "db.ajfs"),
+		},
+		Root: root,
+	}
+
+	r, err := doctor.Diagnose(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, runtime.GOOS, r.OS)
+	assert.Equal(t, runtime.NumCPU(), r.CPUs)
+	assert.Equal(t, cfg.DbPath, r.Db.Path)
+	assert.Equal(t, root, r.Root.Path)
+	assert.Positive(t, r.SuggestedJobs)
+
+	if runtime.GOOS != "windows" {
+		assert.True(t, r.SameDevice)
+		assert.True(t, containsPitfall(r, "same device"))
+	}
+
+	// The scan root has to exist
+	cfg.Root = filepath.Join(root, "missing")
+	_, err = doctor.Diagnose(context.Background(), cfg)
+	assert.Error(t, err)
+}
+
+func TestEvaluate(t *testing.T) {
+	r := doctor.Report{CPUs: 16}
+	r.Evaluate()
+	assert.Equal(t, 16, r.SuggestedJobs)
+	assert.Empty(t, r.Pitfalls)
+
+	r = doctor.Report{
+		CPUs: 16,
+		Root: doctor.PathReport{Path: "/mnt/share", FileSystem: "cifs"},
+		Db:   doctor.PathReport{Path: "/tmp/db.ajfs", FileSystem: "tmpfs"},
+	}
+	r.Evaluate()
+	assert.Equal(t, 4, r.SuggestedJobs)
+	assert.True(t, containsPitfall(r, "network file system (cifs)"))
+	assert.True(t, containsPitfall(r, "stored in memory"))
+
+	r = doctor.Report{
+		CPUs:            16,
+		AvailableMemory: 256 * 1024 * 1024,
+		Root:            doctor.PathReport{Path: "/media/usb", FileSystem: "vfat"},
+		Db:              doctor.PathReport{Path: "/mnt/nas/db.ajfs", FileSystem: "nfs"},
+	}
+	r.Evaluate()
+	assert.Equal(t, 2, r.SuggestedJobs)
+	assert.True(t, containsPitfall(r, "--mtime-precision 2s"))
+	assert.True(t, containsPitfall(r, "database is on a network file system"))
+	assert.True(t, containsPitfall(r, "of memory is available"))
+}
+
+func TestRun(t *testing.T) {
+	var outBuffer bytes.Buffer
+	cfg := doctor.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+			DbPath: filepath.Join(t.TempDir(), "db.ajfs"),
+		},
+	}
+
+	require.NoError(t, doctor.Run(context.Background(), cfg))
+	assert.Contains(t, outBuffer.String(), "CPUs:")
+	assert.Contains(t, outBuffer.String(), "Jobs:")
+	assert.NotContains(t, outBuffer.String(), "Scan root")
+}
+
+func containsPitfall(r doctor.Report, s string) bool {
+	for _, p := range r.Pitfalls {
+		if strings.Contains(p, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package dupes
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/andrejacobs/ajfs/internal/catalog"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/ajfs/internal/style"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/andrejacobs/go-aj/human"
+)
+
+// A file that belongs to one of the volumes in a catalog.
+type volumeEntry struct {
+	volume string
+	info   path.Info
+}
+
+// Find duplicate files across all the volumes in a catalog.
+// Only volumes that were hashed using the same algorithm as the first hashed volume can be compared.
+func catalogDuplicates(ctx context.Context, cfg Config) error {
+	if cfg.Subtrees || cfg.Within != "" || cfg.Against != "" || cfg.IgnoreFile != "" || cfg.Potential || cfg.Unreviewed || cfg.ConfirmBytes ||
+		cfg.Format != FormatDefault {
+		return fmt.Errorf("subtrees, within, against, ignore file, potential, unreviewed, confirm bytes and format can't be used with a catalog")
+	}
+
+	volumes, err := catalog.Volumes(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+
+	groups := make(map[string][]volumeEntry, 1024)
+	var algo *ajhash.Algo
+
+	donePhase := cfg.StartPhase("finding duplicates")
+	for _, v := range volumes {
+		dbf, err := db.OpenDatabase(v.Path)
+		if err != nil {
+			cfg.Errorln(fmt.Sprintf("WARNING: skipping the volume %q. %v", v.Name, err))
+			continue
+		}
+
+		err = collectVolumeHashes(ctx, cfg, dbf, v.Name, &algo, groups)
+		dbf.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	grandTotalSize := uint64(0)
+
+	var summary *extensionSummary
+	if cfg.ByExtension {
+		summary = newExtensionSummary()
+	}
+
+	for _, hash := range slices.Sorted(maps.Keys(groups)) {
+		members := groups[hash]
+		if len(members) < 2 || members[0].info.Size == 0 {
+			continue
+		}
+		var exts []string
+		if cfg.MixedExtensions {
+			if exts = extensions(members, func(m volumeEntry) string { return m.info.Path }); len(exts) < 2 {
+				continue
+			}
+		}
+
+		if summary != nil {
+			addGroup(summary, members, func(m volumeEntry) (string, uint64) { return m.info.Path, m.info.Size })
+			for _, m := range members {
+				grandTotalSize += m.info.Size
+			}
+			continue
+		}
+
+		fmt.Fprintln(cfg.Stdout, cfg.Paint(style.Dim, ">>>"))
+		fmt.Fprintln(cfg.Stdout, cfg.Paint(style.Bold, "Hash: "+hash))
+		fmt.Fprintf(cfg.Stdout, "Size: %d [%s]\n", members[0].info.Size, human.Bytes(members[0].info.Size))
+		if exts != nil {
+			fmt.Fprintf(cfg.Stdout, "Extensions: %s\n", strings.Join(exts, ", "))
+		}
+		fmt.Fprintln(cfg.Stdout)
+
+		totalSize := uint64(0)
+		for i, m := range members {
+			fmt.Fprintf(cfg.Stdout, "%s [%s] %s\n", memberLabel(&cfg.CommonConfig, i, len(members)), m.volume, m.info.Path)
+			totalSize += m.info.Size
+		}
+		grandTotalSize += totalSize
+
+		fmt.Fprintln(cfg.Stdout)
+		fmt.Fprintf(cfg.Stdout, "Count: %d\n", len(members))
+		fmt.Fprintln(cfg.Stdout, cfg.Paint(style.Yellow, fmt.Sprintf("Total Size: %d [%s]", totalSize, human.Bytes(totalSize))))
+		fmt.Fprintln(cfg.Stdout, cfg.Paint(style.Dim, "<<<"))
+		fmt.Fprintln(cfg.Stdout)
+	}
+	donePhase()
+
+	if summary != nil {
+		summary.print(cfg)
+	}
+	fmt.Fprintf(cfg.Stdout, "Total size of all duplicates: %d [%s]\n", grandTotalSize, human.Bytes(grandTotalSize))
+	return nil
+}
+
+// Add the hashed files of the volume to the groups of files that share the same hash.
+// algo is set to the algorithm of the first volume that has a hash table.
+func collectVolumeHashes(ctx context.Context, cfg Config, dbf *db.DatabaseFile, volume string,
+	algo **ajhash.Algo, groups map[string][]volumeEntry) error {
+
+	cfg.WarnIfLimited(dbf)
+
+	if !dbf.Features().HasHashTable() {
+		cfg.Errorln(fmt.Sprintf("WARNING: skipping the volume %q because it does not contain file signature hashes", volume))
+		return nil
+	}
+
+	volumeAlgo, err := dbf.HashTableAlgo()
+	if err != nil {
+		cfg.Errorln(fmt.Sprintf("WARNING: skipping the volume %q. %v", volume, err))
+		return nil
+	}
+
+	if *algo == nil {
+		*algo = &volumeAlgo
+	} else if **algo != volumeAlgo {
+		cfg.Errorln(fmt.Sprintf("WARNING: skipping the volume %q because it was hashed using %s instead of %s",
+			volume, db.AlgoString(volumeAlgo), db.AlgoString(**algo)))
+		return nil
+	}
+
+	return dbf.ReadAllEntriesWithHashes(ctx, func(idx int, pi path.Info, hash []byte) error {
+		if !cfg.IsUnder(pi.Path) {
+			return nil
+		}
+
+		key := hex.EncodeToString(hash)
+		groups[key] = append(groups[key], volumeEntry{volume: volume, info: pi})
+		return nil
+	})
+}
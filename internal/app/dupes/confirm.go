@@ -0,0 +1,177 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package dupes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/ajfs/internal/style"
+)
+
+// The outcome of comparing the bytes of the files in a duplicate group.
+type confirmation int
+
+const (
+	bytesIdentical     confirmation = iota // All the files have the same bytes
+	bytesDiffer                            // At least one file has different bytes than the others
+	bytesUnconfirmable                     // At least one file could not be read
+)
+
+func (c confirmation) String() string {
+	switch c {
+	case bytesIdentical:
+		return "identical"
+	case bytesDiffer:
+		return "differ"
+	default:
+		return "unconfirmable"
+	}
+}
+
+// The color used to display the outcome.
+func (c confirmation) color() style.Color {
+	switch c {
+	case bytesIdentical:
+		return style.Green
+	case bytesDiffer:
+		return style.Red
+	default:
+		return style.Yellow
+	}
+}
+
+// Number of duplicate groups per outcome of the byte comparison.
+type confirmStats struct {
+	identical     int
+	differ        int
+	unconfirmable int
+}
+
+func (s *confirmStats) add(c confirmation) {
+	switch c {
+	case bytesIdentical:
+		s.identical++
+	case bytesDiffer:
+		s.differ++
+	default:
+		s.unconfirmable++
+	}
+}
+
+// Compare the bytes of the members on disk against the first member that can be read.
+// Returns the outcome for the group and a note for each member that is not identical (empty otherwise).
+func confirmBytes(root string, members []path.Info) (confirmation, []string) {
+	result := bytesIdentical
+	notes := make([]string, len(members))
+
+	reference := ""
+	for i, pi := range members {
+		p := filepath.Join(root, pi.Path)
+
+		if reference == "" {
+			f, err := os.Open(p)
+			if err != nil {
+				notes[i] = fmt.Sprintf("unreadable: %v", err)
+				result = bytesUnconfirmable
+				continue
+			}
+			_ = f.Close()
+			reference = p
+			continue
+		}
+
+		same, err := sameBytes(reference, p)
+		switch {
+		case err != nil:
+			notes[i] = fmt.Sprintf("unreadable: %v", err)
+			result = bytesUnconfirmable
+		case !same:
+			notes[i] = "bytes differ"
+			if result == bytesIdentical {
+				result = bytesDiffer
+			}
+		}
+	}
+
+	return result, notes
+}
+
+// The size of the chunks in which the files are compared.
+const compareChunkSize = 64 * 1024
+
+// Report whether the two files have the same bytes.
+// The files are read in chunks and the comparison stops at the first difference.
+func sameBytes(a string, b string) (bool, error) {
+	fa, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+
+	fb, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+
+	// Files of different sizes can't be the same
+	ia, err := fa.Stat()
+	if err != nil {
+		return false, err
+	}
+	ib, err := fb.Stat()
+	if err != nil {
+		return false, err
+	}
+	if ia.Size() != ib.Size() {
+		return false, nil
+	}
+
+	bufA := make([]byte, compareChunkSize)
+	bufB := make([]byte, compareChunkSize)
+	for {
+		na, errA := io.ReadFull(fa, bufA)
+		nb, errB := io.ReadFull(fb, bufB)
+		if errA != nil && !isEndOfFile(errA) {
+			return false, fmt.Errorf("failed to read %q. %w", a, errA)
+		}
+		if errB != nil && !isEndOfFile(errB) {
+			return false, fmt.Errorf("failed to read %q. %w", b, errB)
+		}
+
+		if !bytes.Equal(bufA[:na], bufB[:nb]) {
+			return false, nil
+		}
+		if errA != nil || errB != nil {
+			// Both reached the end at the same point since the chunks were equal
+			return errA != nil && errB != nil, nil
+		}
+	}
+}
+
+func isEndOfFile(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package dupes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSameBytes(t *testing.T) {
+	tempDir := t.TempDir()
+	write := func(name string, data []byte) string {
+		p := filepath.Join(tempDir, name)
+		require.NoError(t, os.WriteFile(p, data, 0o644))
+		return p
+	}
+
+	// Span multiple chunks with the difference in the last chunk
+	data := make([]byte, 3*compareChunkSize+10)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	a := write("a", data)
+	b := write("b", data)
+
+	data[len(data)-1]++
+	c := write("c", data)
+	d := write("d", data[:len(data)-1])
+	empty1 := write("empty1", nil)
+	empty2 := write("empty2", nil)
+
+	same, err := sameBytes(a, b)
+	require.NoError(t, err)
+	assert.True(t, same)
+
+	same, err = sameBytes(a, c)
+	require.NoError(t, err)
+	assert.False(t, same)
+
+	same, err = sameBytes(a, d)
+	require.NoError(t, err)
+	assert.False(t, same)
+
+	same, err = sameBytes(empty1, empty2)
+	require.NoError(t, err)
+	assert.True(t, same)
+
+	_, err = sameBytes(a, filepath.Join(tempDir, "missing"))
+	assert.Error(t, err)
+}
@@ -22,98 +22,386 @@
 package dupes
 
 import (
+	"context"
 	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/andrejacobs/ajfs/internal/app/config"
 	"github.com/andrejacobs/ajfs/internal/app/tree"
+	"github.com/andrejacobs/ajfs/internal/catalog"
 	"github.com/andrejacobs/ajfs/internal/db"
 	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/ajfs/internal/style"
 	"github.com/andrejacobs/go-aj/human"
 )
 
 // Config for the ajfs info command.
 type Config struct {
 	config.CommonConfig
+	config.UnderConfig
 
 	Subtrees  bool
 	PrintTree bool
+
+	DisplayFullPaths bool // If true then the path of each duplicate file will be prefixed with the root path of the database.
+
+	// Only display duplicates that have at least one file at or below Within
+	// and another copy at or below Against. Empty means anywhere.
+	Within  string
+	Against string
+
+	IgnoreFile   string // Skip the known-acceptable duplicates listed in this file.
+	AppendIgnore bool   // Append the displayed groups to the IgnoreFile.
+
+	Potential bool // Also display files without a hash that share the same size and name.
+
+	MixedExtensions bool // Only display duplicates that exist under different file extensions.
+	ByExtension     bool // Display the number and total size of the redundant copies per file extension instead of each group.
+
+	Unreviewed bool // Skip the groups that have been labelled (see ajfs annotate).
+
+	ConfirmBytes bool // Compare the bytes of the files on disk before a group is displayed.
+
+	// Format in which the groups are written. See FormatDefault, FormatFdupes and FormatRmlint.
+	// Only the groups are written by the other formats and with ConfirmBytes only the identical groups.
+	Format int
+
+	// Display the files at or below LivePath that already exist in the database.
+	// The files are hashed on the fly using at most Jobs files at the same time (0 uses the number of CPUs).
+	LivePath      string
+	Jobs          int
+	HashCachePath string // Path to the cache used to reuse previously calculated hashes. Empty disables the cache.
 }
 
 // Process the ajfs info command.
-func Run(cfg Config) error {
+// The database path can also be a catalog in which case duplicate files are found across all of its volumes.
+func Run(ctx context.Context, cfg Config) error {
+	if catalog.IsCatalog(cfg.DbPath) {
+		if cfg.LivePath != "" {
+			return fmt.Errorf("a live directory can only be compared against a database and not a catalog")
+		}
+		return catalogDuplicates(ctx, cfg)
+	}
+
 	dbf, err := db.OpenDatabase(cfg.DbPath)
 	if err != nil {
 		return err
 	}
 	defer dbf.Close()
+	cfg.WarnIfLimited(dbf)
+
+	if cfg.LivePath != "" {
+		return liveDuplicates(ctx, cfg, dbf)
+	}
 
 	if cfg.Subtrees {
-		return duplicateSubtrees(cfg)
+		if cfg.Within != "" || cfg.Against != "" || cfg.IgnoreFile != "" || cfg.MixedExtensions || cfg.ByExtension || cfg.Unreviewed || cfg.ConfirmBytes ||
+			cfg.Format != FormatDefault {
+			return fmt.Errorf("within, against, ignore file, extensions, unreviewed, confirm bytes and format can only be used when finding duplicate files")
+		}
+		return duplicateSubtrees(ctx, cfg)
 	}
 
 	if !dbf.Features().HasHashTable() {
 		return fmt.Errorf("require file signature hashes to be present in the database %q", cfg.DbPath)
 	}
+	if cfg.ByExtension && cfg.AppendIgnore {
+		return fmt.Errorf("the summary per extension does not display any groups that can be appended to the ignore file")
+	}
+	if cfg.ByExtension && cfg.ConfirmBytes {
+		return fmt.Errorf("the bytes can only be confirmed when the groups are displayed and not for the summary per extension")
+	}
+	if cfg.Format != FormatDefault && (cfg.ByExtension || cfg.Potential) {
+		return fmt.Errorf("the summary per extension and potential duplicates can't be written in the fdupes or rmlint format")
+	}
+
+	var writer groupWriter
+	if cfg.Format != FormatDefault {
+		if writer, err = newGroupWriter(cfg.Format, cfg.Stdout, dbf); err != nil {
+			return err
+		}
+		if err = writer.begin(); err != nil {
+			return err
+		}
+	}
+	var writeErr error
+
+	ignore := NewIgnoreList()
+	if cfg.IgnoreFile != "" {
+		ignore, err = LoadIgnoreList(cfg.IgnoreFile, cfg.AppendIgnore)
+		if err != nil {
+			return err
+		}
+	}
+	var displayed []IgnoreGroup
+
+	annotations, err := dbf.ReadAnnotations()
+	if err != nil {
+		return err
+	}
 
 	grandTotalSize := uint64(0)
 
-	totalSize := uint64(0)
-	numberOfDupes := 0
-	currentGroup := -1
-	needFooter := false
+	// Members of the current group are buffered since entries can be filtered out
+	// and a group is only displayed when it still contains duplicates
+	currentGroup := ""
+	var currentHash string
+	members := make([]path.Info, 0, 8)
 
-	err = dbf.FindDuplicates(func(group, idx int, pi path.Info, hash string) error {
-		if currentGroup != group {
-			if pi.Size == 0 {
-				needFooter = true
-				return nil
+	var summary *extensionSummary
+	if cfg.ByExtension {
+		summary = newExtensionSummary()
+	}
+
+	var confirmed confirmStats
+
+	within, err := config.NewUnderConfig(cfg.Within, dbf.RootPath())
+	if err != nil {
+		return fmt.Errorf("invalid within path. %w", err)
+	}
+	against, err := config.NewUnderConfig(cfg.Against, dbf.RootPath())
+	if err != nil {
+		return fmt.Errorf("invalid against path. %w", err)
+	}
+
+	printGroup := func() {
+		if len(members) < 2 || members[0].Size == 0 {
+			return
+		}
+		if ignore.IgnoreHash(currentGroup) {
+			return
+		}
+		if !inScope(members, within, against, ignore) {
+			return
+		}
+		groupLabel, labelled := annotations.Groups[currentGroup]
+		if cfg.Unreviewed && (labelled || allLabelled(members, annotations)) {
+			return
+		}
+		var exts []string
+		if cfg.MixedExtensions {
+			if exts = extensions(members, func(pi path.Info) string { return pi.Path }); len(exts) < 2 {
+				return
 			}
+		}
 
-			if currentGroup != -1 {
-				needFooter = false
-				fmt.Fprintln(cfg.Stdout)
-				fmt.Fprintf(cfg.Stdout, "Count: %d\n", numberOfDupes)
-				fmt.Fprintf(cfg.Stdout, "Total Size: %d [%s]\n", totalSize, human.Bytes(uint64(totalSize)))
-				fmt.Fprintln(cfg.Stdout, "<<<")
-				fmt.Fprintln(cfg.Stdout)
+		if summary != nil {
+			addGroup(summary, members, func(pi path.Info) (string, uint64) { return pi.Path, pi.Size })
+			for _, pi := range members {
+				grandTotalSize += pi.Size
 			}
+			return
+		}
 
-			fmt.Fprintln(cfg.Stdout, ">>>")
-			fmt.Fprintf(cfg.Stdout, "Hash: %s\n", hash)
-			fmt.Fprintf(cfg.Stdout, "Size: %d [%s]\n\n", pi.Size, human.Bytes(uint64(pi.Size)))
+		var notes []string
+		var outcome confirmation
+		if cfg.ConfirmBytes {
+			outcome, notes = confirmBytes(dbf.RootPath(), members)
+			confirmed.add(outcome)
+		}
 
-			currentGroup = group
-			numberOfDupes = 0
-			totalSize = uint64(0)
+		if writer != nil {
+			if writeErr == nil && (!cfg.ConfirmBytes || outcome == bytesIdentical) {
+				displayed = append(displayed, IgnoreGroup{Hash: currentGroup, Path: members[0].Path})
+				writeErr = writer.group(currentHash, members)
+			}
+			return
+		}
+
+		fmt.Fprintln(cfg.Stdout, cfg.Paint(style.Dim, ">>>"))
+		displayed = append(displayed, IgnoreGroup{Hash: currentGroup, Path: members[0].Path})
+		if currentHash != "" {
+			fmt.Fprintln(cfg.Stdout, cfg.Paint(style.Bold, "Hash: "+currentHash))
+		} else {
+			fmt.Fprintln(cfg.Stdout, cfg.Paint(style.Bold, "Hash: none (potential duplicates with the same size and name)"))
+			fmt.Fprintf(cfg.Stdout, "Group: %s\n", currentGroup)
+		}
+		if labelled {
+			fmt.Fprintf(cfg.Stdout, "Label: %s\n", groupLabel.Label)
+		}
+		fmt.Fprintf(cfg.Stdout, "Size: %d [%s]\n", members[0].Size, human.Bytes(uint64(members[0].Size)))
+		if exts != nil {
+			fmt.Fprintf(cfg.Stdout, "Extensions: %s\n", strings.Join(exts, ", "))
 		}
+		if cfg.ConfirmBytes {
+			fmt.Fprintln(cfg.Stdout, cfg.Paint(outcome.color(), "Bytes: "+outcome.String()))
+		}
+		fmt.Fprintln(cfg.Stdout)
+
+		totalSize := uint64(0)
+		for i, pi := range members {
+			line := memberLabel(&cfg.CommonConfig, i, len(members)) + " " + displayPath(cfg, dbf, pi.Path)
+			if a, exists := annotations.Entries[pi.Id]; exists {
+				line += " " + cfg.Paint(style.Dim, "("+a.Label.String()+")")
+			}
+			if notes != nil && notes[i] != "" {
+				line += " " + cfg.Paint(style.Red, "("+notes[i]+")")
+			}
+			fmt.Fprintln(cfg.Stdout, line)
+			totalSize += pi.Size
+		}
+		grandTotalSize += totalSize
+
+		fmt.Fprintln(cfg.Stdout)
+		fmt.Fprintf(cfg.Stdout, "Count: %d\n", len(members))
+		fmt.Fprintln(cfg.Stdout, cfg.Paint(style.Yellow, fmt.Sprintf("Total Size: %d [%s]", totalSize, human.Bytes(uint64(totalSize)))))
+		fmt.Fprintln(cfg.Stdout, cfg.Paint(style.Dim, "<<<"))
+		fmt.Fprintln(cfg.Stdout)
+	}
 
-		fmt.Fprintf(cfg.Stdout, "[%d]: %s\n", numberOfDupes, pi.Path)
+	collect := func(group string, idx int, pi path.Info, hash string) error {
+		if currentGroup != group {
+			printGroup()
+			currentGroup = group
+			currentHash = hash
+			members = members[:0]
+		}
 
-		totalSize += pi.Size
-		grandTotalSize += pi.Size
-		numberOfDupes++
-		needFooter = true
+		if cfg.IsUnder(pi.Path) && (within.IsUnder(pi.Path) || against.IsUnder(pi.Path)) {
+			members = append(members, pi)
+		}
 		return nil
-	})
-	if err != nil {
+	}
+
+	donePhase := cfg.StartPhase("finding duplicates")
+	if err = dbf.FindDuplicates(ctx, collect); err != nil {
 		return err
 	}
+	printGroup()
+	donePhase()
 
-	if needFooter {
-		fmt.Fprintln(cfg.Stdout)
-		fmt.Fprintf(cfg.Stdout, "Count: %d\n", numberOfDupes)
-		fmt.Fprintf(cfg.Stdout, "Total Size: %d [%s]\n", totalSize, human.Bytes(uint64(totalSize)))
-		fmt.Fprintln(cfg.Stdout, "<<<")
-		fmt.Fprintln(cfg.Stdout)
+	if writer != nil {
+		if writeErr != nil {
+			return fmt.Errorf("failed to write the duplicates. %w", writeErr)
+		}
+		if err = writer.end(); err != nil {
+			return fmt.Errorf("failed to write the duplicates. %w", err)
+		}
+		return appendIgnore(cfg, displayed)
 	}
 
+	if summary != nil {
+		summary.print(cfg)
+	}
 	fmt.Fprintf(cfg.Stdout, "Total size of all duplicates: %d [%s]\n", grandTotalSize, human.Bytes(grandTotalSize))
+
+	unhashed, err := dbf.CountUnhashedEntries(ctx)
+	if err != nil {
+		return err
+	}
+
+	if unhashed > 0 {
+		fmt.Fprintf(cfg.Stdout, "%d files skipped (no hash)\n", unhashed)
+
+		if cfg.Potential {
+			fmt.Fprintln(cfg.Stdout)
+
+			grandTotalSize = 0
+			currentGroup = ""
+			members = members[:0]
+			if summary != nil {
+				summary = newExtensionSummary()
+			}
+
+			if err = dbf.FindPotentialDuplicates(ctx, collect); err != nil {
+				return err
+			}
+			printGroup()
+
+			if summary != nil {
+				summary.print(cfg)
+			}
+
+			fmt.Fprintf(cfg.Stdout, "Total size of all potential duplicates: %d [%s]\n", grandTotalSize, human.Bytes(grandTotalSize))
+		}
+	}
+
+	if cfg.ConfirmBytes {
+		fmt.Fprintf(cfg.Stdout, "Byte comparison: %d identical, %d differ, %d unconfirmable\n",
+			confirmed.identical, confirmed.differ, confirmed.unconfirmable)
+	}
+
+	return appendIgnore(cfg, displayed)
+}
+
+// Append the displayed groups to the ignore file if requested.
+func appendIgnore(cfg Config, displayed []IgnoreGroup) error {
+	if cfg.AppendIgnore && cfg.IgnoreFile != "" && len(displayed) > 0 {
+		if err := AppendToIgnoreFile(cfg.IgnoreFile, displayed); err != nil {
+			return err
+		}
+		fmt.Fprintf(cfg.Stderr, "Appended %d groups to the ignore file %q\n", len(displayed), cfg.IgnoreFile)
+	}
 	return nil
 }
 
-func duplicateSubtrees(cfg Config) error {
+// Check that at least one member is within and a different member is against.
+// Pairs of members listed in the ignore list are not considered duplicates.
+func inScope(members []path.Info, within config.UnderConfig, against config.UnderConfig, ignore *IgnoreList) bool {
+	for i, w := range members {
+		if !within.IsUnder(w.Path) {
+			continue
+		}
+		for j, a := range members {
+			if i != j && against.IsUnder(a.Path) && !ignore.IgnorePair(w.Path, a.Path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Check if each of the members has been labelled individually.
+func allLabelled(members []path.Info, annotations *db.Annotations) bool {
+	for _, pi := range members {
+		if _, exists := annotations.Entries[pi.Id]; !exists {
+			return false
+		}
+	}
+	return true
+}
+
+// Return the path of a duplicate file as it should be displayed.
+func displayPath(cfg Config, dbf *db.DatabaseFile, p string) string {
+	if cfg.DisplayFullPaths {
+		return filepath.Join(dbf.RootPath(), p)
+	}
+	return p
+}
+
+// Return the label displayed in front of the i-th member of a group with count members.
+// Styled output right aligns the labels so that the paths of large groups line up.
+func memberLabel(cfg *config.CommonConfig, i int, count int) string {
+	if !cfg.Styled {
+		return fmt.Sprintf("[%d]:", i)
+	}
+	width := len(strconv.Itoa(count - 1))
+	return cfg.Paint(style.Dim, fmt.Sprintf("[%*d]:", width, i))
+}
+
+// Returns the distinct file extensions (lowercase and sorted) used by the members.
+// The extension of a file without one is displayed as "(none)".
+func extensions[T any](members []T, pathFn func(m T) string) []string {
+	exts := make([]string, 0, 2)
+	for _, m := range members {
+		ext := strings.ToLower(filepath.Ext(pathFn(m)))
+		if ext == "" {
+			ext = "(none)"
+		}
+		if !slices.Contains(exts, ext) {
+			exts = append(exts, ext)
+		}
+	}
+	slices.Sort(exts)
+	return exts
+}
+
+func duplicateSubtrees(ctx context.Context, cfg Config) error {
 
-	stree, err := tree.SignaturedTreeFromDatabase(cfg.DbPath)
+	stree, err := tree.SignaturedTreeFromDatabase(ctx, cfg.DbPath, cfg.Under)
 	if err != nil {
 		return err
 	}
@@ -22,14 +22,23 @@ package dupes_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/andrejacobs/ajfs/internal/app/annotate"
 	"github.com/andrejacobs/ajfs/internal/app/config"
 	"github.com/andrejacobs/ajfs/internal/app/dupes"
 	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/catalog"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
 	"github.com/andrejacobs/go-aj/ajhash"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -49,14 +58,14 @@ func TestNoHashes(t *testing.T) {
 		Root: "../../testdata/scan",
 	}
 
-	err := scan.Run(scanCfg)
+	err := scan.Run(context.Background(), scanCfg)
 	require.NoError(t, err)
 
 	cfg := dupes.Config{
 		CommonConfig: scanCfg.CommonConfig,
 	}
 
-	err = dupes.Run(cfg)
+	err = dupes.Run(context.Background(), cfg)
 	require.ErrorContains(t, err, "require file signature hashes to be present in the database")
 }
 
@@ -76,7 +85,7 @@ func TestRun(t *testing.T) {
 		Algo:            ajhash.AlgoSHA1,
 	}
 
-	err := scan.Run(scanCfg)
+	err := scan.Run(context.Background(), scanCfg)
 	require.NoError(t, err)
 
 	var outBuffer bytes.Buffer
@@ -90,7 +99,7 @@ func TestRun(t *testing.T) {
 		},
 	}
 
-	err = dupes.Run(cfg)
+	err = dupes.Run(context.Background(), cfg)
 	require.NoError(t, err)
 
 	expected := `>>>
@@ -111,6 +120,14 @@ Total size of all duplicates: 2420 [2.4 kB]
 `
 	assert.Equal(t, expected, outBuffer.String())
 	assert.Equal(t, "", errBuffer.String())
+
+	outBuffer.Reset()
+	cfg.DisplayFullPaths = true
+	require.NoError(t, dupes.Run(context.Background(), cfg))
+	root, err := filepath.Abs("../../testdata/scan")
+	require.NoError(t, err)
+	assert.Contains(t, outBuffer.String(), "[0]: "+filepath.Join(root, "1.txt")+"\n")
+	assert.Contains(t, outBuffer.String(), "[4]: "+filepath.Join(root, "b/b1/b1a/same-as-1.txt")+"\n")
 }
 
 func TestSubtrees(t *testing.T) {
@@ -127,7 +144,7 @@ func TestSubtrees(t *testing.T) {
 		Root: "../../testdata/dupe-dirs",
 	}
 
-	err := scan.Run(scanCfg)
+	err := scan.Run(context.Background(), scanCfg)
 	require.NoError(t, err)
 
 	var outBuffer bytes.Buffer
@@ -142,7 +159,7 @@ func TestSubtrees(t *testing.T) {
 		Subtrees: true,
 	}
 
-	err = dupes.Run(cfg)
+	err = dupes.Run(context.Background(), cfg)
 	require.NoError(t, err)
 
 	absRoot, err := filepath.Abs(scanCfg.Root)
@@ -161,7 +178,7 @@ Signature: 5c09ba250cd65d1d4e244c268346af99b77209ba
 	errBuffer.Reset()
 
 	cfg.PrintTree = true
-	err = dupes.Run(cfg)
+	err = dupes.Run(context.Background(), cfg)
 	require.NoError(t, err)
 
 	expected = absRoot + `
@@ -175,3 +192,759 @@ Signature: 5c09ba250cd65d1d4e244c268346af99b77209ba
 	assert.Equal(t, expected, outBuffer.String())
 	assert.Equal(t, "", errBuffer.String())
 }
+
+func TestRunUnder(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+	_ = os.Remove(tempFile)
+	defer os.Remove(tempFile)
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Root:            "../../testdata/scan",
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}
+
+	err := scan.Run(context.Background(), scanCfg)
+	require.NoError(t, err)
+
+	var outBuffer bytes.Buffer
+
+	cfg := dupes.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		UnderConfig: config.UnderConfig{
+			Under: "b",
+		},
+	}
+
+	err = dupes.Run(context.Background(), cfg)
+	require.NoError(t, err)
+
+	expected := `>>>
+Hash: e3d157020b35944b552ba9987eb668228c073d30
+Size: 484 [484 B]
+
+[0]: b/b1/b1a/1.txt
+[1]: b/b1/b1a/same-as-1.txt
+
+Count: 2
+Total Size: 968 [968 B]
+<<<
+
+Total size of all duplicates: 968 [968 B]
+`
+	assert.Equal(t, expected, outBuffer.String())
+
+	// Only a single member of the group is under the path
+	outBuffer.Reset()
+	cfg.Under = "a/a1"
+
+	err = dupes.Run(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "Total size of all duplicates: 0 [0 B]\n", outBuffer.String())
+}
+
+func TestRunWithinAgainst(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+	_ = os.Remove(tempFile)
+	defer os.Remove(tempFile)
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Root:            "../../testdata/scan",
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}
+
+	err := scan.Run(context.Background(), scanCfg)
+	require.NoError(t, err)
+
+	var outBuffer bytes.Buffer
+
+	cfg := dupes.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Within:  "a/a2",
+		Against: "b",
+	}
+
+	err = dupes.Run(context.Background(), cfg)
+	require.NoError(t, err)
+
+	expected := `>>>
+Hash: e3d157020b35944b552ba9987eb668228c073d30
+Size: 484 [484 B]
+
+[0]: a/a2/same-as-1.txt
+[1]: b/b1/b1a/1.txt
+[2]: b/b1/b1a/same-as-1.txt
+
+Count: 3
+Total Size: 1452 [1.5 kB]
+<<<
+
+Total size of all duplicates: 1452 [1.5 kB]
+`
+	assert.Equal(t, expected, outBuffer.String())
+
+	// The paths are normalized the same way as --under
+	absRoot, err := filepath.Abs(scanCfg.Root)
+	require.NoError(t, err)
+
+	outBuffer.Reset()
+	cfg.Within = "./a/a2/"
+	cfg.Against = filepath.Join(absRoot, "b") + string(filepath.Separator)
+
+	err = dupes.Run(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, expected, outBuffer.String())
+
+	cfg.Against = filepath.Join("..", "b")
+	assert.ErrorContains(t, dupes.Run(context.Background(), cfg), "invalid against path")
+	cfg.Against = "b"
+
+	// Duplicates that only exist inside of the against path are ignored
+	outBuffer.Reset()
+	cfg.Within = "c"
+
+	err = dupes.Run(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "Total size of all duplicates: 0 [0 B]\n", outBuffer.String())
+
+	cfg.Subtrees = true
+	err = dupes.Run(context.Background(), cfg)
+	require.Error(t, err)
+}
+
+func TestRunIgnoreFile(t *testing.T) {
+	tempDir := t.TempDir()
+	tempFile := filepath.Join(tempDir, "unit-testing")
+	ignoreFile := filepath.Join(tempDir, "dupes.ignore")
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Root:            "../../testdata/scan",
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}
+
+	err := scan.Run(context.Background(), scanCfg)
+	require.NoError(t, err)
+
+	var outBuffer bytes.Buffer
+
+	cfg := dupes.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		UnderConfig: config.UnderConfig{
+			Under: "b",
+		},
+		IgnoreFile: ignoreFile,
+	}
+
+	// The ignore file must exist unless groups are being appended
+	err = dupes.Run(context.Background(), cfg)
+	require.Error(t, err)
+
+	// Path pairs
+	require.NoError(t, os.WriteFile(ignoreFile, []byte("# Known duplicates\n\nb/b1/b1a/same-as-1.txt\tb/b1/b1a/1.txt\n"), 0666))
+	err = dupes.Run(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "Total size of all duplicates: 0 [0 B]\n", outBuffer.String())
+
+	// Append the displayed groups
+	require.NoError(t, os.Remove(ignoreFile))
+	outBuffer.Reset()
+	cfg.AppendIgnore = true
+	err = dupes.Run(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.Contains(t, outBuffer.String(), "Count: 2")
+
+	data, err := os.ReadFile(ignoreFile)
+	require.NoError(t, err)
+	assert.Equal(t, "# b/b1/b1a/1.txt\ne3d157020b35944b552ba9987eb668228c073d30\n", string(data))
+
+	// Hashes
+	outBuffer.Reset()
+	cfg.AppendIgnore = false
+	cfg.Under = ""
+	err = dupes.Run(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.NotContains(t, outBuffer.String(), "e3d157020b35944b552ba9987eb668228c073d30")
+}
+
+func TestRunPotential(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+
+	// Database with a hash table where none of the hashes have been calculated
+	dbf, err := db.CreateDatabase(tempFile, "/test/", db.FeatureHashTable)
+	require.NoError(t, err)
+
+	for _, p := range []string{"a.txt", "x/a.txt", "b.txt"} {
+		pi := path.Info{
+			Id:      path.IdFromPath(p),
+			Path:    p,
+			Size:    42,
+			Mode:    0640,
+			ModTime: time.Now(),
+		}
+		require.NoError(t, dbf.WriteEntry(&pi))
+	}
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.StartHashTable(ajhash.AlgoSHA1))
+	require.NoError(t, dbf.FinishHashTable())
+	require.NoError(t, dbf.Close())
+
+	var outBuffer bytes.Buffer
+
+	cfg := dupes.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+	}
+
+	err = dupes.Run(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "Total size of all duplicates: 0 [0 B]\n3 files skipped (no hash)\n", outBuffer.String())
+
+	outBuffer.Reset()
+	cfg.Potential = true
+	err = dupes.Run(context.Background(), cfg)
+	require.NoError(t, err)
+
+	expected := `Total size of all duplicates: 0 [0 B]
+3 files skipped (no hash)
+
+>>>
+Hash: none (potential duplicates with the same size and name)
+Group: ` + db.PotentialGroupId(42, "a.txt") + `
+Size: 42 [42 B]
+
+[0]: a.txt
+[1]: x/a.txt
+
+Count: 2
+Total Size: 84 [84 B]
+<<<
+
+Total size of all potential duplicates: 84 [84 B]
+`
+	assert.Equal(t, expected, outBuffer.String())
+
+	// The group can be ignored using its identifier
+	ignoreFile := filepath.Join(t.TempDir(), "dupes.ignore")
+	require.NoError(t, os.WriteFile(ignoreFile, []byte(db.PotentialGroupId(42, "a.txt")+"\n"), 0644))
+
+	outBuffer.Reset()
+	cfg.IgnoreFile = ignoreFile
+	require.NoError(t, dupes.Run(context.Background(), cfg))
+	assert.NotContains(t, outBuffer.String(), "a.txt")
+
+	// Or labelled using its identifier
+	annotateCfg := annotate.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: cfg.DbPath,
+		},
+		Label:  "reviewed",
+		Groups: []string{db.PotentialGroupId(42, "a.txt")},
+	}
+	require.NoError(t, annotate.Run(context.Background(), annotateCfg))
+
+	outBuffer.Reset()
+	cfg.IgnoreFile = ""
+	cfg.Unreviewed = true
+	require.NoError(t, dupes.Run(context.Background(), cfg))
+	assert.NotContains(t, outBuffer.String(), "a.txt")
+}
+
+func TestRunMixedExtensions(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"photo.jpg":     "same image",
+		"copy/IMG.JPEG": "same image",
+		"a.txt":         "same text",
+		"b.txt":         "same text",
+		"movie.mov":     "same movie",
+		"movie":         "same movie",
+	}
+	for p, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, p)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, p), []byte(content), 0o644))
+	}
+
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Root:            root,
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	var outBuffer bytes.Buffer
+
+	cfg := dupes.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		MixedExtensions: true,
+	}
+
+	require.NoError(t, dupes.Run(context.Background(), cfg))
+	out := outBuffer.String()
+	assert.Contains(t, out, "Extensions: .jpeg, .jpg\n")
+	assert.Contains(t, out, "Extensions: (none), .mov\n")
+	assert.NotContains(t, out, "a.txt")
+	assert.Contains(t, out, "Total size of all duplicates: 40 [40 B]")
+
+	cfg.Subtrees = true
+	assert.Error(t, dupes.Run(context.Background(), cfg))
+}
+
+func TestRunByExtension(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"a/IMG_1.CR2": "raw image content",
+		"b/IMG_1.cr2": "raw image content",
+		"c/IMG_1.CR2": "raw image content",
+		"a/clip.mp4":  "movie",
+		"b/clip.mp4":  "movie",
+		"a/notes":     "notes",
+		"b/notes.txt": "notes",
+		"unique.txt":  "unique",
+	}
+	for p, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, p)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, p), []byte(content), 0o644))
+	}
+
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Root:            root,
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	var outBuffer bytes.Buffer
+
+	cfg := dupes.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		ByExtension: true,
+	}
+
+	require.NoError(t, dupes.Run(context.Background(), cfg))
+	expected := `Extension      Files  Size
+.cr2               2  34 [34 B]
+.mp4               1  5 [5 B]
+.txt               1  5 [5 B]
+
+Total size that could be freed: 44 [44 B]
+Total size of all duplicates: 71 [71 B]
+`
+	assert.Equal(t, expected, outBuffer.String())
+
+	cfg.AppendIgnore = true
+	cfg.IgnoreFile = filepath.Join(t.TempDir(), "dupes.ignore")
+	assert.Error(t, dupes.Run(context.Background(), cfg))
+}
+
+func TestRunUnreviewed(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+
+	commonCfg := config.CommonConfig{
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+		DbPath: tempFile,
+	}
+	scanCfg := scan.Config{
+		CommonConfig:    commonCfg,
+		Root:            "../../testdata/scan",
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	const hash = "e3d157020b35944b552ba9987eb668228c073d30"
+	annotateCfg := annotate.Config{
+		CommonConfig: commonCfg,
+		Label:        "reviewed",
+		Groups:       []string{hash},
+	}
+	require.NoError(t, annotate.Run(context.Background(), annotateCfg))
+
+	annotateCfg.Label = "keep"
+	annotateCfg.Groups = nil
+	annotateCfg.Paths = []string{"1.txt"}
+	require.NoError(t, annotate.Run(context.Background(), annotateCfg))
+
+	var outBuffer bytes.Buffer
+	cfg := dupes.Config{
+		CommonConfig: commonCfg,
+	}
+	cfg.Stdout = &outBuffer
+
+	require.NoError(t, dupes.Run(context.Background(), cfg))
+	expected := `>>>
+Hash: e3d157020b35944b552ba9987eb668228c073d30
+Label: reviewed
+Size: 484 [484 B]
+
+[0]: 1.txt (keep)
+[1]: a/a1/a1a/a1a1/1.txt
+[2]: a/a2/same-as-1.txt
+[3]: b/b1/b1a/1.txt
+[4]: b/b1/b1a/same-as-1.txt
+
+Count: 5
+Total Size: 2420 [2.4 kB]
+<<<
+
+Total size of all duplicates: 2420 [2.4 kB]
+`
+	assert.Equal(t, expected, outBuffer.String())
+
+	// The labelled group is skipped
+	outBuffer.Reset()
+	cfg.Unreviewed = true
+	require.NoError(t, dupes.Run(context.Background(), cfg))
+	assert.Equal(t, "Total size of all duplicates: 0 [0 B]\n", outBuffer.String())
+
+	// A group is only reviewed once all of its files are labelled
+	annotateCfg.Label = ""
+	annotateCfg.Clear = true
+	annotateCfg.Groups = []string{hash}
+	annotateCfg.Paths = nil
+	require.NoError(t, annotate.Run(context.Background(), annotateCfg))
+
+	outBuffer.Reset()
+	require.NoError(t, dupes.Run(context.Background(), cfg))
+	assert.Contains(t, outBuffer.String(), "Count: 5\n")
+
+	annotateCfg.Label = "delete-candidate"
+	annotateCfg.Clear = false
+	annotateCfg.Groups = nil
+	annotateCfg.Paths = []string{"a/a1/a1a/a1a1/1.txt", "a/a2/same-as-1.txt", "b/b1/b1a/1.txt", "b/b1/b1a/same-as-1.txt"}
+	require.NoError(t, annotate.Run(context.Background(), annotateCfg))
+
+	outBuffer.Reset()
+	require.NoError(t, dupes.Run(context.Background(), cfg))
+	assert.Equal(t, "Total size of all duplicates: 0 [0 B]\n", outBuffer.String())
+}
+
+func TestRunCatalog(t *testing.T) {
+	tempDir := t.TempDir()
+
+	scanVolume := func(name string, root string, algo ajhash.Algo) string {
+		dbPath := filepath.Join(tempDir, name+".ajfs")
+		scanCfg := scan.Config{
+			CommonConfig: config.CommonConfig{
+				Stdout: io.Discard,
+				Stderr: io.Discard,
+				DbPath: dbPath,
+			},
+			Root:            root,
+			CalculateHashes: true,
+			Algo:            algo,
+		}
+		require.NoError(t, scan.Run(context.Background(), scanCfg))
+		return dbPath
+	}
+
+	catPath := filepath.Join(tempDir, "backups.ajfscat")
+	c := catalog.New(catPath)
+	require.NoError(t, c.Add("", scanVolume("laptop", "../../testdata/scan/a/a2", ajhash.AlgoSHA1)))
+	require.NoError(t, c.Add("", scanVolume("nas", "../../testdata/scan/b/b1", ajhash.AlgoSHA1)))
+	require.NoError(t, c.Add("", scanVolume("other", "../../testdata/scan", ajhash.AlgoSHA256)))
+	require.NoError(t, c.Add("missing", filepath.Join(tempDir, "missing.ajfs")))
+	require.NoError(t, c.Save())
+
+	var outBuffer bytes.Buffer
+	var errBuffer bytes.Buffer
+
+	cfg := dupes.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: &errBuffer,
+			DbPath: catPath,
+		},
+	}
+
+	err := dupes.Run(context.Background(), cfg)
+	require.NoError(t, err)
+
+	expected := `>>>
+Hash: e3d157020b35944b552ba9987eb668228c073d30
+Size: 484 [484 B]
+
+[0]: [laptop] same-as-1.txt
+[1]: [nas] b1a/1.txt
+[2]: [nas] b1a/same-as-1.txt
+
+Count: 3
+Total Size: 1452 [1.5 kB]
+<<<
+
+Total size of all duplicates: 1452 [1.5 kB]
+`
+	assert.Equal(t, expected, outBuffer.String())
+	assert.Contains(t, errBuffer.String(), `skipping the volume "other"`)
+	assert.Contains(t, errBuffer.String(), `skipping the volume "missing"`)
+
+	// Options that only apply to a single database
+	cfg.Subtrees = true
+	err = dupes.Run(context.Background(), cfg)
+	assert.Error(t, err)
+}
+
+func TestRunConfirmBytes(t *testing.T) {
+	tempDir := t.TempDir()
+	root := filepath.Join(tempDir, "root")
+	files := map[string]string{
+		"identical/1.txt":     "the same content",
+		"identical/2.txt":     "the same content",
+		"differ/1.txt":        "changed after the scan",
+		"differ/2.txt":        "changed after the scan",
+		"unconfirmable/1.txt": "removed after the scan",
+		"unconfirmable/2.txt": "removed after the scan",
+	}
+	for p, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(p)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, p), []byte(content), 0o644))
+	}
+
+	commonCfg := config.CommonConfig{
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+		DbPath: filepath.Join(tempDir, "unit-testing"),
+	}
+	scanCfg := scan.Config{
+		CommonConfig:    commonCfg,
+		Root:            root,
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	// Same size but different bytes
+	require.NoError(t, os.WriteFile(filepath.Join(root, "differ/2.txt"), []byte("CHANGED after the scan"), 0o644))
+	require.NoError(t, os.Remove(filepath.Join(root, "unconfirmable/2.txt")))
+
+	var outBuffer bytes.Buffer
+	cfg := dupes.Config{
+		CommonConfig: commonCfg,
+		ConfirmBytes: true,
+	}
+	cfg.Stdout = &outBuffer
+
+	require.NoError(t, dupes.Run(context.Background(), cfg))
+	out := outBuffer.String()
+
+	assert.Contains(t, out, "Bytes: identical\n\n[0]: identical/1.txt\n[1]: identical/2.txt\n")
+	assert.Contains(t, out, "Bytes: differ\n\n[0]: differ/1.txt\n[1]: differ/2.txt (bytes differ)\n")
+	assert.Contains(t, out, "Bytes: unconfirmable\n\n[0]: unconfirmable/1.txt\n[1]: unconfirmable/2.txt (unreadable: ")
+	assert.Contains(t, out, "Byte comparison: 1 identical, 1 differ, 1 unconfirmable\n")
+
+	// Not supported for the summary per extension
+	cfg.ByExtension = true
+	assert.Error(t, dupes.Run(context.Background(), cfg))
+}
+
+func TestRunFormats(t *testing.T) {
+	tempDir := t.TempDir()
+	root := filepath.Join(tempDir, "root")
+	files := map[string]string{
+		"a.txt":     "first group",
+		"b/a.txt":   "first group",
+		"b/c/a.txt": "first group",
+		"x.txt":     "second group!",
+		"y.txt":     "second group!",
+		"unique":    "not a duplicate",
+	}
+	for p, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(p)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, p), []byte(content), 0o644))
+	}
+
+	commonCfg := config.CommonConfig{
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+		DbPath: filepath.Join(tempDir, "unit-testing"),
+	}
+	scanCfg := scan.Config{
+		CommonConfig:    commonCfg,
+		Root:            root,
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA256,
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	var outBuffer bytes.Buffer
+	cfg := dupes.Config{
+		CommonConfig: commonCfg,
+		Format:       dupes.FormatFdupes,
+	}
+	cfg.Stdout = &outBuffer
+
+	require.NoError(t, dupes.Run(context.Background(), cfg))
+	groups := strings.Split(strings.TrimSuffix(outBuffer.String(), "\n\n"), "\n\n")
+	slices.Sort(groups)
+	assert.Equal(t, []string{
+		filepath.Join(root, "a.txt") + "\n" + filepath.Join(root, "b/a.txt") + "\n" + filepath.Join(root, "b/c/a.txt"),
+		filepath.Join(root, "x.txt") + "\n" + filepath.Join(root, "y.txt"),
+	}, groups)
+
+	// rmlint
+	outBuffer.Reset()
+	cfg.Format = dupes.FormatRmlint
+	require.NoError(t, dupes.Run(context.Background(), cfg))
+
+	var doc []map[string]any
+	require.NoError(t, json.Unmarshal(outBuffer.Bytes(), &doc))
+	require.Len(t, doc, 7)
+
+	assert.Equal(t, "rmlint json-dump of lint files", doc[0]["description"])
+	assert.Equal(t, "sha256", doc[0]["checksum_type"])
+
+	originals := 0
+	for _, entry := range doc[1:6] {
+		assert.Equal(t, "duplicate_file", entry["type"])
+		assert.True(t, strings.HasPrefix(entry["path"].(string), root))
+		assert.Len(t, entry["checksum"], 64)
+		if entry["is_original"].(bool) {
+			originals++
+		}
+	}
+	assert.Equal(t, 2, originals)
+
+	footer := doc[6]
+	assert.Equal(t, float64(3), footer["duplicates"])
+	assert.Equal(t, float64(2), footer["duplicate_sets"])
+	assert.Equal(t, float64(2*len("first group")+len("second group!")), footer["total_lint_size"])
+	assert.Equal(t, float64(6), footer["total_files"])
+
+	// Not supported for potential duplicates
+	cfg.Potential = true
+	assert.Error(t, dupes.Run(context.Background(), cfg))
+}
+
+func TestRunLive(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Root:            "../../testdata/scan",
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	content, err := os.ReadFile("../../testdata/scan/1.txt")
+	require.NoError(t, err)
+
+	live := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(live, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(live, "sub", "copy.txt"), content, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(live, "new.txt"), []byte("not in the database"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(live, "empty.txt"), nil, 0o644))
+	// Same size as 1.txt but different content
+	different := bytes.Repeat([]byte{'x'}, len(content))
+	require.NoError(t, os.WriteFile(filepath.Join(live, "different.txt"), different, 0o644))
+
+	var outBuffer bytes.Buffer
+	var errBuffer bytes.Buffer
+
+	cfg := dupes.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: &errBuffer,
+			DbPath: tempFile,
+		},
+		LivePath:      live,
+		Jobs:          2,
+		HashCachePath: filepath.Join(t.TempDir(), "cache"),
+	}
+
+	expected := `>>>
+Path: ` + filepath.Join(live, "sub", "copy.txt") + `
+Size: 484 [484 B]
+
+[0]: 1.txt
+[1]: a/a1/a1a/a1a1/1.txt
+[2]: a/a2/same-as-1.txt
+[3]: b/b1/b1a/1.txt
+[4]: b/b1/b1a/same-as-1.txt
+<<<
+
+Already in the database: 1 of 4 files
+Total size of the files already in the database: 484 [484 B]
+`
+
+	require.NoError(t, dupes.Run(context.Background(), cfg))
+	assert.Equal(t, expected, outBuffer.String())
+	assert.Equal(t, "", errBuffer.String())
+
+	// The hashes are reused from the cache
+	outBuffer.Reset()
+	require.NoError(t, dupes.Run(context.Background(), cfg))
+	assert.Equal(t, expected, outBuffer.String())
+
+	// Only the database side can be limited
+	outBuffer.Reset()
+	cfg.UnderConfig = config.UnderConfig{Under: "a/a2"}
+	require.NoError(t, dupes.Run(context.Background(), cfg))
+	assert.Contains(t, outBuffer.String(), "[0]: a/a2/same-as-1.txt\n<<<")
+
+	cfg.UnderConfig = config.UnderConfig{}
+	cfg.LivePath = filepath.Join(live, "new.txt")
+	assert.ErrorContains(t, dupes.Run(context.Background(), cfg), "is not a directory")
+
+	cfg.LivePath = live
+	cfg.Subtrees = true
+	assert.Error(t, dupes.Run(context.Background(), cfg))
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package dupes
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/andrejacobs/ajfs/internal/style"
+	"github.com/andrejacobs/go-aj/human"
+)
+
+// Summary of the duplicate files grouped by file extension.
+type extensionSummary struct {
+	totals map[string]*extensionTotal
+}
+
+// Number of redundant copies and their total size for a file extension.
+type extensionTotal struct {
+	ext   string
+	count int
+	size  uint64
+}
+
+func newExtensionSummary() *extensionSummary {
+	return &extensionSummary{
+		totals: make(map[string]*extensionTotal, 16),
+	}
+}
+
+// Add the redundant copies of a duplicate group to the totals of their extensions.
+// The first member is counted as the original that is kept, so only the other n-1 copies are added.
+// Extensions are compared case insensitive and a file without one is counted as "(none)".
+func addGroup[T any](s *extensionSummary, members []T, fn func(m T) (string, uint64)) {
+	for _, m := range members[min(1, len(members)):] {
+		s.add(fn(m))
+	}
+}
+
+func (s *extensionSummary) add(p string, size uint64) {
+	ext := strings.ToLower(filepath.Ext(p))
+	if ext == "" {
+		ext = "(none)"
+	}
+
+	total, ok := s.totals[ext]
+	if !ok {
+		total = &extensionTotal{ext: ext}
+		s.totals[ext] = total
+	}
+	total.count++
+	total.size += size
+}
+
+// Display the totals ordered from the most to the least duplicate bytes.
+func (s *extensionSummary) print(cfg Config) {
+	totals := slices.SortedFunc(maps.Values(s.totals), func(a, b *extensionTotal) int {
+		if c := cmp.Compare(b.size, a.size); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ext, b.ext)
+	})
+
+	width := len("Extension")
+	for _, t := range totals {
+		width = max(width, len(t.ext))
+	}
+
+	fmt.Fprintln(cfg.Stdout, cfg.Paint(style.Bold, fmt.Sprintf("%-*s %10s  %s", width, "Extension", "Files", "Size")))
+	var total uint64
+	for _, t := range totals {
+		fmt.Fprintf(cfg.Stdout, "%-*s %10d  %d [%s]\n", width, t.ext, t.count, t.size, human.Bytes(t.size))
+		total += t.size
+	}
+	fmt.Fprintln(cfg.Stdout)
+	fmt.Fprintf(cfg.Stdout, "Total size that could be freed: %d [%s]\n", total, human.Bytes(total))
+}
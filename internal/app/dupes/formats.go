@@ -0,0 +1,212 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package dupes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
+)
+
+const (
+	FormatDefault int = iota // The ajfs format that displays each group with its hash, size and count.
+	FormatFdupes             // The paths of each group on a line each, followed by a blank line (as output by fdupes and jdupes).
+	FormatRmlint             // The JSON format of rmlint, which can be replayed using "rmlint --replay".
+)
+
+// Writes the duplicate groups in the format of another duplicate finder, so that its tooling can act on them.
+// The paths are written as the full paths on disk.
+type groupWriter interface {
+	begin() error
+	group(hash string, members []path.Info) error
+	end() error
+}
+
+// Return the writer for the format.
+func newGroupWriter(format int, w io.Writer, dbf *db.DatabaseFile) (groupWriter, error) {
+	switch format {
+	case FormatFdupes:
+		return &fdupesWriter{w: w, root: dbf.RootPath()}, nil
+	case FormatRmlint:
+		algo, err := dbf.HashTableAlgo()
+		if err != nil {
+			return nil, err
+		}
+		return &rmlintWriter{w: w, root: dbf.RootPath(), algo: algo, totalFiles: dbf.FileEntriesCount()}, nil
+	}
+	return nil, fmt.Errorf("invalid duplicates format %v", format)
+}
+
+//-----------------------------------------------------------------------------
+
+type fdupesWriter struct {
+	w    io.Writer
+	root string
+}
+
+func (f *fdupesWriter) begin() error {
+	return nil
+}
+
+func (f *fdupesWriter) group(_ string, members []path.Info) error {
+	for _, pi := range members {
+		if _, err := fmt.Fprintln(f.w, filepath.Join(f.root, pi.Path)); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(f.w)
+	return err
+}
+
+func (f *fdupesWriter) end() error {
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// The rmlint JSON document is an array that starts with a header object, followed by an object for each
+// duplicate file and ends with a footer object. The first file of each group is the original.
+type rmlintWriter struct {
+	w          io.Writer
+	root       string
+	algo       ajhash.Algo
+	totalFiles int
+
+	elements      int // Number of objects written to the array
+	id            int
+	duplicates    int
+	duplicateSets int
+	lintSize      uint64
+}
+
+type rmlintHeader struct {
+	Description  string `json:"description"`
+	Cwd          string `json:"cwd"`
+	Args         string `json:"args"`
+	Progress     int    `json:"progress"`
+	ChecksumType string `json:"checksum_type"`
+}
+
+type rmlintFile struct {
+	Id         int     `json:"id"`
+	Type       string  `json:"type"`
+	Progress   int     `json:"progress"`
+	Checksum   string  `json:"checksum"`
+	Path       string  `json:"path"`
+	Size       uint64  `json:"size"`
+	Depth      int     `json:"depth"`
+	IsOriginal bool    `json:"is_original"`
+	MTime      float64 `json:"mtime"`
+}
+
+type rmlintFooter struct {
+	Aborted        bool   `json:"aborted"`
+	Progress       int    `json:"progress"`
+	TotalFiles     int    `json:"total_files"`
+	IgnoredFiles   int    `json:"ignored_files"`
+	IgnoredFolders int    `json:"ignored_folders"`
+	Duplicates     int    `json:"duplicates"`
+	DuplicateSets  int    `json:"duplicate_sets"`
+	TotalLintSize  uint64 `json:"total_lint_size"`
+}
+
+func (r *rmlintWriter) begin() error {
+	if _, err := io.WriteString(r.w, "["); err != nil {
+		return err
+	}
+	return r.write(rmlintHeader{
+		Description:  "rmlint json-dump of lint files",
+		Cwd:          r.root,
+		Args:         "ajfs dupes --format rmlint",
+		ChecksumType: rmlintChecksumType(r.algo),
+	})
+}
+
+func (r *rmlintWriter) group(hash string, members []path.Info) error {
+	r.duplicateSets++
+	for i, pi := range members {
+		r.id++
+		if i > 0 {
+			r.duplicates++
+			r.lintSize += pi.Size
+		}
+
+		err := r.write(rmlintFile{
+			Id:         r.id,
+			Type:       "duplicate_file",
+			Progress:   100,
+			Checksum:   hash,
+			Path:       filepath.Join(r.root, pi.Path),
+			Size:       pi.Size,
+			Depth:      strings.Count(pi.Path, string(filepath.Separator)) + 1,
+			IsOriginal: i == 0,
+			MTime:      float64(pi.ModTime.UnixNano()) / 1e9,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *rmlintWriter) end() error {
+	err := r.write(rmlintFooter{
+		Progress:      100,
+		TotalFiles:    r.totalFiles,
+		Duplicates:    r.duplicates,
+		DuplicateSets: r.duplicateSets,
+		TotalLintSize: r.lintSize,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(r.w, "\n]\n")
+	return err
+}
+
+// Write the object as the next element of the array.
+func (r *rmlintWriter) write(v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the rmlint json. %w", err)
+	}
+
+	separator := ",\n"
+	if r.elements == 0 {
+		separator = "\n"
+	}
+	r.elements++
+	_, err = fmt.Fprintf(r.w, "%s%s", separator, data)
+	return err
+}
+
+// Return the name rmlint uses for the hash algorithm.
+func rmlintChecksumType(algo ajhash.Algo) string {
+	if algo == db.AlgoMD5 {
+		return "md5"
+	}
+	return strings.ToLower(strings.ReplaceAll(algo.String(), "-", ""))
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package dupes
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// IgnoreList contains the known-acceptable duplicates that should not be displayed.
+//
+// The ignore file is a plain text file where each line is one of:
+//   - A file signature hash. The whole group of duplicates is ignored.
+//   - Two paths separated by a tab. These files are not considered duplicates of each other.
+//
+// Empty lines and lines starting with # are skipped.
+type IgnoreList struct {
+	hashes map[string]struct{}
+	pairs  map[[2]string]struct{}
+}
+
+// Create a new empty ignore list.
+func NewIgnoreList() *IgnoreList {
+	return &IgnoreList{
+		hashes: make(map[string]struct{}),
+		pairs:  make(map[[2]string]struct{}),
+	}
+}
+
+// Load the ignore list from the file.
+// If the file does not exist and allowMissing is true then an empty list is returned.
+func LoadIgnoreList(path string, allowMissing bool) (*IgnoreList, error) {
+	l := NewIgnoreList()
+
+	f, err := os.Open(path)
+	if err != nil {
+		if allowMissing && errors.Is(err, fs.ErrNotExist) {
+			return l, nil
+		}
+		return nil, fmt.Errorf("failed to open the ignore file %q. %w", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		lhs, rhs, isPair := strings.Cut(line, "\t")
+		if isPair {
+			lhs = strings.TrimSpace(lhs)
+			rhs = strings.TrimSpace(rhs)
+			if lhs == "" || rhs == "" {
+				return nil, fmt.Errorf("invalid path pair on line %d in the ignore file %q", lineNumber, path)
+			}
+			l.AddPair(lhs, rhs)
+		} else {
+			l.AddHash(line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the ignore file %q. %w", path, err)
+	}
+
+	return l, nil
+}
+
+// Ignore the group of duplicates with the file signature hash.
+func (l *IgnoreList) AddHash(hash string) {
+	l.hashes[strings.ToLower(hash)] = struct{}{}
+}
+
+// Ignore the two files from being considered duplicates of each other.
+func (l *IgnoreList) AddPair(lhs string, rhs string) {
+	l.pairs[pairKey(lhs, rhs)] = struct{}{}
+}
+
+// Check if the group of duplicates with the file signature hash is ignored.
+func (l *IgnoreList) IgnoreHash(hash string) bool {
+	_, exists := l.hashes[strings.ToLower(hash)]
+	return exists
+}
+
+// Check if the two files are ignored from being duplicates of each other.
+func (l *IgnoreList) IgnorePair(lhs string, rhs string) bool {
+	_, exists := l.pairs[pairKey(lhs, rhs)]
+	return exists
+}
+
+// Append the file signature hashes to the ignore file.
+// Each hash is preceded by a comment containing the first path in the group.
+// The file will be created if it does not exist.
+func AppendToIgnoreFile(path string, groups []IgnoreGroup) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return fmt.Errorf("failed to open the ignore file %q. %w", path, err)
+	}
+
+	w := bufio.NewWriter(f)
+	for _, g := range groups {
+		fmt.Fprintf(w, "# %s\n%s\n", g.Path, g.Hash)
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write to the ignore file %q. %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close the ignore file %q. %w", path, err)
+	}
+
+	return nil
+}
+
+// IgnoreGroup describes a group of duplicates to be appended to the ignore file.
+type IgnoreGroup struct {
+	Hash string
+	Path string
+}
+
+// Pairs are stored in sorted order so that lookups do not depend on the order of the paths.
+func pairKey(lhs string, rhs string) [2]string {
+	if rhs < lhs {
+		lhs, rhs = rhs, lhs
+	}
+	return [2]string{lhs, rhs}
+}
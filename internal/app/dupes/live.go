@@ -0,0 +1,257 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package dupes
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/ajfs/internal/style"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/andrejacobs/go-aj/file"
+	"github.com/andrejacobs/go-aj/human"
+)
+
+// A file found below the live directory that needs to be hashed.
+type liveFile struct {
+	path    string // Absolute path to the file.
+	size    uint64
+	modTime time.Time
+	hash    []byte // Set once the hash has been calculated.
+	err     error  // Set when the hash could not be calculated.
+}
+
+// Display the files below the live directory that already exist in the database.
+// Only the files with the same size as a file in the database are hashed, using at most cfg.Jobs files at the same
+// time. Empty files are never considered to be duplicates.
+func liveDuplicates(ctx context.Context, cfg Config, dbf *db.DatabaseFile) error {
+	if cfg.Subtrees || cfg.Within != "" || cfg.Against != "" || cfg.IgnoreFile != "" || cfg.Potential || cfg.MixedExtensions ||
+		cfg.ByExtension || cfg.Unreviewed || cfg.ConfirmBytes || cfg.Format != FormatDefault {
+		return fmt.Errorf("subtrees, within, against, ignore file, potential, extensions, unreviewed, confirm bytes and format can't be used with a live directory")
+	}
+	if cfg.Jobs < 0 {
+		return fmt.Errorf("the number of jobs can't be negative")
+	}
+	if !dbf.Features().HasHashTable() {
+		return fmt.Errorf("require file signature hashes to be present in the database %q", cfg.DbPath)
+	}
+
+	liveRoot, err := filepath.Abs(cfg.LivePath)
+	if err != nil {
+		return fmt.Errorf("failed to get the absolute path from %q. %w", cfg.LivePath, err)
+	}
+	stat, err := os.Stat(liveRoot)
+	if err != nil {
+		return fmt.Errorf("failed to access the directory %q. %w", cfg.LivePath, err)
+	}
+	if !stat.IsDir() {
+		return fmt.Errorf("%q is not a directory", cfg.LivePath)
+	}
+
+	algo, err := dbf.HashTableAlgo()
+	if err != nil {
+		return err
+	}
+
+	donePhase := cfg.StartPhase("reading the database")
+	sizes := make(map[uint64]struct{}, 1024)
+	existing := make(map[string][]path.Info, 1024)
+	err = dbf.ReadAllEntriesWithHashes(ctx, func(idx int, pi path.Info, hash []byte) error {
+		if !pi.IsFile() || (pi.Size == 0) || (hash == nil) || !cfg.IsUnder(pi.Path) {
+			return nil
+		}
+		sizes[pi.Size] = struct{}{}
+		key := hex.EncodeToString(hash)
+		existing[key] = append(existing[key], pi)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	donePhase()
+
+	donePhase = cfg.StartPhase(fmt.Sprintf("walking %q", liveRoot))
+	files, total, err := findLiveCandidates(ctx, cfg, liveRoot, sizes)
+	if err != nil {
+		return err
+	}
+	donePhase()
+
+	donePhase = cfg.StartPhase(fmt.Sprintf("calculating %s file signatures", db.AlgoString(algo)))
+	if err = hashLiveFiles(ctx, cfg, algo, files); err != nil {
+		return err
+	}
+	donePhase()
+
+	found := 0
+	failed := 0
+	totalSize := uint64(0)
+	for _, f := range files {
+		if f.err != nil {
+			cfg.Errorln(fmt.Sprintf("failed to calculate the hash for %q. %v", f.path, f.err))
+			failed++
+			continue
+		}
+
+		members := existing[hex.EncodeToString(f.hash)]
+		if len(members) == 0 {
+			continue
+		}
+		found++
+		totalSize += f.size
+
+		fmt.Fprintln(cfg.Stdout, cfg.Paint(style.Dim, ">>>"))
+		fmt.Fprintln(cfg.Stdout, cfg.Paint(style.Bold, "Path: "+f.path))
+		fmt.Fprintf(cfg.Stdout, "Size: %d [%s]\n", f.size, human.Bytes(f.size))
+		fmt.Fprintln(cfg.Stdout)
+		for i, pi := range members {
+			fmt.Fprintf(cfg.Stdout, "%s %s\n", memberLabel(&cfg.CommonConfig, i, len(members)), displayPath(cfg, dbf, pi.Path))
+		}
+		fmt.Fprintln(cfg.Stdout, cfg.Paint(style.Dim, "<<<"))
+		fmt.Fprintln(cfg.Stdout)
+	}
+
+	fmt.Fprintf(cfg.Stdout, "Already in the database: %d of %d files\n", found, total)
+	fmt.Fprintln(cfg.Stdout, cfg.Paint(style.Yellow, fmt.Sprintf("Total size of the files already in the database: %d [%s]", totalSize, human.Bytes(totalSize))))
+	if failed > 0 {
+		fmt.Fprintf(cfg.Stderr, "Failed to calculate the hash for %d files\n", failed)
+	}
+	return nil
+}
+
+// Walk the live directory and return the files that have the same size as a file in the database.
+// total is the number of files that were found.
+// Directories that can't be read are reported and skipped.
+func findLiveCandidates(ctx context.Context, cfg Config, liveRoot string, sizes map[uint64]struct{}) (files []*liveFile, total int, err error) {
+	err = filepath.WalkDir(liveRoot, func(p string, d fs.DirEntry, rcvErr error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if rcvErr != nil {
+			if p == liveRoot {
+				return rcvErr
+			}
+			cfg.Errorln(fmt.Sprintf("WARNING: skipping %q. %v", p, rcvErr))
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			cfg.Errorln(fmt.Sprintf("WARNING: skipping %q. %v", p, err))
+			return nil
+		}
+		total++
+
+		size := uint64(info.Size()) //nolint:gosec // disable G115
+		if _, exists := sizes[size]; !exists {
+			return nil
+		}
+		files = append(files, &liveFile{path: p, size: size, modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to walk the directory %q. %w", liveRoot, err)
+	}
+	return files, total, nil
+}
+
+// Calculate the hashes of the files, reusing the hashes from the hash cache for files that have not changed.
+// The hash cache is only used from this goroutine while the files are hashed by the workers.
+func hashLiveFiles(ctx context.Context, cfg Config, algo ajhash.Algo, files []*liveFile) error {
+	cache, err := cfg.OpenHashCache(cfg.HashCachePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := cache.Close(); err != nil {
+			cfg.Errorln(err)
+		}
+	}()
+
+	jobs := cfg.Jobs
+	if jobs == 0 {
+		jobs = runtime.NumCPU()
+	}
+
+	pending := make(chan *liveFile, jobs)
+	hashed := make(chan *liveFile, jobs)
+
+	var wg sync.WaitGroup
+	for range jobs {
+		wg.Go(func() {
+			for f := range pending {
+				f.hash, _, f.err = file.Hash(ctx, f.path, db.AlgoHasher(algo), nil)
+				hashed <- f
+			}
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(hashed)
+	}()
+
+	// Queue the files that are not in the cache while storing the calculated hashes in the cache
+	var cacheErr error
+	add := func(f *liveFile) {
+		if f.err == nil && cacheErr == nil {
+			cacheErr = cache.Add(f.path, f.size, f.modTime, algo, f.hash)
+		}
+	}
+
+	for _, f := range files {
+		if hash, ok := cache.Lookup(f.path, f.size, f.modTime, algo); ok {
+			f.hash = hash
+			continue
+		}
+		for sent := false; !sent; {
+			select {
+			case pending <- f:
+				sent = true
+			case done := <-hashed:
+				add(done)
+			}
+		}
+	}
+	close(pending)
+
+	for f := range hashed {
+		add(f)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return cacheErr
+}
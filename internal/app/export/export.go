@@ -23,16 +23,17 @@ package export
 
 import (
 	"bufio"
+	"context"
 	"encoding/csv"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io/fs"
 	"os"
 	"path/filepath"
-	"time"
+	"strings"
 
 	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/search"
 	"github.com/andrejacobs/ajfs/internal/db"
 	"github.com/andrejacobs/ajfs/internal/path"
 	"github.com/andrejacobs/go-aj/ajhash"
@@ -41,21 +42,45 @@ import (
 // Config for the ajfs export command.
 type Config struct {
 	config.CommonConfig
+	config.UnderConfig
 
 	ExportPath string
 	Format     int
 	FullPaths  bool
+	Compress   bool // gzip compress the output (only supported for CSV and NDJSON).
+
+	Expression search.Expression // [optional] Only export the entries that match the search expression.
+}
+
+// Check if the entry should be exported.
+// The hash is optional and will be nil if the database does not have a file signature hash for the entry.
+func (cfg *Config) include(pi path.Info, hash []byte) (bool, error) {
+	if !cfg.IsUnder(pi.Path) {
+		return false, nil
+	}
+	if cfg.Expression == nil {
+		return true, nil
+	}
+	return cfg.Expression.Match(pi, hash)
 }
 
 // Process the ajfs export command.
-func Run(cfg Config) error {
+func Run(ctx context.Context, cfg Config) error {
+	defer cfg.StartPhase("exporting")()
+
+	if cfg.Compress && (cfg.Format != FormatCSV) && (cfg.Format != FormatNDJSON) {
+		return fmt.Errorf("compression is only supported for the CSV and NDJSON export formats")
+	}
+
 	switch cfg.Format {
 	case FormatCSV:
-		return exportCSV(cfg)
+		return exportCSV(ctx, cfg)
 	case FormatJSON:
-		return exportJSON(cfg)
+		return exportJSON(ctx, cfg)
 	case FormatHashdeep:
-		return exportHashdeep(cfg)
+		return exportHashdeep(ctx, cfg)
+	case FormatNDJSON:
+		return exportNDJSON(ctx, cfg)
 	}
 
 	return fmt.Errorf("invalid export format %v", cfg.Format)
@@ -64,22 +89,35 @@ func Run(cfg Config) error {
 //-----------------------------------------------------------------------------
 // CSV
 
-func exportCSV(cfg Config) error {
+func exportCSV(ctx context.Context, cfg Config) error {
 	dbf, err := db.OpenDatabase(cfg.DbPath)
 	if err != nil {
 		return err
 	}
 	defer dbf.Close()
+	cfg.WarnIfLimited(dbf)
+	if err = search.Prepare(ctx, cfg.Expression, dbf); err != nil {
+		return err
+	}
 
-	outFile, err := os.OpenFile(cfg.ExportPath, os.O_WRONLY|os.O_CREATE, 0666)
+	if cfg.Compress && !strings.HasSuffix(cfg.ExportPath, ".gz") {
+		cfg.ExportPath += ".gz"
+	}
+
+	out, err := newStreamWriter(cfg.ExportPath, cfg.Compress)
 	if err != nil {
-		return fmt.Errorf("failed to create the export file %q. %w", cfg.ExportPath, err)
+		return err
 	}
-	defer outFile.Close()
+	defer out.Abort()
 
 	cfg.VerbosePrintln(fmt.Sprintf("Exporting database %q to CSV file %q", cfg.DbPath, cfg.ExportPath))
 
-	csvWriter := csv.NewWriter(outFile)
+	// Header comments that can be skipped by setting csv.Reader.Comment = '#'
+	fmt.Fprintf(out, "# Root: %s\n", dbf.RootPath())
+
+	csvWriter := csv.NewWriter(out)
+	fields := csvFields()
+	opts := path.FieldOptions{DisplayTime: cfg.DisplayTime}
 
 	// With a hash table
 	if dbf.Features().HasHashTable() {
@@ -88,16 +126,22 @@ func exportCSV(cfg Config) error {
 			return err
 		}
 
-		hashTable, err := dbf.ReadHashTable()
+		hashTable, err := dbf.ReadHashTable(ctx)
 		if err != nil {
 			return err
 		}
 
-		if err = csvWriter.Write([]string{"Id", "Size", "Mode", "ModTime", "IsDir", "Hash (" + algo.String() + ")", "Path"}); err != nil {
+		fmt.Fprintf(out, "# Hash: %s\n", db.AlgoString(algo))
+
+		if err = csvWriter.Write(csvHeader(fields, "Hash ("+db.AlgoString(algo)+")")); err != nil {
 			return err
 		}
 
-		err = dbf.ReadAllEntries(func(idx int, pi path.Info) error {
+		err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+			if ok, err := cfg.include(pi, hashTable[idx]); !ok || err != nil {
+				return err
+			}
+
 			var hashStr string
 			if !pi.IsDir() {
 				hash, ok := hashTable[idx]
@@ -111,50 +155,27 @@ func exportCSV(cfg Config) error {
 				pi.Path = filepath.Join(dbf.RootPath(), pi.Path)
 			}
 
-			err := csvWriter.Write([]string{
-				fmt.Sprintf("%x", pi.Id),
-				fmt.Sprintf("%d", pi.Size),
-				pi.Mode.String(),
-				pi.ModTime.Format(time.RFC3339Nano),
-				fmt.Sprintf("%t", pi.IsDir()),
-				hashStr,
-				pi.Path,
-			})
-			if err != nil {
-				return err
-			}
-
-			csvWriter.Flush()
-			return csvWriter.Error()
+			return csvWriter.Write(csvRecord(fields, &pi, opts, &hashStr))
 		})
 		if err != nil {
 			return fmt.Errorf("failed to export to file %q. %w", cfg.ExportPath, err)
 		}
 	} else {
 		// Without a hash table
-		if err = csvWriter.Write([]string{"Id", "Size", "Mode", "ModTime", "IsDir", "Path"}); err != nil {
+		if err = csvWriter.Write(csvHeader(fields, "")); err != nil {
 			return err
 		}
 
-		err = dbf.ReadAllEntries(func(idx int, pi path.Info) error {
-			if cfg.FullPaths {
-				pi.Path = filepath.Join(dbf.RootPath(), pi.Path)
+		err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+			if ok, err := cfg.include(pi, nil); !ok || err != nil {
+				return err
 			}
 
-			err := csvWriter.Write([]string{
-				fmt.Sprintf("%x", pi.Id),
-				fmt.Sprintf("%d", pi.Size),
-				pi.Mode.String(),
-				pi.ModTime.Format(time.RFC3339Nano),
-				fmt.Sprintf("%t", pi.IsDir()),
-				pi.Path,
-			})
-			if err != nil {
-				return err
+			if cfg.FullPaths {
+				pi.Path = filepath.Join(dbf.RootPath(), pi.Path)
 			}
 
-			csvWriter.Flush()
-			return csvWriter.Error()
+			return csvWriter.Write(csvRecord(fields, &pi, opts, nil))
 		})
 		if err != nil {
 			return fmt.Errorf("failed to export to file %q. %w", cfg.ExportPath, err)
@@ -166,6 +187,10 @@ func exportCSV(cfg Config) error {
 		return fmt.Errorf("failed to export to file %q. %w", cfg.ExportPath, err)
 	}
 
+	if err = out.Close(); err != nil {
+		return err
+	}
+
 	cfg.VerbosePrintln("Done!")
 	return nil
 }
@@ -173,23 +198,49 @@ func exportCSV(cfg Config) error {
 //-----------------------------------------------------------------------------
 // JSON
 
-type jsonEntry struct {
-	Id      string      `json:"id"`
-	Path    string      `json:"path"`
-	Size    uint64      `json:"size"`
-	Mode    fs.FileMode `json:"mode"`
-	ModeStr string      `json:"modeStr"`
-	ModTime time.Time   `json:"modTime"`
+type jsonHeader struct {
+	Version          int             `json:"version"`
+	DbPath           string          `json:"dbPath"`
+	Root             string          `json:"root"`
+	Features         db.FeatureFlags `json:"features"`
+	EntriesCount     int             `json:"entriesCount"`
+	FileEntriesCount int             `json:"fileCount"`
+	Meta             db.MetaEntry    `json:"meta"`
+	HashTableAlgo    string          `json:"hashTableAlgo,omitempty"`
+}
 
-	Hash string `json:"hash,omitempty"`
+func newJSONHeader(dbf *db.DatabaseFile) (jsonHeader, error) {
+	var hashAlgo string
+	if dbf.Features().HasHashTable() {
+		algo, err := dbf.HashTableAlgo()
+		if err != nil {
+			return jsonHeader{}, err
+		}
+		hashAlgo = db.AlgoString(algo)
+	}
+
+	return jsonHeader{
+		Version:          dbf.Version(),
+		DbPath:           dbf.Path(),
+		Root:             dbf.RootPath(),
+		Features:         dbf.Features(),
+		EntriesCount:     dbf.EntriesCount(),
+		FileEntriesCount: dbf.FileEntriesCount(),
+		Meta:             dbf.Meta(),
+		HashTableAlgo:    hashAlgo,
+	}, nil
 }
 
-func exportJSON(cfg Config) error {
+func exportJSON(ctx context.Context, cfg Config) error {
 	dbf, err := db.OpenDatabase(cfg.DbPath)
 	if err != nil {
 		return err
 	}
 	defer dbf.Close()
+	cfg.WarnIfLimited(dbf)
+	if err = search.Prepare(ctx, cfg.Expression, dbf); err != nil {
+		return err
+	}
 
 	outFile, err := os.OpenFile(cfg.ExportPath, os.O_WRONLY|os.O_CREATE, 0666)
 	if err != nil {
@@ -208,34 +259,12 @@ func exportJSON(cfg Config) error {
 		return fmt.Errorf("failed to create the export file %q. %w", cfg.ExportPath, err)
 	}
 
-	var hashAlgo string
-	if dbf.Features().HasHashTable() {
-		algo, err := dbf.HashTableAlgo()
-		if err != nil {
-			return err
-		}
-		hashAlgo = algo.String()
-	}
-
-	data, err := json.MarshalIndent(struct {
-		Version          int             `json:"version"`
-		DbPath           string          `json:"dbPath"`
-		Root             string          `json:"root"`
-		Features         db.FeatureFlags `json:"features"`
-		EntriesCount     int             `json:"entriesCount"`
-		FileEntriesCount int             `json:"fileCount"`
-		Meta             db.MetaEntry    `json:"meta"`
-		HashTableAlgo    string          `json:"hashTableAlgo,omitempty"`
-	}{
-		Version:          dbf.Version(),
-		DbPath:           dbf.Path(),
-		Root:             dbf.RootPath(),
-		Features:         dbf.Features(),
-		EntriesCount:     dbf.EntriesCount(),
-		FileEntriesCount: dbf.FileEntriesCount(),
-		Meta:             dbf.Meta(),
-		HashTableAlgo:    hashAlgo,
-	}, "\t", "\t")
+	header, err := newJSONHeader(dbf)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(header, "\t", "\t")
 	if err != nil {
 		return fmt.Errorf("failed to export json. encoding of header failed. %w", err)
 	}
@@ -252,17 +281,23 @@ func exportJSON(cfg Config) error {
 		return fmt.Errorf("failed to create the export file %q. %w", cfg.ExportPath, err)
 	}
 
+	fields := jsonFields()
+	opts := path.FieldOptions{DisplayTime: cfg.DisplayTime}
+
 	// With a hash table
 	if dbf.Features().HasHashTable() {
-		hashTable, err := dbf.ReadHashTable()
+		hashTable, err := dbf.ReadHashTable(ctx)
 		if err != nil {
 			return err
 		}
 
 		count := 0
-		expectedCount := dbf.EntriesCount()
 
-		err = dbf.ReadAllEntries(func(idx int, pi path.Info) error {
+		err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+			if ok, err := cfg.include(pi, hashTable[idx]); !ok || err != nil {
+				return err
+			}
+
 			var hashStr string
 			if !pi.IsDir() {
 				hash, ok := hashTable[idx]
@@ -276,31 +311,25 @@ func exportJSON(cfg Config) error {
 				pi.Path = filepath.Join(dbf.RootPath(), pi.Path)
 			}
 
-			data, err := json.MarshalIndent(jsonEntry{
-				Id:      hex.EncodeToString(pi.Id[:]),
-				Path:    pi.Path,
-				Size:    pi.Size,
-				Mode:    pi.Mode,
-				ModeStr: pi.Mode.String(),
-				ModTime: pi.ModTime,
-				Hash:    hashStr,
-			}, "\t\t", "\t")
+			data, err := marshalEntry(fields, &pi, opts, hashStr, "\t\t", "\t")
 
 			if err != nil {
 				return fmt.Errorf("failed to export json. encoding entry (index = %d) failed. %w", idx, err)
 			}
-			_, err = f.Write(data)
-			if err != nil {
-				return fmt.Errorf("failed to export json. writing entry (index = %d) failed. %w", idx, err)
-			}
 
-			count++
-			if count < expectedCount {
+			// Entries can be filtered out and thus the separator is written before every entry except the first
+			if count > 0 {
 				_, err = fmt.Fprintf(f, ",\n\t\t")
 				if err != nil {
 					return fmt.Errorf("failed to export json. writing entry (index = %d) failed. %w", idx, err)
 				}
 			}
+			count++
+
+			_, err = f.Write(data)
+			if err != nil {
+				return fmt.Errorf("failed to export json. writing entry (index = %d) failed. %w", idx, err)
+			}
 
 			if err = f.Flush(); err != nil {
 				return fmt.Errorf("failed to export json. writing entry (index = %d) failed. %w", idx, err)
@@ -316,37 +345,35 @@ func exportJSON(cfg Config) error {
 	} else {
 		// Without a hash table
 		count := 0
-		expectedCount := dbf.EntriesCount()
 
-		err = dbf.ReadAllEntries(func(idx int, pi path.Info) error {
+		err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+			if ok, err := cfg.include(pi, nil); !ok || err != nil {
+				return err
+			}
+
 			if cfg.FullPaths {
 				pi.Path = filepath.Join(dbf.RootPath(), pi.Path)
 			}
 
-			data, err := json.MarshalIndent(jsonEntry{
-				Id:      hex.EncodeToString(pi.Id[:]),
-				Path:    pi.Path,
-				Size:    pi.Size,
-				Mode:    pi.Mode,
-				ModeStr: pi.Mode.String(),
-				ModTime: pi.ModTime,
-			}, "\t\t", "\t")
+			data, err := marshalEntry(fields, &pi, opts, "", "\t\t", "\t")
 
 			if err != nil {
 				return fmt.Errorf("failed to export json. encoding entry (index = %d) failed. %w", idx, err)
 			}
-			_, err = f.Write(data)
-			if err != nil {
-				return fmt.Errorf("failed to export json. writing entry (index = %d) failed. %w", idx, err)
-			}
 
-			count++
-			if count < expectedCount {
+			// Entries can be filtered out and thus the separator is written before every entry except the first
+			if count > 0 {
 				_, err = fmt.Fprintf(f, ",\n\t\t")
 				if err != nil {
 					return fmt.Errorf("failed to export json. writing entry (index = %d) failed. %w", idx, err)
 				}
 			}
+			count++
+
+			_, err = f.Write(data)
+			if err != nil {
+				return fmt.Errorf("failed to export json. writing entry (index = %d) failed. %w", idx, err)
+			}
 
 			if err = f.Flush(); err != nil {
 				return fmt.Errorf("failed to export json. writing entry (index = %d) failed. %w", idx, err)
@@ -374,15 +401,109 @@ func exportJSON(cfg Config) error {
 	return nil
 }
 
+//-----------------------------------------------------------------------------
+// NDJSON
+
+// The first line in the NDJSON export is the header record and every line after that is an entry.
+type ndjsonHeader struct {
+	Database jsonHeader `json:"database"`
+}
+
+func exportNDJSON(ctx context.Context, cfg Config) error {
+	dbf, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+	defer dbf.Close()
+	cfg.WarnIfLimited(dbf)
+	if err = search.Prepare(ctx, cfg.Expression, dbf); err != nil {
+		return err
+	}
+
+	if cfg.Compress && !strings.HasSuffix(cfg.ExportPath, ".gz") {
+		cfg.ExportPath += ".gz"
+	}
+
+	out, err := newStreamWriter(cfg.ExportPath, cfg.Compress)
+	if err != nil {
+		return err
+	}
+	defer out.Abort()
+
+	cfg.VerbosePrintln(fmt.Sprintf("Exporting database %q to NDJSON file %q", cfg.DbPath, cfg.ExportPath))
+
+	header, err := newJSONHeader(dbf)
+	if err != nil {
+		return err
+	}
+
+	// json.Encoder terminates every value with a newline
+	enc := json.NewEncoder(out)
+	if err = enc.Encode(ndjsonHeader{Database: header}); err != nil {
+		return fmt.Errorf("failed to export ndjson. writing of header failed. %w", err)
+	}
+
+	var hashTable db.HashTable
+	if dbf.Features().HasHashTable() {
+		hashTable, err = dbf.ReadHashTable(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	fields := jsonFields()
+	opts := path.FieldOptions{DisplayTime: cfg.DisplayTime}
+
+	err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		if ok, err := cfg.include(pi, hashTable[idx]); !ok || err != nil {
+			return err
+		}
+
+		var hashStr string
+		if !pi.IsDir() {
+			if hash, ok := hashTable[idx]; ok {
+				hashStr = hex.EncodeToString(hash)
+			}
+		}
+
+		if cfg.FullPaths {
+			pi.Path = filepath.Join(dbf.RootPath(), pi.Path)
+		}
+
+		data, err := marshalEntry(fields, &pi, opts, hashStr, "", "")
+		if err == nil {
+			_, err = fmt.Fprintf(out, "%s\n", data)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to export ndjson. writing entry (index = %d) failed. %w", idx, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export to file %q. %w", cfg.ExportPath, err)
+	}
+
+	if err = out.Close(); err != nil {
+		return err
+	}
+
+	cfg.VerbosePrintln("Done!")
+	return nil
+}
+
 //-----------------------------------------------------------------------------
 // Hashdeep
 
-func exportHashdeep(cfg Config) error {
+func exportHashdeep(ctx context.Context, cfg Config) error {
 	dbf, err := db.OpenDatabase(cfg.DbPath)
 	if err != nil {
 		return err
 	}
 	defer dbf.Close()
+	cfg.WarnIfLimited(dbf)
+	if err = search.Prepare(ctx, cfg.Expression, dbf); err != nil {
+		return err
+	}
 
 	if !dbf.Features().HasHashTable() {
 		return fmt.Errorf("failed to create the export file %q because the ajfs database %q does not contain a hash table",
@@ -416,8 +537,10 @@ func exportHashdeep(cfg Config) error {
 		hashStr = "sha1"
 	case ajhash.AlgoSHA256:
 		hashStr = "sha256"
+	case db.AlgoMD5:
+		hashStr = "md5"
 	default:
-		return fmt.Errorf("failed to create the export file %q. hashdeep does not support %q", cfg.ExportPath, algo.String())
+		return fmt.Errorf("failed to create the export file %q. hashdeep does not support %q", cfg.ExportPath, db.AlgoString(algo))
 	}
 
 	_, err = fmt.Fprintf(f, "%%%%%%%% size,%s,filename\n", hashStr)
@@ -435,7 +558,11 @@ func exportHashdeep(cfg Config) error {
 		return fmt.Errorf("failed to create the export file %q. %w", cfg.ExportPath, err)
 	}
 
-	err = dbf.ReadAllEntriesWithHashes(func(idx int, pi path.Info, hash []byte) error {
+	err = dbf.ReadAllEntriesWithHashes(ctx, func(idx int, pi path.Info, hash []byte) error {
+		if ok, err := cfg.include(pi, hash); !ok || err != nil {
+			return err
+		}
+
 		hashStr := hex.EncodeToString(hash)
 
 		var err error
@@ -467,4 +594,5 @@ const (
 	FormatCSV int = iota
 	FormatJSON
 	FormatHashdeep
+	FormatNDJSON
 )
@@ -21,6 +21,8 @@
 package export_test
 
 import (
+	"compress/gzip"
+	"context"
 	"encoding/csv"
 	"encoding/hex"
 	"encoding/json"
@@ -29,12 +31,14 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/andrejacobs/ajfs/internal/app/config"
 	"github.com/andrejacobs/ajfs/internal/app/export"
 	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/app/search"
 	"github.com/andrejacobs/ajfs/internal/db"
 	"github.com/andrejacobs/ajfs/internal/path"
 	"github.com/andrejacobs/ajfs/internal/testshared"
@@ -58,6 +62,7 @@ func TestExportCSV(t *testing.T) {
 	require.NoError(t, err)
 	defer os.Remove(expectedF.Name())
 
+	fmt.Fprintln(expectedF, "# Root: /test")
 	csvWriter := csv.NewWriter(expectedF)
 	csvWriter.Write([]string{"Id", "Size", "Mode", "ModTime", "IsDir", "Path"})
 
@@ -86,7 +91,7 @@ func TestExportCSV(t *testing.T) {
 		ExportPath: tempExportFile,
 	}
 
-	require.NoError(t, export.Run(cfg))
+	require.NoError(t, export.Run(context.Background(), cfg))
 
 	testshared.SimpleDiff(t, expectedF.Name(), tempExportFile)
 }
@@ -105,6 +110,8 @@ func TestExportWithHashesCSV(t *testing.T) {
 	require.NoError(t, err)
 	defer os.Remove(expectedF.Name())
 
+	fmt.Fprintln(expectedF, "# Root: /test")
+	fmt.Fprintln(expectedF, "# Hash: "+ajhash.AlgoSHA1.String())
 	csvWriter := csv.NewWriter(expectedF)
 	csvWriter.Write([]string{"Id", "Size", "Mode", "ModTime", "IsDir", "Hash (" + ajhash.AlgoSHA1.String() + ")", "Path"})
 
@@ -136,11 +143,54 @@ func TestExportWithHashesCSV(t *testing.T) {
 		ExportPath: tempExportFile,
 	}
 
-	require.NoError(t, export.Run(cfg))
+	require.NoError(t, export.Run(context.Background(), cfg))
 
 	testshared.SimpleDiff(t, expectedF.Name(), tempExportFile)
 }
 
+func TestExportCompressedCSV(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+	tempExportFile := filepath.Join(t.TempDir(), "unit-test.ajfs.csv")
+
+	expected := expectedDatabase(t, tempFile, true)
+
+	cfg := export.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: tempFile,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		Format:     export.FormatCSV,
+		ExportPath: tempExportFile,
+		Compress:   true,
+	}
+
+	require.NoError(t, export.Run(context.Background(), cfg))
+
+	// The .gz extension is added
+	f, err := os.Open(tempExportFile + ".gz")
+	require.NoError(t, err)
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+
+	csvReader := csv.NewReader(gz)
+	csvReader.Comment = '#'
+	records, err := csvReader.ReadAll()
+	require.NoError(t, err)
+
+	require.Len(t, records, len(expected)+1)
+	for i, exp := range expected {
+		assert.Equal(t, hex.EncodeToString(exp.hash), records[i+1][5])
+		assert.Equal(t, exp.pi.Path, records[i+1][6])
+	}
+
+	// Only CSV can be compressed
+	cfg.Format = export.FormatJSON
+	assert.Error(t, export.Run(context.Background(), cfg))
+}
+
 //-----------------------------------------------------------------------------
 
 type JsonEntry struct {
@@ -192,7 +242,7 @@ func TestExportJSON(t *testing.T) {
 
 	entries := make([]JsonEntry, 0, dbf.EntriesCount())
 
-	err = dbf.ReadAllEntries(func(idx int, pi path.Info) error {
+	err = dbf.ReadAllEntries(context.Background(), func(idx int, pi path.Info) error {
 		entry := JsonEntry{
 			Id:      hex.EncodeToString(pi.Id[:]),
 			Path:    pi.Path,
@@ -235,7 +285,7 @@ func TestExportJSON(t *testing.T) {
 		ExportPath: tempExportFile,
 	}
 
-	require.NoError(t, export.Run(cfg))
+	require.NoError(t, export.Run(context.Background(), cfg))
 
 	testshared.SimpleDiff(t, expectedF.Name(), tempExportFile)
 }
@@ -270,10 +320,10 @@ func TestExportWithHashesJSON(t *testing.T) {
 
 	entries := make([]JsonEntry, 0, dbf.EntriesCount())
 
-	hashTable, err := dbf.ReadHashTable()
+	hashTable, err := dbf.ReadHashTable(context.Background())
 	require.NoError(t, err)
 
-	err = dbf.ReadAllEntries(func(idx int, pi path.Info) error {
+	err = dbf.ReadAllEntries(context.Background(), func(idx int, pi path.Info) error {
 		var hashStr string
 		if !pi.IsDir() {
 			hash, ok := hashTable[idx]
@@ -326,13 +376,189 @@ func TestExportWithHashesJSON(t *testing.T) {
 		ExportPath: tempExportFile,
 	}
 
-	require.NoError(t, export.Run(cfg))
+	require.NoError(t, export.Run(context.Background(), cfg))
+
+	testshared.SimpleDiff(t, expectedF.Name(), tempExportFile)
+}
+
+func TestExportNDJSON(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+	tempExportFile := filepath.Join(t.TempDir(), "unit-test.ajfs.ndjson")
+
+	expectedDatabase(t, tempFile, true)
+
+	dbf, err := db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+
+	algo, err := dbf.HashTableAlgo()
+	require.NoError(t, err)
+
+	expectedF, err := os.CreateTemp("", "unit-test.ajfs.expected.ndjson")
+	require.NoError(t, err)
+	defer os.Remove(expectedF.Name())
+
+	encoder := json.NewEncoder(expectedF)
+
+	header := struct {
+		Database JsonDatabase `json:"database"`
+	}{
+		Database: JsonDatabase{
+			Version:          dbf.Version(),
+			DbPath:           dbf.Path(),
+			Root:             dbf.RootPath(),
+			Features:         dbf.Features(),
+			EntriesCount:     dbf.EntriesCount(),
+			FileEntriesCount: dbf.FileEntriesCount(),
+			Meta:             dbf.Meta(),
+			HashTableAlgo:    algo.String(),
+		},
+	}
+	require.NoError(t, encoder.Encode(&header))
+
+	hashTable, err := dbf.ReadHashTable(context.Background())
+	require.NoError(t, err)
+
+	err = dbf.ReadAllEntries(context.Background(), func(idx int, pi path.Info) error {
+		var hashStr string
+		if !pi.IsDir() {
+			hash, ok := hashTable[idx]
+
+			if ok {
+				hashStr = hex.EncodeToString(hash)
+			}
+		}
+
+		return encoder.Encode(JsonEntry{
+			Id:      hex.EncodeToString(pi.Id[:]),
+			Path:    pi.Path,
+			Size:    pi.Size,
+			Mode:    pi.Mode,
+			ModeStr: pi.Mode.String(),
+			ModTime: pi.ModTime,
+			Hash:    hashStr,
+		})
+	})
+	require.NoError(t, err)
+	require.NoError(t, dbf.Close())
+	require.NoError(t, expectedF.Close())
+
+	cfg := export.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: tempFile,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		Format:     export.FormatNDJSON,
+		ExportPath: tempExportFile,
+	}
+
+	require.NoError(t, export.Run(context.Background(), cfg))
 
 	testshared.SimpleDiff(t, expectedF.Name(), tempExportFile)
 }
 
 //-----------------------------------------------------------------------------
 
+func TestExportUnderJSON(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+	tempExportFile := filepath.Join(t.TempDir(), "unit-test.ajfs.json")
+
+	expectedDatabase(t, tempFile, true)
+
+	cfg := export.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: tempFile,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		UnderConfig: config.UnderConfig{
+			Under: "some",
+		},
+		Format:     export.FormatJSON,
+		ExportPath: tempExportFile,
+	}
+
+	require.NoError(t, export.Run(context.Background(), cfg))
+
+	data, err := os.ReadFile(tempExportFile)
+	require.NoError(t, err)
+
+	var actual struct {
+		Entries []JsonEntry `json:"entries"`
+	}
+	require.NoError(t, json.Unmarshal(data, &actual))
+
+	require.Len(t, actual.Entries, 1)
+	assert.Equal(t, "some/dir", actual.Entries[0].Path)
+}
+
+func TestExportWithExpression(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+	tempExportFile := filepath.Join(t.TempDir(), "unit-test.ajfs.json")
+
+	expectedDatabase(t, tempFile, true)
+
+	exp, err := search.NewType("d")
+	require.NoError(t, err)
+
+	cfg := export.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: tempFile,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		Format:     export.FormatJSON,
+		ExportPath: tempExportFile,
+		Expression: exp,
+	}
+
+	require.NoError(t, export.Run(context.Background(), cfg))
+
+	data, err := os.ReadFile(tempExportFile)
+	require.NoError(t, err)
+
+	var actual struct {
+		Entries []JsonEntry `json:"entries"`
+	}
+	require.NoError(t, json.Unmarshal(data, &actual))
+
+	require.Len(t, actual.Entries, 1)
+	assert.Equal(t, "some/dir", actual.Entries[0].Path)
+}
+
+func TestExportCancelled(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+	_ = expectedDatabase(t, tempFile, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := []struct {
+		desc   string
+		format int
+	}{
+		{desc: "csv", format: export.FormatCSV},
+		{desc: "json", format: export.FormatJSON},
+		{desc: "ndjson", format: export.FormatNDJSON},
+		{desc: "hashdeep", format: export.FormatHashdeep},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			cfg := export.Config{
+				CommonConfig: config.CommonConfig{
+					DbPath: tempFile,
+					Stdout: io.Discard,
+					Stderr: io.Discard,
+				},
+				Format:     tC.format,
+				ExportPath: filepath.Join(t.TempDir(), "unit-test.export"),
+			}
+
+			assert.ErrorIs(t, export.Run(ctx, cfg), context.Canceled)
+		})
+	}
+}
+
 func TestExportHashdeep(t *testing.T) {
 	testCases := []struct {
 		algo         ajhash.Algo
@@ -367,7 +593,7 @@ func TestExportHashdeep(t *testing.T) {
 				Algo:            algo,
 			}
 
-			err := scan.Run(cfg)
+			err := scan.Run(context.Background(), cfg)
 			require.NoError(t, err)
 
 			tempExportFile := filepath.Join(t.TempDir(), "unit-test.ajfs.hashdeep")
@@ -384,7 +610,7 @@ func TestExportHashdeep(t *testing.T) {
 				ExportPath: tempExportFile,
 			}
 
-			require.NoError(t, export.Run(exportCfg))
+			require.NoError(t, export.Run(context.Background(), exportCfg))
 
 			// Validate
 			expectedHashDeep, err := testshared.ReadHashDeepFile(tC.hashDeepFile)
@@ -412,6 +638,7 @@ func TestExportFullPath(t *testing.T) {
 	require.NoError(t, err)
 	defer os.Remove(expectedF.Name())
 
+	fmt.Fprintln(expectedF, "# Root: /test")
 	csvWriter := csv.NewWriter(expectedF)
 	csvWriter.Write([]string{"Id", "Size", "Mode", "ModTime", "IsDir", "Path"})
 
@@ -441,11 +668,65 @@ func TestExportFullPath(t *testing.T) {
 		FullPaths:  true,
 	}
 
-	require.NoError(t, export.Run(cfg))
+	require.NoError(t, export.Run(context.Background(), cfg))
 
 	testshared.SimpleDiff(t, expectedF.Name(), tempExportFile)
 }
 
+func TestExportInvalidUTF8(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test/", db.FeatureJustEntries)
+	require.NoError(t, err)
+	pi := path.Info{
+		Id:      path.IdFromPath("caf\xe9\\x.txt"),
+		Path:    "caf\xe9\\x.txt",
+		Size:    uint64(42),
+		Mode:    0640,
+		ModTime: time.Now(),
+	}
+	require.NoError(t, dbf.WriteEntry(&pi))
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.Close())
+
+	exportAs := func(format int) string {
+		exportPath := filepath.Join(t.TempDir(), "export")
+		cfg := export.Config{
+			CommonConfig: config.CommonConfig{
+				DbPath: tempFile,
+				Stdout: io.Discard,
+				Stderr: io.Discard,
+			},
+			Format:     format,
+			ExportPath: exportPath,
+		}
+		require.NoError(t, export.Run(context.Background(), cfg))
+
+		data, err := os.ReadFile(exportPath)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	reader := csv.NewReader(strings.NewReader(exportAs(export.FormatCSV)))
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	escaped := records[1][len(records[1])-1]
+	assert.Equal(t, `caf\xe9\\x.txt`, escaped)
+	assert.Equal(t, pi.Path, path.Unescape(escaped))
+
+	lines := strings.Split(strings.TrimSpace(exportAs(export.FormatNDJSON)), "\n")
+	require.Len(t, lines, 2)
+	var entry struct {
+		Path       string `json:"path"`
+		PathBase64 []byte `json:"pathBase64"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, escaped, entry.Path)
+	assert.Equal(t, pi.Path, string(entry.PathBase64))
+}
+
 //-----------------------------------------------------------------------------
 
 type expectedEntry struct {
@@ -0,0 +1,140 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/andrejacobs/ajfs/internal/path"
+)
+
+// The layout of the fields that the CSV export has always contained. The other registered fields are added
+// after these, followed by the hash and the path which is always the last column.
+var csvLayout = []string{"id", "size", "mode", "modTime", "isDir"}
+
+// The layout of the fields that the JSON exports have always contained. The other registered fields are added
+// after these, followed by the hash.
+var jsonLayout = []string{"id", "path", "size", "mode", "modeStr", "modTime"}
+
+// Return the fields exported as CSV, excluding the path.
+func csvFields() []path.Field {
+	result := path.FieldsNamed(csvLayout...)
+	for _, f := range path.FieldsExcept(append(slices.Clone(csvLayout), "path")...) {
+		if f.Header != "" {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// Return the CSV column headers. No hash column is added when hashHeader is empty.
+func csvHeader(fields []path.Field, hashHeader string) []string {
+	result := make([]string, 0, len(fields)+2)
+	for _, f := range fields {
+		result = append(result, f.Header)
+	}
+	if hashHeader != "" {
+		result = append(result, hashHeader)
+	}
+	return append(result, "Path")
+}
+
+// Return the CSV record for the entry. No hash column is added when hash is nil.
+func csvRecord(fields []path.Field, pi *path.Info, opts path.FieldOptions, hash *string) []string {
+	result := make([]string, 0, len(fields)+2)
+	for _, f := range fields {
+		result = append(result, f.Text(pi, opts))
+	}
+	if hash != nil {
+		result = append(result, *hash)
+	}
+	return append(result, path.Escape(pi.Path))
+}
+
+// Return the fields exported as JSON.
+func jsonFields() []path.Field {
+	result := path.FieldsNamed(jsonLayout...)
+	for _, f := range path.FieldsExcept(jsonLayout...) {
+		if f.Value != nil {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// Encode the entry as a JSON object with the keys in the same order as the fields.
+// The exact bytes of a path that is not valid UTF-8 are added as "pathBase64", since the "path" is escaped
+// (see [path.Escape]) and JSON strings can't contain invalid UTF-8.
+// The hash is only added when it is not empty. The object is indented when indent is not empty (see [json.Indent]).
+func marshalEntry(fields []path.Field, pi *path.Info, opts path.FieldOptions, hash string, prefix string, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	write := func(key string, value any) error {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		data, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte(':')
+
+		data, err = json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode the field %q. %w", key, err)
+		}
+		buf.Write(data)
+		return nil
+	}
+
+	for _, f := range fields {
+		if err := write(f.Name, f.Value(pi, opts)); err != nil {
+			return nil, err
+		}
+	}
+	if !utf8.ValidString(pi.Path) {
+		if err := write("pathBase64", base64.StdEncoding.EncodeToString([]byte(pi.Path))); err != nil {
+			return nil, err
+		}
+	}
+	if hash != "" {
+		if err := write("hash", hash); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+
+	if indent == "" {
+		return buf.Bytes(), nil
+	}
+
+	var result bytes.Buffer
+	if err := json.Indent(&result, buf.Bytes(), prefix, indent); err != nil {
+		return nil, err
+	}
+	return result.Bytes(), nil
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package export
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+)
+
+// streamWriter buffers the writes to the export file and optionally gzip compresses the data.
+type streamWriter struct {
+	path string
+	file *os.File
+	gz   *gzip.Writer
+	buf  *bufio.Writer
+}
+
+// Size of the write buffer. Large enough to not hit the file system for every row.
+const streamBufferSize = 256 * 1024
+
+// Create (or truncate) the export file.
+func newStreamWriter(path string, compress bool) (*streamWriter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the export file %q. %w", path, err)
+	}
+
+	w := &streamWriter{
+		path: path,
+		file: f,
+	}
+
+	var dst io.Writer = f
+	if compress {
+		w.gz = gzip.NewWriter(f)
+		dst = w.gz
+	}
+	w.buf = bufio.NewWriterSize(dst, streamBufferSize)
+
+	return w, nil
+}
+
+// Write implements io.Writer.
+func (w *streamWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+// Flush all buffered data and close the file.
+func (w *streamWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.buf.Flush()
+	if (err == nil) && (w.gz != nil) {
+		err = w.gz.Close()
+	}
+
+	closeErr := w.file.Close()
+	w.file = nil
+
+	if err != nil {
+		return fmt.Errorf("failed to write to the export file %q. %w", w.path, err)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to close the export file %q. %w", w.path, closeErr)
+	}
+	return nil
+}
+
+// Close the file without flushing any buffered data. Does nothing if Close has already been called.
+func (w *streamWriter) Abort() {
+	if w.file != nil {
+		_ = w.file.Close()
+		w.file = nil
+	}
+}
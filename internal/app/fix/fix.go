@@ -23,6 +23,7 @@ package fix
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -39,13 +40,25 @@ type Config struct {
 	Stdin       io.Reader
 	DryRun      bool   // Only display what needs to be fixed.
 	RestorePath string // Path to a backup header to be restored.
+	Force       bool   // Make changes even if the database has been sealed.
+	SalvagePath string // Path to a new database into which the readable entries will be copied.
 }
 
 // Process the ajfs fix command.
-func Run(cfg Config) error {
+func Run(ctx context.Context, cfg Config) error {
+
+	// Salvage? The damaged database is only read and thus no confirmation is needed
+	if cfg.SalvagePath != "" {
+		fmt.Fprintf(cfg.Stdout, "Salvaging database file: %q to: %q\n", cfg.DbPath, cfg.SalvagePath)
+		return db.SalvageDatabase(cfg.Stdout, cfg.DbPath, cfg.SalvagePath)
+	}
 
 	// Confirm with user
 	if !cfg.DryRun {
+		if err := db.EnsureNotSealed(cfg.DbPath, cfg.Force); err != nil {
+			return err
+		}
+
 		r := bufio.NewReader(cfg.Stdin)
 		fmt.Fprintf(cfg.Stdout, "WARNING: Changes might be made to the database: %q\n", cfg.DbPath)
 		fmt.Fprintf(cfg.Stdout, "Type 'yes' to confirm you want to continue: ")
@@ -73,6 +86,7 @@ func Run(cfg Config) error {
 
 	bakPath := filepath.Join(cwd, filepath.Base(cfg.DbPath)+".bak")
 
+	// Deliberately not cancellable since stopping part way could leave the database in a worse state
 	if err := db.FixDatabase(cfg.Stdout, cfg.DbPath, cfg.DryRun, bakPath); err != nil {
 		fmt.Fprintf(cfg.Stderr, "!! ERROR: %v\n", err)
 		return err
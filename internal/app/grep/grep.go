@@ -0,0 +1,319 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package grep provides the functionality for ajfs grep command.
+package grep
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sync"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/search"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+)
+
+// ErrNoMatch is returned when none of the files contain the pattern.
+var ErrNoMatch = errors.New("no matches found")
+
+// Config for the ajfs grep command.
+type Config struct {
+	config.CommonConfig
+	config.UnderConfig
+
+	Pattern          string            // The regular expression used to match the lines of each file.
+	FixedString      bool              // Match the pattern as a literal string instead of a regular expression.
+	IgnoreCase       bool              // Match the pattern case insensitive.
+	FilesWithMatches bool              // Only display the path entries of the files that contain a match.
+	Expression       search.Expression // [optional] Only search the files matching the expression.
+	AlsoHashes       bool              // If the hashes need to also be checked, because we know one of the expressions require this.
+	Jobs             int               // Maximum number of files being searched at the same time. Zero means the number of CPUs.
+	DisplayFullPaths bool              // If true then each path will be prefixed with the root path of the database.
+}
+
+// Process the ajfs grep command.
+// Returns [ErrNoMatch] when none of the files contain the pattern.
+func Run(ctx context.Context, cfg Config) error {
+	if cfg.Pattern == "" {
+		return fmt.Errorf("expected a pattern to search for")
+	}
+	if cfg.Jobs < 0 {
+		return fmt.Errorf("the number of jobs can't be negative")
+	}
+
+	re, err := compilePattern(cfg)
+	if err != nil {
+		return err
+	}
+
+	jobs := cfg.Jobs
+	if jobs == 0 {
+		jobs = runtime.NumCPU()
+	}
+
+	dbf, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+	defer dbf.Close()
+	cfg.WarnIfLimited(dbf)
+	if err = search.Prepare(ctx, cfg.Expression, dbf); err != nil {
+		return err
+	}
+
+	root := dbf.RootPath()
+	if _, err := os.Stat(root); err != nil {
+		return fmt.Errorf("failed to access the root path %q of the database. %w", root, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	candidates := make(chan candidate, jobs)
+	results := make(chan fileResult, jobs)
+
+	// Reading the database is not safe for concurrent use, so only the file contents are searched concurrently
+	var readErr error
+	go func() {
+		defer close(candidates)
+		readErr = readCandidates(ctx, cfg, dbf, func(c candidate) error {
+			select {
+			case candidates <- c:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for range jobs {
+		wg.Go(func() {
+			for c := range candidates {
+				results <- searchFile(root, c, re, cfg.FilesWithMatches)
+			}
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Display the results in the order stored in the database
+	pending := make(map[int]fileResult)
+	next := 0
+	matchedFiles := 0
+
+	for r := range results {
+		pending[r.seq] = r
+		for {
+			r, exists := pending[next]
+			if !exists {
+				break
+			}
+			delete(pending, next)
+			next++
+
+			if display(cfg, root, r) {
+				matchedFiles++
+			}
+		}
+	}
+
+	if readErr != nil {
+		return readErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if matchedFiles == 0 {
+		return ErrNoMatch
+	}
+	return nil
+}
+
+// Compile the regular expression used to match the lines.
+func compilePattern(cfg Config) (*regexp.Regexp, error) {
+	pattern := cfg.Pattern
+	if cfg.FixedString {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if cfg.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile the pattern %q. %w", cfg.Pattern, err)
+	}
+	return re, nil
+}
+
+// A file from the database of which the contents need to be searched.
+type candidate struct {
+	seq  int // Order in which the candidate was found in the database.
+	info path.Info
+}
+
+// Read the files from the database that need to be searched and call fn for each.
+func readCandidates(ctx context.Context, cfg Config, dbf *db.DatabaseFile, fn func(c candidate) error) error {
+	seq := 0
+
+	matchFn := func(pi path.Info, hash []byte) error {
+		if !pi.Mode.IsRegular() || !cfg.IsUnder(pi.Path) {
+			return nil
+		}
+
+		if cfg.Expression != nil {
+			matched, err := cfg.Expression.Match(pi, hash)
+			if err != nil {
+				return err
+			}
+			if !matched {
+				return nil
+			}
+		}
+
+		c := candidate{seq: seq, info: pi}
+		seq++
+		return fn(c)
+	}
+
+	if cfg.AlsoHashes && dbf.Features().HasHashTable() {
+		return dbf.ReadAllEntriesWithHashes(ctx, func(idx int, pi path.Info, hash []byte) error {
+			return matchFn(pi, hash)
+		})
+	}
+
+	return dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		return matchFn(pi, nil)
+	})
+}
+
+// The outcome of searching a single file.
+type fileResult struct {
+	candidate
+	matches []lineMatch
+	binary  bool  // The file contains binary data and thus the matching lines are not displayed.
+	changed bool  // The size or last modification time on disk differs from the snapshot.
+	err     error // Set when the file could not be searched.
+}
+
+// A line that matched the pattern.
+type lineMatch struct {
+	number int
+	text   string
+}
+
+// Number of bytes inspected at the start of a file to decide if it contains binary data.
+const binaryPeekSize = 8 * 1024
+
+// Search the contents of the file on disk for lines that match the pattern.
+// When firstOnly is true then the search stops at the first matching line.
+func searchFile(root string, c candidate, re *regexp.Regexp, firstOnly bool) fileResult {
+	result := fileResult{candidate: c}
+
+	f, err := os.Open(filepath.Join(root, c.info.Path))
+	if err != nil {
+		result.err = err
+		return result
+	}
+	defer f.Close()
+
+	if fi, err := f.Stat(); err == nil {
+		result.changed = (fi.Size() != int64(c.info.Size)) || !fi.ModTime().Equal(c.info.ModTime) //nolint:gosec // disable G115
+	}
+
+	r := bufio.NewReader(f)
+
+	// Same heuristic as grep, a NUL byte means binary data
+	head, _ := r.Peek(binaryPeekSize)
+	result.binary = bytes.IndexByte(head, 0) >= 0
+	firstOnly = firstOnly || result.binary
+
+	number := 0
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			number++
+			line = bytes.TrimRight(line, "\r\n")
+			if re.Match(line) {
+				result.matches = append(result.matches, lineMatch{number: number, text: string(line)})
+				if firstOnly {
+					return result
+				}
+			}
+		}
+
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				result.err = err
+			}
+			return result
+		}
+	}
+}
+
+// Display the outcome of searching a file and return true if the file contained a match.
+func display(cfg Config, root string, r fileResult) bool {
+	displayPath := r.info.Path
+	if cfg.DisplayFullPaths {
+		displayPath = filepath.Join(root, displayPath)
+	}
+
+	if r.err != nil {
+		cfg.Errorln(fmt.Sprintf("WARNING: failed to search %q. %v", displayPath, r.err))
+	}
+	if len(r.matches) == 0 {
+		return false
+	}
+
+	if r.changed {
+		cfg.Errorln(fmt.Sprintf("WARNING: %q has changed since the database was created", displayPath))
+	}
+
+	if cfg.FilesWithMatches {
+		pi := r.info
+		pi.Path = displayPath
+		cfg.Println(pi)
+		return true
+	}
+
+	if r.binary {
+		cfg.Println(fmt.Sprintf("Binary file %s matches", displayPath))
+		return true
+	}
+
+	for _, m := range r.matches {
+		cfg.Println(fmt.Sprintf("%s:%d:%s", displayPath, m.number, m.text))
+	}
+	return true
+}
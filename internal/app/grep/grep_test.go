@@ -0,0 +1,135 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package grep_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/grep"
+	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/app/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrep(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "docs"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("hello\nTODO: first\nbye\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "b.go"), []byte("package b\n// todo: second"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "docs", "c.txt"), []byte("nothing here\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "d.bin"), []byte("TODO\x00\x01"), 0o644))
+
+	dbPath := filepath.Join(t.TempDir(), "unit-testing")
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: dbPath,
+		},
+		Root: root,
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	var outBuffer bytes.Buffer
+	var errBuffer bytes.Buffer
+
+	cfg := grep.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: &errBuffer,
+			DbPath: dbPath,
+		},
+		Pattern: "TODO",
+		Jobs:    2,
+	}
+
+	require.NoError(t, grep.Run(context.Background(), cfg))
+	assert.Equal(t, "a.txt:2:TODO: first\nBinary file d.bin matches\n", outBuffer.String())
+	assert.Empty(t, errBuffer.String())
+
+	// Case insensitive
+	outBuffer.Reset()
+	cfg.IgnoreCase = true
+	require.NoError(t, grep.Run(context.Background(), cfg))
+	assert.Equal(t, "a.txt:2:TODO: first\nb.go:2:// todo: second\nBinary file d.bin matches\n", outBuffer.String())
+
+	// Narrowed down using an expression
+	outBuffer.Reset()
+	exp, err := search.NewShellPattern("*.go", false, false)
+	require.NoError(t, err)
+	cfg.Expression = exp
+	require.NoError(t, grep.Run(context.Background(), cfg))
+	assert.Equal(t, "b.go:2:// todo: second\n", outBuffer.String())
+	cfg.Expression = nil
+
+	// Fixed string
+	outBuffer.Reset()
+	cfg.IgnoreCase = false
+	cfg.FixedString = true
+	cfg.Pattern = "TODO:"
+	require.NoError(t, grep.Run(context.Background(), cfg))
+	assert.Equal(t, "a.txt:2:TODO: first\n", outBuffer.String())
+
+	cfg.Pattern = "TO.O"
+	assert.ErrorIs(t, grep.Run(context.Background(), cfg), grep.ErrNoMatch)
+
+	// Only the files
+	outBuffer.Reset()
+	cfg.FixedString = false
+	cfg.FilesWithMatches = true
+	cfg.Pattern = "e"
+	require.NoError(t, grep.Run(context.Background(), cfg))
+	out := outBuffer.String()
+	assert.Contains(t, out, "a.txt")
+	assert.Contains(t, out, "docs/c.txt")
+	assert.NotContains(t, out, "d.bin")
+
+	// Changed and missing files are reported
+	outBuffer.Reset()
+	cfg.FilesWithMatches = false
+	cfg.Pattern = "here"
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(root, "docs", "c.txt"), later, later))
+	require.NoError(t, os.Remove(filepath.Join(root, "a.txt")))
+	require.NoError(t, grep.Run(context.Background(), cfg))
+	assert.Equal(t, "docs/c.txt:1:nothing here\n", outBuffer.String())
+	assert.Contains(t, errBuffer.String(), `WARNING: failed to search "a.txt"`)
+	assert.Contains(t, errBuffer.String(), `WARNING: "docs/c.txt" has changed`)
+}
+
+func TestGrepInvalidPattern(t *testing.T) {
+	cfg := grep.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		Pattern: "(",
+	}
+	assert.Error(t, grep.Run(context.Background(), cfg))
+}
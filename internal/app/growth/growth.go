@@ -0,0 +1,238 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package growth provides the functionality for ajfs growth command.
+package growth
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/human"
+)
+
+// Config for the ajfs growth command.
+type Config struct {
+	config.CommonConfig
+	config.UnderConfig
+
+	OldPath string // Path to the older database.
+	NewPath string // Path to the newer database.
+
+	HumanSizes bool // Display sizes in a human friendly format (e.g. 1.2 GB).
+}
+
+// Process the ajfs growth command.
+func Run(ctx context.Context, cfg Config) error {
+	groups, err := Calculate(ctx, cfg.OldPath, cfg.NewPath, cfg.UnderConfig)
+	if err != nil {
+		return err
+	}
+
+	var total Growth
+	for _, g := range groups {
+		total.OldSize += g.OldSize
+		total.NewSize += g.NewSize
+		total.OldFiles += g.OldFiles
+		total.NewFiles += g.NewFiles
+		total.Added += g.Added
+		total.Removed += g.Removed
+	}
+
+	sizeWidth := 14
+	if cfg.HumanSizes {
+		sizeWidth = 10
+	}
+
+	cfg.Println(fmt.Sprintf("%*s  %*s  %*s  %8s  %8s  %s", sizeWidth, "Growth", sizeWidth, "Old size", sizeWidth, "New size",
+		"New", "Removed", "Path"))
+
+	format := func(g Growth, name string) string {
+		return fmt.Sprintf("%*s  %*s  %*s  %8d  %8d  %s", sizeWidth, cfg.formatDelta(g.Delta()), sizeWidth, cfg.formatSize(g.OldSize),
+			sizeWidth, cfg.formatSize(g.NewSize), g.Added, g.Removed, name)
+	}
+
+	for _, g := range groups {
+		if !g.Changed() {
+			continue
+		}
+		name := g.Path
+		if g.IsDir {
+			name += string(filepath.Separator)
+		}
+		cfg.Println(format(g, name))
+	}
+
+	cfg.Println()
+	cfg.Println(format(total, "Total"))
+	return nil
+}
+
+func (cfg *Config) formatSize(size uint64) string {
+	if cfg.HumanSizes {
+		return human.Bytes(size)
+	}
+	return fmt.Sprintf("%d", size)
+}
+
+func (cfg *Config) formatDelta(delta int64) string {
+	if !cfg.HumanSizes {
+		return fmt.Sprintf("%+d", delta)
+	}
+	if delta < 0 {
+		return "-" + human.Bytes(uint64(-delta))
+	}
+	return "+" + human.Bytes(uint64(delta))
+}
+
+// Growth is the change in the size and number of files of a directory between two databases.
+type Growth struct {
+	Path  string // Directory (relative to the root) or a file when it is not inside a directory.
+	IsDir bool   // False if Path is a file that is not inside a directory.
+
+	OldSize  uint64 // Total size of the files at or below Path in the older database
+	NewSize  uint64 // Total size of the files at or below Path in the newer database
+	OldFiles int    // Number of files at or below Path in the older database
+	NewFiles int    // Number of files at or below Path in the newer database
+
+	Added   int // Number of files that only exist in the newer database
+	Removed int // Number of files that only exist in the older database
+}
+
+// Return the number of bytes by which the files grew (positive) or shrunk (negative).
+func (g Growth) Delta() int64 {
+	return int64(g.NewSize) - int64(g.OldSize) //nolint:gosec // disable G115
+}
+
+// Return true if any files were added or removed or the total size changed.
+func (g Growth) Changed() bool {
+	return g.Added > 0 || g.Removed > 0 || g.OldSize != g.NewSize
+}
+
+// Calculate the growth of each top level directory (or of each directory directly below under) between the
+// older and newer database.
+// Files are paired up using their path relative to the root. A file that changed in size is counted in the size
+// of both databases but not as added or removed.
+// The result is ordered from the largest growth to the largest shrinkage and then by path.
+func Calculate(ctx context.Context, oldPath string, newPath string, under config.UnderConfig) ([]Growth, error) {
+	groups := make(map[string]*Growth, 64)
+	groupFor := func(p string) *Growth {
+		key, isDir := topLevel(p, under.Under)
+		g, exists := groups[key]
+		if !exists {
+			g = &Growth{Path: key, IsDir: isDir}
+			groups[key] = g
+		}
+		return g
+	}
+
+	// The group of each file in the older database that has not been found in the newer database yet
+	oldFiles := make(map[path.Id]*Growth, 1024)
+
+	err := readFiles(ctx, oldPath, under, func(pi path.Info) {
+		g := groupFor(pi.Path)
+		g.OldSize += pi.Size
+		g.OldFiles++
+		oldFiles[path.IdFromPath(pi.Path)] = g
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readFiles(ctx, newPath, under, func(pi path.Info) {
+		g := groupFor(pi.Path)
+		g.NewSize += pi.Size
+		g.NewFiles++
+
+		id := path.IdFromPath(pi.Path)
+		if _, exists := oldFiles[id]; exists {
+			delete(oldFiles, id)
+		} else {
+			g.Added++
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, g := range oldFiles {
+		g.Removed++
+	}
+
+	result := make([]Growth, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, *g)
+	}
+	slices.SortFunc(result, func(a, b Growth) int {
+		if c := cmp.Compare(b.Delta(), a.Delta()); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+	return result, nil
+}
+
+// Call fn for each file in the database that is at or below under.
+func readFiles(ctx context.Context, dbPath string, under config.UnderConfig, fn func(pi path.Info)) error {
+	dbf, err := db.OpenDatabase(dbPath)
+	if err != nil {
+		return err
+	}
+	defer dbf.Close()
+
+	err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		if pi.IsFile() && under.IsUnder(pi.Path) {
+			fn(pi)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read the entries from %q. %w", dbPath, err)
+	}
+	return dbf.Close()
+}
+
+// Return the directory directly below under (or the root) that contains the path.
+// isDir is false when the path is not inside such a directory, in which case the path itself is returned.
+func topLevel(p string, under string) (key string, isDir bool) {
+	prefix := ""
+	if under != "" {
+		under = filepath.Clean(under)
+		if under != "." {
+			prefix = under + string(filepath.Separator)
+		}
+	}
+
+	rest, found := strings.CutPrefix(p, prefix)
+	if !found {
+		return p, false
+	}
+	before, _, found := strings.Cut(rest, string(filepath.Separator))
+	if !found {
+		return p, false
+	}
+	return prefix + before, true
+}
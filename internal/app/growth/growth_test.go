@@ -0,0 +1,134 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package growth_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/growth"
+	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculate(t *testing.T) {
+	oldPath, newPath := createSnapshots(t)
+
+	groups, err := growth.Calculate(context.Background(), oldPath, newPath, config.UnderConfig{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []growth.Growth{
+		{Path: "a", IsDir: true, OldSize: 150, NewSize: 320, OldFiles: 2, NewFiles: 2, Added: 1, Removed: 1},
+		{Path: "c", IsDir: true, OldSize: 0, NewSize: 7, OldFiles: 0, NewFiles: 1, Added: 1},
+		{Path: "r.txt", OldSize: 5, NewSize: 5, OldFiles: 1, NewFiles: 1},
+		{Path: "b", IsDir: true, OldSize: 10, NewSize: 0, OldFiles: 1, NewFiles: 0, Removed: 1},
+	}, groups)
+
+	// Directly below a subpath
+	groups, err = growth.Calculate(context.Background(), oldPath, newPath, config.UnderConfig{Under: "a"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []growth.Growth{
+		{Path: "a/sub", IsDir: true, OldSize: 100, NewSize: 300, OldFiles: 1, NewFiles: 1},
+		{Path: "a/w.txt", OldSize: 0, NewSize: 20, OldFiles: 0, NewFiles: 1, Added: 1},
+		{Path: "a/y.txt", OldSize: 50, NewSize: 0, OldFiles: 1, NewFiles: 0, Removed: 1},
+	}, groups)
+}
+
+func TestRun(t *testing.T) {
+	oldPath, newPath := createSnapshots(t)
+
+	var outBuffer bytes.Buffer
+	cfg := growth.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+		},
+		OldPath: oldPath,
+		NewPath: newPath,
+	}
+	require.NoError(t, growth.Run(context.Background(), cfg))
+
+	expected := `        Growth        Old size        New size       New   Removed  Path
+          +170             150             320         1         1  a/
+            +7               0               7         1         0  c/
+           -10              10               0         0         1  b/
+
+          +167             165             332         2         2  Total
+`
+	assert.Equal(t, expected, outBuffer.String())
+
+	outBuffer.Reset()
+	cfg.HumanSizes = true
+	require.NoError(t, growth.Run(context.Background(), cfg))
+	lines := strings.Split(outBuffer.String(), "\n")
+	assert.Equal(t, "    +170 B       150 B       320 B         1         1  a/", lines[1])
+	assert.Equal(t, "     -10 B        10 B         0 B         0         1  b/", lines[3])
+
+	cfg.OldPath = filepath.Join(t.TempDir(), "missing.ajfs")
+	assert.Error(t, growth.Run(context.Background(), cfg))
+}
+
+// Create an older and newer database in which a grew, b was removed and c was added.
+func createSnapshots(t *testing.T) (string, string) {
+	t.Helper()
+
+	snapshot := func(name string, files map[string]int) string {
+		root := filepath.Join(t.TempDir(), name)
+		for p, size := range files {
+			full := filepath.Join(root, p)
+			require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+			require.NoError(t, os.WriteFile(full, bytes.Repeat([]byte{'x'}, size), 0644))
+		}
+
+		dbPath := filepath.Join(t.TempDir(), name+".ajfs")
+		scanCfg := scan.Config{
+			CommonConfig: config.CommonConfig{
+				Stdout: io.Discard,
+				Stderr: io.Discard,
+				DbPath: dbPath,
+			},
+			Root: root,
+		}
+		require.NoError(t, scan.Run(context.Background(), scanCfg))
+		return dbPath
+	}
+
+	oldPath := snapshot("old", map[string]int{
+		"a/sub/x.txt": 100,
+		"a/y.txt":     50,
+		"b/z.txt":     10,
+		"r.txt":       5,
+	})
+	newPath := snapshot("new", map[string]int{
+		"a/sub/x.txt": 300,
+		"a/w.txt":     20,
+		"c/n.txt":     7,
+		"r.txt":       5,
+	})
+	return oldPath, newPath
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package hashdeep provides the functionality for ajfs compare-hashdeep command.
+package hashdeep
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+)
+
+// ErrAuditFailed is returned when the database does not match the hashdeep manifest.
+var ErrAuditFailed = errors.New("audit failed")
+
+// Config for the ajfs compare-hashdeep command.
+type Config struct {
+	config.CommonConfig
+
+	ManifestPath string
+}
+
+// AuditResult contains the outcome of comparing the database against a hashdeep manifest.
+type AuditResult struct {
+	Matched []string     // Same path with the same size and hash.
+	Changed []string     // Same path but the size or hash differs.
+	Moved   []MovedEntry // The hash is known in the manifest but under a different path.
+	New     []string     // Neither the path nor the hash is known in the manifest.
+	Missing []string     // Manifest entries for which neither the path nor the hash was found.
+	Skipped []string     // Files in the database without a calculated hash.
+}
+
+// MovedEntry is a file in the database that is known in the manifest under a different path.
+type MovedEntry struct {
+	Path string // Path in the database.
+	From string // Path in the manifest.
+}
+
+// Passed returns true if every file matched the manifest.
+func (r *AuditResult) Passed() bool {
+	return len(r.Changed) == 0 && len(r.Moved) == 0 && len(r.New) == 0 && len(r.Missing) == 0
+}
+
+// Process the ajfs compare-hashdeep command.
+func Run(ctx context.Context, cfg Config) error {
+	dbf, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+	defer dbf.Close()
+	cfg.WarnIfLimited(dbf)
+
+	result, err := Audit(ctx, dbf, cfg.ManifestPath)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Verbose {
+		for _, p := range result.Matched {
+			cfg.Println(fmt.Sprintf("Matched: %s", p))
+		}
+	}
+	for _, p := range result.Changed {
+		cfg.Println(fmt.Sprintf("Changed: %s", p))
+	}
+	for _, m := range result.Moved {
+		cfg.Println(fmt.Sprintf("Moved:   %s (from %s)", m.Path, m.From))
+	}
+	for _, p := range result.New {
+		cfg.Println(fmt.Sprintf("New:     %s", p))
+	}
+	for _, p := range result.Missing {
+		cfg.Println(fmt.Sprintf("Missing: %s", p))
+	}
+	for _, p := range result.Skipped {
+		cfg.Errorln(fmt.Sprintf("WARNING: no file signature hash for %q", p))
+	}
+
+	if result.Passed() {
+		cfg.Println("ajfs: Audit passed")
+	} else {
+		cfg.Println("ajfs: Audit failed")
+	}
+	cfg.Println(fmt.Sprintf("          Files matched: %d", len(result.Matched)))
+	cfg.Println(fmt.Sprintf("          Files changed: %d", len(result.Changed)))
+	cfg.Println(fmt.Sprintf("            Files moved: %d", len(result.Moved)))
+	cfg.Println(fmt.Sprintf("        New files found: %d", len(result.New)))
+	cfg.Println(fmt.Sprintf("  Known files not found: %d", len(result.Missing)))
+
+	if !result.Passed() {
+		return ErrAuditFailed
+	}
+	return nil
+}
+
+// Audit compares the file entries in the database against the hashdeep manifest.
+func Audit(ctx context.Context, dbf *db.DatabaseFile, manifestPath string) (AuditResult, error) {
+	result := AuditResult{}
+
+	if !dbf.Features().HasHashTable() {
+		return result, fmt.Errorf("require file signature hashes to be present in the database %q", dbf.Path())
+	}
+
+	algo, err := dbf.HashTableAlgo()
+	if err != nil {
+		return result, err
+	}
+
+	manifest, err := ReadManifest(manifestPath, algo)
+	if err != nil {
+		return result, err
+	}
+
+	knownPaths := make(map[string]ManifestEntry, len(manifest))
+	knownHashes := make(map[string]string, len(manifest))
+	for _, e := range manifest {
+		p := manifestPathRelativeTo(e.Path, dbf.RootPath())
+		e.Path = p
+		knownPaths[p] = e
+		if _, exists := knownHashes[e.Hash]; !exists {
+			knownHashes[e.Hash] = p
+		}
+	}
+
+	hashTable, err := dbf.ReadHashTable(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	seenPaths := make(map[string]struct{}, len(manifest))
+	seenHashes := make(map[string]struct{}, len(manifest))
+
+	err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		if !pi.IsFile() {
+			return nil
+		}
+
+		hash, ok := hashTable[idx]
+		if !ok {
+			result.Skipped = append(result.Skipped, pi.Path)
+			return nil
+		}
+		hashStr := hex.EncodeToString(hash)
+		seenHashes[hashStr] = struct{}{}
+
+		if known, exists := knownPaths[pi.Path]; exists {
+			seenPaths[pi.Path] = struct{}{}
+			if (known.Hash == hashStr) && (known.Size == pi.Size) {
+				result.Matched = append(result.Matched, pi.Path)
+			} else {
+				result.Changed = append(result.Changed, pi.Path)
+			}
+			return nil
+		}
+
+		if from, exists := knownHashes[hashStr]; exists {
+			result.Moved = append(result.Moved, MovedEntry{Path: pi.Path, From: from})
+			return nil
+		}
+
+		result.New = append(result.New, pi.Path)
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	for p, e := range knownPaths {
+		if _, exists := seenPaths[p]; exists {
+			continue
+		}
+		if _, exists := seenHashes[e.Hash]; exists {
+			continue
+		}
+		result.Missing = append(result.Missing, p)
+	}
+	sort.Strings(result.Missing)
+
+	return result, nil
+}
+
+// Paths in the manifest are made relative to the root path of the database.
+// Relative paths are assumed to already be relative to the root.
+func manifestPathRelativeTo(p string, root string) string {
+	if filepath.IsAbs(p) {
+		rel, err := filepath.Rel(root, p)
+		if err == nil && !path.IsUnder(rel, "..") {
+			return rel
+		}
+	}
+	return filepath.Clean(p)
+}
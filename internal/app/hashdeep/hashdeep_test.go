@@ -0,0 +1,164 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package hashdeep_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/hashdeep"
+	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditPassed(t *testing.T) {
+	dbPath := createTestDatabase(t)
+
+	var outBuffer bytes.Buffer
+	cfg := hashdeep.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: dbPath,
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+		},
+		ManifestPath: "../../testdata/expected/scan.sha1",
+	}
+
+	require.NoError(t, hashdeep.Run(context.Background(), cfg))
+	assert.True(t, strings.HasPrefix(outBuffer.String(), "ajfs: Audit passed\n"))
+	assert.Contains(t, outBuffer.String(), "        New files found: 0\n")
+}
+
+func TestAuditLegacyMD5(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "unit-testing")
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: dbPath,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		Root:            "../../testdata/scan",
+		CalculateHashes: true,
+		Algo:            db.AlgoMD5,
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	cfg := hashdeep.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: dbPath,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		ManifestPath: "../../testdata/expected/scan.md5",
+	}
+	require.NoError(t, hashdeep.Run(context.Background(), cfg))
+}
+
+func TestAuditFailed(t *testing.T) {
+	dbPath := createTestDatabase(t)
+
+	data, err := os.ReadFile("../../testdata/expected/scan.sha1")
+	require.NoError(t, err)
+	manifest := string(data)
+
+	// Moved
+	manifest = strings.Replace(manifest, ",./a/3.txt\n", ",./old/3.txt\n", 1)
+	// Changed
+	manifest = strings.Replace(manifest, "793,c7389462ca5ccb62c4ffe7a8d62d1da92c10cd27,./a/a2/6.txt",
+		"793,0000000000000000000000000000000000000000,./a/a2/6.txt", 1)
+	// Missing
+	manifest += "42,1111111111111111111111111111111111111111,./gone.txt\n"
+	// New
+	manifest = strings.Replace(manifest, "616,0c76100cba4c7495a3d63c79f07be88937d1b911,./c/c.txt\n", "", 1)
+
+	manifestPath := filepath.Join(t.TempDir(), "manifest.sha1")
+	require.NoError(t, os.WriteFile(manifestPath, []byte(manifest), 0666))
+
+	var outBuffer bytes.Buffer
+	cfg := hashdeep.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: dbPath,
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+		},
+		ManifestPath: manifestPath,
+	}
+
+	err = hashdeep.Run(context.Background(), cfg)
+	assert.ErrorIs(t, err, hashdeep.ErrAuditFailed)
+
+	out := outBuffer.String()
+	assert.Contains(t, out, "Changed: a/a2/6.txt\n")
+	assert.Contains(t, out, "Moved:   a/3.txt (from old/3.txt)\n")
+	assert.Contains(t, out, "New:     c/c.txt\n")
+	assert.Contains(t, out, "Missing: gone.txt\n")
+	assert.Contains(t, out, "ajfs: Audit failed\n")
+	assert.Contains(t, out, "          Files changed: 1\n")
+	assert.Contains(t, out, "            Files moved: 1\n")
+	assert.Contains(t, out, "        New files found: 1\n")
+	assert.Contains(t, out, "  Known files not found: 1\n")
+}
+
+func TestReadManifest(t *testing.T) {
+	manifestPath := filepath.Join(t.TempDir(), "manifest")
+	content := `%%%% HASHDEEP-1.0
+%%%% size,md5,sha256,filename
+## Invoked from: /test
+##
+10,aa,BB,./some,file.txt
+`
+	require.NoError(t, os.WriteFile(manifestPath, []byte(content), 0666))
+
+	entries, err := hashdeep.ReadManifest(manifestPath, ajhash.AlgoSHA256)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, hashdeep.ManifestEntry{Size: 10, Hash: "bb", Path: "some,file.txt"}, entries[0])
+
+	_, err = hashdeep.ReadManifest(manifestPath, ajhash.AlgoSHA1)
+	assert.Error(t, err)
+}
+
+func createTestDatabase(t *testing.T) string {
+	dbPath := filepath.Join(t.TempDir(), "unit-testing")
+
+	cfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: dbPath,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		Root:            "../../testdata/scan",
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}
+	require.NoError(t, scan.Run(context.Background(), cfg))
+
+	return dbPath
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package hashdeep
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/go-aj/ajhash"
+)
+
+// ManifestEntry is a single file entry in a hashdeep manifest.
+type ManifestEntry struct {
+	Size uint64
+	Hash string // Hex encoded and lowercase.
+	Path string
+}
+
+// ReadManifest parses the hashdeep file and returns the entries using the hashes for the algorithm.
+// The manifest may contain multiple hash columns, but it must contain one for the algorithm.
+func ReadManifest(manifestPath string, algo ajhash.Algo) ([]ManifestEntry, error) {
+	f, err := os.Open(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the hashdeep file %q. %w", manifestPath, err)
+	}
+	defer f.Close()
+
+	algoName, err := hashdeepAlgoName(algo)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ManifestEntry, 0, 64)
+	var columns []string
+	sizeCol, hashCol := -1, -1
+
+	scanner := bufio.NewScanner(f)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "##") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "%%%%") {
+			header := strings.TrimSpace(strings.TrimPrefix(line, "%%%%"))
+			if strings.HasPrefix(header, "HASHDEEP") {
+				continue
+			}
+
+			columns = strings.Split(header, ",")
+			sizeCol, hashCol = -1, -1
+			for i, c := range columns {
+				switch strings.TrimSpace(c) {
+				case "size":
+					sizeCol = i
+				case algoName:
+					hashCol = i
+				}
+			}
+
+			if hashCol < 0 {
+				return nil, fmt.Errorf("the hashdeep file %q does not contain %s hashes", manifestPath, algoName)
+			}
+			if sizeCol < 0 {
+				return nil, fmt.Errorf("the hashdeep file %q does not contain the file sizes", manifestPath)
+			}
+			continue
+		}
+
+		if columns == nil {
+			return nil, fmt.Errorf("missing the column header before line %d in the hashdeep file %q", lineNumber, manifestPath)
+		}
+
+		// The filename is the last column and may itself contain commas
+		parts := strings.SplitN(line, ",", len(columns))
+		if len(parts) != len(columns) {
+			return nil, fmt.Errorf("failed to parse line %d in the hashdeep file %q", lineNumber, manifestPath)
+		}
+
+		size, err := strconv.ParseUint(parts[sizeCol], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the size on line %d in the hashdeep file %q. %w", lineNumber, manifestPath, err)
+		}
+
+		result = append(result, ManifestEntry{
+			Size: size,
+			Hash: strings.ToLower(parts[hashCol]),
+			Path: strings.TrimPrefix(parts[len(parts)-1], "./"),
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the hashdeep file %q. %w", manifestPath, err)
+	}
+
+	return result, nil
+}
+
+// Name of the hash column used by hashdeep.
+func hashdeepAlgoName(algo ajhash.Algo) (string, error) {
+	switch algo {
+	case ajhash.AlgoSHA1:
+		return "sha1", nil
+	case ajhash.AlgoSHA256:
+		return "sha256", nil
+	case ajhash.AlgoSHA512:
+		return "sha512", nil
+	case db.AlgoMD5:
+		return "md5", nil
+	}
+	return "", fmt.Errorf("hashdeep does not support %q", db.AlgoString(algo))
+}
@@ -22,6 +22,7 @@
 package info
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -33,10 +34,11 @@ import (
 // Config for the ajfs info command.
 type Config struct {
 	config.CommonConfig
+	Layout bool // Only display the layout of the database file.
 }
 
 // Process the ajfs info command.
-func Run(cfg Config) error {
+func Run(ctx context.Context, cfg Config) error {
 
 	fileInfo, err := os.Stat(cfg.DbPath)
 	if err != nil {
@@ -48,6 +50,11 @@ func Run(cfg Config) error {
 		return err
 	}
 	defer dbf.Close()
+	cfg.WarnIfLimited(dbf)
+
+	if cfg.Layout {
+		return printLayout(cfg, dbf)
+	}
 
 	cfg.Println(fmt.Sprintf("Database path: %s", dbf.Path()))
 	cfg.Println(fmt.Sprintf("Version:       %d", dbf.Version()))
@@ -59,18 +66,77 @@ func Run(cfg Config) error {
 	cfg.Println(fmt.Sprintf("Entries:       %d", dbf.EntriesCount()))
 	cfg.Println(fmt.Sprintf("File size:     %s", human.Bytes(uint64(fileInfo.Size())))) //nolint:gosec // disable G115
 	cfg.Println(fmt.Sprintf("Features:      0x%x", dbf.Features()))
+	if unsupported := dbf.Features().Unsupported(); unsupported != 0 {
+		cfg.Println(fmt.Sprintf("  Unsupported: 0x%x", unsupported))
+	}
+
+	// The hash table can't be read from a database that was created by a newer version of ajfs
+	readHashTable := dbf.Features().HasHashTable()
 
 	if dbf.Features().HasHashTable() {
 		cfg.Println("  Hash table:  yes")
 		algo, err := dbf.HashTableAlgo()
 		if err != nil {
-			return err
+			if !dbf.Limited() {
+				return err
+			}
+			cfg.Println("    Algo:      not supported")
+			readHashTable = false
+		} else {
+			cfg.Println("    Algo:      " + db.AlgoString(algo))
+		}
+		if dbf.HashTableSorted() {
+			cfg.Println("    Order:     sorted by hash")
+		}
+		if dbf.Features().HasHashTimes() {
+			cfg.Println("    Times:     recorded")
+		}
+		if readHashTable && dbf.Features().HasExtraHashes() {
+			algos, err := dbf.HashTableAlgos(ctx)
+			if err != nil {
+				return err
+			}
+			for _, algo := range algos[1:] {
+				cfg.Println("    Extra:     " + db.AlgoString(algo))
+			}
 		}
-		cfg.Println("    Algo:      " + algo.String())
 	} else {
 		cfg.Println("  Hash table:  no")
 	}
 
+	if dbf.Features().HasDirStats() {
+		cfg.Println("  Dir stats:   yes")
+	} else {
+		cfg.Println("  Dir stats:   no")
+	}
+
+	if dbf.Features().HasSignature() {
+		cfg.Println("  Signature:   yes")
+	} else {
+		cfg.Println("  Signature:   no")
+	}
+
+	if dbf.Features().HasAnnotations() {
+		cfg.Println("  Annotations: yes")
+	} else {
+		cfg.Println("  Annotations: no")
+	}
+
+	if dbf.Features().HasIdNamespace() {
+		cfg.Println("  Namespace:   " + dbf.IdNamespace())
+	} else {
+		cfg.Println("  Namespace:   no")
+	}
+
+	cfg.Println(fmt.Sprintf("Checksum:      %s", dbf.ChecksumAlgo()))
+
+	if dbf.Sealed() {
+		cfg.Println("Sealed:        yes")
+	} else {
+		cfg.Println("Sealed:        no")
+	}
+	cfg.Println("Path display:  " + dbf.PathDisplay().String())
+
 	cfg.Println("\nVerifying checksum...")
 	if err = dbf.VerifyChecksums(); err != nil {
 		cfg.Errorln("Invalid checksum!")
@@ -81,7 +147,7 @@ func Run(cfg Config) error {
 
 	cfg.Println("\nCalculating statistics...")
 
-	stats, err := dbf.CalculateStats()
+	stats, err := dbf.CalculateStats(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to calculate statistics. %w", err)
 	}
@@ -92,11 +158,15 @@ func Run(cfg Config) error {
 	cfg.Println(fmt.Sprintf("Max file size: %s [single biggest file]", human.Bytes(stats.MaxFileSize)))
 	cfg.Println(fmt.Sprintf("Avg file size: %s", human.Bytes(stats.AvgFileSize)))
 
+	if err = printStorage(cfg, dbf, uint64(fileInfo.Size()), stats.TotalPathSize); err != nil { //nolint:gosec // disable G115
+		return err
+	}
+
 	// Hash table
-	if dbf.Features().HasHashTable() {
+	if readHashTable {
 		cfg.Println("\nCalculating Hash table statistics...")
 
-		stats, err := dbf.CalculateHashTableStats()
+		stats, err := dbf.CalculateHashTableStats(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to calculate hash table statistics. %w", err)
 		}
@@ -111,3 +181,55 @@ func Run(cfg Config) error {
 
 	return nil
 }
+
+// Print the format version, the lookup table revision and where each section is stored.
+func printLayout(cfg Config, dbf *db.DatabaseFile) error {
+	layout, err := dbf.Layout()
+	if err != nil {
+		return err
+	}
+
+	cfg.Println(fmt.Sprintf("Database path: %s", dbf.Path()))
+	cfg.Println(fmt.Sprintf("Version:       %d", layout.Version))
+	cfg.Println(fmt.Sprintf("Lookup table:  %s", layout.LookupTableRevision))
+
+	cfg.Println("\nSections:")
+	for _, section := range layout.Sections {
+		cfg.Println(fmt.Sprintf("  %-14s offset %10d size %10d", section.Name+":", section.Offset, section.Size))
+	}
+	return nil
+}
+
+// Print how much space each section of the database takes up and how that compares to the raw path strings.
+func printStorage(cfg Config, dbf *db.DatabaseFile, fileSize uint64, pathSize uint64) error {
+	sections, err := dbf.Sections()
+	if err != nil {
+		return err
+	}
+
+	cfg.Println("\nSections:")
+	var entriesSize uint64
+	for _, section := range sections {
+		cfg.Println(fmt.Sprintf("  %-14s %8s %5.1f%% [%d bytes]", section.Name+":",
+			human.Bytes(section.Size), percentage(section.Size, fileSize), section.Size))
+		if section.Name == "entries" {
+			entriesSize = section.Size
+		}
+	}
+
+	if dbf.EntriesCount() > 0 {
+		cfg.Println(fmt.Sprintf("Avg entry size: %s", human.Bytes(entriesSize/uint64(dbf.EntriesCount())))) //nolint:gosec // disable G115
+	}
+	cfg.Println(fmt.Sprintf("Path size:      %s [all path strings together]", human.Bytes(pathSize)))
+	if pathSize > 0 && fileSize > pathSize {
+		cfg.Println(fmt.Sprintf("Overhead:       %.1f%% [file size compared to the path strings]", percentage(fileSize-pathSize, pathSize)))
+	}
+	return nil
+}
+
+func percentage(value uint64, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(value) * 100 / float64(total)
+}
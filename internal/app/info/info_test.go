@@ -22,6 +22,7 @@ package info_test
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/fs"
@@ -54,7 +55,7 @@ func TestInfo(t *testing.T) {
 		Root: "../../testdata/scan",
 	}
 
-	err := scan.Run(scanCfg)
+	err := scan.Run(context.Background(), scanCfg)
 	require.NoError(t, err)
 
 	var outBuffer bytes.Buffer
@@ -68,7 +69,7 @@ func TestInfo(t *testing.T) {
 		},
 	}
 
-	err = info.Run(cfg)
+	err = info.Run(context.Background(), cfg)
 	assert.NoError(t, err)
 
 	fileInfo, err := os.Stat(cfg.DbPath)
@@ -87,7 +88,7 @@ Tool:          %s
 OS:            %s
 Architecture:  %s`,
 		tempFile,
-		1,
+		2,
 		absRoot,
 		"ajfs: v0.0.0 ",
 		runtime.GOOS,
@@ -115,6 +116,12 @@ Avg file size: %s`,
 	assert.Contains(t, outStr, expOut1)
 	assert.Contains(t, outStr, expOut2)
 	assert.Contains(t, outStr, expOut3)
+	assert.Contains(t, outStr, "\n  Annotations: no\n  Namespace:   no\n")
+	assert.Contains(t, outStr, "\nSections:\n  header: ")
+	assert.Contains(t, outStr, "\n  entries: ")
+	assert.Contains(t, outStr, "\n  lookup table: ")
+	assert.Contains(t, outStr, "\nAvg entry size: ")
+	assert.Contains(t, outStr, "\nOverhead: ")
 
 	assert.Equal(t, "", errBuffer.String())
 }
@@ -161,3 +168,34 @@ func expected(scanDir string) (expectedResults, error) {
 	result.avgFileSize = result.totalSize / uint64(result.fileCount)
 	return result, err
 }
+
+func TestInfoLayout(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Root: "../../testdata/scan",
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	var outBuffer bytes.Buffer
+	cfg := info.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Layout: true,
+	}
+	require.NoError(t, info.Run(context.Background(), cfg))
+
+	outStr := outBuffer.String()
+	assert.Contains(t, outStr, "\nLookup table:  revision 1 (identifier and offset of each entry)\n")
+	assert.Contains(t, outStr, "\nSections:\n  header:        offset          0 size ")
+	assert.Contains(t, outStr, "\n  lookup table:  offset ")
+	assert.NotContains(t, outStr, "File count:")
+}
@@ -22,81 +22,295 @@
 package list
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/collate"
 	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/errlog"
 	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/ajfs/internal/style"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/andrejacobs/go-aj/human"
 )
 
 // Config for the ajfs list command.
 type Config struct {
 	config.CommonConfig
+	config.UnderConfig
 
 	DisplayFullPaths bool // If true then each path entry will be prefixed with the root path of the database.
 	DisplayHashes    bool // Display file signature hashes if available.
 	DisplayMinimal   bool // Display only the paths.
+	DisplayLong      bool // Display the entries in the same columns as "ls -l".
+
+	DisplayHashStatus bool // Display whether each entry has been hashed, is pending, failed or skipped.
+
+	Collator *collate.Collator // [optional] Sort the entries in the order of a language instead of the stored order.
+
+	Offset int  // Number of entries to skip before displaying.
+	Limit  int  // Maximum number of entries to display. 0 means no limit.
+	Tail   bool // Count Offset and Limit from the last entry instead of the first.
 }
 
 // Process the ajfs list command.
-func Run(cfg Config) error {
+func Run(ctx context.Context, cfg Config) error {
 	dbf, err := db.OpenDatabase(cfg.DbPath)
 	if err != nil {
 		return err
 	}
 	defer dbf.Close()
+	cfg.WarnIfLimited(dbf)
+
+	// Prefix each line with the hash status of the entry
+	prefix := func(idx int) string { return "" }
+	if cfg.DisplayHashStatus {
+		statuses, err := readHashStatuses(ctx, cfg.DbPath, dbf)
+		if err != nil {
+			return err
+		}
+
+		format := "%s, "
+		if cfg.DisplayMinimal || cfg.DisplayLong || cfg.Styled {
+			format = "%-7s  "
+		}
+		prefix = func(idx int) string {
+			return fmt.Sprintf(format, statuses[idx])
+		}
+	}
+
+	if cfg.DisplayLong {
+		return printLong(ctx, cfg, dbf, cfg.DisplayHashes && dbf.Features().HasHashTable(), prefix)
+	}
 
 	if cfg.DisplayMinimal {
-		if err = displayOnlyMinimal(cfg, dbf); err != nil {
+		return readEntries(ctx, cfg, dbf, false, func(idx int, pi path.Info, hash []byte) {
+			cfg.Println(prefix(idx) + path.Escape(pi.Path))
+		})
+	}
+
+	withHashes := cfg.DisplayHashes && dbf.Features().HasHashTable()
+
+	if cfg.Styled {
+		return printStyled(ctx, cfg, dbf, withHashes, prefix)
+	}
+
+	if cfg.Verbose {
+		header := path.Header()
+		if withHashes {
+			header = path.HeaderWithHash()
+		}
+		if cfg.DisplayHashStatus {
+			header = "Hash status, " + header
+		}
+		cfg.Println(header)
+	}
+
+	if withHashes {
+		return readEntries(ctx, cfg, dbf, true, func(idx int, pi path.Info, hash []byte) {
+			hashStr := hex.EncodeToString(hash)
+			cfg.Println(prefix(idx) + pi.StringWithHash(hashStr, path.FieldOptions{DisplayTime: cfg.DisplayTime}))
+		})
+	}
+
+	return readEntries(ctx, cfg, dbf, false, func(idx int, pi path.Info, hash []byte) {
+		cfg.Println(prefix(idx) + pi.String())
+	})
+}
+
+// Display the entries in aligned columns with human readable sizes and the directories in color.
+func printStyled(ctx context.Context, cfg Config, dbf *db.DatabaseFile, withHashes bool, prefix func(idx int) string) error {
+	hashWidth := 0
+	if withHashes {
+		algo, err := dbf.HashTableAlgo()
+		if err != nil {
 			return err
 		}
-		return nil
+		hashWidth = hex.EncodedLen(db.AlgoSize(algo))
 	}
 
 	if cfg.Verbose {
-		if cfg.DisplayHashes && dbf.Features().HasHashTable() {
-			cfg.Println(path.HeaderWithHash())
-		} else {
-			cfg.Println(path.Header())
+		header := fmt.Sprintf("%-10s %8s  %-19s  ", "Mode", "Size", "Modified")
+		if withHashes {
+			header += fmt.Sprintf("%-*s  ", hashWidth, "Hash")
 		}
+		if cfg.DisplayHashStatus {
+			header = fmt.Sprintf("%-7s  ", "Status") + header
+		}
+		cfg.Println(style.Paint(style.Bold, header+"Path"))
 	}
 
-	if cfg.DisplayHashes && dbf.Features().HasHashTable() {
-		err = dbf.ReadAllEntriesWithHashes(func(idx int, pi path.Info, hash []byte) error {
-			if cfg.DisplayFullPaths {
-				pi.Path = filepath.Join(dbf.RootPath(), pi.Path)
+	return readEntries(ctx, cfg, dbf, withHashes, func(idx int, pi path.Info, hash []byte) {
+		line := fmt.Sprintf("%-10s %8s  %s  ", pi.Mode, human.Bytes(pi.Size), cfg.DisplayTime(pi.ModTime).Format(time.DateTime))
+		if withHashes {
+			hashStr := "-"
+			if hash != nil {
+				hashStr = hex.EncodeToString(hash)
 			}
+			line += style.Paint(style.Dim, fmt.Sprintf("%-*s", hashWidth, hashStr)) + "  "
+		}
 
-			hashStr := hex.EncodeToString(hash)
-			cfg.Println(fmt.Sprintf("{%x}, %s, %v, %q, %v, %v", pi.Id, hashStr, pi.Size, pi.Path, pi.Mode, pi.ModTime.Format(time.RFC3339Nano)))
+		name := path.Escape(pi.Path)
+		if pi.IsDir() {
+			name = style.Paint(style.Blue, name)
+		}
+		cfg.Println(prefix(idx) + line + name)
+	})
+}
+
+// Read the entries that are under the configured path and call fn for each of them that falls within the
+// configured offset and limit.
+// When a collator is configured then all the entries are first read and sorted.
+func readEntries(ctx context.Context, cfg Config, dbf *db.DatabaseFile, withHashes bool, fn func(idx int, pi path.Info, hash []byte)) error {
+	if cfg.Offset < 0 || cfg.Limit < 0 {
+		return fmt.Errorf("invalid offset %d or limit %d, expected positive values", cfg.Offset, cfg.Limit)
+	}
+
+	display := func(idx int, pi path.Info, hash []byte) {
+		if cfg.DisplayFullPaths {
+			pi.Path = filepath.Join(dbf.RootPath(), pi.Path)
+		}
+		fn(idx, pi, hash)
+	}
+
+	// Every entry is displayed in the stored order and thus the range can be read directly using the lookup table
+	if cfg.Under == "" && cfg.Collator == nil && !withHashes {
+		start, end := cfg.bounds(dbf.EntriesCount())
+		return dbf.ReadEntriesRange(ctx, start, end, func(idx int, pi path.Info) error {
+			display(idx, pi, nil)
 			return nil
 		})
-		return err
-	} else {
-		err = dbf.ReadAllEntries(func(idx int, pi path.Info) error {
-			if cfg.DisplayFullPaths {
-				pi.Path = filepath.Join(dbf.RootPath(), pi.Path)
-			}
+	}
 
-			cfg.Println(pi)
+	type entry struct {
+		idx  int
+		pi   path.Info
+		hash []byte
+	}
+	var buffered []entry
+	bufferAll := cfg.Collator != nil || cfg.Tail
+	count := 0
+
+	add := func(idx int, pi path.Info, hash []byte) error {
+		if !cfg.IsUnder(pi.Path) {
 			return nil
+		}
+
+		if bufferAll {
+			buffered = append(buffered, entry{idx: idx, pi: pi, hash: hash})
+			return nil
+		}
+
+		count++
+		if count <= cfg.Offset {
+			return nil
+		}
+		display(idx, pi, hash)
+		if cfg.Limit > 0 && count >= cfg.Offset+cfg.Limit {
+			return db.SkipAll
+		}
+		return nil
+	}
+
+	var err error
+	if withHashes {
+		err = dbf.ReadAllEntriesWithHashes(ctx, func(idx int, pi path.Info, hash []byte) error {
+			return add(idx, pi, hash)
 		})
+	} else {
+		err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+			return add(idx, pi, nil)
+		})
+	}
+	if err != nil {
 		return err
 	}
+
+	if bufferAll {
+		if cfg.Collator != nil {
+			slices.SortStableFunc(buffered, func(a, b entry) int {
+				return cfg.Collator.ComparePaths(a.pi.Path, b.pi.Path, filepath.Separator)
+			})
+		}
+
+		start, end := cfg.bounds(len(buffered))
+		for _, e := range buffered[start:end] {
+			display(e.idx, e.pi, e.hash)
+		}
+	}
+
+	return nil
 }
 
-func displayOnlyMinimal(cfg Config, dbf *db.DatabaseFile) error {
-	err := dbf.ReadAllEntries(func(idx int, pi path.Info) error {
-		if cfg.DisplayFullPaths {
-			pi.Path = filepath.Join(dbf.RootPath(), pi.Path)
+// Return the range [start, end) of the entries to be displayed out of total entries.
+func (cfg *Config) bounds(total int) (int, int) {
+	if cfg.Tail {
+		end := max(total-cfg.Offset, 0)
+		if cfg.Limit == 0 {
+			return 0, end
 		}
+		return max(end-cfg.Limit, 0), end
+	}
+
+	start := min(cfg.Offset, total)
+	if cfg.Limit == 0 {
+		return start, total
+	}
+	return start, min(start+cfg.Limit, total)
+}
+
+// Read the hash status of each entry, indexed by the entry's index.
+// Files without a hash are reported as failed when they are recorded in the error log of the database.
+func readHashStatuses(ctx context.Context, dbPath string, dbf *db.DatabaseFile) ([]string, error) {
+	if !dbf.Features().HasHashTable() {
+		return nil, fmt.Errorf("the database %q does not contain file signature hashes", dbPath)
+	}
+
+	errLog, err := errlog.Open(errlog.PathFor(dbPath))
+	if err != nil {
+		return nil, err
+	}
+	defer errLog.Close()
+
+	// Entries without a hash table entry (e.g. directories and symbolic links) are never hashed
+	statuses := make([]string, dbf.EntriesCount())
+	for i := range statuses {
+		statuses[i] = "skipped"
+	}
+
+	err = dbf.ReadHashTableEntries(ctx, func(idx int, hash []byte) error {
+		if idx < 0 || idx >= len(statuses) {
+			return fmt.Errorf("invalid path entry index %d in the hash table", idx)
+		}
+		if ajhash.AllZeroBytes(hash) {
+			statuses[idx] = "pending"
+		} else {
+			statuses[idx] = "hashed"
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if errLog.Count() == 0 {
+		return statuses, nil
+	}
 
-		cfg.Println(pi.Path)
+	err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		if statuses[idx] == "pending" && errLog.Contains(pi.Path) {
+			statuses[idx] = "failed"
+		}
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	return err
+	return statuses, nil
 }
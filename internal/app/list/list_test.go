@@ -23,6 +23,7 @@ package list_test
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/fs"
@@ -30,14 +31,19 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/andrejacobs/ajfs/internal/app/config"
 	"github.com/andrejacobs/ajfs/internal/app/list"
 	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/collate"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/errlog"
 	"github.com/andrejacobs/ajfs/internal/path"
 	"github.com/andrejacobs/ajfs/internal/scanner"
 	"github.com/andrejacobs/go-aj/ajhash"
 	"github.com/andrejacobs/go-aj/file"
+	"github.com/andrejacobs/go-aj/human"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -56,7 +62,7 @@ func TestList(t *testing.T) {
 		Root: "../../testdata/scan",
 	}
 
-	err := scan.Run(scanCfg)
+	err := scan.Run(context.Background(), scanCfg)
 	require.NoError(t, err)
 
 	var outBuffer bytes.Buffer
@@ -70,7 +76,7 @@ func TestList(t *testing.T) {
 		},
 	}
 
-	err = list.Run(cfg)
+	err = list.Run(context.Background(), cfg)
 	assert.NoError(t, err)
 
 	exp, err := expected(scanCfg.Root, cfg.DisplayFullPaths)
@@ -82,7 +88,7 @@ func TestList(t *testing.T) {
 	// Full paths
 	outBuffer.Reset()
 	cfg.DisplayFullPaths = true
-	err = list.Run(cfg)
+	err = list.Run(context.Background(), cfg)
 	assert.NoError(t, err)
 
 	exp, err = expected(scanCfg.Root, cfg.DisplayFullPaths)
@@ -95,7 +101,7 @@ func TestList(t *testing.T) {
 	outBuffer.Reset()
 	cfg.CommonConfig.Verbose = true
 
-	err = list.Run(cfg)
+	err = list.Run(context.Background(), cfg)
 	assert.NoError(t, err)
 	assert.Contains(t, outBuffer.String(), path.Header())
 }
@@ -116,7 +122,7 @@ func TestListWithHashes(t *testing.T) {
 		Algo:            ajhash.AlgoSHA1,
 	}
 
-	err := scan.Run(scanCfg)
+	err := scan.Run(context.Background(), scanCfg)
 	require.NoError(t, err)
 
 	var outBuffer bytes.Buffer
@@ -131,7 +137,7 @@ func TestListWithHashes(t *testing.T) {
 		DisplayHashes: true,
 	}
 
-	err = list.Run(cfg)
+	err = list.Run(context.Background(), cfg)
 	assert.NoError(t, err)
 
 	scanner := bufio.NewScanner(&outBuffer)
@@ -145,11 +151,291 @@ func TestListWithHashes(t *testing.T) {
 	outBuffer.Reset()
 	cfg.CommonConfig.Verbose = true
 
-	err = list.Run(cfg)
+	err = list.Run(context.Background(), cfg)
 	assert.NoError(t, err)
 	assert.Contains(t, outBuffer.String(), path.HeaderWithHash())
 }
 
+func TestListWithLocale(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"Zebra.txt", "ärta.txt", "apple.txt", "Öl.txt", "banana.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(name), 0644))
+	}
+
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Root: root,
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	var outBuffer bytes.Buffer
+
+	cfg := list.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		DisplayMinimal: true,
+	}
+
+	cfg.Collator, _ = collate.New("en_US")
+	require.NoError(t, list.Run(context.Background(), cfg))
+	assert.Equal(t, ".\napple.txt\närta.txt\nbanana.txt\nÖl.txt\nZebra.txt\n", outBuffer.String())
+
+	outBuffer.Reset()
+	cfg.Collator, _ = collate.New("sv_SE")
+	require.NoError(t, list.Run(context.Background(), cfg))
+	assert.Equal(t, ".\napple.txt\nbanana.txt\nZebra.txt\närta.txt\nÖl.txt\n", outBuffer.String())
+}
+
+func TestListRange(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Root: "../../testdata/scan",
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	exp, err := expected(scanCfg.Root, false)
+	require.NoError(t, err)
+	expLines := strings.SplitAfter(exp, "\n")
+	expLines = expLines[:len(expLines)-1]
+	require.Len(t, expLines, 26)
+
+	var outBuffer bytes.Buffer
+
+	cfg := list.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+	}
+
+	testCases := []struct {
+		desc   string
+		offset int
+		limit  int
+		tail   bool
+		exp    []string
+	}{
+		{desc: "head", limit: 3, exp: expLines[:3]},
+		{desc: "offset and limit", offset: 5, limit: 4, exp: expLines[5:9]},
+		{desc: "only offset", offset: 20, exp: expLines[20:]},
+		{desc: "offset past the end", offset: 30, limit: 4, exp: nil},
+		{desc: "tail", limit: 3, tail: true, exp: expLines[23:]},
+		{desc: "tail with offset", offset: 2, limit: 3, tail: true, exp: expLines[21:24]},
+		{desc: "tail larger than entries", limit: 50, tail: true, exp: expLines},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			cfg.Offset = tC.offset
+			cfg.Limit = tC.limit
+			cfg.Tail = tC.tail
+
+			// Read directly using the offset table
+			outBuffer.Reset()
+			cfg.Under = ""
+			require.NoError(t, list.Run(context.Background(), cfg))
+			assert.Equal(t, strings.Join(tC.exp, ""), outBuffer.String())
+
+			// Read while filtering (all entries are under ".")
+			outBuffer.Reset()
+			cfg.Under = "."
+			require.NoError(t, list.Run(context.Background(), cfg))
+			assert.Equal(t, strings.Join(tC.exp, ""), outBuffer.String())
+		})
+	}
+
+	cfg.Offset = -1
+	assert.Error(t, list.Run(context.Background(), cfg))
+}
+
+func TestListHashStatus(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "dir"), 0755))
+	for _, name := range []string{"hashed.txt", "pending.txt", "failed.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(root, "dir", name), []byte(name), 0644))
+	}
+
+	dbPath := filepath.Join(t.TempDir(), "unit-testing")
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: dbPath,
+		},
+		Root:            root,
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+		InitOnly:        true,
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	dbf, err := db.ResumeDatabase(dbPath)
+	require.NoError(t, err)
+	v, err := dbf.FindEntryIndexAndOffset(path.IdFromPath("dir/hashed.txt"))
+	require.NoError(t, err)
+	require.NoError(t, dbf.WriteHashEntry(int(v.Index), bytes.Repeat([]byte{0x42}, ajhash.AlgoSHA1.Size())))
+	require.NoError(t, dbf.Close())
+
+	errLog, err := errlog.Open(errlog.PathFor(dbPath))
+	require.NoError(t, err)
+	require.NoError(t, errLog.Add("dir/failed.txt", fmt.Errorf("permission denied")))
+	require.NoError(t, errLog.Close())
+
+	var outBuffer bytes.Buffer
+	cfg := list.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+			DbPath: dbPath,
+		},
+		DisplayMinimal:    true,
+		DisplayHashStatus: true,
+	}
+	require.NoError(t, list.Run(context.Background(), cfg))
+
+	lines := strings.Split(strings.TrimSpace(outBuffer.String()), "\n")
+	assert.ElementsMatch(t, []string{
+		"skipped  .",
+		"skipped  dir",
+		"failed   dir/failed.txt",
+		"hashed   dir/hashed.txt",
+		"pending  dir/pending.txt",
+	}, lines)
+
+	// Without a hash table
+	require.NoError(t, os.Remove(dbPath))
+	scanCfg.CalculateHashes = false
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+	assert.ErrorContains(t, list.Run(context.Background(), cfg), "does not contain file signature hashes")
+}
+
+func TestListStyled(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Root:            "../../testdata/scan",
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	run := func(withHashes bool) []string {
+		var outBuffer bytes.Buffer
+		cfg := list.Config{
+			CommonConfig: config.CommonConfig{
+				Stdout:  &outBuffer,
+				Stderr:  io.Discard,
+				DbPath:  tempFile,
+				Verbose: true,
+				Styled:  true,
+				UTC:     true,
+			},
+			DisplayHashes: withHashes,
+		}
+		require.NoError(t, list.Run(context.Background(), cfg))
+		return strings.Split(strings.TrimSpace(outBuffer.String()), "\n")
+	}
+
+	dbf, err := db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	// Without hashes all entries are displayed
+	lines := run(false)
+	require.Len(t, lines, 27)
+	assert.Equal(t, "\x1b[1mMode           Size  Modified             Path\x1b[0m", lines[0])
+
+	for idx, line := range lines[1:] {
+		pi, err := dbf.ReadEntryAtIndex(idx)
+		require.NoError(t, err)
+
+		name := pi.Path
+		if pi.IsDir() {
+			name = "\x1b[34m" + name + "\x1b[0m"
+		}
+
+		exp := fmt.Sprintf("%-10s %8s  %s  %s", pi.Mode, human.Bytes(pi.Size), pi.ModTime.UTC().Format(time.DateTime), name)
+		assert.Equal(t, exp, line)
+	}
+
+	// With hashes only the hashed files are displayed
+	lines = run(true)
+	require.Len(t, lines, 16)
+	assert.Equal(t, "\x1b[1mMode           Size  Modified             Hash                                      Path\x1b[0m", lines[0])
+	assert.Equal(t, "-rw-rw-r--    617 B  2026-06-02 05:30:38  \x1b[2m617df30582ef818ed9213b282e5cec0c714beacf\x1b[0m  a/2.txt", lines[2])
+}
+
+func TestListLong(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Root: "../../testdata/scan",
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	var outBuffer bytes.Buffer
+	cfg := list.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+			UTC:    true,
+		},
+		DisplayMinimal: true,
+		DisplayLong:    true,
+		Limit:          3,
+	}
+	require.NoError(t, list.Run(context.Background(), cfg))
+
+	lines := strings.Split(strings.TrimSpace(outBuffer.String()), "\n")
+	require.Len(t, lines, 3)
+
+	dbf, err := db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	for idx, line := range lines {
+		pi, err := dbf.ReadEntryAtIndex(idx)
+		require.NoError(t, err)
+
+		fields := strings.Fields(line)
+		require.Len(t, fields, 9, line)
+		assert.Equal(t, []string{"-", "-", "-"}, fields[1:4])
+		assert.Equal(t, fmt.Sprintf("%d", pi.Size), fields[4])
+		assert.Equal(t, pi.Path, fields[8])
+		if pi.IsDir() {
+			assert.Equal(t, byte('d'), fields[0][0])
+		} else {
+			assert.Equal(t, byte('-'), fields[0][0])
+		}
+	}
+}
+
 func expected(scanDir string, fullPaths bool) (string, error) {
 	w := file.NewWalker()
 	w.FileExcluder = scanner.DefaultFileExcluder()
@@ -0,0 +1,118 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package list
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/ajfs/internal/style"
+)
+
+// Placeholder displayed in the columns of "ls -l" for which the metadata is not stored in the database.
+const longUnknown = "-"
+
+// Display the entries in the same columns as "ls -l": permissions, links, owner, group, size, date and name.
+// The database does not store the number of links, owner, group or the target of a symbolic link and thus these
+// columns are displayed as "-" to keep the positions of the columns the same for tools that parse ls output.
+func printLong(ctx context.Context, cfg Config, dbf *db.DatabaseFile, withHashes bool, prefix func(idx int) string) error {
+	now := time.Now()
+
+	return readEntries(ctx, cfg, dbf, withHashes, func(idx int, pi path.Info, hash []byte) {
+		line := fmt.Sprintf("%s %s %s %s %10d %s ", longMode(pi.Mode), longUnknown, longUnknown, longUnknown,
+			pi.Size, longTime(cfg.DisplayTime(pi.ModTime), now))
+		if withHashes {
+			hashStr := longUnknown
+			if hash != nil {
+				hashStr = hex.EncodeToString(hash)
+			}
+			line += hashStr + " "
+		}
+
+		name := path.Escape(pi.Path)
+		if cfg.Styled && pi.IsDir() {
+			name = style.Paint(style.Blue, name)
+		}
+		cfg.Println(prefix(idx) + line + name)
+	})
+}
+
+// Return the permissions as displayed by "ls -l", e.g. "drwxr-xr-x".
+// This differs from [fs.FileMode.String] in the type characters used and the setuid, setgid and sticky bits that
+// replace the execute permission.
+func longMode(m fs.FileMode) string {
+	var b [10]byte
+
+	switch {
+	case m.IsDir():
+		b[0] = 'd'
+	case m&fs.ModeSymlink != 0:
+		b[0] = 'l'
+	case m&fs.ModeNamedPipe != 0:
+		b[0] = 'p'
+	case m&fs.ModeSocket != 0:
+		b[0] = 's'
+	case m&fs.ModeCharDevice != 0:
+		b[0] = 'c'
+	case m&fs.ModeDevice != 0:
+		b[0] = 'b'
+	default:
+		b[0] = '-'
+	}
+
+	const rwx = "rwxrwxrwx"
+	for i := range 9 {
+		if m&(1<<(8-i)) != 0 {
+			b[i+1] = rwx[i]
+		} else {
+			b[i+1] = '-'
+		}
+	}
+
+	special := func(pos int, set bool, c byte) {
+		if !set {
+			return
+		}
+		if b[pos] == '-' {
+			c -= 'a' - 'A' // Uppercase when the execute permission is not set
+		}
+		b[pos] = c
+	}
+	special(3, m&fs.ModeSetuid != 0, 's')
+	special(6, m&fs.ModeSetgid != 0, 's')
+	special(9, m&fs.ModeSticky != 0, 't')
+
+	return string(b[:])
+}
+
+// Return the modification time as displayed by "ls -l".
+// Times within the last six months (and not in the future) display the time of day, older times display the year.
+func longTime(t time.Time, now time.Time) string {
+	const sixMonths = 182 * 24 * time.Hour
+	if t.After(now.Add(-sixMonths)) && !t.After(now) {
+		return t.Format("Jan _2 15:04")
+	}
+	return t.Format("Jan _2  2006")
+}
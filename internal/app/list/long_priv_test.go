@@ -0,0 +1,53 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package list
+
+import (
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLongMode(t *testing.T) {
+	assert.Equal(t, "-rw-r--r--", longMode(0644))
+	assert.Equal(t, "drwxr-xr-x", longMode(fs.ModeDir|0755))
+	assert.Equal(t, "lrwxrwxrwx", longMode(fs.ModeSymlink|0777))
+	assert.Equal(t, "prw-------", longMode(fs.ModeNamedPipe|0600))
+	assert.Equal(t, "srwxr-xr-x", longMode(fs.ModeSocket|0755))
+	assert.Equal(t, "crw-rw-rw-", longMode(fs.ModeDevice|fs.ModeCharDevice|0666))
+	assert.Equal(t, "brw-rw----", longMode(fs.ModeDevice|0660))
+	assert.Equal(t, "-rwsr-xr-x", longMode(fs.ModeSetuid|0755))
+	assert.Equal(t, "-rwSr--r--", longMode(fs.ModeSetuid|0644))
+	assert.Equal(t, "-rwxr-sr-x", longMode(fs.ModeSetgid|0755))
+	assert.Equal(t, "drwxrwxrwt", longMode(fs.ModeDir|fs.ModeSticky|0777))
+	assert.Equal(t, "drwxrwxrwT", longMode(fs.ModeDir|fs.ModeSticky|0776))
+}
+
+func TestLongTime(t *testing.T) {
+	now := time.Date(2025, time.June, 15, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "Jun  1 09:05", longTime(time.Date(2025, time.June, 1, 9, 5, 0, 0, time.UTC), now))
+	assert.Equal(t, "Jan 10 23:59", longTime(time.Date(2025, time.January, 10, 23, 59, 0, 0, time.UTC), now))
+	assert.Equal(t, "Nov 20  2024", longTime(time.Date(2024, time.November, 20, 8, 0, 0, 0, time.UTC), now))
+	// Future times display the year
+	assert.Equal(t, "Jul  4  2025", longTime(time.Date(2025, time.July, 4, 8, 0, 0, 0, time.UTC), now))
+}
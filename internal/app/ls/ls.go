@@ -0,0 +1,158 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package ls provides the functionality for ajfs ls command.
+package ls
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/collate"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+)
+
+// Config for the ajfs ls command.
+type Config struct {
+	config.CommonConfig
+
+	// Path inside the database to be listed. If the path is a directory then the direct children will be listed.
+	// The path may also be a shell pattern (e.g. photos/2023/*) as supported by [filepath.Match].
+	Pattern string
+
+	DisplayFullPaths bool // If true then each path entry will be prefixed with the root path of the database.
+
+	Collator *collate.Collator // [optional] Sort the entries in the order of a language instead of by the raw bytes.
+}
+
+// Process the ajfs ls command.
+func Run(ctx context.Context, cfg Config) error {
+	dbf, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+	defer dbf.Close()
+	cfg.WarnIfLimited(dbf)
+
+	pattern := filepath.Clean(cfg.Pattern)
+	if pattern == "/" {
+		pattern = "."
+	}
+	pattern = strings.TrimPrefix(pattern, string(filepath.Separator))
+
+	glob := hasMeta(pattern)
+	if glob {
+		// Validate pattern
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q. %w", cfg.Pattern, err)
+		}
+	}
+
+	found := false
+	isDir := false
+	entries := make([]path.Info, 0, 64)
+
+	err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		if glob {
+			if pi.Path == "." {
+				return nil
+			}
+			matched, err := filepath.Match(pattern, pi.Path)
+			if err != nil {
+				return err
+			}
+			if matched {
+				entries = append(entries, pi)
+			}
+			return nil
+		}
+
+		if pi.Path == pattern {
+			found = true
+			isDir = pi.IsDir()
+			if !isDir {
+				entries = append(entries, pi)
+			}
+			return nil
+		}
+
+		if pi.Path != "." && filepath.Dir(pi.Path) == pattern {
+			entries = append(entries, pi)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !glob && !found {
+		return fmt.Errorf("failed to find the path %q in the database %q", cfg.Pattern, cfg.DbPath)
+	}
+
+	slices.SortFunc(entries, func(a, b path.Info) int {
+		if cfg.Collator != nil {
+			return cfg.Collator.ComparePaths(a.Path, b.Path, filepath.Separator)
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	for _, pi := range entries {
+		name := pi.Path
+		if cfg.DisplayFullPaths {
+			name = filepath.Join(dbf.RootPath(), pi.Path)
+		} else if !glob && isDir {
+			name = filepath.Base(pi.Path)
+		}
+
+		cfg.Println(Format(pi, name, cfg.UTC))
+	}
+
+	return nil
+}
+
+// Format the path entry in a similar way that ls -l does.
+// name is the text used to display the path.
+// utc displays the modification time in UTC instead of the local time zone.
+func Format(pi path.Info, name string, utc bool) string {
+	if pi.IsDir() {
+		name += string(filepath.Separator)
+	}
+
+	modTime := pi.ModTime.Local()
+	if utc {
+		modTime = pi.ModTime.UTC()
+	}
+	return fmt.Sprintf("%v %12d %s %s", pi.Mode, pi.Size, modTime.Format(timeFormat), name)
+}
+
+// Return true if the path contains any of the shell pattern meta characters.
+func hasMeta(path string) bool {
+	return strings.ContainsAny(path, `*?[`)
+}
+
+const (
+	timeFormat = time.DateTime
+)
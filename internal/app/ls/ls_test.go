@@ -0,0 +1,150 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package ls_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/ls"
+	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+	_ = os.Remove(tempFile)
+	defer os.Remove(tempFile)
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Root: "../../testdata/scan",
+	}
+
+	err := scan.Run(context.Background(), scanCfg)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		desc     string
+		pattern  string
+		expected []string
+	}{
+		{
+			desc:     "root",
+			pattern:  "",
+			expected: []string{"1.txt", "a/", "b/", "blank.txt", "c/"},
+		},
+		{
+			desc:     "dir",
+			pattern:  "a",
+			expected: []string{"2.txt", "3.txt", "a1/", "a2/"},
+		},
+		{
+			desc:     "file",
+			pattern:  "a/a2/6.txt",
+			expected: []string{"a/a2/6.txt"},
+		},
+		{
+			desc:     "glob",
+			pattern:  "a/*.txt",
+			expected: []string{"a/2.txt", "a/3.txt"},
+		},
+		{
+			desc:     "glob single level",
+			pattern:  "b/*",
+			expected: []string{"b/b1/"},
+		},
+		{
+			desc:     "no match",
+			pattern:  "x/*",
+			expected: []string{},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var outBuffer bytes.Buffer
+
+			cfg := ls.Config{
+				CommonConfig: config.CommonConfig{
+					Stdout: &outBuffer,
+					Stderr: io.Discard,
+					DbPath: tempFile,
+				},
+				Pattern: tC.pattern,
+			}
+
+			err := ls.Run(context.Background(), cfg)
+			require.NoError(t, err)
+
+			names := make([]string, 0, len(tC.expected))
+			scanner := bufio.NewScanner(&outBuffer)
+			for scanner.Scan() {
+				fields := strings.Fields(scanner.Text())
+				require.Len(t, fields, 5)
+				names = append(names, fields[4])
+			}
+
+			assert.Equal(t, tC.expected, names)
+		})
+	}
+}
+
+func TestRunNotFound(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+	_ = os.Remove(tempFile)
+	defer os.Remove(tempFile)
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Root: "../../testdata/scan",
+	}
+
+	err := scan.Run(context.Background(), scanCfg)
+	require.NoError(t, err)
+
+	cfg := ls.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Pattern: "does/not/exist",
+	}
+
+	err = ls.Run(context.Background(), cfg)
+	assert.ErrorContains(t, err, "failed to find the path")
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package matrix provides the functionality for ajfs matrix command.
+package matrix
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/diff"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/andrejacobs/go-aj/human"
+)
+
+// Config for the ajfs matrix command.
+type Config struct {
+	config.CommonConfig
+
+	Paths []string // Paths to the databases to be compared with each other.
+}
+
+// Process the ajfs matrix command.
+func Run(ctx context.Context, cfg Config) error {
+	if len(cfg.Paths) < 2 {
+		return fmt.Errorf("at least two databases are required")
+	}
+
+	m, err := Build(ctx, cfg.Paths)
+	if err != nil {
+		return err
+	}
+
+	m.Print(cfg.Stdout)
+	return nil
+}
+
+// Matrix is the summary of the pairwise differences between databases.
+type Matrix struct {
+	Paths   []string // Paths to the databases, in the order of the rows and columns
+	Digests []Digest // Root digest of each database
+	Cells   [][]Cell // Cells[i][j] is the difference between database i and j
+}
+
+// Cell is the summary of the differences between two databases.
+type Cell struct {
+	Differences int    // Number of paths that are only in one of the databases or that have changed
+	Size        uint64 // Total size of the files that differ. Changed files are counted with the size of the row's database
+	Identical   bool   // The root digests are the same
+}
+
+// Compare each database with every other database.
+func Build(ctx context.Context, paths []string) (*Matrix, error) {
+	m := &Matrix{
+		Paths:   paths,
+		Digests: make([]Digest, len(paths)),
+		Cells:   make([][]Cell, len(paths)),
+	}
+
+	for i, p := range paths {
+		d, err := CalculateDigest(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		m.Digests[i] = d
+		m.Cells[i] = make([]Cell, len(paths))
+	}
+
+	// The number of differences is the same in both directions and thus each pair is only compared once
+	for i := range paths {
+		m.Cells[i][i] = Cell{Identical: true}
+
+		for j := i + 1; j < len(paths); j++ {
+			var cell Cell
+			err := diff.Compare(ctx, paths[i], paths[j], nil, nil, func(d diff.Diff) error {
+				if d.Type == diff.TypeNothing {
+					return nil
+				}
+				cell.Differences++
+				if !d.IsDir {
+					cell.Size += d.Size
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to compare %q with %q. %w", paths[i], paths[j], err)
+			}
+
+			cell.Identical = m.Digests[i].Equal(m.Digests[j])
+			m.Cells[i][j] = cell
+			m.Cells[j][i] = cell
+		}
+	}
+
+	return m, nil
+}
+
+// Display the list of databases followed by the matrix.
+func (m *Matrix) Print(w io.Writer) {
+	fmt.Fprintln(w, "Databases:")
+	for i, p := range m.Paths {
+		fmt.Fprintf(w, "  [%d] %s (digest %s)\n", i+1, p, m.Digests[i])
+	}
+	fmt.Fprintln(w)
+
+	rows := make([][]string, 0, len(m.Paths)+1)
+	header := []string{""}
+	for i := range m.Paths {
+		header = append(header, fmt.Sprintf("[%d]", i+1))
+	}
+	rows = append(rows, header)
+
+	for i := range m.Paths {
+		row := []string{fmt.Sprintf("[%d]", i+1)}
+		for j := range m.Paths {
+			row = append(row, m.cellString(i, j))
+		}
+		rows = append(rows, row)
+	}
+
+	widths := make([]int, len(header))
+	for _, row := range rows {
+		for c, s := range row {
+			widths[c] = max(widths[c], len(s))
+		}
+	}
+
+	for _, row := range rows {
+		var sb strings.Builder
+		for c, s := range row {
+			if c > 0 {
+				sb.WriteString("  ")
+			}
+			fmt.Fprintf(&sb, "%-*s", widths[c], s)
+		}
+		fmt.Fprintln(w, strings.TrimRight(sb.String(), " "))
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Each cell shows the number of differences and the size of the files that differ,
+or "identical" when the root digests of both databases match.`)
+}
+
+func (m *Matrix) cellString(i int, j int) string {
+	if i == j {
+		return "-"
+	}
+
+	c := m.Cells[i][j]
+	if c.Identical {
+		return "identical"
+	}
+	return fmt.Sprintf("%d / %s", c.Differences, human.Bytes(c.Size))
+}
+
+//-----------------------------------------------------------------------------
+
+// Digest is a fingerprint of the contents of a database.
+// Two databases with the same digest describe the same file hierarchy, regardless of the order in which
+// the entries were stored.
+type Digest struct {
+	Meta    []byte      // SHA-256 of the path, mode, size and last modification time of each entry
+	Content []byte      // Same as Meta but also includes the file signature hashes. nil without a hash table
+	Algo    ajhash.Algo // Algorithm of the file signature hashes included in Content
+}
+
+// Equal returns true if both digests describe the same file hierarchy.
+// The file signature hashes are only taken into account when both databases have them.
+func (d Digest) Equal(other Digest) bool {
+	if (d.Content != nil) && (other.Content != nil) && (d.Algo == other.Algo) {
+		return bytes.Equal(d.Content, other.Content)
+	}
+	return bytes.Equal(d.Meta, other.Meta)
+}
+
+// String returns the beginning of the most specific digest in hex.
+func (d Digest) String() string {
+	digest := d.Meta
+	if d.Content != nil {
+		digest = d.Content
+	}
+	return hex.EncodeToString(digest[:6])
+}
+
+// Calculate the root digest of the database.
+func CalculateDigest(ctx context.Context, dbPath string) (Digest, error) {
+	dbf, err := db.OpenDatabase(dbPath)
+	if err != nil {
+		return Digest{}, err
+	}
+	defer dbf.Close()
+
+	type item struct {
+		pi   path.Info
+		hash []byte
+	}
+	items := make([]item, 0, dbf.EntriesCount())
+
+	err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		items = append(items, item{pi: pi})
+		return nil
+	})
+	if err != nil {
+		return Digest{}, fmt.Errorf("failed to calculate the digest of %q. %w", dbPath, err)
+	}
+
+	var result Digest
+	hasHashes := dbf.Features().HasHashTable()
+	if hasHashes {
+		result.Algo, err = dbf.HashTableAlgo()
+		if err != nil {
+			return Digest{}, err
+		}
+
+		hashTable, err := dbf.ReadHashTable(ctx)
+		if err != nil {
+			return Digest{}, fmt.Errorf("failed to calculate the digest of %q. %w", dbPath, err)
+		}
+		for idx, hash := range hashTable {
+			items[idx].hash = hash
+		}
+	}
+
+	slices.SortFunc(items, func(a, b item) int {
+		return cmp.Compare(a.pi.Path, b.pi.Path)
+	})
+
+	meta := sha256.New()
+	content := sha256.New()
+	var buf [8]byte
+	for _, it := range items {
+		for _, h := range []io.Writer{meta, content} {
+			_, _ = io.WriteString(h, it.pi.Path)
+			_, _ = h.Write([]byte{0})
+			binary.LittleEndian.PutUint32(buf[:4], uint32(it.pi.Mode))
+			_, _ = h.Write(buf[:4])
+			binary.LittleEndian.PutUint64(buf[:], it.pi.Size)
+			_, _ = h.Write(buf[:])
+			binary.LittleEndian.PutUint64(buf[:], uint64(it.pi.ModTime.UnixNano())) //nolint:gosec // disable G115
+			_, _ = h.Write(buf[:])
+		}
+
+		// Files that have not been hashed yet are distinguishable from a hash with all zero bytes
+		_, _ = content.Write([]byte{byte(len(it.hash))})
+		_, _ = content.Write(it.hash)
+	}
+
+	result.Meta = meta.Sum(nil)
+	if hasHashes {
+		result.Content = content.Sum(nil)
+	}
+	return result, nil
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package matrix_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/matrix"
+	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/testshared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatrix(t *testing.T) {
+	root := t.TempDir()
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeFile := func(name string, content string) {
+		p := filepath.Join(root, name)
+		require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+		require.NoError(t, os.Chtimes(p, modTime, modTime))
+		require.NoError(t, os.Chtimes(root, modTime, modTime))
+	}
+	writeFile("a.txt", "hello")
+	writeFile("b.txt", "world")
+
+	tempDir := t.TempDir()
+	disk1 := filepath.Join(tempDir, "disk1.ajfs")
+	disk2 := filepath.Join(tempDir, "disk2.ajfs")
+	disk3 := filepath.Join(tempDir, "disk3.ajfs")
+	disk4 := filepath.Join(tempDir, "disk4.ajfs")
+
+	testshared.ScanDatabase(t, disk1, root, true)
+	// Same hierarchy stored in a different order
+	sortedCfg := testshared.ScanConfig(disk2, root, true)
+	sortedCfg.Sorted = true
+	require.NoError(t, scan.Run(context.Background(), sortedCfg))
+	// Only the content of the file differs
+	writeFile("b.txt", "w0rld")
+	testshared.ScanDatabase(t, disk3, root, true)
+	// Without hashes the content change is not detectable
+	writeFile("c.txt", "new file")
+	testshared.ScanDatabase(t, disk4, root, false)
+
+	m, err := matrix.Build(context.Background(), []string{disk1, disk2, disk3, disk4})
+	require.NoError(t, err)
+
+	assert.True(t, m.Cells[0][1].Identical)
+	assert.Equal(t, 0, m.Cells[0][1].Differences)
+
+	assert.False(t, m.Cells[0][2].Identical)
+	assert.Equal(t, 1, m.Cells[0][2].Differences)
+	assert.Equal(t, uint64(5), m.Cells[0][2].Size)
+	assert.Equal(t, m.Cells[0][2], m.Cells[2][0])
+
+	assert.False(t, m.Cells[2][3].Identical)
+	assert.Equal(t, 1, m.Cells[2][3].Differences)
+	assert.Equal(t, uint64(8), m.Cells[2][3].Size)
+
+	for i := range 4 {
+		assert.True(t, m.Cells[i][i].Identical)
+	}
+
+	var outBuffer bytes.Buffer
+	cfg := matrix.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+		},
+		Paths: []string{disk1, disk2, disk3},
+	}
+	require.NoError(t, matrix.Run(context.Background(), cfg))
+	assert.Contains(t, outBuffer.String(), "     [1]        [2]        [3]\n")
+	assert.Contains(t, outBuffer.String(), "[1]  -          identical  1 / 5 B\n")
+	assert.Contains(t, outBuffer.String(), "[3]  1 / 5 B    1 / 5 B    -\n")
+
+	cfg.Paths = []string{disk1}
+	assert.ErrorContains(t, matrix.Run(context.Background(), cfg), "at least two databases")
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package prune provides the functionality for ajfs prune command.
+package prune
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/search"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+)
+
+// Config for the ajfs prune command.
+type Config struct {
+	config.CommonConfig
+	config.UnderConfig
+
+	Expression search.Expression // Entries that match the expression will be removed.
+	DryRun     bool              // Only display the entries that would be removed.
+	Force      bool              // Remove the entries even if the database has been sealed.
+}
+
+// Process the ajfs prune command.
+func Run(ctx context.Context, cfg Config) error {
+	if cfg.Expression == nil {
+		return fmt.Errorf("expected a search expression")
+	}
+
+	// Expressions such as the number of duplicates need to know about all the entries up front
+	dbf, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+	err = search.Prepare(ctx, cfg.Expression, dbf)
+	dbf.Close()
+	if err != nil {
+		return err
+	}
+
+	// Removing a directory also removes everything below it
+	prunedDirs := make(map[string]bool)
+
+	keep := func(idx int, pi path.Info, hash []byte) (bool, error) {
+		if pi.Path == "." {
+			return true, nil
+		}
+
+		for dir := filepath.Dir(pi.Path); dir != "."; dir = filepath.Dir(dir) {
+			if prunedDirs[dir] {
+				return false, nil
+			}
+		}
+
+		if !cfg.IsUnder(pi.Path) {
+			return true, nil
+		}
+
+		matched, err := cfg.Expression.Match(pi, hash)
+		if err != nil {
+			return false, err
+		}
+		if !matched {
+			return true, nil
+		}
+
+		if pi.IsDir() {
+			prunedDirs[pi.Path] = true
+		}
+
+		if cfg.DryRun {
+			cfg.Println(pi.Path)
+		} else {
+			cfg.VerbosePrintln(fmt.Sprintf("Removing %q", pi.Path))
+		}
+		return false, nil
+	}
+
+	if cfg.DryRun {
+		removed, err := dryRun(ctx, cfg, keep)
+		if err != nil {
+			return err
+		}
+		cfg.Println(fmt.Sprintf("[DRY-RUN] Would remove %d entries", removed))
+		return nil
+	}
+
+	if err := db.EnsureNotSealed(cfg.DbPath, cfg.Force); err != nil {
+		return err
+	}
+
+	removed, err := db.PruneDatabase(ctx, cfg.DbPath, keep)
+	if err != nil {
+		return err
+	}
+
+	cfg.Println(fmt.Sprintf("Removed %d entries", removed))
+	return nil
+}
+
+func dryRun(ctx context.Context, cfg Config, keep db.KeepEntryFn) (int, error) {
+	dbf, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		return 0, err
+	}
+	defer dbf.Close()
+	cfg.WarnIfLimited(dbf)
+
+	var hashTable db.HashTable
+	if dbf.Features().HasHashTable() {
+		hashTable, err = dbf.ReadHashTable(ctx)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	removed := 0
+	err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		ok, err := keep(idx, pi, hashTable[idx])
+		if err != nil {
+			return err
+		}
+		if !ok {
+			removed++
+		}
+		return nil
+	})
+
+	return removed, err
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package prune_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/prune"
+	"github.com/andrejacobs/ajfs/internal/app/search"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/ajfs/internal/testshared"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	dbPath := testshared.ScanDatabase(t, filepath.Join(t.TempDir(), "db.ajfs"), "../../testdata/scan", true)
+	allPaths := databasePaths(t, dbPath)
+
+	testCases := []struct {
+		desc    string
+		pattern string
+		dirs    bool
+		removed []string
+	}{
+		{
+			desc:    "files",
+			pattern: "same-as-*",
+			removed: []string{"a/a2/same-as-1.txt", "b/b1/b1a/same-as-1.txt"},
+		},
+		{
+			desc:    "directory and everything below it",
+			pattern: "a1",
+			dirs:    true,
+			removed: []string{
+				"a/a1",
+				"a/a1/a1a",
+				"a/a1/a1a/a1a1",
+				"a/a1/a1a/a1a1/1.txt",
+				"a/a1/a1a/a1a1/4.txt",
+				"a/a1/a1a/a1a1/blank.txt",
+				"a/a1/a1b",
+				"a/a1/a1b/5.txt",
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dbPath := testshared.ScanDatabase(t, filepath.Join(t.TempDir(), "db.ajfs"), "../../testdata/scan", true)
+
+			exp, err := search.NewShellPattern(tC.pattern, true, false)
+			require.NoError(t, err)
+
+			cfg := prune.Config{
+				CommonConfig: config.CommonConfig{
+					DbPath: dbPath,
+					Stdout: io.Discard,
+					Stderr: io.Discard,
+				},
+				Expression: exp,
+			}
+			if tC.dirs {
+				dirType, err := search.NewType("d")
+				require.NoError(t, err)
+				cfg.Expression = search.NewAnd(exp, dirType)
+			}
+
+			require.NoError(t, prune.Run(context.Background(), cfg))
+
+			expected := slices.DeleteFunc(slices.Clone(allPaths), func(p string) bool {
+				return slices.Contains(tC.removed, p)
+			})
+			assert.Equal(t, expected, databasePaths(t, dbPath))
+
+			// The remaining files keep their hashes
+			dbf, err := db.OpenDatabase(dbPath)
+			require.NoError(t, err)
+			defer dbf.Close()
+
+			err = dbf.ReadAllEntriesWithHashes(context.Background(), func(idx int, pi path.Info, hash []byte) error {
+				assert.False(t, ajhash.AllZeroBytes(hash), pi.Path)
+				return nil
+			})
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	dbPath := testshared.ScanDatabase(t, filepath.Join(t.TempDir(), "db.ajfs"), "../../testdata/scan", true)
+	expected := databasePaths(t, dbPath)
+
+	exp, err := search.NewShellPattern("*.txt", true, false)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	cfg := prune.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: dbPath,
+			Stdout: &buf,
+			Stderr: io.Discard,
+		},
+		Expression: exp,
+		DryRun:     true,
+	}
+
+	require.NoError(t, prune.Run(context.Background(), cfg))
+	assert.Contains(t, buf.String(), "a/a2/6.txt\n")
+	assert.Contains(t, buf.String(), "[DRY-RUN] Would remove 15 entries")
+	assert.Equal(t, expected, databasePaths(t, dbPath))
+}
+
+func databasePaths(t *testing.T, dbPath string) []string {
+	t.Helper()
+
+	entries, err := testshared.DatabasePaths(dbPath)
+	require.NoError(t, err)
+
+	result := make([]string, 0, len(entries))
+	for _, pi := range entries {
+		result = append(result, pi.Path)
+	}
+	return result
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package refreshmeta provides the functionality for ajfs refresh-meta command.
+package refreshmeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+)
+
+// Config for the ajfs refresh-meta command.
+type Config struct {
+	config.CommonConfig
+
+	DryRun bool // Only display the entries that would be refreshed.
+	Force  bool // Refresh the entries even if the database has been sealed.
+}
+
+// A refreshed entry.
+type change struct {
+	info        path.Info
+	discardHash bool
+}
+
+// Process the ajfs refresh-meta command.
+func Run(ctx context.Context, cfg Config) error {
+	if !cfg.DryRun {
+		if err := db.EnsureNotSealed(cfg.DbPath, cfg.Force); err != nil {
+			return err
+		}
+	}
+
+	changes, missing, err := findChanges(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	rehash := 0
+	for _, c := range changes {
+		if c.discardHash {
+			rehash++
+		}
+	}
+
+	if missing > 0 {
+		cfg.Errorln(fmt.Sprintf("%d paths no longer exist and were left unchanged (see \"ajfs update\")", missing))
+	}
+
+	if cfg.DryRun {
+		cfg.Println(fmt.Sprintf("[DRY-RUN] Would refresh %d entries, %d of which would need to be hashed again", len(changes), rehash))
+		return nil
+	}
+
+	if len(changes) > 0 {
+		err = db.RefreshDatabase(ctx, cfg.DbPath, func(idx int, pi *path.Info, hash []byte) (bool, error) {
+			c, ok := changes[idx]
+			if !ok {
+				return false, nil
+			}
+			*pi = c.info
+			return c.discardHash && (hash != nil), nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cfg.Println(fmt.Sprintf("Refreshed %d entries", len(changes)))
+	if rehash > 0 {
+		cfg.Println(fmt.Sprintf("%d files changed in size and need to be hashed again (see \"ajfs resume\")", rehash))
+	}
+	return nil
+}
+
+// Stat each path and return the entries for which the size, mode or last modification time changed (by index) along
+// with the number of paths that no longer exist.
+func findChanges(ctx context.Context, cfg Config) (map[int]change, int, error) {
+	dbf, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer dbf.Close()
+	cfg.WarnIfLimited(dbf)
+
+	changes := make(map[int]change)
+	missing := 0
+	hasHashTable := dbf.Features().HasHashTable()
+
+	err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		fullPath := filepath.Join(dbf.RootPath(), pi.Path)
+		stat, err := os.Lstat(fullPath)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				cfg.VerbosePrintln(fmt.Sprintf("Missing %q", pi.Path))
+				missing++
+				return nil
+			}
+			return fmt.Errorf("failed to refresh %q. %w", fullPath, err)
+		}
+
+		current, err := path.InfoFromWalk(pi.Path, fs.FileInfoToDirEntry(stat))
+		if err != nil {
+			return err
+		}
+		current.Id = pi.Id
+
+		if current.Equals(&pi) {
+			return nil
+		}
+
+		// Only a change in size (or type) means the content has to be hashed again
+		discardHash := hasHashTable && pi.IsFile() && ((current.Size != pi.Size) || !current.IsFile())
+		changes[idx] = change{info: current, discardHash: discardHash}
+
+		if cfg.DryRun {
+			cfg.Println(pi.Path)
+		} else {
+			cfg.VerbosePrintln(fmt.Sprintf("Refreshing %q", pi.Path))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return changes, missing, nil
+}
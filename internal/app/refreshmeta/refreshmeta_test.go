@@ -0,0 +1,124 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package refreshmeta_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/refreshmeta"
+	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "same.txt"), []byte("1"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "touched.txt"), []byte("2"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "grown.txt"), []byte("3"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "removed.txt"), []byte("4"), 0644))
+
+	dbPath := filepath.Join(t.TempDir(), "unit-test.ajfs")
+	require.NoError(t, scan.Run(context.Background(), scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: dbPath,
+		},
+		Root:            root,
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}))
+
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(root, "touched.txt"), modTime, modTime))
+	require.NoError(t, os.Chmod(filepath.Join(root, "touched.txt"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "grown.txt"), []byte("333"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(root, "removed.txt")))
+
+	var outBuffer bytes.Buffer
+	var errBuffer bytes.Buffer
+	cfg := refreshmeta.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: &errBuffer,
+			DbPath: dbPath,
+		},
+		DryRun: true,
+	}
+
+	// Dry run only displays the changes
+	require.NoError(t, refreshmeta.Run(context.Background(), cfg))
+	assert.Contains(t, outBuffer.String(), "touched.txt\n")
+	assert.Contains(t, outBuffer.String(), "grown.txt\n")
+	assert.NotContains(t, outBuffer.String(), "same.txt")
+	assert.Contains(t, errBuffer.String(), "1 paths no longer exist")
+
+	before := readEntries(t, dbPath)
+	assert.Len(t, before, 5)
+
+	cfg.DryRun = false
+	require.NoError(t, refreshmeta.Run(context.Background(), cfg))
+
+	after := readEntries(t, dbPath)
+	assert.Equal(t, before["same.txt"], after["same.txt"])
+	assert.Equal(t, before["removed.txt"], after["removed.txt"])
+
+	touched := after["touched.txt"]
+	assert.True(t, touched.info.ModTime.Equal(modTime))
+	assert.Equal(t, os.FileMode(0600), touched.info.Mode.Perm())
+	assert.Equal(t, before["touched.txt"].hash, touched.hash)
+
+	grown := after["grown.txt"]
+	assert.Equal(t, uint64(3), grown.info.Size)
+	assert.Nil(t, grown.hash)
+}
+
+type entry struct {
+	info path.Info
+	hash []byte
+}
+
+func readEntries(t *testing.T, dbPath string) map[string]entry {
+	t.Helper()
+	dbf, err := db.OpenDatabase(dbPath)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	hashes, err := dbf.ReadHashTable(context.Background())
+	require.NoError(t, err)
+
+	result := make(map[string]entry)
+	require.NoError(t, dbf.ReadAllEntries(context.Background(), func(idx int, pi path.Info) error {
+		result[pi.Path] = entry{info: pi, hash: hashes[idx]}
+		return nil
+	}))
+	return result
+}
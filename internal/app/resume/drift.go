@@ -0,0 +1,97 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package resume
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/go-aj/ajhash"
+)
+
+// ErrRootDrift is returned when too many of the files still to be hashed are missing from the root path.
+var ErrRootDrift = errors.New("the root path appears to have moved")
+
+const (
+	driftSampleSize = 100 // Maximum number of files that are checked for existence.
+	driftThreshold  = 0.5 // Fraction of missing files at which resuming is aborted.
+)
+
+// Check a random sample of the files that still need to be hashed to see if they still exist below the root path.
+// A warning is displayed when some are missing and [ErrRootDrift] is returned when most of them are missing, since
+// that normally means the root was remounted elsewhere and resuming would fail on every file.
+func checkRootDrift(ctx context.Context, cfg Config, dbf *db.DatabaseFile) error {
+	root := dbf.RootPath()
+	if _, err := os.Stat(root); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w. the root path %q does not exist, use \"ajfs set-root\" if it was moved", ErrRootDrift, root)
+		}
+		return err
+	}
+
+	indices := make([]int, 0, 512)
+	err := dbf.ReadHashTableEntries(ctx, func(idx int, hash []byte) error {
+		if ajhash.AllZeroBytes(hash) {
+			indices = append(indices, idx)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Partial shuffle so that the first entries are a random sample
+	sampleSize := min(len(indices), driftSampleSize)
+	for i := range sampleSize {
+		j := i + rand.IntN(len(indices)-i) //nolint:gosec // not used for security
+		indices[i], indices[j] = indices[j], indices[i]
+	}
+
+	missing := 0
+	for _, idx := range indices[:sampleSize] {
+		pi, err := dbf.ReadEntryAtIndex(idx)
+		if err != nil {
+			return err
+		}
+
+		if _, err := os.Lstat(filepath.Join(root, pi.Path)); errors.Is(err, fs.ErrNotExist) {
+			missing++
+		}
+	}
+
+	if missing == 0 {
+		return nil
+	}
+
+	if float64(missing) >= float64(sampleSize)*driftThreshold {
+		return fmt.Errorf("%w. %d of %d sampled files are missing below the root path %q. use \"ajfs set-root\" if it was moved, \"ajfs update\" if the files changed or \"--skip-root-check\" to resume anyway",
+			ErrRootDrift, missing, sampleSize, root)
+	}
+
+	cfg.Errorln(fmt.Sprintf("WARNING: %d of %d sampled files are missing below the root path %q", missing, sampleSize, root))
+	return nil
+}
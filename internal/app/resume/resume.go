@@ -22,6 +22,7 @@
 package resume
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -30,12 +31,16 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"syscall"
+	"time"
 
 	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/archive"
+	"github.com/andrejacobs/ajfs/internal/checkpoint"
 	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/errlog"
 	"github.com/andrejacobs/ajfs/internal/path"
-	"github.com/andrejacobs/go-aj/file"
 	"github.com/andrejacobs/go-aj/human"
 	"github.com/schollz/progressbar/v3"
 )
@@ -44,6 +49,18 @@ import (
 type Config struct {
 	config.CommonConfig
 
+	RetryErrors   bool   // Also retry the files that are recorded in the error log.
+	Force         bool   // Resume even if the database has been sealed.
+	HashCachePath string // Path to the cache used to reuse previously calculated hashes. Empty disables the cache.
+	SkipRootCheck bool   // Don't check if the files still exist below the root path before resuming.
+	SortHashes    bool   // Sort the hash table by hash once resuming has finished.
+	AutoFix       bool   // Fix the database (after backing up the headers) when the header does not match the contents.
+
+	FlushInterval time.Duration // Interval at which calculated hashes are written to the database. 0 writes each hash immediately.
+	SyncPolicy    db.SyncPolicy // How often the database is synced to disk while the hashes are written.
+
+	CheckpointInterval time.Duration // Interval at which the checkpoint file is written next to the database. 0 disables it.
+
 	hashFn hashFn // Hashing function
 }
 
@@ -51,23 +68,77 @@ type Config struct {
 type hashFn func(ctx context.Context, path string, hasher hash.Hash, w io.Writer) ([]byte, uint64, error)
 
 // Process the ajfs scan command.
-func Run(cfg Config) error {
+func Run(ctx context.Context, cfg Config) error {
 	if cfg.hashFn == nil {
-		cfg.hashFn = file.Hash
+		hasher := archive.NewHasher()
+		defer hasher.Close()
+		cfg.hashFn = hasher.Hash
+	}
+
+	if err := db.EnsureNotSealed(cfg.DbPath, cfg.Force); err != nil {
+		return err
+	}
+
+	if cfg.AutoFix {
+		if err := autoFix(cfg); err != nil {
+			return err
+		}
+	}
+
+	// A checkpoint is only left behind by a scan or resume that did not exit cleanly
+	previous, err := checkpoint.Read(checkpoint.PathFor(cfg.DbPath))
+	if err != nil {
+		return err
+	}
+	if previous != nil {
+		cfg.Println(fmt.Sprintf("The previous %s did not exit cleanly. It last %s", previous.Command, previous.Describe()))
 	}
 
 	cfg.ProgressPrintln(fmt.Sprintf("Resuming database file at %q", cfg.DbPath))
 	dbf, err := db.ResumeDatabase(cfg.DbPath)
 	if err != nil {
+		if errors.Is(err, db.ErrDirty) {
+			return fmt.Errorf("%w. alternatively use \"ajfs resume --auto-fix\" to fix it and resume in one step", err)
+		}
 		return err
 	}
+	// Ensure the database is always closed cleanly, Close can safely be called more than once
+	defer dbf.Close()
 
 	if !dbf.Features().HasHashTable() {
+		if err = dbf.Close(); err != nil {
+			return err
+		}
+
+		// The header can be missing the hash table while the file contains one (e.g. after a crash)
+		if !cfg.AutoFix && (db.FixDatabase(io.Discard, cfg.DbPath, true, "") != nil) {
+			return fmt.Errorf("the database header does not match the contents of %q. use \"ajfs resume --auto-fix\" to fix it and resume", cfg.DbPath)
+		}
+
 		cfg.VerbosePrintln("Nothing to resume")
 		return nil
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	if cfg.SortHashes {
+		dbf.SortHashTableOnClose()
+	}
+	dbf.SetHashFlushInterval(cfg.FlushInterval)
+	dbf.SetSyncPolicy(cfg.SyncPolicy)
+
+	if !cfg.SkipRootCheck {
+		if err = checkRootDrift(ctx, cfg, dbf); err != nil {
+			return err
+		}
+	}
+
+	cp := checkpoint.NewWriter(checkpoint.PathFor(cfg.DbPath), "resume", cfg.CheckpointInterval)
+	defer func() {
+		if err := cp.Close(); err != nil {
+			cfg.Errorln(err)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	// Hook into listening for the SIGINT (Ctrl+C) and SIGTERM signals
@@ -84,7 +155,20 @@ func Run(cfg Config) error {
 		interruptedCh <- true
 	}()
 
-	if err = resumeCalculatingHashes(ctx, cfg, dbf); err != nil {
+	if previous != nil {
+		if previous.Phase == checkpoint.PhaseHashing {
+			if err = verifyAfterCheckpoint(ctx, cfg, dbf, previous.LastIndex); err != nil {
+				if !errors.Is(err, context.Canceled) {
+					return err
+				}
+			}
+		}
+		if err = checkpoint.Remove(checkpoint.PathFor(cfg.DbPath)); err != nil {
+			return err
+		}
+	}
+
+	if err = resumeCalculatingHashes(ctx, cfg, dbf, cp); err != nil {
 		if !errors.Is(err, context.Canceled) {
 			return err
 		}
@@ -104,22 +188,72 @@ func Run(cfg Config) error {
 	return nil
 }
 
-func resumeCalculatingHashes(ctx context.Context, cfg Config, dbf *db.DatabaseFile) error {
+// Fix the database when the header does not match the contents, the same as "ajfs fix" would.
+// The original headers are backed up next to the database so that they can be restored using "ajfs fix --restore".
+func autoFix(cfg Config) error {
+	if err := db.FixDatabase(io.Discard, cfg.DbPath, true, ""); err == nil {
+		return nil
+	}
+
+	var out io.Writer = io.Discard
+	if cfg.Verbose {
+		out = cfg.Stdout
+	}
+
+	bakPath := cfg.DbPath + ".header.bak"
+	cfg.Println(fmt.Sprintf("Fixing the database %q, the original headers are backed up to %q", cfg.DbPath, bakPath))
+	if err := db.FixDatabase(out, cfg.DbPath, false, bakPath); err != nil {
+		return fmt.Errorf("failed to fix the database %q. %w", cfg.DbPath, err)
+	}
+	return nil
+}
+
+func resumeCalculatingHashes(ctx context.Context, cfg Config, dbf *db.DatabaseFile, cp *checkpoint.Writer) error {
+	defer cfg.StartPhase("resuming file signatures")()
+
 	algo, err := dbf.HashTableAlgo()
 	if err != nil {
 		return err
 	}
 
 	cfg.VerbosePrintln("Calculating file signature hashes ...")
-	cfg.VerbosePrintln(fmt.Sprintf("  Algorithm: %s", algo))
+	cfg.VerbosePrintln(fmt.Sprintf("  Algorithm: %s", db.AlgoString(algo)))
+
+	errLog, err := errlog.Open(errlog.PathFor(cfg.DbPath))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := errLog.Close(); err != nil {
+			cfg.Errorln(err)
+		}
+	}()
+
+	cache, err := cfg.OpenHashCache(cfg.HashCachePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := cache.Close(); err != nil {
+			cfg.Errorln(err)
+		}
+	}()
+	cached := 0
+
+	// Files that failed before are likely to fail again and are only retried when asked to
+	skip := func(pi path.Info) bool {
+		return !cfg.RetryErrors && errLog.Contains(pi.Path)
+	}
+	skipped := 0
+	failed := 0
 
 	var progress *progressbar.ProgressBar
 	count := uint64(0)
 	totalCount := uint64(0)
 
-	if cfg.Progress {
+	if cfg.Progress || cfg.Metrics != nil {
 		cfg.ProgressPrintln("Calculating progress information ...")
-		stats, err := dbf.CalculateStats()
+		stats, err := dbf.CalculateStats(ctx)
 		if err != nil {
 			return err
 		}
@@ -128,7 +262,10 @@ func resumeCalculatingHashes(ctx context.Context, cfg Config, dbf *db.DatabaseFi
 
 		todoSize := uint64(0)
 		todoCount := uint64(0)
-		err = dbf.EntriesNeedHashing(func(idx int, pi path.Info) error {
+		err = dbf.EntriesNeedHashing(ctx, func(idx int, pi path.Info) error {
+			if skip(pi) {
+				return nil
+			}
 			todoSize += pi.Size
 			todoCount++
 			return nil
@@ -139,14 +276,23 @@ func resumeCalculatingHashes(ctx context.Context, cfg Config, dbf *db.DatabaseFi
 
 		cfg.VerbosePrintln(fmt.Sprintf("Still need to process %d files [%s]", todoCount, human.Bytes(todoSize)))
 
-		progress = progressbar.DefaultBytes(int64(stats.TotalFileSize)) //nolint:gosec // disable G115
-		if err = progress.Set64(int64(stats.TotalFileSize - todoSize)); err != nil {
-			return err
+		if cfg.Progress {
+			progress = progressbar.DefaultBytes(int64(stats.TotalFileSize)) //nolint:gosec // disable G115
+			if err = progress.Set64(int64(stats.TotalFileSize - todoSize)); err != nil {
+				return err
+			}
 		}
 		count = totalCount - todoCount
+		cfg.Metrics.StartHashing(todoCount, todoSize)
 	}
 
-	err = dbf.EntriesNeedHashing(func(idx int, pi path.Info) error {
+	err = dbf.EntriesNeedHashing(ctx, func(idx int, pi path.Info) error {
+		if skip(pi) {
+			skipped++
+			return nil
+		}
+		cp.Hashing(idx, pi.Path)
+
 		if progress != nil {
 			progress.Describe(fmt.Sprintf("[%d/%d]", count+1, totalCount))
 		} else {
@@ -154,7 +300,22 @@ func resumeCalculatingHashes(ctx context.Context, cfg Config, dbf *db.DatabaseFi
 		}
 
 		path := filepath.Join(dbf.RootPath(), pi.Path)
-		hash, _, err := cfg.hashFn(ctx, path, algo.Hasher(), progress)
+
+		if hash, ok := cache.Lookup(path, pi.Size, pi.ModTime, algo); ok {
+			if err := dbf.WriteHashEntry(idx, hash); err != nil {
+				return fmt.Errorf("failed to write the hash for %q. %w", path, err)
+			}
+			if progress != nil {
+				_ = progress.Add64(int64(pi.Size)) //nolint:gosec // disable G115
+			}
+			cfg.Metrics.FileHashed(pi.Size)
+			errLog.Resolve(pi.Path)
+			cached++
+			count++
+			return nil
+		}
+
+		hash, _, err := cfg.hashFn(ctx, path, db.AlgoHasher(algo), progress)
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
 				return err
@@ -162,10 +323,20 @@ func resumeCalculatingHashes(ctx context.Context, cfg Config, dbf *db.DatabaseFi
 
 			// Continue hashing
 			fmt.Fprintf(cfg.Stderr, "failed to calculate the hash for %q. %v\n", path, err)
+			failed++
+			cfg.Metrics.FileFailed(pi.Size)
+			if err = errLog.Add(pi.Path, err); err != nil {
+				return err
+			}
 		} else {
 			if err = dbf.WriteHashEntry(idx, hash); err != nil {
 				return fmt.Errorf("failed to write the hash for %q. %w", path, err)
 			}
+			if err = cache.Add(path, pi.Size, pi.ModTime, algo, hash); err != nil {
+				return err
+			}
+			cfg.Metrics.FileHashed(pi.Size)
+			errLog.Resolve(pi.Path)
 		}
 
 		count++
@@ -179,5 +350,71 @@ func resumeCalculatingHashes(ctx context.Context, cfg Config, dbf *db.DatabaseFi
 		return err
 	}
 
+	if cached > 0 {
+		cfg.VerbosePrintln(fmt.Sprintf("Reused %d hashes from the cache %q", cached, cache.Path()))
+	}
+	if skipped > 0 {
+		fmt.Fprintf(cfg.Stderr, "Skipped %d files that failed before, use --retry-errors to try them again\n", skipped)
+	}
+	if failed > 0 {
+		fmt.Fprintf(cfg.Stderr, "Failed to calculate the hash for %d files, see %q\n", failed, errLog.Path())
+	}
+
+	return nil
+}
+
+// Calculate the hashes again that were written after the checkpoint of the previous command, since these were
+// the last hashes written before it did not exit cleanly. Hashes that don't match are replaced.
+func verifyAfterCheckpoint(ctx context.Context, cfg Config, dbf *db.DatabaseFile, fromIdx int) error {
+	defer cfg.StartPhase("verifying the hashes after the checkpoint")()
+
+	algo, err := dbf.HashTableAlgo()
+	if err != nil {
+		return err
+	}
+
+	hashes, err := dbf.ReadHashTable(ctx)
+	if err != nil {
+		return err
+	}
+	indices := make([]int, 0, 64)
+	for idx := range hashes {
+		if idx >= fromIdx {
+			indices = append(indices, idx)
+		}
+	}
+	slices.Sort(indices)
+
+	replaced := 0
+	for _, idx := range indices {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		pi, err := dbf.ReadEntryAtIndex(idx)
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dbf.RootPath(), pi.Path)
+		hash, _, err := cfg.hashFn(ctx, path, db.AlgoHasher(algo), nil)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return err
+			}
+			fmt.Fprintf(cfg.Stderr, "failed to verify the hash for %q. %v\n", path, err)
+			continue
+		}
+
+		if !bytes.Equal(hash, hashes[idx]) {
+			cfg.VerbosePrintln(fmt.Sprintf("Replacing the hash for %q", pi.Path))
+			if err = dbf.WriteHashEntry(idx, hash); err != nil {
+				return fmt.Errorf("failed to write the hash for %q. %w", path, err)
+			}
+			replaced++
+		}
+	}
+
+	cfg.Println(fmt.Sprintf("Verified %d hashes written after the checkpoint, replaced %d", len(indices), replaced))
 	return nil
 }
@@ -23,16 +23,22 @@ package resume
 import (
 	"bytes"
 	"context"
+	"crypto/sha1" // #nosec G505 -- SHA1 is not used for cryptography
 	"fmt"
 	"hash"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/andrejacobs/ajfs/internal/app/config"
 	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/checkpoint"
 	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/errlog"
 	"github.com/andrejacobs/ajfs/internal/path"
 	"github.com/andrejacobs/go-aj/ajhash"
 	"github.com/andrejacobs/go-aj/file"
@@ -57,7 +63,7 @@ func TestResumeWithHashingErrors(t *testing.T) {
 		InitOnly:        true,
 	}
 
-	err := scan.Run(cfg)
+	err := scan.Run(context.Background(), cfg)
 	require.NoError(t, err)
 
 	// Resume calculating hashes
@@ -79,7 +85,7 @@ func TestResumeWithHashingErrors(t *testing.T) {
 	// Resume
 	var errOutput bytes.Buffer
 	resumeCfg.Stderr = &errOutput
-	err = Run(resumeCfg)
+	err = Run(context.Background(), resumeCfg)
 	require.NoError(t, err)
 	require.Contains(t, errOutput.String(), expErrMsg)
 
@@ -88,7 +94,7 @@ func TestResumeWithHashingErrors(t *testing.T) {
 	require.NoError(t, err)
 
 	count = 0
-	err = dbf.EntriesNeedHashing(func(idx int, pi path.Info) error {
+	err = dbf.EntriesNeedHashing(context.Background(), func(idx int, pi path.Info) error {
 		count++
 		return nil
 	})
@@ -96,20 +102,121 @@ func TestResumeWithHashingErrors(t *testing.T) {
 	require.Equal(t, 2, count)
 	require.NoError(t, dbf.Close())
 
-	// Resume without errors
+	// Check the error log
+	errLog, err := errlog.Open(errlog.PathFor(cfg.DbPath))
+	require.NoError(t, err)
+	entries := errLog.Entries()
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		require.Equal(t, expErrMsg, entry.Error)
+		require.Equal(t, 1, entry.Attempt)
+	}
+	require.NoError(t, errLog.Close())
+
+	// Resume skips the files that failed before
 	resumeCfg.hashFn = nil
-	err = Run(resumeCfg)
+	errOutput.Reset()
+	err = Run(context.Background(), resumeCfg)
+	require.NoError(t, err)
+	require.Contains(t, errOutput.String(), "Skipped 2 files that failed before")
+
+	dbf, err = db.OpenDatabase(cfg.DbPath)
+	require.NoError(t, err)
+	count = 0
+	err = dbf.EntriesNeedHashing(context.Background(), func(idx int, pi path.Info) error {
+		count++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	require.NoError(t, dbf.Close())
+
+	// Retry the errors
+	resumeCfg.RetryErrors = true
+	err = Run(context.Background(), resumeCfg)
 	require.NoError(t, err)
+	require.NoFileExists(t, errlog.PathFor(cfg.DbPath))
 
 	dbf, err = db.OpenDatabase(cfg.DbPath)
 	require.NoError(t, err)
 	defer dbf.Close()
 
 	count = 0
-	err = dbf.EntriesNeedHashing(func(idx int, pi path.Info) error {
+	err = dbf.EntriesNeedHashing(context.Background(), func(idx int, pi path.Info) error {
 		count++
 		return nil
 	})
 	require.NoError(t, err)
 	require.Equal(t, 0, count)
 }
+
+func TestResumeVerifiesAfterCheckpoint(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+
+	cfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: tempFile,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		Root:            "../../testdata/scan",
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+		InitOnly:        true,
+	}
+	require.NoError(t, scan.Run(context.Background(), cfg))
+
+	// Record the wrong hash for every file
+	resumeCfg := Config{
+		CommonConfig: cfg.CommonConfig,
+	}
+	resumeCfg.hashFn = func(ctx context.Context, path string, hasher hash.Hash, w io.Writer) ([]byte, uint64, error) {
+		_, _ = hasher.Write([]byte("wrong"))
+		return hasher.Sum(nil), 5, nil
+	}
+	require.NoError(t, Run(context.Background(), resumeCfg))
+
+	dbf, err := db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	hashes, err := dbf.ReadHashTable(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, dbf.Close())
+	indices := slices.Sorted(maps.Keys(hashes))
+	require.Greater(t, len(indices), 4)
+	fromIdx := indices[len(indices)/2]
+
+	// Simulate a crash after the checkpoint was written while hashing
+	cp := checkpoint.NewWriter(checkpoint.PathFor(tempFile), "scan", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	cp.Hashing(fromIdx, "some/file")
+	require.FileExists(t, checkpoint.PathFor(tempFile))
+
+	var outBuffer bytes.Buffer
+	resumeCfg.hashFn = nil
+	resumeCfg.Stdout = &outBuffer
+	require.NoError(t, Run(context.Background(), resumeCfg))
+
+	expCount := len(indices) - len(indices)/2
+	require.Contains(t, outBuffer.String(), "The previous scan did not exit cleanly. It last hashed \"some/file\"")
+	require.Contains(t, outBuffer.String(), fmt.Sprintf("Verified %d hashes written after the checkpoint, replaced %d", expCount, expCount))
+	require.NoFileExists(t, checkpoint.PathFor(tempFile))
+
+	// Only the hashes after the checkpoint have been replaced
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+	fixed, err := dbf.ReadHashTable(context.Background())
+	require.NoError(t, err)
+	for _, idx := range indices {
+		pi, err := dbf.ReadEntryAtIndex(idx)
+		require.NoError(t, err)
+		expected, _, err := file.Hash(context.Background(), filepath.Join(dbf.RootPath(), pi.Path), sha1.New(), nil)
+		require.NoError(t, err)
+
+		if idx >= fromIdx {
+			require.Equal(t, expected, fixed[idx], pi.Path)
+		} else {
+			require.Equal(t, hashes[idx], fixed[idx], pi.Path)
+		}
+	}
+}
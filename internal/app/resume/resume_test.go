@@ -21,6 +21,9 @@
 package resume_test
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -30,6 +33,7 @@ import (
 	"github.com/andrejacobs/ajfs/internal/app/export"
 	"github.com/andrejacobs/ajfs/internal/app/resume"
 	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/db"
 	"github.com/andrejacobs/ajfs/internal/testshared"
 	"github.com/andrejacobs/go-aj/ajhash"
 	"github.com/stretchr/testify/assert"
@@ -70,7 +74,7 @@ func TestResume(t *testing.T) {
 				InitOnly:        true,
 			}
 
-			err := scan.Run(cfg)
+			err := scan.Run(context.Background(), cfg)
 			require.NoError(t, err)
 
 			// Resume calculating hashes
@@ -78,7 +82,7 @@ func TestResume(t *testing.T) {
 				CommonConfig: cfg.CommonConfig,
 			}
 
-			err = resume.Run(resumeCfg)
+			err = resume.Run(context.Background(), resumeCfg)
 			require.NoError(t, err)
 
 			// Export hashdeep
@@ -91,7 +95,7 @@ func TestResume(t *testing.T) {
 				Format:       export.FormatHashdeep,
 				ExportPath:   tempExportFile,
 			}
-			err = export.Run(exportCfg)
+			err = export.Run(context.Background(), exportCfg)
 			require.NoError(t, err)
 
 			// Validate
@@ -105,3 +109,123 @@ func TestResume(t *testing.T) {
 		})
 	}
 }
+
+func TestResumeAutoFix(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+
+	cfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: tempFile,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		Root:            "../../testdata/scan",
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+		InitOnly:        true,
+	}
+	require.NoError(t, scan.Run(context.Background(), cfg))
+
+	// Simulate resuming that crashed before the database could be closed cleanly
+	crashed, err := db.ResumeDatabase(tempFile)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = crashed.Interrupted()
+	})
+
+	resumeCfg := resume.Config{
+		CommonConfig: cfg.CommonConfig,
+	}
+	err = resume.Run(context.Background(), resumeCfg)
+	assert.ErrorIs(t, err, db.ErrDirty)
+	assert.ErrorContains(t, err, "--auto-fix")
+
+	resumeCfg.AutoFix = true
+	require.NoError(t, resume.Run(context.Background(), resumeCfg))
+	assert.FileExists(t, tempFile+".header.bak")
+
+	dbf, err := db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	hashTable, err := dbf.ReadHashTable(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, hashTable, dbf.FileEntriesCount())
+}
+
+func TestResumeRootDrift(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "root")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub"), 0755))
+	for i := range 10 {
+		require.NoError(t, os.WriteFile(filepath.Join(root, "sub", fmt.Sprintf("%d.txt", i)), []byte("data"), 0644))
+	}
+
+	var errBuffer bytes.Buffer
+	cfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: filepath.Join(t.TempDir(), "unit-testing"),
+			Stdout: io.Discard,
+			Stderr: &errBuffer,
+		},
+		Root:            root,
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+		InitOnly:        true,
+	}
+	require.NoError(t, scan.Run(context.Background(), cfg))
+
+	resumeCfg := resume.Config{
+		CommonConfig: cfg.CommonConfig,
+	}
+
+	// The root was moved
+	moved := root + "-moved"
+	require.NoError(t, os.Rename(root, moved))
+	err := resume.Run(context.Background(), resumeCfg)
+	assert.ErrorIs(t, err, resume.ErrRootDrift)
+	assert.ErrorContains(t, err, "set-root")
+
+	// Most of the files were moved
+	require.NoError(t, os.Rename(moved, root))
+	require.NoError(t, os.Rename(filepath.Join(root, "sub"), filepath.Join(root, "other")))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "sub", "0.txt"), []byte("data"), 0644))
+	err = resume.Run(context.Background(), resumeCfg)
+	assert.ErrorIs(t, err, resume.ErrRootDrift)
+	assert.ErrorContains(t, err, "9 of 10 sampled files are missing")
+
+	// Only a few files are missing
+	for i := 1; i < 8; i++ {
+		name := fmt.Sprintf("%d.txt", i)
+		require.NoError(t, os.Rename(filepath.Join(root, "other", name), filepath.Join(root, "sub", name)))
+	}
+	errBuffer.Reset()
+	require.NoError(t, resume.Run(context.Background(), resumeCfg))
+	assert.Contains(t, errBuffer.String(), "WARNING: 2 of 10 sampled files are missing")
+}
+
+func TestResumeSkipRootCheck(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "root")
+	require.NoError(t, os.MkdirAll(root, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("data"), 0644))
+
+	cfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: filepath.Join(t.TempDir(), "unit-testing"),
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		Root:            root,
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+		InitOnly:        true,
+	}
+	require.NoError(t, scan.Run(context.Background(), cfg))
+	require.NoError(t, os.Remove(filepath.Join(root, "a.txt")))
+
+	resumeCfg := resume.Config{
+		CommonConfig:  cfg.CommonConfig,
+		SkipRootCheck: true,
+	}
+	require.NoError(t, resume.Run(context.Background(), resumeCfg))
+}
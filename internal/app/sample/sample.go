@@ -0,0 +1,222 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package sample provides the functionality for ajfs sample command.
+package sample
+
+import (
+	"cmp"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/rand/v2"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/search"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+)
+
+// Config for the ajfs sample command.
+type Config struct {
+	config.CommonConfig
+	config.UnderConfig
+
+	Count            int               // Number of entries to select.
+	OnlyFiles        bool              // Only select regular files.
+	OnlyHashed       bool              // Only select entries for which a file signature hash has been calculated.
+	Expression       search.Expression // [optional] Only select entries matching the expression.
+	Seed             uint64            // Seed for the random selection. Zero means a different selection every time.
+	DisplayFullPaths bool              // If true then each path entry will be prefixed with the root path of the database.
+}
+
+// Process the ajfs sample command.
+func Run(ctx context.Context, cfg Config) error {
+	if cfg.Count <= 0 {
+		return fmt.Errorf("the number of entries to sample must be larger than 0")
+	}
+
+	dbf, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+	defer dbf.Close()
+	cfg.WarnIfLimited(dbf)
+	if err = search.Prepare(ctx, cfg.Expression, dbf); err != nil {
+		return err
+	}
+
+	withHashes := dbf.Features().HasHashTable()
+	if cfg.OnlyHashed && !withHashes {
+		return fmt.Errorf("the database %q does not contain file signature hashes", cfg.DbPath)
+	}
+
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+	rng := rand.New(rand.NewPCG(seed, seed))
+
+	var selected []sampled
+	if cfg.Under == "" && cfg.Expression == nil && !cfg.OnlyFiles && !cfg.OnlyHashed {
+		selected, err = sampleIndices(ctx, dbf, rng, cfg.Count, withHashes)
+	} else {
+		selected, err = sampleMatching(ctx, cfg, dbf, rng, withHashes)
+	}
+	if err != nil {
+		return err
+	}
+
+	if cfg.Verbose {
+		if withHashes {
+			cfg.Println(path.HeaderWithHash())
+		} else {
+			cfg.Println(path.Header())
+		}
+	}
+
+	// Display in the order stored in the database
+	slices.SortFunc(selected, func(a, b sampled) int {
+		return cmp.Compare(a.idx, b.idx)
+	})
+
+	for _, s := range selected {
+		pi := s.info
+		if cfg.DisplayFullPaths {
+			pi.Path = filepath.Join(dbf.RootPath(), pi.Path)
+		}
+
+		if withHashes {
+			cfg.Println(pi.StringWithHash(hex.EncodeToString(s.hash), path.FieldOptions{DisplayTime: cfg.DisplayTime}))
+		} else {
+			cfg.Println(pi)
+		}
+	}
+
+	return nil
+}
+
+// A randomly selected path entry.
+type sampled struct {
+	idx  int
+	info path.Info
+	hash []byte
+}
+
+// Select count distinct entries out of all the entries in the database.
+// Only the selected entries are read using the entries lookup table.
+func sampleIndices(ctx context.Context, dbf *db.DatabaseFile, rng *rand.Rand, count int, withHashes bool) ([]sampled, error) {
+	total := dbf.EntriesCount()
+	count = min(count, total)
+
+	// Robert Floyd's algorithm for selecting distinct random numbers without shuffling all of them
+	chosen := make(map[int]struct{}, count)
+	for i := total - count; i < total; i++ {
+		j := rng.IntN(i + 1)
+		if _, exists := chosen[j]; exists {
+			j = i
+		}
+		chosen[j] = struct{}{}
+	}
+
+	var hashTable db.HashTable
+	if withHashes {
+		var err error
+		hashTable, err = dbf.ReadHashTable(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]sampled, 0, count)
+	for idx := range chosen {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		pi, err := dbf.ReadEntryAtIndex(idx)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, sampled{idx: idx, info: pi, hash: hashTable[idx]})
+	}
+
+	return result, nil
+}
+
+// Select cfg.Count entries out of the entries that match the configured criteria.
+// Reservoir sampling is used so that every matching entry has the same chance of being selected.
+func sampleMatching(ctx context.Context, cfg Config, dbf *db.DatabaseFile, rng *rand.Rand, withHashes bool) ([]sampled, error) {
+	result := make([]sampled, 0, cfg.Count)
+	seen := 0
+
+	fn := func(idx int, pi path.Info, hash []byte) error {
+		if !cfg.IsUnder(pi.Path) {
+			return nil
+		}
+		if cfg.OnlyFiles && !pi.Mode.IsRegular() {
+			return nil
+		}
+		if cfg.Expression != nil {
+			matched, err := cfg.Expression.Match(pi, hash)
+			if err != nil {
+				return err
+			}
+			if !matched {
+				return nil
+			}
+		}
+
+		seen++
+		if len(result) < cfg.Count {
+			result = append(result, sampled{idx: idx, info: pi, hash: hash})
+			return nil
+		}
+
+		if j := rng.IntN(seen); j < cfg.Count {
+			result[j] = sampled{idx: idx, info: pi, hash: hash}
+		}
+		return nil
+	}
+
+	var err error
+	if cfg.OnlyHashed {
+		// Only entries with a calculated hash are passed along
+		err = dbf.ReadAllEntriesWithHashes(ctx, fn)
+	} else if withHashes {
+		var hashTable db.HashTable
+		hashTable, err = dbf.ReadHashTable(ctx)
+		if err != nil {
+			return nil, err
+		}
+		err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+			return fn(idx, pi, hashTable[idx])
+		})
+	} else {
+		err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+			return fn(idx, pi, nil)
+		})
+	}
+
+	return result, err
+}
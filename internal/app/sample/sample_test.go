@@ -0,0 +1,144 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package sample_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/sample"
+	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/app/search"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSample(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Root: "../../testdata/scan",
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	var outBuffer bytes.Buffer
+
+	cfg := sample.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Count: 5,
+		Seed:  42,
+	}
+
+	require.NoError(t, sample.Run(context.Background(), cfg))
+	first := outBuffer.String()
+	assert.Len(t, lines(first), 5)
+
+	// Same seed gives the same selection
+	outBuffer.Reset()
+	require.NoError(t, sample.Run(context.Background(), cfg))
+	assert.Equal(t, first, outBuffer.String())
+
+	// More than the number of entries
+	outBuffer.Reset()
+	cfg.Count = 100
+	require.NoError(t, sample.Run(context.Background(), cfg))
+	assert.Len(t, lines(outBuffer.String()), 26)
+
+	// Only files
+	outBuffer.Reset()
+	cfg.OnlyFiles = true
+	require.NoError(t, sample.Run(context.Background(), cfg))
+	assert.Len(t, lines(outBuffer.String()), 15)
+
+	// Matching an expression
+	outBuffer.Reset()
+	cfg.Count = 2
+	exp, err := search.NewShellPattern("a/*", false, false)
+	require.NoError(t, err)
+	cfg.Expression = exp
+	require.NoError(t, sample.Run(context.Background(), cfg))
+	for _, line := range lines(outBuffer.String()) {
+		assert.Contains(t, line, `"a/`)
+	}
+	assert.Len(t, lines(outBuffer.String()), 2)
+
+	// Hashes are required
+	cfg.OnlyHashed = true
+	assert.ErrorContains(t, sample.Run(context.Background(), cfg), "does not contain file signature hashes")
+
+	cfg.Count = 0
+	assert.Error(t, sample.Run(context.Background(), cfg))
+}
+
+func TestSampleOnlyHashed(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Root:            "../../testdata/scan",
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	var outBuffer bytes.Buffer
+
+	cfg := sample.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Count:      100,
+		OnlyHashed: true,
+	}
+
+	require.NoError(t, sample.Run(context.Background(), cfg))
+	result := lines(outBuffer.String())
+	assert.Len(t, result, 15)
+	assert.Contains(t, outBuffer.String(), "c7389462")
+	for _, line := range result {
+		assert.Len(t, strings.Split(line, ","), 6)
+	}
+}
+
+func lines(s string) []string {
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
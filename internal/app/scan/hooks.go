@@ -0,0 +1,137 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package scan
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/andrejacobs/ajfs/internal/archive"
+	"github.com/andrejacobs/ajfs/internal/path"
+)
+
+// Hooks are called while a snapshot is being created so that each discovered file can be processed
+// (e.g. thumbnailing or virus scanning) as part of the scan instead of walking the file hierarchy again.
+// An error returned by OnEntry or OnHash aborts the scan.
+type Hooks interface {
+	// Called after an entry has been written to the database. fullPath is the path that was walked.
+	OnEntry(ctx context.Context, fullPath string, pi path.Info) error
+
+	// Called after the file signature hash of a file has been calculated or reused from the hash cache.
+	OnHash(ctx context.Context, fullPath string, pi path.Info, hash []byte) error
+
+	// Called for each path that could not be read or hashed.
+	OnError(ctx context.Context, fullPath string, err error)
+}
+
+// HookFuncs implements [Hooks] using optional functions.
+type HookFuncs struct {
+	Entry func(ctx context.Context, fullPath string, pi path.Info) error
+	Hash  func(ctx context.Context, fullPath string, pi path.Info, hash []byte) error
+	Error func(ctx context.Context, fullPath string, err error)
+}
+
+// Hooks implementation.
+func (h HookFuncs) OnEntry(ctx context.Context, fullPath string, pi path.Info) error {
+	if h.Entry == nil {
+		return nil
+	}
+	return h.Entry(ctx, fullPath, pi)
+}
+
+// Hooks implementation.
+func (h HookFuncs) OnHash(ctx context.Context, fullPath string, pi path.Info, hash []byte) error {
+	if h.Hash == nil {
+		return nil
+	}
+	return h.Hash(ctx, fullPath, pi, hash)
+}
+
+// Hooks implementation.
+func (h HookFuncs) OnError(ctx context.Context, fullPath string, err error) {
+	if h.Error != nil {
+		h.Error(ctx, fullPath, err)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// ExecHook runs a shell command for each file that is written to the database.
+// Each "{}" in the command is replaced by the quoted full path of the file, which is also available in the
+// environment variable AJFS_PATH. Archive members are skipped because they don't exist on the file system.
+// A command that fails is reported to Stderr and does not abort the scan.
+type ExecHook struct {
+	Command string
+
+	Stdout io.Writer // Writer used for the standard out of the command
+	Stderr io.Writer // Writer used for the standard error of the command
+}
+
+// Hooks implementation.
+func (h *ExecHook) OnEntry(ctx context.Context, fullPath string, pi path.Info) error {
+	if !pi.IsFile() {
+		return nil
+	}
+	if _, _, ok := archive.Split(fullPath); ok {
+		return nil
+	}
+
+	command := strings.ReplaceAll(h.Command, "{}", shellQuote(fullPath))
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Stdout = h.Stdout
+	cmd.Stderr = h.Stderr
+	cmd.Env = append(os.Environ(), "AJFS_PATH="+fullPath)
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		fmt.Fprintf(h.Stderr, "failed to run the command %q for %q. %v\n", h.Command, fullPath, err)
+	}
+	return nil
+}
+
+// Hooks implementation.
+func (h *ExecHook) OnHash(ctx context.Context, fullPath string, pi path.Info, hash []byte) error {
+	return nil
+}
+
+// Hooks implementation.
+func (h *ExecHook) OnError(ctx context.Context, fullPath string, err error) {
+}
+
+// Quote the path so that the shell passes it as a single argument.
+func shellQuote(s string) string {
+	if runtime.GOOS == "windows" {
+		return `"` + s + `"`
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package scan
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+)
+
+// The hashes of an older snapshot that are reused for the files that have not changed since.
+// A nil *previousSnapshot is valid and never finds a hash.
+type previousSnapshot struct {
+	path   string
+	hashes map[string]previousHash // Keyed by the path relative to the root
+	reused int
+}
+
+// Hash of a file in the older snapshot.
+type previousHash struct {
+	size         uint64
+	modTime      time.Time
+	hash         []byte
+	calculatedAt time.Time // Zero when the older snapshot did not record the time
+}
+
+// Load the hashes from the older snapshot specified by cfg.PreviousDbPath.
+// The snapshot must have been hashed using the same algorithm.
+func openPreviousSnapshot(ctx context.Context, cfg Config) (*previousSnapshot, error) {
+	if cfg.PreviousDbPath == "" {
+		return nil, nil
+	}
+
+	dbf, err := db.OpenDatabase(cfg.PreviousDbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer dbf.Close()
+
+	if !dbf.Features().HasHashTable() {
+		return nil, fmt.Errorf("the previous snapshot %q does not contain file signature hashes", cfg.PreviousDbPath)
+	}
+
+	algo, err := dbf.HashTableAlgo()
+	if err != nil {
+		return nil, err
+	}
+	if algo != cfg.Algo {
+		return nil, fmt.Errorf("the previous snapshot %q was hashed using %s instead of %s",
+			cfg.PreviousDbPath, db.AlgoString(algo), db.AlgoString(cfg.Algo))
+	}
+
+	var hashTimes db.HashTimes
+	if dbf.Features().HasHashTimes() {
+		hashTimes, err = dbf.ReadHashTimes(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p := &previousSnapshot{
+		path:   cfg.PreviousDbPath,
+		hashes: make(map[string]previousHash, dbf.EntriesCount()),
+	}
+
+	err = dbf.ReadAllEntriesWithHashes(ctx, func(idx int, pi path.Info, hash []byte) error {
+		p.hashes[pi.Path] = previousHash{
+			size:         pi.Size,
+			modTime:      pi.ModTime,
+			hash:         hash,
+			calculatedAt: hashTimes[idx],
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.VerbosePrintln(fmt.Sprintf("Loaded %d hashes from the previous snapshot %q", len(p.hashes), p.path))
+	return p, nil
+}
+
+// Find the hash of the file if its path, size and last modification time match the older snapshot.
+// Also returns the time at which the hash was originally calculated, which is zero if it is not known.
+func (p *previousSnapshot) lookup(pi path.Info) ([]byte, time.Time, bool) {
+	if p == nil {
+		return nil, time.Time{}, false
+	}
+
+	prev, ok := p.hashes[pi.Path]
+	if !ok || prev.size != pi.Size || !prev.modTime.Equal(pi.ModTime) {
+		return nil, time.Time{}, false
+	}
+	p.reused++
+	return prev.hash, prev.calculatedAt, true
+}
+
+// Display the number of hashes that were reused.
+func (p *previousSnapshot) printReused(cfg Config) {
+	if p == nil || p.reused == 0 {
+		return
+	}
+	cfg.VerbosePrintln(fmt.Sprintf("Reused %d hashes from the previous snapshot %q", p.reused, p.path))
+}
@@ -35,11 +35,15 @@ import (
 	"time"
 
 	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/archive"
+	"github.com/andrejacobs/ajfs/internal/checkpoint"
 	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/errlog"
 	"github.com/andrejacobs/ajfs/internal/path"
 	"github.com/andrejacobs/ajfs/internal/scanner"
 	"github.com/andrejacobs/go-aj/ajhash"
 	"github.com/andrejacobs/go-aj/file"
+	"github.com/andrejacobs/go-aj/human"
 	"github.com/andrejacobs/go-aj/stats"
 	"github.com/schollz/progressbar/v3"
 )
@@ -49,14 +53,40 @@ type Config struct {
 	config.CommonConfig
 	config.FilterConfig
 
-	Root string // The path to be scanned.
+	Root  string // The path to be scanned.
+	Image bool   // Root is a disk image (or archive) and its contents are scanned instead.
 
 	ForceOverride bool // Override any existing database file.
 
+	ChecksumAlgo db.ChecksumAlgo // Algorithm used for the database file integrity checksum.
+	IdNamespace  string          // Namespace used to derive the entry identifiers (see db.NewIdNamespace). Empty derives them from the path only.
+
+	DirStats bool // Store the child counts and cumulative sizes for each directory.
+
+	SkipUnreadable bool // Record paths that can't be read due to permissions and continue scanning.
+	Sorted         bool // Write the entries in lexicographic path order instead of walk order.
+
+	RequireComplete bool // Fail the scan, without creating the database, when any path or file could not be read.
+
+	Archives bool // Record the members of tar and zip archives as virtual entries.
+
+	Hooks Hooks // [optional] Called for each entry, hash and error while scanning.
+
 	CalculateHashes bool        // Calculate file signature hashes.
 	Algo            ajhash.Algo // Algorithm to use for calculating the hashes.
+	HashCachePath   string      // Path to the cache used to reuse previously calculated hashes. Empty disables the cache.
+	PreviousDbPath  string      // [optional] Older snapshot whose hashes are reused for files with the same path, size and modification time.
+	SortHashes      bool        // Sort the hash table by hash once the database has been created.
+	HashTimes       bool        // Record the time at which each file signature hash was calculated.
 	hashFn          hashFn      // Hashing function
 
+	FlushInterval time.Duration // Interval at which calculated hashes are written to the database. 0 writes each hash immediately.
+	SyncPolicy    db.SyncPolicy // How often the database is synced to disk while the hashes are written.
+
+	SinglePass bool // Calculate the hashes while walking instead of reading all files again afterwards.
+
+	CheckpointInterval time.Duration // Interval at which the checkpoint file is written next to the database. 0 disables it.
+
 	DryRun   bool // Only display files and directories that would have been stored in the database.
 	InitOnly bool // The initial database will be created without long running processes (hashing).
 
@@ -68,15 +98,21 @@ type Config struct {
 type hashFn func(ctx context.Context, path string, hasher hash.Hash, w io.Writer) ([]byte, uint64, error)
 
 // Process the ajfs scan command.
-func Run(cfg Config) error {
+func Run(ctx context.Context, cfg Config) error {
 	if cfg.hashFn == nil {
-		cfg.hashFn = file.Hash
+		hasher := archive.NewHasher()
+		defer hasher.Close()
+		cfg.hashFn = hasher.Hash
 	}
 
 	if cfg.DryRun {
 		return dryRun(cfg)
 	}
 
+	if cfg.PreviousDbPath != "" && !cfg.CalculateHashes {
+		return fmt.Errorf("reusing the hashes of a previous snapshot requires the file signature hashes to be calculated")
+	}
+
 	cfg.VerbosePrintln(fmt.Sprintf("Scanning root path %q", cfg.Root))
 
 	exists, err := file.FileExists(cfg.DbPath)
@@ -95,19 +131,64 @@ func Run(cfg Config) error {
 		}
 	}
 
+	// Fail before creating the database when the previous snapshot can't be used
+	previous, err := openPreviousSnapshot(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
 	features := db.FeatureFlags(db.FeatureJustEntries)
 	if cfg.CalculateHashes {
 		features |= db.FeatureHashTable
 		cfg.VerbosePrintln("Will be creating a hash table")
+
+		if cfg.HashTimes {
+			features |= db.FeatureHashTimes
+			cfg.VerbosePrintln("Will be recording the time each hash was calculated")
+		}
+	}
+	if cfg.DirStats {
+		features |= db.FeatureDirStats
+		cfg.VerbosePrintln("Will be storing directory statistics")
 	}
 
 	cfg.VerbosePrintln(fmt.Sprintf("Creating database file at %q", cfg.DbPath))
-	dbf, err := db.CreateDatabase(cfg.DbPath, cfg.Root, db.FeatureFlags(features))
+	root := cfg.Root
+	if cfg.Image {
+		root = scanner.ImageRoot(cfg.Root)
+	}
+	dbf, err := db.CreateDatabaseWithIdNamespace(cfg.DbPath, root, db.FeatureFlags(features), cfg.ChecksumAlgo, db.CurrentVersion(), cfg.IdNamespace)
 	if err != nil {
 		return err
 	}
 
+	if cfg.Archives {
+		dbf.SetArchives()
+	}
+	if cfg.SortHashes && !cfg.InitOnly {
+		dbf.SortHashTableOnClose()
+	}
+	dbf.SetHashFlushInterval(cfg.FlushInterval)
+	dbf.SetSyncPolicy(cfg.SyncPolicy)
+
+	// Errors and the checkpoint from a previous database at the same path no longer apply
+	if err = errlog.Remove(errlog.PathFor(cfg.DbPath)); err != nil {
+		cfg.Errorln(err)
+	}
+	if err = checkpoint.Remove(checkpoint.PathFor(cfg.DbPath)); err != nil {
+		cfg.Errorln(err)
+	}
+
+	// The checkpoint is removed when the scan finishes, it only remains after a crash
+	cp := checkpoint.NewWriter(checkpoint.PathFor(cfg.DbPath), "scan", cfg.CheckpointInterval)
+	defer func() {
+		if err := cp.Close(); err != nil {
+			cfg.Errorln(err)
+		}
+	}()
+
 	safeToShutdown := false
+	incomplete := false
 
 	defer func() {
 		if safeToShutdown {
@@ -117,12 +198,14 @@ func Run(cfg Config) error {
 			}
 		} else {
 			// Close file and remove it since it is damaged
-			cfg.Errorln("\nApp was interrupted and the ajfs database file is incomplete. File will be deleted.")
+			if !incomplete {
+				cfg.Errorln("\nApp was interrupted and the ajfs database file is incomplete. File will be deleted.")
+			}
 			_ = dbf.Interrupted()
 		}
 	}()
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	// Hook into listening for the SIGINT (Ctrl+C) and SIGTERM signals
@@ -145,16 +228,91 @@ func Run(cfg Config) error {
 	s.DirIncluder = cfg.DirIncluder
 	s.FileExcluder = cfg.FileExcluder
 	s.DirExcluder = cfg.DirExcluder
+	s.Sorted = cfg.Sorted
+	s.Archives = cfg.Archives
+
+	if cfg.SkipUnreadable || cfg.RequireComplete {
+		s.Unreadable, err = errlog.Open(errlog.PathFor(cfg.DbPath))
+		if err != nil {
+			return err
+		}
+	}
 
 	cfg.ProgressPrintln("Scanning ...")
+	var inline *inlineHasher
+	if cfg.SinglePass && cfg.CalculateHashes && !cfg.InitOnly {
+		cfg.VerbosePrintln("Will be calculating the file signature hashes while scanning")
+		inline, err = newInlineHasher(cfg, previous)
+		if err != nil {
+			return err
+		}
+	}
+	unreadableSize := uint64(0)
+	if inline != nil || cfg.Metrics != nil || cfg.Hooks != nil || cfg.RequireComplete || cp != nil {
+		s.OnEntry = func(idx int, path string, pi path.Info) error {
+			cfg.Metrics.EntryScanned()
+			cp.Scanned(pi.Path)
+			if cfg.RequireComplete {
+				unreadable, err := checkReadable(s.Unreadable, path, pi)
+				if err != nil {
+					return err
+				}
+				if unreadable {
+					unreadableSize += pi.Size
+				}
+			}
+			if cfg.Hooks != nil {
+				if err := cfg.Hooks.OnEntry(ctx, path, pi); err != nil {
+					return err
+				}
+			}
+			if inline == nil {
+				return nil
+			}
+			return inline.onEntry(ctx, idx, path, pi)
+		}
+	}
+
 	startTime := time.Now()
-	if err = s.Scan(ctx, dbf); err != nil {
+	donePhase := cfg.StartPhase("scanning")
+	if cfg.Image {
+		err = s.ScanImage(ctx, dbf)
+	} else {
+		err = s.Scan(ctx, dbf)
+	}
+	if inline != nil {
+		inline.close()
+	}
+	if s.Unreadable != nil {
+		if closeErr := s.Unreadable.Close(); closeErr != nil {
+			cfg.Errorln(closeErr)
+		}
+	}
+	if err != nil {
 		return err
 	}
+	donePhase()
 	if cfg.Verbose {
 		stats.PrintTimeTaken(cfg.Stdout, "scanning", startTime, time.Now())
 	}
 
+	if s.Unreadable != nil && s.Unreadable.Count() > 0 {
+		printUnreadable(cfg, s.Unreadable, unreadableSize)
+		cfg.Metrics.AddErrors(s.Unreadable.Count())
+
+		if cfg.Hooks != nil {
+			for _, entry := range s.Unreadable.Entries() {
+				cfg.Hooks.OnError(ctx, filepath.Join(dbf.RootPath(), entry.Path), errors.New(entry.Error))
+			}
+		}
+
+		if cfg.RequireComplete {
+			incomplete = true
+			return fmt.Errorf("the scan is incomplete because %d paths could not be read and thus the database was not created",
+				s.Unreadable.Count())
+		}
+	}
+
 	safeToShutdown = true
 
 	if cfg.simulateScanningError {
@@ -170,7 +328,7 @@ func Run(cfg Config) error {
 	}
 
 	if cfg.CalculateHashes && (ctx.Err() == nil) {
-		if err = calculateHashes(ctx, cfg, dbf); err != nil {
+		if err = calculateHashes(ctx, cfg, dbf, inline, previous, cp); err != nil {
 			if !errors.Is(err, context.Canceled) {
 				return err
 			}
@@ -195,13 +353,18 @@ func Run(cfg Config) error {
 	return nil
 }
 
-func calculateHashes(ctx context.Context, cfg Config, dbf *db.DatabaseFile) error {
+// inline is only set when the hashes have already been calculated while scanning.
+// previous is only set when the hashes of unchanged files are copied from an older snapshot.
+// cp is only set when the checkpoint is written.
+func calculateHashes(ctx context.Context, cfg Config, dbf *db.DatabaseFile, inline *inlineHasher, previous *previousSnapshot,
+	cp *checkpoint.Writer) error {
 	if cfg.Verbose {
 		defer stats.MeasureElapsedTime(cfg.Stdout, "calculating file signatures", time.Now())
 	}
+	defer cfg.StartPhase("calculating file signatures")()
 
 	cfg.VerbosePrintln("Calculating file signature hashes ...")
-	cfg.VerbosePrintln(fmt.Sprintf("  Algorithm: %s", cfg.Algo))
+	cfg.VerbosePrintln(fmt.Sprintf("  Algorithm: %s", db.AlgoString(cfg.Algo)))
 
 	// Write the initial hash table
 	cfg.VerbosePrintln("Creating initial hash table ...")
@@ -218,26 +381,56 @@ func calculateHashes(ctx context.Context, cfg Config, dbf *db.DatabaseFile) erro
 		return nil
 	}
 
+	if inline != nil {
+		return inline.writeHashes(dbf)
+	}
+
 	var progress *progressbar.ProgressBar
 	count := 0
 	totalCount := uint64(0)
 
-	if cfg.Progress {
+	if cfg.Progress || cfg.Metrics != nil {
 		cfg.ProgressPrintln("Calculating progress information ...")
-		stats, err := dbf.CalculateStats()
+		stats, err := dbf.CalculateStats(ctx)
 		if err != nil {
 			return err
 		}
 
-		progress = progressbar.DefaultBytes(int64(stats.TotalFileSize)) //nolint:gosec // disable G115
+		if cfg.Progress {
+			progress = progressbar.DefaultBytes(int64(stats.TotalFileSize)) //nolint:gosec // disable G115
+		}
 		totalCount = stats.FileCount
+		cfg.Metrics.StartHashing(stats.FileCount, stats.TotalFileSize)
 	}
 
 	if cfg.simulateHashingError {
 		return fmt.Errorf("simulating an error while calculating file signature hashes")
 	}
 
-	err := dbf.EntriesNeedHashing(func(idx int, pi path.Info) error {
+	errLog, err := errlog.Open(errlog.PathFor(cfg.DbPath))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := errLog.Close(); err != nil {
+			cfg.Errorln(err)
+		}
+	}()
+
+	cache, err := cfg.OpenHashCache(cfg.HashCachePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := cache.Close(); err != nil {
+			cfg.Errorln(err)
+		}
+	}()
+	cached := 0
+	failed := 0
+
+	err = dbf.EntriesNeedHashing(ctx, func(idx int, pi path.Info) error {
+		cp.Hashing(idx, pi.Path)
 
 		if progress != nil {
 			progress.Describe(fmt.Sprintf("[%d/%d]", count+1, totalCount))
@@ -246,7 +439,33 @@ func calculateHashes(ctx context.Context, cfg Config, dbf *db.DatabaseFile) erro
 		}
 
 		path := filepath.Join(dbf.RootPath(), pi.Path)
-		hash, _, err := cfg.hashFn(ctx, path, cfg.Algo.Hasher(), progress)
+
+		if hash, calculatedAt, ok := previous.lookup(pi); ok {
+			if err := dbf.WriteHashEntryAt(idx, hash, calculatedAt); err != nil {
+				return fmt.Errorf("failed to write the hash for %q. %w", path, err)
+			}
+			if progress != nil {
+				_ = progress.Add64(int64(pi.Size)) //nolint:gosec // disable G115
+			}
+			cfg.Metrics.FileHashed(pi.Size)
+			count++
+			return cfg.onHash(ctx, path, pi, hash)
+		}
+
+		if hash, ok := cache.Lookup(path, pi.Size, pi.ModTime, cfg.Algo); ok {
+			if err := dbf.WriteHashEntry(idx, hash); err != nil {
+				return fmt.Errorf("failed to write the hash for %q. %w", path, err)
+			}
+			if progress != nil {
+				_ = progress.Add64(int64(pi.Size)) //nolint:gosec // disable G115
+			}
+			cfg.Metrics.FileHashed(pi.Size)
+			cached++
+			count++
+			return cfg.onHash(ctx, path, pi, hash)
+		}
+
+		hash, _, err := cfg.hashFn(ctx, path, db.AlgoHasher(cfg.Algo), progress)
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
 				return err
@@ -254,10 +473,25 @@ func calculateHashes(ctx context.Context, cfg Config, dbf *db.DatabaseFile) erro
 
 			// Continue hashing
 			fmt.Fprintf(cfg.Stderr, "failed to calculate the hash for %q. %v\n", path, err)
+			failed++
+			cfg.Metrics.FileFailed(pi.Size)
+			if cfg.Hooks != nil {
+				cfg.Hooks.OnError(ctx, path, err)
+			}
+			if err = errLog.Add(pi.Path, err); err != nil {
+				return err
+			}
 		} else {
 			if err = dbf.WriteHashEntry(idx, hash); err != nil {
 				return fmt.Errorf("failed to write the hash for %q. %w", path, err)
 			}
+			if err = cache.Add(path, pi.Size, pi.ModTime, cfg.Algo, hash); err != nil {
+				return err
+			}
+			cfg.Metrics.FileHashed(pi.Size)
+			if err = cfg.onHash(ctx, path, pi, hash); err != nil {
+				return err
+			}
 		}
 
 		count++
@@ -271,9 +505,63 @@ func calculateHashes(ctx context.Context, cfg Config, dbf *db.DatabaseFile) erro
 		return err
 	}
 
+	previous.printReused(cfg)
+	if cached > 0 {
+		cfg.VerbosePrintln(fmt.Sprintf("Reused %d hashes from the cache %q", cached, cache.Path()))
+	}
+	if failed > 0 {
+		fmt.Fprintf(cfg.Stderr, "Failed to calculate the hash for %d files, see %q\n", failed, errLog.Path())
+	}
+
 	return nil
 }
 
+// Call the OnHash hook if hooks were configured.
+func (cfg *Config) onHash(ctx context.Context, fullPath string, pi path.Info, hash []byte) error {
+	if cfg.Hooks == nil {
+		return nil
+	}
+	return cfg.Hooks.OnHash(ctx, fullPath, pi, hash)
+}
+
+// Print the summary of the paths that could not be read while scanning.
+// size is the combined size of the files that were found but could not be read.
+func printUnreadable(cfg Config, l *errlog.Log, size uint64) {
+	if size > 0 {
+		cfg.Errorln(fmt.Sprintf("Unable to read %d paths (files totalling %s), see %q", l.Count(), human.Bytes(size), l.Path()))
+	} else {
+		cfg.Errorln(fmt.Sprintf("Unable to read %d paths, see %q", l.Count(), l.Path()))
+	}
+	for _, entry := range l.Entries() {
+		cfg.Errorln(fmt.Sprintf("  %s", entry.Path))
+	}
+
+	if !privileged() {
+		cfg.Errorln("Not running with elevated privileges, scanning as root (e.g. using sudo) might be able to read these paths.")
+	}
+}
+
+// Check that the file can be opened for reading.
+// A file that can't be read because of a permission error is recorded in the log and true is returned.
+func checkReadable(l *errlog.Log, fullPath string, pi path.Info) (bool, error) {
+	if !pi.IsFile() {
+		return false, nil
+	}
+	if _, _, ok := archive.Split(fullPath); ok {
+		// Archive members are read through the archive itself
+		return false, nil
+	}
+
+	f, err := os.Open(fullPath)
+	if err != nil {
+		if !errors.Is(err, fs.ErrPermission) {
+			return false, nil
+		}
+		return true, l.Add(pi.Path, err)
+	}
+	return false, f.Close()
+}
+
 func dryRun(cfg Config) error {
 	cfg.VerbosePrintln(fmt.Sprintf("[DRY-RUN] Scan root path %q", cfg.Root))
 
@@ -284,15 +572,19 @@ func dryRun(cfg Config) error {
 	w.DirExcluder = cfg.DirExcluder
 
 	fn := func(rcvPath string, d fs.DirEntry, rcvErr error) error {
-		if rcvErr != nil {
-			return rcvErr
-		}
-
 		relPath, err := filepath.Rel(cfg.Root, rcvPath)
 		if err != nil {
 			return err
 		}
 
+		if rcvErr != nil {
+			if !cfg.SkipUnreadable || !errors.Is(rcvErr, fs.ErrPermission) || (relPath == ".") {
+				return rcvErr
+			}
+			cfg.Errorln(fmt.Sprintf("Unable to read %q. %v", relPath, rcvErr))
+			return fs.SkipDir
+		}
+
 		cfg.Println(relPath)
 
 		return nil
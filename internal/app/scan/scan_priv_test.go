@@ -23,6 +23,7 @@ package scan
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"fmt"
 	"hash"
 	"io"
@@ -33,8 +34,8 @@ import (
 	"github.com/andrejacobs/ajfs/internal/app/config"
 	"github.com/andrejacobs/ajfs/internal/app/resume"
 	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/errlog"
 	"github.com/andrejacobs/ajfs/internal/path"
-	"github.com/andrejacobs/ajfs/internal/testshared"
 	"github.com/andrejacobs/go-aj/ajhash"
 	"github.com/andrejacobs/go-aj/file"
 	"github.com/stretchr/testify/assert"
@@ -56,54 +57,98 @@ func TestScanWithErrors(t *testing.T) {
 
 	var err error
 	require.NotPanics(t, func() {
-		err = Run(cfg)
+		err = Run(context.Background(), cfg)
 	})
 
 	require.ErrorContains(t, err, "simulating an error while scanning")
 }
 
-func TestScanWithHashingErrors(t *testing.T) {
-	tempFile := filepath.Join(t.TempDir(), "unit-testing")
-	_ = os.Remove(tempFile)
-	defer os.Remove(tempFile)
+func initialConfig() Config {
+	cfg := Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		Root: "../../testdata/scan",
+	}
+	return cfg
+}
+
+func TestScanWithHashCache(t *testing.T) {
+	tempDir := t.TempDir()
 
 	cfg := initialConfig()
-	cfg.DbPath = tempFile
+	cfg.DbPath = filepath.Join(tempDir, "first.ajfs")
 	cfg.CalculateHashes = true
 	cfg.Algo = ajhash.AlgoSHA1
+	cfg.HashCachePath = filepath.Join(tempDir, "cache", "hashes.jsonl")
 
-	// Cause an error while hashing
-	cfg.simulateHashingError = true
+	count := 0
+	cfg.hashFn = func(ctx context.Context, path string, hasher hash.Hash, w io.Writer) ([]byte, uint64, error) {
+		count++
+		return file.Hash(ctx, path, hasher, w)
+	}
 
-	err := Run(cfg)
-	require.Error(t, err)
+	require.NoError(t, Run(context.Background(), cfg))
+	expCount := count
+	assert.Greater(t, expCount, 0)
 
-	// Validate: Expect the database to still be valid
-	paths, err := testshared.DatabasePaths(cfg.DbPath)
-	require.NoError(t, err)
+	expHashes := readHashes(t, cfg.DbPath)
+	assert.Len(t, expHashes, expCount)
 
-	expPaths, err := testshared.ExpectedPaths(cfg.Root, nil)
+	// All the hashes are reused from the cache
+	count = 0
+	cfg.DbPath = filepath.Join(tempDir, "second.ajfs")
+	require.NoError(t, Run(context.Background(), cfg))
+	assert.Equal(t, 0, count)
+
+	assert.Equal(t, expHashes, readHashes(t, cfg.DbPath))
+
+	// A different algorithm can't reuse the hashes
+	cfg.DbPath = filepath.Join(tempDir, "third.ajfs")
+	cfg.Algo = ajhash.AlgoSHA256
+	require.NoError(t, Run(context.Background(), cfg))
+	assert.Equal(t, expCount, count)
+
+	// Cache disabled
+	count = 0
+	cfg.DbPath = filepath.Join(tempDir, "fourth.ajfs")
+	cfg.HashCachePath = ""
+	require.NoError(t, Run(context.Background(), cfg))
+	assert.Equal(t, expCount, count)
+}
+
+// Map from path to the hex encoded file signature hash.
+func readHashes(t *testing.T, dbPath string) map[string]string {
+	dbf, err := db.OpenDatabase(dbPath)
 	require.NoError(t, err)
+	defer dbf.Close()
 
-	assert.ElementsMatch(t, expPaths, paths)
+	result := make(map[string]string)
+	err = dbf.ReadAllEntriesWithHashes(context.Background(), func(idx int, pi path.Info, hash []byte) error {
+		result[pi.Path] = hex.EncodeToString(hash)
+		return nil
+	})
+	require.NoError(t, err)
+	return result
 }
 
-func TestScanWithHashingErrorsShouldBeAbleToContinue(t *testing.T) {
-	tempFile := filepath.Join(t.TempDir(), "unit-testing")
-	_ = os.Remove(tempFile)
-	defer os.Remove(tempFile)
+func TestScanSinglePass(t *testing.T) {
+	tempDir := t.TempDir()
 
 	cfg := initialConfig()
-	cfg.DbPath = tempFile
+	cfg.DbPath = filepath.Join(tempDir, "two-pass.ajfs")
 	cfg.CalculateHashes = true
 	cfg.Algo = ajhash.AlgoSHA1
+	require.NoError(t, Run(context.Background(), cfg))
+	expHashes := readHashes(t, cfg.DbPath)
 
 	// Cause an error while hashing
 	const expErrMsg = "simulating a file hashing that failed"
 	count := 0
 	cfg.hashFn = func(ctx context.Context, path string, hasher hash.Hash, w io.Writer) ([]byte, uint64, error) {
 		count++
-		if count == 3 || count == 7 {
+		if count == 2 {
 			return nil, 0, fmt.Errorf(expErrMsg)
 		}
 		return file.Hash(ctx, path, hasher, w)
@@ -111,61 +156,21 @@ func TestScanWithHashingErrorsShouldBeAbleToContinue(t *testing.T) {
 
 	var errOutput bytes.Buffer
 	cfg.Stderr = &errOutput
-
-	err := Run(cfg)
-	require.NoError(t, err)
-
+	cfg.DbPath = filepath.Join(tempDir, "single-pass.ajfs")
+	cfg.SinglePass = true
+	require.NoError(t, Run(context.Background(), cfg))
+	assert.Equal(t, len(expHashes), count)
 	require.Contains(t, errOutput.String(), expErrMsg)
 
-	// Validate: Expect the database to still be valid
-	paths, err := testshared.DatabasePaths(cfg.DbPath)
-	require.NoError(t, err)
-
-	expPaths, err := testshared.ExpectedPaths(cfg.Root, nil)
-	require.NoError(t, err)
-
-	assert.ElementsMatch(t, expPaths, paths)
-
-	// Count incomplete hashes
-	dbf, err := db.OpenDatabase(cfg.DbPath)
+	errLog, err := errlog.Open(errlog.PathFor(cfg.DbPath))
 	require.NoError(t, err)
+	assert.Equal(t, 1, errLog.Count())
+	require.NoError(t, errLog.Close())
 
-	count = 0
-	err = dbf.ReadHashTableEntries(func(idx int, hash []byte) error {
-		if ajhash.AllZeroBytes(hash) {
-			count++
-		}
-		return nil
-	})
-	require.NoError(t, dbf.Close())
-	require.NoError(t, err)
-	require.Equal(t, 2, count)
-
-	// Resume
+	// Resume calculates the hash that failed
 	cfg.Stderr = io.Discard
-	err = resume.Run(resume.Config{CommonConfig: cfg.CommonConfig})
+	err = resume.Run(context.Background(), resume.Config{CommonConfig: cfg.CommonConfig, RetryErrors: true})
 	require.NoError(t, err)
 
-	dbf, err = db.OpenDatabase(cfg.DbPath)
-	require.NoError(t, err)
-	defer dbf.Close()
-
-	count = 0
-	err = dbf.EntriesNeedHashing(func(idx int, pi path.Info) error {
-		count++
-		return nil
-	})
-	require.NoError(t, err)
-	require.Equal(t, 0, count)
-}
-
-func initialConfig() Config {
-	cfg := Config{
-		CommonConfig: config.CommonConfig{
-			Stdout: io.Discard,
-			Stderr: io.Discard,
-		},
-		Root: "../../testdata/scan",
-	}
-	return cfg
+	assert.Equal(t, expHashes, readHashes(t, cfg.DbPath))
 }
@@ -22,17 +22,29 @@ package scan_test
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha1"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/resume"
 	"github.com/andrejacobs/ajfs/internal/app/scan"
 	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/errlog"
+	"github.com/andrejacobs/ajfs/internal/path"
 	"github.com/andrejacobs/ajfs/internal/testshared"
 	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/andrejacobs/go-aj/file"
 	"github.com/andrejacobs/go-aj/random"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -46,7 +58,7 @@ func TestExistingFile(t *testing.T) {
 	cfg := initialConfig()
 	cfg.DbPath = tempFile
 
-	err = scan.Run(cfg)
+	err = scan.Run(context.Background(), cfg)
 	assert.ErrorContains(t, err, "file already exists at")
 }
 
@@ -59,7 +71,7 @@ func TestOverrideExistingFile(t *testing.T) {
 	cfg.DbPath = tempFile
 	cfg.ForceOverride = true
 
-	err = scan.Run(cfg)
+	err = scan.Run(context.Background(), cfg)
 	assert.NoError(t, err)
 }
 
@@ -71,7 +83,7 @@ func TestScan(t *testing.T) {
 	cfg := initialConfig()
 	cfg.DbPath = tempFile
 
-	err := scan.Run(cfg)
+	err := scan.Run(context.Background(), cfg)
 	require.NoError(t, err)
 
 	// Validate
@@ -96,7 +108,7 @@ func TestScanEmptyDir(t *testing.T) {
 	cfg.DbPath = tempFile
 	cfg.Root = scanDir
 
-	err = scan.Run(cfg)
+	err = scan.Run(context.Background(), cfg)
 	require.NoError(t, err)
 
 	paths, err := testshared.DatabasePaths(cfg.DbPath)
@@ -119,10 +131,14 @@ func TestScanWithHashes(t *testing.T) {
 			algo:         ajhash.AlgoSHA256,
 			hashDeepFile: "../../testdata/expected/scan.sha256",
 		},
+		{
+			algo:         db.AlgoMD5,
+			hashDeepFile: "../../testdata/expected/scan.md5",
+		},
 		// Can't test SHA-512 atm because hashdeep doesn't support it
 	}
 	for _, tC := range testCases {
-		t.Run(tC.algo.String(), func(t *testing.T) {
+		t.Run(db.AlgoString(tC.algo), func(t *testing.T) {
 			algo := tC.algo
 
 			tempFile := filepath.Join(t.TempDir(), "unit-testing")
@@ -134,7 +150,7 @@ func TestScanWithHashes(t *testing.T) {
 			cfg.CalculateHashes = true
 			cfg.Algo = algo
 
-			err := scan.Run(cfg)
+			err := scan.Run(context.Background(), cfg)
 			require.NoError(t, err)
 
 			// Validate
@@ -159,7 +175,7 @@ func TestScanWithHashes(t *testing.T) {
 			require.NoError(t, err)
 			defer dbf.Close()
 
-			ht, err := dbf.ReadHashTable()
+			ht, err := dbf.ReadHashTable(context.Background())
 			require.NoError(t, err)
 
 			result := make(map[string]string, len(ht))
@@ -176,6 +192,43 @@ func TestScanWithHashes(t *testing.T) {
 	}
 }
 
+func TestScanSortHashes(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+
+	cfg := initialConfig()
+	cfg.DbPath = tempFile
+	cfg.CalculateHashes = true
+	cfg.Algo = ajhash.AlgoSHA1
+	cfg.SortHashes = true
+
+	require.NoError(t, scan.Run(context.Background(), cfg))
+
+	dbf, err := db.OpenDatabase(cfg.DbPath)
+	require.NoError(t, err)
+	defer dbf.Close()
+	assert.True(t, dbf.HashTableSorted())
+
+	expectedHashDeep, err := testshared.ReadHashDeepFile("../../testdata/expected/scan.sha1")
+	require.NoError(t, err)
+
+	for _, hd := range expectedHashDeep {
+		hash, err := hex.DecodeString(hd.Hash)
+		require.NoError(t, err)
+
+		indices, err := dbf.FindHash(context.Background(), hash)
+		require.NoError(t, err)
+		require.NotEmpty(t, indices, hd.Path)
+
+		var paths []string
+		for _, idx := range indices {
+			pi, err := dbf.ReadEntryAtIndex(idx)
+			require.NoError(t, err)
+			paths = append(paths, pi.Path)
+		}
+		assert.Contains(t, paths, hd.Path)
+	}
+}
+
 func TestScanInitOnly(t *testing.T) {
 	testCases := []struct {
 		algo ajhash.Algo
@@ -202,7 +255,7 @@ func TestScanInitOnly(t *testing.T) {
 			cfg.Algo = tC.algo
 			cfg.InitOnly = true
 
-			err := scan.Run(cfg)
+			err := scan.Run(context.Background(), cfg)
 			require.NoError(t, err)
 
 			// Verify
@@ -215,7 +268,7 @@ func TestScanInitOnly(t *testing.T) {
 			require.NoError(t, err)
 			assert.Equal(t, tC.algo, algo)
 
-			ht, err := dbf.ReadHashTable()
+			ht, err := dbf.ReadHashTable(context.Background())
 			require.NoError(t, err)
 			assert.Empty(t, ht)
 		})
@@ -237,7 +290,7 @@ func TestScanVerbose(t *testing.T) {
 	cfg.CalculateHashes = true
 	cfg.Algo = ajhash.AlgoSHA1
 
-	err := scan.Run(cfg)
+	err := scan.Run(context.Background(), cfg)
 	require.NoError(t, err)
 
 	outStr := out.String()
@@ -250,8 +303,367 @@ func TestScanVerbose(t *testing.T) {
 	assert.Contains(t, outStr, "Done!")
 }
 
+func TestScanMetrics(t *testing.T) {
+	cfg := initialConfig()
+	cfg.DbPath = filepath.Join(t.TempDir(), "unit-testing")
+	cfg.CalculateHashes = true
+	cfg.Algo = ajhash.AlgoSHA1
+	cfg.Metrics = &config.Metrics{}
+
+	require.NoError(t, scan.Run(context.Background(), cfg))
+
+	var out bytes.Buffer
+	_, err := cfg.Metrics.WriteTo(&out)
+	require.NoError(t, err)
+
+	outStr := out.String()
+	assert.Contains(t, outStr, "ajfs_entries_scanned_total 26\n")
+	assert.Contains(t, outStr, "ajfs_files_hashed_total 15\n")
+	assert.Contains(t, outStr, "ajfs_files_to_hash 15\n")
+	assert.Contains(t, outStr, "ajfs_errors_total 0\n")
+	assert.Contains(t, outStr, "ajfs_eta_seconds 0\n")
+}
+
+func TestScanHooks(t *testing.T) {
+	for _, singlePass := range []bool{false, true} {
+		t.Run(fmt.Sprintf("single-pass=%v", singlePass), func(t *testing.T) {
+			cfg := initialConfig()
+			cfg.DbPath = filepath.Join(t.TempDir(), "unit-testing")
+			cfg.CalculateHashes = true
+			cfg.Algo = ajhash.AlgoSHA1
+			cfg.SinglePass = singlePass
+
+			root, err := filepath.Abs(cfg.Root)
+			require.NoError(t, err)
+
+			entries := make(map[string]bool)
+			hashes := make(map[string]string)
+			cfg.Hooks = scan.HookFuncs{
+				Entry: func(ctx context.Context, fullPath string, pi path.Info) error {
+					entries[pi.Path] = true
+					assert.Equal(t, filepath.Join(root, pi.Path), fullPath)
+					return nil
+				},
+				Hash: func(ctx context.Context, fullPath string, pi path.Info, hash []byte) error {
+					hashes[pi.Path] = hex.EncodeToString(hash)
+					return nil
+				},
+			}
+
+			require.NoError(t, scan.Run(context.Background(), cfg))
+
+			expPaths, err := testshared.ExpectedPaths(cfg.Root, nil)
+			require.NoError(t, err)
+			assert.Len(t, entries, len(expPaths))
+
+			dbf, err := db.OpenDatabase(cfg.DbPath)
+			require.NoError(t, err)
+			defer dbf.Close()
+
+			ht, err := dbf.ReadHashTable(context.Background())
+			require.NoError(t, err)
+			require.Len(t, hashes, len(ht))
+			for idx, hash := range ht {
+				pi, err := dbf.ReadEntryAtIndex(idx)
+				require.NoError(t, err)
+				assert.Equal(t, hex.EncodeToString(hash), hashes[pi.Path])
+			}
+		})
+	}
+}
+
+func TestScanHookAborts(t *testing.T) {
+	cfg := initialConfig()
+	cfg.DbPath = filepath.Join(t.TempDir(), "unit-testing")
+	cfg.Hooks = scan.HookFuncs{
+		Entry: func(ctx context.Context, fullPath string, pi path.Info) error {
+			return errors.New("hook failed")
+		},
+	}
+
+	err := scan.Run(context.Background(), cfg)
+	assert.ErrorContains(t, err, "hook failed")
+	assert.NoFileExists(t, cfg.DbPath)
+}
+
+func TestScanExecHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test command requires a POSIX shell")
+	}
+
+	outPath := filepath.Join(t.TempDir(), "exec.txt")
+
+	cfg := initialConfig()
+	cfg.DbPath = filepath.Join(t.TempDir(), "unit-testing")
+	cfg.Hooks = &scan.ExecHook{
+		Command: `test "$AJFS_PATH" = {} && echo {} >> ` + outPath,
+		Stdout:  io.Discard,
+		Stderr:  io.Discard,
+	}
+
+	require.NoError(t, scan.Run(context.Background(), cfg))
+
+	data, err := os.ReadFile(outPath)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	root, err := filepath.Abs(cfg.Root)
+	require.NoError(t, err)
+	expPaths, err := testshared.ExpectedPaths(cfg.Root, nil)
+	require.NoError(t, err)
+	expected := make([]string, 0, len(expPaths))
+	for _, pi := range expPaths {
+		if pi.IsFile() {
+			expected = append(expected, filepath.Join(root, pi.Path))
+		}
+	}
+
+	assert.ElementsMatch(t, expected, lines)
+}
+
 //-----------------------------------------------------------------------------
 
+func TestScanExcludeFromDb(t *testing.T) {
+	for _, singlePass := range []bool{false, true} {
+		t.Run(fmt.Sprintf("single-pass=%v", singlePass), func(t *testing.T) {
+			root := t.TempDir()
+			modTime := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+			for _, name := range []string{"unchanged.txt", "touched.txt"} {
+				p := filepath.Join(root, name)
+				require.NoError(t, os.WriteFile(p, []byte("original"), 0o644))
+				require.NoError(t, os.Chtimes(p, modTime, modTime))
+			}
+
+			prevCfg := initialConfig()
+			prevCfg.Root = root
+			prevCfg.DbPath = filepath.Join(t.TempDir(), "previous.ajfs")
+			prevCfg.CalculateHashes = true
+			prevCfg.Algo = ajhash.AlgoSHA1
+			prevCfg.HashTimes = true
+			require.NoError(t, scan.Run(context.Background(), prevCfg))
+
+			// Same size and modification time, thus the previous (now stale) hash is expected to be reused
+			p := filepath.Join(root, "unchanged.txt")
+			require.NoError(t, os.WriteFile(p, []byte("modified"), 0o644))
+			require.NoError(t, os.Chtimes(p, modTime, modTime))
+
+			// Modification time changed, thus needs to be hashed again
+			p = filepath.Join(root, "touched.txt")
+			require.NoError(t, os.WriteFile(p, []byte("modified"), 0o644))
+			require.NoError(t, os.WriteFile(filepath.Join(root, "new.txt"), []byte("modified"), 0o644))
+
+			cfg := initialConfig()
+			cfg.Root = root
+			cfg.DbPath = filepath.Join(t.TempDir(), "unit-testing")
+			cfg.CalculateHashes = true
+			cfg.Algo = ajhash.AlgoSHA1
+			cfg.HashTimes = true
+			cfg.SinglePass = singlePass
+			cfg.PreviousDbPath = prevCfg.DbPath
+			require.NoError(t, scan.Run(context.Background(), cfg))
+
+			prevHashes, prevTimes := readHashesAndTimes(t, prevCfg.DbPath)
+			hashes, times := readHashesAndTimes(t, cfg.DbPath)
+
+			sum := sha1.Sum([]byte("modified"))
+			modified := hex.EncodeToString(sum[:])
+			assert.Len(t, hashes, 3)
+			assert.Equal(t, prevHashes["unchanged.txt"], hashes["unchanged.txt"])
+			assert.True(t, prevTimes["unchanged.txt"].Equal(times["unchanged.txt"]))
+			assert.Equal(t, modified, hashes["touched.txt"])
+			assert.Equal(t, modified, hashes["new.txt"])
+		})
+	}
+}
+
+func TestScanExcludeFromDbAlgoMismatch(t *testing.T) {
+	prevCfg := initialConfig()
+	prevCfg.DbPath = filepath.Join(t.TempDir(), "previous.ajfs")
+	prevCfg.CalculateHashes = true
+	prevCfg.Algo = ajhash.AlgoSHA1
+	require.NoError(t, scan.Run(context.Background(), prevCfg))
+
+	cfg := initialConfig()
+	cfg.DbPath = filepath.Join(t.TempDir(), "unit-testing")
+	cfg.CalculateHashes = true
+	cfg.Algo = ajhash.AlgoSHA256
+	cfg.PreviousDbPath = prevCfg.DbPath
+	assert.ErrorContains(t, scan.Run(context.Background(), cfg), "was hashed using SHA-1 instead of SHA-256")
+	assert.NoFileExists(t, cfg.DbPath)
+
+	cfg.DbPath = filepath.Join(t.TempDir(), "unit-testing")
+	cfg.CalculateHashes = false
+	assert.Error(t, scan.Run(context.Background(), cfg))
+}
+
+func TestScanRequireComplete(t *testing.T) {
+	cfg := initialConfig()
+	cfg.DbPath = filepath.Join(t.TempDir(), "unit-testing")
+	cfg.RequireComplete = true
+	require.NoError(t, scan.Run(context.Background(), cfg))
+	assert.FileExists(t, cfg.DbPath)
+
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for the root user")
+	}
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "readable.txt"), []byte("readable"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0o200))
+	locked := filepath.Join(root, "locked")
+	require.NoError(t, os.Mkdir(locked, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(locked, "file.txt"), []byte("hidden"), 0o644))
+	require.NoError(t, os.Chmod(locked, 0))
+	defer os.Chmod(locked, 0o755) //nolint:errcheck
+
+	var errBuffer bytes.Buffer
+	cfg = initialConfig()
+	cfg.Stderr = &errBuffer
+	cfg.Root = root
+	cfg.DbPath = filepath.Join(t.TempDir(), "unit-testing")
+	cfg.RequireComplete = true
+	assert.ErrorContains(t, scan.Run(context.Background(), cfg), "2 paths could not be read")
+	assert.NoFileExists(t, cfg.DbPath)
+
+	out := errBuffer.String()
+	assert.Contains(t, out, "Unable to read 2 paths (files totalling 6 B)")
+	assert.Contains(t, out, "  locked\n")
+	assert.Contains(t, out, "  secret.txt\n")
+	assert.NotContains(t, out, "App was interrupted")
+
+	// Without requiring a complete scan the unreadable file is recorded as usual
+	cfg.Stderr = io.Discard
+	cfg.RequireComplete = false
+	cfg.SkipUnreadable = true
+	require.NoError(t, scan.Run(context.Background(), cfg))
+	assert.FileExists(t, cfg.DbPath)
+}
+
+// Read the hex encoded hashes and the times they were calculated keyed by path.
+func TestScanWithHashingErrors(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+	_ = os.Remove(tempFile)
+	defer os.Remove(tempFile)
+
+	cfg := initialConfig()
+	cfg.DbPath = tempFile
+	cfg.CalculateHashes = true
+	cfg.Algo = ajhash.AlgoSHA1
+
+	// Cause an error while hashing
+	scan.SimulateHashingError(&cfg)
+
+	err := scan.Run(context.Background(), cfg)
+	require.Error(t, err)
+
+	// Validate: Expect the database to still be valid
+	paths, err := testshared.DatabasePaths(cfg.DbPath)
+	require.NoError(t, err)
+
+	expPaths, err := testshared.ExpectedPaths(cfg.Root, nil)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, expPaths, paths)
+}
+
+func TestScanWithHashingErrorsShouldBeAbleToContinue(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+	_ = os.Remove(tempFile)
+	defer os.Remove(tempFile)
+
+	cfg := initialConfig()
+	cfg.DbPath = tempFile
+	cfg.CalculateHashes = true
+	cfg.Algo = ajhash.AlgoSHA1
+
+	// Cause an error while hashing
+	const expErrMsg = "simulating a file hashing that failed"
+	count := 0
+	scan.SetHashFn(&cfg, func(ctx context.Context, path string, hasher hash.Hash, w io.Writer) ([]byte, uint64, error) {
+		count++
+		if count == 3 || count == 7 {
+			return nil, 0, fmt.Errorf(expErrMsg)
+		}
+		return file.Hash(ctx, path, hasher, w)
+	})
+
+	var errOutput bytes.Buffer
+	cfg.Stderr = &errOutput
+
+	err := scan.Run(context.Background(), cfg)
+	require.NoError(t, err)
+
+	require.Contains(t, errOutput.String(), expErrMsg)
+
+	// Validate: Expect the database to still be valid
+	paths, err := testshared.DatabasePaths(cfg.DbPath)
+	require.NoError(t, err)
+
+	expPaths, err := testshared.ExpectedPaths(cfg.Root, nil)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, expPaths, paths)
+
+	// Count incomplete hashes
+	dbf, err := db.OpenDatabase(cfg.DbPath)
+	require.NoError(t, err)
+
+	count = 0
+	err = dbf.ReadHashTableEntries(context.Background(), func(idx int, hash []byte) error {
+		if ajhash.AllZeroBytes(hash) {
+			count++
+		}
+		return nil
+	})
+	require.NoError(t, dbf.Close())
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	// The failed files are recorded in the error log
+	errLog, err := errlog.Open(errlog.PathFor(cfg.DbPath))
+	require.NoError(t, err)
+	assert.Equal(t, 2, errLog.Count())
+	require.NoError(t, errLog.Close())
+
+	// Resume and retry the failed files
+	cfg.Stderr = io.Discard
+	err = resume.Run(context.Background(), resume.Config{CommonConfig: cfg.CommonConfig, RetryErrors: true})
+	require.NoError(t, err)
+
+	dbf, err = db.OpenDatabase(cfg.DbPath)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	count = 0
+	err = dbf.EntriesNeedHashing(context.Background(), func(idx int, pi path.Info) error {
+		count++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+}
+
+func readHashesAndTimes(t *testing.T, dbPath string) (map[string]string, map[string]time.Time) {
+	t.Helper()
+
+	dbf, err := db.OpenDatabase(dbPath)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	times, err := dbf.ReadHashTimes(context.Background())
+	require.NoError(t, err)
+
+	hashes := make(map[string]string)
+	pathTimes := make(map[string]time.Time)
+	err = dbf.ReadAllEntriesWithHashes(context.Background(), func(idx int, pi path.Info, hash []byte) error {
+		hashes[pi.Path] = hex.EncodeToString(hash)
+		pathTimes[pi.Path] = times[idx]
+		return nil
+	})
+	require.NoError(t, err)
+	return hashes, pathTimes
+}
+
 func initialConfig() scan.Config {
 	cfg := scan.Config{
 		CommonConfig: config.CommonConfig{
@@ -0,0 +1,180 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/errlog"
+	"github.com/andrejacobs/ajfs/internal/hashcache"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/schollz/progressbar/v3"
+)
+
+// inlineHasher calculates the file signature hashes while the file hierarchy is being walked.
+// The hash table is only written once all entries are known and thus the hashes are kept
+// in memory until then.
+type inlineHasher struct {
+	cfg      Config
+	cache    *hashcache.Cache
+	previous *previousSnapshot
+	progress *progressbar.ProgressBar
+
+	hashes   map[int][]byte    // map from path entry index to the calculated hash
+	reusedAt map[int]time.Time // map from path entry index to the time a hash from the previous snapshot was calculated
+	failed   []failedHash
+	cached   int
+}
+
+// A file for which the hash could not be calculated.
+type failedHash struct {
+	path string // Path relative to the root
+	err  error
+}
+
+func newInlineHasher(cfg Config, previous *previousSnapshot) (*inlineHasher, error) {
+	cache, err := cfg.OpenHashCache(cfg.HashCachePath)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &inlineHasher{
+		cfg:      cfg,
+		cache:    cache,
+		previous: previous,
+		hashes:   make(map[int][]byte),
+		reusedAt: make(map[int]time.Time),
+	}
+
+	if cfg.Progress {
+		// The total size is not known until the walk has finished
+		h.progress = progressbar.DefaultBytes(-1, "scanning and hashing")
+	}
+
+	return h, nil
+}
+
+// Called by the scanner for each entry written to the database.
+func (h *inlineHasher) onEntry(ctx context.Context, idx int, fullPath string, pi path.Info) error {
+	if !pi.IsFile() {
+		return nil
+	}
+
+	if hash, calculatedAt, ok := h.previous.lookup(pi); ok {
+		h.hashes[idx] = hash
+		h.reusedAt[idx] = calculatedAt
+		if h.progress != nil {
+			_ = h.progress.Add64(int64(pi.Size)) //nolint:gosec // disable G115
+		}
+		h.cfg.Metrics.FileHashed(pi.Size)
+		return h.cfg.onHash(ctx, fullPath, pi, hash)
+	}
+
+	if hash, ok := h.cache.Lookup(fullPath, pi.Size, pi.ModTime, h.cfg.Algo); ok {
+		h.hashes[idx] = hash
+		if h.progress != nil {
+			_ = h.progress.Add64(int64(pi.Size)) //nolint:gosec // disable G115
+		}
+		h.cfg.Metrics.FileHashed(pi.Size)
+		h.cached++
+		return h.cfg.onHash(ctx, fullPath, pi, hash)
+	}
+
+	if h.progress == nil {
+		h.cfg.VerbosePrintln(fmt.Sprintf("Hashing %q", pi.Path))
+	}
+
+	hash, _, err := h.cfg.hashFn(ctx, fullPath, db.AlgoHasher(h.cfg.Algo), h.progress)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+
+		// Continue scanning, the hash can be calculated later using resume
+		fmt.Fprintf(h.cfg.Stderr, "failed to calculate the hash for %q. %v\n", fullPath, err)
+		h.failed = append(h.failed, failedHash{path: pi.Path, err: err})
+		h.cfg.Metrics.FileFailed(pi.Size)
+		if h.cfg.Hooks != nil {
+			h.cfg.Hooks.OnError(ctx, fullPath, err)
+		}
+		return nil
+	}
+
+	h.hashes[idx] = hash
+	h.cfg.Metrics.FileHashed(pi.Size)
+	if err = h.cache.Add(fullPath, pi.Size, pi.ModTime, h.cfg.Algo, hash); err != nil {
+		return err
+	}
+	return h.cfg.onHash(ctx, fullPath, pi, hash)
+}
+
+// Finish hashing and release the resources.
+func (h *inlineHasher) close() {
+	if h.progress != nil {
+		_ = h.progress.Finish()
+	}
+	if err := h.cache.Close(); err != nil {
+		h.cfg.Errorln(err)
+	}
+}
+
+// Write the hashes calculated during the walk to the initial hash table.
+func (h *inlineHasher) writeHashes(dbf *db.DatabaseFile) error {
+	for idx, hash := range h.hashes {
+		calculatedAt, reused := h.reusedAt[idx]
+		if !reused {
+			calculatedAt = time.Now()
+		}
+		if err := dbf.WriteHashEntryAt(idx, hash, calculatedAt); err != nil {
+			return fmt.Errorf("failed to write the hash for entry %d. %w", idx, err)
+		}
+	}
+
+	h.previous.printReused(h.cfg)
+	if h.cached > 0 {
+		h.cfg.VerbosePrintln(fmt.Sprintf("Reused %d hashes from the cache %q", h.cached, h.cache.Path()))
+	}
+
+	if len(h.failed) == 0 {
+		return nil
+	}
+
+	errLog, err := errlog.Open(errlog.PathFor(h.cfg.DbPath))
+	if err != nil {
+		return err
+	}
+	for _, f := range h.failed {
+		if err = errLog.Add(f.path, f.err); err != nil {
+			_ = errLog.Close()
+			return err
+		}
+	}
+	if err = errLog.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(h.cfg.Stderr, "Failed to calculate the hash for %d files, see %q\n", len(h.failed), errLog.Path())
+	return nil
+}
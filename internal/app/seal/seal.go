@@ -0,0 +1,69 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package seal provides the functionality for ajfs seal command.
+package seal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/db"
+)
+
+// Config for the ajfs seal command.
+type Config struct {
+	config.CommonConfig
+
+	Unseal bool // Remove the seal instead.
+}
+
+// Process the ajfs seal command.
+func Run(ctx context.Context, cfg Config) error {
+	// Ensure it is a valid database before changing the header
+	dbf, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+	sealed := dbf.Sealed()
+	if err = dbf.Close(); err != nil {
+		return err
+	}
+
+	if sealed == !cfg.Unseal {
+		if sealed {
+			cfg.VerbosePrintln(fmt.Sprintf("The database %q is already sealed", cfg.DbPath))
+		} else {
+			cfg.VerbosePrintln(fmt.Sprintf("The database %q is not sealed", cfg.DbPath))
+		}
+		return nil
+	}
+
+	if err = db.SetSealed(cfg.DbPath, !cfg.Unseal); err != nil {
+		return err
+	}
+
+	if cfg.Unseal {
+		cfg.VerbosePrintln(fmt.Sprintf("Unsealed %q", cfg.DbPath))
+	} else {
+		cfg.VerbosePrintln(fmt.Sprintf("Sealed %q", cfg.DbPath))
+	}
+	return nil
+}
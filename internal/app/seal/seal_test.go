@@ -0,0 +1,77 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package seal_test
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/resume"
+	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/app/seal"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.ajfs")
+
+	commonCfg := config.CommonConfig{
+		DbPath: dbPath,
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+	}
+
+	scanCfg := scan.Config{
+		CommonConfig: commonCfg,
+		Root:         "../../testdata/scan",
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	cfg := seal.Config{
+		CommonConfig: commonCfg,
+	}
+	require.NoError(t, seal.Run(context.Background(), cfg))
+	// Sealing again is not an error
+	require.NoError(t, seal.Run(context.Background(), cfg))
+
+	resumeCfg := resume.Config{
+		CommonConfig: commonCfg,
+	}
+	err := resume.Run(context.Background(), resumeCfg)
+	assert.ErrorIs(t, err, db.ErrSealed)
+
+	resumeCfg.Force = true
+	assert.NoError(t, resume.Run(context.Background(), resumeCfg))
+
+	cfg.Unseal = true
+	require.NoError(t, seal.Run(context.Background(), cfg))
+
+	resumeCfg.Force = false
+	assert.NoError(t, resume.Run(context.Background(), resumeCfg))
+
+	// Not a database
+	cfg.DbPath = filepath.Join(t.TempDir(), "missing.ajfs")
+	assert.Error(t, seal.Run(context.Background(), cfg))
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package search
+
+import (
+	"fmt"
+)
+
+// Criteria describes a search expression in a form that can be filled in from
+// command line flags or decoded from JSON.
+// All the specified criteria need to match for a path entry to match.
+type Criteria struct {
+	Regex            []string `json:"regex,omitempty"`        // Regular expressions matched against the path.
+	RegexInsensitive []string `json:"iregex,omitempty"`       // Case insensitive regular expressions matched against the path.
+	Name             []string `json:"name,omitempty"`         // Shell patterns matched against the base name.
+	NameInsensitive  []string `json:"iname,omitempty"`        // Case insensitive shell patterns matched against the base name.
+	Path             []string `json:"path,omitempty"`         // Shell patterns matched against the path.
+	PathInsensitive  []string `json:"ipath,omitempty"`        // Case insensitive shell patterns matched against the path.
+	Size             []string `json:"size,omitempty"`         // Size expressions (see [NewSize]).
+	SizeBlocks       bool     `json:"size_blocks,omitempty"`  // Round up the size to the unit used before comparing (see [NewSizeInBlocks]).
+	Type             string   `json:"type,omitempty"`         // Type of entry (see [NewType]).
+	Hash             string   `json:"hash,omitempty"`         // Prefix of the file signature hash.
+	Id               string   `json:"id,omitempty"`           // Prefix of the path identifier.
+	Before           string   `json:"before,omitempty"`       // Last modification time before (see [NewModTimeBefore]).
+	After            string   `json:"after,omitempty"`        // Last modification time after (see [NewModTimeAfter]).
+	Between          string   `json:"between,omitempty"`      // Last modification time between (see [NewModTimeBetween]).
+	Dupes            string   `json:"dupes,omitempty"`        // Number of other files with the same hash (see [NewDupes]).
+	InvalidUTF8      bool     `json:"invalid_utf8,omitempty"` // Path is not valid UTF-8.
+}
+
+// Build the search expression from the criteria.
+// Returns nil as the expression when none of the criteria were specified.
+// alsoHashes will be true when the expression requires the file signature hashes.
+func (c Criteria) Build() (exp Expression, alsoHashes bool, err error) {
+	var prev Expression
+	var and Expression
+
+	// Regex
+	prev = &Always{}
+	for _, regexStr := range c.Regex {
+		exp, err := NewRegex(regexStr)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to parse regular expression %q. %v", regexStr, err)
+		}
+
+		and = NewAnd(prev, exp)
+		prev = and
+	}
+
+	// Case insensitive regex
+	for _, regexStr := range c.RegexInsensitive {
+		exp, err := NewRegex("(?i)" + regexStr)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to parse regular expression '(?i)%s'. %v", regexStr, err)
+		}
+
+		and = NewAnd(prev, exp)
+		prev = and
+	}
+
+	// Name (base name only)
+	for _, pattern := range c.Name {
+		exp, err := NewShellPattern(pattern, true, false)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to parse shell pattern %q. %v", pattern, err)
+		}
+
+		and = NewAnd(prev, exp)
+		prev = and
+	}
+
+	// Case insensitive name (base name only)
+	for _, pattern := range c.NameInsensitive {
+		exp, err := NewShellPattern(pattern, true, true)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to parse shell pattern %q. %v", pattern, err)
+		}
+
+		and = NewAnd(prev, exp)
+		prev = and
+	}
+
+	// Path
+	for _, pattern := range c.Path {
+		exp, err := NewShellPattern(pattern, false, false)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to parse shell pattern %q. %v", pattern, err)
+		}
+
+		and = NewAnd(prev, exp)
+		prev = and
+	}
+
+	// Case insensitive path
+	for _, pattern := range c.PathInsensitive {
+		exp, err := NewShellPattern(pattern, false, true)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to parse shell pattern %q. %v", pattern, err)
+		}
+
+		and = NewAnd(prev, exp)
+		prev = and
+	}
+
+	// Size
+	for _, sizeStr := range c.Size {
+		newSize := NewSize
+		if c.SizeBlocks {
+			newSize = NewSizeInBlocks
+		}
+
+		exp, err := newSize(sizeStr)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to parse size expression from %q'. %v", sizeStr, err)
+		}
+
+		and = NewAnd(prev, exp)
+		prev = and
+	}
+
+	// Type
+	if c.Type != "" {
+		exp, err := NewType(c.Type)
+		if err != nil {
+			return nil, false, err
+		}
+
+		and = NewAnd(prev, exp)
+		prev = and
+	}
+
+	// Hash
+	if c.Hash != "" {
+		exp := &Hash{Prefix: c.Hash}
+		and = NewAnd(prev, exp)
+		prev = and
+
+		alsoHashes = true
+	}
+
+	// Id
+	if c.Id != "" {
+		exp := &Id{Prefix: c.Id}
+		and = NewAnd(prev, exp)
+		prev = and
+	}
+
+	// Invalid UTF-8
+	if c.InvalidUTF8 {
+		exp := &InvalidUTF8{}
+		and = NewAnd(prev, exp)
+		prev = and
+	}
+
+	// Before date/time
+	if c.Before != "" {
+		exp, err := NewModTimeBefore(c.Before)
+		if err != nil {
+			return nil, false, err
+		}
+
+		and = NewAnd(prev, exp)
+		prev = and
+	}
+
+	// After date/time
+	if c.After != "" {
+		exp, err := NewModTimeAfter(c.After)
+		if err != nil {
+			return nil, false, err
+		}
+
+		and = NewAnd(prev, exp)
+		prev = and
+	}
+
+	// Between date/times
+	if c.Between != "" {
+		exp, err := NewModTimeBetween(c.Between)
+		if err != nil {
+			return nil, false, err
+		}
+
+		and = NewAnd(prev, exp)
+		prev = and
+	}
+
+	// Number of duplicates
+	if c.Dupes != "" {
+		exp, err := NewDupes(c.Dupes)
+		if err != nil {
+			return nil, false, err
+		}
+
+		and = NewAnd(prev, exp)
+		prev = and
+
+		alsoHashes = true
+	}
+
+	_ = prev
+
+	return and, alsoHashes, nil
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package search
+
+import (
+	"cmp"
+	"container/heap"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+)
+
+// SortOrder determines the order in which the matching entries are displayed.
+type SortOrder int
+
+const (
+	SortNone  SortOrder = iota // The order in which the entries are stored in the database.
+	SortPath                   // Alphabetically by path.
+	SortSize                   // From the largest to the smallest size.
+	SortMTime                  // From the most to the least recently modified.
+)
+
+// Parse the name of the sort order (path, size or mtime).
+func ParseSortOrder(name string) (SortOrder, error) {
+	switch strings.ToLower(name) {
+	case "":
+		return SortNone, nil
+	case "path":
+		return SortPath, nil
+	case "size":
+		return SortSize, nil
+	case "mtime":
+		return SortMTime, nil
+	}
+	return SortNone, fmt.Errorf("invalid sort order %q. valid values are 'path', 'size' and 'mtime'", name)
+}
+
+//-----------------------------------------------------------------------------
+
+// A matching entry that is ready to be displayed.
+type match struct {
+	prefix   string // Identifies the volume of a catalog.
+	info     path.Info
+	hash     []byte
+	withHash bool
+}
+
+// Receives the matching entries and applies the limit, count and sort options before displaying them.
+type results struct {
+	cfg     *Config
+	count   int
+	entries matchHeap
+}
+
+func newResults(cfg *Config) *results {
+	return &results{cfg: cfg}
+}
+
+// Return true once the limit has been reached and no more entries need to be read.
+func (r *results) done() bool {
+	return (r.cfg.Limit > 0) && (r.cfg.CountOnly || r.cfg.Sort == SortNone) && (r.count >= r.cfg.Limit)
+}
+
+// Add a matching entry.
+// Returns [db.SkipAll] once the limit has been reached.
+func (r *results) add(m match) error {
+	if r.cfg.CountOnly || r.cfg.Sort == SortNone {
+		r.count++
+		if !r.cfg.CountOnly {
+			r.cfg.printMatch(m)
+		}
+		if r.done() {
+			return db.SkipAll
+		}
+		return nil
+	}
+
+	// Only the best n entries are kept when a limit is specified
+	r.entries.order = r.cfg.Sort
+	if r.cfg.Limit < 1 || len(r.entries.items) < r.cfg.Limit {
+		heap.Push(&r.entries, m)
+		return nil
+	}
+
+	if compareMatches(r.cfg.Sort, m, r.entries.items[0]) > 0 {
+		r.entries.items[0] = m
+		heap.Fix(&r.entries, 0)
+	}
+	return nil
+}
+
+// Display the sorted entries or the count.
+func (r *results) finish() {
+	if r.cfg.CountOnly {
+		r.cfg.Println(r.count)
+		return
+	}
+
+	sorted := slices.Clone(r.entries.items)
+	slices.SortFunc(sorted, func(a, b match) int {
+		return compareMatches(r.cfg.Sort, b, a)
+	})
+	for _, m := range sorted {
+		r.cfg.printMatch(m)
+	}
+}
+
+func (cfg *Config) printMatch(m match) {
+	pi := m.info
+
+	if m.withHash {
+		hashStr := hex.EncodeToString(m.hash)
+		if cfg.DisplayMinimal {
+			cfg.Println(fmt.Sprintf("%s%s, %q", m.prefix, hashStr, pi.Path))
+		} else {
+			cfg.Println(m.prefix + pi.StringWithHash(hashStr, path.FieldOptions{DisplayTime: cfg.DisplayTime}))
+		}
+		return
+	}
+
+	if cfg.DisplayMinimal {
+		cfg.Println(m.prefix + path.Escape(pi.Path))
+	} else {
+		cfg.Println(fmt.Sprintf("%s%v", m.prefix, pi))
+	}
+}
+
+// Return a positive number if a should be displayed before b.
+// Entries that are equal are displayed alphabetically.
+func compareMatches(order SortOrder, a, b match) int {
+	c := 0
+	switch order {
+	case SortSize:
+		c = cmp.Compare(a.info.Size, b.info.Size)
+	case SortMTime:
+		c = a.info.ModTime.Compare(b.info.ModTime)
+	}
+	if c != 0 {
+		return c
+	}
+
+	if c = strings.Compare(b.prefix, a.prefix); c != 0 {
+		return c
+	}
+	return strings.Compare(b.info.Path, a.info.Path)
+}
+
+// Min-heap used by [container/heap] which keeps the entry that would be displayed last at the root.
+type matchHeap struct {
+	order SortOrder
+	items []match
+}
+
+func (h matchHeap) Len() int           { return len(h.items) }
+func (h matchHeap) Less(i, j int) bool { return compareMatches(h.order, h.items[i], h.items[j]) < 0 }
+func (h matchHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *matchHeap) Push(x any) {
+	h.items = append(h.items, x.(match))
+}
+
+func (h *matchHeap) Pop() any {
+	old := h.items
+	n := len(old)
+	x := old[n-1]
+	h.items = old[:n-1]
+	return x
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// SavedSearches maps the name of a saved search to its criteria.
+type SavedSearches map[string]Criteria
+
+// Return the default path of the saved searches file, which is located in the user's config directory.
+func DefaultSavedSearchesPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine the user config directory. %w", err)
+	}
+	return filepath.Join(dir, "ajfs", "searches.json"), nil
+}
+
+// Load the saved searches from the file.
+// An empty set is returned if the file does not exist yet.
+func LoadSavedSearches(path string) (SavedSearches, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return SavedSearches{}, nil
+		}
+		return nil, fmt.Errorf("failed to read the saved searches %q. %w", path, err)
+	}
+
+	result := SavedSearches{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse the saved searches %q. %w", path, err)
+	}
+	return result, nil
+}
+
+// Write the saved searches to the file, replacing the existing file.
+func (s SavedSearches) Write(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create the directory for the saved searches %q. %w", path, err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the saved searches. %w", err)
+	}
+	data = append(data, '\n')
+
+	// Write to a temporary file first so that the existing searches are not lost when writing fails
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0666); err != nil {
+		return fmt.Errorf("failed to write the saved searches %q. %w", path, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write the saved searches %q. %w", path, err)
+	}
+	return nil
+}
+
+// Get the criteria of the saved search.
+func (s SavedSearches) Get(name string) (Criteria, error) {
+	c, ok := s[name]
+	if !ok {
+		if len(s) == 0 {
+			return Criteria{}, fmt.Errorf("no saved search named %q, no searches have been saved yet", name)
+		}
+		names := slices.Sorted(maps.Keys(s))
+		return Criteria{}, fmt.Errorf("no saved search named %q, available searches are: %s", name, strings.Join(names, ", "))
+	}
+	return c, nil
+}
+
+// Save the criteria under the name, replacing an existing search with the same name.
+func (s SavedSearches) Save(name string, c Criteria) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("the name of a saved search can't be empty")
+	}
+	exp, _, err := c.Build()
+	if err != nil {
+		return err
+	}
+	if exp == nil {
+		return fmt.Errorf("the search %q has no criteria to be saved", name)
+	}
+	s[name] = c
+	return nil
+}
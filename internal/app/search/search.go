@@ -22,16 +22,21 @@
 package search
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 	"io/fs"
+	"math"
+	"math/bits"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/catalog"
 	"github.com/andrejacobs/ajfs/internal/db"
 	"github.com/andrejacobs/ajfs/internal/path"
 )
@@ -39,93 +44,132 @@ import (
 // Config for the ajfs info command.
 type Config struct {
 	config.CommonConfig
+	config.UnderConfig
 	Expresion        Expression // The search expression used to match path entries against.
 	AlsoHashes       bool       // If the hashes need to also be checked, because we know one of the expressions require this.
 	DisplayFullPaths bool       // If true then each path entry will be prefixed with the root path of the database.
 	DisplayMinimal   bool       // Display only the paths.
+
+	Limit     int       // Maximum number of matching entries to display. Zero means no limit.
+	CountOnly bool      // Only display the number of matching entries.
+	Sort      SortOrder // The order in which the matching entries are displayed.
 }
 
 // Process the ajfs info command.
-func Run(cfg Config) error {
+// The database path can also be a catalog in which case all of its volumes are searched.
+func Run(ctx context.Context, cfg Config) error {
 
 	if cfg.Expresion == nil {
 		return fmt.Errorf("expected a search expression")
 	}
+	if cfg.Limit < 0 {
+		return fmt.Errorf("the limit can't be negative")
+	}
 
-	dbf, err := db.OpenDatabase(cfg.DbPath)
+	res := newResults(&cfg)
+
+	if !catalog.IsCatalog(cfg.DbPath) {
+		dbf, err := db.OpenDatabase(cfg.DbPath)
+		if err != nil {
+			return err
+		}
+		defer dbf.Close()
+		cfg.WarnIfLimited(dbf)
+
+		if err = searchDatabase(ctx, cfg, dbf, "", res); err != nil {
+			return err
+		}
+		res.finish()
+		return nil
+	}
+
+	volumes, err := catalog.Volumes(cfg.DbPath)
 	if err != nil {
 		return err
 	}
-	defer dbf.Close()
+
+	for _, v := range volumes {
+		if res.done() {
+			break
+		}
+
+		// Databases of offline volumes could have been moved, so keep searching the others
+		dbf, err := db.OpenDatabase(v.Path)
+		if err != nil {
+			cfg.Errorln(fmt.Sprintf("WARNING: skipping the volume %q. %v", v.Name, err))
+			continue
+		}
+		cfg.WarnIfLimited(dbf)
+
+		err = searchDatabase(ctx, cfg, dbf, fmt.Sprintf("[%s] ", v.Name), res)
+		dbf.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	res.finish()
+	return nil
+}
+
+// Search a single database and add the matching entries to the results.
+// prefix is written in front of each matching entry (used to identify the volume of a catalog).
+func searchDatabase(ctx context.Context, cfg Config, dbf *db.DatabaseFile, prefix string, res *results) error {
+	withHashes := cfg.AlsoHashes && dbf.Features().HasHashTable()
+
+	if err := Prepare(ctx, cfg.Expresion, dbf); err != nil {
+		return err
+	}
 
 	// Header
-	if cfg.Verbose {
-		if cfg.AlsoHashes && dbf.Features().HasHashTable() {
+	if cfg.Verbose && !cfg.CountOnly {
+		if withHashes {
 			if cfg.DisplayMinimal {
-				cfg.Println("Hash, Path")
+				cfg.Println(prefix + "Hash, Path")
 			} else {
-				cfg.Println(path.HeaderWithHash())
+				cfg.Println(prefix + path.HeaderWithHash())
 			}
 		} else {
 			if cfg.DisplayMinimal {
-				cfg.Println("Path")
+				cfg.Println(prefix + "Path")
 			} else {
-				cfg.Println(path.Header())
+				cfg.Println(prefix + path.Header())
 			}
 		}
 	}
 
-	// Hashes?
-	if cfg.AlsoHashes && dbf.Features().HasHashTable() {
-		err = dbf.ReadAllEntriesWithHashes(func(idx int, pi path.Info, hash []byte) error {
-			matched, err := cfg.Expresion.Match(pi, hash)
-			if err != nil {
-				return err
-			}
-
-			if !matched {
-				return nil
-			}
-
-			if cfg.DisplayFullPaths {
-				pi.Path = filepath.Join(dbf.RootPath(), pi.Path)
-			}
+	fn := func(pi path.Info, hash []byte) error {
+		if !cfg.IsUnder(pi.Path) {
+			return nil
+		}
 
-			hashStr := hex.EncodeToString(hash)
+		matched, err := cfg.Expresion.Match(pi, hash)
+		if err != nil {
+			return err
+		}
 
-			if cfg.DisplayMinimal {
-				cfg.Println(fmt.Sprintf("%s, %q", hashStr, pi.Path))
-			} else {
-				cfg.Println(fmt.Sprintf("{%x}, %s, %v, %q, %v, %v", pi.Id, hashStr, pi.Size, pi.Path, pi.Mode, pi.ModTime.Format(time.RFC3339Nano)))
-			}
+		if !matched {
 			return nil
-		})
-		return err
-	} else {
-		// Without hashes
-		err = dbf.ReadAllEntries(func(idx int, pi path.Info) error {
-			matched, err := cfg.Expresion.Match(pi, nil)
-			if err != nil {
-				return err
-			}
+		}
 
-			if !matched {
-				return nil
-			}
+		if cfg.DisplayFullPaths {
+			pi.Path = filepath.Join(dbf.RootPath(), pi.Path)
+		}
 
-			if cfg.DisplayFullPaths {
-				pi.Path = filepath.Join(dbf.RootPath(), pi.Path)
-			}
+		return res.add(match{prefix: prefix, info: pi, hash: hash, withHash: withHashes})
+	}
 
-			if cfg.DisplayMinimal {
-				cfg.Println(pi.Path)
-			} else {
-				cfg.Println(pi)
-			}
-			return nil
+	// Hashes?
+	if withHashes {
+		return dbf.ReadAllEntriesWithHashes(ctx, func(idx int, pi path.Info, hash []byte) error {
+			return fn(pi, hash)
 		})
-		return err
 	}
+
+	// Without hashes
+	return dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		return fn(pi, nil)
+	})
 }
 
 //-----------------------------------------------------------------------------
@@ -388,8 +432,14 @@ func (s *searchType) Match(pi path.Info, hash []byte) (bool, error) {
 // Size
 
 type searchSize struct {
-	size uint64
-	op   searchSizeOp
+	size uint64 // The exact size, or lower bound when op is range
+	unit uint64 // The scale of the size
+
+	upper     uint64 // Upper bound when op is range
+	upperUnit uint64 // The scale of the upper bound
+
+	op     searchSizeOp
+	blocks bool // Round up the file size to the unit before comparing (as done by find)
 }
 
 type searchSizeOp int
@@ -398,11 +448,11 @@ const (
 	searchSizeOpEqual searchSizeOp = iota
 	searchSizeOpLess
 	searchSizeOpGreater
+	searchSizeOpRange
 )
 
 // Match path based on a file size expression.
-// NOTE: This does not work on the BLOCKSIZE and rouding up concept used by find.
-// Expresion can be in the format of: [+/-]<n>[suffix]
+// Expresion can be in the format of: [+/-]<n>[suffix] or a range <n>[suffix]..<n>[suffix]
 // No suffix means exactly n bytes.
 // Valid suffixes are:
 // k/K for Kilobytes (1 KB = 1000 bytes). e.g. 1k
@@ -410,9 +460,15 @@ const (
 // g/G for Gigabytes (1 GB = 1000 MB). e.g. 1g
 // t/T for Terrabytes (1 TB = 1000 GB). e.g. 1t
 // p/P for Petabytes (1 PB = 1000 TB). e.g. 1p
+// KiB for Kibibytes (1 KiB = 1024 bytes). e.g. 1KiB
+// MiB for Mebibytes (1 MiB = 1024 KiB). e.g. 1MiB
+// GiB for Gibibytes (1 GiB = 1024 MiB). e.g. 1GiB
+// TiB for Tebibytes (1 TiB = 1024 GiB). e.g. 1TiB
+// PiB for Pebibytes (1 PiB = 1024 TiB). e.g. 1PiB
 // Valid prefixes are:
 // + means Greater than. e.g. +1k
 // - means Less than. e.g. -1
+// A range matches sizes between the two bounds (inclusive), either bound can be omitted. e.g. 1m..50m, ..1KiB
 // .
 func NewSize(expression string) (*searchSize, error) {
 	s := &searchSize{}
@@ -423,78 +479,154 @@ func NewSize(expression string) (*searchSize, error) {
 	return s, nil
 }
 
+// Same as [NewSize] except that the file size is first rounded up to the unit used in the expression
+// before comparing, which is the same as what find does.
+// For example -1M only matches empty files, since any file smaller than 1 MB is rounded up to 1 MB.
+func NewSizeInBlocks(expression string) (*searchSize, error) {
+	s, err := NewSize(expression)
+	if err != nil {
+		return nil, err
+	}
+	s.blocks = true
+	return s, nil
+}
+
+// Size returns the parsed size in bytes.
+func (s *searchSize) Size() uint64 {
+	return s.size
+}
+
+// IsExact returns true if the expression is a single size without a + or - prefix.
+func (s *searchSize) IsExact() bool {
+	return s.op == searchSizeOpEqual
+}
+
 func (s *searchSize) parse(expression string) error {
-	lenExp := len(expression)
-	if lenExp == 0 {
+	if len(expression) == 0 {
 		s.size = 0
+		s.unit = 1
 		s.op = searchSizeOpEqual
 		return nil
 	}
 
-	// Default is exact size in bytes
-	blocksize := uint64(1)
+	if lower, upper, isRange := strings.Cut(expression, ".."); isRange {
+		s.op = searchSizeOpRange
+		s.upper = math.MaxUint64
+		s.upperUnit = 1
+		s.unit = 1
 
-	// Parse scale suffix
-	suffix := expression[lenExp-1:]
-	switch strings.ToLower(suffix) {
-	case "k":
-		// Kilobytes
-		blocksize = 1000
-		expression = expression[:lenExp-1]
-	case "m":
-		// Megabytes
-		blocksize = 1000 * 1000
-		expression = expression[:lenExp-1]
-	case "g":
-		// Gigabytes
-		blocksize = 1000 * 1000 * 1000
-		expression = expression[:lenExp-1]
-	case "t":
-		// Terrabytes
-		blocksize = 1000 * 1000 * 1000 * 1000
-		expression = expression[:lenExp-1]
-	case "p":
-		// Petabytes
-		blocksize = 1000 * 1000 * 1000 * 1000 * 1000
-		expression = expression[:lenExp-1]
-	}
+		if lower == "" && upper == "" {
+			return fmt.Errorf("failed to parse the size expression %q. the range needs at least one bound", expression)
+		}
 
-	lenExp = len(expression)
-	if lenExp == 0 {
-		return fmt.Errorf("failed to parse the size expression %q after removing scale suffix", expression)
+		var err error
+		if lower != "" {
+			if s.size, s.unit, err = parseSizeValue(lower); err != nil {
+				return err
+			}
+		}
+		if upper != "" {
+			if s.upper, s.upperUnit, err = parseSizeValue(upper); err != nil {
+				return err
+			}
+		}
+		if s.size > s.upper {
+			return fmt.Errorf("failed to parse the size expression %q. the lower bound is larger than the upper bound", expression)
+		}
+		return nil
 	}
 
-	prefix := expression[:1]
-	switch prefix {
+	switch expression[:1] {
 	case "+":
 		s.op = searchSizeOpGreater
-		expression = expression[1:lenExp]
+		expression = expression[1:]
 	case "-":
 		s.op = searchSizeOpLess
-		expression = expression[1:lenExp]
+		expression = expression[1:]
 	default:
 		s.op = searchSizeOpEqual
 	}
 
-	value, err := strconv.Atoi(expression)
+	var err error
+	s.size, s.unit, err = parseSizeValue(expression)
+	return err
+}
+
+// The scaling suffixes, the binary suffixes need to be checked before the decimal ones.
+var sizeSuffixes = []struct {
+	suffix string
+	unit   uint64
+}{
+	{"kib", 1 << 10},
+	{"mib", 1 << 20},
+	{"gib", 1 << 30},
+	{"tib", 1 << 40},
+	{"pib", 1 << 50},
+	{"k", 1000},
+	{"m", 1000 * 1000},
+	{"g", 1000 * 1000 * 1000},
+	{"t", 1000 * 1000 * 1000 * 1000},
+	{"p", 1000 * 1000 * 1000 * 1000 * 1000},
+}
+
+// Parse a size in the format <n>[suffix] and return the size in bytes and the unit of the suffix.
+func parseSizeValue(expression string) (uint64, uint64, error) {
+	unit := uint64(1)
+	lower := strings.ToLower(expression)
+	for _, sfx := range sizeSuffixes {
+		if strings.HasSuffix(lower, sfx.suffix) {
+			unit = sfx.unit
+			expression = expression[:len(expression)-len(sfx.suffix)]
+			break
+		}
+	}
+
+	if len(expression) == 0 {
+		return 0, 0, fmt.Errorf("failed to parse the size expression %q after removing scale suffix", expression)
+	}
+
+	value, err := strconv.ParseUint(expression, 10, 64)
 	if err != nil {
-		return fmt.Errorf("failed to parse the size expression %q. %v", expression, err)
+		return 0, 0, fmt.Errorf("failed to parse the size expression %q. %v", expression, err)
+	}
+
+	overflow, size := bits.Mul64(value, unit)
+	if overflow != 0 {
+		return 0, 0, fmt.Errorf("failed to parse the size expression %q. the size is too large", expression)
 	}
 
-	s.size = uint64(value) * blocksize //nolint:gosec // disable G115
+	return size, unit, nil
+}
 
-	return nil
+// Round the size up to the number of units, but only when matching in blocks.
+func (s *searchSize) scaled(size uint64, unit uint64) (uint64, uint64) {
+	if !s.blocks || unit == 1 {
+		return size, 0
+	}
+	// Compare the number of units instead of bytes
+	return (size + unit - 1) / unit, unit
 }
 
 func (s *searchSize) Match(pi path.Info, hash []byte) (bool, error) {
+	size, target := pi.Size, s.size
+	if n, unit := s.scaled(size, s.unit); unit != 0 {
+		size, target = n, s.size/unit
+	}
+
 	matched := false
 	switch s.op {
 	case searchSizeOpEqual:
-		matched = (pi.Size == s.size)
+		matched = (size == target)
 	case searchSizeOpGreater:
-		matched = (pi.Size > s.size)
+		matched = (size > target)
 	case searchSizeOpLess:
-		matched = (pi.Size < s.size)
+		matched = (size < target)
+	case searchSizeOpRange:
+		upperSize, upperTarget := pi.Size, s.upper
+		if n, unit := s.scaled(upperSize, s.upperUnit); unit != 0 {
+			upperSize, upperTarget = n, s.upper/unit
+		}
+		matched = (size >= target) && (upperSize <= upperTarget)
 	}
 
 	return matched, nil
@@ -541,11 +673,7 @@ func NewModTimeBefore(expression string) (*searchModTime, error) {
 }
 
 // Match if the entry's last modification time is after the specified date.
-// The following formats are allowed:
-// YYYY-MM-DD
-// YYYY-MM-DD HH:mm:ss
-// YYYY-MM-DDTHH:mm:ss
-// .
+// The same formats as [NewModTimeBefore] are allowed, e.g. 7D means within the last 7 days.
 func NewModTimeAfter(expression string) (*searchModTime, error) {
 	s := &searchModTime{}
 	err := s.parse(expression, true)
@@ -645,11 +773,6 @@ func (s *searchModTime) parse(expression string, after bool) error {
 		}
 
 	} else {
-		// Suffix not allowed when using "after date" option
-		if after {
-			return fmt.Errorf("date/time search does not allow shorthand suffixes when using 'after' option. %q", expression)
-		}
-
 		value, err := strconv.Atoi(expression)
 		if err != nil {
 			return fmt.Errorf("failed to parse the date/time expression %q. %v", expression, err)
@@ -678,6 +801,11 @@ func (s *searchModTime) parse(expression string, after bool) error {
 	return nil
 }
 
+// Reference returns the parsed date/time that entries are compared against.
+func (s *searchModTime) Reference() time.Time {
+	return s.reference
+}
+
 func (s *searchModTime) Match(pi path.Info, hash []byte) (bool, error) {
 	compare := pi.ModTime.Compare(s.reference)
 	if s.after {
@@ -686,6 +814,57 @@ func (s *searchModTime) Match(pi path.Info, hash []byte) (bool, error) {
 	return compare == -1, nil
 }
 
+type searchModTimeBetween struct {
+	after  *searchModTime
+	before *searchModTime
+}
+
+// Match if the entry's last modification time is between two date/times (exclusive).
+// The expression is in the format "A,B" where both A and B can use the same formats as [NewModTimeBefore].
+// The order of A and B does not matter, e.g. "30D,7D" and "7D,30D" both match entries modified
+// between 30 and 7 days ago.
+func NewModTimeBetween(expression string) (*searchModTimeBetween, error) {
+	a, b, found := strings.Cut(expression, ",")
+	if !found {
+		return nil, fmt.Errorf("failed to parse the date/time range %q. expected the format A,B", expression)
+	}
+
+	after, err := NewModTimeAfter(strings.TrimSpace(a))
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := NewModTimeBefore(strings.TrimSpace(b))
+	if err != nil {
+		return nil, err
+	}
+
+	if after.reference.After(before.reference) {
+		after.reference, before.reference = before.reference, after.reference
+	}
+
+	return &searchModTimeBetween{after: after, before: before}, nil
+}
+
+func (s *searchModTimeBetween) Match(pi path.Info, hash []byte) (bool, error) {
+	matched, err := s.after.Match(pi, hash)
+	if err != nil || !matched {
+		return false, err
+	}
+	return s.before.Match(pi, hash)
+}
+
+//-----------------------------------------------------------------------------
+// Invalid UTF-8
+
+// InvalidUTF8 matches the entries of which the path is not valid UTF-8.
+// These paths are escaped when displayed or exported (see [path.Escape]) and are usually worth renaming.
+type InvalidUTF8 struct{}
+
+func (s *InvalidUTF8) Match(pi path.Info, hash []byte) (bool, error) {
+	return !utf8.ValidString(pi.Path), nil
+}
+
 //-----------------------------------------------------------------------------
 // Id
 
@@ -699,3 +878,147 @@ func (s *Id) Match(pi path.Info, hash []byte) (bool, error) {
 	matched := strings.HasPrefix(strings.ToLower(str), strings.ToLower(s.Prefix))
 	return matched, nil
 }
+
+//-----------------------------------------------------------------------------
+// Duplicates
+
+type searchDupes struct {
+	count uint64 // The exact count, or lower bound when op is range
+	upper uint64 // Upper bound when op is range
+	op    searchSizeOp
+
+	counts map[string]int // Number of files per file signature hash, nil until prepared
+}
+
+// Match files based on the number of other files that have the same file signature hash.
+// Expression can be in the format of: [+/-]<n> or a range <n>..<n>
+// No prefix means exactly n duplicates. e.g. 0 matches files of which no copies exist.
+// + means more than, e.g. +2 matches files of which at least 3 other copies exist.
+// - means fewer than. e.g. -1
+// A range matches counts between the two bounds (inclusive), either bound can be omitted. e.g. 2..5, 10..
+// Only files with a calculated hash can match and empty files are never seen as duplicates (same as "ajfs dupes").
+// [Prepare] needs to be called for each database before matching its entries.
+func NewDupes(expression string) (*searchDupes, error) {
+	s := &searchDupes{}
+	if err := s.parse(expression); err != nil {
+		return nil, fmt.Errorf("failed to parse the duplicate count expression %q. %w", expression, err)
+	}
+	return s, nil
+}
+
+func (s *searchDupes) parse(expression string) error {
+	if lower, upper, isRange := strings.Cut(expression, ".."); isRange {
+		s.op = searchSizeOpRange
+		s.upper = math.MaxUint64
+
+		if lower == "" && upper == "" {
+			return fmt.Errorf("the range needs at least one bound")
+		}
+
+		var err error
+		if lower != "" {
+			if s.count, err = strconv.ParseUint(lower, 10, 64); err != nil {
+				return err
+			}
+		}
+		if upper != "" {
+			if s.upper, err = strconv.ParseUint(upper, 10, 64); err != nil {
+				return err
+			}
+		}
+		if s.count > s.upper {
+			return fmt.Errorf("the lower bound is larger than the upper bound")
+		}
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(expression, "+"):
+		s.op = searchSizeOpGreater
+		expression = expression[1:]
+	case strings.HasPrefix(expression, "-"):
+		s.op = searchSizeOpLess
+		expression = expression[1:]
+	default:
+		s.op = searchSizeOpEqual
+	}
+
+	var err error
+	s.count, err = strconv.ParseUint(expression, 10, 64)
+	return err
+}
+
+// Count the number of files that share each file signature hash.
+func (s *searchDupes) prepare(ctx context.Context, dbf *db.DatabaseFile) error {
+	s.counts = make(map[string]int)
+	if !dbf.Features().HasHashTable() {
+		return nil
+	}
+
+	hashTable, err := dbf.ReadHashTable(ctx)
+	if err != nil {
+		return err
+	}
+	for _, hash := range hashTable {
+		s.counts[string(hash)]++
+	}
+	return nil
+}
+
+func (s *searchDupes) Match(pi path.Info, hash []byte) (bool, error) {
+	if s.counts == nil {
+		return false, fmt.Errorf("the duplicate counts have not been calculated")
+	}
+	if hash == nil || !pi.IsFile() {
+		return false, nil
+	}
+
+	dupes := uint64(0)
+	if pi.Size > 0 {
+		dupes = uint64(max(s.counts[string(hash)]-1, 0)) //nolint:gosec // disable G115
+	}
+
+	matched := false
+	switch s.op {
+	case searchSizeOpEqual:
+		matched = (dupes == s.count)
+	case searchSizeOpGreater:
+		matched = (dupes > s.count)
+	case searchSizeOpLess:
+		matched = (dupes < s.count)
+	case searchSizeOpRange:
+		matched = (dupes >= s.count) && (dupes <= s.upper)
+	}
+
+	return matched, nil
+}
+
+//-----------------------------------------------------------------------------
+// Prepare
+
+// Implemented by expressions that need information about the whole database before they can match its entries.
+type preparer interface {
+	prepare(ctx context.Context, dbf *db.DatabaseFile) error
+}
+
+// Prepare the expression for matching the entries of the database (e.g. count the duplicates for [NewDupes]).
+// This needs to be called for each database before any of its entries are matched.
+func Prepare(ctx context.Context, exp Expression, dbf *db.DatabaseFile) error {
+	switch e := exp.(type) {
+	case *searchAnd:
+		if err := Prepare(ctx, e.lhs, dbf); err != nil {
+			return err
+		}
+		return Prepare(ctx, e.rhs, dbf)
+	case *searchOr:
+		if err := Prepare(ctx, e.lhs, dbf); err != nil {
+			return err
+		}
+		return Prepare(ctx, e.rhs, dbf)
+	case *searchNot:
+		return Prepare(ctx, e.exp, dbf)
+	case preparer:
+		return e.prepare(ctx, dbf)
+	}
+	return nil
+}
@@ -35,12 +35,14 @@ func TestModTimeExpression(t *testing.T) {
 	_, err = NewModTimeBefore("1984/01/23 13:42:23")
 	assert.ErrorContains(t, err, "failed to parse the date/time expression")
 
-	_, err = NewModTimeAfter("42D")
-	assert.ErrorContains(t, err, "date/time search does not allow shorthand suffixes when using 'after' option")
-
 	now := time.Now().Round(time.Second)
 
-	s, err := NewModTimeBefore("1984-01-23")
+	s, err := NewModTimeAfter("42D")
+	require.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, -42), s.reference)
+	assert.True(t, s.after)
+
+	s, err = NewModTimeBefore("1984-01-23")
 	require.NoError(t, err)
 	assert.Equal(t, time.Date(1984, 1, 23, 0, 0, 0, 0, time.UTC), s.reference)
 
@@ -23,12 +23,14 @@ package search_test
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/hex"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -36,7 +38,9 @@ import (
 	"github.com/andrejacobs/ajfs/internal/app/config"
 	"github.com/andrejacobs/ajfs/internal/app/scan"
 	"github.com/andrejacobs/ajfs/internal/app/search"
+	"github.com/andrejacobs/ajfs/internal/catalog"
 	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -278,6 +282,20 @@ func TestSize(t *testing.T) {
 		{desc: "Less than - exact size - false", exp: "-400", size: 400, expected: false},
 		{desc: "Less than - pass", exp: "-2k", size: 1890, expected: true},
 		{desc: "Less than - fail", exp: "-2k", size: 2 * 1000, expected: false},
+		{desc: "Kibibytes", exp: "1KiB", size: 1024, expected: true},
+		{desc: "Mebibytes", exp: "2mib", size: 2 * 1024 * 1024, expected: true},
+		{desc: "Gibibytes - greater than", exp: "+1GiB", size: 1000 * 1000 * 1000, expected: false},
+		{desc: "Range - pass", exp: "1m..50m", size: 10 * 1000 * 1000, expected: true},
+		{desc: "Range - inclusive lower bound", exp: "1m..50m", size: 1000 * 1000, expected: true},
+		{desc: "Range - inclusive upper bound", exp: "1m..50m", size: 50 * 1000 * 1000, expected: true},
+		{desc: "Range - below", exp: "1m..50m", size: 999 * 1000, expected: false},
+		{desc: "Range - above", exp: "1m..50m", size: 50*1000*1000 + 1, expected: false},
+		{desc: "Range - no upper bound", exp: "1KiB..", size: 1 << 40, expected: true},
+		{desc: "Range - no lower bound", exp: "..1KiB", size: 1025, expected: false},
+		{desc: "Range - no bounds", exp: "..", expectedError: "needs at least one bound"},
+		{desc: "Range - invalid bound", exp: "1m..zebra", expectedError: "failed to parse the size expression"},
+		{desc: "Range - inverted", exp: "50m..1m", expectedError: "lower bound is larger"},
+		{desc: "Too large", exp: "100000000PiB", expectedError: "too large"},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -296,6 +314,34 @@ func TestSize(t *testing.T) {
 	}
 }
 
+func TestSizeInBlocks(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		exp      string
+		size     uint64
+		expected bool
+	}{
+		{desc: "Less than only matches empty files", exp: "-1M", size: 0, expected: true},
+		{desc: "Less than is rounded up", exp: "-1M", size: 1, expected: false},
+		{desc: "Exact is rounded up", exp: "2k", size: 1001, expected: true},
+		{desc: "Exact is rounded up - fail", exp: "2k", size: 2001, expected: false},
+		{desc: "Greater than is rounded up", exp: "+1k", size: 1001, expected: true},
+		{desc: "Bytes are not rounded", exp: "-100", size: 99, expected: true},
+		{desc: "Range is rounded up", exp: "1KiB..2KiB", size: 2049, expected: false},
+		{desc: "Range is rounded up - pass", exp: "1KiB..2KiB", size: 1025, expected: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s, err := search.NewSizeInBlocks(tC.exp)
+			require.NoError(t, err)
+
+			m, err := s.Match(path.Info{Size: tC.size}, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tC.expected, m)
+		})
+	}
+}
+
 func TestHash(t *testing.T) {
 	e := search.Hash{Prefix: "abc"}
 
@@ -352,6 +398,10 @@ func TestModTimeExpression(t *testing.T) {
 		{exp: "160M", modTime: now.AddDate(0, -42, 0), expected: false, desc: "before 160M - false"},
 		{exp: "160Y", modTime: now.AddDate(-170, 0, 0), expected: true, desc: "before 160Y"},
 		{exp: "160Y", modTime: now.AddDate(-42, 0, 0), expected: false, desc: "before 160Y - false"},
+
+		{exp: "7D", after: true, modTime: now.AddDate(0, 0, -2), expected: true, desc: "after 7D"},
+		{exp: "7D", after: true, modTime: now.AddDate(0, 0, -8), expected: false, desc: "after 7D - false"},
+		{exp: "1M", after: true, modTime: now.Add(time.Hour * -1), expected: true, desc: "after 1M"},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
@@ -371,6 +421,41 @@ func TestModTimeExpression(t *testing.T) {
 	}
 }
 
+func TestModTimeBetween(t *testing.T) {
+	now := time.Now().Round(time.Second)
+
+	testCases := []struct {
+		desc     string
+		exp      string
+		modTime  time.Time
+		expected bool
+	}{
+		{exp: "1999-01-01,1999-12-31", modTime: time.Date(1999, 8, 27, 0, 0, 0, 0, time.UTC), expected: true, desc: "dates"},
+		{exp: "1999-01-01,1999-12-31", modTime: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC), expected: false, desc: "dates - after"},
+		{exp: "1999-01-01,1999-12-31", modTime: time.Date(1998, 1, 2, 0, 0, 0, 0, time.UTC), expected: false, desc: "dates - before"},
+		{exp: "30D,7D", modTime: now.AddDate(0, 0, -10), expected: true, desc: "relative"},
+		{exp: "7D, 30D", modTime: now.AddDate(0, 0, -10), expected: true, desc: "relative - reversed"},
+		{exp: "30D,7D", modTime: now.AddDate(0, 0, -2), expected: false, desc: "relative - too recent"},
+		{exp: "2020-01-01,1D", modTime: now.AddDate(0, 0, -2), expected: true, desc: "mixed"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s, err := search.NewModTimeBetween(tC.exp)
+			require.NoError(t, err)
+
+			m, err := s.Match(path.Info{ModTime: tC.modTime}, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tC.expected, m)
+		})
+	}
+
+	_, err := search.NewModTimeBetween("30D")
+	require.ErrorContains(t, err, "expected the format A,B")
+
+	_, err = search.NewModTimeBetween("30D,zebra")
+	require.Error(t, err)
+}
+
 func TestScanAndSearch(t *testing.T) {
 	tempFile := filepath.Join(t.TempDir(), "unit-testing")
 	_ = os.Remove(tempFile)
@@ -385,7 +470,7 @@ func TestScanAndSearch(t *testing.T) {
 		Root: "../../testdata/scan",
 	}
 
-	err := scan.Run(scanCfg)
+	err := scan.Run(context.Background(), scanCfg)
 	require.NoError(t, err)
 
 	var outBuffer bytes.Buffer
@@ -406,7 +491,7 @@ func TestScanAndSearch(t *testing.T) {
 		DisplayMinimal: true,
 	}
 
-	err = search.Run(cfg)
+	err = search.Run(context.Background(), cfg)
 	assert.NoError(t, err)
 
 	result := make([]string, 0, 2)
@@ -427,6 +512,138 @@ func TestScanAndSearch(t *testing.T) {
 	assert.Equal(t, expected, result)
 }
 
+func TestSearchLimitSortCount(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Root: "../../testdata/scan",
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	files, err := search.NewType("f")
+	require.NoError(t, err)
+
+	run := func(limit int, countOnly bool, order search.SortOrder) []string {
+		var outBuffer bytes.Buffer
+		cfg := search.Config{
+			CommonConfig: config.CommonConfig{
+				Stdout: &outBuffer,
+				Stderr: io.Discard,
+				DbPath: tempFile,
+			},
+			Expresion:      files,
+			DisplayMinimal: true,
+			Limit:          limit,
+			CountOnly:      countOnly,
+			Sort:           order,
+		}
+		require.NoError(t, search.Run(context.Background(), cfg))
+		return strings.Split(strings.TrimSpace(outBuffer.String()), "\n")
+	}
+
+	all := run(0, false, search.SortPath)
+	assert.Len(t, all, 15)
+	assert.True(t, slices.IsSorted(all))
+
+	assert.Equal(t, []string{"15"}, run(0, true, search.SortNone))
+	assert.Equal(t, []string{"4"}, run(4, true, search.SortSize))
+	assert.Len(t, run(4, false, search.SortNone), 4)
+	assert.Equal(t, all[:3], run(3, false, search.SortPath))
+	assert.Equal(t, []string{"b/b1/b1a/7.txt", "a/a2/6.txt", "a/2.txt"}, run(3, false, search.SortSize))
+
+	// Equal sizes are displayed alphabetically
+	largest := run(0, false, search.SortSize)
+	assert.Equal(t, []string{"a/a1/a1a/a1a1/blank.txt", "b/b1/b1a/blank.txt", "blank.txt"}, largest[len(largest)-3:])
+
+	_, err = search.ParseSortOrder("name")
+	require.Error(t, err)
+}
+
+func TestDupes(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Root:            "../../testdata/scan",
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	run := func(c search.Criteria) []string {
+		exp, alsoHashes, err := c.Build()
+		require.NoError(t, err)
+		assert.True(t, alsoHashes)
+
+		var outBuffer bytes.Buffer
+		cfg := search.Config{
+			CommonConfig: config.CommonConfig{
+				Stdout: &outBuffer,
+				Stderr: io.Discard,
+				DbPath: tempFile,
+			},
+			Expresion:      exp,
+			AlsoHashes:     alsoHashes,
+			DisplayMinimal: true,
+			Sort:           search.SortPath,
+		}
+		require.NoError(t, search.Run(context.Background(), cfg))
+
+		// Each line is: hash, "path"
+		result := make([]string, 0)
+		for _, line := range strings.Split(strings.TrimSpace(outBuffer.String()), "\n") {
+			if _, quoted, ok := strings.Cut(line, ", "); ok {
+				p, err := strconv.Unquote(quoted)
+				require.NoError(t, err)
+				result = append(result, p)
+			}
+		}
+		return result
+	}
+
+	copiesOf1 := []string{
+		"1.txt",
+		"a/a1/a1a/a1a1/1.txt",
+		"a/a2/same-as-1.txt",
+		"b/b1/b1a/1.txt",
+		"b/b1/b1a/same-as-1.txt",
+	}
+
+	assert.Equal(t, copiesOf1, run(search.Criteria{Dupes: "+2"}))
+	assert.Equal(t, copiesOf1, run(search.Criteria{Dupes: "4"}))
+	assert.Equal(t, copiesOf1, run(search.Criteria{Dupes: "1.."}))
+	assert.Empty(t, run(search.Criteria{Dupes: "+4"}))
+	assert.Empty(t, run(search.Criteria{Dupes: "+0", Size: []string{"+500"}}))
+	assert.Equal(t, []string{"1.txt"}, run(search.Criteria{Dupes: "+0", Name: []string{"1.txt"}, Path: []string{"?.txt"}}))
+
+	// Empty files are not seen as duplicates
+	unique := run(search.Criteria{Dupes: "0"})
+	assert.Len(t, unique, 10)
+	assert.Contains(t, unique, "blank.txt")
+	assert.Equal(t, unique, run(search.Criteria{Dupes: "-1"}))
+	assert.Equal(t, unique, run(search.Criteria{Dupes: "..0"}))
+
+	for _, invalid := range []string{"", "x", "+", "..", "5..2", "1.5", "-1..2"} {
+		_, err := search.NewDupes(invalid)
+		assert.Error(t, err, invalid)
+	}
+
+	// The counts need to be calculated first
+	exp, err := search.NewDupes("0")
+	require.NoError(t, err)
+	_, err = exp.Match(path.Info{Mode: 0}, []byte{1})
+	assert.Error(t, err)
+}
+
 func TestId(t *testing.T) {
 	id1 := path.IdFromPath("abc.xyz")
 	id2 := path.IdFromPath("not.found")
@@ -445,3 +662,104 @@ func TestId(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, found)
 }
+
+func TestSearchCatalog(t *testing.T) {
+	tempDir := t.TempDir()
+
+	catPath := filepath.Join(tempDir, "backups.ajfscat")
+	c := catalog.New(catPath)
+
+	for _, v := range []struct {
+		name string
+		root string
+	}{
+		{name: "laptop", root: "../../testdata/scan/a"},
+		{name: "nas", root: "../../testdata/scan/b"},
+	} {
+		dbPath := filepath.Join(tempDir, v.name+".ajfs")
+		scanCfg := scan.Config{
+			CommonConfig: config.CommonConfig{
+				Stdout: io.Discard,
+				Stderr: io.Discard,
+				DbPath: dbPath,
+			},
+			Root: v.root,
+		}
+		require.NoError(t, scan.Run(context.Background(), scanCfg))
+		require.NoError(t, c.Add("", dbPath))
+	}
+	require.NoError(t, c.Add("missing", filepath.Join(tempDir, "missing.ajfs")))
+	require.NoError(t, c.Save())
+
+	var outBuffer bytes.Buffer
+	var errBuffer bytes.Buffer
+
+	s, err := search.NewShellPattern("1.txt", true, false)
+	require.NoError(t, err)
+
+	cfg := search.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: &errBuffer,
+			DbPath: catPath,
+		},
+		Expresion:      s,
+		DisplayMinimal: true,
+	}
+
+	err = search.Run(context.Background(), cfg)
+	require.NoError(t, err)
+
+	expected := `[laptop] a1/a1a/a1a1/1.txt
+[nas] b1/b1a/1.txt
+`
+	assert.Equal(t, expected, outBuffer.String())
+	assert.Contains(t, errBuffer.String(), `skipping the volume "missing"`)
+}
+
+func TestSavedSearches(t *testing.T) {
+	savedPath := filepath.Join(t.TempDir(), "ajfs", "searches.json")
+
+	saved, err := search.LoadSavedSearches(savedPath)
+	require.NoError(t, err)
+	assert.Empty(t, saved)
+
+	_, err = saved.Get("big-media")
+	assert.ErrorContains(t, err, "no searches have been saved yet")
+
+	criteria := search.Criteria{
+		NameInsensitive: []string{"*.mp4", "*.mkv"},
+		Size:            []string{"+1G"},
+		Type:            "f",
+	}
+	require.NoError(t, saved.Save("big-media", criteria))
+	require.NoError(t, saved.Save("pdfs", search.Criteria{NameInsensitive: []string{"*.pdf"}}))
+	require.NoError(t, saved.Write(savedPath))
+
+	assert.ErrorContains(t, saved.Save("", criteria), "can't be empty")
+	assert.ErrorContains(t, saved.Save("nothing", search.Criteria{}), "no criteria")
+	assert.Error(t, saved.Save("invalid", search.Criteria{Size: []string{"+1X"}}))
+
+	loaded, err := search.LoadSavedSearches(savedPath)
+	require.NoError(t, err)
+	assert.Len(t, loaded, 2)
+
+	c, err := loaded.Get("big-media")
+	require.NoError(t, err)
+	assert.Equal(t, criteria, c)
+
+	_, err = loaded.Get("missing")
+	assert.ErrorContains(t, err, "available searches are: big-media, pdfs")
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	e := search.InvalidUTF8{}
+
+	found, err := e.Match(path.Info{Path: "a/caf\xe9.txt"}, nil)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = e.Match(path.Info{Path: "a/café.txt"}, nil)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
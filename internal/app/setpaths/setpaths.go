@@ -0,0 +1,61 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package setpaths provides the functionality for ajfs set-paths command.
+package setpaths
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/db"
+)
+
+// Config for the ajfs set-paths command.
+type Config struct {
+	config.CommonConfig
+
+	PathDisplay db.PathDisplay // The preferred way in which the paths are displayed.
+}
+
+// Process the ajfs set-paths command.
+func Run(ctx context.Context, cfg Config) error {
+	// Ensure it is a valid database before changing the header
+	dbf, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+	current := dbf.PathDisplay()
+	if err = dbf.Close(); err != nil {
+		return err
+	}
+
+	if current == cfg.PathDisplay {
+		cfg.VerbosePrintln(fmt.Sprintf("The database %q already displays %s paths", cfg.DbPath, current))
+		return nil
+	}
+
+	if err = db.SetPathDisplay(cfg.DbPath, cfg.PathDisplay); err != nil {
+		return err
+	}
+
+	cfg.VerbosePrintln(fmt.Sprintf("The database %q now displays %s paths", cfg.DbPath, cfg.PathDisplay))
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package setroot provides the functionality for ajfs set-root command.
+package setroot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/go-aj/file"
+)
+
+// Config for the ajfs set-root command.
+type Config struct {
+	config.CommonConfig
+
+	Root  string // The new root path to be stored in the database.
+	Force bool   // Change the root path even if the database has been sealed.
+}
+
+// Process the ajfs set-root command.
+func Run(ctx context.Context, cfg Config) error {
+	if cfg.Root == "" {
+		return fmt.Errorf("a new root path is required")
+	}
+
+	if err := db.EnsureNotSealed(cfg.DbPath, cfg.Force); err != nil {
+		return err
+	}
+
+	dbf, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+	oldRoot := dbf.RootPath()
+	if err = dbf.Close(); err != nil {
+		return err
+	}
+
+	// The drive might not be mounted at the moment and this is not a reason to fail
+	exists, err := file.DirExists(cfg.Root)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		cfg.Errorln(fmt.Sprintf("WARNING: the new root path %q does not exist", cfg.Root))
+	}
+
+	cfg.VerbosePrintln(fmt.Sprintf("Changing the root path of %q", cfg.DbPath))
+	cfg.VerbosePrintln(fmt.Sprintf("  from: %q", oldRoot))
+	cfg.VerbosePrintln(fmt.Sprintf("  to:   %q", cfg.Root))
+
+	if err = db.SetRootPath(ctx, cfg.DbPath, cfg.Root); err != nil {
+		return err
+	}
+
+	cfg.VerbosePrintln("Done!")
+	return nil
+}
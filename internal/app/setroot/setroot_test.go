@@ -0,0 +1,90 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package setroot_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/app/setroot"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "db.ajfs")
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: dbPath,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		Root: "../../testdata/scan",
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	dbf, err := db.OpenDatabase(dbPath)
+	require.NoError(t, err)
+	expCount := dbf.EntriesCount()
+	require.NoError(t, dbf.Close())
+
+	testCases := []struct {
+		desc    string
+		root    string
+		warning bool
+	}{
+		{desc: "existing root", root: tempDir},
+		{desc: "missing root", root: filepath.Join(tempDir, "not-mounted"), warning: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var errOutput bytes.Buffer
+			cfg := setroot.Config{
+				CommonConfig: config.CommonConfig{
+					DbPath: dbPath,
+					Stdout: io.Discard,
+					Stderr: &errOutput,
+				},
+				Root: tC.root,
+			}
+			require.NoError(t, setroot.Run(context.Background(), cfg))
+
+			if tC.warning {
+				assert.Contains(t, errOutput.String(), "WARNING")
+			} else {
+				assert.Empty(t, errOutput.String())
+			}
+
+			dbf, err := db.OpenDatabase(dbPath)
+			require.NoError(t, err)
+			defer dbf.Close()
+			assert.Equal(t, tC.root, dbf.RootPath())
+			assert.Equal(t, expCount, dbf.EntriesCount())
+		})
+	}
+}
@@ -0,0 +1,480 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package shell provides the functionality for ajfs shell command.
+//
+// The databases are read into memory once and then any number of commands can be run against them.
+package shell
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/ls"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/human"
+	"golang.org/x/term"
+)
+
+// Config for the ajfs shell command.
+type Config struct {
+	config.CommonConfig
+
+	DbPaths []string // Paths to the databases to be explored.
+
+	// Where the commands are read from, one per line.
+	// When nil then stdin is used and if stdin is a terminal then line editing and history are supported.
+	Input io.Reader
+}
+
+// Process the ajfs shell command.
+func Run(ctx context.Context, cfg Config) error {
+	if len(cfg.DbPaths) == 0 {
+		return fmt.Errorf("expected at least one database")
+	}
+
+	s := &session{cfg: &cfg, cwd: "."}
+	for _, p := range cfg.DbPaths {
+		cfg.VerbosePrintln(fmt.Sprintf("Loading database %q", p))
+		v, err := loadVolume(ctx, &cfg, p)
+		if err != nil {
+			return err
+		}
+		s.volumes = append(s.volumes, v)
+	}
+
+	if cfg.Input != nil {
+		return s.runScript(ctx, cfg.Input)
+	}
+
+	fd := int(os.Stdin.Fd()) //nolint:gosec // disable G115
+	if !term.IsTerminal(fd) {
+		return s.runScript(ctx, os.Stdin)
+	}
+	return s.runInteractive(ctx, fd)
+}
+
+//-----------------------------------------------------------------------------
+
+// A database that has been loaded into memory.
+type volume struct {
+	name     string
+	dbPath   string
+	rootPath string
+	algo     string
+
+	entries  []path.Info
+	indices  map[string]int   // map from path to the index in entries
+	children map[string][]int // map from directory path to the indices of the direct children
+	hashes   db.HashTable
+}
+
+func loadVolume(ctx context.Context, cfg *Config, dbPath string) (*volume, error) {
+	dbf, err := db.OpenDatabase(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer dbf.Close()
+	cfg.WarnIfLimited(dbf)
+
+	v := &volume{
+		name:     strings.TrimSuffix(filepath.Base(dbPath), filepath.Ext(dbPath)),
+		dbPath:   dbPath,
+		rootPath: dbf.RootPath(),
+		entries:  make([]path.Info, 0, dbf.EntriesCount()),
+		indices:  make(map[string]int, dbf.EntriesCount()),
+		children: make(map[string][]int, 256),
+	}
+
+	err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		v.indices[pi.Path] = len(v.entries)
+		if pi.Path != "." {
+			parent := filepath.Dir(pi.Path)
+			v.children[parent] = append(v.children[parent], len(v.entries))
+		}
+		v.entries = append(v.entries, pi)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range v.children {
+		slices.SortFunc(c, func(a, b int) int {
+			return strings.Compare(v.entries[a].Path, v.entries[b].Path)
+		})
+	}
+
+	if dbf.Features().HasHashTable() && !dbf.Limited() {
+		algo, err := dbf.HashTableAlgo()
+		if err != nil {
+			return nil, err
+		}
+		v.algo = db.AlgoString(algo)
+
+		// Both the hash table and the entries are indexed by the order in which they are stored
+		v.hashes, err = dbf.ReadHashTable(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return v, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// The state of the interactive session.
+type session struct {
+	cfg     *Config
+	volumes []*volume
+	current int    // index of the volume being explored
+	cwd     string // current directory inside the volume
+}
+
+func (s *session) volume() *volume {
+	return s.volumes[s.current]
+}
+
+func (s *session) prompt() string {
+	cwd := "/"
+	if s.cwd != "" && s.cwd != "." {
+		cwd += filepath.ToSlash(s.cwd)
+	}
+	return fmt.Sprintf("%s:%s> ", s.volume().name, cwd)
+}
+
+// Read and execute the commands until the end of the input.
+func (s *session) runScript(ctx context.Context, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if quit := s.exec(scanner.Text()); quit {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// Read and execute the commands from the terminal with support for line editing and history.
+func (s *session) runInteractive(ctx context.Context, fd int) error {
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to prepare the terminal. %w", err)
+	}
+	defer term.Restore(fd, state) //nolint:errcheck
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, s.prompt())
+
+	// The terminal takes care of translating new lines while in raw mode
+	s.cfg.Stdout = t
+	s.cfg.Stderr = t
+
+	s.cfg.Println(`Type "help" to see the available commands.`)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		t.SetPrompt(s.prompt())
+		line, err := t.ReadLine()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		if quit := s.exec(line); quit {
+			return nil
+		}
+	}
+}
+
+// Execute a single command line and return true if the session should end.
+func (s *session) exec(line string) bool {
+	args := strings.Fields(line)
+	if len(args) == 0 || strings.HasPrefix(args[0], "#") {
+		return false
+	}
+
+	var err error
+	switch args[0] {
+	case "exit", "quit":
+		return true
+	case "help":
+		s.help()
+	case "pwd":
+		s.cfg.Println(s.absolute(s.cwd))
+	case "cd":
+		err = s.cd(args[1:])
+	case "ls":
+		err = s.ls(args[1:])
+	case "find":
+		err = s.find(args[1:])
+	case "hash":
+		err = s.hash(args[1:])
+	case "info":
+		s.info()
+	case "dbs":
+		s.dbs()
+	case "use":
+		err = s.use(args[1:])
+	default:
+		err = fmt.Errorf("unknown command %q, type \"help\" to see the available commands", args[0])
+	}
+
+	if err != nil {
+		s.cfg.Errorln("ERROR:", err)
+	}
+	return false
+}
+
+func (s *session) help() {
+	s.cfg.Println(`Commands:
+  ls [path|pattern]  List a directory or the entries matching a shell pattern.
+  cd [path]          Change the current directory. No path changes to the root.
+  pwd                Display the current directory.
+  find <pattern>     Find the entries at or below the current directory whose name matches the shell pattern.
+  hash <path>        Display the file signature hash of a file.
+  info               Display information about the current database.
+  dbs                List the databases that have been loaded.
+  use <name|number>  Switch to another database.
+  help               Display this help.
+  exit, quit         End the session.`)
+}
+
+// Resolve a path argument against the current directory.
+// Paths starting with / are relative to the root of the database.
+func (s *session) resolve(arg string) (string, error) {
+	var p string
+	if strings.HasPrefix(arg, "/") {
+		p = filepath.Clean(strings.TrimLeft(filepath.FromSlash(arg), string(filepath.Separator)))
+	} else {
+		p = filepath.Join(s.cwd, filepath.FromSlash(arg))
+	}
+
+	if p == "" {
+		p = "."
+	}
+	if p == ".." || strings.HasPrefix(p, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("the path %q is outside of the database root", arg)
+	}
+	return p, nil
+}
+
+// Display a path inside the database starting with /.
+func (s *session) absolute(p string) string {
+	if p == "" || p == "." {
+		return "/"
+	}
+	return "/" + filepath.ToSlash(p)
+}
+
+func (s *session) lookup(arg string) (path.Info, error) {
+	p, err := s.resolve(arg)
+	if err != nil {
+		return path.Info{}, err
+	}
+
+	idx, exists := s.volume().indices[p]
+	if !exists {
+		return path.Info{}, fmt.Errorf("no such file or directory %q", arg)
+	}
+	return s.volume().entries[idx], nil
+}
+
+func (s *session) cd(args []string) error {
+	if len(args) == 0 {
+		s.cwd = "."
+		return nil
+	}
+
+	pi, err := s.lookup(args[0])
+	if err != nil {
+		return err
+	}
+	if !pi.IsDir() {
+		return fmt.Errorf("not a directory %q", args[0])
+	}
+
+	s.cwd = pi.Path
+	return nil
+}
+
+func (s *session) ls(args []string) error {
+	v := s.volume()
+	arg := "."
+	if len(args) > 0 {
+		arg = args[0]
+	}
+
+	if strings.ContainsAny(arg, `*?[`) {
+		pattern, err := s.resolve(arg)
+		if err != nil {
+			return err
+		}
+		if _, err = filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q. %w", arg, err)
+		}
+
+		for _, pi := range v.entries {
+			if matched, _ := filepath.Match(pattern, pi.Path); matched && pi.Path != "." {
+				s.cfg.Println(ls.Format(pi, s.relative(pi.Path), s.cfg.UTC))
+			}
+		}
+		return nil
+	}
+
+	pi, err := s.lookup(arg)
+	if err != nil {
+		return err
+	}
+
+	if !pi.IsDir() {
+		s.cfg.Println(ls.Format(pi, s.relative(pi.Path), s.cfg.UTC))
+		return nil
+	}
+
+	for _, idx := range v.children[pi.Path] {
+		child := v.entries[idx]
+		s.cfg.Println(ls.Format(child, filepath.Base(child.Path), s.cfg.UTC))
+	}
+	return nil
+}
+
+// Return the path relative to the current directory.
+func (s *session) relative(p string) string {
+	rel, err := filepath.Rel(s.cwd, p)
+	if err != nil {
+		return p
+	}
+	return rel
+}
+
+func (s *session) find(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected a shell pattern (e.g. *.pdf)")
+	}
+	pattern := args[0]
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q. %w", pattern, err)
+	}
+
+	for _, pi := range s.volume().entries {
+		if pi.Path == "." || !path.IsUnder(pi.Path, s.cwd) {
+			continue
+		}
+		if matched, _ := filepath.Match(pattern, filepath.Base(pi.Path)); matched {
+			s.cfg.Println(s.absolute(pi.Path))
+		}
+	}
+	return nil
+}
+
+func (s *session) hash(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected the path of a file")
+	}
+
+	v := s.volume()
+	if v.hashes == nil {
+		return fmt.Errorf("the database %q does not contain file signature hashes", v.dbPath)
+	}
+
+	pi, err := s.lookup(args[0])
+	if err != nil {
+		return err
+	}
+	if !pi.IsFile() {
+		return fmt.Errorf("not a file %q", args[0])
+	}
+
+	hash, exists := v.hashes[v.indices[pi.Path]]
+	if !exists {
+		return fmt.Errorf("no file signature hash for %q", args[0])
+	}
+	s.cfg.Println(fmt.Sprintf("%s  %s", hex.EncodeToString(hash), s.absolute(pi.Path)))
+	return nil
+}
+
+func (s *session) info() {
+	v := s.volume()
+
+	files := 0
+	size := uint64(0)
+	for _, pi := range v.entries {
+		if pi.IsFile() {
+			files++
+			size += pi.Size
+		}
+	}
+
+	s.cfg.Println(fmt.Sprintf("Database:      %s", v.dbPath))
+	s.cfg.Println(fmt.Sprintf("Root:          %s", v.rootPath))
+	s.cfg.Println(fmt.Sprintf("Entries:       %d", len(v.entries)))
+	s.cfg.Println(fmt.Sprintf("Files:         %d", files))
+	s.cfg.Println(fmt.Sprintf("Total size:    %s", human.Bytes(size)))
+	if v.hashes != nil {
+		s.cfg.Println(fmt.Sprintf("Hashes:        %s", v.algo))
+	} else {
+		s.cfg.Println("Hashes:        no")
+	}
+}
+
+func (s *session) dbs() {
+	for i, v := range s.volumes {
+		marker := " "
+		if i == s.current {
+			marker = "*"
+		}
+		s.cfg.Println(fmt.Sprintf("%s [%d] %s (%s)", marker, i+1, v.name, v.dbPath))
+	}
+}
+
+func (s *session) use(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected the name or number of a database")
+	}
+
+	for i, v := range s.volumes {
+		if args[0] == v.name || args[0] == fmt.Sprint(i+1) {
+			s.current = i
+			s.cwd = "."
+			return nil
+		}
+	}
+	return fmt.Errorf("no database named %q, see \"dbs\"", args[0])
+}
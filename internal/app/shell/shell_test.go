@@ -0,0 +1,111 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package shell_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/shell"
+	"github.com/andrejacobs/ajfs/internal/testshared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShell(t *testing.T) {
+	tempDir := t.TempDir()
+	withHashes := testshared.ScanDatabase(t, filepath.Join(tempDir, "hashes.ajfs"), "../../testdata/scan", true)
+	withoutHashes := testshared.ScanDatabase(t, filepath.Join(tempDir, "plain.ajfs"), "../../testdata/scan/b", false)
+
+	script := `
+# comments and blank lines are ignored
+pwd
+cd a/a2
+pwd
+ls
+cd ..
+find same-as-*
+hash a2/6.txt
+cd /b/b1/b1a
+pwd
+cd ../../..
+pwd
+cd ..
+use plain
+pwd
+ls
+hash b1/b1a/7.txt
+bogus
+exit
+pwd
+`
+
+	var outBuffer bytes.Buffer
+	var errBuffer bytes.Buffer
+	cfg := shell.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: &errBuffer,
+		},
+		DbPaths: []string{withHashes, withoutHashes},
+		Input:   strings.NewReader(script),
+	}
+
+	require.NoError(t, shell.Run(context.Background(), cfg))
+
+	lines := strings.Split(strings.TrimSpace(outBuffer.String()), "\n")
+	require.Len(t, lines, 10)
+	assert.Equal(t, "/", lines[0])
+	assert.Equal(t, "/a/a2", lines[1])
+	assert.True(t, strings.HasSuffix(lines[2], " 6.txt"))
+	assert.True(t, strings.HasSuffix(lines[3], " same-as-1.txt"))
+	assert.Equal(t, "/a/a2/same-as-1.txt", lines[4])
+	assert.Equal(t, "c7389462ca5ccb62c4ffe7a8d62d1da92c10cd27  /a/a2/6.txt", lines[5])
+	assert.Equal(t, "/b/b1/b1a", lines[6])
+	assert.Equal(t, "/", lines[7])
+	assert.Equal(t, "/", lines[8])
+	assert.True(t, strings.HasSuffix(lines[9], " b1/"))
+
+	errors := errBuffer.String()
+	assert.Contains(t, errors, "outside of the database root")
+	assert.Contains(t, errors, "does not contain file signature hashes")
+	assert.Contains(t, errors, `unknown command "bogus"`)
+}
+
+func TestShellMissingDatabase(t *testing.T) {
+	cfg := shell.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		DbPaths: []string{filepath.Join(t.TempDir(), "missing.ajfs")},
+		Input:   strings.NewReader(""),
+	}
+	require.Error(t, shell.Run(context.Background(), cfg))
+
+	cfg.DbPaths = nil
+	require.Error(t, shell.Run(context.Background(), cfg))
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package sign provides the functionality for ajfs sign and verify-signature commands.
+package sign
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/db"
+)
+
+// Config for the ajfs sign and verify-signature commands.
+type Config struct {
+	config.CommonConfig
+
+	KeyPath string // Path to the PEM encoded Ed25519 key.
+}
+
+// Process the ajfs sign command.
+func Run(ctx context.Context, cfg Config) error {
+	key, err := loadPrivateKey(cfg.KeyPath)
+	if err != nil {
+		return err
+	}
+
+	if err = db.SignDatabase(cfg.DbPath, key); err != nil {
+		return err
+	}
+
+	cfg.VerbosePrintln(fmt.Sprintf("Signed %q", cfg.DbPath))
+	cfg.VerbosePrintln(fmt.Sprintf("  Public key: %s", hex.EncodeToString(key.Public().(ed25519.PublicKey))))
+	return nil
+}
+
+// Process the ajfs verify-signature command.
+func Verify(ctx context.Context, cfg Config) error {
+	var key ed25519.PublicKey
+	if cfg.KeyPath != "" {
+		var err error
+		key, err = loadPublicKey(cfg.KeyPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	dbf, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+	defer dbf.Close()
+
+	if !dbf.Features().HasSignature() {
+		return fmt.Errorf("the database %q has not been signed", cfg.DbPath)
+	}
+
+	sig, err := dbf.ReadSignature()
+	if err != nil {
+		return err
+	}
+
+	if err = dbf.VerifySignature(key); err != nil {
+		return err
+	}
+
+	cfg.Println("Valid signature")
+	cfg.Println(fmt.Sprintf("  Public key: %s", hex.EncodeToString(sig.PublicKey)))
+	if key == nil {
+		cfg.Errorln("WARNING: no key was specified, only the integrity of the database was verified and not who signed it")
+	}
+
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// Load the Ed25519 private key from a PEM encoded PKCS #8 file.
+// For example as created by: openssl genpkey -algorithm ed25519 -out key.pem.
+func loadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the private key %q. %w", path, err)
+	}
+
+	result, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the private key %q is not an Ed25519 key", path)
+	}
+	return result, nil
+}
+
+// Load the Ed25519 public key from a PEM encoded PKIX file.
+// The public key is derived when a private key file is specified instead.
+func loadPublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Type == "PRIVATE KEY" {
+		key, err := loadPrivateKey(path)
+		if err != nil {
+			return nil, err
+		}
+		return key.Public().(ed25519.PublicKey), nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the public key %q. %w", path, err)
+	}
+
+	result, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("the public key %q is not an Ed25519 key", path)
+	}
+	return result, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the key %q. %w", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode the key %q (expected PEM encoding)", path)
+	}
+	return block, nil
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package sign_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/app/sign"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "db.ajfs")
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: dbPath,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		Root: "../../testdata/scan",
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	keyPath, pubPath := writeKeys(t, tempDir, "key")
+	otherKeyPath, otherPubPath := writeKeys(t, tempDir, "other")
+
+	var outBuffer bytes.Buffer
+	var errBuffer bytes.Buffer
+	cfg := sign.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: dbPath,
+			Stdout: &outBuffer,
+			Stderr: &errBuffer,
+		},
+	}
+
+	// Not signed yet
+	assert.ErrorContains(t, sign.Verify(context.Background(), cfg), "has not been signed")
+
+	// Only private keys can be used to sign
+	cfg.KeyPath = pubPath
+	assert.Error(t, sign.Run(context.Background(), cfg))
+
+	cfg.KeyPath = keyPath
+	require.NoError(t, sign.Run(context.Background(), cfg))
+
+	for _, keyPath := range []string{"", pubPath, keyPath} {
+		outBuffer.Reset()
+		errBuffer.Reset()
+
+		cfg.KeyPath = keyPath
+		require.NoError(t, sign.Verify(context.Background(), cfg))
+		assert.Contains(t, outBuffer.String(), "Valid signature")
+		if keyPath == "" {
+			assert.Contains(t, errBuffer.String(), "WARNING")
+		} else {
+			assert.Empty(t, errBuffer.String())
+		}
+	}
+
+	for _, keyPath := range []string{otherPubPath, otherKeyPath} {
+		cfg.KeyPath = keyPath
+		assert.Error(t, sign.Verify(context.Background(), cfg))
+	}
+}
+
+// Write a new Ed25519 key pair in the same PEM formats as created by openssl.
+func writeKeys(t *testing.T, dir string, name string) (string, string) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	keyBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	keyPath := filepath.Join(dir, name+".pem")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes}), 0600))
+
+	pubBytes, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	pubPath := filepath.Join(dir, name+"-pub.pem")
+	require.NoError(t, os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubBytes}), 0600))
+
+	return keyPath, pubPath
+}
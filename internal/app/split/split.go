@@ -0,0 +1,165 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package split provides the functionality for ajfs split command.
+package split
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+)
+
+// Config for the ajfs split command.
+type Config struct {
+	config.CommonConfig
+	config.UnderConfig
+
+	OutPath string // Path to the new database that will be created.
+}
+
+// Process the ajfs split command.
+func Run(ctx context.Context, cfg Config) error {
+	under := filepath.Clean(cfg.Under)
+	if cfg.Under == "" || under == "." {
+		return fmt.Errorf("a subpath inside the database is required to split the database")
+	}
+
+	cfg.VerbosePrintln(fmt.Sprintf("Splitting %q from database %q to %q", under, cfg.DbPath, cfg.OutPath))
+
+	inDbf, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+	defer inDbf.Close()
+	cfg.WarnIfLimited(inDbf)
+
+	subRoot, err := inDbf.ReadEntryById(inDbf.IdFromPath(under))
+	if err != nil {
+		return fmt.Errorf("failed to find the path %q in the database %q. %w", cfg.Under, cfg.DbPath, err)
+	}
+	if !subRoot.IsDir() {
+		return fmt.Errorf("the path %q in the database %q is not a directory", cfg.Under, cfg.DbPath)
+	}
+
+	features := db.FeatureFlags(db.FeatureJustEntries)
+	if inDbf.Features().HasHashTable() {
+		features |= db.FeatureHashTable
+	}
+	if inDbf.Features().HasDirStats() {
+		features |= db.FeatureDirStats
+	}
+	if inDbf.Features().HasHashTimes() {
+		features |= db.FeatureHashTimes
+	}
+
+	outDbf, err := db.CreateDatabaseWithIdNamespace(cfg.OutPath, filepath.Join(inDbf.RootPath(), under), features, inDbf.ChecksumAlgo(),
+		db.CurrentVersion(), inDbf.IdNamespace())
+	if err != nil {
+		return err
+	}
+
+	// Called when an error happened and the incomplete database needs to be removed
+	errFn := func(rcvErr error) error {
+		if err := outDbf.Interrupted(); err != nil {
+			return fmt.Errorf("failed to remove the incomplete database %q with error (%w). original error: %w", cfg.OutPath, err, rcvErr)
+		}
+		return rcvErr
+	}
+
+	// Copy the path entries below the subpath while making them relative to the new root.
+	// inIndices[outIdx] is the index of the same entry in the original database.
+	inIndices := make([]int, 0, 1024)
+
+	err = inDbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		if !cfg.IsUnder(pi.Path) {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(under, pi.Path)
+		if err != nil {
+			return err
+		}
+
+		pi.Path = relPath
+		pi.Id = outDbf.IdFromPath(relPath)
+
+		inIndices = append(inIndices, idx)
+		return outDbf.WriteEntry(&pi)
+	})
+	if err != nil {
+		return errFn(fmt.Errorf("failed to copy the entries from %q. %w", cfg.DbPath, err))
+	}
+
+	if err = outDbf.FinishEntries(); err != nil {
+		return errFn(err)
+	}
+
+	if features.HasHashTable() {
+		algo, err := inDbf.HashTableAlgo()
+		if err != nil {
+			return errFn(err)
+		}
+
+		if err = outDbf.StartHashTable(algo); err != nil {
+			return errFn(err)
+		}
+
+		if err = outDbf.FinishHashTable(); err != nil {
+			return errFn(err)
+		}
+
+		cfg.VerbosePrintln("Copying the existing file signature hashes")
+		hashTable, err := inDbf.ReadHashTable(ctx)
+		if err != nil {
+			return errFn(err)
+		}
+
+		var hashTimes db.HashTimes
+		if features.HasHashTimes() {
+			hashTimes, err = inDbf.ReadHashTimes(ctx)
+			if err != nil {
+				return errFn(err)
+			}
+		}
+
+		for outIdx, inIdx := range inIndices {
+			hash, exists := hashTable[inIdx]
+			if !exists {
+				continue
+			}
+
+			if err = outDbf.WriteHashEntryAt(outIdx, hash, hashTimes[inIdx]); err != nil {
+				return errFn(err)
+			}
+		}
+	}
+
+	if err = outDbf.Close(); err != nil {
+		return err
+	}
+
+	cfg.VerbosePrintln(fmt.Sprintf("Copied %d entries", len(inIndices)))
+	cfg.VerbosePrintln("Done!")
+	return nil
+}
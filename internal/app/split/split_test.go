@@ -0,0 +1,128 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package split_test
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/app/split"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplit(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "db.ajfs")
+	outPath := filepath.Join(tempDir, "split.ajfs")
+	expPath := filepath.Join(tempDir, "expected.ajfs")
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: dbPath,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		Root:            "../../testdata/scan",
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	// Splitting should result in the same database as scanning the subpath directly
+	scanCfg.DbPath = expPath
+	scanCfg.Root = "../../testdata/scan/a"
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	cfg := split.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: dbPath,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		UnderConfig: config.UnderConfig{Under: "a/"},
+		OutPath:     outPath,
+	}
+	require.NoError(t, split.Run(context.Background(), cfg))
+
+	expDbf, err := db.OpenDatabase(expPath)
+	require.NoError(t, err)
+	defer expDbf.Close()
+
+	outDbf, err := db.OpenDatabase(outPath)
+	require.NoError(t, err)
+	defer outDbf.Close()
+
+	assert.Equal(t, expDbf.RootPath(), outDbf.RootPath())
+
+	expEntries, err := expDbf.BuildIdToInfoMap(context.Background())
+	require.NoError(t, err)
+	outEntries, err := outDbf.BuildIdToInfoMap(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, expEntries, outEntries)
+
+	expHashes, err := expDbf.BuildIdToHashMap(context.Background())
+	require.NoError(t, err)
+	outHashes, err := outDbf.BuildIdToHashMap(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, expHashes, outHashes)
+}
+
+func TestSplitInvalidSubpath(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "db.ajfs")
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: dbPath,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		Root: "../../testdata/scan",
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	testCases := []struct {
+		desc  string
+		under string
+	}{
+		{desc: "root", under: "."},
+		{desc: "not found", under: "does-not-exist"},
+		{desc: "file", under: "a/2.txt"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			outPath := filepath.Join(t.TempDir(), "split.ajfs")
+			cfg := split.Config{
+				CommonConfig: scanCfg.CommonConfig,
+				UnderConfig:  config.UnderConfig{Under: tC.under},
+				OutPath:      outPath,
+			}
+			assert.Error(t, split.Run(context.Background(), cfg))
+			assert.NoFileExists(t, outPath)
+		})
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package testfilter provides the functionality for ajfs test-filter command.
+package testfilter
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/go-aj/file"
+)
+
+// Config for the ajfs test-filter command.
+type Config struct {
+	config.CommonConfig
+	config.FilterConfig
+
+	Path string // The file or directory to be tested.
+	Root string // The root the filters are applied relative to. Defaults to Path when it is a directory, otherwise to its parent.
+
+	OnlyIncluded bool // Only display the paths that would be included.
+	OnlyExcluded bool // Only display the paths that would not be included.
+}
+
+// Decision made by the filters for a path.
+type Decision int
+
+const (
+	Included       Decision = iota // The path would be walked.
+	NotIncluded                    // The path did not match any of the include filters.
+	Excluded                       // The path matched an exclude filter.
+	ParentExcluded                 // A parent directory would not be walked.
+)
+
+// Stringer implementation.
+func (d Decision) String() string {
+	switch d {
+	case Included:
+		return "included"
+	case NotIncluded:
+		return "not included"
+	case Excluded:
+		return "excluded"
+	case ParentExcluded:
+		return "parent excluded"
+	}
+	return fmt.Sprintf("Decision(%d)", int(d))
+}
+
+// Result of testing a path against the filters.
+type Result struct {
+	Path     string // Path relative to the root.
+	IsDir    bool
+	Decision Decision
+}
+
+// Process the ajfs test-filter command.
+func Run(ctx context.Context, cfg Config) error {
+	included := 0
+	excluded := 0
+
+	err := Test(ctx, cfg, func(r Result) error {
+		if r.Decision == Included {
+			included++
+			if cfg.OnlyExcluded {
+				return nil
+			}
+		} else {
+			excluded++
+			if cfg.OnlyIncluded {
+				return nil
+			}
+		}
+
+		p := r.Path
+		if r.IsDir && (p != ".") {
+			p += string(filepath.Separator)
+		}
+		cfg.Println(fmt.Sprintf("%-15s  %s", r.Decision, p))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	cfg.VerbosePrintln(fmt.Sprintf("\nIncluded: %d\nNot included: %d", included, excluded))
+	return nil
+}
+
+// Test calls fn with the decision made by the filters for the path and, when it is a directory, for each of its
+// descendants that would be visited while scanning. A directory that is not included is reported once and its
+// contents are skipped, the same as a scan would do.
+func Test(ctx context.Context, cfg Config, fn func(r Result) error) error {
+	cfg.setDefaults()
+
+	target, err := file.ExpandPath(cfg.Path)
+	if err != nil {
+		return fmt.Errorf("failed to expand the path %q. %w", cfg.Path, err)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return fmt.Errorf("failed to test the filters on %q. %w", cfg.Path, err)
+	}
+
+	root := cfg.Root
+	if root == "" {
+		root = target
+		if !info.IsDir() {
+			root = filepath.Dir(target)
+		}
+	}
+	root, err = file.ExpandPath(root)
+	if err != nil {
+		return fmt.Errorf("failed to expand the path %q. %w", cfg.Root, err)
+	}
+
+	relTarget, err := filepath.Rel(root, target)
+	if err != nil {
+		return err
+	}
+	if (relTarget == "..") || strings.HasPrefix(relTarget, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("the path %q is not inside the root %q", cfg.Path, root)
+	}
+
+	// A path deep inside the root is only walked when all of its parent directories are walked
+	if relTarget != "." {
+		parts := strings.Split(relTarget, string(filepath.Separator))
+		for i := 1; i < len(parts); i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			relParent := filepath.Join(parts[:i]...)
+			parentInfo, err := os.Stat(filepath.Join(root, relParent))
+			if err != nil {
+				return err
+			}
+
+			decision, err := cfg.decide(relParent, fs.FileInfoToDirEntry(parentInfo))
+			if err != nil {
+				return err
+			}
+			if decision != Included {
+				if err = fn(Result{Path: relParent, IsDir: true, Decision: decision}); err != nil {
+					return err
+				}
+				return fn(Result{Path: relTarget, IsDir: info.IsDir(), Decision: ParentExcluded})
+			}
+		}
+	}
+
+	return filepath.WalkDir(target, func(p string, d fs.DirEntry, rcvErr error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+
+		if rcvErr != nil {
+			// Keep going so that the rest of the hierarchy can still be tested
+			cfg.Errorln(fmt.Sprintf("Unable to read %q. %v", relPath, rcvErr))
+			if (d != nil) && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		decision := Included
+		if relPath != "." {
+			if decision, err = cfg.decide(relPath, d); err != nil {
+				return err
+			}
+		}
+
+		if err = fn(Result{Path: relPath, IsDir: d.IsDir(), Decision: decision}); err != nil {
+			return err
+		}
+
+		if d.IsDir() && (decision != Included) {
+			return fs.SkipDir
+		}
+		return nil
+	})
+}
+
+// Apply the filters in the same order as the walker used for scanning.
+func (cfg *Config) decide(relPath string, d fs.DirEntry) (Decision, error) {
+	includer, excluder := cfg.FileIncluder, cfg.FileExcluder
+	if d.IsDir() {
+		includer, excluder = cfg.DirIncluder, cfg.DirExcluder
+	}
+
+	include, err := includer(relPath, d)
+	if err != nil {
+		return Included, err
+	}
+	if !include {
+		return NotIncluded, nil
+	}
+
+	exclude, err := excluder(relPath, d)
+	if err != nil {
+		return Included, err
+	}
+	if exclude {
+		return Excluded, nil
+	}
+	return Included, nil
+}
+
+func (cfg *Config) setDefaults() {
+	if cfg.DirIncluder == nil {
+		cfg.DirIncluder = file.MatchAlways
+	}
+	if cfg.FileIncluder == nil {
+		cfg.FileIncluder = file.MatchAlways
+	}
+	if cfg.DirExcluder == nil {
+		cfg.DirExcluder = file.MatchNever
+	}
+	if cfg.FileExcluder == nil {
+		cfg.FileExcluder = file.MatchNever
+	}
+}
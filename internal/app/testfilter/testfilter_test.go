@@ -0,0 +1,134 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package testfilter_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/testfilter"
+	"github.com/andrejacobs/ajfs/internal/filter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "docs", "temp"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.pdf"), []byte("1"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "b.txt"), []byte("2"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "docs", "c.pdf"), []byte("3"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "docs", "temp", "d.pdf"), []byte("4"), 0644))
+	return root
+}
+
+func filterConfig(t *testing.T, include []string, exclude []string) config.FilterConfig {
+	t.Helper()
+	var cfg config.FilterConfig
+	var err error
+	cfg.FileIncluder, cfg.DirIncluder, err = filter.ParsePathRegexToMatchPathFn(include, true)
+	require.NoError(t, err)
+	cfg.FileExcluder, cfg.DirExcluder, err = filter.ParsePathRegexToMatchPathFn(exclude, false)
+	require.NoError(t, err)
+	return cfg
+}
+
+func TestTest(t *testing.T) {
+	root := createTestTree(t)
+
+	cfg := testfilter.Config{
+		FilterConfig: filterConfig(t, []string{`f:\.pdf$`}, []string{`d:temp$`}),
+		Path:         root,
+	}
+
+	results := make(map[string]testfilter.Decision)
+	require.NoError(t, testfilter.Test(context.Background(), cfg, func(r testfilter.Result) error {
+		results[filepath.ToSlash(r.Path)] = r.Decision
+		return nil
+	}))
+
+	assert.Equal(t, map[string]testfilter.Decision{
+		".":          testfilter.Included,
+		"a.pdf":      testfilter.Included,
+		"b.txt":      testfilter.NotIncluded,
+		"docs":       testfilter.Included,
+		"docs/c.pdf": testfilter.Included,
+		"docs/temp":  testfilter.Excluded,
+	}, results)
+}
+
+func TestTestSinglePath(t *testing.T) {
+	root := createTestTree(t)
+
+	test := func(p string) []testfilter.Result {
+		cfg := testfilter.Config{
+			FilterConfig: filterConfig(t, nil, []string{`d:temp$`}),
+			Path:         filepath.Join(root, p),
+			Root:         root,
+		}
+
+		var results []testfilter.Result
+		require.NoError(t, testfilter.Test(context.Background(), cfg, func(r testfilter.Result) error {
+			results = append(results, r)
+			return nil
+		}))
+		return results
+	}
+
+	assert.Equal(t, []testfilter.Result{
+		{Path: filepath.Join("docs", "c.pdf"), Decision: testfilter.Included},
+	}, test(filepath.Join("docs", "c.pdf")))
+
+	assert.Equal(t, []testfilter.Result{
+		{Path: filepath.Join("docs", "temp"), IsDir: true, Decision: testfilter.Excluded},
+		{Path: filepath.Join("docs", "temp", "d.pdf"), Decision: testfilter.ParentExcluded},
+	}, test(filepath.Join("docs", "temp", "d.pdf")))
+
+	// The path must be inside the root
+	cfg := testfilter.Config{Path: root, Root: filepath.Join(root, "docs")}
+	assert.ErrorContains(t, testfilter.Test(context.Background(), cfg, func(r testfilter.Result) error { return nil }),
+		"is not inside the root")
+}
+
+func TestRun(t *testing.T) {
+	root := createTestTree(t)
+
+	var outBuffer bytes.Buffer
+	cfg := testfilter.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+		},
+		FilterConfig: filterConfig(t, nil, []string{`d:temp$`, `f:\.txt$`}),
+		Path:         root,
+		OnlyExcluded: true,
+	}
+	require.NoError(t, testfilter.Run(context.Background(), cfg))
+
+	expected := "excluded         b.txt\n" +
+		"excluded         " + filepath.Join("docs", "temp") + string(filepath.Separator) + "\n"
+	assert.Equal(t, expected, outBuffer.String())
+}
@@ -0,0 +1,197 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package top provides the functionality for ajfs top command.
+package top
+
+import (
+	"cmp"
+	"container/heap"
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/human"
+)
+
+// Config for the ajfs top command.
+type Config struct {
+	config.CommonConfig
+	config.UnderConfig
+
+	Files int // Number of the largest files to display. Zero means none.
+	Dirs  int // Number of the largest directories to display. Zero means none.
+
+	DisplayFullPaths bool // If true then each path entry will be prefixed with the root path of the database.
+	HumanSizes       bool // Display sizes in a human friendly format (e.g. 1.2 GB).
+}
+
+// Process the ajfs top command.
+func Run(ctx context.Context, cfg Config) error {
+	if cfg.Files < 0 || cfg.Dirs < 0 {
+		return fmt.Errorf("the number of files and directories to display can't be negative")
+	}
+
+	dbf, err := db.OpenDatabase(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+	defer dbf.Close()
+	cfg.WarnIfLimited(dbf)
+
+	files := newRanking(cfg.Files)
+	dirSizes := make(map[string]uint64, 64)
+
+	err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		if !pi.IsFile() || !cfg.IsUnder(pi.Path) {
+			return nil
+		}
+
+		files.add(pi.Path, pi.Size)
+
+		// The size of a directory is the total size of all the files at or below it
+		if cfg.Dirs > 0 {
+			for dir := filepath.Dir(pi.Path); dir != "." && cfg.IsUnder(dir); dir = filepath.Dir(dir) {
+				dirSizes[dir] += pi.Size
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	dirs := newRanking(cfg.Dirs)
+	for dir, size := range dirSizes {
+		dirs.add(dir, size)
+	}
+
+	if cfg.Files > 0 {
+		cfg.Println("Largest files:")
+		for _, e := range files.sorted() {
+			cfg.Println(cfg.format(dbf, e, false))
+		}
+	}
+
+	if cfg.Dirs > 0 {
+		if cfg.Files > 0 {
+			cfg.Println()
+		}
+		cfg.Println("Largest directories:")
+		for _, e := range dirs.sorted() {
+			cfg.Println(cfg.format(dbf, e, true))
+		}
+	}
+
+	return nil
+}
+
+func (cfg *Config) format(dbf *db.DatabaseFile, e rankedEntry, isDir bool) string {
+	name := e.path
+	if cfg.DisplayFullPaths {
+		name = filepath.Join(dbf.RootPath(), name)
+	}
+	if isDir {
+		name += string(filepath.Separator)
+	}
+
+	if cfg.HumanSizes {
+		return fmt.Sprintf("%10s  %s", human.Bytes(e.size), name)
+	}
+	return fmt.Sprintf("%12d  %s", e.size, name)
+}
+
+//-----------------------------------------------------------------------------
+
+type rankedEntry struct {
+	path string
+	size uint64
+}
+
+// Orders by size and then by path in reverse so that equal sizes are displayed alphabetically.
+func compareRanked(a, b rankedEntry) int {
+	if c := cmp.Compare(a.size, b.size); c != 0 {
+		return c
+	}
+	return strings.Compare(b.path, a.path)
+}
+
+// Keeps track of the n largest entries without having to hold all of them in memory.
+type ranking struct {
+	limit   int
+	entries rankingHeap
+}
+
+func newRanking(limit int) *ranking {
+	return &ranking{
+		limit:   limit,
+		entries: make(rankingHeap, 0, limit),
+	}
+}
+
+func (r *ranking) add(p string, size uint64) {
+	if r.limit < 1 {
+		return
+	}
+
+	e := rankedEntry{path: p, size: size}
+	if len(r.entries) < r.limit {
+		heap.Push(&r.entries, e)
+		return
+	}
+
+	// Replace the smallest entry
+	if compareRanked(e, r.entries[0]) > 0 {
+		r.entries[0] = e
+		heap.Fix(&r.entries, 0)
+	}
+}
+
+// Return the entries from the largest to the smallest.
+func (r *ranking) sorted() []rankedEntry {
+	result := slices.Clone(r.entries)
+	slices.SortFunc(result, func(a, b rankedEntry) int {
+		return compareRanked(b, a)
+	})
+	return result
+}
+
+// Min-heap used by [container/heap].
+type rankingHeap []rankedEntry
+
+func (h rankingHeap) Len() int           { return len(h) }
+func (h rankingHeap) Less(i, j int) bool { return compareRanked(h[i], h[j]) < 0 }
+func (h rankingHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *rankingHeap) Push(x any) {
+	*h = append(*h, x.(rankedEntry))
+}
+
+func (h *rankingHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
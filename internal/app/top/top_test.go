@@ -0,0 +1,120 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package top_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/ajfs/internal/app/top"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+	_ = os.Remove(tempFile)
+	defer os.Remove(tempFile)
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Root: "../../testdata/scan",
+	}
+
+	err := scan.Run(context.Background(), scanCfg)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		desc     string
+		files    int
+		dirs     int
+		under    string
+		expected string
+	}{
+		{
+			desc:  "files and dirs",
+			files: 3,
+			dirs:  4,
+			expected: `Largest files:
+         961  b/b1/b1a/7.txt
+         793  a/a2/6.txt
+         617  a/2.txt
+
+Largest directories:
+        3796  a/
+        1929  b/
+        1929  b/b1/
+        1929  b/b1/b1a/
+`,
+		},
+		{
+			desc:  "equal sizes are sorted by path",
+			files: 7,
+			expected: `Largest files:
+         961  b/b1/b1a/7.txt
+         793  a/a2/6.txt
+         617  a/2.txt
+         616  c/c.txt
+         503  a/a1/a1a/a1a1/4.txt
+         484  1.txt
+         484  a/a1/a1a/a1a1/1.txt
+`,
+		},
+		{
+			desc:  "only dirs under",
+			dirs:  10,
+			under: "a/a1",
+			expected: `Largest directories:
+        1467  a/a1/
+         987  a/a1/a1a/
+         987  a/a1/a1a/a1a1/
+         480  a/a1/a1b/
+`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			cfg := top.Config{
+				CommonConfig: config.CommonConfig{
+					DbPath: tempFile,
+					Stdout: &buf,
+					Stderr: io.Discard,
+				},
+				UnderConfig: config.UnderConfig{Under: tC.under},
+				Files:       tC.files,
+				Dirs:        tC.dirs,
+			}
+
+			require.NoError(t, top.Run(context.Background(), cfg))
+			assert.Equal(t, tC.expected, buf.String())
+		})
+	}
+}
@@ -22,8 +22,11 @@
 package tosync
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"path/filepath"
+	"time"
 
 	"github.com/andrejacobs/ajfs/internal/app/config"
 	"github.com/andrejacobs/ajfs/internal/app/diff"
@@ -42,19 +45,24 @@ type Config struct {
 	OnlyHashes bool
 	FullPaths  bool
 
+	Summary   bool   // Display the total number of files and bytes that need to be synced.
+	Bandwidth uint64 // Bytes per second used to estimate the transfer time. 0 means no estimate. Implies Summary.
+
+	Match diff.MatchMode // How the files of both sides are paired up. Not used when only the hashes are compared.
+
 	Fn diff.CompareFn
 }
 
 // Process the ajfs diff command.
-func Run(cfg Config) error {
+func Run(ctx context.Context, cfg Config) error {
 	if cfg.Fn == nil {
 		panic("expected a compare function")
 	}
 
-	return tosync(cfg)
+	return tosync(ctx, cfg)
 }
 
-func tosync(cfg Config) error {
+func tosync(ctx context.Context, cfg Config) error {
 	cfg.VerbosePrintln("Checking which files would need to be synced")
 	cfg.VerbosePrintln(fmt.Sprintf("  from LHS: %q", cfg.LhsPath))
 	cfg.VerbosePrintln(fmt.Sprintf("    to RHS: %q\n", cfg.RhsPath))
@@ -64,15 +72,17 @@ func tosync(cfg Config) error {
 		return fmt.Errorf("failed to open left hand side database. %w", err)
 	}
 	defer lhs.Close()
+	cfg.WarnIfLimited(lhs)
 
 	rhs, err := db.OpenDatabase(cfg.RhsPath)
 	if err != nil {
 		return fmt.Errorf("failed to open right hand side database. %w", err)
 	}
 	defer rhs.Close()
+	cfg.WarnIfLimited(rhs)
 
 	if cfg.OnlyHashes {
-		err = compareOnlyHashes(cfg, lhs, rhs, cfg.Fn)
+		err = compareOnlyHashes(ctx, cfg, lhs, rhs, cfg.Fn)
 		if err != nil {
 			if err != diff.SkipAll {
 				return err
@@ -80,7 +90,7 @@ func tosync(cfg Config) error {
 			return nil
 		}
 	} else {
-		err = compare(cfg, lhs, rhs, cfg.Fn)
+		err = compare(ctx, cfg, lhs, rhs, cfg.Fn)
 		if err != nil {
 			if err != diff.SkipAll {
 				return err
@@ -92,13 +102,14 @@ func tosync(cfg Config) error {
 	return nil
 }
 
-func compare(cfg Config, lhs *db.DatabaseFile, rhs *db.DatabaseFile, fn diff.CompareFn) error {
+func compare(ctx context.Context, cfg Config, lhs *db.DatabaseFile, rhs *db.DatabaseFile, fn diff.CompareFn) error {
 	changedMask := ^diff.ChangedFlags(diff.ChangedModTime | diff.ChangedMode)
 
 	count := 0
 	totalSize := uint64(0)
 
-	err := diff.CompareDatabases(lhs, rhs, true, func(d diff.Diff) error {
+	cfg.VerbosePrintln(fmt.Sprintf("Pairing files by %s\n", cfg.Match))
+	err := diff.CompareDatabasesWithMode(ctx, lhs, rhs, true, cfg.Match, func(d diff.Diff) error {
 		// Ignore if the entry is a directory or if nothing has changed
 		if d.IsDir || (d.Type == diff.TypeNothing) {
 			return nil
@@ -123,12 +134,40 @@ func compare(cfg Config, lhs *db.DatabaseFile, rhs *db.DatabaseFile, fn diff.Com
 		return err
 	}
 
-	cfg.VerbosePrintln(fmt.Sprintf("\nTotal of %d files with a size of %d bytes [%s] need to be synced", count, totalSize, human.Bytes(totalSize)))
-
+	printTotals(cfg, count, totalSize)
 	return nil
 }
 
-func compareOnlyHashes(cfg Config, lhs *db.DatabaseFile, rhs *db.DatabaseFile, fn diff.CompareFn) error {
+// Display the totals that need to be synced along with the estimated transfer time.
+// The totals are only displayed in verbose mode unless a summary was requested.
+func printTotals(cfg Config, count int, totalSize uint64) {
+	totals := fmt.Sprintf("\nTotal of %d files with a size of %d bytes [%s] need to be synced", count, totalSize, human.Bytes(totalSize))
+	if !cfg.Summary && cfg.Bandwidth == 0 {
+		cfg.VerbosePrintln(totals)
+		return
+	}
+
+	cfg.Println(totals)
+	if cfg.Bandwidth > 0 {
+		cfg.Println(fmt.Sprintf("Estimated transfer time at %s/s: %s", human.Bytes(cfg.Bandwidth), EstimateTransferTime(totalSize, cfg.Bandwidth)))
+	}
+}
+
+// EstimateTransferTime returns how long it would take to transfer size bytes at the bandwidth (bytes per second).
+// The estimate is rounded up to the nearest second.
+func EstimateTransferTime(size uint64, bandwidth uint64) time.Duration {
+	if bandwidth == 0 {
+		panic("expected a bandwidth larger than 0")
+	}
+
+	seconds := size / bandwidth
+	if size%bandwidth != 0 {
+		seconds++
+	}
+	return time.Duration(min(seconds, uint64(math.MaxInt64/time.Second))) * time.Second //nolint:gosec // disable G115
+}
+
+func compareOnlyHashes(ctx context.Context, cfg Config, lhs *db.DatabaseFile, rhs *db.DatabaseFile, fn diff.CompareFn) error {
 	if !lhs.Features().HasHashTable() {
 		return fmt.Errorf("left hand side database %q does not have a hash table", lhs.Path())
 	}
@@ -148,15 +187,15 @@ func compareOnlyHashes(cfg Config, lhs *db.DatabaseFile, rhs *db.DatabaseFile, f
 	}
 
 	if lhsAlgo != rhsAlgo {
-		return fmt.Errorf("can't compare the two databases because left uses %q and right uses %q", lhsAlgo, rhsAlgo)
+		return fmt.Errorf("can't compare the two databases because left uses %q and right uses %q", db.AlgoString(lhsAlgo), db.AlgoString(rhsAlgo))
 	}
 
-	lhsHashes, err := lhs.BuildHashStrToIndexMap()
+	lhsHashes, err := lhs.BuildHashStrToIndexMap(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get the left hand side's hash table. %w", err)
 	}
 
-	rhsHashes, err := rhs.BuildHashStrToIndexMap()
+	rhsHashes, err := rhs.BuildHashStrToIndexMap(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get the right hand side's hash table. %w", err)
 	}
@@ -164,6 +203,9 @@ func compareOnlyHashes(cfg Config, lhs *db.DatabaseFile, rhs *db.DatabaseFile, f
 	// What exists only on the LHS (removed from RHS)
 	lhsOnly := collection.MapDifference(lhsHashes, rhsHashes)
 
+	count := 0
+	totalSize := uint64(0)
+
 	for _, v := range lhsOnly {
 		pi, err := lhs.ReadEntryAtIndex(v)
 		if err != nil {
@@ -174,6 +216,7 @@ func compareOnlyHashes(cfg Config, lhs *db.DatabaseFile, rhs *db.DatabaseFile, f
 			Type:  diff.TypeLeftOnly,
 			Id:    pi.Id,
 			Path:  pi.Path,
+			Size:  pi.Size,
 			IsDir: pi.IsDir(),
 		}
 
@@ -181,11 +224,15 @@ func compareOnlyHashes(cfg Config, lhs *db.DatabaseFile, rhs *db.DatabaseFile, f
 			d.Path = filepath.Join(lhs.RootPath(), d.Path)
 		}
 
+		count++
+		totalSize += pi.Size
+
 		err = fn(d)
 		if err != nil {
 			return err
 		}
 	}
 
+	printTotals(cfg, count, totalSize)
 	return nil
 }
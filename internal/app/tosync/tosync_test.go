@@ -21,11 +21,14 @@
 package tosync_test
 
 import (
+	"bytes"
+	"context"
 	"io"
 	"os"
 	"path/filepath"
 	"slices"
 	"testing"
+	"time"
 
 	"github.com/andrejacobs/ajfs/internal/app/config"
 	"github.com/andrejacobs/ajfs/internal/app/diff"
@@ -63,7 +66,7 @@ func TestToSync(t *testing.T) {
 		return nil
 	}
 
-	require.NoError(t, tosync.Run(cfg))
+	require.NoError(t, tosync.Run(context.Background(), cfg))
 
 	expected := []string{
 		"blank.txt",
@@ -76,6 +79,47 @@ func TestToSync(t *testing.T) {
 	assert.Equal(t, expected, result)
 }
 
+func TestToSyncSummary(t *testing.T) {
+	aPath := filepath.Join("testdata", "../../../testdata/need-sync/a")
+	bPath := filepath.Join("testdata", "../../../testdata/need-sync/b")
+
+	lhsPath, rhsPath, err := makeTwoDatabases(aPath, bPath, false, false)
+	require.NoError(t, err)
+	defer func() {
+		_ = os.Remove(lhsPath)
+		_ = os.Remove(rhsPath)
+	}()
+
+	var outBuffer bytes.Buffer
+	cfg := tosync.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+		},
+		LhsPath:   lhsPath,
+		RhsPath:   rhsPath,
+		Bandwidth: 10,
+		Fn:        func(d diff.Diff) error { return nil },
+	}
+
+	require.NoError(t, tosync.Run(context.Background(), cfg))
+	assert.Contains(t, outBuffer.String(), "Total of 2 files with a size of 24 bytes [24 B] need to be synced\n")
+	assert.Contains(t, outBuffer.String(), "Estimated transfer time at 10 B/s: 3s\n")
+
+	// Totals are only displayed in verbose mode by default
+	outBuffer.Reset()
+	cfg.Bandwidth = 0
+	require.NoError(t, tosync.Run(context.Background(), cfg))
+	assert.Empty(t, outBuffer.String())
+}
+
+func TestEstimateTransferTime(t *testing.T) {
+	assert.Equal(t, time.Duration(0), tosync.EstimateTransferTime(0, 100))
+	assert.Equal(t, time.Second, tosync.EstimateTransferTime(1, 100))
+	assert.Equal(t, 2*time.Second, tosync.EstimateTransferTime(200, 100))
+	assert.Equal(t, 10*time.Hour, tosync.EstimateTransferTime(36*1000*1000*1000*1000, 1000*1000*1000))
+}
+
 func TestToSyncNothing(t *testing.T) {
 	aPath := filepath.Join("testdata", "../../../testdata/need-sync/a")
 
@@ -100,7 +144,7 @@ func TestToSyncNothing(t *testing.T) {
 		return nil
 	}
 
-	require.NoError(t, tosync.Run(cfg))
+	require.NoError(t, tosync.Run(context.Background(), cfg))
 }
 
 func TestToSyncOnlyHashes(t *testing.T) {
@@ -131,7 +175,7 @@ func TestToSyncOnlyHashes(t *testing.T) {
 		return nil
 	}
 
-	require.NoError(t, tosync.Run(cfg))
+	require.NoError(t, tosync.Run(context.Background(), cfg))
 
 	expected := []string{
 		"blank.txt",
@@ -168,7 +212,7 @@ func TestToSyncOnlyHashesWithDifferentAlgos(t *testing.T) {
 		return nil
 	}
 
-	require.ErrorContains(t, tosync.Run(cfg), "can't compare the two databases")
+	require.ErrorContains(t, tosync.Run(context.Background(), cfg), "can't compare the two databases")
 }
 
 //-----------------------------------------------------------------------------
@@ -191,7 +235,7 @@ func makeTwoDatabases(scanA string, scanB string, hashes bool, differentAlgos bo
 		cfg.Algo = ajhash.AlgoSHA1
 	}
 
-	if err := scan.Run(cfg); err != nil {
+	if err := scan.Run(context.Background(), cfg); err != nil {
 		_ = os.Remove(lhsPath)
 		return "", "", err
 	}
@@ -206,7 +250,7 @@ func makeTwoDatabases(scanA string, scanB string, hashes bool, differentAlgos bo
 		cfg.Algo = ajhash.AlgoSHA256
 	}
 
-	if err := scan.Run(cfg); err != nil {
+	if err := scan.Run(context.Background(), cfg); err != nil {
 		_ = os.Remove(lhsPath)
 		_ = os.Remove(rhsPath)
 		return "", "", err
@@ -22,9 +22,11 @@
 package tree
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/collate"
 	"github.com/andrejacobs/ajfs/internal/db"
 	"github.com/andrejacobs/ajfs/internal/path"
 	itree "github.com/andrejacobs/ajfs/internal/tree"
@@ -33,52 +35,93 @@ import (
 // Config for the ajfs tree command.
 type Config struct {
 	config.CommonConfig
+	config.UnderConfig
 	Subpath string
 
 	OnlyDirs bool
 	Limit    int
+	Sizes    bool // Display sizes using the stored directory statistics.
+
+	Collator *collate.Collator // [optional] Sort the entries in the order of a language instead of by the raw bytes.
 }
 
 // Process the ajfs info command.
-func Run(cfg Config) error {
+func Run(ctx context.Context, cfg Config) error {
 
-	tr, err := FromDatabase(cfg.DbPath, cfg.OnlyDirs)
+	tr, err := fromDatabase(ctx, cfg.DbPath, cfg.OnlyDirs, cfg.Under, cfg.Sizes)
 	if err != nil {
 		return err
 	}
 
+	opts := itree.PrintOptions{
+		Limit: cfg.Limit,
+		Sizes: cfg.Sizes,
+		Color: cfg.Styled,
+	}
+	if cfg.Collator != nil {
+		opts.Order = itree.NewOrder(cfg.Collator.Compare)
+	}
+
 	if cfg.Subpath != "" {
 		node := tr.Find(cfg.Subpath)
 		if node == nil {
 			return fmt.Errorf("failed to find the path %q in the database %q", cfg.Subpath, cfg.DbPath)
 		}
-		node.PrintWithLimit(cfg.Stdout, cfg.Limit)
+		node.PrintWithOptions(cfg.Stdout, opts)
 	} else {
-		tr.PrintWithLimit(cfg.Stdout, cfg.Limit)
+		tr.PrintWithOptions(cfg.Stdout, opts)
 	}
 
 	return nil
 }
 
 // Create a tree from the path entries in an ajfs database.
-func FromDatabase(dbPath string, onlyDirs bool) (itree.Tree, error) {
+// If under is not empty then only the entries at or below this relative path will be inserted.
+func FromDatabase(ctx context.Context, dbPath string, onlyDirs bool, under string) (itree.Tree, error) {
+	return fromDatabase(ctx, dbPath, onlyDirs, under, false)
+}
+
+// Create a tree from the path entries in an ajfs database.
+// If dirSizes is true then the directory sizes are set from the stored directory statistics.
+func fromDatabase(ctx context.Context, dbPath string, onlyDirs bool, under string, dirSizes bool) (itree.Tree, error) {
 	dbf, err := db.OpenDatabase(dbPath)
 	if err != nil {
 		return itree.Tree{}, err
 	}
 	defer dbf.Close()
 
+	var dirStats db.DirStatsTable
+	if dirSizes {
+		if !dbf.Features().HasDirStats() || dbf.Limited() {
+			return itree.Tree{}, fmt.Errorf("the database %q does not contain the directory statistics. Use \"ajfs scan --dir-stats\" to create them", dbPath)
+		}
+
+		dirStats, err = dbf.ReadDirStats(ctx)
+		if err != nil {
+			return itree.Tree{}, err
+		}
+	}
+
 	tr := itree.New(dbf.RootPath())
+	underCfg := config.UnderConfig{Under: under}
 
-	err = dbf.ReadAllEntries(func(idx int, pi path.Info) error {
+	err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
 		if onlyDirs && !pi.IsDir() {
 			return nil
 		}
 
+		if !underCfg.IsUnder(pi.Path) {
+			return nil
+		}
+
 		node := tr.Insert(pi)
 		if node == nil {
 			return fmt.Errorf("failed to insert new node into the tree (index = %d, path = %q)", idx, pi.Path)
 		}
+
+		if s, exists := dirStats[idx]; exists {
+			node.DirSize = s.Size
+		}
 		return nil
 	})
 	if err != nil {
@@ -89,8 +132,9 @@ func FromDatabase(dbPath string, onlyDirs bool) (itree.Tree, error) {
 }
 
 // Create a signatured tree from the path entries in an ajfs database.
-func SignaturedTreeFromDatabase(dbPath string) (itree.SignaturedTree, error) {
-	tr, err := FromDatabase(dbPath, false)
+// If under is not empty then only the entries at or below this relative path will be inserted.
+func SignaturedTreeFromDatabase(ctx context.Context, dbPath string, under string) (itree.SignaturedTree, error) {
+	tr, err := FromDatabase(ctx, dbPath, false, under)
 	if err != nil {
 		return itree.SignaturedTree{}, err
 	}
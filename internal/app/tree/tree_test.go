@@ -22,6 +22,7 @@ package tree_test
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"os"
 	"os/exec"
@@ -50,7 +51,7 @@ func TestRun(t *testing.T) {
 		Root: "../../testdata/scan",
 	}
 
-	err := scan.Run(scanCfg)
+	err := scan.Run(context.Background(), scanCfg)
 	require.NoError(t, err)
 
 	var outBuffer bytes.Buffer
@@ -64,7 +65,7 @@ func TestRun(t *testing.T) {
 		},
 	}
 
-	err = tree.Run(config)
+	err = tree.Run(context.Background(), config)
 	require.NoError(t, err)
 
 	absRoot, err := filepath.Abs(scanCfg.Root)
@@ -132,7 +133,7 @@ func TestSubpath(t *testing.T) {
 		Root: "../../testdata/scan",
 	}
 
-	err := scan.Run(scanCfg)
+	err := scan.Run(context.Background(), scanCfg)
 	require.NoError(t, err)
 
 	var outBuffer bytes.Buffer
@@ -147,7 +148,7 @@ func TestSubpath(t *testing.T) {
 		Subpath: "a/a1",
 	}
 
-	err = tree.Run(config)
+	err = tree.Run(context.Background(), config)
 	require.NoError(t, err)
 
 	expected := `a1
@@ -181,7 +182,7 @@ func TestSubpathDoesNotExist(t *testing.T) {
 		Root: "../../testdata/scan",
 	}
 
-	err := scan.Run(scanCfg)
+	err := scan.Run(context.Background(), scanCfg)
 	require.NoError(t, err)
 
 	var outBuffer bytes.Buffer
@@ -196,7 +197,7 @@ func TestSubpathDoesNotExist(t *testing.T) {
 		Subpath: "the/quick/brown/fox",
 	}
 
-	err = tree.Run(config)
+	err = tree.Run(context.Background(), config)
 	assert.ErrorContains(t, err, "failed to find the path")
 }
 
@@ -214,7 +215,7 @@ func TestOnlyDirs(t *testing.T) {
 		Root: "../../testdata/scan",
 	}
 
-	err := scan.Run(scanCfg)
+	err := scan.Run(context.Background(), scanCfg)
 	require.NoError(t, err)
 
 	var outBuffer bytes.Buffer
@@ -229,7 +230,7 @@ func TestOnlyDirs(t *testing.T) {
 		OnlyDirs: true,
 	}
 
-	err = tree.Run(config)
+	err = tree.Run(context.Background(), config)
 	require.NoError(t, err)
 
 	absRoot, err := filepath.Abs(scanCfg.Root)
@@ -269,7 +270,7 @@ func TestLimit(t *testing.T) {
 		Root: "../../testdata/scan",
 	}
 
-	err := scan.Run(scanCfg)
+	err := scan.Run(context.Background(), scanCfg)
 	require.NoError(t, err)
 
 	var outBuffer bytes.Buffer
@@ -285,7 +286,7 @@ func TestLimit(t *testing.T) {
 		Limit:    2,
 	}
 
-	err = tree.Run(config)
+	err = tree.Run(context.Background(), config)
 	require.NoError(t, err)
 
 	absRoot, err := filepath.Abs(scanCfg.Root)
@@ -309,7 +310,7 @@ func TestLimit(t *testing.T) {
 	outBuffer.Reset()
 
 	config.Subpath = "a"
-	err = tree.Run(config)
+	err = tree.Run(context.Background(), config)
 	require.NoError(t, err)
 
 	expected = `a
@@ -326,3 +327,51 @@ func TestLimit(t *testing.T) {
 	assert.Equal(t, "", errBuffer.String())
 
 }
+
+func TestRunWithSizes(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-testing")
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		Root:     "../../testdata/scan",
+		DirStats: true,
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	var outBuffer bytes.Buffer
+	cfg := tree.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &outBuffer,
+			Stderr: io.Discard,
+			DbPath: tempFile,
+		},
+		OnlyDirs: true,
+		Limit:    1,
+		Sizes:    true,
+	}
+	require.NoError(t, tree.Run(context.Background(), cfg))
+
+	absRoot, err := filepath.Abs(scanCfg.Root)
+	require.NoError(t, err)
+
+	expected := absRoot + `
+├── [  3.8 kB]  a
+├── [  1.9 kB]  b
+└── [   616 B]  c
+
+4 directories, 0 files
+`
+	assert.Equal(t, expected, outBuffer.String())
+
+	// Directory sizes require the directory statistics
+	scanCfg.DirStats = false
+	scanCfg.ForceOverride = true
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	err = tree.Run(context.Background(), cfg)
+	require.ErrorContains(t, err, "does not contain the directory statistics")
+}
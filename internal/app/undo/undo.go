@@ -0,0 +1,71 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package undo provides the functionality for ajfs undo command.
+package undo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/trash"
+)
+
+// Config for the ajfs undo command.
+type Config struct {
+	config.CommonConfig
+	ManifestPath string // Path to the undo manifest written when files were moved into the trash.
+	DryRun       bool   // Only display which files would be restored.
+}
+
+// Process the ajfs undo command.
+func Run(ctx context.Context, cfg Config) error {
+	if cfg.DryRun {
+		entries, err := trash.ReadManifest(cfg.ManifestPath)
+		if err != nil {
+			return err
+		}
+		for i := len(entries) - 1; i >= 0; i-- {
+			cfg.Println(fmt.Sprintf("[DRY-RUN] restore %q from %q", entries[i].Original, entries[i].Trashed))
+		}
+		return nil
+	}
+
+	restored := 0
+	failed := 0
+	err := trash.Restore(cfg.ManifestPath, func(entry trash.Entry, err error) {
+		if err != nil {
+			cfg.Errorln(err)
+			failed++
+			return
+		}
+		cfg.VerbosePrintln(fmt.Sprintf("Restored %q", entry.Original))
+		restored++
+	})
+	if err != nil {
+		return err
+	}
+
+	cfg.Println(fmt.Sprintf("Restored %d files", restored))
+	if failed > 0 {
+		return fmt.Errorf("failed to restore %d files, these are kept in the undo manifest %q", failed, cfg.ManifestPath)
+	}
+	return nil
+}
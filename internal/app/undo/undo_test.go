@@ -0,0 +1,73 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package undo_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/undo"
+	"github.com/andrejacobs/ajfs/internal/trash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUndo(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "1.txt")
+	require.NoError(t, os.WriteFile(filePath, []byte("1"), 0644))
+
+	manifestPath := filepath.Join(tempDir, "undo.jsonl")
+	tr, err := trash.New(manifestPath)
+	require.NoError(t, err)
+	tr.Dir = filepath.Join(tempDir, "trash")
+	_, err = tr.Move(filePath)
+	require.NoError(t, err)
+	require.NoError(t, tr.Close())
+
+	var out bytes.Buffer
+	cfg := undo.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: &out,
+			Stderr: io.Discard,
+		},
+		ManifestPath: manifestPath,
+		DryRun:       true,
+	}
+
+	require.NoError(t, undo.Run(context.Background(), cfg))
+	assert.Contains(t, out.String(), "[DRY-RUN] restore")
+	assert.NoFileExists(t, filePath)
+
+	out.Reset()
+	cfg.DryRun = false
+	require.NoError(t, undo.Run(context.Background(), cfg))
+	assert.Equal(t, "Restored 1 files\n", out.String())
+	assert.FileExists(t, filePath)
+	assert.NoFileExists(t, manifestPath)
+
+	// Already restored
+	require.Error(t, undo.Run(context.Background(), cfg))
+}
@@ -26,12 +26,14 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/andrejacobs/ajfs/internal/app/config"
 	"github.com/andrejacobs/ajfs/internal/app/resume"
 	"github.com/andrejacobs/ajfs/internal/app/scan"
 	"github.com/andrejacobs/ajfs/internal/db"
 	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
 	"github.com/andrejacobs/go-aj/file"
 )
 
@@ -40,22 +42,44 @@ type Config struct {
 	config.CommonConfig
 	config.FilterConfig
 
-	KeepCopyPath string // Path to where a copy of the existing database should be kept
+	KeepCopyPath  string // Path to where a copy of the existing database should be kept
+	Force         bool   // Update the database even if it has been sealed.
+	HashCachePath string // Path to the cache used to reuse previously calculated hashes. Empty disables the cache.
+
+	SkipUnreadable bool // Record paths that can't be read due to permissions and continue scanning.
+	Sorted         bool // Write the entries in lexicographic path order instead of walk order.
+	SortHashes     bool // Sort the hash table by hash. A hash table that was already sorted will be sorted again.
+	Archives       bool // Record the members of tar and zip archives as virtual entries. Always done when the existing database recorded them.
+
+	FlushInterval time.Duration // Interval at which calculated hashes are written to the database. 0 writes each hash immediately.
+	SyncPolicy    db.SyncPolicy // How often the database is synced to disk while the hashes are written.
 }
 
 // Process the ajfs update command.
-func Run(cfg Config) error {
+func Run(ctx context.Context, cfg Config) error {
 	cfg.VerbosePrintln(fmt.Sprintf("Updating database file at %q", cfg.DbPath))
 
+	if err := db.EnsureNotSealed(cfg.DbPath, cfg.Force); err != nil {
+		return err
+	}
+	// The updated database is created from scratch and thus needs to be sealed again
+	sealed, err := db.IsSealed(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+	pathDisplay, err := db.ReadPathDisplay(cfg.DbPath)
+	if err != nil {
+		return err
+	}
+
 	if cfg.KeepCopyPath != "" {
-		var err error
 		cfg.KeepCopyPath, err = file.ExpandPath(cfg.KeepCopyPath)
 		if err != nil {
 			return fmt.Errorf("failed to expand path %q. %w", cfg.KeepCopyPath, err)
 		}
 
 		cfg.VerbosePrintln(fmt.Sprintf("creating a copy at: %q", cfg.KeepCopyPath))
-		_, err = file.CopyFile(context.Background(), cfg.DbPath, cfg.KeepCopyPath)
+		_, err = file.CopyFile(ctx, cfg.DbPath, cfg.KeepCopyPath)
 		if err != nil {
 			return fmt.Errorf("failed to create a copy of the database file %q to %q. %w", cfg.DbPath, cfg.KeepCopyPath, err)
 		}
@@ -95,7 +119,15 @@ func Run(cfg Config) error {
 		CommonConfig: cfg.CommonConfig,
 		FilterConfig: cfg.FilterConfig,
 		Root:         oldDbf.RootPath(),
+		ChecksumAlgo: oldDbf.ChecksumAlgo(),
+		DirStats:     oldDbf.Features().HasDirStats(),
+		HashTimes:    oldDbf.Features().HasHashTimes(),
 		InitOnly:     true,
+		IdNamespace:  oldDbf.IdNamespace(),
+
+		SkipUnreadable: cfg.SkipUnreadable,
+		Sorted:         cfg.Sorted,
+		Archives:       cfg.Archives || oldDbf.Archives(),
 	}
 
 	if oldDbf.Features().HasHashTable() {
@@ -106,18 +138,30 @@ func Run(cfg Config) error {
 		}
 	}
 
-	if err = scan.Run(scanCfg); err != nil {
+	if err = scan.Run(ctx, scanCfg); err != nil {
 		return errFn(err)
 	}
 
 	// Copy existing hashes over for matching entries
 	if oldDbf.Features().HasHashTable() {
+		donePhase := cfg.StartPhase("copying existing hashes")
 		newDbf, err = db.ResumeDatabase(cfg.DbPath)
 		if err != nil {
 			return errFn(err)
 		}
+		newDbf.SetHashFlushInterval(cfg.FlushInterval)
+		newDbf.SetSyncPolicy(cfg.SyncPolicy)
 
-		err = oldDbf.ReadAllEntriesWithHashes(func(idx int, pi path.Info, hash []byte) error {
+		// Keep the time at which the copied hashes were originally calculated
+		var hashTimes db.HashTimes
+		if oldDbf.Features().HasHashTimes() {
+			hashTimes, err = oldDbf.ReadHashTimes(ctx)
+			if err != nil {
+				return errFn(err)
+			}
+		}
+
+		err = oldDbf.ReadAllEntriesWithHashes(ctx, func(idx int, pi path.Info, hash []byte) error {
 			v, err := newDbf.FindEntryIndexAndOffset(pi.Id)
 			if err != nil {
 				if !errors.Is(err, db.ErrNotFound) {
@@ -127,7 +171,7 @@ func Run(cfg Config) error {
 				return nil
 			}
 
-			return newDbf.WriteHashEntry(int(v.Index), hash)
+			return newDbf.WriteHashEntryAt(int(v.Index), hash, hashTimes[idx])
 		})
 		if err != nil {
 			return errFn(err)
@@ -136,17 +180,133 @@ func Run(cfg Config) error {
 		if err = newDbf.Close(); err != nil {
 			return errFn(err)
 		}
+		donePhase()
 
 		// Start hashing new entries
 		resumeCfg := resume.Config{
-			CommonConfig: cfg.CommonConfig,
+			CommonConfig:  cfg.CommonConfig,
+			HashCachePath: cfg.HashCachePath,
+			SortHashes:    cfg.SortHashes || oldDbf.HashTableSorted(),
+			FlushInterval: cfg.FlushInterval,
+			SyncPolicy:    cfg.SyncPolicy,
 		}
-		if err = resume.Run(resumeCfg); err != nil {
+		if err = resume.Run(ctx, resumeCfg); err != nil {
 			// Only state in which we will keep the backup and new one
 			return err
 		}
+
+		if err = copyExtraHashTables(ctx, oldDbf, cfg.DbPath); err != nil {
+			return err
+		}
+	}
+
+	if err = copyAnnotations(oldDbf, cfg.DbPath); err != nil {
+		return err
+	}
+
+	if sealed {
+		if err = db.SetSealed(cfg.DbPath, true); err != nil {
+			return err
+		}
+	}
+	if pathDisplay != db.PathDisplayDefault {
+		if err = db.SetPathDisplay(cfg.DbPath, pathDisplay); err != nil {
+			return err
+		}
 	}
 
 	// Delete the back up
 	return os.Remove(backupDbPath)
 }
+
+// Copy the additional hash tables for the entries that still exist in the new database.
+// Hashes for the new entries can be calculated using "ajfs add-hash".
+func copyExtraHashTables(ctx context.Context, oldDbf *db.DatabaseFile, dbPath string) error {
+	if !oldDbf.Features().HasExtraHashes() {
+		return nil
+	}
+
+	algos, err := oldDbf.HashTableAlgos(ctx)
+	if err != nil {
+		return err
+	}
+
+	newDbf, err := db.OpenDatabase(dbPath)
+	if err != nil {
+		return err
+	}
+	defer newDbf.Close()
+
+	tables := make(map[ajhash.Algo]db.HashTable, len(algos)-1)
+	for _, algo := range algos[1:] {
+		oldTable, err := oldDbf.ReadHashTableWithAlgo(ctx, algo)
+		if err != nil {
+			return err
+		}
+
+		table := make(db.HashTable, len(oldTable))
+		for idx, hash := range oldTable {
+			pi, err := oldDbf.ReadEntryAtIndex(idx)
+			if err != nil {
+				return err
+			}
+
+			v, err := newDbf.FindEntryIndexAndOffset(pi.Id)
+			if err != nil {
+				if !errors.Is(err, db.ErrNotFound) {
+					return err
+				}
+				// Entry no longer exists in new database
+				continue
+			}
+			table[int(v.Index)] = hash
+		}
+		tables[algo] = table
+	}
+
+	if err = newDbf.Close(); err != nil {
+		return err
+	}
+
+	for _, algo := range algos[1:] {
+		if err = db.AddHashTable(ctx, dbPath, algo, tables[algo]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Copy the annotations of the entries that still exist in the new database.
+// The annotations of the duplicate groups are copied as is since they are keyed by the file signature hash.
+func copyAnnotations(oldDbf *db.DatabaseFile, dbPath string) error {
+	if !oldDbf.Features().HasAnnotations() {
+		return nil
+	}
+
+	annotations, err := oldDbf.ReadAnnotations()
+	if err != nil {
+		return err
+	}
+
+	newDbf, err := db.OpenDatabase(dbPath)
+	if err != nil {
+		return err
+	}
+	defer newDbf.Close()
+
+	for id := range annotations.Entries {
+		if _, err := newDbf.FindEntryIndexAndOffset(id); err != nil {
+			if !errors.Is(err, db.ErrNotFound) {
+				return err
+			}
+			// Entry no longer exists in new database
+			delete(annotations.Entries, id)
+		}
+	}
+
+	if err = newDbf.Close(); err != nil {
+		return err
+	}
+
+	return db.SetAnnotations(dbPath, annotations)
+}
@@ -21,16 +21,20 @@
 package update_test
 
 import (
+	"context"
 	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/andrejacobs/ajfs/internal/app/config"
 	"github.com/andrejacobs/ajfs/internal/app/export"
 	"github.com/andrejacobs/ajfs/internal/app/scan"
 	"github.com/andrejacobs/ajfs/internal/app/update"
+	"github.com/andrejacobs/ajfs/internal/db"
 	"github.com/andrejacobs/ajfs/internal/filter"
+	"github.com/andrejacobs/ajfs/internal/path"
 	"github.com/andrejacobs/ajfs/internal/testshared"
 	"github.com/andrejacobs/go-aj/ajhash"
 	"github.com/andrejacobs/go-aj/file"
@@ -57,13 +61,13 @@ func TestUpdate(t *testing.T) {
 	exclF, _, err := filter.ParsePathRegexToMatchPathFn([]string{"f:blank\\.txt$"}, false)
 	require.NoError(t, err)
 	scanCfg.FileExcluder = file.MatchAppleDSStore(exclF)
-	require.NoError(t, scan.Run(scanCfg))
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
 
 	// Update (without filtering)
 	updateCfg := update.Config{
 		CommonConfig: scanCfg.CommonConfig,
 	}
-	require.NoError(t, update.Run(updateCfg))
+	require.NoError(t, update.Run(context.Background(), updateCfg))
 
 	expPaths, err := testshared.ExpectedPaths(scanCfg.Root, nil)
 	require.NoError(t, err)
@@ -95,13 +99,13 @@ func TestUpdateWithHashes(t *testing.T) {
 	exclF, _, err := filter.ParsePathRegexToMatchPathFn([]string{"f:blank\\.txt$"}, false)
 	require.NoError(t, err)
 	scanCfg.FileExcluder = file.MatchAppleDSStore(exclF)
-	require.NoError(t, scan.Run(scanCfg))
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
 
 	// Update (without filtering)
 	updateCfg := update.Config{
 		CommonConfig: scanCfg.CommonConfig,
 	}
-	require.NoError(t, update.Run(updateCfg))
+	require.NoError(t, update.Run(context.Background(), updateCfg))
 
 	expPaths, err := testshared.ExpectedPaths(scanCfg.Root, nil)
 	require.NoError(t, err)
@@ -126,7 +130,7 @@ func TestUpdateWithHashes(t *testing.T) {
 		ExportPath: tempExportFile,
 	}
 
-	require.NoError(t, export.Run(exportCfg))
+	require.NoError(t, export.Run(context.Background(), exportCfg))
 
 	// Validate
 	expectedHashDeep, err := testshared.ReadHashDeepFile("../../testdata/expected/scan.sha1")
@@ -152,14 +156,14 @@ func TestUpdateKeepACopy(t *testing.T) {
 		},
 		Root: "../../testdata/scan",
 	}
-	require.NoError(t, scan.Run(scanCfg))
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
 
 	// Update and a keep a copy of existing database
 	updateCfg := update.Config{
 		CommonConfig: scanCfg.CommonConfig,
 		KeepCopyPath: filepath.Join(t.TempDir(), "unit-testing-copy"),
 	}
-	require.NoError(t, update.Run(updateCfg))
+	require.NoError(t, update.Run(context.Background(), updateCfg))
 
 	expPaths, err := testshared.DatabasePaths(scanCfg.DbPath)
 	require.NoError(t, err)
@@ -169,3 +173,141 @@ func TestUpdateKeepACopy(t *testing.T) {
 
 	assert.ElementsMatch(t, expPaths, dbPaths)
 }
+
+func TestUpdateSealed(t *testing.T) {
+	dbFile := filepath.Join(t.TempDir(), "unit-testing")
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: dbFile,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		Root:            "../../testdata/scan",
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+	require.NoError(t, db.SetSealed(dbFile, true))
+
+	updateCfg := update.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: dbFile,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+	}
+	err := update.Run(context.Background(), updateCfg)
+	assert.ErrorIs(t, err, db.ErrSealed)
+
+	// The updated database remains sealed
+	updateCfg.Force = true
+	require.NoError(t, update.Run(context.Background(), updateCfg))
+
+	sealed, err := db.IsSealed(dbFile)
+	require.NoError(t, err)
+	assert.True(t, sealed)
+}
+
+func TestUpdateAnnotations(t *testing.T) {
+	dbFile := filepath.Join(t.TempDir(), "unit-testing")
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: dbFile,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		Root: "../../testdata/scan",
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	at := time.Unix(time.Now().Unix(), 0)
+	annotations := db.NewAnnotations()
+	annotations.Entries[path.IdFromPath("1.txt")] = db.Annotation{Label: db.LabelKeep, Time: at}
+	annotations.Entries[path.IdFromPath("removed.txt")] = db.Annotation{Label: db.LabelDeleteCandidate, Time: at}
+	annotations.Groups["e3d157020b35944b552ba9987eb668228c073d30"] = db.Annotation{Label: db.LabelReviewed, Time: at}
+	require.NoError(t, db.SetAnnotations(dbFile, annotations))
+
+	updateCfg := update.Config{
+		CommonConfig: scanCfg.CommonConfig,
+	}
+	require.NoError(t, update.Run(context.Background(), updateCfg))
+
+	dbf, err := db.OpenDatabase(dbFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	// Only the annotations of the entries that still exist are kept
+	delete(annotations.Entries, path.IdFromPath("removed.txt"))
+	result, err := dbf.ReadAnnotations()
+	require.NoError(t, err)
+	assert.Equal(t, annotations, result)
+}
+
+func TestUpdateKeepsIdNamespace(t *testing.T) {
+	dbFile := filepath.Join(t.TempDir(), "unit-testing")
+
+	namespace, err := db.NewIdNamespace(db.IdModeUUID, "../../testdata/scan")
+	require.NoError(t, err)
+
+	scanCfg := scan.Config{
+		CommonConfig: config.CommonConfig{
+			DbPath: dbFile,
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+		},
+		Root:            "../../testdata/scan",
+		IdNamespace:     namespace,
+		CalculateHashes: true,
+		Algo:            ajhash.AlgoSHA1,
+	}
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	updateCfg := update.Config{
+		CommonConfig: scanCfg.CommonConfig,
+	}
+	require.NoError(t, update.Run(context.Background(), updateCfg))
+
+	dbf, err := db.OpenDatabase(dbFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	assert.Equal(t, namespace, dbf.IdNamespace())
+	pi, err := dbf.ReadEntryById(path.IdFromPathInNamespace(namespace, "1.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "1.txt", pi.Path)
+
+	hashes, err := dbf.ReadHashTable(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, hashes, dbf.FileEntriesCount())
+}
+
+func TestUpdateKeepsArchives(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "1.txt"), []byte("1"), 0o644))
+	testshared.CreateTar(t, filepath.Join(root, "backup.tar"), map[string]string{"2.txt": "2", "3.txt": "3"})
+
+	dbFile := filepath.Join(t.TempDir(), "unit-testing")
+	scanCfg := testshared.ScanConfig(dbFile, root, true)
+	scanCfg.Archives = true
+	require.NoError(t, scan.Run(context.Background(), scanCfg))
+
+	expected, err := testshared.DatabasePaths(dbFile)
+	require.NoError(t, err)
+	require.Len(t, expected, 5)
+
+	// The archives are expanded again without having to pass Archives
+	updateCfg := update.Config{
+		CommonConfig: scanCfg.CommonConfig,
+	}
+	require.NoError(t, update.Run(context.Background(), updateCfg))
+
+	paths, err := testshared.DatabasePaths(dbFile)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, expected, paths)
+
+	archives, err := db.ReadArchives(dbFile)
+	require.NoError(t, err)
+	assert.True(t, archives)
+}
@@ -0,0 +1,488 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package archive is used to read the members of tar and zip archives, as well as ISO9660 disk images, as virtual
+// path entries.
+//
+// A member is identified by the path of the archive followed by [Marker], the path separator and the path of the
+// member inside the archive. For example the file "dir/file.txt" inside "backup.tar" has the virtual path
+// "backup.tar!/dir/file.txt". Nested archives are not descended into.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/andrejacobs/go-aj/file"
+)
+
+// Marker is appended to the path of an archive to form the virtual path of its members.
+const Marker = "!"
+
+// ErrMemberNotFound is returned when a member does not exist inside an archive.
+var ErrMemberNotFound = errors.New("member not found in archive")
+
+type kind int
+
+const (
+	kindNone kind = iota
+	kindTar
+	kindTarGzip
+	kindZip
+	kindISO
+)
+
+func kindOf(p string) kind {
+	name := strings.ToLower(p)
+	switch {
+	case strings.HasSuffix(name, ".tar"):
+		return kindTar
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return kindTarGzip
+	case strings.HasSuffix(name, ".zip"):
+		return kindZip
+	case strings.HasSuffix(name, ".iso"):
+		return kindISO
+	}
+	return kindNone
+}
+
+// Return true if the path has the file extension of a supported archive (.tar, .tar.gz, .tgz, .zip or .iso).
+func IsArchive(p string) bool {
+	return kindOf(p) != kindNone
+}
+
+// Member describes a file or directory inside an archive.
+type Member struct {
+	Name    string      // Slash separated path of the member inside the archive.
+	Size    uint64      // Uncompressed size in bytes, if it is a file.
+	Mode    fs.FileMode // Type and permission bits.
+	ModTime time.Time   // Last modification time.
+}
+
+// Return the virtual path of the member named name inside the archive at archivePath.
+func VirtualPath(archivePath string, name string) string {
+	return archivePath + Marker + string(filepath.Separator) + filepath.FromSlash(name)
+}
+
+// Split a virtual path into the path of the archive and the slash separated name of the member.
+// ok is false when p is not the path of an archive member.
+func Split(p string) (archivePath string, name string, ok bool) {
+	sep := Marker + string(filepath.Separator)
+	offset := 0
+	for {
+		i := strings.Index(p[offset:], sep)
+		if i < 0 {
+			return "", "", false
+		}
+		i += offset
+		if IsArchive(p[:i]) {
+			return p[:i], filepath.ToSlash(p[i+len(sep):]), true
+		}
+		offset = i + len(sep)
+	}
+}
+
+// Walk calls fn for each file and directory member inside the archive in the order in which they are stored.
+// Symbolic links and other special members are skipped.
+func Walk(ctx context.Context, archivePath string, fn func(m Member) error) error {
+	var err error
+	switch kindOf(archivePath) {
+	case kindTar, kindTarGzip:
+		err = walkTar(ctx, archivePath, fn)
+	case kindZip:
+		err = walkZip(ctx, archivePath, fn)
+	case kindISO:
+		err = walkISO(ctx, archivePath, fn)
+	default:
+		err = fmt.Errorf("unsupported archive format")
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to read the archive %q. %w", archivePath, err)
+	}
+	return nil
+}
+
+func walkTar(ctx context.Context, archivePath string, fn func(m Member) error) error {
+	tr, err := openTar(archivePath)
+	if err != nil {
+		return err
+	}
+	defer tr.Close()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		hdr, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		m, ok := memberFromTar(hdr)
+		if !ok {
+			continue
+		}
+		if err = fn(m); err != nil {
+			return err
+		}
+	}
+}
+
+func walkZip(ctx context.Context, archivePath string, fn func(m Member) error) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		m, ok := memberFromZip(f)
+		if !ok {
+			continue
+		}
+		if err = fn(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkISO(ctx context.Context, archivePath string, fn func(m Member) error) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	img, err := openISO(f)
+	if err != nil {
+		return err
+	}
+	return img.walk(ctx, func(e isoEntry) error {
+		return fn(e.Member)
+	})
+}
+
+func memberFromTar(hdr *tar.Header) (Member, bool) {
+	name, ok := cleanName(hdr.Name)
+	if !ok {
+		return Member{}, false
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeReg, tar.TypeRegA: //nolint:staticcheck // TypeRegA is still written by older tools
+		return Member{
+			Name:    name,
+			Size:    uint64(hdr.Size), //nolint:gosec // disable G115
+			Mode:    fs.FileMode(hdr.Mode).Perm(),
+			ModTime: hdr.ModTime,
+		}, true
+	case tar.TypeDir:
+		return Member{
+			Name:    name,
+			Mode:    fs.ModeDir | fs.FileMode(hdr.Mode).Perm(),
+			ModTime: hdr.ModTime,
+		}, true
+	}
+	return Member{}, false
+}
+
+func memberFromZip(f *zip.File) (Member, bool) {
+	name, ok := cleanName(f.Name)
+	if !ok {
+		return Member{}, false
+	}
+
+	mode := f.Mode()
+	switch {
+	case mode.IsDir() || strings.HasSuffix(f.Name, "/"):
+		return Member{
+			Name:    name,
+			Mode:    fs.ModeDir | mode.Perm(),
+			ModTime: f.Modified,
+		}, true
+	case mode.IsRegular():
+		return Member{
+			Name:    name,
+			Size:    f.UncompressedSize64,
+			Mode:    mode.Perm(),
+			ModTime: f.Modified,
+		}, true
+	}
+	return Member{}, false
+}
+
+// Clean the name of a member so that it is always relative to the root of the archive.
+// Names that would escape the archive (e.g. "../file") are resolved against the root.
+func cleanName(name string) (string, bool) {
+	name = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+	return name, name != ""
+}
+
+// Reader of a tar archive that is optionally gzip compressed.
+type tarReader struct {
+	*tar.Reader
+	path   string
+	closer []io.Closer
+}
+
+func openTar(archivePath string) (*tarReader, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &tarReader{path: archivePath, closer: []io.Closer{f}}
+	var rd io.Reader = f
+	if kindOf(archivePath) == kindTarGzip {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		r.closer = append(r.closer, gz)
+		rd = gz
+	}
+	r.Reader = tar.NewReader(rd)
+	return r, nil
+}
+
+func (r *tarReader) Close() error {
+	var err error
+	for i := len(r.closer) - 1; i >= 0; i-- {
+		err = errors.Join(err, r.closer[i].Close())
+	}
+	return err
+}
+
+//-----------------------------------------------------------------------------
+// Hashing
+
+// Hasher calculates file signature hashes of regular files as well as archive members.
+//
+// Members are usually hashed in the order in which they were walked. The last opened archive is kept open so that
+// the members of a tar archive can be read sequentially instead of reading the archive from the start for each one.
+// A Hasher is not safe for concurrent use.
+type Hasher struct {
+	tar *tarReader
+
+	zipPath string
+	zip     *zip.ReadCloser
+	zipMap  map[string]*zip.File
+
+	isoPath string
+	iso     *os.File
+	isoMap  map[string]isoEntry
+}
+
+// Create a new hasher. Close must be called once all hashes have been calculated.
+func NewHasher() *Hasher {
+	return &Hasher{}
+}
+
+// Hash the file or archive member at p and optionally copy the read bytes to the io.Writer.
+// Return the calculated hash and the total number of bytes copied.
+// It has the same semantics as [file.Hash] for paths that are not archive members.
+func (h *Hasher) Hash(ctx context.Context, p string, hasher hash.Hash, w io.Writer) ([]byte, uint64, error) {
+	archivePath, name, ok := Split(p)
+	if !ok {
+		return file.Hash(ctx, p, hasher, w)
+	}
+	// A real file takes precedence over an archive member with the same path
+	if _, err := os.Lstat(p); err == nil {
+		return file.Hash(ctx, p, hasher, w)
+	}
+
+	var rd io.Reader
+	var err error
+	switch kindOf(archivePath) {
+	case kindZip:
+		rd, err = h.openZipMember(archivePath, name)
+	case kindISO:
+		rd, err = h.openISOMember(ctx, archivePath, name)
+	default:
+		rd, err = h.openTarMember(ctx, archivePath, name)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to hash the archive member '%s'. %w", p, err)
+	}
+	if c, ok := rd.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	return file.HashFromReader(ctx, rd, hasher, w)
+}
+
+func (h *Hasher) openZipMember(archivePath string, name string) (io.Reader, error) {
+	if h.zipPath != archivePath {
+		h.closeZip()
+
+		zr, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return nil, err
+		}
+		h.zip = zr
+		h.zipPath = archivePath
+		h.zipMap = make(map[string]*zip.File, len(zr.File))
+		for _, f := range zr.File {
+			n, ok := cleanName(f.Name)
+			if _, exists := h.zipMap[n]; ok && !exists {
+				h.zipMap[n] = f
+			}
+		}
+	}
+
+	f, ok := h.zipMap[name]
+	if !ok {
+		return nil, ErrMemberNotFound
+	}
+	return f.Open()
+}
+
+func (h *Hasher) openISOMember(ctx context.Context, archivePath string, name string) (io.Reader, error) {
+	if h.isoPath != archivePath {
+		h.closeISO()
+
+		f, err := os.Open(archivePath)
+		if err != nil {
+			return nil, err
+		}
+		img, err := openISO(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+
+		entries := make(map[string]isoEntry)
+		err = img.walk(ctx, func(e isoEntry) error {
+			if _, exists := entries[e.Name]; !exists && e.Mode.IsRegular() {
+				entries[e.Name] = e
+			}
+			return nil
+		})
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+
+		h.iso = f
+		h.isoPath = archivePath
+		h.isoMap = entries
+	}
+
+	e, ok := h.isoMap[name]
+	if !ok {
+		return nil, ErrMemberNotFound
+	}
+	return e.reader(h.iso), nil
+}
+
+func (h *Hasher) openTarMember(ctx context.Context, archivePath string, name string) (io.Reader, error) {
+	if (h.tar != nil) && (h.tar.path != archivePath) {
+		h.closeTar()
+	}
+
+	// Continue from the current position and only start from the beginning once the end has been reached
+	restarted := false
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if h.tar == nil {
+			tr, err := openTar(archivePath)
+			if err != nil {
+				return nil, err
+			}
+			h.tar = tr
+		}
+
+		hdr, err := h.tar.Next()
+		if err != nil {
+			h.closeTar()
+			if errors.Is(err, io.EOF) && !restarted {
+				restarted = true
+				continue
+			}
+			if errors.Is(err, io.EOF) {
+				return nil, ErrMemberNotFound
+			}
+			return nil, err
+		}
+
+		if m, ok := memberFromTar(hdr); ok && (m.Name == name) && m.Mode.IsRegular() {
+			return h.tar.Reader, nil
+		}
+	}
+}
+
+func (h *Hasher) closeTar() {
+	if h.tar != nil {
+		_ = h.tar.Close()
+		h.tar = nil
+	}
+}
+
+func (h *Hasher) closeZip() {
+	if h.zip != nil {
+		_ = h.zip.Close()
+		h.zip = nil
+		h.zipPath = ""
+		h.zipMap = nil
+	}
+}
+
+func (h *Hasher) closeISO() {
+	if h.iso != nil {
+		_ = h.iso.Close()
+		h.iso = nil
+		h.isoPath = ""
+		h.isoMap = nil
+	}
+}
+
+// Close any archive that is still open.
+func (h *Hasher) Close() error {
+	h.closeTar()
+	h.closeZip()
+	h.closeISO()
+	return nil
+}
@@ -0,0 +1,325 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package archive_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/andrejacobs/ajfs/internal/archive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMembers = []struct {
+	name    string
+	content string
+}{
+	{"dir/", ""},
+	{"dir/a.txt", "The quick brown fox"},
+	{"dir/b.txt", "jumps over the lazy dog"},
+	{"c.txt", "!"},
+}
+
+func writeTestTar(t *testing.T, w io.Writer) {
+	t.Helper()
+	tw := tar.NewWriter(w)
+	for _, m := range testMembers {
+		hdr := &tar.Header{Name: m.name, Mode: 0o644, Size: int64(len(m.content)), ModTime: time.Unix(1700000000, 0), Typeflag: tar.TypeReg}
+		if m.content == "" {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Mode = 0o755
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err := tw.Write([]byte(m.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+}
+
+func createTestArchive(t *testing.T, name string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(p)
+	require.NoError(t, err)
+	defer f.Close()
+
+	switch filepath.Ext(name) {
+	case ".tar":
+		writeTestTar(t, f)
+	case ".tgz":
+		gz := gzip.NewWriter(f)
+		writeTestTar(t, gz)
+		require.NoError(t, gz.Close())
+	case ".zip":
+		zw := zip.NewWriter(f)
+		for _, m := range testMembers {
+			w, err := zw.Create(m.name)
+			require.NoError(t, err)
+			_, err = w.Write([]byte(m.content))
+			require.NoError(t, err)
+		}
+		require.NoError(t, zw.Close())
+	}
+	return p
+}
+
+func TestIsArchive(t *testing.T) {
+	assert.True(t, archive.IsArchive("backup.tar"))
+	assert.True(t, archive.IsArchive("backup.TAR.GZ"))
+	assert.True(t, archive.IsArchive("backup.tgz"))
+	assert.True(t, archive.IsArchive("dir/backup.zip"))
+	assert.False(t, archive.IsArchive("backup.gz"))
+	assert.False(t, archive.IsArchive("backup.txt"))
+}
+
+func TestVirtualPath(t *testing.T) {
+	p := archive.VirtualPath(filepath.Join("a", "backup.zip"), "dir/file.txt")
+	assert.Equal(t, filepath.Join("a", "backup.zip!", "dir", "file.txt"), p)
+
+	archivePath, name, ok := archive.Split(p)
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join("a", "backup.zip"), archivePath)
+	assert.Equal(t, "dir/file.txt", name)
+
+	// The marker is only recognised after an archive
+	_, _, ok = archive.Split(filepath.Join("a!", "b.txt"))
+	assert.False(t, ok)
+
+	archivePath, name, ok = archive.Split(archive.VirtualPath(filepath.Join("wow!", "x.tar"), "y"))
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join("wow!", "x.tar"), archivePath)
+	assert.Equal(t, "y", name)
+}
+
+func TestWalk(t *testing.T) {
+	for _, name := range []string{"test.tar", "test.tgz", "test.zip"} {
+		t.Run(name, func(t *testing.T) {
+			p := createTestArchive(t, name)
+
+			var members []archive.Member
+			require.NoError(t, archive.Walk(context.Background(), p, func(m archive.Member) error {
+				members = append(members, m)
+				return nil
+			}))
+
+			require.Len(t, members, len(testMembers))
+			assert.Equal(t, "dir", members[0].Name)
+			assert.True(t, members[0].Mode.IsDir())
+			for i, m := range members[1:] {
+				assert.Equal(t, testMembers[i+1].name, m.Name)
+				assert.True(t, m.Mode.IsRegular())
+				assert.Equal(t, uint64(len(testMembers[i+1].content)), m.Size)
+			}
+		})
+	}
+}
+
+func TestWalkDamaged(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "damaged.zip")
+	require.NoError(t, os.WriteFile(p, []byte("not a zip file"), 0o644))
+
+	err := archive.Walk(context.Background(), p, func(m archive.Member) error { return nil })
+	assert.ErrorContains(t, err, "failed to read the archive")
+}
+
+func TestHasher(t *testing.T) {
+	for _, name := range []string{"test.tar", "test.tgz", "test.zip"} {
+		t.Run(name, func(t *testing.T) {
+			p := createTestArchive(t, name)
+
+			h := archive.NewHasher()
+			defer h.Close()
+
+			// Out of order access requires the tar archive to be read again from the start
+			for _, i := range []int{1, 2, 3, 1} {
+				m := testMembers[i]
+				hash, size, err := h.Hash(context.Background(), archive.VirtualPath(p, m.name), sha256.New(), nil)
+				require.NoError(t, err)
+
+				expected := sha256.Sum256([]byte(m.content))
+				assert.Equal(t, expected[:], hash)
+				assert.Equal(t, uint64(len(m.content)), size)
+			}
+
+			_, _, err := h.Hash(context.Background(), archive.VirtualPath(p, "missing.txt"), sha256.New(), nil)
+			assert.ErrorIs(t, err, archive.ErrMemberNotFound)
+
+			// Regular files are hashed as is
+			hash, _, err := h.Hash(context.Background(), p, sha256.New(), nil)
+			require.NoError(t, err)
+			assert.Len(t, hash, sha256.Size)
+		})
+	}
+}
+
+func TestWalkISO(t *testing.T) {
+	for _, joliet := range []bool{false, true} {
+		p := createTestISO(t, joliet)
+
+		var members []archive.Member
+		require.NoError(t, archive.Walk(context.Background(), p, func(m archive.Member) error {
+			members = append(members, m)
+			return nil
+		}))
+
+		names := make([]string, 0, len(members))
+		for _, m := range members {
+			names = append(names, m.Name)
+		}
+
+		if joliet {
+			assert.Equal(t, []string{"docs", "docs/Long File Name.txt", "readme"}, names)
+		} else {
+			assert.Equal(t, []string{"DOCS", "DOCS/LONGFILE.TXT", "README"}, names)
+		}
+		assert.True(t, members[0].Mode.IsDir())
+		assert.Equal(t, uint64(len(isoContentA)), members[1].Size)
+		assert.Equal(t, time.Date(2024, time.March, 4, 5, 6, 7, 0, time.UTC), members[1].ModTime.UTC())
+
+		h := archive.NewHasher()
+		hash, size, err := h.Hash(context.Background(), archive.VirtualPath(p, names[2]), sha256.New(), nil)
+		require.NoError(t, err)
+		expected := sha256.Sum256([]byte(isoContentB))
+		assert.Equal(t, expected[:], hash)
+		assert.Equal(t, uint64(len(isoContentB)), size)
+		require.NoError(t, h.Close())
+	}
+}
+
+func TestWalkISONotAnImage(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "empty.iso")
+	require.NoError(t, os.WriteFile(p, make([]byte, 17*2048), 0o644))
+
+	err := archive.Walk(context.Background(), p, func(m archive.Member) error { return nil })
+	assert.ErrorContains(t, err, "not an ISO9660 image")
+}
+
+//-----------------------------------------------------------------------------
+
+const (
+	isoContentA = "The quick brown fox"
+	isoContentB = "jumps over the lazy dog"
+)
+
+// Create a minimal ISO9660 image containing a directory with one file and a file in the root.
+// When joliet is true a Joliet supplementary volume descriptor is also written.
+func createTestISO(t *testing.T, joliet bool) string {
+	t.Helper()
+
+	const (
+		pvd = 16 + iota
+		svd
+		terminator
+		rootDir
+		subDir
+		jolietRootDir
+		jolietSubDir
+		dataA
+		dataB
+		sectorCount
+	)
+
+	img := make([]byte, sectorCount*2048)
+	sector := func(i int) []byte { return img[i*2048 : (i+1)*2048] }
+
+	record := func(extent int, size int, dir bool, name []byte) []byte {
+		length := 33 + len(name)
+		if len(name)%2 == 0 {
+			length++
+		}
+		b := make([]byte, length)
+		b[0] = byte(length)
+		binary.LittleEndian.PutUint32(b[2:], uint32(extent))
+		binary.BigEndian.PutUint32(b[6:], uint32(extent))
+		binary.LittleEndian.PutUint32(b[10:], uint32(size))
+		binary.BigEndian.PutUint32(b[14:], uint32(size))
+		copy(b[18:], []byte{124, 3, 4, 5, 6, 7, 0})
+		if dir {
+			b[25] = 0x02
+		}
+		b[32] = byte(len(name))
+		copy(b[33:], name)
+		return b
+	}
+	ucs2 := func(s string) []byte {
+		u := utf16.Encode([]rune(s))
+		b := make([]byte, len(u)*2)
+		for i, c := range u {
+			binary.BigEndian.PutUint16(b[i*2:], c)
+		}
+		return b
+	}
+	writeDir := func(i int, parent int, records ...[]byte) {
+		b := sector(i)[:0]
+		b = append(b, record(i, 2048, true, []byte{0})...)
+		b = append(b, record(parent, 2048, true, []byte{1})...)
+		for _, r := range records {
+			b = append(b, r...)
+		}
+	}
+	descriptor := func(i int, kind byte, root int) {
+		b := sector(i)
+		b[0] = kind
+		copy(b[1:], "CD001")
+		b[6] = 1
+		if root > 0 {
+			copy(b[156:], record(root, 2048, true, []byte{0}))
+		}
+	}
+
+	descriptor(pvd, 1, rootDir)
+	writeDir(rootDir, rootDir,
+		record(subDir, 2048, true, []byte("DOCS")),
+		record(dataB, len(isoContentB), false, []byte("README.;1")))
+	writeDir(subDir, rootDir,
+		record(dataA, len(isoContentA), false, []byte("LONGFILE.TXT;1")))
+
+	if joliet {
+		descriptor(svd, 2, jolietRootDir)
+		copy(sector(svd)[88:], "%/E")
+		writeDir(jolietRootDir, jolietRootDir,
+			record(jolietSubDir, 2048, true, ucs2("docs")),
+			record(dataB, len(isoContentB), false, ucs2("readme;1")))
+		writeDir(jolietSubDir, jolietRootDir,
+			record(dataA, len(isoContentA), false, ucs2("Long File Name.txt;1")))
+	} else {
+		// Unused volume descriptor types are ignored
+		descriptor(svd, 3, 0)
+	}
+	descriptor(terminator, 255, 0)
+
+	copy(sector(dataA), isoContentA)
+	copy(sector(dataB), isoContentB)
+
+	p := filepath.Join(t.TempDir(), "test.iso")
+	require.NoError(t, os.WriteFile(p, img, 0o644))
+	return p
+}
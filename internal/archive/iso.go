@@ -0,0 +1,291 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// ISO9660 (ECMA-119) disk image reader.
+// Only the information needed to list and read the files is parsed. Joliet names are used when the image contains
+// a Joliet supplementary volume descriptor, otherwise the primary (8.3 style) names are used. Rock Ridge extensions
+// are ignored.
+
+const (
+	isoSectorSize      = 2048
+	isoFirstDescriptor = 16
+	isoMaxDescriptors  = 64
+	isoMaxDirSize      = 64 * 1024 * 1024 // Guard against damaged images claiming huge directories
+	isoMaxDepth        = 256
+
+	isoFlagDir         = 0x02
+	isoFlagMultiExtent = 0x80
+)
+
+var errNotISO = errors.New("not an ISO9660 image")
+
+// A contiguous run of file data inside the image.
+type isoExtent struct {
+	offset int64
+	size   int64
+}
+
+// A file or directory found inside the image.
+type isoEntry struct {
+	Member
+	extents []isoExtent
+}
+
+// Return a reader for the contents of the file.
+func (e *isoEntry) reader(r io.ReaderAt) io.Reader {
+	readers := make([]io.Reader, 0, len(e.extents))
+	for _, ext := range e.extents {
+		readers = append(readers, io.NewSectionReader(r, ext.offset, ext.size))
+	}
+	return io.MultiReader(readers...)
+}
+
+type isoImage struct {
+	r      io.ReaderAt
+	root   isoRecord
+	joliet bool
+}
+
+// A parsed directory record.
+type isoRecord struct {
+	extent  uint32
+	size    uint32
+	modTime time.Time
+	flags   byte
+	name    string
+}
+
+func openISO(r io.ReaderAt) (*isoImage, error) {
+	var primary, joliet *isoImage
+
+	buf := make([]byte, isoSectorSize)
+	for i := 0; i < isoMaxDescriptors; i++ {
+		if _, err := r.ReadAt(buf, int64(isoFirstDescriptor+i)*isoSectorSize); err != nil {
+			if (i == 0) && errors.Is(err, io.EOF) {
+				return nil, errNotISO
+			}
+			return nil, err
+		}
+		if string(buf[1:6]) != "CD001" {
+			return nil, errNotISO
+		}
+
+		switch buf[0] {
+		case 1: // Primary volume descriptor
+			rec, _, err := parseISORecord(buf[156:190], false)
+			if err != nil {
+				return nil, err
+			}
+			primary = &isoImage{r: r, root: rec}
+		case 2: // Supplementary volume descriptor
+			esc := buf[88:91]
+			if bytes.Equal(esc, []byte("%/@")) || bytes.Equal(esc, []byte("%/C")) || bytes.Equal(esc, []byte("%/E")) {
+				rec, _, err := parseISORecord(buf[156:190], true)
+				if err != nil {
+					return nil, err
+				}
+				joliet = &isoImage{r: r, root: rec, joliet: true}
+			}
+		case 255: // Volume descriptor set terminator
+			if joliet != nil {
+				return joliet, nil
+			}
+			if primary != nil {
+				return primary, nil
+			}
+			return nil, fmt.Errorf("no primary volume descriptor found")
+		}
+	}
+
+	return nil, fmt.Errorf("no volume descriptor set terminator found")
+}
+
+// Parse the directory record at the start of b and return it along with its length.
+func parseISORecord(b []byte, joliet bool) (isoRecord, int, error) {
+	if len(b) < 1 {
+		return isoRecord{}, 0, io.ErrUnexpectedEOF
+	}
+	length := int(b[0])
+	if length == 0 {
+		return isoRecord{}, 0, nil
+	}
+	if (length < 34) || (length > len(b)) {
+		return isoRecord{}, 0, fmt.Errorf("invalid directory record length %d", length)
+	}
+
+	nameLen := int(b[32])
+	if 33+nameLen > length {
+		return isoRecord{}, 0, fmt.Errorf("invalid directory record name length %d", nameLen)
+	}
+
+	rec := isoRecord{
+		extent:  binary.LittleEndian.Uint32(b[2:6]),
+		size:    binary.LittleEndian.Uint32(b[10:14]),
+		modTime: parseISOTime(b[18:25]),
+		flags:   b[25],
+	}
+
+	name := b[33 : 33+nameLen]
+	switch {
+	case (nameLen == 1) && (name[0] <= 1):
+		// The current (0x00) and parent (0x01) directory
+		rec.name = string(name)
+	case joliet:
+		u := make([]uint16, nameLen/2)
+		for i := range u {
+			u[i] = binary.BigEndian.Uint16(name[i*2:])
+		}
+		rec.name = cleanISOName(string(utf16.Decode(u)), false)
+	default:
+		rec.name = cleanISOName(string(name), rec.flags&isoFlagDir == 0)
+	}
+
+	return rec, length, nil
+}
+
+// Remove the file version (";1") and the trailing "." of files without an extension.
+func cleanISOName(name string, isFile bool) string {
+	if i := strings.LastIndexByte(name, ';'); i >= 0 {
+		name = name[:i]
+	}
+	if isFile {
+		name = strings.TrimSuffix(name, ".")
+	}
+	return name
+}
+
+func parseISOTime(b []byte) time.Time {
+	if (b[0] == 0) && (b[1] == 0) && (b[2] == 0) {
+		return time.Time{}
+	}
+	offset := int(int8(b[6])) * 15 * 60
+	return time.Date(1900+int(b[0]), time.Month(b[1]), int(b[2]), int(b[3]), int(b[4]), int(b[5]), 0,
+		time.FixedZone("", offset))
+}
+
+// Walk all the files and directories in the image.
+func (img *isoImage) walk(ctx context.Context, fn func(e isoEntry) error) error {
+	visited := make(map[uint32]bool)
+	return img.walkDir(ctx, img.root, "", 0, visited, fn)
+}
+
+func (img *isoImage) walkDir(ctx context.Context, dir isoRecord, prefix string, depth int,
+	visited map[uint32]bool, fn func(e isoEntry) error) error {
+
+	if depth > isoMaxDepth {
+		return fmt.Errorf("directory hierarchy is too deep at %q", prefix)
+	}
+	if visited[dir.extent] {
+		return fmt.Errorf("directory loop detected at %q", prefix)
+	}
+	visited[dir.extent] = true
+
+	if dir.size > isoMaxDirSize {
+		return fmt.Errorf("directory %q is too large (%d bytes)", prefix, dir.size)
+	}
+	data := make([]byte, dir.size)
+	if _, err := img.r.ReadAt(data, int64(dir.extent)*isoSectorSize); err != nil {
+		return fmt.Errorf("failed to read the directory %q. %w", prefix, err)
+	}
+
+	var current *isoEntry
+	emit := func() error {
+		if current == nil {
+			return nil
+		}
+		e := *current
+		current = nil
+		return fn(e)
+	}
+
+	for pos := 0; pos < len(data); {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rec, length, err := parseISORecord(data[pos:], img.joliet)
+		if err != nil {
+			return fmt.Errorf("failed to read the directory %q. %w", prefix, err)
+		}
+		if length == 0 {
+			// Records don't span sectors, the rest of the sector is padding
+			pos = (pos/isoSectorSize + 1) * isoSectorSize
+			continue
+		}
+		pos += length
+
+		if (rec.name == "\x00") || (rec.name == "\x01") {
+			continue
+		}
+		name, ok := cleanName(prefix + rec.name)
+		if !ok {
+			continue
+		}
+
+		if rec.flags&isoFlagDir != 0 {
+			if err = emit(); err != nil {
+				return err
+			}
+			if err = fn(isoEntry{Member: Member{Name: name, Mode: fs.ModeDir | 0o555, ModTime: rec.modTime}}); err != nil {
+				return err
+			}
+			if err = img.walkDir(ctx, rec, name+"/", depth+1, visited, fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Files larger than 4 GiB are stored as multiple records with the same name
+		ext := isoExtent{offset: int64(rec.extent) * isoSectorSize, size: int64(rec.size)}
+		if (current != nil) && (current.Name == name) {
+			current.extents = append(current.extents, ext)
+			current.Size += uint64(rec.size)
+		} else {
+			if err = emit(); err != nil {
+				return err
+			}
+			current = &isoEntry{
+				Member:  Member{Name: name, Size: uint64(rec.size), Mode: 0o444, ModTime: rec.modTime},
+				extents: []isoExtent{ext},
+			}
+		}
+		if rec.flags&isoFlagMultiExtent == 0 {
+			if err = emit(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return emit()
+}
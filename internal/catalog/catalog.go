@@ -0,0 +1,207 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package catalog provides support for multi-volume database sets.
+//
+// A catalog is a small JSON file (with the .ajfscat extension) that references many ajfs databases,
+// typically one per drive, so that they can be queried as a single set.
+// Relative database paths are resolved against the directory that contains the catalog file.
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// File extension used to identify a catalog.
+const FileExtension = ".ajfscat"
+
+// Volume is a database that belongs to the catalog.
+type Volume struct {
+	Name string `json:"name"` // Unique name used to identify the volume (e.g. the label of the drive).
+	Path string `json:"path"` // Path to the database, relative paths are relative to the catalog file.
+}
+
+// Catalog references many databases that can be queried as a single set.
+type Catalog struct {
+	path string
+
+	Version int      `json:"version"`
+	Volumes []Volume `json:"volumes"`
+}
+
+// Return true if the path refers to a catalog file (based on the file extension).
+func IsCatalog(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), FileExtension)
+}
+
+// Create a new empty catalog that will be saved to the path.
+func New(path string) *Catalog {
+	return &Catalog{
+		path:    path,
+		Version: currentVersion,
+		Volumes: make([]Volume, 0, 8),
+	}
+}
+
+// Load the catalog from the file.
+func Load(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the catalog %q. %w", path, err)
+	}
+
+	c := New(path)
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to decode the catalog %q. %w", path, err)
+	}
+
+	if c.Version > currentVersion {
+		return nil, fmt.Errorf("not a supported catalog (invalid version %d, expected <= %d). path: %q", c.Version, currentVersion, path)
+	}
+
+	return c, nil
+}
+
+// File path of the catalog.
+func (c *Catalog) Path() string {
+	return c.path
+}
+
+// Add the database to the catalog.
+// If name is empty then the base name of the database file (without the extension) is used.
+func (c *Catalog) Add(name string, dbPath string) error {
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(dbPath), filepath.Ext(dbPath))
+	}
+
+	if c.Find(name) != nil {
+		return fmt.Errorf("a volume named %q already exists in the catalog %q", name, c.path)
+	}
+
+	if IsCatalog(dbPath) {
+		return fmt.Errorf("can't add the catalog %q to the catalog %q", dbPath, c.path)
+	}
+
+	c.Volumes = append(c.Volumes, Volume{
+		Name: name,
+		Path: c.relativePath(dbPath),
+	})
+	return nil
+}
+
+// Remove the volume with the specified name from the catalog.
+func (c *Catalog) Remove(name string) error {
+	idx := slices.IndexFunc(c.Volumes, func(v Volume) bool {
+		return v.Name == name
+	})
+	if idx < 0 {
+		return fmt.Errorf("failed to find the volume %q in the catalog %q", name, c.path)
+	}
+
+	c.Volumes = slices.Delete(c.Volumes, idx, idx+1)
+	return nil
+}
+
+// Find the volume with the specified name.
+// Returns nil if the volume does not exist.
+func (c *Catalog) Find(name string) *Volume {
+	for i := range c.Volumes {
+		if c.Volumes[i].Name == name {
+			return &c.Volumes[i]
+		}
+	}
+	return nil
+}
+
+// Return the path to the volume's database, resolved against the directory of the catalog.
+func (c *Catalog) DatabasePath(v Volume) string {
+	if filepath.IsAbs(v.Path) {
+		return v.Path
+	}
+	return filepath.Join(filepath.Dir(c.path), v.Path)
+}
+
+// Save the catalog to the file.
+func (c *Catalog) Save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the catalog %q. %w", c.path, err)
+	}
+	data = append(data, '\n')
+
+	if err := os.WriteFile(c.path, data, 0644); err != nil { //nolint:gosec // G306: same permissions as the databases
+		return fmt.Errorf("failed to write the catalog %q. %w", c.path, err)
+	}
+	return nil
+}
+
+// Store the path relative to the directory of the catalog when possible, otherwise as an absolute path.
+func (c *Catalog) relativePath(dbPath string) string {
+	absPath, err := filepath.Abs(dbPath)
+	if err != nil {
+		return dbPath
+	}
+
+	absDir, err := filepath.Abs(filepath.Dir(c.path))
+	if err != nil {
+		return absPath
+	}
+
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return absPath
+	}
+	return rel
+}
+
+//-----------------------------------------------------------------------------
+
+// Resolve the path to the databases it represents.
+// If the path is a catalog then all the volumes are returned with their database paths resolved,
+// otherwise a single volume without a name is returned for the database.
+func Volumes(path string) ([]Volume, error) {
+	if !IsCatalog(path) {
+		return []Volume{{Path: path}}, nil
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.Volumes) == 0 {
+		return nil, fmt.Errorf("the catalog %q does not contain any volumes", path)
+	}
+
+	result := make([]Volume, 0, len(c.Volumes))
+	for _, v := range c.Volumes {
+		result = append(result, Volume{
+			Name: v.Name,
+			Path: c.DatabasePath(v),
+		})
+	}
+	return result, nil
+}
+
+const currentVersion = 1
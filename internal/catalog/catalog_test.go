@@ -0,0 +1,115 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package catalog_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/catalog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsCatalog(t *testing.T) {
+	assert.True(t, catalog.IsCatalog("backups.ajfscat"))
+	assert.True(t, catalog.IsCatalog("/some/path/BACKUPS.AJFSCAT"))
+	assert.False(t, catalog.IsCatalog("db.ajfs"))
+	assert.False(t, catalog.IsCatalog("ajfscat"))
+}
+
+func TestAddRemove(t *testing.T) {
+	c := catalog.New("backups.ajfscat")
+
+	require.NoError(t, c.Add("", "laptop.ajfs"))
+	require.NoError(t, c.Add("offsite", "usb.ajfs"))
+	assert.Error(t, c.Add("laptop", "another.ajfs"))
+	assert.Error(t, c.Add("", "nested.ajfscat"))
+
+	require.Len(t, c.Volumes, 2)
+	assert.Equal(t, "laptop", c.Volumes[0].Name)
+	assert.Equal(t, "laptop.ajfs", c.Volumes[0].Path)
+	assert.Equal(t, "offsite", c.Volumes[1].Name)
+
+	v := c.Find("offsite")
+	require.NotNil(t, v)
+	assert.Equal(t, "usb.ajfs", v.Path)
+	assert.Nil(t, c.Find("nas"))
+
+	require.NoError(t, c.Remove("laptop"))
+	assert.Error(t, c.Remove("laptop"))
+	require.Len(t, c.Volumes, 1)
+	assert.Equal(t, "offsite", c.Volumes[0].Name)
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	tempDir := t.TempDir()
+	catPath := filepath.Join(tempDir, "backups.ajfscat")
+	outsidePath, err := filepath.Abs(filepath.Join(tempDir, "..", "outside.ajfs"))
+	require.NoError(t, err)
+
+	c := catalog.New(catPath)
+	require.NoError(t, c.Add("", filepath.Join(tempDir, "dbs", "laptop.ajfs")))
+	require.NoError(t, c.Add("", outsidePath))
+	require.NoError(t, c.Save())
+
+	loaded, err := catalog.Load(catPath)
+	require.NoError(t, err)
+	assert.Equal(t, catPath, loaded.Path())
+	require.Len(t, loaded.Volumes, 2)
+
+	// Databases below the catalog are stored as relative paths
+	assert.Equal(t, filepath.Join("dbs", "laptop.ajfs"), loaded.Volumes[0].Path)
+	assert.Equal(t, filepath.Join(tempDir, "dbs", "laptop.ajfs"), loaded.DatabasePath(loaded.Volumes[0]))
+
+	assert.Equal(t, outsidePath, loaded.Volumes[1].Path)
+	assert.Equal(t, outsidePath, loaded.DatabasePath(loaded.Volumes[1]))
+
+	volumes, err := catalog.Volumes(catPath)
+	require.NoError(t, err)
+	assert.Equal(t, []catalog.Volume{
+		{Name: "laptop", Path: filepath.Join(tempDir, "dbs", "laptop.ajfs")},
+		{Name: "outside", Path: outsidePath},
+	}, volumes)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tempDir := t.TempDir()
+
+	_, err := catalog.Load(filepath.Join(tempDir, "missing.ajfscat"))
+	assert.Error(t, err)
+
+	catPath := filepath.Join(tempDir, "future.ajfscat")
+	require.NoError(t, os.WriteFile(catPath, []byte(`{"version": 999, "volumes": []}`), 0644))
+	_, err = catalog.Load(catPath)
+	assert.ErrorContains(t, err, "invalid version")
+
+	emptyPath := filepath.Join(tempDir, "empty.ajfscat")
+	require.NoError(t, catalog.New(emptyPath).Save())
+	_, err = catalog.Volumes(emptyPath)
+	assert.ErrorContains(t, err, "does not contain any volumes")
+}
+
+func TestVolumesForDatabase(t *testing.T) {
+	volumes, err := catalog.Volumes("db.ajfs")
+	require.NoError(t, err)
+	assert.Equal(t, []catalog.Volume{{Path: "db.ajfs"}}, volumes)
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package checkpoint keeps a record of how far a scan or resume got, to diagnose crashes.
+//
+// The checkpoint is stored as a small JSON sidecar file next to the database (e.g. db.ajfs.checkpoint.json).
+// It is rewritten at an interval while the entries are scanned and the hashes are calculated and it is removed
+// when the command finishes. A checkpoint that still exists means the command did not exit cleanly and it
+// describes the last path that was being processed.
+package checkpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// The phases of creating a database.
+const (
+	PhaseScanning = "scanning"
+	PhaseHashing  = "hashing"
+)
+
+// DefaultInterval is the default interval at which the checkpoint file is written.
+const DefaultInterval = 30 * time.Second
+
+// State describes how far the command got.
+type State struct {
+	Command   string    `json:"command"`   // The command that was running, e.g. "scan".
+	Pid       int       `json:"pid"`       // Process identifier of the command.
+	Phase     string    `json:"phase"`     // PhaseScanning or PhaseHashing.
+	LastPath  string    `json:"lastPath"`  // Path, relative to the root, of the last entry that was processed.
+	LastIndex int       `json:"lastIndex"` // Index of the last entry that was hashed. -1 while scanning.
+	Scanned   int       `json:"scanned"`   // Number of entries that have been scanned.
+	Hashed    int       `json:"hashed"`    // Number of files that have been processed by this command before LastPath.
+	Started   time.Time `json:"started"`   // When the command started.
+	Time      time.Time `json:"time"`      // When the checkpoint was written.
+}
+
+// Writer writes the checkpoint file at an interval.
+// All the methods are safe to be called on a nil writer, which does nothing.
+type Writer struct {
+	path     string
+	interval time.Duration
+
+	mu      sync.Mutex
+	state   State
+	written time.Time
+	err     error
+}
+
+// Return the path of the checkpoint file for the database.
+func PathFor(dbPath string) string {
+	return dbPath + ".checkpoint.json"
+}
+
+// Create a writer for the checkpoint file at path that is written at most once per interval.
+// Returns nil when the interval is 0, which disables the checkpoint.
+func NewWriter(path string, command string, interval time.Duration) *Writer {
+	if interval <= 0 {
+		return nil
+	}
+	now := time.Now()
+	return &Writer{
+		path:     path,
+		interval: interval,
+		state: State{
+			Command:   command,
+			Pid:       os.Getpid(),
+			Phase:     PhaseScanning,
+			LastIndex: -1,
+			Started:   now,
+		},
+		// Don't write a checkpoint for commands that finish within the interval
+		written: now,
+	}
+}
+
+// Record that the entry has been scanned.
+func (w *Writer) Scanned(path string) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.state.Phase = PhaseScanning
+	w.state.LastPath = path
+	w.state.Scanned++
+	w.writeIfDue()
+}
+
+// Record that the file signature hash of the entry at the index is about to be calculated.
+func (w *Writer) Hashing(idx int, path string) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.state.Phase == PhaseHashing {
+		// The previous file has been processed
+		w.state.Hashed++
+	}
+	w.state.Phase = PhaseHashing
+	w.state.LastPath = path
+	w.state.LastIndex = idx
+	w.writeIfDue()
+}
+
+// Remove the checkpoint file since the command finished.
+// Returns the first error that happened while writing the checkpoint, if any.
+func (w *Writer) Close() error {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := Remove(w.path); err != nil {
+		return err
+	}
+	return w.err
+}
+
+// Must be called while holding the lock.
+func (w *Writer) writeIfDue() {
+	now := time.Now()
+	if now.Sub(w.written) < w.interval || w.err != nil {
+		return
+	}
+	w.written = now
+	w.state.Time = now
+	w.err = write(w.path, w.state)
+}
+
+// Write the checkpoint to a temporary file first so that a crash never leaves a partially written checkpoint.
+func write(path string, state State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the checkpoint. %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err = os.WriteFile(tmpPath, append(data, '\n'), 0644); err != nil { //nolint:gosec // G306: same permissions as the databases
+		return fmt.Errorf("failed to write the checkpoint %q. %w", tmpPath, err)
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write the checkpoint %q. %w", path, err)
+	}
+	return nil
+}
+
+// Read the checkpoint file. Returns nil if the file does not exist.
+func Read(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read the checkpoint %q. %w", path, err)
+	}
+
+	var state State
+	if err = json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to read the checkpoint %q. %w", path, err)
+	}
+	return &state, nil
+}
+
+// Remove the checkpoint file if it exists.
+func Remove(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove the checkpoint %q. %w", path, err)
+	}
+	return nil
+}
+
+// Describe what the command was doing when the checkpoint was written.
+func (s *State) Describe() string {
+	if s.Phase == PhaseHashing {
+		return fmt.Sprintf("hashed %q (entry %d) at %s, after processing %d files",
+			s.LastPath, s.LastIndex, s.Time.Format(time.DateTime), s.Hashed)
+	}
+	return fmt.Sprintf("scanned %q at %s, after scanning %d entries",
+		s.LastPath, s.Time.Format(time.DateTime), s.Scanned)
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package checkpoint_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/checkpoint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	cpPath := checkpoint.PathFor(filepath.Join(t.TempDir(), "unit-testing"))
+
+	// Disabled
+	w := checkpoint.NewWriter(cpPath, "scan", 0)
+	assert.Nil(t, w)
+	w.Scanned("a")
+	w.Hashing(1, "a")
+	assert.NoError(t, w.Close())
+	assert.NoFileExists(t, cpPath)
+
+	// Not written before the interval has passed
+	w = checkpoint.NewWriter(cpPath, "scan", time.Hour)
+	w.Scanned("a")
+	assert.NoFileExists(t, cpPath)
+	require.NoError(t, w.Close())
+
+	w = checkpoint.NewWriter(cpPath, "scan", time.Millisecond)
+	w.Scanned("a")
+	w.Scanned("b")
+	time.Sleep(2 * time.Millisecond)
+	w.Scanned("c")
+	require.FileExists(t, cpPath)
+
+	state, err := checkpoint.Read(cpPath)
+	require.NoError(t, err)
+	require.NotNil(t, state)
+	assert.Equal(t, "scan", state.Command)
+	assert.Equal(t, os.Getpid(), state.Pid)
+	assert.Equal(t, checkpoint.PhaseScanning, state.Phase)
+	assert.Equal(t, "c", state.LastPath)
+	assert.Equal(t, -1, state.LastIndex)
+	assert.Equal(t, 3, state.Scanned)
+	assert.Contains(t, state.Describe(), `scanned "c" at `)
+
+	w.Hashing(0, "a")
+	w.Hashing(2, "c")
+	time.Sleep(2 * time.Millisecond)
+	w.Hashing(5, "f")
+
+	state, err = checkpoint.Read(cpPath)
+	require.NoError(t, err)
+	assert.Equal(t, checkpoint.PhaseHashing, state.Phase)
+	assert.Equal(t, "f", state.LastPath)
+	assert.Equal(t, 5, state.LastIndex)
+	assert.Equal(t, 2, state.Hashed)
+	assert.Contains(t, state.Describe(), `hashed "f" (entry 5) at `)
+
+	// Removed on a clean exit
+	require.NoError(t, w.Close())
+	assert.NoFileExists(t, cpPath)
+
+	state, err = checkpoint.Read(cpPath)
+	require.NoError(t, err)
+	assert.Nil(t, state)
+}
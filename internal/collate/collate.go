@@ -0,0 +1,313 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package collate is used to sort names and paths the way a person would expect
+// for a specific language, instead of by the raw bytes.
+//
+// Letters with diacritics sort together with their base letter (e.g. "é" with "e")
+// and upper and lower case letters sort together. Languages that treat some letters
+// as separate letters of the alphabet (e.g. "å" in Swedish sorts after "z") are
+// supported using tailorings. This is a small subset of the Unicode Collation
+// Algorithm that covers the Latin scripts. Other scripts are sorted by code point.
+package collate
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Collator compares strings using the sort order of a language.
+type Collator struct {
+	locale    string
+	tailoring *tailoring
+}
+
+// Tailoring of the letters that sort as separate letters of the alphabet.
+type tailoring struct {
+	letters      map[rune]uint32
+	contractions []contraction       // Ordered with the longest sequences first.
+	lower        unicode.SpecialCase // Case mapping used instead of the default (can be nil).
+}
+
+// A sequence of letters that sorts as a single letter (e.g. "ch" in Czech).
+type contraction struct {
+	letters string
+	weight  uint32
+}
+
+// Primary weights leave room between the letters for the tailored letters.
+const weightShift = 8
+
+// Swedish and Finnish: å, ä, ö after z (æ and ø are treated as ä and ö).
+var swedish = map[rune]uint32{
+	'å': 'z'<<weightShift + 1,
+	'ä': 'z'<<weightShift + 2, 'æ': 'z'<<weightShift + 2,
+	'ö': 'z'<<weightShift + 3, 'ø': 'z'<<weightShift + 3,
+}
+
+// Danish and Norwegian: æ, ø, å after z (ä and ö are treated as æ and ø).
+var danish = map[rune]uint32{
+	'æ': 'z'<<weightShift + 1, 'ä': 'z'<<weightShift + 1,
+	'ø': 'z'<<weightShift + 2, 'ö': 'z'<<weightShift + 2,
+	'å': 'z'<<weightShift + 3,
+}
+
+// Spanish: ñ after n.
+var spanish = map[rune]uint32{
+	'ñ': 'n'<<weightShift + 1,
+}
+
+// Czech: č, ř, š, ž after their base letter and "ch" after h.
+var czech = map[rune]uint32{
+	'č': 'c'<<weightShift + 1,
+	'ř': 'r'<<weightShift + 1,
+	'š': 's'<<weightShift + 1,
+	'ž': 'z'<<weightShift + 1,
+}
+
+var czechContractions = []contraction{
+	{"ch", 'h'<<weightShift + 1},
+}
+
+// Polish: ą, ć, ę, ł, ń, ó, ś after their base letter and ź, ż after z.
+var polish = map[rune]uint32{
+	'ą': 'a'<<weightShift + 1,
+	'ć': 'c'<<weightShift + 1,
+	'ę': 'e'<<weightShift + 1,
+	'ł': 'l'<<weightShift + 1,
+	'ń': 'n'<<weightShift + 1,
+	'ó': 'o'<<weightShift + 1,
+	'ś': 's'<<weightShift + 1,
+	'ź': 'z'<<weightShift + 1,
+	'ż': 'z'<<weightShift + 2,
+}
+
+// Hungarian: ö, ő after o and ü, ű after u.
+var hungarian = map[rune]uint32{
+	'ö': 'o'<<weightShift + 1, 'ő': 'o'<<weightShift + 1,
+	'ü': 'u'<<weightShift + 1, 'ű': 'u'<<weightShift + 1,
+}
+
+// Hungarian: the double and triple letters after their first letter.
+// Doubled forms (e.g. "ssz" for "sz" + "sz") are not expanded.
+var hungarianContractions = []contraction{
+	{"dzs", 'd'<<weightShift + 2},
+	{"cs", 'c'<<weightShift + 1},
+	{"dz", 'd'<<weightShift + 1},
+	{"gy", 'g'<<weightShift + 1},
+	{"ly", 'l'<<weightShift + 1},
+	{"ny", 'n'<<weightShift + 1},
+	{"sz", 's'<<weightShift + 1},
+	{"ty", 't'<<weightShift + 1},
+	{"zs", 'z'<<weightShift + 1},
+}
+
+// Turkish: ç, ğ, ö, ş, ü after their base letter and the dotless ı before i.
+var turkish = map[rune]uint32{
+	'ç': 'c'<<weightShift + 1,
+	'ğ': 'g'<<weightShift + 1,
+	'ı': 'h'<<weightShift + 1,
+	'ö': 'o'<<weightShift + 1,
+	'ş': 's'<<weightShift + 1,
+	'ü': 'u'<<weightShift + 1,
+}
+
+// Tailorings of the letters that sort as separate letters of the alphabet.
+var tailorings = map[string]*tailoring{
+	"sv": {letters: swedish},
+	"fi": {letters: swedish},
+	"da": {letters: danish},
+	"nb": {letters: danish},
+	"nn": {letters: danish},
+	"no": {letters: danish},
+	"es": {letters: spanish},
+	"cs": {letters: czech, contractions: czechContractions},
+	"pl": {letters: polish},
+	"hu": {letters: hungarian, contractions: hungarianContractions},
+	"tr": {letters: turkish, lower: unicode.TurkishCase}, // I is the upper case of ı
+}
+
+// Languages that use the default order.
+var untailored = []string{"und", "en", "de", "fr", "it", "pt", "nl", "af", "ca", "ro"}
+
+// Create a new collator for the locale.
+// The locale can be a language (e.g. "sv") or a POSIX style locale (e.g. "sv_SE.UTF-8").
+// An empty locale, "C" or "POSIX" returns nil which means the raw bytes should be compared.
+func New(locale string) (*Collator, error) {
+	if locale == "" || locale == "C" || locale == "POSIX" {
+		return nil, nil
+	}
+
+	lang := strings.ToLower(locale)
+	if i := strings.IndexAny(lang, "-_.@"); i >= 0 {
+		lang = lang[:i]
+	}
+
+	if t, ok := tailorings[lang]; ok {
+		return &Collator{locale: locale, tailoring: t}, nil
+	}
+	if slices.Contains(untailored, lang) {
+		return &Collator{locale: locale}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported locale %q. supported languages: %s", locale, strings.Join(Languages(), ", "))
+}
+
+// Return the supported languages.
+func Languages() []string {
+	return slices.Sorted(slices.Values(append(slices.Collect(maps.Keys(tailorings)), untailored...)))
+}
+
+// Locale used to create the collator.
+func (c *Collator) Locale() string {
+	return c.locale
+}
+
+// Compare two strings and return -1 if a sorts before b, 0 if they are equal and +1 if a sorts after b.
+// If the collator is nil then the raw bytes are compared.
+func (c *Collator) Compare(a, b string) int {
+	if c == nil {
+		return strings.Compare(a, b)
+	}
+
+	// Primary: the base letters
+	if r := c.comparePrimary(a, b); r != 0 {
+		return r
+	}
+
+	// Secondary: the diacritics
+	if r := compareRunes(a, b, c.toLower); r != 0 {
+		return r
+	}
+
+	// Tertiary: lower case before upper case
+	if r := compareRunes(a, b, func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return 1
+		}
+		return 0
+	}); r != 0 {
+		return r
+	}
+
+	return strings.Compare(a, b)
+}
+
+// Compare two paths one component at a time so that the entries inside of a directory
+// sort directly after the directory.
+// If the collator is nil then the components are compared using the raw bytes.
+func (c *Collator) ComparePaths(a, b string, separator rune) int {
+	for {
+		aHead, aTail, aMore := strings.Cut(a, string(separator))
+		bHead, bTail, bMore := strings.Cut(b, string(separator))
+
+		if r := c.Compare(aHead, bHead); r != 0 {
+			return r
+		}
+
+		switch {
+		case !aMore && !bMore:
+			return 0
+		case !aMore:
+			return -1
+		case !bMore:
+			return 1
+		}
+		a, b = aTail, bTail
+	}
+}
+
+func (c *Collator) comparePrimary(a, b string) int {
+	var aBuf, bBuf [8]uint32
+	aw := c.appendWeights(aBuf[:0], a)
+	bw := c.appendWeights(bBuf[:0], b)
+	return slices.Compare(aw, bw)
+}
+
+// Append the primary weight of each letter (ignoring case and diacritics) to dst.
+func (c *Collator) appendWeights(dst []uint32, s string) []uint32 {
+	for len(s) > 0 {
+		if w, size, ok := c.contraction(s); ok {
+			dst = append(dst, w)
+			s = s[size:]
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		r = c.toLower(r)
+
+		if c.tailoring != nil {
+			if w, ok := c.tailoring.letters[r]; ok {
+				dst = append(dst, w)
+				continue
+			}
+		}
+
+		if base, ok := fold[r]; ok {
+			for _, b := range base {
+				dst = append(dst, uint32(b)<<weightShift)
+			}
+			continue
+		}
+
+		dst = append(dst, uint32(r)<<weightShift)
+	}
+	return dst
+}
+
+// Return the weight and length of the contraction that s starts with.
+func (c *Collator) contraction(s string) (uint32, int, bool) {
+	if c.tailoring == nil {
+		return 0, 0, false
+	}
+	for _, ct := range c.tailoring.contractions {
+		if len(s) >= len(ct.letters) && strings.EqualFold(s[:len(ct.letters)], ct.letters) {
+			return ct.weight, len(ct.letters), true
+		}
+	}
+	return 0, 0, false
+}
+
+func (c *Collator) toLower(r rune) rune {
+	if c.tailoring != nil && c.tailoring.lower != nil {
+		return c.tailoring.lower.ToLower(r)
+	}
+	return unicode.ToLower(r)
+}
+
+// Compare the strings after mapping each rune.
+func compareRunes(a, b string, mapFn func(r rune) rune) int {
+	for len(a) > 0 && len(b) > 0 {
+		ar, aSize := utf8.DecodeRuneInString(a)
+		br, bSize := utf8.DecodeRuneInString(b)
+		if r := int(mapFn(ar)) - int(mapFn(br)); r != 0 {
+			if r < 0 {
+				return -1
+			}
+			return 1
+		}
+		a, b = a[aSize:], b[bSize:]
+	}
+	return len(a) - len(b)
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package collate_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/collate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	for _, locale := range []string{"", "C", "POSIX"} {
+		c, err := collate.New(locale)
+		require.NoError(t, err)
+		assert.Nil(t, c)
+	}
+
+	for _, locale := range []string{"en", "sv", "sv_SE.UTF-8", "de-DE", "nb_NO", "ES"} {
+		c, err := collate.New(locale)
+		require.NoError(t, err, locale)
+		assert.Equal(t, locale, c.Locale())
+	}
+
+	_, err := collate.New("xx_XX")
+	assert.ErrorContains(t, err, "unsupported locale")
+}
+
+func TestCompare(t *testing.T) {
+	testCases := []struct {
+		locale   string
+		input    []string
+		expected []string
+	}{
+		{
+			locale:   "",
+			input:    []string{"b", "Émile", "a", "B", "éa", "ea"},
+			expected: []string{"B", "a", "b", "ea", "Émile", "éa"},
+		},
+		{
+			locale:   "en",
+			input:    []string{"b", "Émile", "a", "B", "éa", "ea", "Zoë", "zebra"},
+			expected: []string{"a", "b", "B", "ea", "éa", "Émile", "zebra", "Zoë"},
+		},
+		{
+			locale:   "en",
+			input:    []string{"Straße", "strasse", "Strasse", "strase"},
+			expected: []string{"strase", "strasse", "Strasse", "Straße"},
+		},
+		{
+			locale:   "sv",
+			input:    []string{"öl", "zebra", "åka", "äpple", "apa", "ål"},
+			expected: []string{"apa", "zebra", "åka", "ål", "äpple", "öl"},
+		},
+		{
+			locale:   "da",
+			input:    []string{"år", "ært", "zoo", "øl", "abe"},
+			expected: []string{"abe", "zoo", "ært", "øl", "år"},
+		},
+		{
+			locale:   "es",
+			input:    []string{"nube", "ñu", "oso", "nzz"},
+			expected: []string{"nube", "nzz", "ñu", "oso"},
+		},
+		{
+			locale:   "cs",
+			input:    []string{"čaj", "ibis", "chata", "cukr", "hrad", "dům"},
+			expected: []string{"cukr", "čaj", "dům", "hrad", "chata", "ibis"},
+		},
+		{
+			locale:   "pl",
+			input:    []string{"łódź", "żaba", "lz", "źle", "zebra", "mama"},
+			expected: []string{"lz", "łódź", "mama", "zebra", "źle", "żaba"},
+		},
+		{
+			locale:   "hu",
+			input:    []string{"ökör", "csak", "ozon", "cukor", "szél", "sút", "tó"},
+			expected: []string{"cukor", "csak", "ozon", "ökör", "sút", "szél", "tó"},
+		},
+		{
+			locale:   "tr",
+			input:    []string{"çay", "iğne", "Irmak", "cam", "hz", "şu", "sz"},
+			expected: []string{"cam", "çay", "hz", "Irmak", "iğne", "sz", "şu"},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.locale, func(t *testing.T) {
+			c, err := collate.New(tC.locale)
+			require.NoError(t, err)
+
+			result := slices.Clone(tC.input)
+			slices.SortFunc(result, c.Compare)
+			assert.Equal(t, tC.expected, result)
+		})
+	}
+}
+
+func TestComparePaths(t *testing.T) {
+	c, err := collate.New("en")
+	require.NoError(t, err)
+
+	input := []string{"b", "a b", "a/c", "a", "a/b/c", "Á/a", "a-b"}
+	slices.SortFunc(input, func(a, b string) int {
+		return c.ComparePaths(a, b, '/')
+	})
+	assert.Equal(t, []string{"a", "a/b/c", "a/c", "Á/a", "a b", "a-b", "b"}, input)
+
+	var raw *collate.Collator
+	assert.Equal(t, -1, raw.ComparePaths("a/b", "a b", '/'))
+}
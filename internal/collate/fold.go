@@ -0,0 +1,59 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package collate
+
+// The base letters for the lower case letters with diacritics and ligatures of the
+// Latin-1 Supplement and Latin Extended-A blocks.
+var fold = buildFold(map[string]string{
+	"a":  "àáâãäåāăąǎ",
+	"c":  "çćĉċč",
+	"d":  "ďđð",
+	"e":  "èéêëēĕėęě",
+	"g":  "ĝğġģ",
+	"h":  "ĥħ",
+	"i":  "ìíîïĩīĭįıǐ",
+	"j":  "ĵ",
+	"k":  "ķĸ",
+	"l":  "ĺļľŀł",
+	"n":  "ñńņňŉŋ",
+	"o":  "òóôõöøōŏőǒ",
+	"r":  "ŕŗř",
+	"s":  "śŝşšſ",
+	"t":  "ţťŧ",
+	"u":  "ùúûüũūŭůűųǔ",
+	"w":  "ŵ",
+	"y":  "ýÿŷ",
+	"z":  "źżž",
+	"ae": "æ",
+	"oe": "œ",
+	"ss": "ß",
+	"th": "þ",
+	"ij": "ĳ",
+})
+
+func buildFold(m map[string]string) map[rune]string {
+	result := make(map[rune]string, 256)
+	for base, letters := range m {
+		for _, r := range letters {
+			result[r] = base
+		}
+	}
+	return result
+}
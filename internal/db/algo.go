@@ -0,0 +1,105 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package db
+
+import (
+	"crypto/md5" // #nosec G501 -- MD5 is only used for interop with legacy manifests
+	"hash"
+	"slices"
+
+	"github.com/andrejacobs/go-aj/ajhash"
+)
+
+// AlgoMD5 is the legacy MD5 hashing algorithm.
+// It is only supported to be able to verify and compare against old md5 based manifests.
+// The value is outside of the range used by ajhash so that it won't clash with algorithms added there.
+const AlgoMD5 ajhash.Algo = 0x80
+
+// AlgoString returns the display name of the hashing algorithm.
+func AlgoString(algo ajhash.Algo) string {
+	if algo == AlgoMD5 {
+		return "MD5 (legacy)"
+	}
+	return algo.String()
+}
+
+// AlgoSize returns the number of bytes of a hash calculated with the algorithm.
+func AlgoSize(algo ajhash.Algo) int {
+	if algo == AlgoMD5 {
+		return md5.Size
+	}
+	return algo.Size()
+}
+
+// AlgoHasher returns a new hasher for the algorithm.
+func AlgoHasher(algo ajhash.Algo) hash.Hash {
+	if algo == AlgoMD5 {
+		return md5.New() // #nosec G401 -- MD5 is only used for interop with legacy manifests
+	}
+	return algo.Hasher()
+}
+
+// AlgoZeroValue returns a zeroed buffer that is big enough to hold a hash calculated with the algorithm.
+func AlgoZeroValue(algo ajhash.Algo) []byte {
+	return make([]byte, AlgoSize(algo))
+}
+
+// Check if the hashing algorithm is known.
+func validAlgo(algo ajhash.Algo) bool {
+	switch algo {
+	case ajhash.AlgoSHA1, ajhash.AlgoSHA256, ajhash.AlgoSHA512, AlgoMD5:
+		return true
+	}
+	return false
+}
+
+// The relative strength of the hashing algorithm, higher is stronger.
+func algoStrength(algo ajhash.Algo) int {
+	switch algo {
+	case AlgoMD5:
+		return 1
+	case ajhash.AlgoSHA1:
+		return 2
+	case ajhash.AlgoSHA256:
+		return 3
+	case ajhash.AlgoSHA512:
+		return 4
+	}
+	return 0
+}
+
+// StrongestCommonAlgo returns the strongest hashing algorithm that is present in both lhs and rhs.
+// Returns false if the two don't have an algorithm in common.
+func StrongestCommonAlgo(lhs []ajhash.Algo, rhs []ajhash.Algo) (ajhash.Algo, bool) {
+	var result ajhash.Algo
+	found := false
+
+	for _, algo := range lhs {
+		if !slices.Contains(rhs, algo) {
+			continue
+		}
+		if !found || (algoStrength(algo) > algoStrength(result)) {
+			result = algo
+			found = true
+		}
+	}
+
+	return result, found
+}
@@ -0,0 +1,375 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package db
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajmath/safe"
+)
+
+// file format
+// ... <all the other sections except the signature>
+// sentinel
+// annotationsHeader
+// n * (annotationEntry, key), where n == number of annotations
+// sentinel
+//
+// The annotations are added after the database was created and are therefore stored after all the other sections
+// (only the signature can follow). An annotation records a review decision for a path entry (keyed by the path
+// identifier) or for a group of duplicate files (keyed by the file signature hash they share).
+
+// Label records the review decision made for a path entry or a group of duplicate files.
+type Label uint8
+
+const (
+	LabelReviewed        Label = 1 + iota // The item has been reviewed.
+	LabelKeep                             // The item should be kept.
+	LabelDeleteCandidate                  // The item can be deleted.
+)
+
+var labelNames = map[Label]string{
+	LabelReviewed:        "reviewed",
+	LabelKeep:            "keep",
+	LabelDeleteCandidate: "delete-candidate",
+}
+
+// Stringer implementation.
+func (l Label) String() string {
+	if name, ok := labelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("label(%d)", uint8(l))
+}
+
+// Parse the label from its name, e.g. "keep".
+func ParseLabel(s string) (Label, error) {
+	for l, name := range labelNames {
+		if strings.EqualFold(s, name) {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid label %q. valid labels are reviewed, keep and delete-candidate", s)
+}
+
+// Annotation is the label given to an item and when it was given.
+type Annotation struct {
+	Label Label
+	Time  time.Time
+}
+
+// Annotations are the review decisions recorded in a database.
+type Annotations struct {
+	Entries map[path.Id]Annotation // Annotated path entries keyed by the path identifier.
+	Groups  map[string]Annotation  // Annotated groups of duplicate files keyed by the hex encoded file signature hash.
+}
+
+// Create an empty set of annotations.
+func NewAnnotations() *Annotations {
+	return &Annotations{
+		Entries: make(map[path.Id]Annotation),
+		Groups:  make(map[string]Annotation),
+	}
+}
+
+// Return the number of annotations.
+func (a *Annotations) Len() int {
+	return len(a.Entries) + len(a.Groups)
+}
+
+// Replace the annotations stored in the database. Empty annotations remove the section.
+// Any signature is removed.
+func SetAnnotations(dbPath string, a *Annotations) error {
+	dbf, err := OpenDatabase(dbPath)
+	if err != nil {
+		return err
+	}
+	limited := dbf.Limited()
+	h := dbf.header
+	info, err := dbf.file.File().Stat()
+	if err != nil {
+		dbf.Close()
+		return err
+	}
+	if err = dbf.Close(); err != nil {
+		return err
+	}
+
+	if limited {
+		return fmt.Errorf("can't annotate %q because it can only be processed in a limited way", dbPath)
+	}
+
+	// The section replaces the existing one and any signature that follows it
+	offset := info.Size()
+	if h.Features.HasSignature() {
+		offset = int64(h.SignatureOffset)
+	}
+	if h.Features.HasAnnotations() {
+		offset = int64(h.AnnotationsOffset)
+	}
+
+	// The content changes and thus an existing signature would no longer be valid
+	h.Features &^= FeatureSignature | FeatureAnnotations
+	h.SignatureOffset = 0
+	h.AnnotationsOffset = 0
+
+	f, err := os.OpenFile(dbPath, os.O_RDWR|os.O_EXCL, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open the ajfs database file. path: %q. %w", dbPath, err)
+	}
+	defer f.Close()
+
+	if err = f.Truncate(offset); err != nil {
+		return fmt.Errorf("failed to remove the existing annotations. path: %q. %w", dbPath, err)
+	}
+
+	if (a != nil) && (a.Len() > 0) {
+		if err = appendAnnotations(f, offset, &h, a); err != nil {
+			return fmt.Errorf("failed to write the annotations. path: %q. %w", dbPath, err)
+		}
+	}
+
+	// The header is only updated once the annotations have been written
+	if _, err = f.Seek(headerOffset(), io.SeekStart); err != nil {
+		return err
+	}
+	if err = h.write(f); err != nil {
+		return fmt.Errorf("failed to update the ajfs header. %w", err)
+	}
+
+	return f.Sync()
+}
+
+// Read the annotations. Returns empty annotations if the database does not contain any.
+func (dbf *DatabaseFile) ReadAnnotations() (*Annotations, error) {
+	if !dbf.header.Features.HasAnnotations() {
+		return NewAnnotations(), nil
+	}
+
+	if dbf.newerVersion() {
+		return nil, fmt.Errorf("the annotations of the database %q are not supported (file format version %d, expected <= %d)",
+			dbf.path, dbf.prefixHeader.Version, currentVersion)
+	}
+
+	_, err := dbf.file.Seek(int64(dbf.header.AnnotationsOffset), io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the annotations. %w", err)
+	}
+	dbf.file.ResetReadBuffer()
+
+	return readAnnotationsFrom(dbf.file)
+}
+
+//-----------------------------------------------------------------------------
+// Reading and writing
+
+// Kind of item that was annotated.
+type annotationKind uint8
+
+const (
+	annotatedEntry annotationKind = 1 + iota // Key is the path identifier
+	annotatedGroup                           // Key is the file signature hash
+)
+
+// Write the annotations section at offset (the current end of the file) and update the header to include it.
+func appendAnnotations(f *os.File, offset int64, h *header, a *Annotations) error {
+	var err error
+	h.AnnotationsOffset, err = safe.Int64ToUint32(offset)
+	if err != nil {
+		return fmt.Errorf("failed to set the ajfs annotations offset. %w", err)
+	}
+	h.Features |= FeatureAnnotations
+
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	if err = writeAnnotations(w, a); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// Read the annotations section from the reader.
+func readAnnotationsFrom(r io.Reader) (*Annotations, error) {
+	// Check 1st sentinel
+	var s [4]byte
+	_, err := io.ReadFull(r, s[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the annotations (1st sentinel). %w", err)
+	}
+	if s != annotationsSentinel {
+		return nil, fmt.Errorf("failed to read the annotations (1st sentinel %q does not match %q)", s, annotationsSentinel)
+	}
+
+	header := annotationsHeader{}
+	if err := header.read(r); err != nil {
+		return nil, fmt.Errorf("failed to read the annotations header. %w", err)
+	}
+
+	result := NewAnnotations()
+	for i := range header.Count {
+		entry := annotationEntry{}
+		if err := entry.read(r); err != nil {
+			return nil, fmt.Errorf("failed to read the annotation at index %d. %w", i, err)
+		}
+
+		key := make([]byte, entry.KeyLength)
+		if _, err := io.ReadFull(r, key); err != nil {
+			return nil, fmt.Errorf("failed to read the key of the annotation at index %d. %w", i, err)
+		}
+
+		a := Annotation{
+			Label: entry.Label,
+			Time:  time.Unix(entry.Time, 0),
+		}
+
+		switch entry.Kind {
+		case annotatedEntry:
+			var id path.Id
+			if len(key) != len(id) {
+				return nil, fmt.Errorf("the annotation at index %d has an invalid path identifier size %d", i, len(key))
+			}
+			copy(id[:], key)
+			result.Entries[id] = a
+		case annotatedGroup:
+			result.Groups[hex.EncodeToString(key)] = a
+		default:
+			return nil, fmt.Errorf("the annotation at index %d has an unknown kind %d", i, entry.Kind)
+		}
+	}
+
+	// Check 2nd sentinel
+	_, err = io.ReadFull(r, s[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the annotations (2nd sentinel). %w", err)
+	}
+	if s != annotationsSentinel {
+		return nil, fmt.Errorf("failed to read the annotations (2nd sentinel %q does not match %q)", s, annotationsSentinel)
+	}
+
+	return result, nil
+}
+
+// Write the annotations section.
+// The annotations are written in a stable order so that the same annotations always produce the same bytes.
+func writeAnnotations(w io.Writer, a *Annotations) error {
+	header := annotationsHeader{}
+	var err error
+	header.Count, err = safe.IntToUint32(a.Len())
+	if err != nil {
+		return err
+	}
+
+	if _, err = w.Write(annotationsSentinel[:]); err != nil {
+		return err
+	}
+	if err = header.write(w); err != nil {
+		return err
+	}
+
+	ids := slices.SortedFunc(maps.Keys(a.Entries), func(x, y path.Id) int {
+		return strings.Compare(string(x[:]), string(y[:]))
+	})
+	for _, id := range ids {
+		if err = writeAnnotation(w, annotatedEntry, id[:], a.Entries[id]); err != nil {
+			return err
+		}
+	}
+
+	for _, hash := range slices.Sorted(maps.Keys(a.Groups)) {
+		key, err := hex.DecodeString(hash)
+		if err != nil {
+			return fmt.Errorf("invalid file signature hash %q. %w", hash, err)
+		}
+		if err = writeAnnotation(w, annotatedGroup, key, a.Groups[hash]); err != nil {
+			return err
+		}
+	}
+
+	_, err = w.Write(annotationsSentinel[:])
+	return err
+}
+
+func writeAnnotation(w io.Writer, kind annotationKind, key []byte, a Annotation) error {
+	if len(key) > 255 {
+		return fmt.Errorf("annotation key size %d is too large", len(key))
+	}
+
+	entry := annotationEntry{
+		Kind:      kind,
+		Label:     a.Label,
+		KeyLength: uint8(len(key)), //nolint:gosec // disable G115
+		Time:      a.Time.Unix(),
+	}
+	if err := entry.write(w); err != nil {
+		return err
+	}
+	_, err := w.Write(key)
+	return err
+}
+
+//-----------------------------------------------------------------------------
+// Header and entries
+
+type annotationsHeader struct {
+	Count uint32 // The number of annotations
+}
+
+func (s *annotationsHeader) read(r io.Reader) error {
+	return binary.Read(r, binary.LittleEndian, s)
+}
+
+func (s *annotationsHeader) write(w io.Writer) error {
+	return binary.Write(w, binary.LittleEndian, s)
+}
+
+type annotationEntry struct {
+	Kind      annotationKind // What was annotated
+	Label     Label          // The review decision
+	KeyLength uint8          // Size of the key that follows the entry
+	Time      int64          // Unix time at which the annotation was made
+}
+
+func (s *annotationEntry) read(r io.Reader) error {
+	return binary.Read(r, binary.LittleEndian, s)
+}
+
+func (s *annotationEntry) write(w io.Writer) error {
+	return binary.Write(w, binary.LittleEndian, s)
+}
+
+//-----------------------------------------------------------------------------
+// Constants and Misc
+
+var (
+	annotationsSentinel = [4]byte{0x41, 0x4A, 0x41, 0x4E} // AJAN
+)
@@ -0,0 +1,145 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package db_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetAnnotations(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test", db.FeatureHashTable)
+	require.NoError(t, err)
+
+	const count = 4
+	ids := make([]path.Id, count)
+	for i := range count {
+		p := fmt.Sprintf("some/path/%d.txt", i)
+		ids[i] = path.IdFromPath(p)
+		pi := path.Info{
+			Id:      ids[i],
+			Path:    p,
+			Size:    uint64(i),
+			ModTime: time.Now(),
+		}
+		require.NoError(t, dbf.WriteEntry(&pi))
+	}
+	require.NoError(t, dbf.FinishEntries())
+
+	hash := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a,
+		0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x11, 0x12, 0x13, 0x14}
+	require.NoError(t, dbf.StartHashTable(ajhash.AlgoSHA1))
+	require.NoError(t, dbf.WriteHashEntry(1, hash))
+	require.NoError(t, dbf.WriteHashEntry(2, hash))
+	require.NoError(t, dbf.FinishHashTable())
+	require.NoError(t, dbf.Close())
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.NoError(t, db.SignDatabase(tempFile, key))
+
+	read := func() *db.Annotations {
+		dbf, err := db.OpenDatabase(tempFile)
+		require.NoError(t, err)
+		defer dbf.Close()
+
+		a, err := dbf.ReadAnnotations()
+		require.NoError(t, err)
+		return a
+	}
+
+	// No annotations yet
+	assert.Equal(t, 0, read().Len())
+
+	at := time.Unix(time.Now().Unix(), 0)
+	expected := db.NewAnnotations()
+	expected.Entries[ids[1]] = db.Annotation{Label: db.LabelKeep, Time: at}
+	expected.Entries[ids[2]] = db.Annotation{Label: db.LabelDeleteCandidate, Time: at}
+	expected.Groups[hex.EncodeToString(hash)] = db.Annotation{Label: db.LabelReviewed, Time: at}
+	require.NoError(t, db.SetAnnotations(tempFile, expected))
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	assert.True(t, dbf.Features().HasAnnotations())
+	assert.False(t, dbf.Features().HasSignature())
+	require.NoError(t, dbf.VerifyChecksums())
+	require.NoError(t, dbf.Close())
+
+	assert.Equal(t, expected, read())
+
+	// Replace the annotations
+	delete(expected.Entries, ids[2])
+	require.NoError(t, db.SetAnnotations(tempFile, expected))
+	assert.Equal(t, expected, read())
+
+	// The annotations are kept when an additional hash table is added
+	require.NoError(t, db.AddHashTable(context.Background(), tempFile, ajhash.AlgoSHA256, db.HashTable{}))
+	assert.Equal(t, expected, read())
+
+	// The header matches the contents
+	require.NoError(t, db.FixDatabase(io.Discard, tempFile, true, ""))
+
+	// Pruning only keeps the annotations of the remaining entries
+	_, err = db.PruneDatabase(context.Background(), tempFile, func(idx int, pi path.Info, hash []byte) (bool, error) {
+		return idx != 1, nil
+	})
+	require.NoError(t, err)
+
+	a := read()
+	assert.Empty(t, a.Entries)
+	assert.Equal(t, expected.Groups, a.Groups)
+
+	// Removing all the annotations removes the section
+	require.NoError(t, db.SetAnnotations(tempFile, db.NewAnnotations()))
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+	assert.False(t, dbf.Features().HasAnnotations())
+}
+
+func TestParseLabel(t *testing.T) {
+	for _, l := range []db.Label{db.LabelReviewed, db.LabelKeep, db.LabelDeleteCandidate} {
+		parsed, err := db.ParseLabel(l.String())
+		require.NoError(t, err)
+		assert.Equal(t, l, parsed)
+	}
+
+	l, err := db.ParseLabel("Delete-Candidate")
+	require.NoError(t, err)
+	assert.Equal(t, db.LabelDeleteCandidate, l)
+
+	_, err = db.ParseLabel("maybe")
+	assert.Error(t, err)
+}
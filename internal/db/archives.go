@@ -0,0 +1,43 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package db
+
+// Record that the members of tar and zip archives are stored as virtual entries (e.g. "photos.tar!/a.jpg").
+// The flag is stored in the header so that the archives are expanded again when the database is updated.
+func (dbf *DatabaseFile) SetArchives() {
+	dbf.panicIfNotWriting()
+	dbf.header.Status |= statusArchives
+}
+
+// Returns true if the members of tar and zip archives are stored as virtual entries, see [SetArchives].
+func (dbf *DatabaseFile) Archives() bool {
+	return (dbf.header.Status & statusArchives) != 0
+}
+
+// Returns true if the members of tar and zip archives are stored as virtual entries, see [SetArchives].
+// Only the headers are read which means this is cheap enough to be called before a command opens the database.
+func ReadArchives(dbPath string) (bool, error) {
+	h, err := readHeader(dbPath)
+	if err != nil {
+		return false, err
+	}
+	return (h.Status & statusArchives) != 0, nil
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package db
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/andrejacobs/go-aj/ajmath/safe"
+)
+
+// file format
+// ... <entries and entries lookup table>
+// sentinel
+// n bytes checksum, where n is determined by the checksum algorithm
+//
+// The extended checksum is only present when an algorithm other than CRC-32 is used.
+// The CRC-32 checksum is always stored in the header so that older versions can still verify the database.
+
+// ChecksumAlgo is the algorithm used to calculate the database file integrity checksum.
+type ChecksumAlgo uint32
+
+const (
+	ChecksumCRC32  ChecksumAlgo = iota // CRC-32 (IEEE) stored in the header.
+	ChecksumSHA256                     // SHA-256 stored in the extended checksum section.
+)
+
+func (c ChecksumAlgo) String() string {
+	switch c {
+	case ChecksumCRC32:
+		return "CRC-32"
+	case ChecksumSHA256:
+		return "SHA-256"
+	}
+	return fmt.Sprintf("unknown (%d)", uint32(c))
+}
+
+// Return the hasher used to calculate the extended checksum or nil if only the CRC-32 is used.
+func (c ChecksumAlgo) newHasher() (hash.Hash, error) {
+	switch c {
+	case ChecksumCRC32:
+		return nil, nil
+	case ChecksumSHA256:
+		return sha256.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported checksum algorithm %d", uint32(c))
+}
+
+//-----------------------------------------------------------------------------
+// DatabaseFile
+
+// The algorithm used to calculate the database file integrity checksum.
+func (dbf *DatabaseFile) ChecksumAlgo() ChecksumAlgo {
+	return dbf.header.ChecksumAlgo
+}
+
+// Write the extended checksum at the current offset.
+func (dbf *DatabaseFile) writeExtendedChecksum() error {
+	var err error
+	dbf.header.ChecksumOffset, err = safe.Uint64ToUint32(dbf.file.Offset())
+	if err != nil {
+		return fmt.Errorf("failed to set the ajfs checksum offset. %w", err)
+	}
+
+	if _, err = dbf.file.Write(checksumSentinel[:]); err != nil {
+		return fmt.Errorf("failed to write the extended checksum (sentinel). %w", err)
+	}
+
+	if _, err = dbf.file.Write(dbf.extChecksumHasher.Sum(nil)); err != nil {
+		return fmt.Errorf("failed to write the extended checksum. %w", err)
+	}
+
+	if err := dbf.file.Flush(); err != nil {
+		return fmt.Errorf("failed to write the extended checksum (flush). %w", err)
+	}
+
+	return nil
+}
+
+// Read the extended checksum stored in the database.
+func (dbf *DatabaseFile) readExtendedChecksum(size int) ([]byte, error) {
+	_, err := dbf.file.Seek(int64(dbf.header.ChecksumOffset), io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the extended checksum. %w", err)
+	}
+	dbf.file.ResetReadBuffer()
+
+	return readExtendedChecksumFrom(dbf.file, size)
+}
+
+// Read the sentinel and extended checksum from the reader.
+func readExtendedChecksumFrom(r io.Reader, size int) ([]byte, error) {
+	var s [4]byte
+	if _, err := io.ReadFull(r, s[:]); err != nil {
+		return nil, fmt.Errorf("failed to read the extended checksum (sentinel). %w", err)
+	}
+	if s != checksumSentinel {
+		return nil, fmt.Errorf("failed to read the extended checksum (sentinel %q does not match %q)", s, checksumSentinel)
+	}
+
+	result := make([]byte, size)
+	if _, err := io.ReadFull(r, result); err != nil {
+		return nil, fmt.Errorf("failed to read the extended checksum. %w", err)
+	}
+
+	return result, nil
+}
+
+var (
+	checksumSentinel = [4]byte{0x41, 0x4A, 0x43, 0x4B} // AJCK
+)
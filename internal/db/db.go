@@ -22,6 +22,9 @@
 package db
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -29,9 +32,12 @@ import (
 	"hash/crc32"
 	"io"
 	"io/fs"
+	"math"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/andrejacobs/ajfs/internal/path"
@@ -51,8 +57,12 @@ import (
 // root [c]
 // meta [c]
 // entries [c]
-// entry lookup table [c]
+// entry lookup table [c] (the identifier and file offset of each entry, in the order the entries were written)
+//   The revision of the lookup table is identified by the marker it starts with (see LookupTableRevision)
+// [optional] extended checksum
+// [optional] directory statistics
 // [optional] hash table
+// [optional] hash times
 // [optional] future features (without breaking existing databases)
 
 // DatabaseFile is the underlying data storage used by ajfs as a single file.
@@ -64,6 +74,9 @@ import (
 // - Finish
 // - Close
 // .
+//
+// NOTE: Only ReadEntryAtIndex, ReadEntryById and FindEntryIndexAndOffset are safe to be called concurrently.
+// All the other methods share a single file offset and must not be called concurrently.
 type DatabaseFile struct {
 	file *trackedoffset.File
 	path string
@@ -73,19 +86,28 @@ type DatabaseFile struct {
 	root         rootEntry
 	meta         MetaEntry
 
-	entryLookups  []entryLookup
-	entryIdLookup map[path.Id]EntryIndexAndOffset
+	entryLookups []entryLookup
+	entryIdIndex []entryIdIndex // entryLookups ordered by Id, used for binary searching
+
+	readAhead *bufio.Reader // cached buffer used by startSequentialRead
 
 	// only for creation
 	creating       bool
 	createFeatures FeatureFlags
 	fileIndices    []uint32 // indices of path info entries that are files
 
-	checksumHasher hash.Hash32
-	checksumWriter io.Writer
+	checksumHasher    hash.Hash32
+	extChecksumHasher hash.Hash // nil when only the CRC-32 checksum is used
+	checksumWriter    io.Writer
 
 	createHashTable createHashTable
+	createDirStats  *createDirStats
+	entryCoder      pathEntryCoder // encodes the path entries being written
+	journal         *hashJournal   // created by the first WriteHashEntry
+	hashBatch       hashBatch      // hash table entries that are still to be written
+	syncPolicy      SyncPolicy
 	resuming        bool
+	sortOnClose     bool
 }
 
 // Create a new file
@@ -94,11 +116,57 @@ type DatabaseFile struct {
 // root is the file path that the database will represents and that will be used to scan the file hierarchy.
 // features indicate the expected features that will be present in the database.
 func CreateDatabase(path string, root string, features FeatureFlags) (*DatabaseFile, error) {
+	return CreateDatabaseWithChecksum(path, root, features, ChecksumCRC32)
+}
+
+// Create a new file that will use the specified algorithm to calculate the file integrity checksum.
+// See [CreateDatabase] for the other parameters.
+func CreateDatabaseWithChecksum(path string, root string, features FeatureFlags, checksumAlgo ChecksumAlgo) (*DatabaseFile, error) {
+	return createDatabase(path, root, features, checksumAlgo, nil, currentVersion)
+}
+
+// Create a new file using an older file format version, e.g. to be read by older versions of ajfs.
+// version must be between 1 and [CurrentVersion].
+// See [CreateDatabase] for the other parameters.
+func CreateDatabaseWithVersion(path string, root string, features FeatureFlags, checksumAlgo ChecksumAlgo, version int) (*DatabaseFile, error) {
+	return CreateDatabaseWithIdNamespace(path, root, features, checksumAlgo, version, "")
+}
+
+// Create a new file of which the identifiers of the path entries are derived from the path and the namespace
+// (see [NewIdNamespace]) instead of only the path. An empty namespace is the same as [CreateDatabaseWithVersion].
+// See [CreateDatabaseWithVersion] for the other parameters.
+func CreateDatabaseWithIdNamespace(path string, root string, features FeatureFlags, checksumAlgo ChecksumAlgo, version int,
+	namespace string) (*DatabaseFile, error) {
+	if version < 1 || version > int(currentVersion) {
+		return nil, fmt.Errorf("unsupported file format version %d (supported versions are 1 to %d)", version, currentVersion)
+	}
+
+	var meta MetaEntry
+	meta.init()
+	meta.IdNamespace = namespace
+	return createDatabase(path, root, features, checksumAlgo, &meta, uint16(version))
+}
+
+// Create a new file.
+// meta is the meta entry to be written, if nil then a new meta entry will be created.
+// version is the file format version to be written.
+func createDatabase(path string, root string, features FeatureFlags, checksumAlgo ChecksumAlgo, meta *MetaEntry, version uint16) (*DatabaseFile, error) {
+	extChecksumHasher, err := checksumAlgo.newHasher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the ajfs database file. path: %q. %w", path, err)
+	}
+
 	absRoot, err := filepath.Abs(root)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get the absolute root path from %q. %w", root, err)
 	}
 
+	// The feature is determined by the meta entry since the namespace is stored in it
+	features &^= FeatureIdNamespace
+	if (meta != nil) && (meta.IdNamespace != "") {
+		features |= FeatureIdNamespace
+	}
+
 	dbf := &DatabaseFile{
 		path:           path,
 		creating:       true,
@@ -109,17 +177,28 @@ func CreateDatabase(path string, root string, features FeatureFlags) (*DatabaseF
 	if err != nil {
 		return nil, fmt.Errorf("failed to create the ajfs database file. path: %q. %w", path, err)
 	}
+	resizeIOBuffers(dbf.file)
 
+	dbf.header.ChecksumAlgo = checksumAlgo
 	dbf.checksumHasher = crc32.NewIEEE()
-	dbf.checksumWriter = io.MultiWriter(dbf.file, dbf.checksumHasher)
+	if extChecksumHasher != nil {
+		dbf.extChecksumHasher = extChecksumHasher
+		dbf.checksumWriter = io.MultiWriter(dbf.file, dbf.checksumHasher, dbf.extChecksumHasher)
+	} else {
+		dbf.checksumWriter = io.MultiWriter(dbf.file, dbf.checksumHasher)
+	}
 
 	// Write prefix
 	dbf.prefixHeader.init()
+	dbf.prefixHeader.Version = version
+	dbf.entryCoder = newPathEntryCoder(version)
 	if err := dbf.prefixHeader.write(dbf.file); err != nil {
 		return nil, fmt.Errorf("failed to write the ajfs prefix header. path: %q. %w", path, err)
 	}
 
 	// Write initial empty header (this should be updated before finishing the file)
+	// The dirty flag will only be cleared once the file has been closed cleanly
+	dbf.header.Status |= statusDirty
 	if err := dbf.header.write(dbf.file); err != nil {
 		return nil, fmt.Errorf("failed to write the ajfs header. path: %q. %w", path, err)
 	}
@@ -131,10 +210,17 @@ func CreateDatabase(path string, root string, features FeatureFlags) (*DatabaseF
 	}
 
 	// Meta entry
-	dbf.meta.init()
+	if meta != nil {
+		dbf.meta = *meta
+	} else {
+		dbf.meta.init()
+	}
 	if err := dbf.meta.write(dbf.checksumWriter); err != nil {
 		return nil, fmt.Errorf("failed to write the ajfs meta entry. path: %q. %w", path, err)
 	}
+	if dbf.meta.IdNamespace != "" {
+		dbf.header.Features |= FeatureIdNamespace
+	}
 
 	if err := dbf.file.Flush(); err != nil {
 		return nil, fmt.Errorf("failed to create the ajfs database. path: %q. %w", path, err)
@@ -152,10 +238,16 @@ func CreateDatabase(path string, root string, features FeatureFlags) (*DatabaseF
 		dbf.fileIndices = make([]uint32, 0, 4096)
 	}
 
+	if dbf.createFeatures.HasDirStats() {
+		dbf.createDirStats = newCreateDirStats()
+	}
+
 	return dbf, nil
 }
 
-// Open an existing database file (as read-only) and check the signature is valid and the version is supported.
+// Open an existing database file (as read-only) and check the signature is valid.
+// A database created by a newer version of ajfs, or that contains unsupported features, can still be opened as long as
+// the core sections are intact. In this case only the path entries can be processed, see [DatabaseFile.Limited].
 func OpenDatabase(path string) (*DatabaseFile, error) {
 	dbf := &DatabaseFile{
 		path: path,
@@ -166,6 +258,7 @@ func OpenDatabase(path string) (*DatabaseFile, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to open the ajfs database file. path: %q. %w", path, err)
 	}
+	resizeIOBuffers(dbf.file)
 
 	if err = dbf.readHeadersAndVerify(); err != nil {
 		return nil, err
@@ -186,6 +279,7 @@ func ResumeDatabase(path string) (*DatabaseFile, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to open the ajfs database file. path: %q. %w", path, err)
 	}
+	resizeIOBuffers(dbf.file)
 
 	if err = dbf.readHeadersAndVerify(); err != nil {
 		return nil, err
@@ -199,6 +293,14 @@ func ResumeDatabase(path string) (*DatabaseFile, error) {
 		}
 	}
 
+	// Mark the database as dirty until it has been closed cleanly
+	dbf.header.Status |= statusDirty
+	// Writing hashes in place would break the order of a sorted hash table
+	dbf.header.Status &^= statusSorted
+	if err = dbf.updateHeader(); err != nil {
+		return nil, fmt.Errorf("failed to mark the ajfs database as dirty. path: %q. %w", path, err)
+	}
+
 	return dbf, nil
 }
 
@@ -210,14 +312,21 @@ func (dbf *DatabaseFile) readHeadersAndVerify() error {
 	if dbf.prefixHeader.Signature != signature {
 		return fmt.Errorf("not a valid ajfs file (invalid signature %q, expected %q). path: %q", dbf.prefixHeader.Signature, signature, dbf.path)
 	}
-	if dbf.prefixHeader.Version > currentVersion {
+	if dbf.resuming && dbf.prefixHeader.Version > currentVersion {
 		return fmt.Errorf("not a supported ajfs file (invalid version %d, expected <= %d). path: %q", dbf.prefixHeader.Version, currentVersion, dbf.path)
 	}
 
 	// Read the header
+	// NOTE: Newer versions are expected to keep the version 1 header layout and only make use of the reserved fields
 	if err := dbf.header.read(dbf.file); err != nil {
 		return fmt.Errorf("failed to read the ajfs header. path: %q. %w", dbf.path, err)
 	}
+	if dbf.header.isDirty() {
+		return fmt.Errorf("%w. path: %q", ErrDirty, dbf.path)
+	}
+	if dbf.resuming && dbf.header.Features.Unsupported() != 0 {
+		return fmt.Errorf("not a supported ajfs file (unsupported features 0x%x). path: %q", dbf.header.Features.Unsupported(), dbf.path)
+	}
 
 	// Read the root info
 	if err := dbf.root.read(dbf.file); err != nil {
@@ -225,13 +334,13 @@ func (dbf *DatabaseFile) readHeadersAndVerify() error {
 	}
 
 	// Read the meta info
-	if err := dbf.meta.read(dbf.file); err != nil {
+	if err := dbf.meta.read(dbf.file, dbf.header.Features); err != nil {
 		return fmt.Errorf("failed to read the ajfs meta entry. path: %q. %w", dbf.path, err)
 	}
 
-	// Read the entry offset table
+	// Read the entry lookup table
 	if err := dbf.readEntryLookupTable(); err != nil {
-		return fmt.Errorf("failed to read the ajfs entry offset table. path: %q. %w", dbf.path, err)
+		return fmt.Errorf("failed to read the ajfs entry lookup table. path: %q. %w", dbf.path, err)
 	}
 
 	return nil
@@ -244,6 +353,9 @@ func (dbf *DatabaseFile) Close() error {
 	}
 
 	if dbf.creating {
+		if err := dbf.FlushHashEntries(); err != nil {
+			return err
+		}
 		if err := dbf.Flush(); err != nil {
 			return err
 		}
@@ -252,21 +364,41 @@ func (dbf *DatabaseFile) Close() error {
 			if err := dbf.finishCreation(); err != nil {
 				return err
 			}
+		} else {
+			dbf.header.Status &^= statusDirty
+			if err := dbf.updateHeader(); err != nil {
+				return err
+			}
 		}
 
 		if err := dbf.file.Sync(); err != nil {
 			return err
 		}
+
+		// The hash table entries have been written completely
+		if dbf.journal != nil {
+			if err := dbf.journal.remove(); err != nil {
+				return fmt.Errorf("failed to remove the hash table journal. %w", err)
+			}
+			dbf.journal = nil
+		}
 	}
 
 	if err := dbf.file.Close(); err != nil {
 		return err
 	}
 
+	sortHashes := dbf.creating && dbf.sortOnClose && dbf.header.Features.HasHashTable()
+
 	dbf.file = nil
 	dbf.entryLookups = nil
+	dbf.entryIdIndex = nil
 	dbf.fileIndices = nil
+	dbf.sortOnClose = false
 
+	if sortHashes {
+		return SortHashTable(context.Background(), dbf.path)
+	}
 	return nil
 }
 
@@ -285,8 +417,16 @@ func (dbf *DatabaseFile) Interrupted() error {
 		return err
 	}
 
+	if dbf.journal != nil {
+		if err := dbf.journal.remove(); err != nil {
+			return err
+		}
+		dbf.journal = nil
+	}
+
 	dbf.file = nil
 	dbf.entryLookups = nil
+	dbf.entryIdIndex = nil
 	dbf.fileIndices = nil
 	return nil
 }
@@ -297,6 +437,11 @@ func (dbf *DatabaseFile) Flush() error {
 	return dbf.file.Flush()
 }
 
+// The file format version used when creating new databases.
+func CurrentVersion() int {
+	return int(currentVersion)
+}
+
 // File format version.
 func (dbf *DatabaseFile) Version() int {
 	return int(dbf.prefixHeader.Version)
@@ -312,6 +457,30 @@ func (dbf *DatabaseFile) Features() FeatureFlags {
 	return dbf.header.Features
 }
 
+// Return true if the database was created by a newer version of ajfs or contains unsupported features.
+// Only the path entries can be processed by this version of ajfs.
+func (dbf *DatabaseFile) Limited() bool {
+	return dbf.newerVersion() || (dbf.header.Features.Unsupported() != 0)
+}
+
+// Describe why the database can only be processed in a limited way.
+func (dbf *DatabaseFile) Warnings() []string {
+	result := make([]string, 0, 2)
+	if dbf.newerVersion() {
+		result = append(result, fmt.Sprintf("the database %q was created with a newer file format version %d (supported <= %d). Only the path entries can be processed",
+			dbf.path, dbf.prefixHeader.Version, currentVersion))
+	}
+	if unsupported := dbf.header.Features.Unsupported(); unsupported != 0 {
+		result = append(result, fmt.Sprintf("the database %q contains unsupported features 0x%x that will be ignored", dbf.path, unsupported))
+	}
+	return result
+}
+
+// Returns true if the database has been sealed (marked as read-only), see [SetSealed].
+func (dbf *DatabaseFile) Sealed() bool {
+	return dbf.header.isSealed()
+}
+
 // The file path that the database represents and that was used to scan the file hierarchy.
 func (dbf *DatabaseFile) RootPath() string {
 	return dbf.root.path
@@ -333,9 +502,16 @@ func (dbf *DatabaseFile) FileEntriesCount() int {
 }
 
 // Write the path info to the database.
+// The path is normalized (see [path.Normalize]) and an error is returned if it is absolute or escapes the root.
 func (dbf *DatabaseFile) WriteEntry(pi *path.Info) error {
 	dbf.panicIfNotWriting()
 
+	normalized, err := normalizeEntry(pi, dbf.meta.IdNamespace)
+	if err != nil {
+		return err
+	}
+	pi = &normalized
+
 	offset, err := safe.Uint64ToUint32(dbf.file.Offset())
 	if err != nil {
 		return err
@@ -348,7 +524,7 @@ func (dbf *DatabaseFile) WriteEntry(pi *path.Info) error {
 	index := dbf.header.EntriesCount
 
 	entry := pathEntryFromPathInfo(pi)
-	if err := entry.write(dbf.checksumWriter); err != nil {
+	if err := dbf.entryCoder.write(dbf.checksumWriter, &entry); err != nil {
 		return err
 	}
 
@@ -368,25 +544,45 @@ func (dbf *DatabaseFile) WriteEntry(pi *path.Info) error {
 		}
 	}
 
+	if dbf.createDirStats != nil {
+		if err := dbf.createDirStats.add(index, pi); err != nil {
+			return fmt.Errorf("failed to update the directory statistics. path: %q. %w", pi.Path, err)
+		}
+	}
+
 	return nil
 }
 
+// Return a copy of the path info with the path normalized.
+// The identifier is derived from the normalized path and the namespace if it was derived from the original path
+// without a namespace (see [path.IdFromPath]), which is how the scanner creates the entries.
+func normalizeEntry(pi *path.Info, namespace string) (path.Info, error) {
+	result := *pi
+
+	normalized, err := path.Normalize(pi.Path)
+	if err != nil {
+		return result, fmt.Errorf("failed to write the entry. %w", err)
+	}
+
+	if (normalized != pi.Path) || (namespace != "") {
+		if pi.Id == path.IdFromPath(pi.Path) {
+			result.Id = path.IdFromPathInNamespace(namespace, normalized)
+		}
+		result.Path = normalized
+	}
+	return result, nil
+}
+
 // Read the path info object with the specified index.
+// This is safe to be called concurrently from multiple goroutines, as long as the database is not being written to.
 func (dbf *DatabaseFile) ReadEntryAtIndex(idx int) (path.Info, error) {
 	if idx >= int(dbf.header.EntriesCount) {
 		panic(fmt.Sprintf("invalid index %d, EntriesCount = %d", idx, dbf.header.EntriesCount))
 	}
 
-	offset := dbf.entryLookups[idx].Offset
-	_, err := dbf.file.Seek(int64(offset), io.SeekStart)
+	entry, err := dbf.readEntryAt(idx)
 	if err != nil {
-		return path.Info{}, fmt.Errorf("failed to read entry at index %d (offset %d). %w", idx, offset, err)
-	}
-	dbf.file.ResetReadBuffer()
-
-	entry := pathEntry{}
-	if err := entry.read(dbf.file); err != nil {
-		return path.Info{}, fmt.Errorf("failed to read entry at index %d (offset %d). %w", idx, offset, err)
+		return path.Info{}, fmt.Errorf("failed to read entry at index %d (offset %d). %w", idx, dbf.entryLookups[idx].Offset, err)
 	}
 
 	return pathInfoFromPathEntry(&entry), nil
@@ -395,36 +591,82 @@ func (dbf *DatabaseFile) ReadEntryAtIndex(idx int) (path.Info, error) {
 // ErrNotFound is returned when a path entry could not be found in the database.
 var ErrNotFound = errors.New("path entry not found")
 
+// ErrDirty is returned when a database was not closed cleanly and needs to be repaired.
+var ErrDirty = errors.New("the ajfs database was not closed cleanly, use \"ajfs fix\" to repair it")
+
 // Read the path info object with the specified identifier.
+// The entry is found using a binary search of the identifiers, O(log n).
 // Returns [ErrNotFound] if the entry does not exist.
-func (dbf *DatabaseFile) ReadEntryWithId(id path.Id) (path.Info, error) {
-	v, exist := dbf.entryIdLookup[id]
-	if !exist {
-		return path.Info{}, ErrNotFound
+// This is safe to be called concurrently from multiple goroutines, as long as the database is not being written to.
+func (dbf *DatabaseFile) ReadEntryById(id path.Id) (path.Info, error) {
+	v, err := dbf.FindEntryIndexAndOffset(id)
+	if err != nil {
+		return path.Info{}, err
 	}
 
-	_, err := dbf.file.Seek(int64(v.Offset), io.SeekStart)
+	entry, err := dbf.readEntryAt(int(v.Index))
 	if err != nil {
 		return path.Info{}, fmt.Errorf("failed to read entry at offset %d (index = %d). %w", v.Offset, v.Index, err)
 	}
-	dbf.file.ResetReadBuffer()
 
+	return pathInfoFromPathEntry(&entry), nil
+}
+
+// Read the path entry at the specified index without using or changing the shared file offset.
+// A prefix compressed path can only be decoded by reading from the preceding restart entry.
+// This is safe to be called concurrently from multiple goroutines.
+func (dbf *DatabaseFile) readEntryAt(idx int) (pathEntry, error) {
+	r, _ := entryReaderPool.Get().(*bufio.Reader)
+	defer func() {
+		r.Reset(nil)
+		entryReaderPool.Put(r)
+	}()
+
+	first := dbf.restartIndex(idx)
+	offset := dbf.entryLookups[first].Offset
+	r.Reset(io.NewSectionReader(dbf.file.File(), int64(offset), math.MaxInt64-int64(offset)))
+
+	coder := newPathEntryCoder(dbf.prefixHeader.Version)
 	entry := pathEntry{}
-	if err := entry.read(dbf.file); err != nil {
-		return path.Info{}, fmt.Errorf("failed to read entry at offset %d (index = %d). %w", v.Offset, v.Index, err)
+	for range idx - first + 1 {
+		if err := coder.read(r, &entry); err != nil {
+			return pathEntry{}, err
+		}
 	}
 
-	return pathInfoFromPathEntry(&entry), nil
+	return entry, nil
+}
+
+// Return the index of the closest entry, at or before idx, from which the entries can be decoded.
+func (dbf *DatabaseFile) restartIndex(idx int) int {
+	if !dbf.prefixHeader.prefixCompressed() {
+		return idx
+	}
+	return idx - (idx % pathRestartInterval)
+}
+
+// Pool of readers used to read path entries concurrently.
+var entryReaderPool = sync.Pool{
+	New: func() any {
+		return bufio.NewReaderSize(nil, 512)
+	},
 }
 
 // Lookup the index and offset for a path entry with the specified identifier.
 // Returns [ErrNotFound] if the entry does not exist.
 func (dbf *DatabaseFile) FindEntryIndexAndOffset(id path.Id) (EntryIndexAndOffset, error) {
-	v, exist := dbf.entryIdLookup[id]
-	if !exist {
+	i, found := slices.BinarySearchFunc(dbf.entryIdIndex, id, func(e entryIdIndex, id path.Id) int {
+		return bytes.Compare(e.Id[:], id[:])
+	})
+	if !found {
 		return EntryIndexAndOffset{}, ErrNotFound
 	}
-	return v, nil
+
+	index := dbf.entryIdIndex[i].Index
+	return EntryIndexAndOffset{
+		Index:  index,
+		Offset: dbf.entryLookups[index].Offset,
+	}, nil
 }
 
 // ReadAllEntriesFn will be called by ReadAllEntries for each entry that was read from the database.
@@ -435,16 +677,27 @@ type ReadAllEntriesFn func(idx int, pi path.Info) error
 
 // Read all the path info objects from the database and call the callback function.
 // If the callback function returns [SkipAll] then the reading process will be stopped and nil will be returned as the error.
-func (dbf *DatabaseFile) ReadAllEntries(fn ReadAllEntriesFn) error {
+// Reading stops with the context's error once the context is cancelled.
+func (dbf *DatabaseFile) ReadAllEntries(ctx context.Context, fn ReadAllEntriesFn) error {
 	_, err := dbf.file.Seek(int64(dbf.header.EntriesOffset), io.SeekStart)
 	if err != nil {
 		return fmt.Errorf("failed to read all entries. %w", err)
 	}
-	dbf.file.ResetReadBuffer()
 
+	restore, err := dbf.startSequentialRead()
+	if err != nil {
+		return fmt.Errorf("failed to read all entries. %w", err)
+	}
+	defer restore()
+
+	coder := newPathEntryCoder(dbf.prefixHeader.Version)
 	for idx := range dbf.header.EntriesCount {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		entry := pathEntry{}
-		if err := entry.read(dbf.file); err != nil {
+		if err := coder.read(dbf.file, &entry); err != nil {
 			offset := dbf.file.Offset()
 			return fmt.Errorf("failed to read entry at index %d (offset %d). %w", idx, offset, err)
 		}
@@ -460,10 +713,56 @@ func (dbf *DatabaseFile) ReadAllEntries(fn ReadAllEntriesFn) error {
 	return nil
 }
 
-// Write the entries offset table after all path info objects have been written.
+// Read the path info objects with indices from start up to (but excluding) end and call the callback function.
+// The entries lookup table is used to seek directly to the first entry and thus the preceding entries are not read.
+// If the callback function returns [SkipAll] then the reading process will be stopped and nil will be returned as the error.
+// Reading stops with the context's error once the context is cancelled.
+func (dbf *DatabaseFile) ReadEntriesRange(ctx context.Context, start int, end int, fn ReadAllEntriesFn) error {
+	if start < 0 || end > int(dbf.header.EntriesCount) || start > end {
+		panic(fmt.Sprintf("invalid range [%d, %d), EntriesCount = %d", start, end, dbf.header.EntriesCount))
+	}
+	if start == end {
+		return nil
+	}
+
+	first := dbf.restartIndex(start)
+	_, err := dbf.file.Seek(int64(dbf.entryLookups[first].Offset), io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("failed to read entries from index %d. %w", start, err)
+	}
+	dbf.file.ResetReadBuffer()
+
+	coder := newPathEntryCoder(dbf.prefixHeader.Version)
+	for idx := first; idx < end; idx++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		entry := pathEntry{}
+		if err := coder.read(dbf.file, &entry); err != nil {
+			offset := dbf.file.Offset()
+			return fmt.Errorf("failed to read entry at index %d (offset %d). %w", idx, offset, err)
+		}
+		if idx < start {
+			continue
+		}
+
+		if err := fn(idx, pathInfoFromPathEntry(&entry)); err != nil {
+			if err == SkipAll {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Write the entries lookup table after all path info objects have been written.
+// The directory statistics are also written at this point if the feature was requested.
 func (dbf *DatabaseFile) FinishEntries() error {
 	if dbf.header.EntriesCount == 0 {
-		return nil
+		return dbf.writeDirStats()
 	}
 
 	if err := dbf.Flush(); err != nil {
@@ -477,7 +776,7 @@ func (dbf *DatabaseFile) FinishEntries() error {
 	}
 
 	if err := dbf.writeEntryLookupTable(); err != nil {
-		return fmt.Errorf("failed to finish writing the entries (offset table). %w", err)
+		return fmt.Errorf("failed to finish writing the entries (lookup table). %w", err)
 	}
 
 	dbf.header.FeaturesOffset, err = safe.Uint64ToUint32(dbf.file.Offset())
@@ -485,6 +784,17 @@ func (dbf *DatabaseFile) FinishEntries() error {
 		return fmt.Errorf("failed to finish writing the entries (features offset). %w", err)
 	}
 
+	// The extended checksum only covers the sections up to the features and is thus written first
+	if dbf.extChecksumHasher != nil {
+		if err := dbf.writeExtendedChecksum(); err != nil {
+			return err
+		}
+	}
+
+	if err := dbf.writeDirStats(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -501,8 +811,18 @@ func (dbf *DatabaseFile) VerifyChecksums() error {
 
 	count := int64(dbf.header.FeaturesOffset) - offset
 
+	extHasher, err := dbf.header.ChecksumAlgo.newHasher()
+	if err != nil {
+		return fmt.Errorf("failed to verify checksum. %w", err)
+	}
+
 	hasher := crc32.NewIEEE()
-	_, err = io.CopyN(hasher, dbf.file, count)
+	var w io.Writer = hasher
+	if extHasher != nil {
+		w = io.MultiWriter(hasher, extHasher)
+	}
+
+	_, err = io.CopyN(w, dbf.file, count)
 	if err != nil {
 		return fmt.Errorf("failed to verify checksum. %w", err)
 	}
@@ -511,6 +831,17 @@ func (dbf *DatabaseFile) VerifyChecksums() error {
 		return ErrInvalidChecksum
 	}
 
+	if extHasher != nil {
+		expected, err := dbf.readExtendedChecksum(extHasher.Size())
+		if err != nil {
+			return fmt.Errorf("failed to verify checksum. %w", err)
+		}
+
+		if !bytes.Equal(expected, extHasher.Sum(nil)) {
+			return ErrInvalidChecksum
+		}
+	}
+
 	return nil
 }
 
@@ -521,10 +852,10 @@ func (dbf *DatabaseFile) VerifyChecksums() error {
 type IdToInfoMap map[path.Id]path.Info
 
 // Build a map from a path's identifier to the path info entry.
-func (dbf *DatabaseFile) BuildIdToInfoMap() (IdToInfoMap, error) {
+func (dbf *DatabaseFile) BuildIdToInfoMap(ctx context.Context) (IdToInfoMap, error) {
 	result := make(IdToInfoMap, dbf.EntriesCount())
 
-	err := dbf.ReadAllEntries(func(idx int, pi path.Info) error {
+	err := dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
 		result[pi.Id] = pi
 		return nil
 	})
@@ -553,12 +884,30 @@ func (dbf *DatabaseFile) finishCreation() error {
 		panic("hash table was not written")
 	}
 
+	if dbf.header.Features.HasDirStats() && (dbf.header.DirStatsOffset == 0) {
+		panic("directory statistics were not written")
+	}
+
 	dbf.header.Checksum = dbf.checksumHasher.Sum32()
+	dbf.header.Status &^= statusDirty
+
+	// The extended checksum is only written by FinishEntries and thus not present when there are no entries
+	if dbf.header.ChecksumOffset == 0 {
+		dbf.header.ChecksumAlgo = ChecksumCRC32
+	}
+
+	if err := dbf.updateHeader(); err != nil {
+		return fmt.Errorf("failed to finish creating the ajfs database. %w", err)
+	}
 
-	// Update the header
+	return nil
+}
+
+// Write the header in place and ensure it has been synced to disk.
+func (dbf *DatabaseFile) updateHeader() error {
 	_, err := dbf.file.Seek(headerOffset(), io.SeekStart)
 	if err != nil {
-		return fmt.Errorf("failed to finish creating the ajfs database. failed to seek to header offset. %w", err)
+		return fmt.Errorf("failed to seek to header offset. %w", err)
 	}
 	dbf.file.ResetWriteBuffer()
 
@@ -570,10 +919,10 @@ func (dbf *DatabaseFile) finishCreation() error {
 		return err
 	}
 
-	return nil
+	return dbf.file.Sync()
 }
 
-// Read the entry offset table.
+// Read the entry lookup table.
 func (dbf *DatabaseFile) readEntryLookupTable() error {
 	if dbf.header.EntriesCount == 0 {
 		return nil
@@ -591,12 +940,11 @@ func (dbf *DatabaseFile) readEntryLookupTable() error {
 	if err != nil {
 		return fmt.Errorf("failed to read the entry lookup table (1st sentinel). %w", err)
 	}
-	if s != sentinel {
-		return fmt.Errorf("failed to read the entry lookup table (1st sentinel %q does not match %q)", s, sentinel)
+	if _, err := detectLookupTableRevision(s); err != nil {
+		return fmt.Errorf("failed to read the entry lookup table. %w", err)
 	}
 
 	dbf.entryLookups = make([]entryLookup, dbf.header.EntriesCount)
-	dbf.entryIdLookup = make(map[path.Id]EntryIndexAndOffset, dbf.header.EntriesCount)
 
 	for i := range dbf.header.EntriesCount {
 		entry := &dbf.entryLookups[i]
@@ -605,11 +953,6 @@ func (dbf *DatabaseFile) readEntryLookupTable() error {
 		if err != nil {
 			return fmt.Errorf("failed to read the entry lookup table (near index %d). %w", i, err)
 		}
-
-		dbf.entryIdLookup[entry.Id] = EntryIndexAndOffset{
-			Index:  i,
-			Offset: entry.Offset,
-		}
 	}
 
 	// Check 2nd sentinel
@@ -621,6 +964,7 @@ func (dbf *DatabaseFile) readEntryLookupTable() error {
 		return fmt.Errorf("failed to read the entry lookup table (2nd sentinel %q does not match %q)", s, sentinel)
 	}
 
+	dbf.buildEntryIdIndex()
 	return nil
 }
 
@@ -656,6 +1000,11 @@ func (dbf *DatabaseFile) writeEntryLookupTable() error {
 	return nil
 }
 
+// Return true if the database was created with a newer file format version.
+func (dbf *DatabaseFile) newerVersion() bool {
+	return dbf.prefixHeader.Version > currentVersion
+}
+
 // Panic if the database was not opened for creation (as in file writing).
 func (dbf *DatabaseFile) panicIfNotWriting() {
 	if !dbf.creating {
@@ -677,6 +1026,11 @@ func (s *prefixHeader) init() {
 	s.Version = currentVersion
 }
 
+// Return true if the path strings of the path entries are prefix compressed (version 2 and later).
+func (s *prefixHeader) prefixCompressed() bool {
+	return s.Version >= 2
+}
+
 func (s *prefixHeader) read(r io.Reader) error {
 	return binary.Read(r, binary.LittleEndian, s)
 }
@@ -700,7 +1054,35 @@ type header struct {
 
 	HashTableOffset uint32 // The start of the hash table
 
-	FeatureReserved [8]uint32 // 8x feature offsets reserved for future use without breaking backwards compatibility
+	ChecksumAlgo   ChecksumAlgo // Algorithm used for the extended checksum (taken from the reserved feature offsets)
+	ChecksumOffset uint32       // The start of the extended checksum. 0 if only the CRC-32 checksum is used
+
+	Status uint32 // Status flags (taken from the reserved feature offsets). See statusDirty, statusSealed, statusSorted, statusFullPaths and statusArchives
+
+	DirStatsOffset uint32 // The start of the directory statistics (taken from the reserved feature offsets)
+
+	SignatureOffset uint32 // The start of the signature (taken from the reserved feature offsets)
+
+	HashTimesOffset uint32 // The start of the times at which the hashes were calculated (taken from the reserved feature offsets)
+
+	ExtraHashTablesOffset uint32 // The start of the additional hash tables (taken from the reserved feature offsets)
+
+	AnnotationsOffset uint32 // The start of the annotations (taken from the reserved feature offsets)
+}
+
+// Return true if the database was not closed cleanly.
+func (s *header) isDirty() bool {
+	return (s.Status & statusDirty) != 0
+}
+
+// Return true if the database has been marked as read-only.
+func (s *header) isSealed() bool {
+	return (s.Status & statusSealed) != 0
+}
+
+// Return true if the hash table entries are ordered by hash instead of by path entry index.
+func (s *header) isSorted() bool {
+	return (s.Status & statusSorted) != 0
 }
 
 func (s *header) read(r io.Reader) error {
@@ -755,6 +1137,10 @@ type MetaEntry struct {
 	Arch      string    `json:"arch"`      // The architecture (e.g. arm64 etc.)
 	CreatedAt time.Time `json:"createdAt"` // Time of database creation (this is captured instead of relying on the file system time)
 
+	// Namespace used to derive the identifiers (see [NewIdNamespace]). Only written when the namespace is not empty,
+	// in which case the database has the [FeatureIdNamespace] feature.
+	IdNamespace string `json:"idNamespace,omitempty"`
+
 	// NOTE: You can see the list of GOOS values at: https://github.com/golang/go/blob/master/src/go/build/syslist.go
 }
 
@@ -765,7 +1151,7 @@ func (s *MetaEntry) init() {
 	s.CreatedAt = time.Now()
 }
 
-func (s *MetaEntry) read(r vardata.Reader) error {
+func (s *MetaEntry) read(r vardata.Reader, features FeatureFlags) error {
 	tool, _, err := varData.ReadString(r)
 	if err != nil {
 		return fmt.Errorf("failed to read the tool info. %w", err)
@@ -792,6 +1178,14 @@ func (s *MetaEntry) read(r vardata.Reader) error {
 		return fmt.Errorf("failed to read creation time (decoding failed). %w", err)
 	}
 
+	if features.HasIdNamespace() {
+		namespace, _, err := varData.ReadString(r)
+		if err != nil {
+			return fmt.Errorf("failed to read the identifier namespace. %w", err)
+		}
+		s.IdNamespace = namespace
+	}
+
 	return nil
 }
 
@@ -819,6 +1213,12 @@ func (s *MetaEntry) write(w io.Writer) error {
 		return fmt.Errorf("failed to write creation time. %w", err)
 	}
 
+	if s.IdNamespace != "" {
+		if _, err := varData.WriteString(w, s.IdNamespace); err != nil {
+			return fmt.Errorf("failed to write the identifier namespace. %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -841,7 +1241,48 @@ type pathEntryHeader struct {
 	Mode fs.FileMode
 }
 
+// Read a version 1 path entry.
 func (s *pathEntry) read(r vardata.Reader) error {
+	if err := s.readFixed(r); err != nil {
+		return err
+	}
+
+	// Path
+	data, _, err := varData.Read(r, nil)
+	if err != nil {
+		return fmt.Errorf("failed to read path entry's path string. %w", err)
+	}
+
+	s.path = string(data)
+	return nil
+}
+
+// Read a version 2 path entry of which the path shares a prefix with prevPath.
+func (s *pathEntry) readCompressed(r vardata.Reader, prevPath string) error {
+	if err := s.readFixed(r); err != nil {
+		return err
+	}
+
+	// Path
+	shared, err := binary.ReadUvarint(r)
+	if err != nil {
+		return fmt.Errorf("failed to read path entry's shared prefix length. %w", err)
+	}
+	if shared > uint64(len(prevPath)) {
+		return fmt.Errorf("failed to read path entry's path string (shared prefix length %d exceeds the previous path length %d)", shared, len(prevPath))
+	}
+
+	data, _, err := varData.Read(r, nil)
+	if err != nil {
+		return fmt.Errorf("failed to read path entry's path string. %w", err)
+	}
+
+	s.path = prevPath[:shared] + string(data)
+	return nil
+}
+
+// Read the fields that are the same in all versions.
+func (s *pathEntry) readFixed(r vardata.Reader) error {
 	if err := binary.Read(r, binary.LittleEndian, &s.header); err != nil {
 		return fmt.Errorf("failed to read path entry header. %w", err)
 	}
@@ -855,17 +1296,45 @@ func (s *pathEntry) read(r vardata.Reader) error {
 		return fmt.Errorf("failed to read path entry modification time (decoding failed). %w", err)
 	}
 
+	return nil
+}
+
+// Write a version 1 path entry.
+func (s *pathEntry) write(w io.Writer) error {
+	if err := s.writeFixed(w); err != nil {
+		return err
+	}
+
 	// Path
-	data, _, err = varData.Read(r, nil)
-	if err != nil {
-		return fmt.Errorf("failed to read path entry's path string. %w", err)
+	if _, err := varData.WriteString(w, s.path); err != nil {
+		return fmt.Errorf("failed to write path entry's path string. path: %q. %w", s.path, err)
 	}
 
-	s.path = string(data)
 	return nil
 }
 
-func (s *pathEntry) write(w io.Writer) error {
+// Write a version 2 path entry with only the part of the path that follows the prefix shared with prevPath.
+func (s *pathEntry) writeCompressed(w io.Writer, prevPath string) error {
+	if err := s.writeFixed(w); err != nil {
+		return err
+	}
+
+	// Path
+	shared := sharedPrefixLen(prevPath, s.path)
+	buffer := binary.AppendUvarint(make([]byte, 0, binary.MaxVarintLen64), uint64(shared))
+	if _, err := w.Write(buffer); err != nil {
+		return fmt.Errorf("failed to write path entry's shared prefix length. path: %q. %w", s.path, err)
+	}
+
+	if _, err := varData.WriteString(w, s.path[shared:]); err != nil {
+		return fmt.Errorf("failed to write path entry's path string. path: %q. %w", s.path, err)
+	}
+
+	return nil
+}
+
+// Write the fields that are the same in all versions.
+func (s *pathEntry) writeFixed(w io.Writer) error {
 	if err := binary.Write(w, binary.LittleEndian, s.header); err != nil {
 		return fmt.Errorf("failed to write path entry header. path: %q. %w", s.path, err)
 	}
@@ -879,11 +1348,66 @@ func (s *pathEntry) write(w io.Writer) error {
 		return fmt.Errorf("failed to write path entry modification time. path: %q. %w", s.path, err)
 	}
 
-	// Path
-	if _, err := varData.WriteString(w, s.path); err != nil {
-		return fmt.Errorf("failed to write path entry's path string. path: %q. %w", s.path, err)
+	return nil
+}
+
+// Return the length in bytes of the prefix shared by a and b.
+func sharedPrefixLen(a string, b string) int {
+	n := min(len(a), len(b))
+	for i := range n {
+		if a[i] != b[i] {
+			return i
+		}
 	}
+	return n
+}
+
+// Number of consecutive path entries that share prefixes before a path is stored in full again.
+// This limits how many entries have to be decoded to read an entry at a random index.
+const pathRestartInterval = 16
+
+// Reads or writes consecutive path entries in the layout of a file format version.
+// Version 2 stores each path as the length of the prefix shared with the previous path followed by the
+// rest of the path, since the paths of a directory tree repeat the same parent directories many times.
+type pathEntryCoder struct {
+	compressed bool
+	prevPath   string
+	count      int // Number of entries written
+}
 
+func newPathEntryCoder(version uint16) pathEntryCoder {
+	ph := prefixHeader{Version: version}
+	return pathEntryCoder{compressed: ph.prefixCompressed()}
+}
+
+// Read the next path entry.
+func (c *pathEntryCoder) read(r vardata.Reader, entry *pathEntry) error {
+	if !c.compressed {
+		return entry.read(r)
+	}
+
+	if err := entry.readCompressed(r, c.prevPath); err != nil {
+		return err
+	}
+	c.prevPath = entry.path
+	return nil
+}
+
+// Write the next path entry.
+// Every pathRestartInterval entries the path is written in full.
+func (c *pathEntryCoder) write(w io.Writer, entry *pathEntry) error {
+	if !c.compressed {
+		return entry.write(w)
+	}
+
+	if c.count%pathRestartInterval == 0 {
+		c.prevPath = ""
+	}
+	if err := entry.writeCompressed(w, c.prevPath); err != nil {
+		return err
+	}
+	c.prevPath = entry.path
+	c.count++
 	return nil
 }
 
@@ -910,6 +1434,12 @@ func (s *entryLookup) write(w io.Writer) error {
 	return nil
 }
 
+// Maps an identifier to the index of the path info entry.
+type entryIdIndex struct {
+	Id    path.Id
+	Index uint32
+}
+
 type EntryIndexAndOffset struct {
 	Index  uint32 // Index of the path info entry.
 	Offset uint32 // Offset in the file where the entry can be found
@@ -923,12 +1453,50 @@ type FeatureFlags uint16
 const (
 	FeatureJustEntries = 0         // Contains no extra features. Only path info entries.
 	FeatureHashTable   = 1 << iota // Contains the calculated file hash signatures for the path objects.
+	FeatureDirStats                // Contains the child counts and cumulative sizes for each directory.
+	FeatureSignature               // Contains an Ed25519 signature of the database.
+	FeatureHashTimes               // Contains the time at which each file signature hash was calculated.
+	FeatureExtraHashes             // Contains additional hash tables calculated using other hashing algorithms.
+	FeatureAnnotations             // Contains the review decisions made for path entries and groups of duplicates.
+	FeatureIdNamespace             // The identifiers are derived from the path and the namespace stored in the meta entry.
 )
 
+// All the features supported by this version of ajfs.
+const supportedFeatures = FeatureFlags(FeatureHashTable | FeatureDirStats | FeatureSignature | FeatureHashTimes | FeatureExtraHashes | FeatureAnnotations | FeatureIdNamespace)
+
 func (f FeatureFlags) HasHashTable() bool {
 	return (f & FeatureHashTable) != 0
 }
 
+func (f FeatureFlags) HasDirStats() bool {
+	return (f & FeatureDirStats) != 0
+}
+
+func (f FeatureFlags) HasSignature() bool {
+	return (f & FeatureSignature) != 0
+}
+
+func (f FeatureFlags) HasHashTimes() bool {
+	return (f & FeatureHashTimes) != 0
+}
+
+func (f FeatureFlags) HasExtraHashes() bool {
+	return (f & FeatureExtraHashes) != 0
+}
+
+func (f FeatureFlags) HasAnnotations() bool {
+	return (f & FeatureAnnotations) != 0
+}
+
+func (f FeatureFlags) HasIdNamespace() bool {
+	return (f & FeatureIdNamespace) != 0
+}
+
+// Return the features that are not supported by this version of ajfs.
+func (f FeatureFlags) Unsupported() FeatureFlags {
+	return f &^ supportedFeatures
+}
+
 //-----------------------------------------------------------------------------
 // Helpers
 
@@ -974,5 +1542,14 @@ var (
 var toolMeta = fmt.Sprintf("ajfs: %s", buildinfo.VersionString())
 
 const (
-	currentVersion = uint16(1)
+	currentVersion = uint16(2) // Version 2 added the prefix compression of the path strings
+
+	statusDirty  = uint32(1)      // Set while the database is being created or resumed
+	statusSealed = uint32(1) << 1 // Set by "ajfs seal" to mark the database as read-only
+	statusSorted = uint32(1) << 2 // Set by SortHashTable when the hash table entries are ordered by hash
+
+	statusFullPaths     = uint32(1) << 3 // Set by SetPathDisplay when full paths should be displayed by default
+	statusRelativePaths = uint32(1) << 4 // Set by SetPathDisplay when relative paths should be displayed by default
+
+	statusArchives = uint32(1) << 5 // Set by SetArchives when the members of archives are recorded as virtual entries
 )
@@ -21,6 +21,8 @@
 package db_test
 
 import (
+	"bytes"
+	"context"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -28,6 +30,8 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -70,7 +74,7 @@ func TestCreateDatabase(t *testing.T) {
 	require.NoError(t, err)
 	expSignature := [4]byte{0x41, 0x4A, 0x46, 0x53} // AJFS
 	assert.Equal(t, expSignature, prefix.Signature)
-	assert.Equal(t, uint16(1), prefix.Version)
+	assert.Equal(t, uint16(2), prefix.Version)
 
 	header := header{}
 	err = binary.Read(f, binary.LittleEndian, &header)
@@ -125,10 +129,68 @@ func TestOpenDatabaseWhenInvalidFile(t *testing.T) {
 	require.NoError(t, binary.Write(f, binary.LittleEndian, &prefix))
 	_ = f.Close()
 
+	// Newer versions are allowed to be opened as long as the core sections can be read
 	_, err = db.OpenDatabase(f.Name())
+	assert.ErrorContains(t, err, "failed to read the ajfs header")
+
+	_, err = db.ResumeDatabase(f.Name())
 	assert.ErrorContains(t, err, "not a supported ajfs file (invalid version")
 }
 
+func TestOpenDatabaseForwardCompatible(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test", db.FeatureJustEntries)
+	require.NoError(t, err)
+
+	p1 := path.Info{
+		Id:      path.IdFromPath("a.txt"),
+		Path:    "a.txt",
+		Size:    uint64(42),
+		Mode:    0740,
+		ModTime: time.Now().Add(-10 * time.Minute),
+	}
+	require.NoError(t, dbf.WriteEntry(&p1))
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.Close())
+
+	// Supported
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	assert.False(t, dbf.Limited())
+	assert.Empty(t, dbf.Warnings())
+	require.NoError(t, dbf.Close())
+
+	// Pretend the database was created by a newer version of ajfs with an unknown feature
+	f, err := os.OpenFile(tempFile, os.O_RDWR, 0)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte{0x03, 0x00}, 4) // version
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte{0x00, 0x80}, 6+(5*4)) // features
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	assert.Equal(t, 3, dbf.Version())
+	assert.True(t, dbf.Limited())
+	assert.Equal(t, db.FeatureFlags(0x8000), dbf.Features().Unsupported())
+	require.Len(t, dbf.Warnings(), 2)
+	assert.Contains(t, dbf.Warnings()[0], "newer file format version 3")
+	assert.Contains(t, dbf.Warnings()[1], "unsupported features 0x8000")
+
+	// Entry level operations are still supported
+	pi, err := dbf.ReadEntryAtIndex(0)
+	require.NoError(t, err)
+	assert.Equal(t, p1.Path, pi.Path)
+
+	// Writing is not allowed
+	_, err = db.ResumeDatabase(tempFile)
+	assert.ErrorContains(t, err, "not a supported ajfs file")
+}
+
 func TestCreateDatabaseAbsRoot(t *testing.T) {
 	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
 	_ = os.Remove(tempFile)
@@ -160,7 +222,7 @@ func TestOpenDatabase(t *testing.T) {
 	defer f.Close()
 
 	assert.Equal(t, tempFile, f.Path())
-	assert.Equal(t, 1, f.Version())
+	assert.Equal(t, 2, f.Version())
 	assert.Equal(t, db.FeatureFlags(0), f.Features())
 	assert.Equal(t, expRoot, f.RootPath())
 
@@ -217,15 +279,15 @@ func TestWritePathInfo(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, p1.Equals(&c1))
 
-	c2, err = dbf.ReadEntryWithId(p2.Id)
+	c2, err = dbf.ReadEntryById(p2.Id)
 	require.NoError(t, err)
 	assert.True(t, p2.Equals(&c2))
 
-	c1, err = dbf.ReadEntryWithId(p1.Id)
+	c1, err = dbf.ReadEntryById(p1.Id)
 	require.NoError(t, err)
 	assert.True(t, p1.Equals(&c1))
 
-	_, err = dbf.ReadEntryWithId(path.IdFromPath("does not exist"))
+	_, err = dbf.ReadEntryById(path.IdFromPath("does not exist"))
 	assert.ErrorIs(t, err, db.ErrNotFound)
 
 	v, err := dbf.FindEntryIndexAndOffset(p1.Id)
@@ -240,6 +302,41 @@ func TestWritePathInfo(t *testing.T) {
 	assert.ErrorIs(t, err, db.ErrNotFound)
 }
 
+func TestWriteEntryNormalizesPath(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test", db.FeatureJustEntries)
+	require.NoError(t, err)
+
+	for _, p := range []string{"/abs/path", "..", "../outside", "a/../../outside", ""} {
+		err := dbf.WriteEntry(&path.Info{Id: path.IdFromPath(p), Path: p})
+		assert.ErrorIs(t, err, path.ErrInvalidPath, p)
+	}
+
+	// The identifier is derived from the normalized path when it was derived from the original path
+	p := "./some//dir/"
+	require.NoError(t, dbf.WriteEntry(&path.Info{Id: path.IdFromPath(p), Path: p, Mode: fs.ModeDir}))
+	// Otherwise it is kept
+	customId := path.IdFromPath("custom")
+	require.NoError(t, dbf.WriteEntry(&path.Info{Id: customId, Path: "some/dir/../file.txt"}))
+
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.Close())
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+	require.Equal(t, 2, dbf.EntriesCount())
+
+	pi, err := dbf.ReadEntryById(path.IdFromPath("some/dir"))
+	require.NoError(t, err)
+	assert.Equal(t, "some/dir", pi.Path)
+
+	pi, err = dbf.ReadEntryById(customId)
+	require.NoError(t, err)
+	assert.Equal(t, "some/file.txt", pi.Path)
+}
+
 func TestReadAll(t *testing.T) {
 	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
 	_ = os.Remove(tempFile)
@@ -253,7 +350,7 @@ func TestReadAll(t *testing.T) {
 	expTime := time.Now().Add(-10 * time.Minute)
 
 	for i := range expCount {
-		filePath := fmt.Sprintf("/some/path/%d.txt", i)
+		filePath := fmt.Sprintf("some/path/%d.txt", i)
 		p := path.Info{
 			Id:      path.IdFromPath(filePath),
 			Path:    filePath,
@@ -277,7 +374,7 @@ func TestReadAll(t *testing.T) {
 	rcvCount := 0
 	fn := func(idx int, pi path.Info) error {
 		rcvCount += 1
-		filePath := fmt.Sprintf("/some/path/%d.txt", idx)
+		filePath := fmt.Sprintf("some/path/%d.txt", idx)
 		assert.Equal(t, path.IdFromPath(filePath), pi.Id)
 		assert.Equal(t, filePath, pi.Path)
 		assert.Equal(t, uint64(idx), pi.Size)
@@ -286,7 +383,7 @@ func TestReadAll(t *testing.T) {
 		return nil
 	}
 
-	assert.NoError(t, dbf.ReadAllEntries(fn))
+	assert.NoError(t, dbf.ReadAllEntries(context.Background(), fn))
 	assert.Equal(t, expCount, rcvCount)
 
 	// Search for an entry and then stop
@@ -299,8 +396,45 @@ func TestReadAll(t *testing.T) {
 		return nil
 	}
 
-	assert.NoError(t, dbf.ReadAllEntries(fnSearch))
+	assert.NoError(t, dbf.ReadAllEntries(context.Background(), fnSearch))
 	assert.Equal(t, 6, rcvCount)
+
+	// Cancel while reading
+	rcvCount = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fnCancel := func(idx int, pi path.Info) error {
+		rcvCount += 1
+		if idx == 5 {
+			cancel()
+		}
+		return nil
+	}
+
+	assert.ErrorIs(t, dbf.ReadAllEntries(ctx, fnCancel), context.Canceled)
+	assert.Equal(t, 6, rcvCount)
+
+	// Read a range
+	rcvCount = 0
+	var indices []int
+	fnRange := func(idx int, pi path.Info) error {
+		indices = append(indices, idx)
+		return fn(idx, pi)
+	}
+
+	assert.NoError(t, dbf.ReadEntriesRange(context.Background(), 7, 10, fnRange))
+	assert.Equal(t, []int{7, 8, 9}, indices)
+
+	indices = nil
+	assert.NoError(t, dbf.ReadEntriesRange(context.Background(), 2, 4, fnRange))
+	assert.Equal(t, []int{2, 3}, indices)
+
+	indices = nil
+	assert.NoError(t, dbf.ReadEntriesRange(context.Background(), 5, 5, fnRange))
+	assert.Empty(t, indices)
+
+	assert.Panics(t, func() { _ = dbf.ReadEntriesRange(context.Background(), 5, 11, fnRange) })
+	assert.Panics(t, func() { _ = dbf.ReadEntriesRange(context.Background(), 6, 5, fnRange) })
 }
 
 func TestReadWritePanicConditions(t *testing.T) {
@@ -388,6 +522,291 @@ func TestVerifyChecksums(t *testing.T) {
 	require.NoError(t, dbf.Close())
 }
 
+func TestVerifyChecksumsSHA256(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabaseWithChecksum(tempFile, "/test", db.FeatureJustEntries, db.ChecksumSHA256)
+	require.NoError(t, err)
+
+	p1 := path.Info{
+		Id:      path.IdFromPath("a.txt"),
+		Path:    "a.txt",
+		Size:    uint64(42),
+		Mode:    0740,
+		ModTime: time.Now().Add(-10 * time.Minute),
+	}
+	require.NoError(t, dbf.WriteEntry(&p1))
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.Close())
+
+	// Open and validate
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	assert.Equal(t, db.ChecksumSHA256, dbf.ChecksumAlgo())
+	require.NoError(t, dbf.VerifyChecksums())
+
+	pi, err := dbf.ReadEntryAtIndex(0)
+	require.NoError(t, err)
+	assert.Equal(t, p1.Path, pi.Path)
+	require.NoError(t, dbf.Close())
+
+	// Change 1 byte in the last entry's path
+	data, err := os.ReadFile(tempFile)
+	require.NoError(t, err)
+	idx := bytes.LastIndex(data, []byte("a.txt"))
+	require.Greater(t, idx, 0)
+
+	f, err := os.OpenFile(tempFile, os.O_RDWR, 0)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte("b"), int64(idx))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	assert.ErrorIs(t, dbf.VerifyChecksums(), db.ErrInvalidChecksum)
+	require.NoError(t, dbf.Close())
+}
+
+func TestDirtyFlag(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test", db.FeatureJustEntries)
+	require.NoError(t, err)
+
+	p1 := path.Info{
+		Id:      path.IdFromPath("a.txt"),
+		Path:    "a.txt",
+		Size:    uint64(42),
+		Mode:    0740,
+		ModTime: time.Now().Add(-10 * time.Minute),
+	}
+	require.NoError(t, dbf.WriteEntry(&p1))
+	require.NoError(t, dbf.FinishEntries())
+
+	// Still busy being created
+	_, err = db.OpenDatabase(tempFile)
+	require.ErrorIs(t, err, db.ErrDirty)
+
+	require.NoError(t, dbf.Close())
+
+	f, err := db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// Still busy being resumed
+	dbf, err = db.ResumeDatabase(tempFile)
+	require.NoError(t, err)
+
+	_, err = db.OpenDatabase(tempFile)
+	require.ErrorIs(t, err, db.ErrDirty)
+
+	require.NoError(t, dbf.Close())
+
+	f, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	require.NoError(t, f.VerifyChecksums())
+	require.NoError(t, f.Close())
+}
+
+func TestConcurrentReads(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test", db.FeatureJustEntries)
+	require.NoError(t, err)
+
+	expected := make([]path.Info, 0, 100)
+	for i := range 100 {
+		p := path.Info{
+			Id:      path.IdFromPath(fmt.Sprintf("dir/%d.txt", i)),
+			Path:    fmt.Sprintf("dir/%d.txt", i),
+			Size:    uint64(i),
+			Mode:    0740,
+			ModTime: time.Now().Add(-10 * time.Minute),
+		}
+		require.NoError(t, dbf.WriteEntry(&p))
+		expected = append(expected, p)
+	}
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.Close())
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for i := len(expected) - 1; i >= 0; i-- {
+				pi, err := dbf.ReadEntryAtIndex(i)
+				assert.NoError(t, err)
+				assert.Equal(t, expected[i].Path, pi.Path)
+
+				pi, err = dbf.ReadEntryById(expected[i].Id)
+				assert.NoError(t, err)
+				assert.Equal(t, expected[i].Size, pi.Size)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestReadEntryById(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test", db.FeatureJustEntries)
+	require.NoError(t, err)
+
+	expected := make([]path.Info, 0, 50)
+	for i := range 50 {
+		p := path.Info{
+			Id:      path.IdFromPath(fmt.Sprintf("dir/%d.txt", i)),
+			Path:    fmt.Sprintf("dir/%d.txt", i),
+			Size:    uint64(i),
+			Mode:    0740,
+			ModTime: time.Now().Add(-10 * time.Minute),
+		}
+		require.NoError(t, dbf.WriteEntry(&p))
+		expected = append(expected, p)
+	}
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.Close())
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	for i, exp := range expected {
+		pi, err := dbf.ReadEntryById(exp.Id)
+		require.NoError(t, err)
+		assert.True(t, exp.Equals(&pi))
+
+		v, err := dbf.FindEntryIndexAndOffset(exp.Id)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(i), v.Index)
+	}
+
+	_, err = dbf.FindEntryIndexAndOffset(path.IdFromPath("dir/50.txt"))
+	assert.ErrorIs(t, err, db.ErrNotFound)
+}
+
+func TestPrefixCompressedPaths(t *testing.T) {
+	tempDir := t.TempDir()
+
+	expected := make([]path.Info, 0, 40)
+	for i := range 40 {
+		p := fmt.Sprintf("some/very/deep/directory/tree/%d/file-%d.txt", i/7, i)
+		expected = append(expected, path.Info{
+			Id:      path.IdFromPath(p),
+			Path:    p,
+			Size:    uint64(i),
+			Mode:    0740,
+			ModTime: time.Now().Add(-10 * time.Minute),
+		})
+	}
+
+	sizes := make(map[int]int64)
+	for _, version := range []int{1, db.CurrentVersion()} {
+		tempFile := filepath.Join(tempDir, fmt.Sprintf("v%d.ajfs", version))
+
+		dbf, err := db.CreateDatabaseWithVersion(tempFile, "/test", db.FeatureJustEntries, db.ChecksumCRC32, version)
+		require.NoError(t, err)
+		for _, pi := range expected {
+			require.NoError(t, dbf.WriteEntry(&pi))
+		}
+		require.NoError(t, dbf.FinishEntries())
+		require.NoError(t, dbf.Close())
+
+		dbf, err = db.OpenDatabase(tempFile)
+		require.NoError(t, err)
+		assert.Equal(t, version, dbf.Version())
+		require.NoError(t, dbf.VerifyChecksums())
+
+		err = dbf.ReadAllEntries(context.Background(), func(idx int, pi path.Info) error {
+			assert.Equal(t, expected[idx].Path, pi.Path)
+			return nil
+		})
+		require.NoError(t, err)
+
+		for i := len(expected) - 1; i >= 0; i-- {
+			pi, err := dbf.ReadEntryAtIndex(i)
+			require.NoError(t, err)
+			assert.Equal(t, expected[i].Path, pi.Path)
+		}
+
+		count := 0
+		err = dbf.ReadEntriesRange(context.Background(), 21, 35, func(idx int, pi path.Info) error {
+			assert.Equal(t, expected[idx].Path, pi.Path)
+			count++
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 14, count)
+		require.NoError(t, dbf.Close())
+
+		fi, err := os.Stat(tempFile)
+		require.NoError(t, err)
+		sizes[version] = fi.Size()
+	}
+
+	assert.Less(t, sizes[db.CurrentVersion()], sizes[1])
+
+	_, err := db.CreateDatabaseWithVersion(filepath.Join(tempDir, "v42.ajfs"), "/test", db.FeatureJustEntries, db.ChecksumCRC32, 42)
+	assert.ErrorContains(t, err, "unsupported file format version 42")
+}
+
+func TestIdNamespace(t *testing.T) {
+	tempDir := t.TempDir()
+
+	namespace, err := db.NewIdNamespace(db.IdModeUUID, "/test")
+	require.NoError(t, err)
+	assert.Regexp(t, `^uuid:[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, namespace)
+
+	other, err := db.NewIdNamespace(db.IdModeUUID, "/test")
+	require.NoError(t, err)
+	assert.NotEqual(t, namespace, other)
+
+	rootNamespace, err := db.NewIdNamespace(db.IdModeRoot, "/test")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(rootNamespace, "root:"))
+	assert.True(t, strings.HasSuffix(rootNamespace, ":"+filepath.Clean("/test")))
+
+	pathNamespace, err := db.NewIdNamespace(db.IdModePath, "/test")
+	require.NoError(t, err)
+	assert.Empty(t, pathNamespace)
+
+	for _, version := range []int{1, db.CurrentVersion()} {
+		tempFile := filepath.Join(tempDir, fmt.Sprintf("v%d.ajfs", version))
+
+		dbf, err := db.CreateDatabaseWithIdNamespace(tempFile, "/test", db.FeatureJustEntries, db.ChecksumCRC32, version, namespace)
+		require.NoError(t, err)
+		for _, p := range []string{".", "a", "a/b.txt"} {
+			pi := path.Info{Id: path.IdFromPath(p), Path: p, Mode: 0640, ModTime: time.Now()}
+			require.NoError(t, dbf.WriteEntry(&pi))
+		}
+		require.NoError(t, dbf.FinishEntries())
+		require.NoError(t, dbf.Close())
+
+		dbf, err = db.OpenDatabase(tempFile)
+		require.NoError(t, err)
+		require.NoError(t, dbf.VerifyChecksums())
+		assert.True(t, dbf.Features().HasIdNamespace())
+		assert.Equal(t, namespace, dbf.IdNamespace())
+		assert.Equal(t, namespace, dbf.Meta().IdNamespace)
+
+		// Identifiers are derived from the path and the namespace
+		pi, err := dbf.ReadEntryById(dbf.IdFromPath("a/b.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "a/b.txt", pi.Path)
+		assert.Equal(t, path.IdFromPathInNamespace(namespace, "a/b.txt"), pi.Id)
+		assert.NotEqual(t, path.IdFromPath("a/b.txt"), pi.Id)
+
+		_, err = dbf.ReadEntryById(path.IdFromPath("a/b.txt"))
+		assert.ErrorIs(t, err, db.ErrNotFound)
+		require.NoError(t, dbf.Close())
+	}
+}
+
 func TestBuildIdToInfoMap(t *testing.T) {
 	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
 	_ = os.Remove(tempFile)
@@ -401,7 +820,7 @@ func TestBuildIdToInfoMap(t *testing.T) {
 	expTime := time.Now().Add(-10 * time.Minute)
 
 	for i := range expCount {
-		filePath := fmt.Sprintf("/some/path/%d.txt", i)
+		filePath := fmt.Sprintf("some/path/%d.txt", i)
 		p := path.Info{
 			Id:      path.IdFromPath(filePath),
 			Path:    filePath,
@@ -421,12 +840,12 @@ func TestBuildIdToInfoMap(t *testing.T) {
 	defer dbf.Close()
 	assert.Equal(t, expCount, dbf.EntriesCount())
 
-	result, err := dbf.BuildIdToInfoMap()
+	result, err := dbf.BuildIdToInfoMap(context.Background())
 	require.NoError(t, err)
 	assert.Len(t, result, expCount)
 
 	for i := range expCount {
-		filePath := fmt.Sprintf("/some/path/%d.txt", i)
+		filePath := fmt.Sprintf("some/path/%d.txt", i)
 		v, ok := result[path.IdFromPath(filePath)]
 		assert.True(t, ok)
 		assert.Equal(t, filePath, v.Path)
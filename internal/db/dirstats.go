@@ -0,0 +1,297 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package db
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajmath/safe"
+)
+
+// file format
+// ... <entries, entries lookup table and [optional] extended checksum>
+// sentinel
+// header
+// n * dirStatsEntry, where n == number of directory path entries
+// checksum (CRC-32 of the header and entries)
+// sentinel
+
+// DirStats describes the contents of a directory.
+type DirStats struct {
+	Children uint32 // Number of entries directly inside the directory.
+	Files    uint32 // Number of files inside the directory and all of its subdirectories.
+	Size     uint64 // Combined size in bytes of the files inside the directory and all of its subdirectories.
+}
+
+// DirStatsTable maps from the path info index of a directory to its statistics.
+type DirStatsTable map[int]DirStats
+
+//-----------------------------------------------------------------------------
+// DatabaseFile
+
+type createDirStats struct {
+	indices map[string]uint32    // map from directory path to the path entry index
+	stats   map[string]*DirStats // map from directory path to the statistics gathered so far
+}
+
+func newCreateDirStats() *createDirStats {
+	return &createDirStats{
+		indices: make(map[string]uint32, 256),
+		stats:   make(map[string]*DirStats, 256),
+	}
+}
+
+// Update the statistics of the parent directories with the path entry that is being written.
+func (c *createDirStats) add(idx uint32, pi *path.Info) error {
+	if pi.IsDir() {
+		c.indices[pi.Path] = idx
+	}
+
+	if pi.Path == "." {
+		return nil
+	}
+
+	parent := filepath.Dir(pi.Path)
+	s := c.statsFor(parent)
+
+	var err error
+	if s.Children, err = safe.Add32(s.Children, 1); err != nil {
+		return err
+	}
+
+	if !pi.IsFile() {
+		return nil
+	}
+
+	for dir := parent; ; dir = filepath.Dir(dir) {
+		s := c.statsFor(dir)
+		if s.Files, err = safe.Add32(s.Files, 1); err != nil {
+			return err
+		}
+		s.Size += pi.Size
+
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (c *createDirStats) statsFor(dir string) *DirStats {
+	s, exists := c.stats[dir]
+	if !exists {
+		s = &DirStats{}
+		c.stats[dir] = s
+	}
+	return s
+}
+
+// Write the directory statistics gathered while the path entries were written.
+// Called by FinishEntries.
+func (dbf *DatabaseFile) writeDirStats() error {
+	if dbf.createDirStats == nil {
+		return nil
+	}
+
+	var err error
+	dbf.header.DirStatsOffset, err = safe.Uint64ToUint32(dbf.file.Offset())
+	if err != nil {
+		return fmt.Errorf("failed to set the ajfs directory statistics offset. %w", err)
+	}
+
+	// Enable feature
+	dbf.header.Features |= FeatureDirStats
+
+	// 1st sentinel
+	if _, err = dbf.file.Write(dirStatsSentinel[:]); err != nil {
+		return fmt.Errorf("failed to write the directory statistics (1st sentinel). %w", err)
+	}
+
+	hasher := crc32.NewIEEE()
+	w := io.MultiWriter(dbf.file, hasher)
+
+	header := dirStatsHeader{}
+	header.EntriesCount, err = safe.IntToUint32(len(dbf.createDirStats.indices))
+	if err != nil {
+		return fmt.Errorf("failed to write the directory statistics header. %w", err)
+	}
+
+	if err := header.write(w); err != nil {
+		return fmt.Errorf("failed to write the directory statistics header. %w", err)
+	}
+
+	// Sorted by index so that the table can be read in the same order as the path entries
+	dirs := slices.SortedFunc(maps.Keys(dbf.createDirStats.indices), func(l, r string) int {
+		return cmp.Compare(dbf.createDirStats.indices[l], dbf.createDirStats.indices[r])
+	})
+
+	for _, dir := range dirs {
+		entry := dirStatsEntry{
+			Index: dbf.createDirStats.indices[dir],
+		}
+		if s, exists := dbf.createDirStats.stats[dir]; exists {
+			entry.Children = s.Children
+			entry.Files = s.Files
+			entry.Size = s.Size
+		}
+
+		if err := entry.write(w); err != nil {
+			return fmt.Errorf("failed to write the directory statistics entry (index %d). %w", entry.Index, err)
+		}
+	}
+
+	if err := binary.Write(dbf.file, binary.LittleEndian, hasher.Sum32()); err != nil {
+		return fmt.Errorf("failed to write the directory statistics checksum. %w", err)
+	}
+
+	// 2nd sentinel
+	if _, err = dbf.file.Write(dirStatsSentinel[:]); err != nil {
+		return fmt.Errorf("failed to write the directory statistics (2nd sentinel). %w", err)
+	}
+
+	if err := dbf.file.Flush(); err != nil {
+		return fmt.Errorf("failed to write the directory statistics. %w", err)
+	}
+
+	dbf.createDirStats = nil
+	return nil
+}
+
+// Read the statistics for all the directories in the database.
+func (dbf *DatabaseFile) ReadDirStats(ctx context.Context) (DirStatsTable, error) {
+	if !dbf.Features().HasDirStats() {
+		panic("database does not contain the directory statistics")
+	}
+
+	_, err := dbf.file.Seek(int64(dbf.header.DirStatsOffset), io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the directory statistics. %w", err)
+	}
+	dbf.file.ResetReadBuffer()
+
+	return readDirStatsFrom(ctx, dbf.file)
+}
+
+// Read the directory statistics section starting at the 1st sentinel.
+func readDirStatsFrom(ctx context.Context, r io.Reader) (DirStatsTable, error) {
+	// Check 1st sentinel
+	var s [4]byte
+	_, err := io.ReadFull(r, s[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the directory statistics (1st sentinel). %w", err)
+	}
+	if s != dirStatsSentinel {
+		return nil, fmt.Errorf("failed to read the directory statistics (1st sentinel %q does not match %q)", s, dirStatsSentinel)
+	}
+
+	hasher := crc32.NewIEEE()
+	tr := io.TeeReader(r, hasher)
+
+	header := dirStatsHeader{}
+	if err := header.read(tr); err != nil {
+		return nil, fmt.Errorf("failed to read the directory statistics header. %w", err)
+	}
+
+	result := make(DirStatsTable, header.EntriesCount)
+
+	for i := range header.EntriesCount {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		entry := dirStatsEntry{}
+		if err := entry.read(tr); err != nil {
+			return nil, fmt.Errorf("failed to read the directory statistics entry at index %d. %w", i, err)
+		}
+
+		idx, err := safe.Uint32ToInt(entry.Index)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the directory statistics entry at index %d (path entry index %d will cause integer overflow). %w", i, entry.Index, err)
+		}
+
+		result[idx] = DirStats{
+			Children: entry.Children,
+			Files:    entry.Files,
+			Size:     entry.Size,
+		}
+	}
+
+	var checksum uint32
+	if err := binary.Read(r, binary.LittleEndian, &checksum); err != nil {
+		return nil, fmt.Errorf("failed to read the directory statistics checksum. %w", err)
+	}
+	if checksum != hasher.Sum32() {
+		return nil, fmt.Errorf("failed to read the directory statistics. %w", ErrInvalidChecksum)
+	}
+
+	// Check 2nd sentinel
+	_, err = io.ReadFull(r, s[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the directory statistics (2nd sentinel). %w", err)
+	}
+	if s != dirStatsSentinel {
+		return nil, fmt.Errorf("failed to read the directory statistics (2nd sentinel %q does not match %q)", s, dirStatsSentinel)
+	}
+
+	return result, nil
+}
+
+//-----------------------------------------------------------------------------
+
+type dirStatsHeader struct {
+	EntriesCount uint32 // Number of directory statistics entries
+}
+
+func (s *dirStatsHeader) read(r io.Reader) error {
+	return binary.Read(r, binary.LittleEndian, s)
+}
+
+func (s *dirStatsHeader) write(w io.Writer) error {
+	return binary.Write(w, binary.LittleEndian, s)
+}
+
+type dirStatsEntry struct {
+	Index    uint32 // Index of the directory path entry
+	Children uint32
+	Files    uint32
+	Size     uint64
+}
+
+func (s *dirStatsEntry) read(r io.Reader) error {
+	return binary.Read(r, binary.LittleEndian, s)
+}
+
+func (s *dirStatsEntry) write(w io.Writer) error {
+	return binary.Write(w, binary.LittleEndian, s)
+}
+
+var (
+	dirStatsSentinel = [4]byte{0x41, 0x4A, 0x44, 0x53} // AJDS
+)
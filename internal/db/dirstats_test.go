@@ -0,0 +1,145 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package db_test
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirStats(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test/", db.FeatureDirStats)
+	require.NoError(t, err)
+
+	entries := []struct {
+		path string
+		size uint64
+		mode fs.FileMode
+	}{
+		{path: ".", mode: fs.ModeDir},
+		{path: "a", mode: fs.ModeDir},
+		{path: "a/1.txt", size: 10},
+		{path: "a/b", mode: fs.ModeDir},
+		{path: "a/b/2.txt", size: 20},
+		{path: "a/b/3.txt", size: 30},
+		{path: "a/b/link", size: 5, mode: fs.ModeSymlink},
+		{path: "a/empty", mode: fs.ModeDir},
+		{path: "4.txt", size: 40},
+	}
+
+	for _, e := range entries {
+		pi := path.Info{
+			Id:      path.IdFromPath(e.path),
+			Path:    e.path,
+			Size:    e.size,
+			Mode:    e.mode | 0755,
+			ModTime: time.Now(),
+		}
+		require.NoError(t, dbf.WriteEntry(&pi))
+	}
+
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.Close())
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	assert.True(t, dbf.Features().HasDirStats())
+	assert.False(t, dbf.Features().HasHashTable())
+	assert.False(t, dbf.Limited())
+	require.NoError(t, dbf.VerifyChecksums())
+
+	table, err := dbf.ReadDirStats(context.Background())
+	require.NoError(t, err)
+
+	expected := db.DirStatsTable{
+		0: {Children: 2, Files: 4, Size: 100}, // .
+		1: {Children: 3, Files: 3, Size: 60},  // a
+		3: {Children: 3, Files: 2, Size: 50},  // a/b
+		7: {},                                 // a/empty
+	}
+	assert.Equal(t, expected, table)
+}
+
+func TestDirStatsWithoutEntries(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test/", db.FeatureDirStats)
+	require.NoError(t, err)
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.Close())
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	table, err := dbf.ReadDirStats(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, table)
+}
+
+func TestDirStatsAfterPrune(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test/", db.FeatureDirStats)
+	require.NoError(t, err)
+
+	for _, p := range []string{".", "a", "a/1.txt", "a/2.txt"} {
+		pi := path.Info{
+			Id:   path.IdFromPath(p),
+			Path: p,
+			Size: 100,
+		}
+		if filepath.Ext(p) != ".txt" {
+			pi.Mode = fs.ModeDir
+		}
+		require.NoError(t, dbf.WriteEntry(&pi))
+	}
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.Close())
+
+	removed, err := db.PruneDatabase(context.Background(), tempFile, func(idx int, pi path.Info, hash []byte) (bool, error) {
+		return pi.Path != "a/2.txt", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	table, err := dbf.ReadDirStats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, db.DirStatsTable{
+		0: {Children: 1, Files: 1, Size: 100},
+		1: {Children: 1, Files: 1, Size: 100},
+	}, table)
+}
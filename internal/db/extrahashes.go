@@ -0,0 +1,433 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package db
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/andrejacobs/go-aj/ajmath/safe"
+)
+
+// file format
+// ... <all the other sections except the signature>
+// sentinel
+// extraHashesHeader
+// n * (hashTableHeader, m * hashEntry), where n == number of tables and m == number of file path entries
+// sentinel
+//
+// The additional hash tables are added after the database was created and are therefore stored after all the
+// other sections (only the signature can follow). Each table has the same layout as the hash table, where a zero
+// hash means that the file has not been hashed using that algorithm.
+
+// ErrHashTableNotFound is returned when the database does not contain a hash table for the hashing algorithm.
+var ErrHashTableNotFound = errors.New("hash table not found")
+
+// Add a hash table that was calculated using another hashing algorithm than the one used by the hash table.
+// This allows for example a fast algorithm to be used for detecting changes and a stronger one for checking integrity.
+// An existing additional hash table for the same algorithm is replaced. Files missing from table are stored as not
+// being hashed. Any signature is removed.
+func AddHashTable(ctx context.Context, dbPath string, algo ajhash.Algo, table HashTable) error {
+	dbf, err := OpenDatabase(dbPath)
+	if err != nil {
+		return err
+	}
+	defer dbf.Close()
+
+	if dbf.Limited() {
+		return fmt.Errorf("can't add a hash table to %q because it can only be processed in a limited way", dbPath)
+	}
+
+	if !dbf.Features().HasHashTable() {
+		return fmt.Errorf("can't add a hash table to %q because the database does not contain the hash table", dbPath)
+	}
+
+	if !validAlgo(algo) {
+		return fmt.Errorf("can't add a hash table using the unknown hashing algorithm %d", algo)
+	}
+
+	primaryAlgo, err := dbf.HashTableAlgo()
+	if err != nil {
+		return err
+	}
+	if algo == primaryAlgo {
+		return fmt.Errorf("the hash table of %q already uses %s", dbPath, AlgoString(algo))
+	}
+
+	// The indices of the files in the same order as the hash table
+	indices := make([]uint32, 0, dbf.header.FileEntriesCount)
+	err = dbf.ReadHashTableEntries(ctx, func(idx int, hash []byte) error {
+		safeIdx, err := safe.IntToUint32(idx)
+		if err != nil {
+			return err
+		}
+		indices = append(indices, safeIdx)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	tables, err := dbf.readExtraHashTables(ctx, func(ajhash.Algo) bool { return true })
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i := range tables {
+		if tables[i].algo == algo {
+			tables[i].table = table
+			replaced = true
+		}
+	}
+	if !replaced {
+		tables = append(tables, extraHashTable{algo: algo, table: table})
+	}
+
+	// The section replaces the existing one and any signature that follows it
+	h := dbf.header
+	info, err := dbf.file.File().Stat()
+	if err != nil {
+		return err
+	}
+	offset := info.Size()
+	if h.Features.HasSignature() {
+		offset = int64(h.SignatureOffset)
+	}
+	if h.Features.HasAnnotations() {
+		offset = int64(h.AnnotationsOffset)
+	}
+	if h.Features.HasExtraHashes() {
+		offset = int64(h.ExtraHashTablesOffset)
+	}
+
+	// The annotations are stored after the hash tables and thus need to be written again
+	annotations, err := dbf.ReadAnnotations()
+	if err != nil {
+		return err
+	}
+
+	if err = dbf.Close(); err != nil {
+		return err
+	}
+
+	h.ExtraHashTablesOffset, err = safe.Int64ToUint32(offset)
+	if err != nil {
+		return fmt.Errorf("failed to set the ajfs additional hash tables offset. %w", err)
+	}
+	h.Features |= FeatureExtraHashes
+
+	// The content changes and thus an existing signature would no longer be valid
+	h.Features &^= FeatureSignature | FeatureAnnotations
+	h.SignatureOffset = 0
+	h.AnnotationsOffset = 0
+
+	f, err := os.OpenFile(dbPath, os.O_RDWR|os.O_EXCL, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open the ajfs database file. path: %q. %w", dbPath, err)
+	}
+	defer f.Close()
+
+	if err = f.Truncate(offset); err != nil {
+		return fmt.Errorf("failed to remove the existing additional hash tables. path: %q. %w", dbPath, err)
+	}
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	if err = writeExtraHashTables(w, indices, tables); err != nil {
+		return fmt.Errorf("failed to write the additional hash tables. path: %q. %w", dbPath, err)
+	}
+	if err = w.Flush(); err != nil {
+		return fmt.Errorf("failed to write the additional hash tables. path: %q. %w", dbPath, err)
+	}
+
+	if annotations.Len() > 0 {
+		end, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		if err = appendAnnotations(f, end, &h, annotations); err != nil {
+			return fmt.Errorf("failed to write the annotations. path: %q. %w", dbPath, err)
+		}
+	}
+
+	// The header is only updated once the hash tables have been written
+	if _, err = f.Seek(headerOffset(), io.SeekStart); err != nil {
+		return err
+	}
+	if err = h.write(f); err != nil {
+		return fmt.Errorf("failed to update the ajfs header. %w", err)
+	}
+
+	return f.Sync()
+}
+
+// Return the hashing algorithms of all the hash tables in the database.
+// The algorithm used by the hash table is first followed by those of the additional hash tables.
+func (dbf *DatabaseFile) HashTableAlgos(ctx context.Context) ([]ajhash.Algo, error) {
+	if !dbf.Features().HasHashTable() {
+		return nil, nil
+	}
+
+	algo, err := dbf.HashTableAlgo()
+	if err != nil {
+		return nil, err
+	}
+	result := []ajhash.Algo{algo}
+
+	tables, err := dbf.readExtraHashTables(ctx, func(ajhash.Algo) bool { return false })
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tables {
+		result = append(result, t.algo)
+	}
+
+	return result, nil
+}
+
+// Read the hash table that was calculated using the hashing algorithm.
+// This is either the hash table or one of the additional hash tables.
+// Will only contain the entries for which a file signature hash was calculated.
+// Returns [ErrHashTableNotFound] if the database does not contain a hash table for the algorithm.
+func (dbf *DatabaseFile) ReadHashTableWithAlgo(ctx context.Context, algo ajhash.Algo) (HashTable, error) {
+	if !dbf.Features().HasHashTable() {
+		return nil, fmt.Errorf("failed to read the %s hash table. %w", AlgoString(algo), ErrHashTableNotFound)
+	}
+
+	primaryAlgo, err := dbf.HashTableAlgo()
+	if err != nil {
+		return nil, err
+	}
+	if algo == primaryAlgo {
+		return dbf.ReadHashTable(ctx)
+	}
+
+	tables, err := dbf.readExtraHashTables(ctx, func(a ajhash.Algo) bool { return a == algo })
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tables {
+		if t.algo == algo {
+			return t.table, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to read the %s hash table. %w", AlgoString(algo), ErrHashTableNotFound)
+}
+
+//-----------------------------------------------------------------------------
+// Reading and writing
+
+type extraHashTable struct {
+	algo  ajhash.Algo
+	table HashTable // nil when the entries were not read
+}
+
+// Read the additional hash tables.
+// The entries are only read for the tables for which want returns true.
+func (dbf *DatabaseFile) readExtraHashTables(ctx context.Context, want func(algo ajhash.Algo) bool) ([]extraHashTable, error) {
+	if !dbf.header.Features.HasExtraHashes() {
+		return nil, nil
+	}
+
+	if dbf.newerVersion() {
+		return nil, fmt.Errorf("the additional hash tables of the database %q are not supported (file format version %d, expected <= %d)",
+			dbf.path, dbf.prefixHeader.Version, currentVersion)
+	}
+
+	_, err := dbf.file.Seek(int64(dbf.header.ExtraHashTablesOffset), io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the additional hash tables. %w", err)
+	}
+	dbf.file.ResetReadBuffer()
+
+	restore, err := dbf.startSequentialRead()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the additional hash tables. %w", err)
+	}
+	defer restore()
+
+	return readExtraHashTablesFrom(ctx, dbf.file, dbf.header.FileEntriesCount, want)
+}
+
+// Read the additional hash tables section from the reader.
+// fileEntriesCount is the number of file path entries in the database.
+// See [DatabaseFile.readExtraHashTables] for want.
+func readExtraHashTablesFrom(ctx context.Context, r io.Reader, fileEntriesCount uint32, want func(algo ajhash.Algo) bool) ([]extraHashTable, error) {
+	// Check 1st sentinel
+	var s [4]byte
+	_, err := io.ReadFull(r, s[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the additional hash tables (1st sentinel). %w", err)
+	}
+	if s != extraHashesSentinel {
+		return nil, fmt.Errorf("failed to read the additional hash tables (1st sentinel %q does not match %q)", s, extraHashesSentinel)
+	}
+
+	header := extraHashesHeader{}
+	if err := header.read(r); err != nil {
+		return nil, fmt.Errorf("failed to read the additional hash tables header. %w", err)
+	}
+
+	result := make([]extraHashTable, 0, header.TablesCount)
+	for i := range header.TablesCount {
+		tableHeader := hashTableHeader{}
+		if err := tableHeader.read(r); err != nil {
+			return nil, fmt.Errorf("failed to read the additional hash table header at index %d. %w", i, err)
+		}
+
+		if !validAlgo(tableHeader.Algo) {
+			return nil, fmt.Errorf("the additional hash table at index %d uses an unknown hashing algorithm %d", i, tableHeader.Algo)
+		}
+
+		if fileEntriesCount != tableHeader.EntriesCount {
+			return nil, fmt.Errorf("the number of entries %d of the additional hash table at index %d does not match the number of file path entries %d in the database",
+				tableHeader.EntriesCount, i, fileEntriesCount)
+		}
+
+		t := extraHashTable{algo: tableHeader.Algo}
+		if want(t.algo) {
+			t.table = make(HashTable, tableHeader.EntriesCount)
+		}
+
+		for j := range tableHeader.EntriesCount {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			entry := hashEntry{
+				Hash: AlgoZeroValue(tableHeader.Algo),
+			}
+			if err := entry.read(r); err != nil {
+				return nil, fmt.Errorf("failed to read the %s hash table entry at index %d. %w", AlgoString(t.algo), j, err)
+			}
+
+			if (t.table == nil) || ajhash.AllZeroBytes(entry.Hash) {
+				continue
+			}
+
+			idx, err := safe.Uint32ToInt(entry.Index)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the %s hash table entry at index %d (path entry index %d will cause integer overflow). %w",
+					AlgoString(t.algo), j, entry.Index, err)
+			}
+			t.table[idx] = entry.Hash
+		}
+
+		result = append(result, t)
+	}
+
+	// Check 2nd sentinel
+	_, err = io.ReadFull(r, s[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the additional hash tables (2nd sentinel). %w", err)
+	}
+	if s != extraHashesSentinel {
+		return nil, fmt.Errorf("failed to read the additional hash tables (2nd sentinel %q does not match %q)", s, extraHashesSentinel)
+	}
+
+	return result, nil
+}
+
+// Write the additional hash tables section.
+// indices are the indices of the file path entries for which an entry is written in each table.
+func writeExtraHashTables(w io.Writer, indices []uint32, tables []extraHashTable) error {
+	entriesCount, err := safe.IntToUint32(len(indices))
+	if err != nil {
+		return err
+	}
+
+	header := extraHashesHeader{}
+	header.TablesCount, err = safe.IntToUint32(len(tables))
+	if err != nil {
+		return err
+	}
+
+	if _, err = w.Write(extraHashesSentinel[:]); err != nil {
+		return err
+	}
+	if err = header.write(w); err != nil {
+		return err
+	}
+
+	for _, t := range tables {
+		tableHeader := hashTableHeader{
+			Algo:         t.algo,
+			EntriesCount: entriesCount,
+		}
+		if err = tableHeader.write(w); err != nil {
+			return err
+		}
+
+		zeroHash := AlgoZeroValue(t.algo)
+		for _, idx := range indices {
+			entry := hashEntry{
+				Index: idx,
+				Hash:  zeroHash,
+			}
+
+			hash, ok := t.table[int(idx)]
+			if ok {
+				if len(hash) != len(zeroHash) {
+					return fmt.Errorf("invalid %s hash size %d for index %d, expected size %d", AlgoString(t.algo), len(hash), idx, len(zeroHash))
+				}
+				entry.Hash = hash
+			}
+
+			if err = entry.write(w); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = w.Write(extraHashesSentinel[:])
+	return err
+}
+
+//-----------------------------------------------------------------------------
+// Header
+
+type extraHashesHeader struct {
+	TablesCount uint32 // The number of additional hash tables
+}
+
+func (s *extraHashesHeader) read(r io.Reader) error {
+	return binary.Read(r, binary.LittleEndian, s)
+}
+
+func (s *extraHashesHeader) write(w io.Writer) error {
+	return binary.Write(w, binary.LittleEndian, s)
+}
+
+//-----------------------------------------------------------------------------
+// Constants and Misc
+
+var (
+	extraHashesSentinel = [4]byte{0x41, 0x4A, 0x48, 0x41} // AJHA
+)
@@ -0,0 +1,159 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package db_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/andrejacobs/go-aj/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddHashTable(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test", db.FeatureHashTable)
+	require.NoError(t, err)
+
+	const count = 10
+	for i := range count {
+		p := fmt.Sprintf("some/path/%d.txt", i)
+		pi := path.Info{
+			Id:      path.IdFromPath(p),
+			Path:    p,
+			Size:    uint64(i),
+			ModTime: time.Now(),
+		}
+		require.NoError(t, dbf.WriteEntry(&pi))
+	}
+	require.NoError(t, dbf.FinishEntries())
+
+	randomTable := func(algo ajhash.Algo, indices ...int) db.HashTable {
+		result := make(db.HashTable, len(indices))
+		for _, idx := range indices {
+			h := make([]byte, db.AlgoSize(algo))
+			require.NoError(t, random.SecureBytes(h))
+			result[idx] = h
+		}
+		return result
+	}
+
+	primary := randomTable(ajhash.AlgoSHA1, 1, 5, 9)
+	require.NoError(t, dbf.StartHashTable(ajhash.AlgoSHA1))
+	for idx, h := range primary {
+		require.NoError(t, dbf.WriteHashEntry(idx, h))
+	}
+	require.NoError(t, dbf.FinishHashTable())
+	require.NoError(t, dbf.Close())
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.NoError(t, db.SignDatabase(tempFile, key))
+
+	// The algorithm of the hash table can't be added again
+	assert.Error(t, db.AddHashTable(context.Background(), tempFile, ajhash.AlgoSHA1, primary))
+
+	sha256Table := randomTable(ajhash.AlgoSHA256, 1, 5)
+	require.NoError(t, db.AddHashTable(context.Background(), tempFile, ajhash.AlgoSHA256, sha256Table))
+	sha512Table := randomTable(ajhash.AlgoSHA512, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	require.NoError(t, db.AddHashTable(context.Background(), tempFile, ajhash.AlgoSHA512, sha512Table))
+
+	// Replace an existing table
+	sha256Table = randomTable(ajhash.AlgoSHA256, 1, 5, 9)
+	require.NoError(t, db.AddHashTable(context.Background(), tempFile, ajhash.AlgoSHA256, sha256Table))
+
+	verify := func(expPrimary db.HashTable, expSHA256 db.HashTable, expSHA512 db.HashTable) {
+		dbf, err := db.OpenDatabase(tempFile)
+		require.NoError(t, err)
+		defer dbf.Close()
+
+		assert.True(t, dbf.Features().HasExtraHashes())
+		assert.False(t, dbf.Features().HasSignature())
+
+		algos, err := dbf.HashTableAlgos(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []ajhash.Algo{ajhash.AlgoSHA1, ajhash.AlgoSHA256, ajhash.AlgoSHA512}, algos)
+
+		table, err := dbf.ReadHashTableWithAlgo(context.Background(), ajhash.AlgoSHA1)
+		require.NoError(t, err)
+		assert.Equal(t, expPrimary, table)
+
+		table, err = dbf.ReadHashTableWithAlgo(context.Background(), ajhash.AlgoSHA256)
+		require.NoError(t, err)
+		assert.Equal(t, expSHA256, table)
+
+		table, err = dbf.ReadHashTableWithAlgo(context.Background(), ajhash.AlgoSHA512)
+		require.NoError(t, err)
+		assert.Equal(t, expSHA512, table)
+
+		_, err = dbf.ReadHashTableWithAlgo(context.Background(), db.AlgoMD5)
+		assert.ErrorIs(t, err, db.ErrHashTableNotFound)
+	}
+	verify(primary, sha256Table, sha512Table)
+
+	// The header matches the contents
+	require.NoError(t, db.FixDatabase(io.Discard, tempFile, true, ""))
+
+	// Pruning keeps the additional hash tables
+	_, err = db.PruneDatabase(context.Background(), tempFile, func(idx int, pi path.Info, hash []byte) (bool, error) {
+		return idx != 0, nil
+	})
+	require.NoError(t, err)
+
+	shift := func(table db.HashTable) db.HashTable {
+		result := make(db.HashTable, len(table))
+		for idx, h := range table {
+			if idx > 0 {
+				result[idx-1] = h
+			}
+		}
+		return result
+	}
+	verify(shift(primary), shift(sha256Table), shift(sha512Table))
+}
+
+func TestStrongestCommonAlgo(t *testing.T) {
+	algo, ok := db.StrongestCommonAlgo(
+		[]ajhash.Algo{ajhash.AlgoSHA1, ajhash.AlgoSHA256, ajhash.AlgoSHA512},
+		[]ajhash.Algo{ajhash.AlgoSHA256, ajhash.AlgoSHA1})
+	assert.True(t, ok)
+	assert.Equal(t, ajhash.AlgoSHA256, algo)
+
+	algo, ok = db.StrongestCommonAlgo([]ajhash.Algo{db.AlgoMD5, ajhash.AlgoSHA1}, []ajhash.Algo{db.AlgoMD5})
+	assert.True(t, ok)
+	assert.Equal(t, db.AlgoMD5, algo)
+
+	_, ok = db.StrongestCommonAlgo([]ajhash.Algo{ajhash.AlgoSHA1}, []ajhash.Algo{ajhash.AlgoSHA256})
+	assert.False(t, ok)
+
+	_, ok = db.StrongestCommonAlgo(nil, []ajhash.Algo{ajhash.AlgoSHA256})
+	assert.False(t, ok)
+}
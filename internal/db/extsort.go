@@ -0,0 +1,311 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/andrejacobs/go-aj/ajhash"
+)
+
+// ExternalSortThreshold is the number of file entries above which [DatabaseFile.FindDuplicates] switches from
+// building a map of every hash in memory to sorting the hashes in runs that are spilled to temporary files.
+var ExternalSortThreshold = 5_000_000
+
+// Number of hash entries that are sorted in memory before being spilled to a temporary file.
+var externalSortRunSize = 1_000_000
+
+// Find the duplicates using an external merge sort so that memory usage is bounded by the run size.
+func (dbf *DatabaseFile) findDuplicatesExternal(ctx context.Context, fn FindDuplicatesFn) error {
+	g := duplicateGrouper{dbf: dbf, fn: fn}
+
+	err := dbf.sortHashEntries(ctx, true, g.add)
+	if err == nil {
+		err = g.flush()
+	}
+
+	if err == SkipAll {
+		return nil
+	}
+	return err
+}
+
+// Call fn with each of the hash table entries in the order of the hash and then the path entry index.
+// The entries are sorted in runs of [externalSortRunSize] that are spilled to temporary files and then merged.
+// Each record is the hash followed by the big endian entry index, so sorting the raw bytes gives the required order.
+// skipUnhashed excludes the entries for which the hash has not been calculated yet.
+func (dbf *DatabaseFile) sortHashEntries(ctx context.Context, skipUnhashed bool, fn func(hash []byte, idx int) error) error {
+	header, err := dbf.readHashTableHeader()
+	if err != nil {
+		return err
+	}
+	hashSize := AlgoSize(header.Algo)
+	recordSize := hashSize + 4
+
+	var runPaths []string
+	defer func() {
+		for _, p := range runPaths {
+			_ = os.Remove(p)
+		}
+	}()
+
+	run := records{size: recordSize, buf: make([]byte, 0, min(externalSortRunSize, int(header.EntriesCount))*recordSize)}
+
+	err = dbf.ReadHashTableEntries(ctx, func(idx int, hash []byte) error {
+		if skipUnhashed && ajhash.AllZeroBytes(hash) {
+			return nil
+		}
+
+		run.buf = append(run.buf, hash...)
+		run.buf = binary.BigEndian.AppendUint32(run.buf, uint32(idx)) //nolint:gosec // disable G115
+
+		if run.Len() < externalSortRunSize {
+			return nil
+		}
+
+		p, err := run.spill()
+		if p != "" {
+			runPaths = append(runPaths, p)
+		}
+		if err != nil {
+			return err
+		}
+		run.buf = run.buf[:0]
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// The last run is merged straight from memory
+	sort.Sort(&run)
+	sources := make([]recordSource, 0, len(runPaths)+1)
+	sources = append(sources, &memorySource{recs: &run})
+
+	for _, p := range runPaths {
+		f, err := os.Open(p)
+		if err != nil {
+			return fmt.Errorf("failed to open the sorted run %q. %w", p, err)
+		}
+		defer f.Close()
+		sources = append(sources, &fileSource{r: bufio.NewReader(f), rec: make([]byte, recordSize)})
+	}
+
+	return mergeRecords(ctx, sources, func(rec []byte) error {
+		return fn(rec[:hashSize], int(binary.BigEndian.Uint32(rec[hashSize:])))
+	})
+}
+
+// Groups consecutive entries with the same hash and calls the [FindDuplicatesFn] for each group that has duplicates.
+// The entries must be added in the order of the hash.
+type duplicateGrouper struct {
+	dbf     *DatabaseFile
+	fn      FindDuplicatesFn
+	hash    []byte
+	indices []int
+}
+
+func (g *duplicateGrouper) add(hash []byte, idx int) error {
+	if !bytes.Equal(hash, g.hash) {
+		if err := g.flush(); err != nil {
+			return err
+		}
+		g.hash = append(g.hash[:0], hash...)
+		g.indices = g.indices[:0]
+	}
+	g.indices = append(g.indices, idx)
+	return nil
+}
+
+func (g *duplicateGrouper) flush() error {
+	if len(g.indices) < 2 {
+		return nil
+	}
+
+	hashStr := hex.EncodeToString(g.hash)
+	for _, idx := range g.indices {
+		pi, err := g.dbf.ReadEntryAtIndex(idx)
+		if err != nil {
+			return err
+		}
+		if err = g.fn(hashStr, idx, pi, hashStr); err != nil {
+			return err
+		}
+	}
+	g.indices = g.indices[:0]
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// Fixed size records stored back to back in a single buffer.
+type records struct {
+	size int
+	buf  []byte
+	tmp  []byte
+}
+
+func (r *records) Len() int {
+	return len(r.buf) / r.size
+}
+
+func (r *records) Less(i, j int) bool {
+	return bytes.Compare(r.at(i), r.at(j)) < 0
+}
+
+func (r *records) Swap(i, j int) {
+	if r.tmp == nil {
+		r.tmp = make([]byte, r.size)
+	}
+	copy(r.tmp, r.at(i))
+	copy(r.at(i), r.at(j))
+	copy(r.at(j), r.tmp)
+}
+
+func (r *records) at(i int) []byte {
+	return r.buf[i*r.size : (i+1)*r.size]
+}
+
+// Sort the records and write them to a new temporary file.
+func (r *records) spill() (string, error) {
+	sort.Sort(r)
+
+	f, err := os.CreateTemp("", "ajfs-dupes-*.run")
+	if err != nil {
+		return "", fmt.Errorf("failed to create a temporary file for sorting the hashes. %w", err)
+	}
+
+	if _, err = f.Write(r.buf); err != nil {
+		f.Close()
+		return f.Name(), fmt.Errorf("failed to write the sorted run %q. %w", f.Name(), err)
+	}
+
+	if err = f.Close(); err != nil {
+		return f.Name(), fmt.Errorf("failed to write the sorted run %q. %w", f.Name(), err)
+	}
+	return f.Name(), nil
+}
+
+// Source of sorted records. next returns io.EOF once all the records have been read.
+type recordSource interface {
+	next() ([]byte, error)
+}
+
+type memorySource struct {
+	recs *records
+	pos  int
+}
+
+func (s *memorySource) next() ([]byte, error) {
+	if s.pos >= s.recs.Len() {
+		return nil, io.EOF
+	}
+	rec := s.recs.at(s.pos)
+	s.pos++
+	return rec, nil
+}
+
+type fileSource struct {
+	r   *bufio.Reader
+	rec []byte
+}
+
+func (s *fileSource) next() ([]byte, error) {
+	if _, err := io.ReadFull(s.r, s.rec); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("sorted run is truncated. %w", err)
+		}
+		return nil, err
+	}
+	return s.rec, nil
+}
+
+// Merge the sorted sources and call fn with each record in sorted order.
+func mergeRecords(ctx context.Context, sources []recordSource, fn func(rec []byte) error) error {
+	h := make(mergeHeap, 0, len(sources))
+	for _, s := range sources {
+		rec, err := s.next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				continue
+			}
+			return err
+		}
+		h = append(h, mergeItem{rec: rec, src: s})
+	}
+	heap.Init(&h)
+
+	for h.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		top := &h[0]
+		if err := fn(top.rec); err != nil {
+			return err
+		}
+
+		rec, err := top.src.next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return err
+			}
+			heap.Pop(&h)
+			continue
+		}
+		top.rec = rec
+		heap.Fix(&h, 0)
+	}
+
+	return nil
+}
+
+type mergeItem struct {
+	rec []byte
+	src recordSource
+}
+
+type mergeHeap []mergeItem
+
+func (h mergeHeap) Len() int           { return len(h) }
+func (h mergeHeap) Less(i, j int) bool { return bytes.Compare(h[i].rec, h[j].rec) < 0 }
+func (h mergeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *mergeHeap) Push(x any) {
+	*h = append(*h, x.(mergeItem)) //nolint:forcetypeassert // only mergeItem is pushed
+}
+
+func (h *mergeHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/andrejacobs/go-aj/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindDuplicatesExternal(t *testing.T) {
+	algo := ajhash.AlgoSHA1
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := CreateDatabase(tempFile, "/test", FeatureHashTable)
+	require.NoError(t, err)
+
+	count := 200
+	for i := range count {
+		p := fmt.Sprintf("file-%d.txt", i)
+		pi := path.Info{
+			Id:      path.IdFromPath(p),
+			Path:    p,
+			Size:    uint64(i),
+			Mode:    0644,
+			ModTime: time.Now(),
+		}
+		require.NoError(t, dbf.WriteEntry(&pi))
+	}
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.StartHashTable(algo))
+	require.NoError(t, dbf.FinishHashTable())
+
+	// Plenty of duplicates, some unique hashes and a few files that have not been hashed
+	pool := make([][]byte, 40)
+	for i := range pool {
+		pool[i] = algo.Buffer()
+		require.NoError(t, random.SecureBytes(pool[i]))
+	}
+	for i := range count {
+		switch {
+		case i%17 == 0:
+			continue
+		case i%5 == 0:
+			unique := algo.Buffer()
+			require.NoError(t, random.SecureBytes(unique))
+			require.NoError(t, dbf.WriteHashEntry(i, unique))
+		default:
+			require.NoError(t, dbf.WriteHashEntry(i, pool[(i*7)%len(pool)]))
+		}
+	}
+	require.NoError(t, dbf.Close())
+
+	dbf, err = OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	type found struct {
+		group string
+		idx   int
+		hash  string
+	}
+	collect := func() []found {
+		var result []found
+		err := dbf.FindDuplicates(context.Background(), func(group string, idx int, pi path.Info, hash string) error {
+			assert.Equal(t, fmt.Sprintf("file-%d.txt", idx), pi.Path)
+			result = append(result, found{group: group, idx: idx, hash: hash})
+			return nil
+		})
+		require.NoError(t, err)
+		return result
+	}
+
+	expected := collect()
+	require.NotEmpty(t, expected)
+
+	origThreshold, origRunSize := ExternalSortThreshold, externalSortRunSize
+	t.Cleanup(func() {
+		ExternalSortThreshold, externalSortRunSize = origThreshold, origRunSize
+	})
+
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+
+	ExternalSortThreshold = 0
+	for _, runSize := range []int{7, 50, 1000} {
+		externalSortRunSize = runSize
+		assert.Equal(t, expected, collect(), "run size %d", runSize)
+	}
+
+	// Temporary files are removed
+	entries, err := os.ReadDir(tempDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// Stop early
+	externalSortRunSize = 7
+	calls := 0
+	err = dbf.FindDuplicates(context.Background(), func(group string, idx int, pi path.Info, hash string) error {
+		calls++
+		return SkipAll
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+}
@@ -27,9 +27,11 @@ import (
 	"fmt"
 	"hash/crc32"
 	"io"
+	"maps"
 	"os"
 	"slices"
 
+	"github.com/andrejacobs/go-aj/ajhash"
 	"github.com/andrejacobs/go-aj/ajio/trackedoffset"
 	"github.com/andrejacobs/go-aj/ajmath/safe"
 	"github.com/andrejacobs/go-aj/file"
@@ -52,6 +54,7 @@ func FixDatabase(out io.Writer, dbPath string, dryRun bool, bakPath string) erro
 	if err != nil {
 		return fmt.Errorf("failed to open the ajfs database file. path: %q. %w", dbPath, err)
 	}
+	resizeIOBuffers(dbf.file)
 
 	// > readHeadersAndVerify ---------------------------------------
 
@@ -76,21 +79,35 @@ func FixDatabase(out io.Writer, dbPath string, dryRun bool, bakPath string) erro
 
 	fixHeader := dbf.header
 
+	if dbf.header.isDirty() {
+		fixHeader.Status &^= statusDirty
+		fmt.Fprintln(out, ">> Database was not closed cleanly")
+	}
+
 	checksumHasher := crc32.NewIEEE()
+	var checksumWriter io.Writer = checksumHasher
+
+	extChecksumHasher, err := dbf.header.ChecksumAlgo.newHasher()
+	if err != nil {
+		return err
+	}
+	if extChecksumHasher != nil {
+		checksumWriter = io.MultiWriter(checksumHasher, extChecksumHasher)
+	}
 
 	// Read the root info
 	if err := dbf.root.read(dbf.file); err != nil {
 		return fmt.Errorf("failed to read the ajfs root entry. path: %q. %w", dbf.path, err)
 	}
-	_ = dbf.root.write(checksumHasher)
+	_ = dbf.root.write(checksumWriter)
 
 	fmt.Fprintf(out, "Root: %q\n", dbf.root.path)
 
 	// Read the meta info
-	if err := dbf.meta.read(dbf.file); err != nil {
+	if err := dbf.meta.read(dbf.file, dbf.header.Features); err != nil {
 		return fmt.Errorf("failed to read the ajfs meta entry. path: %q. %w", dbf.path, err)
 	}
-	_ = dbf.meta.write(checksumHasher)
+	_ = dbf.meta.write(checksumWriter)
 
 	fmt.Fprintf(out, "Meta | OS: %q\n", dbf.meta.OS)
 	fmt.Fprintf(out, "Meta | Arch: %q\n", dbf.meta.Arch)
@@ -115,8 +132,17 @@ func FixDatabase(out io.Writer, dbPath string, dryRun bool, bakPath string) erro
 	fileEntriesCount := uint32(0)
 	expectedEntryLookups := make([]entryLookup, 0, 64)
 	fileIndices := make([]uint32, 0, 64)
+	dirIndices := make([]int, 0, 64)
 	var s [4]byte
 
+	// Offsets of the hashes that were not written completely (found using the hash table journal)
+	var tornHashOffsets []int64
+	tornHashSize := 0
+
+	// The entries are written again to calculate the checksum
+	readCoder := newPathEntryCoder(dbf.prefixHeader.Version)
+	writeCoder := newPathEntryCoder(dbf.prefixHeader.Version)
+
 	for keepGoing {
 		offset, err := safe.Uint64ToUint32(dbf.file.Offset())
 		if err != nil {
@@ -124,7 +150,7 @@ func FixDatabase(out io.Writer, dbPath string, dryRun bool, bakPath string) erro
 		}
 
 		entry := pathEntry{}
-		if err := entry.read(dbf.file); err != nil {
+		if err := readCoder.read(dbf.file, &entry); err != nil {
 			if errors.Is(err, io.EOF) {
 				return fmt.Errorf("database is corrupted. reached EOF while reading the entries")
 			}
@@ -132,7 +158,7 @@ func FixDatabase(out io.Writer, dbPath string, dryRun bool, bakPath string) erro
 			return fmt.Errorf("failed to read entry at index %d (offset %d). %w", entriesCount, offset, err)
 		}
 		entriesCount++
-		_ = entry.write(checksumHasher)
+		_ = writeCoder.write(checksumWriter, &entry)
 
 		expectedEntryLookups = append(expectedEntryLookups, entryLookup{
 			Id:     entry.header.Id,
@@ -142,6 +168,8 @@ func FixDatabase(out io.Writer, dbPath string, dryRun bool, bakPath string) erro
 		if entry.header.Mode.IsRegular() {
 			fileEntriesCount++
 			fileIndices = append(fileIndices, entriesCount-1)
+		} else if entry.header.Mode.IsDir() {
+			dirIndices = append(dirIndices, int(entriesCount-1))
 		}
 
 		// Check for entries lookup table sentinel
@@ -152,10 +180,10 @@ func FixDatabase(out io.Writer, dbPath string, dryRun bool, bakPath string) erro
 
 		if bytes.Equal(buf, sentinel[:]) {
 			keepGoing = false
-			_, _ = checksumHasher.Write(sentinel[:])
+			_, _ = checksumWriter.Write(sentinel[:])
 			_, err = dbf.file.Discard(4)
 			if err != nil {
-				return fmt.Errorf("failed to discard 4 bytes while looking for the entries offset table. %w", err)
+				return fmt.Errorf("failed to discard 4 bytes while looking for the entries lookup table. %w", err)
 			}
 		}
 	}
@@ -198,7 +226,7 @@ func FixDatabase(out io.Writer, dbPath string, dryRun bool, bakPath string) erro
 			}
 			return fmt.Errorf("failed to read the entry lookup table (near index %d). %w", i, err)
 		}
-		_ = entry.write(checksumHasher)
+		_ = entry.write(checksumWriter)
 	}
 
 	// Check 2nd sentinel
@@ -209,7 +237,7 @@ func FixDatabase(out io.Writer, dbPath string, dryRun bool, bakPath string) erro
 	if s != sentinel {
 		return fmt.Errorf("failed to read the entry lookup table (2nd sentinel %q does not match %q)", s, sentinel)
 	}
-	_, _ = checksumHasher.Write(sentinel[:])
+	_, _ = checksumWriter.Write(sentinel[:])
 
 	if len(expectedEntryLookups) != len(entryLookups) {
 		return fmt.Errorf("database is corrupted. expected %d entries in the entries lookup table, actual is %d", len(expectedEntryLookups), len(entryLookups))
@@ -247,30 +275,101 @@ func FixDatabase(out io.Writer, dbPath string, dryRun bool, bakPath string) erro
 
 	fmt.Fprintf(out, "Checksum: 0x%x\n", expectedChecksum)
 
-	// Check the hash table if present ------------------------------
-	hashTableOffset, err := safe.Uint64ToUint32(dbf.file.Offset())
+	// Check the extended checksum if present ----------------------
+	var fixExtChecksum []byte
+
+	if extChecksumHasher != nil {
+		fmt.Fprintf(out, "Checksum algorithm: %s\n", dbf.header.ChecksumAlgo)
+
+		checksumOffset, err := safe.Uint64ToUint32(dbf.file.Offset())
+		if err != nil {
+			return err
+		}
+
+		if dbf.header.ChecksumOffset != checksumOffset {
+			fixHeader.ChecksumOffset = checksumOffset
+			fmt.Fprintf(out, ">> Extended checksum offset is expected to be 0x%x, actual is 0x%x\n", checksumOffset, dbf.header.ChecksumOffset)
+		}
+
+		actual, err := readExtendedChecksumFrom(dbf.file, extChecksumHasher.Size())
+		if err != nil {
+			return fmt.Errorf("database is corrupted. %w", err)
+		}
+
+		expected := extChecksumHasher.Sum(nil)
+		if !bytes.Equal(expected, actual) {
+			fixExtChecksum = expected
+			fmt.Fprintf(out, ">> Extended checksum is expected to be 0x%x, actual is 0x%x\n", expected, actual)
+		}
+
+		fmt.Fprintf(out, "Extended checksum: 0x%x\n", expected)
+	}
+
+	// Check the directory statistics if present -------------------
+	dirStatsOffset, err := safe.Uint64ToUint32(dbf.file.Offset())
 	if err != nil {
 		return err
 	}
 
-	eof := false
+	buf, err := dbf.file.Peek(4)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to check for the directory statistics (1st sentinel). %w", err)
+	}
 
-	// 1st sentinel
-	_, err = io.ReadFull(dbf.file, s[:])
+	if bytes.Equal(buf, dirStatsSentinel[:]) {
+		fmt.Fprintln(out, "Directory statistics: Yes")
+
+		fixHeader.Features |= FeatureDirStats
+
+		if dirStatsOffset != dbf.header.DirStatsOffset {
+			fixHeader.DirStatsOffset = dirStatsOffset
+			fmt.Fprintf(out, ">> Directory statistics offset is expected to be 0x%x, actual is 0x%x\n", dirStatsOffset, dbf.header.DirStatsOffset)
+		}
+
+		fmt.Fprintf(out, "Directory statistics offset: 0x%x\n", dirStatsOffset)
+
+		table, err := readDirStatsFrom(context.Background(), dbf.file)
+		if err != nil {
+			return fmt.Errorf("database is corrupted. %w", err)
+		}
+
+		statsIndices := slices.Sorted(maps.Keys(table))
+		if !slices.Equal(dirIndices, statsIndices) {
+			return fmt.Errorf("database is corrupted. directory indices does not match the directory statistics indices")
+		}
+	} else {
+		if dbf.Features().HasDirStats() {
+			return fmt.Errorf("database is corrupted. expected the directory statistics to be present")
+		}
+		fmt.Fprintln(out, "Directory statistics: No")
+	}
+
+	// Check the hash table if present ------------------------------
+	hashTableOffset, err := safe.Uint64ToUint32(dbf.file.Offset())
 	if err != nil {
-		if errors.Is(err, io.EOF) {
-			eof = true
+		return err
+	}
 
-			if dbf.Features().HasHashTable() {
-				return fmt.Errorf("database is corrupted. expected a hash table to be present")
-			}
-			// this is fine, EOF and not expecting a hash table, continue
-		} else {
+	// The signature is the last section and thus follows directly when there is no hash table
+	buf, err = dbf.file.Peek(4)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read the hash table (1st sentinel). %w", err)
+	}
+	noHashTable := (len(buf) == 0) || bytes.Equal(buf, signatureSentinel[:])
+
+	if noHashTable {
+		if dbf.Features().HasHashTable() {
+			return fmt.Errorf("database is corrupted. expected a hash table to be present")
+		}
+		// this is fine, not expecting a hash table, continue
+		fmt.Fprintln(out, "Hash table: No")
+	} else {
+		// 1st sentinel
+		_, err = io.ReadFull(dbf.file, s[:])
+		if err != nil {
 			return fmt.Errorf("failed to read the hash table (1st sentinel). %w", err)
 		}
-	}
 
-	if !eof {
 		fmt.Fprintln(out, "Hash table: Yes")
 
 		// Hash table checks
@@ -292,7 +391,11 @@ func FixDatabase(out io.Writer, dbPath string, dryRun bool, bakPath string) erro
 			return fmt.Errorf("failed to read the hash table header. %w", err)
 		}
 
-		fmt.Fprintf(out, "Hash algorithm: %s\n", header.Algo)
+		if !validAlgo(header.Algo) {
+			return fmt.Errorf("database is corrupted. unknown hashing algorithm %d", header.Algo)
+		}
+
+		fmt.Fprintf(out, "Hash algorithm: %s\n", AlgoString(header.Algo))
 
 		if fileEntriesCount != header.EntriesCount {
 			return fmt.Errorf("database is corrupted. the number of hash table entries %d does not match the number of file path entries %d in the database", header.EntriesCount, fileEntriesCount)
@@ -300,9 +403,19 @@ func FixDatabase(out io.Writer, dbPath string, dryRun bool, bakPath string) erro
 
 		hashFileIndices := make([]uint32, 0, 64)
 
+		journal, err := readHashJournal(dbPath)
+		if err != nil {
+			return err
+		}
+		if journal != nil {
+			fmt.Fprintf(out, "Hash table journal: %d entries\n", len(journal))
+		}
+
 		for i := range header.EntriesCount {
+			offset := dbf.file.Offset()
+
 			entry := hashEntry{
-				Hash: header.Algo.Buffer(),
+				Hash: AlgoZeroValue(header.Algo),
 			}
 			if err := entry.read(dbf.file); err != nil {
 				if errors.Is(err, io.EOF) {
@@ -311,6 +424,12 @@ func FixDatabase(out io.Writer, dbPath string, dryRun bool, bakPath string) erro
 				return fmt.Errorf("failed to read the hash table entry at index %d. %w", i, err)
 			}
 			hashFileIndices = append(hashFileIndices, entry.Index)
+
+			if checksum, ok := journal[entry.Index]; ok && (crc32.ChecksumIEEE(entry.Hash) != checksum) {
+				fmt.Fprintf(out, ">> Hash table entry for index %d was not written completely and needs to be hashed again\n", entry.Index)
+				tornHashOffsets = append(tornHashOffsets, int64(offset)+4)
+				tornHashSize = len(entry.Hash)
+			}
 		}
 
 		// 2nd sentinel
@@ -328,15 +447,168 @@ func FixDatabase(out io.Writer, dbPath string, dryRun bool, bakPath string) erro
 		if !slices.Equal(fileIndices, hashFileIndices) {
 			return fmt.Errorf("database is corrupted. file indices does not match hash table's file indices")
 		}
+
+		// Check the hash times if present ------------------------------
+		hashTimesOffset, err := safe.Uint64ToUint32(dbf.file.Offset())
+		if err != nil {
+			return err
+		}
+
+		buf, err = dbf.file.Peek(4)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to check for the hash times (1st sentinel). %w", err)
+		}
+
+		if bytes.Equal(buf, hashTimesSentinel[:]) {
+			fmt.Fprintln(out, "Hash times: Yes")
+
+			fixHeader.Features |= FeatureHashTimes
+
+			if hashTimesOffset != dbf.header.HashTimesOffset {
+				fixHeader.HashTimesOffset = hashTimesOffset
+				fmt.Fprintf(out, ">> Hash times offset is expected to be 0x%x, actual is 0x%x\n", hashTimesOffset, dbf.header.HashTimesOffset)
+			}
+
+			fmt.Fprintf(out, "Hash times offset: 0x%x\n", hashTimesOffset)
+
+			header, err := readHashTimesHeader(dbf.file)
+			if err != nil {
+				return fmt.Errorf("database is corrupted. %w", err)
+			}
+			if fileEntriesCount != header.EntriesCount {
+				return fmt.Errorf("database is corrupted. the number of hash times %d does not match the number of file path entries %d in the database", header.EntriesCount, fileEntriesCount)
+			}
+
+			if _, err = dbf.file.Seek(int64(dbf.file.Offset())+int64(header.EntriesCount)*4, io.SeekStart); err != nil {
+				return fmt.Errorf("failed to read the hash times. %w", err)
+			}
+			dbf.file.ResetReadBuffer()
+
+			_, err = io.ReadFull(dbf.file, s[:])
+			if err != nil {
+				return fmt.Errorf("database is corrupted. failed to read the hash times (2nd sentinel). %w", err)
+			}
+			if s != hashTimesSentinel {
+				return fmt.Errorf("database is corrupted. hash times 2nd sentinel %q does not match %q", s, hashTimesSentinel)
+			}
+		} else {
+			if dbf.Features().HasHashTimes() {
+				return fmt.Errorf("database is corrupted. expected the hash times to be present")
+			}
+			fmt.Fprintln(out, "Hash times: No")
+		}
+
+		// Check the additional hash tables if present ------------------
+		extraHashesOffset, err := safe.Uint64ToUint32(dbf.file.Offset())
+		if err != nil {
+			return err
+		}
+
+		buf, err = dbf.file.Peek(4)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to check for the additional hash tables (1st sentinel). %w", err)
+		}
+
+		if bytes.Equal(buf, extraHashesSentinel[:]) {
+			fmt.Fprintln(out, "Additional hash tables: Yes")
+
+			fixHeader.Features |= FeatureExtraHashes
+
+			if extraHashesOffset != dbf.header.ExtraHashTablesOffset {
+				fixHeader.ExtraHashTablesOffset = extraHashesOffset
+				fmt.Fprintf(out, ">> Additional hash tables offset is expected to be 0x%x, actual is 0x%x\n", extraHashesOffset, dbf.header.ExtraHashTablesOffset)
+			}
+
+			fmt.Fprintf(out, "Additional hash tables offset: 0x%x\n", extraHashesOffset)
+
+			tables, err := readExtraHashTablesFrom(context.Background(), dbf.file, fileEntriesCount, func(ajhash.Algo) bool { return false })
+			if err != nil {
+				return fmt.Errorf("database is corrupted. %w", err)
+			}
+			for _, t := range tables {
+				fmt.Fprintf(out, "Additional hash table: %s\n", AlgoString(t.algo))
+			}
+		} else {
+			if dbf.Features().HasExtraHashes() {
+				return fmt.Errorf("database is corrupted. expected the additional hash tables to be present")
+			}
+			fmt.Fprintln(out, "Additional hash tables: No")
+		}
+	}
+
+	// Check the annotations if present -----------------------------
+	annotationsOffset, err := safe.Uint64ToUint32(dbf.file.Offset())
+	if err != nil {
+		return err
+	}
+
+	buf, err = dbf.file.Peek(4)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to check for the annotations (1st sentinel). %w", err)
+	}
+
+	if bytes.Equal(buf, annotationsSentinel[:]) {
+		fmt.Fprintln(out, "Annotations: Yes")
+
+		fixHeader.Features |= FeatureAnnotations
+
+		if annotationsOffset != dbf.header.AnnotationsOffset {
+			fixHeader.AnnotationsOffset = annotationsOffset
+			fmt.Fprintf(out, ">> Annotations offset is expected to be 0x%x, actual is 0x%x\n", annotationsOffset, dbf.header.AnnotationsOffset)
+		}
+
+		fmt.Fprintf(out, "Annotations offset: 0x%x\n", annotationsOffset)
+
+		annotations, err := readAnnotationsFrom(dbf.file)
+		if err != nil {
+			return fmt.Errorf("database is corrupted. %w", err)
+		}
+		fmt.Fprintf(out, "Annotations count: %d\n", annotations.Len())
 	} else {
-		fmt.Fprintln(out, "Hash table: No")
+		if dbf.Features().HasAnnotations() {
+			return fmt.Errorf("database is corrupted. expected the annotations to be present")
+		}
+		fmt.Fprintln(out, "Annotations: No")
+	}
+
+	// Check the signature if present -------------------------------
+	signatureOffset, err := safe.Uint64ToUint32(dbf.file.Offset())
+	if err != nil {
+		return err
+	}
+
+	buf, err = dbf.file.Peek(4)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to check for the signature (1st sentinel). %w", err)
+	}
+
+	if bytes.Equal(buf, signatureSentinel[:]) {
+		fmt.Fprintln(out, "Signature: Yes")
+
+		fixHeader.Features |= FeatureSignature
+
+		if signatureOffset != dbf.header.SignatureOffset {
+			fixHeader.SignatureOffset = signatureOffset
+			fmt.Fprintf(out, ">> Signature offset is expected to be 0x%x, actual is 0x%x\n", signatureOffset, dbf.header.SignatureOffset)
+		}
+
+		fmt.Fprintf(out, "Signature offset: 0x%x\n", signatureOffset)
+
+		if _, err := readSignatureFrom(dbf.file); err != nil {
+			return fmt.Errorf("database is corrupted. %w", err)
+		}
+	} else {
+		if dbf.Features().HasSignature() {
+			return fmt.Errorf("database is corrupted. expected the signature to be present")
+		}
+		fmt.Fprintln(out, "Signature: No")
 	}
 
 	if err := dbf.file.Close(); err != nil {
 		return err
 	}
 
-	needFixing := fixHeader != dbf.header
+	needFixing := (fixHeader != dbf.header) || (fixExtChecksum != nil) || (len(tornHashOffsets) > 0)
 
 	// Dry-run / validate finished, next is actual file changes
 	if dryRun {
@@ -352,7 +624,7 @@ func FixDatabase(out io.Writer, dbPath string, dryRun bool, bakPath string) erro
 
 	if !needFixing {
 		fmt.Fprintln(out, "Nothing to be fixed")
-		return nil
+		return removeHashJournal(dbPath)
 	}
 
 	// Make backup of the headers
@@ -376,12 +648,47 @@ func FixDatabase(out io.Writer, dbPath string, dryRun bool, bakPath string) erro
 	if err = fixHeader.write(f); err != nil {
 		return fmt.Errorf("failed to write the fixed header to the database. %w", err)
 	}
-
+	// Seeking does not write the buffered data
 	if err = f.Flush(); err != nil {
 		return err
 	}
 
-	return nil
+	if fixExtChecksum != nil {
+		_, err = f.Seek(int64(fixHeader.ChecksumOffset)+int64(len(checksumSentinel)), io.SeekStart)
+		if err != nil {
+			return err
+		}
+		f.ResetWriteBuffer()
+
+		if _, err = f.Write(fixExtChecksum); err != nil {
+			return fmt.Errorf("failed to write the fixed extended checksum to the database. %w", err)
+		}
+		if err = f.Flush(); err != nil {
+			return err
+		}
+	}
+
+	// Clearing the hashes makes "ajfs resume" calculate them again
+	zeroHash := make([]byte, tornHashSize)
+	for _, offset := range tornHashOffsets {
+		if _, err = f.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		f.ResetWriteBuffer()
+
+		if _, err = f.Write(zeroHash); err != nil {
+			return fmt.Errorf("failed to clear the hash table entry at offset 0x%x. %w", offset, err)
+		}
+		if err = f.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if err = f.Sync(); err != nil {
+		return err
+	}
+
+	return removeHashJournal(dbPath)
 }
 
 // Restore the headers from a backup file.
@@ -22,6 +22,8 @@ package db
 
 import (
 	"bytes"
+	"context"
+	"crypto/ed25519"
 	"fmt"
 	"io"
 	"io/fs"
@@ -72,7 +74,7 @@ func TestFixEmptyDatabase(t *testing.T) {
 	outStr := out.String()
 
 	exp1 := `Signature: AJFS
-Version: 1
+Version: 2
 Root: "/test"
 `
 	assert.Contains(t, outStr, exp1)
@@ -121,7 +123,7 @@ func TestFixEmptyDatabaseWithHashes(t *testing.T) {
 	outStr := out.String()
 
 	exp1 := `Signature: AJFS
-Version: 1
+Version: 2
 Root: "/test"
 `
 	assert.Contains(t, outStr, exp1)
@@ -157,7 +159,7 @@ func TestFixValidDatabase(t *testing.T) {
 	outStr := out.String()
 
 	exp1 := `Signature: AJFS
-Version: 1
+Version: 2
 Root: "/test"
 `
 	assert.Contains(t, outStr, exp1)
@@ -191,7 +193,7 @@ func TestFixValidDatabaseWithHashes(t *testing.T) {
 	outStr := out.String()
 
 	exp1 := `Signature: AJFS
-Version: 1
+Version: 2
 Root: "/test"
 `
 	assert.Contains(t, outStr, exp1)
@@ -209,6 +211,73 @@ Files: 10
 	assert.Contains(t, outStr, "Nothing to be fixed")
 }
 
+func TestFixZeroHeaderWithDirStats(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+	require.NoError(t, createTestDatabaseWithFeatures(tempFile, FeatureDirStats|FeatureHashTable, ChecksumSHA256))
+
+	expectedHeader, err := readHeader(tempFile)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, FixDatabase(&out, tempFile, true, ""))
+	outStr := out.String()
+	assert.NotContains(t, outStr, ">>")
+	assert.Contains(t, outStr, "Directory statistics: Yes")
+	assert.Contains(t, outStr, "Hash table: Yes")
+
+	// Damage database
+	require.NoError(t, replaceHeader(header{ChecksumAlgo: ChecksumSHA256}, tempFile))
+
+	out.Reset()
+	bakPath := tempFile + ".bak"
+	require.NoError(t, FixDatabase(&out, tempFile, false, bakPath))
+	outStr = out.String()
+	assert.Contains(t, outStr, ">> Directory statistics offset is expected to be")
+	assert.Contains(t, outStr, ">> Hash table offset is expected to be")
+
+	resultHeader, err := readHeader(tempFile)
+	require.NoError(t, err)
+	assert.Equal(t, expectedHeader, resultHeader)
+}
+
+func TestFixZeroHeaderWithSignature(t *testing.T) {
+	for _, features := range []FeatureFlags{FeatureJustEntries, FeatureHashTable} {
+		tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+		require.NoError(t, createTestDatabaseWithFeatures(tempFile, features, ChecksumCRC32))
+
+		_, key, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		require.NoError(t, SignDatabase(tempFile, key))
+
+		expectedHeader, err := readHeader(tempFile)
+		require.NoError(t, err)
+
+		var out bytes.Buffer
+		require.NoError(t, FixDatabase(&out, tempFile, true, ""))
+		outStr := out.String()
+		assert.NotContains(t, outStr, ">>")
+		assert.Contains(t, outStr, "Signature: Yes")
+
+		// Damage database
+		require.NoError(t, replaceHeader(header{}, tempFile))
+
+		out.Reset()
+		bakPath := tempFile + ".bak"
+		require.NoError(t, FixDatabase(&out, tempFile, false, bakPath))
+		assert.Contains(t, out.String(), ">> Signature offset is expected to be")
+
+		resultHeader, err := readHeader(tempFile)
+		require.NoError(t, err)
+		assert.Equal(t, expectedHeader, resultHeader)
+
+		// The signature is valid again
+		dbf, err := OpenDatabase(tempFile)
+		require.NoError(t, err)
+		assert.NoError(t, dbf.VerifySignature(nil))
+		require.NoError(t, dbf.Close())
+	}
+}
+
 func TestFixNotADatabase(t *testing.T) {
 	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
 	require.NoError(t, random.CreateFile(tempFile, 100))
@@ -358,6 +427,208 @@ func TestFixZeroHeaderWithHashes(t *testing.T) {
 	assert.Equal(t, expectedHeader, resultHeader)
 }
 
+func TestFixExtendedChecksum(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+	bakPath := tempFile + ".bak"
+
+	require.NoError(t, createTestDatabaseWithChecksum(tempFile, true, ChecksumSHA256))
+
+	// Nothing to fix
+	var out bytes.Buffer
+	require.NoError(t, FixDatabase(&out, tempFile, false, bakPath))
+	outStr := out.String()
+	assert.Contains(t, outStr, "Checksum algorithm: SHA-256")
+	assert.Contains(t, outStr, "Extended checksum:")
+	assert.Contains(t, outStr, "Hash table: Yes")
+	assert.NotContains(t, outStr, ">>")
+
+	// Damage the stored extended checksum
+	hdr, err := readHeader(tempFile)
+	require.NoError(t, err)
+	require.NotZero(t, hdr.ChecksumOffset)
+
+	f, err := os.OpenFile(tempFile, os.O_RDWR, 0)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte{0xDE, 0xAD, 0xBE, 0xEF}, int64(hdr.ChecksumOffset)+int64(len(checksumSentinel)))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	dbf, err := OpenDatabase(tempFile)
+	require.NoError(t, err)
+	assert.ErrorIs(t, dbf.VerifyChecksums(), ErrInvalidChecksum)
+	require.NoError(t, dbf.Close())
+
+	// Fix
+	out.Reset()
+	require.NoError(t, FixDatabase(&out, tempFile, false, bakPath))
+	assert.Contains(t, out.String(), ">> Extended checksum is expected to be")
+
+	dbf, err = OpenDatabase(tempFile)
+	require.NoError(t, err)
+	assert.NoError(t, dbf.VerifyChecksums())
+	require.NoError(t, dbf.Close())
+}
+
+func TestFixDirtyDatabase(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+	bakPath := tempFile + ".bak"
+
+	require.NoError(t, createTestDatabase(tempFile, false))
+
+	// Simulate a crash while resuming
+	dbf, err := ResumeDatabase(tempFile)
+	require.NoError(t, err)
+	require.NoError(t, dbf.file.Close())
+
+	_, err = OpenDatabase(tempFile)
+	require.ErrorIs(t, err, ErrDirty)
+
+	_, err = ResumeDatabase(tempFile)
+	require.ErrorIs(t, err, ErrDirty)
+
+	// Fix
+	var out bytes.Buffer
+	require.NoError(t, FixDatabase(&out, tempFile, false, bakPath))
+	assert.Contains(t, out.String(), ">> Database was not closed cleanly")
+
+	dbf, err = OpenDatabase(tempFile)
+	require.NoError(t, err)
+	assert.NoError(t, dbf.VerifyChecksums())
+	require.NoError(t, dbf.Close())
+}
+
+func TestFixTornHashEntry(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+	bakPath := tempFile + ".bak"
+
+	require.NoError(t, createTestDatabase(tempFile, true))
+
+	// Simulate a crash while writing the hash of index 6
+	dbf, err := ResumeDatabase(tempFile)
+	require.NoError(t, err)
+
+	hash5 := bytes.Repeat([]byte{0x55}, ajhash.AlgoSHA1.Size())
+	hash6 := bytes.Repeat([]byte{0x66}, ajhash.AlgoSHA1.Size())
+	require.NoError(t, dbf.WriteHashEntry(5, hash5))
+	require.NoError(t, dbf.WriteHashEntry(6, hash6))
+
+	offset := int64(dbf.createHashTable.offsets[6]) + 4 + 10
+	_, err = dbf.file.File().WriteAt(make([]byte, ajhash.AlgoSHA1.Size()-10), offset)
+	require.NoError(t, err)
+	require.NoError(t, dbf.file.Close())
+	assert.FileExists(t, journalPath(tempFile))
+
+	// Dry run
+	var out bytes.Buffer
+	require.Error(t, FixDatabase(&out, tempFile, true, bakPath))
+	assert.Contains(t, out.String(), "Hash table journal: 2 entries\n")
+	assert.Contains(t, out.String(), ">> Hash table entry for index 6 was not written completely")
+	assert.NotContains(t, out.String(), "index 5")
+
+	// Fix
+	out.Reset()
+	require.NoError(t, FixDatabase(&out, tempFile, false, bakPath))
+	assert.NoFileExists(t, journalPath(tempFile))
+
+	dbf, err = OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	hashes, err := dbf.ReadHashTable(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, hash5, hashes[5])
+	assert.NotContains(t, hashes, 6)
+}
+
+func TestHashJournalRemovedOnClose(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+	require.NoError(t, createTestDatabase(tempFile, true))
+
+	dbf, err := ResumeDatabase(tempFile)
+	require.NoError(t, err)
+	require.NoError(t, dbf.WriteHashEntry(5, bytes.Repeat([]byte{0x55}, ajhash.AlgoSHA1.Size())))
+	assert.FileExists(t, journalPath(tempFile))
+	require.NoError(t, dbf.Close())
+	assert.NoFileExists(t, journalPath(tempFile))
+}
+
+func TestSalvageTruncatedEntries(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+	require.NoError(t, createTestDatabase(tempFile, true))
+
+	dbf, err := OpenDatabase(tempFile)
+	require.NoError(t, err)
+	header := dbf.header
+	require.NoError(t, dbf.Close())
+
+	// Truncate in the middle of the entries
+	size := header.EntriesOffset + (header.EntriesLookupTableOffset-header.EntriesOffset)/2
+	require.NoError(t, os.Truncate(tempFile, int64(size)))
+
+	var out bytes.Buffer
+	require.Error(t, FixDatabase(&out, tempFile, true, ""))
+
+	out.Reset()
+	salvagePath := filepath.Join(t.TempDir(), "salvaged.ajfs")
+	require.NoError(t, SalvageDatabase(&out, tempFile, salvagePath))
+	assert.Contains(t, out.String(), "(truncated)")
+
+	salvaged, err := OpenDatabase(salvagePath)
+	require.NoError(t, err)
+	defer salvaged.Close()
+
+	require.NoError(t, salvaged.VerifyChecksums())
+	assert.False(t, salvaged.Features().HasHashTable())
+	assert.Greater(t, salvaged.EntriesCount(), 0)
+	assert.Less(t, salvaged.EntriesCount(), int(header.EntriesCount))
+	assert.Equal(t, "/test", salvaged.RootPath())
+
+	// The salvaged entries are the same as the first entries of the original
+	err = salvaged.ReadAllEntries(context.Background(), func(idx int, pi path.Info) error {
+		assert.Equal(t, pi.Id, path.IdFromPath(pi.Path))
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Won't override an existing file
+	assert.ErrorContains(t, SalvageDatabase(&out, tempFile, salvagePath), "already exists")
+}
+
+func TestSalvageTruncatedHashTable(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+	require.NoError(t, createTestDatabase(tempFile, true))
+
+	dbf, err := OpenDatabase(tempFile)
+	require.NoError(t, err)
+	expected, err := dbf.ReadHashTable(context.Background())
+	require.NoError(t, err)
+	require.NotEmpty(t, expected)
+	require.NoError(t, dbf.Close())
+
+	// Truncate the 2nd sentinel and part of the last hash table entry (which has not been calculated)
+	info, err := os.Stat(tempFile)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(tempFile, info.Size()-6))
+
+	var out bytes.Buffer
+	salvagePath := filepath.Join(t.TempDir(), "salvaged.ajfs")
+	require.NoError(t, SalvageDatabase(&out, tempFile, salvagePath))
+	assert.Contains(t, out.String(), "Entries: 15 (complete)")
+
+	salvaged, err := OpenDatabase(salvagePath)
+	require.NoError(t, err)
+	defer salvaged.Close()
+
+	assert.True(t, salvaged.Features().HasHashTable())
+	hashes, err := salvaged.ReadHashTable(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, expected, hashes)
+
+	out.Reset()
+	require.NoError(t, FixDatabase(&out, salvagePath, true, ""))
+	assert.Contains(t, out.String(), "Nothing to be fixed")
+}
+
 func TestRestoreDatabaseHeaderInvalidFile(t *testing.T) {
 	tempFile := filepath.Join(t.TempDir(), "unit-test.not-ajfs")
 	_ = os.Remove(tempFile)
@@ -419,13 +690,22 @@ func TestRestoreDatabaseHeader(t *testing.T) {
 //-----------------------------------------------------------------------------
 
 func createTestDatabase(dbPath string, hashTable bool) error {
-	// Create new database and write N path info objects
+	return createTestDatabaseWithChecksum(dbPath, hashTable, ChecksumCRC32)
+}
+
+func createTestDatabaseWithChecksum(dbPath string, hashTable bool, checksumAlgo ChecksumAlgo) error {
 	var features FeatureFlags = FeatureJustEntries
 	if hashTable {
 		features = FeatureHashTable
 	}
+	return createTestDatabaseWithFeatures(dbPath, features, checksumAlgo)
+}
+
+func createTestDatabaseWithFeatures(dbPath string, features FeatureFlags, checksumAlgo ChecksumAlgo) error {
+	// Create new database and write N path info objects
+	hashTable := features.HasHashTable()
 
-	dbf, err := CreateDatabase(dbPath, "/test", features)
+	dbf, err := CreateDatabaseWithChecksum(dbPath, "/test", features, checksumAlgo)
 	if err != nil {
 		return err
 	}
@@ -450,7 +730,7 @@ func createTestDatabase(dbPath string, hashTable bool) error {
 	expTime := time.Now().Add(-10 * time.Minute)
 
 	for i := range expCount {
-		filePath := fmt.Sprintf("/some/path/%d.txt", i)
+		filePath := fmt.Sprintf("some/path/%d.txt", i)
 		p := path.Info{
 			Id:      path.IdFromPath(filePath),
 			Path:    filePath,
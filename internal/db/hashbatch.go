@@ -0,0 +1,178 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package db
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// Writing every hash table entry as soon as it has been calculated requires a seek, write and flush per file,
+// which dominates the runtime on spinning disks when hashing many small files. When batching is enabled the
+// entries are kept in memory and written in groups that are sorted by offset, so that entries next to each
+// other in the hash table are written with a single write.
+// Entries that have not been written yet are lost when the process is killed, "ajfs resume" calculates
+// those hashes again.
+
+// Default interval at which batched hash table entries are written to the database.
+const DefaultHashFlushInterval = 2 * time.Second
+
+// Number of pending hash table entries after which the batch is written regardless of the interval.
+const maxPendingHashEntries = 4096
+
+// A hash table entry that has not been written to the database yet.
+type pendingHashEntry struct {
+	index        uint32
+	offset       uint32
+	hash         []byte
+	calculatedAt time.Time
+}
+
+type hashBatch struct {
+	interval  time.Duration
+	lastFlush time.Time
+	entries   []pendingHashEntry
+}
+
+// Keep the hash table entries written by [DatabaseFile.WriteHashEntry] in memory and write them to the
+// database at most every interval. A zero interval (the default) writes each entry immediately.
+// Pending entries are written by [DatabaseFile.FinishHashTable], [DatabaseFile.FlushHashEntries] and
+// [DatabaseFile.Close].
+func (dbf *DatabaseFile) SetHashFlushInterval(interval time.Duration) {
+	dbf.hashBatch.interval = max(interval, 0)
+	dbf.hashBatch.lastFlush = time.Now()
+}
+
+// Add the entry to the batch and write the batch when it is due.
+func (dbf *DatabaseFile) batchHashEntry(entry pendingHashEntry) error {
+	// The caller is free to reuse the hash once this returns
+	entry.hash = bytes.Clone(entry.hash)
+	dbf.hashBatch.entries = append(dbf.hashBatch.entries, entry)
+
+	if (len(dbf.hashBatch.entries) >= maxPendingHashEntries) ||
+		(time.Since(dbf.hashBatch.lastFlush) >= dbf.hashBatch.interval) {
+		return dbf.FlushHashEntries()
+	}
+	return nil
+}
+
+// Write the hash table entries that are still pending because of [DatabaseFile.SetHashFlushInterval].
+func (dbf *DatabaseFile) FlushHashEntries() error {
+	dbf.hashBatch.lastFlush = time.Now()
+	pending := dbf.hashBatch.entries
+	if len(pending) == 0 {
+		return nil
+	}
+	dbf.hashBatch.entries = pending[:0]
+
+	// When the same entry was written more than once, the last one must win
+	slices.SortStableFunc(pending, func(a, b pendingHashEntry) int {
+		return cmp.Compare(a.offset, b.offset)
+	})
+
+	if dbf.journal == nil {
+		var err error
+		dbf.journal, err = createHashJournal(dbf.path)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, p := range pending {
+		// Journal the entry first so that a partially written entry can be detected by FixDatabase
+		if err := dbf.journal.append(newJournalRecord(p.index, p.hash), dbf.journalCheckpointSync()); err != nil {
+			return fmt.Errorf("failed to write hash entry for index %d. %w", p.index, err)
+		}
+
+		if err := dbf.seekForBatchWrite(i == 0, int64(p.offset)); err != nil {
+			return fmt.Errorf("failed to write hash entry for index %d (file seek). %w", p.index, err)
+		}
+
+		entry := hashEntry{
+			Index: p.index,
+			Hash:  p.hash,
+		}
+		if err := entry.write(dbf.file); err != nil {
+			return fmt.Errorf("failed to write hash entry for index %d. %w", p.index, err)
+		}
+	}
+
+	if err := dbf.file.Flush(); err != nil {
+		return fmt.Errorf("failed to write the hash table entries. %w", err)
+	}
+
+	if !dbf.header.Features.HasHashTimes() {
+		return dbf.syncIfAlways()
+	}
+
+	// The hash times are stored in path entry order while the hash table can be sorted by hash
+	slices.SortStableFunc(pending, func(a, b pendingHashEntry) int {
+		return cmp.Compare(a.index, b.index)
+	})
+
+	for i, p := range pending {
+		offset, err := dbf.hashTimeOffset(p.index)
+		if err != nil {
+			return err
+		}
+		if err := dbf.seekForBatchWrite(i == 0, offset); err != nil {
+			return fmt.Errorf("failed to write the hash time for index %d (file seek). %w", p.index, err)
+		}
+		if err := dbf.writeHashTimeValue(p.index, p.calculatedAt); err != nil {
+			return err
+		}
+	}
+
+	if err := dbf.file.Flush(); err != nil {
+		return err
+	}
+	return dbf.syncIfAlways()
+}
+
+// Seek to the offset unless the previous write of the batch ended there.
+// Seeking does not flush the write buffer and thus it has to be flushed first.
+func (dbf *DatabaseFile) seekForBatchWrite(first bool, offset int64) error {
+	if !first && (uint64(offset) == dbf.file.Offset()) {
+		return nil
+	}
+
+	if err := dbf.file.Flush(); err != nil {
+		return err
+	}
+	if _, err := dbf.file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	dbf.file.ResetWriteBuffer()
+	return nil
+}
+
+// Write the buffered hash table entries and commit them to stable storage.
+// Called when the journal reaches a checkpoint.
+func (dbf *DatabaseFile) syncHashWrites() error {
+	if err := dbf.file.Flush(); err != nil {
+		return err
+	}
+	return dbf.file.Sync()
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchedHashEntries(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test/", db.FeatureHashTable|db.FeatureHashTimes)
+	require.NoError(t, err)
+
+	// More than a journal checkpoint interval to ensure the database is synced part way through a batch
+	count := 1500
+	for i := range count {
+		filePath := fmt.Sprintf("%d.txt", i)
+		pi := path.Info{
+			Id:      path.IdFromPath(filePath),
+			Path:    filePath,
+			Mode:    0644,
+			ModTime: time.Now(),
+		}
+		require.NoError(t, dbf.WriteEntry(&pi))
+	}
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.StartHashTable(ajhash.AlgoSHA1))
+	require.NoError(t, dbf.FinishHashTable())
+	require.NoError(t, dbf.Close())
+
+	dbf, err = db.ResumeDatabase(tempFile)
+	require.NoError(t, err)
+	dbf.SetHashFlushInterval(time.Hour)
+
+	computed := time.Date(2024, time.March, 2, 10, 30, 0, 0, time.UTC)
+	hash := ajhash.AlgoSHA1.ZeroValue()
+	// Written in reverse order, the batch is written sorted by offset
+	for i := count - 1; i >= 0; i-- {
+		hash[0] = byte(i%255 + 1)
+		require.NoError(t, dbf.WriteHashEntryAt(i, hash, computed.Add(time.Duration(i)*time.Second)))
+	}
+	// The last write of the same entry wins
+	hash[0] = 0xff
+	require.NoError(t, dbf.WriteHashEntryAt(7, hash, computed))
+	require.NoError(t, dbf.Close())
+
+	_, err = os.Stat(tempFile + ".journal")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+	require.NoError(t, dbf.VerifyChecksums())
+
+	hashTable, err := dbf.ReadHashTable(context.Background())
+	require.NoError(t, err)
+	require.Len(t, hashTable, count)
+	times, err := dbf.ReadHashTimes(context.Background())
+	require.NoError(t, err)
+	require.Len(t, times, count)
+
+	for i := range count {
+		expHash := byte(i%255 + 1)
+		expTime := computed.Add(time.Duration(i) * time.Second)
+		if i == 7 {
+			expHash = 0xff
+			expTime = computed
+		}
+		assert.Equal(t, expHash, hashTable[i][0], "index %d", i)
+		assert.True(t, expTime.Equal(times[i]), "index %d", i)
+	}
+}
+
+func TestBatchedHashEntriesFlushInterval(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test/", db.FeatureHashTable)
+	require.NoError(t, err)
+	for _, p := range []string{"a.txt", "b.txt"} {
+		pi := path.Info{Id: path.IdFromPath(p), Path: p, Mode: 0644}
+		require.NoError(t, dbf.WriteEntry(&pi))
+	}
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.StartHashTable(ajhash.AlgoSHA1))
+	require.NoError(t, dbf.FinishHashTable())
+	require.NoError(t, dbf.Close())
+
+	dbf, err = db.ResumeDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+	dbf.SetHashFlushInterval(time.Hour)
+
+	hash := ajhash.AlgoSHA1.ZeroValue()
+	hash[0] = 0x42
+	require.NoError(t, dbf.WriteHashEntry(0, hash))
+
+	// Not written until the batch is flushed
+	hashTable, err := dbf.ReadHashTable(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, hashTable)
+
+	require.NoError(t, dbf.FlushHashEntries())
+	hashTable, err = dbf.ReadHashTable(context.Background())
+	require.NoError(t, err)
+	require.Len(t, hashTable, 1)
+	assert.Equal(t, byte(0x42), hashTable[0][0])
+
+	// An elapsed interval writes the batch immediately
+	dbf.SetHashFlushInterval(time.Nanosecond)
+	hash[0] = 0x43
+	require.NoError(t, dbf.WriteHashEntry(1, hash))
+	hashTable, err = dbf.ReadHashTable(context.Background())
+	require.NoError(t, err)
+	require.Len(t, hashTable, 2)
+	assert.Equal(t, byte(0x43), hashTable[1][0])
+}
@@ -21,12 +21,18 @@
 package db
 
 import (
+	"cmp"
+	"context"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"io"
 	"maps"
+	"path/filepath"
 	"slices"
+	"strings"
+	"time"
 
 	"github.com/andrejacobs/ajfs/internal/path"
 	"github.com/andrejacobs/go-aj/ajhash"
@@ -34,7 +40,7 @@ import (
 )
 
 // file format
-// ... <entries and entries offset table>
+// ... <entries and entries lookup table>
 // sentinel
 // header
 // n * hashEntry, where n == number of file path entries
@@ -50,6 +56,9 @@ type createHashTable struct {
 	header hashTableHeader
 
 	offsets map[uint32]uint32 // map from path entry index to the hash offset
+
+	fileIndices   []uint32 // sorted path entry indices of the files, only used for the hash times
+	hashTimesBase int64    // Unix time that the hash times are relative to
 }
 
 // Start writing the initial hash table.
@@ -90,7 +99,7 @@ func (dbf *DatabaseFile) StartHashTable(algo ajhash.Algo) error {
 	}
 
 	// Write initial empty entries
-	zeroHash := algo.ZeroValue()
+	zeroHash := AlgoZeroValue(algo)
 	for _, idx := range dbf.fileIndices {
 		entry := hashEntry{
 			Index: idx,
@@ -114,6 +123,13 @@ func (dbf *DatabaseFile) StartHashTable(algo ajhash.Algo) error {
 		return fmt.Errorf("failed to write the hash table (1st sentinel). %w", err)
 	}
 
+	if dbf.createFeatures.HasHashTimes() {
+		dbf.createHashTable.fileIndices = dbf.fileIndices
+		if err := dbf.writeInitialHashTimes(); err != nil {
+			return err
+		}
+	}
+
 	if err := dbf.file.Flush(); err != nil {
 		return fmt.Errorf("failed to write the hash table. %w", err)
 	}
@@ -122,13 +138,21 @@ func (dbf *DatabaseFile) StartHashTable(algo ajhash.Algo) error {
 }
 
 // Write the file hash signature for the path info object with the specified index in the database.
+// The current time is recorded as the time the hash was calculated when the database has the hash times.
 // idx Index of the path info object.
 // hash The file hash signature.
 func (dbf *DatabaseFile) WriteHashEntry(idx int, hash []byte) error {
+	return dbf.WriteHashEntryAt(idx, hash, time.Now())
+}
+
+// Write the file hash signature that was calculated at the specified time, e.g. when copying the hashes
+// from another database. The zero time means the time is not known.
+// See [DatabaseFile.WriteHashEntry] for the other parameters.
+func (dbf *DatabaseFile) WriteHashEntryAt(idx int, hash []byte, calculatedAt time.Time) error {
 	dbf.panicIfNotWriting()
 
-	if len(hash) != dbf.createHashTable.header.Algo.Size() {
-		panic(fmt.Sprintf("invalid hash size %d, expected size %d", len(hash), dbf.createHashTable.header.Algo.Size()))
+	if len(hash) != AlgoSize(dbf.createHashTable.header.Algo) {
+		panic(fmt.Sprintf("invalid hash size %d, expected size %d", len(hash), AlgoSize(dbf.createHashTable.header.Algo)))
 	}
 
 	safeIdx, err := safe.IntToUint32(idx)
@@ -141,6 +165,26 @@ func (dbf *DatabaseFile) WriteHashEntry(idx int, hash []byte) error {
 		return fmt.Errorf("failed to write hash entry for index %d, no offset found", idx)
 	}
 
+	if dbf.hashBatch.interval > 0 {
+		return dbf.batchHashEntry(pendingHashEntry{
+			index:        safeIdx,
+			offset:       offset,
+			hash:         hash,
+			calculatedAt: calculatedAt,
+		})
+	}
+
+	// Journal the entry first so that a partially written entry can be detected by FixDatabase
+	if dbf.journal == nil {
+		dbf.journal, err = createHashJournal(dbf.path)
+		if err != nil {
+			return err
+		}
+	}
+	if err := dbf.journal.append(newJournalRecord(safeIdx, hash), dbf.journalCheckpointSync()); err != nil {
+		return fmt.Errorf("failed to write hash entry for index %d. %w", idx, err)
+	}
+
 	_, err = dbf.file.Seek(int64(offset), io.SeekStart)
 	if err != nil {
 		return fmt.Errorf("failed to write hash entry for index %d (file seek). %w", idx, err)
@@ -160,7 +204,13 @@ func (dbf *DatabaseFile) WriteHashEntry(idx int, hash []byte) error {
 		return fmt.Errorf("failed to write hash entry for index %d. %w", idx, err)
 	}
 
-	return nil
+	if dbf.header.Features.HasHashTimes() {
+		if err := dbf.writeHashTime(safeIdx, calculatedAt); err != nil {
+			return err
+		}
+	}
+
+	return dbf.syncIfAlways()
 }
 
 // Called by EntriesNeedHashing.
@@ -171,10 +221,10 @@ func (dbf *DatabaseFile) WriteHashEntry(idx int, hash []byte) error {
 type NeedHashingFn func(idx int, pi path.Info) error
 
 // Look at the hash table and call the passed function for each entry that need the file signature has to be still calculated.
-func (dbf *DatabaseFile) EntriesNeedHashing(fn NeedHashingFn) error {
+func (dbf *DatabaseFile) EntriesNeedHashing(ctx context.Context, fn NeedHashingFn) error {
 	indices := make([]int, 0, 512)
 
-	err := dbf.ReadHashTableEntries(func(idx int, hash []byte) error {
+	err := dbf.ReadHashTableEntries(ctx, func(idx int, hash []byte) error {
 		if ajhash.AllZeroBytes(hash) {
 			indices = append(indices, idx)
 		}
@@ -186,6 +236,10 @@ func (dbf *DatabaseFile) EntriesNeedHashing(fn NeedHashingFn) error {
 	}
 
 	for _, idx := range indices {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		pi, err := dbf.ReadEntryAtIndex(idx)
 		if err != nil {
 			return err
@@ -206,10 +260,18 @@ func (dbf *DatabaseFile) EntriesNeedHashing(fn NeedHashingFn) error {
 func (dbf *DatabaseFile) FinishHashTable() error {
 	dbf.panicIfNotWriting()
 
+	if err := dbf.FlushHashEntries(); err != nil {
+		return fmt.Errorf("failed to finish writing the hash table. %w", err)
+	}
+
 	if err := dbf.Flush(); err != nil {
 		return fmt.Errorf("failed to finish writing the hash table (flush). %w", err)
 	}
 
+	if err := dbf.syncIfAlways(); err != nil {
+		return fmt.Errorf("failed to finish writing the hash table (sync). %w", err)
+	}
+
 	return nil
 }
 
@@ -221,16 +283,27 @@ type ReadHashTableEntryFn func(idx int, hash []byte) error
 
 // Read all hash table entries from the database and call the callback function.
 // If the callback function returns [SkipAll] then the reading process will be stopped and nil will be returned as the error.
-func (dbf *DatabaseFile) ReadHashTableEntries(fn ReadHashTableEntryFn) error {
+// Reading stops with the context's error once the context is cancelled.
+func (dbf *DatabaseFile) ReadHashTableEntries(ctx context.Context, fn ReadHashTableEntryFn) error {
 	header, err := dbf.readHashTableHeader()
 	if err != nil {
 		return err
 	}
 
+	restore, err := dbf.startSequentialRead()
+	if err != nil {
+		return fmt.Errorf("failed to read hash table entries. %w", err)
+	}
+	defer restore()
+
 	// Read the hash entries
 	for i := range header.EntriesCount {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		entry := hashEntry{
-			Hash: header.Algo.Buffer(),
+			Hash: AlgoZeroValue(header.Algo),
 		}
 		if err := entry.read(dbf.file); err != nil {
 			return fmt.Errorf("failed to read the hash table entry at index %d. %w", i, err)
@@ -264,14 +337,14 @@ func (dbf *DatabaseFile) ReadHashTableEntries(fn ReadHashTableEntryFn) error {
 
 // Read the hash table.
 // Will only contain the entries for which a file signature hash was calculated.
-func (dbf *DatabaseFile) ReadHashTable() (HashTable, error) {
+func (dbf *DatabaseFile) ReadHashTable(ctx context.Context) (HashTable, error) {
 	if !dbf.Features().HasHashTable() {
 		panic("database does not contain the hash table")
 	}
 
 	result := make(HashTable, 64)
 
-	err := dbf.ReadHashTableEntries(func(idx int, hash []byte) error {
+	err := dbf.ReadHashTableEntries(ctx, func(idx int, hash []byte) error {
 		if !ajhash.AllZeroBytes(hash) {
 			result[idx] = hash
 		}
@@ -286,12 +359,12 @@ func (dbf *DatabaseFile) ReadHashTable() (HashTable, error) {
 type DuplicateHashes map[string][]uint32
 
 // Find all the hashes that are duplicates with the indices to those path info entries.
-func (dbf *DatabaseFile) FindDuplicateHashes() (DuplicateHashes, error) {
+func (dbf *DatabaseFile) FindDuplicateHashes(ctx context.Context) (DuplicateHashes, error) {
 	if !dbf.Features().HasHashTable() {
 		panic("database does not contain the hash table")
 	}
 
-	ht, err := dbf.ReadHashTable()
+	ht, err := dbf.ReadHashTable(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -326,43 +399,136 @@ func (dbf *DatabaseFile) FindDuplicateHashes() (DuplicateHashes, error) {
 }
 
 // FindDuplicatesFn will be called by FindDuplicates for each duplicate file that was found.
-// group Each of the same duplicates will belong to the same group.
+// group Is the identifier of the group the duplicate belongs to. It is the file signature hash for duplicates
+// that share the same hash (see [PotentialGroupId] for potential duplicates) and thus stays the same across runs.
 // idx Is the index of the entry.
 // pi Is the path info object.
 // hash Is the file signature hash (as a hex encoded string).
 // Return [SkipAll] to stop reading all the entries.
-type FindDuplicatesFn func(group int, idx int, pi path.Info, hash string) error
+type FindDuplicatesFn func(group string, idx int, pi path.Info, hash string) error
+
+// Return the identifier of the group of potential duplicates that share the same size and name.
+// The identifier is the hex encoded SHA-256 digest of the size and name and can thus be used in the same places as
+// a file signature hash (e.g. annotations and ignore files) while staying the same across runs.
+func PotentialGroupId(size uint64, name string) string {
+	h := sha256.New()
+	_ = binary.Write(h, binary.LittleEndian, size)
+	h.Write([]byte(name))
+	return hex.EncodeToString(h.Sum(nil))
+}
 
 // Find duplicate file entries that share the same file signature hash.
-func (dbf *DatabaseFile) FindDuplicates(fn FindDuplicatesFn) error {
+// The groups are ordered by hash and the entries within a group by index.
+// A sorted hash table (see [SortHashTable]) is grouped while streaming it, otherwise databases with more than
+// [ExternalSortThreshold] files are sorted using temporary files to bound the memory usage.
+func (dbf *DatabaseFile) FindDuplicates(ctx context.Context, fn FindDuplicatesFn) error {
 	if !dbf.Features().HasHashTable() {
 		panic("database does not contain the hash table")
 	}
 
-	dupes, err := dbf.FindDuplicateHashes()
+	if dbf.HashTableSorted() {
+		return dbf.findDuplicatesSorted(ctx, fn)
+	}
+	if dbf.FileEntriesCount() > ExternalSortThreshold {
+		return dbf.findDuplicatesExternal(ctx, fn)
+	}
+
+	dupes, err := dbf.FindDuplicateHashes(ctx)
 	if err != nil {
 		return err
 	}
 
 	keys := slices.Sorted(maps.Keys(dupes))
 
-	group := 0
 	for _, hashStr := range keys {
 		indices := dupes[hashStr]
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		for _, idx := range indices {
 			pi, err := dbf.ReadEntryAtIndex(int(idx))
 			if err != nil {
 				return err
 			}
 
-			if err = fn(group, int(idx), pi, hashStr); err != nil {
+			if err = fn(hashStr, int(idx), pi, hashStr); err != nil {
+				if err == SkipAll {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Count the number of file entries that do not yet have a calculated file signature hash.
+func (dbf *DatabaseFile) CountUnhashedEntries(ctx context.Context) (int, error) {
+	if !dbf.Features().HasHashTable() {
+		panic("database does not contain the hash table")
+	}
+
+	count := 0
+	err := dbf.ReadHashTableEntries(ctx, func(idx int, hash []byte) error {
+		if ajhash.AllZeroBytes(hash) {
+			count++
+		}
+		return nil
+	})
+
+	return count, err
+}
+
+// Find file entries without a calculated file signature hash that share the same size and name.
+// These are only potential duplicates since the file contents have not been compared.
+// The hash passed to the callback function will be an empty string.
+func (dbf *DatabaseFile) FindPotentialDuplicates(ctx context.Context, fn FindDuplicatesFn) error {
+	type sizeAndName struct {
+		size uint64
+		name string
+	}
+
+	groups := make(map[sizeAndName][]int, 64)
+	entries := make(map[int]path.Info, 64)
+
+	err := dbf.EntriesNeedHashing(ctx, func(idx int, pi path.Info) error {
+		key := sizeAndName{size: pi.Size, name: filepath.Base(pi.Path)}
+		groups[key] = append(groups[key], idx)
+		entries[idx] = pi
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	keys := slices.SortedFunc(maps.Keys(groups), func(l, r sizeAndName) int {
+		if c := strings.Compare(l.name, r.name); c != 0 {
+			return c
+		}
+		return cmp.Compare(l.size, r.size)
+	})
+
+	for _, key := range keys {
+		indices := groups[key]
+		if len(indices) < 2 {
+			continue
+		}
+		group := PotentialGroupId(key.size, key.name)
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		for _, idx := range indices {
+			if err := fn(group, idx, entries[idx], ""); err != nil {
 				if err == SkipAll {
 					return nil
 				}
 				return err
 			}
 		}
-		group++
 	}
 
 	return nil
@@ -377,17 +543,17 @@ type ReadAllEntriesWithHashesFn func(idx int, pi path.Info, hash []byte) error
 
 // Read all the path info objects along with their file signature hash from the database and call the callback function.
 // If the callback function returns [SkipAll] then the reading process will be stopped and nil will be returned as the error.
-func (dbf *DatabaseFile) ReadAllEntriesWithHashes(fn ReadAllEntriesWithHashesFn) error {
+func (dbf *DatabaseFile) ReadAllEntriesWithHashes(ctx context.Context, fn ReadAllEntriesWithHashesFn) error {
 	if !dbf.Features().HasHashTable() {
 		panic("database does not contain the hash table")
 	}
 
-	hashTable, err := dbf.ReadHashTable()
+	hashTable, err := dbf.ReadHashTable(ctx)
 	if err != nil {
 		return err
 	}
 
-	err = dbf.ReadAllEntries(func(idx int, pi path.Info) error {
+	err = dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
 		hash, ok := hashTable[idx]
 		if !ok {
 			return nil
@@ -412,6 +578,11 @@ func (dbf *DatabaseFile) readHashTableHeader() (hashTableHeader, error) {
 		panic("database contains no hash table")
 	}
 
+	if dbf.newerVersion() {
+		return hashTableHeader{}, fmt.Errorf("the hash table of the database %q is not supported (file format version %d, expected <= %d)",
+			dbf.path, dbf.prefixHeader.Version, currentVersion)
+	}
+
 	_, err := dbf.file.Seek(int64(dbf.header.HashTableOffset), io.SeekStart)
 	if err != nil {
 		return hashTableHeader{}, fmt.Errorf("failed to read hash table entries. %w", err)
@@ -434,6 +605,10 @@ func (dbf *DatabaseFile) readHashTableHeader() (hashTableHeader, error) {
 		return header, fmt.Errorf("failed to read the hash table header. %w", err)
 	}
 
+	if !validAlgo(header.Algo) {
+		return header, fmt.Errorf("the hash table uses an unknown hashing algorithm %d", header.Algo)
+	}
+
 	if dbf.header.FileEntriesCount != header.EntriesCount {
 		return header, fmt.Errorf("the number of hash table entries %d does not match the number of file path entries %d in the database", header.EntriesCount, dbf.header.FileEntriesCount)
 	}
@@ -454,7 +629,7 @@ func (dbf *DatabaseFile) resumeHashTable() error {
 		offsets: make(map[uint32]uint32, dbf.header.FileEntriesCount),
 	}
 
-	buffer := header.Algo.Buffer()
+	buffer := AlgoZeroValue(header.Algo)
 
 	// Read the hash entries and construct the offset map
 	for i := range header.EntriesCount {
@@ -483,6 +658,10 @@ func (dbf *DatabaseFile) resumeHashTable() error {
 		return fmt.Errorf("failed to read the hash table (2nd sentinel %q does not match %q)", s, hashTableSentinel)
 	}
 
+	if dbf.header.Features.HasHashTimes() {
+		return dbf.resumeHashTimes()
+	}
+
 	return nil
 }
 
@@ -493,28 +672,43 @@ func (dbf *DatabaseFile) resumeHashTable() error {
 type IdToHashMap map[path.Id][]byte
 
 // Build a map from a path's identifier to the file signature hash.
-func (dbf *DatabaseFile) BuildIdToHashMap() (IdToHashMap, error) {
-	result := make(IdToHashMap, dbf.EntriesCount())
+func (dbf *DatabaseFile) BuildIdToHashMap(ctx context.Context) (IdToHashMap, error) {
+	hashTable, err := dbf.ReadHashTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return dbf.idToHashMap(hashTable), nil
+}
 
-	err := dbf.ReadAllEntriesWithHashes(func(idx int, pi path.Info, hash []byte) error {
-		result[pi.Id] = hash
-		return nil
-	})
+// Build a map from a path's identifier to the hash calculated using the hashing algorithm.
+// Returns [ErrHashTableNotFound] if the database does not contain a hash table for the algorithm.
+func (dbf *DatabaseFile) BuildIdToHashMapWithAlgo(ctx context.Context, algo ajhash.Algo) (IdToHashMap, error) {
+	hashTable, err := dbf.ReadHashTableWithAlgo(ctx, algo)
 	if err != nil {
 		return nil, err
 	}
+	return dbf.idToHashMap(hashTable), nil
+}
 
-	return result, nil
+// The identifiers are taken from the entry lookup table, thus the path entries don't need to be read and decoded.
+func (dbf *DatabaseFile) idToHashMap(hashTable HashTable) IdToHashMap {
+	result := make(IdToHashMap, len(hashTable))
+	for idx, hash := range hashTable {
+		if idx < len(dbf.entryLookups) {
+			result[dbf.entryLookups[idx].Id] = hash
+		}
+	}
+	return result
 }
 
 // Map from a hash encoded string to the path entry index.
 type HashStrToIndexMap map[string]int
 
 // Build a map from a hash encoded string to the path entry index.
-func (dbf *DatabaseFile) BuildHashStrToIndexMap() (HashStrToIndexMap, error) {
+func (dbf *DatabaseFile) BuildHashStrToIndexMap(ctx context.Context) (HashStrToIndexMap, error) {
 	result := make(HashStrToIndexMap, dbf.EntriesCount())
 
-	ht, err := dbf.ReadHashTable()
+	ht, err := dbf.ReadHashTable(ctx)
 	if err != nil {
 		return nil, err
 	}
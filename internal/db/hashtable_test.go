@@ -21,6 +21,7 @@
 package db_test
 
 import (
+	"context"
 	"encoding/hex"
 	"errors"
 	"io/fs"
@@ -113,7 +114,7 @@ func TestWriteInitialHashTable(t *testing.T) {
 				assert.Equal(t, zeroValue, hash)
 				return nil
 			}
-			assert.NoError(t, dbf.ReadHashTableEntries(fn))
+			assert.NoError(t, dbf.ReadHashTableEntries(context.Background(), fn))
 			assert.Equal(t, dbf.FileEntriesCount(), count)
 		})
 	}
@@ -200,7 +201,7 @@ func TestWriteHashTable(t *testing.T) {
 
 			assert.True(t, dbf.Features().HasHashTable())
 
-			ht, err := dbf.ReadHashTable()
+			ht, err := dbf.ReadHashTable(context.Background())
 			require.NoError(t, err)
 			assert.Len(t, ht, dbf.FileEntriesCount())
 
@@ -267,14 +268,14 @@ func TestEntriesNeedHashing(t *testing.T) {
 
 	// Cause an error
 	expErr := errors.New("unit-testing err")
-	err = dbf.EntriesNeedHashing(func(idx int, pi path.Info) error {
+	err = dbf.EntriesNeedHashing(context.Background(), func(idx int, pi path.Info) error {
 		return expErr
 	})
 	require.ErrorIs(t, err, expErr)
 
 	// Skip
 	rcvIdx := make([]int, 0, 4)
-	err = dbf.EntriesNeedHashing(func(idx int, pi path.Info) error {
+	err = dbf.EntriesNeedHashing(context.Background(), func(idx int, pi path.Info) error {
 		rcvIdx = append(rcvIdx, idx)
 		return db.SkipAll
 	})
@@ -285,7 +286,7 @@ func TestEntriesNeedHashing(t *testing.T) {
 	rcvIdx = make([]int, 0, 4)
 	rcvPi := make([]path.Info, 0, 4)
 
-	err = dbf.EntriesNeedHashing(func(idx int, pi path.Info) error {
+	err = dbf.EntriesNeedHashing(context.Background(), func(idx int, pi path.Info) error {
 		rcvIdx = append(rcvIdx, idx)
 		rcvPi = append(rcvPi, pi)
 		return nil
@@ -305,7 +306,7 @@ func TestEntriesNeedHashing(t *testing.T) {
 	rcvIdx = make([]int, 0, 4)
 	rcvPi = make([]path.Info, 0, 4)
 
-	err = dbf.EntriesNeedHashing(func(idx int, pi path.Info) error {
+	err = dbf.EntriesNeedHashing(context.Background(), func(idx int, pi path.Info) error {
 		rcvIdx = append(rcvIdx, idx)
 		rcvPi = append(rcvPi, pi)
 		return nil
@@ -324,7 +325,7 @@ func TestEntriesNeedHashing(t *testing.T) {
 	rcvIdx = make([]int, 0, 4)
 	rcvPi = make([]path.Info, 0, 4)
 
-	err = dbf.EntriesNeedHashing(func(idx int, pi path.Info) error {
+	err = dbf.EntriesNeedHashing(context.Background(), func(idx int, pi path.Info) error {
 		rcvIdx = append(rcvIdx, idx)
 		rcvPi = append(rcvPi, pi)
 		return nil
@@ -349,9 +350,9 @@ func TestFindDuplicatesPanics(t *testing.T) {
 	require.NoError(t, err)
 	defer dbf.Close()
 
-	assert.Panics(t, func() { _, _ = dbf.FindDuplicateHashes() })
+	assert.Panics(t, func() { _, _ = dbf.FindDuplicateHashes(context.Background()) })
 	assert.Panics(t, func() {
-		_ = dbf.FindDuplicates(func(group int, idx int, pi path.Info, hash string) error { return nil })
+		_ = dbf.FindDuplicates(context.Background(), func(group string, idx int, pi path.Info, hash string) error { return nil })
 	})
 }
 
@@ -420,7 +421,7 @@ func TestFindDuplicates(t *testing.T) {
 	require.NoError(t, err)
 	defer dbf.Close()
 
-	dupes, err := dbf.FindDuplicateHashes()
+	dupes, err := dbf.FindDuplicateHashes(context.Background())
 	require.NoError(t, err)
 
 	assert.Len(t, dupes, 1)
@@ -429,8 +430,8 @@ func TestFindDuplicates(t *testing.T) {
 	expIndices := []uint32{0, 3}
 	assert.ElementsMatch(t, expIndices, indices)
 
-	err = dbf.FindDuplicates(func(group int, idx int, pi path.Info, hash string) error {
-		assert.Equal(t, 0, group)
+	err = dbf.FindDuplicates(context.Background(), func(group string, idx int, pi path.Info, hash string) error {
+		assert.Equal(t, hex.EncodeToString(h1), group)
 		switch idx {
 		case 0:
 			assert.True(t, p1.Equals(&pi))
@@ -444,6 +445,70 @@ func TestFindDuplicates(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestFindPotentialDuplicates(t *testing.T) {
+	algo := ajhash.AlgoSHA1
+
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+	_ = os.Remove(tempFile)
+	defer os.Remove(tempFile)
+
+	dbf, err := db.CreateDatabase(tempFile, "/test/", db.FeatureHashTable)
+	require.NoError(t, err)
+
+	entries := []path.Info{
+		{Path: "a.txt", Size: 42, Mode: 0740},
+		{Path: "some/dir", Size: 142, Mode: 0644 | fs.ModeDir},
+		{Path: "some/dir/a.txt", Size: 42, Mode: 0740},
+		{Path: "c.txt", Size: 442, Mode: 0740},
+		{Path: "some/dir/c.txt", Size: 100, Mode: 0740},
+		{Path: "b.txt", Size: 10, Mode: 0740},
+		{Path: "some/b.txt", Size: 10, Mode: 0740},
+	}
+	for _, pi := range entries {
+		pi.Id = path.IdFromPath(pi.Path)
+		pi.ModTime = time.Now()
+		require.NoError(t, dbf.WriteEntry(&pi))
+	}
+	require.NoError(t, dbf.FinishEntries())
+
+	assert.NoError(t, dbf.StartHashTable(algo))
+	assert.NoError(t, dbf.FinishHashTable())
+
+	// Only b.txt has a hash and is thus no longer a potential duplicate of some/b.txt
+	h := algo.Buffer()
+	require.NoError(t, random.SecureBytes(h))
+	require.NoError(t, dbf.WriteHashEntry(5, h))
+
+	assert.NoError(t, dbf.Close())
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	unhashed, err := dbf.CountUnhashedEntries(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 5, unhashed)
+
+	found := make(map[string][]string)
+	err = dbf.FindPotentialDuplicates(context.Background(), func(group string, idx int, pi path.Info, hash string) error {
+		assert.Empty(t, hash)
+		found[group] = append(found[group], pi.Path)
+		return nil
+	})
+	require.NoError(t, err)
+
+	expected := map[string][]string{
+		db.PotentialGroupId(42, "a.txt"): {"a.txt", "some/dir/a.txt"},
+	}
+	assert.Equal(t, expected, found)
+
+	// The identifier only depends on the size and name
+	assert.Len(t, db.PotentialGroupId(42, "a.txt"), 64)
+	assert.Equal(t, db.PotentialGroupId(42, "a.txt"), db.PotentialGroupId(42, "a.txt"))
+	assert.NotEqual(t, db.PotentialGroupId(42, "a.txt"), db.PotentialGroupId(43, "a.txt"))
+	assert.NotEqual(t, db.PotentialGroupId(42, "a.txt"), db.PotentialGroupId(42, "b.txt"))
+}
+
 func TestReadAllEntriesWithHashes(t *testing.T) {
 	algo := ajhash.AlgoSHA1
 
@@ -501,7 +566,7 @@ func TestReadAllEntriesWithHashes(t *testing.T) {
 	defer dbf.Close()
 
 	result := make([][]byte, 0, 2)
-	err = dbf.ReadAllEntriesWithHashes(func(idx int, pi path.Info, hash []byte) error {
+	err = dbf.ReadAllEntriesWithHashes(context.Background(), func(idx int, pi path.Info, hash []byte) error {
 		result = append(result, hash)
 		return nil
 	})
@@ -558,7 +623,7 @@ func TestBuildIdToHashMap(t *testing.T) {
 	require.NoError(t, err)
 	defer dbf.Close()
 
-	hm, err := dbf.BuildIdToHashMap()
+	hm, err := dbf.BuildIdToHashMap(context.Background())
 	require.NoError(t, err)
 	assert.Len(t, hm, 2)
 
@@ -618,7 +683,7 @@ func TestBuildHashStrToIndexMap(t *testing.T) {
 	require.NoError(t, err)
 	defer dbf.Close()
 
-	hm, err := dbf.BuildHashStrToIndexMap()
+	hm, err := dbf.BuildHashStrToIndexMap(context.Background())
 	require.NoError(t, err)
 	assert.Len(t, hm, 2)
 
@@ -0,0 +1,269 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package db
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/andrejacobs/go-aj/ajmath/safe"
+)
+
+// file format
+// ... <hash table>
+// sentinel
+// header
+// n * uint32, where n == number of file path entries (in the same order as the path entries)
+// sentinel
+//
+// Each entry is the number of seconds after header.Base at which the file signature hash was calculated
+// plus one, 0 means the hash has not been calculated. The entries have a fixed size so that they can be
+// updated in place while resuming and cover a range of 136 years.
+
+// HashTimes maps from the path info index of a file to the time its file signature hash was calculated.
+type HashTimes map[int]time.Time
+
+// The time that the hash times are relative to when creating a new database.
+var hashTimesEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+//-----------------------------------------------------------------------------
+// DatabaseFile
+
+// Write the hash times section with none of the times set.
+// Called by StartHashTable.
+func (dbf *DatabaseFile) writeInitialHashTimes() error {
+	var err error
+	dbf.header.HashTimesOffset, err = safe.Uint64ToUint32(dbf.file.Offset())
+	if err != nil {
+		return fmt.Errorf("failed to set the ajfs hash times offset. %w", err)
+	}
+
+	// Enable feature
+	dbf.header.Features |= FeatureHashTimes
+
+	// 1st sentinel
+	if _, err = dbf.file.Write(hashTimesSentinel[:]); err != nil {
+		return fmt.Errorf("failed to write the hash times (1st sentinel). %w", err)
+	}
+
+	header := hashTimesHeader{
+		Base:         hashTimesEpoch.Unix(),
+		EntriesCount: dbf.header.FileEntriesCount,
+	}
+	if err := header.write(dbf.file); err != nil {
+		return fmt.Errorf("failed to write the hash times header. %w", err)
+	}
+
+	var zero [4]byte
+	for range header.EntriesCount {
+		if _, err := dbf.file.Write(zero[:]); err != nil {
+			return fmt.Errorf("failed to write the initial hash times. %w", err)
+		}
+	}
+
+	// 2nd sentinel
+	if _, err = dbf.file.Write(hashTimesSentinel[:]); err != nil {
+		return fmt.Errorf("failed to write the hash times (2nd sentinel). %w", err)
+	}
+
+	dbf.createHashTable.hashTimesBase = header.Base
+	return nil
+}
+
+// Prepare to update the hash times while resuming.
+// Called by resumeHashTable once the hash table offsets are known.
+func (dbf *DatabaseFile) resumeHashTimes() error {
+	_, err := dbf.file.Seek(int64(dbf.header.HashTimesOffset), io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("failed to read the hash times. %w", err)
+	}
+	dbf.file.ResetReadBuffer()
+
+	header, err := readHashTimesHeader(dbf.file)
+	if err != nil {
+		return err
+	}
+	if header.EntriesCount != dbf.header.FileEntriesCount {
+		return fmt.Errorf("failed to read the hash times (expected %d entries, actual %d)", dbf.header.FileEntriesCount, header.EntriesCount)
+	}
+
+	dbf.createHashTable.hashTimesBase = header.Base
+	dbf.createHashTable.fileIndices = slices.Sorted(maps.Keys(dbf.createHashTable.offsets))
+	return nil
+}
+
+// Record the time at which the hash of the file with the path entry index was calculated.
+// Called by WriteHashEntryAt.
+func (dbf *DatabaseFile) writeHashTime(idx uint32, t time.Time) error {
+	offset, err := dbf.hashTimeOffset(idx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := dbf.file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to write the hash time for index %d (file seek). %w", idx, err)
+	}
+	dbf.file.ResetWriteBuffer()
+
+	if err := dbf.writeHashTimeValue(idx, t); err != nil {
+		return err
+	}
+
+	return dbf.file.Flush()
+}
+
+// Return the offset of the hash time of the file with the path entry index.
+func (dbf *DatabaseFile) hashTimeOffset(idx uint32) (int64, error) {
+	fileIndex, found := slices.BinarySearch(dbf.createHashTable.fileIndices, idx)
+	if !found {
+		return 0, fmt.Errorf("failed to write the hash time for index %d, not a file", idx)
+	}
+
+	return int64(dbf.header.HashTimesOffset) + int64(len(hashTimesSentinel)) + hashTimesHeaderSize() + int64(fileIndex)*4, nil
+}
+
+// Write the encoded hash time at the current offset.
+func (dbf *DatabaseFile) writeHashTimeValue(idx uint32, t time.Time) error {
+	value := encodeHashTime(t, dbf.createHashTable.hashTimesBase)
+	if err := binary.Write(dbf.file, binary.LittleEndian, value); err != nil {
+		return fmt.Errorf("failed to write the hash time for index %d. %w", idx, err)
+	}
+	return nil
+}
+
+// Read the times at which the file signature hashes were calculated.
+// Will only contain the entries for which the time is known.
+func (dbf *DatabaseFile) ReadHashTimes(ctx context.Context) (HashTimes, error) {
+	if !dbf.Features().HasHashTimes() {
+		panic("database does not contain the hash times")
+	}
+
+	// The hash table can be sorted by hash, so the file indices need to be sorted first
+	fileIndices := make([]int, 0, dbf.header.FileEntriesCount)
+	err := dbf.ReadHashTableEntries(ctx, func(idx int, hash []byte) error {
+		fileIndices = append(fileIndices, idx)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(fileIndices)
+
+	_, err = dbf.file.Seek(int64(dbf.header.HashTimesOffset), io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the hash times. %w", err)
+	}
+	dbf.file.ResetReadBuffer()
+
+	header, err := readHashTimesHeader(dbf.file)
+	if err != nil {
+		return nil, err
+	}
+	if int(header.EntriesCount) != len(fileIndices) {
+		return nil, fmt.Errorf("failed to read the hash times (expected %d entries, actual %d)", len(fileIndices), header.EntriesCount)
+	}
+
+	result := make(HashTimes, len(fileIndices))
+	for _, idx := range fileIndices {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var value uint32
+		if err := binary.Read(dbf.file, binary.LittleEndian, &value); err != nil {
+			return nil, fmt.Errorf("failed to read the hash time for index %d. %w", idx, err)
+		}
+		if value != 0 {
+			result[idx] = decodeHashTime(value, header.Base)
+		}
+	}
+
+	// Check 2nd sentinel
+	var s [4]byte
+	if _, err = io.ReadFull(dbf.file, s[:]); err != nil {
+		return nil, fmt.Errorf("failed to read the hash times (2nd sentinel). %w", err)
+	}
+	if s != hashTimesSentinel {
+		return nil, fmt.Errorf("failed to read the hash times (2nd sentinel %q does not match %q)", s, hashTimesSentinel)
+	}
+
+	return result, nil
+}
+
+// Read the hash times section header starting at the 1st sentinel.
+func readHashTimesHeader(r io.Reader) (hashTimesHeader, error) {
+	var s [4]byte
+	if _, err := io.ReadFull(r, s[:]); err != nil {
+		return hashTimesHeader{}, fmt.Errorf("failed to read the hash times (1st sentinel). %w", err)
+	}
+	if s != hashTimesSentinel {
+		return hashTimesHeader{}, fmt.Errorf("failed to read the hash times (1st sentinel %q does not match %q)", s, hashTimesSentinel)
+	}
+
+	header := hashTimesHeader{}
+	if err := header.read(r); err != nil {
+		return hashTimesHeader{}, fmt.Errorf("failed to read the hash times header. %w", err)
+	}
+	return header, nil
+}
+
+// Encode the time as the number of seconds after base plus one.
+// The zero time is encoded as 0 and times outside of the range are clamped.
+func encodeHashTime(t time.Time, base int64) uint32 {
+	if t.IsZero() {
+		return 0
+	}
+	delta := t.Unix() - base
+	return uint32(min(max(delta, 0), math.MaxUint32-1) + 1) //nolint:gosec // disable G115
+}
+
+func decodeHashTime(value uint32, base int64) time.Time {
+	return time.Unix(base+int64(value)-1, 0)
+}
+
+//-----------------------------------------------------------------------------
+
+type hashTimesHeader struct {
+	Base         int64  // Unix time in seconds that the entries are relative to
+	EntriesCount uint32 // Number of hash time entries
+}
+
+func (s *hashTimesHeader) read(r io.Reader) error {
+	return binary.Read(r, binary.LittleEndian, s)
+}
+
+func (s *hashTimesHeader) write(w io.Writer) error {
+	return binary.Write(w, binary.LittleEndian, s)
+}
+
+func hashTimesHeaderSize() int64 {
+	return int64(binary.Size(hashTimesHeader{}))
+}
+
+var (
+	hashTimesSentinel = [4]byte{0x41, 0x4A, 0x48, 0x54} // AJHT
+)
@@ -0,0 +1,101 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package db_test
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashTimes(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test/", db.FeatureHashTable|db.FeatureHashTimes)
+	require.NoError(t, err)
+
+	entries := []struct {
+		path string
+		mode fs.FileMode
+	}{
+		{path: ".", mode: fs.ModeDir},
+		{path: "1.txt"},
+		{path: "a", mode: fs.ModeDir},
+		{path: "a/2.txt"},
+		{path: "a/3.txt"},
+	}
+
+	for _, e := range entries {
+		pi := path.Info{
+			Id:      path.IdFromPath(e.path),
+			Path:    e.path,
+			Mode:    e.mode | 0755,
+			ModTime: time.Now(),
+		}
+		require.NoError(t, dbf.WriteEntry(&pi))
+	}
+
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.StartHashTable(ajhash.AlgoSHA1))
+	require.NoError(t, dbf.FinishHashTable())
+
+	computed := time.Date(2024, time.March, 2, 10, 30, 0, 0, time.UTC)
+	require.NoError(t, dbf.WriteHashEntryAt(3, bytes.Repeat([]byte{0xff}, ajhash.AlgoSHA1.Size()), computed))
+	require.NoError(t, dbf.Close())
+
+	// Resume and calculate another hash
+	dbf, err = db.ResumeDatabase(tempFile)
+	require.NoError(t, err)
+	before := time.Now().Truncate(time.Second)
+	require.NoError(t, dbf.WriteHashEntry(1, bytes.Repeat([]byte{0x11}, ajhash.AlgoSHA1.Size())))
+	require.NoError(t, dbf.Close())
+
+	// Sorting the hash table must not change which file a time belongs to
+	require.NoError(t, db.SortHashTable(context.Background(), tempFile))
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	assert.True(t, dbf.Features().HasHashTimes())
+	require.NoError(t, dbf.VerifyChecksums())
+
+	times, err := dbf.ReadHashTimes(context.Background())
+	require.NoError(t, err)
+	require.Len(t, times, 2)
+	assert.True(t, computed.Equal(times[3]))
+	assert.False(t, times[1].Before(before))
+	assert.False(t, times[1].After(time.Now()))
+
+	var out strings.Builder
+	require.NoError(t, db.FixDatabase(&out, tempFile, true, ""))
+	assert.Contains(t, out.String(), "Hash times: Yes")
+	assert.Contains(t, out.String(), "Nothing to be fixed")
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package db
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/random"
+)
+
+// IdMode determines how the identifiers of the path entries of a new database are derived.
+type IdMode int
+
+const (
+	// Derived from the relative path only. The databases of identical file hierarchies share the same identifiers,
+	// which is what diff relies on to pair up the entries.
+	IdModePath IdMode = iota
+	// Derived from the relative path, the host name and the absolute root path.
+	IdModeRoot
+	// Derived from the relative path and a random UUID.
+	IdModeUUID
+)
+
+// Stringer implementation.
+func (m IdMode) String() string {
+	switch m {
+	case IdModePath:
+		return "path"
+	case IdModeRoot:
+		return "root"
+	case IdModeUUID:
+		return "uuid"
+	}
+	return fmt.Sprintf("IdMode(%d)", int(m))
+}
+
+// Create the namespace used to derive the identifiers of a new database for the root path.
+// The namespace is empty for [IdModePath].
+// Namespaced identifiers are unique across databases, which is needed when the entries of several databases are
+// merged or catalogued together.
+func NewIdNamespace(mode IdMode, root string) (string, error) {
+	switch mode {
+	case IdModePath:
+		return "", nil
+
+	case IdModeRoot:
+		absRoot, err := filepath.Abs(root)
+		if err != nil {
+			return "", fmt.Errorf("failed to get the absolute root path from %q. %w", root, err)
+		}
+		host, err := os.Hostname()
+		if err != nil {
+			return "", fmt.Errorf("failed to get the host name. %w", err)
+		}
+		return "root:" + host + ":" + absRoot, nil
+
+	case IdModeUUID:
+		var b [16]byte
+		if err := random.SecureBytes(b[:]); err != nil {
+			return "", fmt.Errorf("failed to generate a UUID. %w", err)
+		}
+		b[6] = (b[6] & 0x0f) | 0x40 // Version 4
+		b[8] = (b[8] & 0x3f) | 0x80 // Variant RFC 9562
+		return fmt.Sprintf("uuid:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+	}
+
+	return "", fmt.Errorf("invalid identifier mode %v", mode)
+}
+
+// Return the namespace used to derive the identifiers of the path entries.
+// Empty when the identifiers are derived from the relative path only.
+func (dbf *DatabaseFile) IdNamespace() string {
+	return dbf.meta.IdNamespace
+}
+
+// Return the identifier this database uses for the relative path.
+func (dbf *DatabaseFile) IdFromPath(p string) path.Id {
+	return path.IdFromPathInNamespace(dbf.meta.IdNamespace, p)
+}
+
+// Return the identifier this database uses for an entry read from the other database.
+// The identifier is only derived again when the databases use different namespaces.
+func (dbf *DatabaseFile) IdOf(other *DatabaseFile, pi path.Info) path.Id {
+	if other.meta.IdNamespace == dbf.meta.IdNamespace {
+		return pi.Id
+	}
+	return dbf.IdFromPath(pi.Path)
+}
@@ -0,0 +1,212 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+// file format
+// indexCacheHeader
+// n * entryIdIndex, where n == number of file path entries (sorted by Id)
+// sentinel
+//
+// Opening a database requires the entry lookup table to be sorted by path identifier, which is slow for databases
+// with millions of entries. The sorted index can be kept in a sidecar file next to the database so that it only
+// needs to be built once per snapshot. The sidecar is tied to the database by the checksum stored in the header
+// and is rebuilt whenever the database no longer matches.
+
+// Return the path of the sidecar file used to cache the derived indexes of the database.
+func IndexCachePath(dbPath string) string {
+	return dbPath + ".idx"
+}
+
+// Is the sidecar index cache used and created when databases are opened.
+var indexCacheEnabled bool
+
+// Enable or disable the sidecar index cache for the databases opened from now on.
+// A missing or outdated cache is (re)created the first time the database is opened.
+func SetIndexCacheEnabled(enabled bool) {
+	indexCacheEnabled = enabled
+}
+
+// Load the entry identifier index from the sidecar cache.
+// Returns false if the cache does not exist or does not match the database.
+func (dbf *DatabaseFile) loadIndexCache() bool {
+	f, err := os.Open(IndexCachePath(dbf.path))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+
+	var header indexCacheHeader
+	if err := header.read(r); err != nil {
+		return false
+	}
+	if header != dbf.newIndexCacheHeader() {
+		return false
+	}
+
+	index := make([]entryIdIndex, header.EntriesCount)
+	if err := binary.Read(r, binary.LittleEndian, index); err != nil {
+		return false
+	}
+
+	var s [4]byte
+	if _, err := io.ReadFull(r, s[:]); err != nil || s != sentinel {
+		return false
+	}
+
+	if !dbf.validEntryIdIndex(index) {
+		return false
+	}
+
+	dbf.entryIdIndex = index
+	return true
+}
+
+// Check that the index is sorted and maps every identifier to its own entry exactly once.
+// This guards against a cache that was left behind by a different database with the same checksum.
+func (dbf *DatabaseFile) validEntryIdIndex(index []entryIdIndex) bool {
+	if len(index) != len(dbf.entryLookups) {
+		return false
+	}
+
+	seen := make([]bool, len(index))
+	for i, e := range index {
+		if (int(e.Index) >= len(index)) || seen[e.Index] || (dbf.entryLookups[e.Index].Id != e.Id) {
+			return false
+		}
+		seen[e.Index] = true
+
+		if (i > 0) && (bytes.Compare(index[i-1].Id[:], e.Id[:]) >= 0) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Write the entry identifier index to the sidecar cache.
+// The cache is written to a temporary file first so that a concurrent reader never sees a partial cache.
+func (dbf *DatabaseFile) writeIndexCache() error {
+	cachePath := IndexCachePath(dbf.path)
+	tmpPath := cachePath + ".tmp"
+
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create the index cache %q. %w", tmpPath, err)
+	}
+
+	err = func() error {
+		w := bufio.NewWriter(f)
+
+		header := dbf.newIndexCacheHeader()
+		if err := header.write(w); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.LittleEndian, dbf.entryIdIndex); err != nil {
+			return err
+		}
+		if _, err := w.Write(sentinel[:]); err != nil {
+			return err
+		}
+		return w.Flush()
+	}()
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write the index cache %q. %w", tmpPath, err)
+	}
+
+	if err := os.Rename(tmpPath, cachePath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename the index cache %q to %q. %w", tmpPath, cachePath, err)
+	}
+	return nil
+}
+
+// Build the entry identifier index by sorting the entry lookup table.
+// When the index cache is enabled, the cached index is used if it matches the database or else it is (re)created.
+func (dbf *DatabaseFile) buildEntryIdIndex() {
+	if indexCacheEnabled && dbf.loadIndexCache() {
+		return
+	}
+
+	dbf.entryIdIndex = make([]entryIdIndex, len(dbf.entryLookups))
+	for i := range dbf.entryLookups {
+		dbf.entryIdIndex[i] = entryIdIndex{
+			Id:    dbf.entryLookups[i].Id,
+			Index: uint32(i), //nolint:gosec // The number of entries is stored as an uint32
+		}
+	}
+
+	slices.SortFunc(dbf.entryIdIndex, func(a, b entryIdIndex) int {
+		return bytes.Compare(a.Id[:], b.Id[:])
+	})
+
+	if indexCacheEnabled {
+		// The cache is only an optimization, e.g. the database may be stored in a read-only location
+		_ = dbf.writeIndexCache()
+	}
+}
+
+//-----------------------------------------------------------------------------
+// Header
+
+const indexCacheVersion = uint16(1)
+
+var indexCacheSignature = [4]byte{'A', 'J', 'I', 'X'}
+
+type indexCacheHeader struct {
+	Signature    [4]byte
+	Version      uint16
+	_            uint16 // reserved
+	Checksum     uint32 // Checksum of the database the index was derived from
+	EntriesCount uint32 // Number of path entries in the database
+}
+
+func (dbf *DatabaseFile) newIndexCacheHeader() indexCacheHeader {
+	return indexCacheHeader{
+		Signature:    indexCacheSignature,
+		Version:      indexCacheVersion,
+		Checksum:     dbf.header.Checksum,
+		EntriesCount: dbf.header.EntriesCount,
+	}
+}
+
+func (s *indexCacheHeader) read(r io.Reader) error {
+	return binary.Read(r, binary.LittleEndian, s)
+}
+
+func (s *indexCacheHeader) write(w io.Writer) error {
+	return binary.Write(w, binary.LittleEndian, s)
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package db_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexCache(t *testing.T) {
+	db.SetIndexCacheEnabled(true)
+	t.Cleanup(func() { db.SetIndexCacheEnabled(false) })
+
+	dir := t.TempDir()
+	createDb := func(name string, count int) string {
+		dbPath := filepath.Join(dir, name)
+		dbf, err := db.CreateDatabase(dbPath, "/test", 0)
+		require.NoError(t, err)
+		for i := range count {
+			p := fmt.Sprintf("%s/path/%d.txt", name, i)
+			pi := path.Info{
+				Id:      path.IdFromPath(p),
+				Path:    p,
+				Size:    uint64(i),
+				ModTime: time.Now(),
+			}
+			require.NoError(t, dbf.WriteEntry(&pi))
+		}
+		require.NoError(t, dbf.FinishEntries())
+		require.NoError(t, dbf.Close())
+		return dbPath
+	}
+
+	checkLookups := func(dbPath string, name string, count int) {
+		dbf, err := db.OpenDatabase(dbPath)
+		require.NoError(t, err)
+		defer dbf.Close()
+
+		for i := range count {
+			v, err := dbf.FindEntryIndexAndOffset(path.IdFromPath(fmt.Sprintf("%s/path/%d.txt", name, i)))
+			require.NoError(t, err)
+			pi, err := dbf.ReadEntryAtIndex(int(v.Index))
+			require.NoError(t, err)
+			assert.Equal(t, uint64(i), pi.Size)
+		}
+	}
+
+	const count = 50
+	dbA := createDb("a.ajfs", count)
+	dbB := createDb("b.ajfs", count)
+	assert.NoFileExists(t, db.IndexCachePath(dbA))
+
+	// Created on first use
+	checkLookups(dbA, "a.ajfs", count)
+	require.FileExists(t, db.IndexCachePath(dbA))
+	cached, err := os.ReadFile(db.IndexCachePath(dbA))
+	require.NoError(t, err)
+
+	// Reused
+	checkLookups(dbA, "a.ajfs", count)
+	data, err := os.ReadFile(db.IndexCachePath(dbA))
+	require.NoError(t, err)
+	assert.Equal(t, cached, data)
+
+	// A cache from another database is rejected and rebuilt
+	require.NoError(t, os.WriteFile(db.IndexCachePath(dbB), cached, 0644))
+	checkLookups(dbB, "b.ajfs", count)
+	data, err = os.ReadFile(db.IndexCachePath(dbB))
+	require.NoError(t, err)
+	assert.NotEqual(t, cached, data)
+
+	// A damaged cache is rebuilt
+	require.NoError(t, os.WriteFile(db.IndexCachePath(dbA), cached[:len(cached)/2], 0644))
+	checkLookups(dbA, "a.ajfs", count)
+	data, err = os.ReadFile(db.IndexCachePath(dbA))
+	require.NoError(t, err)
+	assert.Equal(t, cached, data)
+}
+
+func TestIndexCacheDisabled(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "unit-test.ajfs")
+	dbf, err := db.CreateDatabase(dbPath, "/test", 0)
+	require.NoError(t, err)
+	pi := path.Info{Id: path.IdFromPath("a.txt"), Path: "a.txt", ModTime: time.Now()}
+	require.NoError(t, dbf.WriteEntry(&pi))
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.Close())
+
+	dbf, err = db.OpenDatabase(dbPath)
+	require.NoError(t, err)
+	_, err = dbf.FindEntryIndexAndOffset(pi.Id)
+	require.NoError(t, err)
+	require.NoError(t, dbf.Close())
+
+	assert.NoFileExists(t, db.IndexCachePath(dbPath))
+}
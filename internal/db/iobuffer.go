@@ -0,0 +1,87 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package db
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/andrejacobs/go-aj/ajio/trackedoffset"
+)
+
+// Size of the read-ahead buffer used while a section is read from start to end.
+const sequentialReadAheadSize = 1024 * 1024
+
+// Size in bytes of the read and write buffers of database files. 0 means the bufio default (4 KiB) is used.
+var ioBufferSize int
+
+// Set the size in bytes of the read and write buffers used by database files opened from now on.
+// A size of 0 or less restores the default size.
+// Larger buffers reduce the number of system calls which helps on spinning disks and network storage.
+func SetIOBufferSize(size int) {
+	ioBufferSize = max(size, 0)
+}
+
+// Replace the read and write buffers of the file with ones of the configured size.
+// Must be called before anything is read from or written to the file.
+func resizeIOBuffers(f *trackedoffset.File) {
+	if ioBufferSize <= 0 {
+		return
+	}
+	*f.Reader() = *bufio.NewReaderSize(f.File(), ioBufferSize)
+	*f.Writer() = *bufio.NewWriterSize(f.File(), ioBufferSize)
+}
+
+// Swap in a large read-ahead buffer while a section is read sequentially.
+// The file is repositioned at the current offset, thus any data buffered by the regular reader is discarded.
+// The returned function restores the regular read buffer.
+func (dbf *DatabaseFile) startSequentialRead() (func(), error) {
+	if err := dbf.syncReadPosition(); err != nil {
+		return nil, err
+	}
+
+	size := max(sequentialReadAheadSize, ioBufferSize)
+	if dbf.readAhead == nil || dbf.readAhead.Size() != size {
+		dbf.readAhead = bufio.NewReaderSize(dbf.file.File(), size)
+	} else {
+		dbf.readAhead.Reset(dbf.file.File())
+	}
+
+	r := dbf.file.Reader()
+	regular := *r
+	*r = *dbf.readAhead
+
+	return func() {
+		*r = regular
+		// The read-ahead moved the underlying file past the data that was actually consumed
+		_ = dbf.syncReadPosition()
+	}, nil
+}
+
+// Seek the underlying file to the tracked offset and discard any buffered data.
+func (dbf *DatabaseFile) syncReadPosition() error {
+	if _, err := dbf.file.Seek(int64(dbf.file.Offset()), io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek to offset %d. %w", dbf.file.Offset(), err)
+	}
+	dbf.file.ResetReadBuffer()
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIOBufferSize(t *testing.T) {
+	for _, size := range []int{0, 16, 4 * 1024 * 1024} {
+		t.Run(fmt.Sprintf("%d", size), func(t *testing.T) {
+			db.SetIOBufferSize(size)
+			t.Cleanup(func() { db.SetIOBufferSize(0) })
+
+			tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+			_ = os.Remove(tempFile)
+			defer os.Remove(tempFile)
+
+			dbf, err := db.CreateDatabase(tempFile, "/test", db.FeatureHashTable)
+			require.NoError(t, err)
+
+			expCount := 100
+			for i := range expCount {
+				filePath := fmt.Sprintf("some/path/%d.txt", i)
+				p := path.Info{
+					Id:      path.IdFromPath(filePath),
+					Path:    filePath,
+					Size:    uint64(i),
+					Mode:    0640,
+					ModTime: time.Now(),
+				}
+				require.NoError(t, dbf.WriteEntry(&p))
+			}
+			require.NoError(t, dbf.FinishEntries())
+
+			require.NoError(t, dbf.StartHashTable(ajhash.AlgoSHA1))
+			for i := range expCount {
+				hash := ajhash.AlgoSHA1.ZeroValue()
+				hash[0] = byte(i + 1)
+				require.NoError(t, dbf.WriteHashEntry(i, hash))
+			}
+			require.NoError(t, dbf.FinishHashTable())
+			require.NoError(t, dbf.Close())
+
+			dbf, err = db.OpenDatabase(tempFile)
+			require.NoError(t, err)
+			defer dbf.Close()
+			require.NoError(t, dbf.VerifyChecksums())
+
+			count := 0
+			err = dbf.ReadAllEntriesWithHashes(context.Background(), func(idx int, pi path.Info, hash []byte) error {
+				assert.Equal(t, fmt.Sprintf("some/path/%d.txt", idx), pi.Path)
+				assert.Equal(t, byte(idx+1), hash[0])
+				count++
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, expCount, count)
+
+			// Random access after a sequential read must not see data buffered by the read-ahead
+			pi, err := dbf.ReadEntryAtIndex(42)
+			require.NoError(t, err)
+			assert.Equal(t, "some/path/42.txt", pi.Path)
+
+			algo, err := dbf.HashTableAlgo()
+			require.NoError(t, err)
+			assert.Equal(t, ajhash.AlgoSHA1, algo)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package db
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// Hash table entries are overwritten in place and a crash part way through writing an entry would leave
+// behind a hash that looks valid. Before an entry is written, its index and the CRC-32 of the new hash are
+// appended to a journal file next to the database. "ajfs fix" uses the journal to find the entries that
+// were not written completely and clears them so that "ajfs resume" calculates their hashes again.
+// The journal is removed once the database has been closed cleanly.
+
+// Number of journal records after which the database is synced to disk and the journal is truncated.
+const journalCheckpointInterval = 1024
+
+// Return the path of the journal used while writing the hash table entries of the database.
+func journalPath(dbPath string) string {
+	return dbPath + ".journal"
+}
+
+// A hash table entry that is about to be written.
+type journalRecord struct {
+	Index    uint32 // Index of the path info entry.
+	Checksum uint32 // CRC-32 of the hash being written.
+}
+
+func newJournalRecord(idx uint32, hash []byte) journalRecord {
+	return journalRecord{Index: idx, Checksum: crc32.ChecksumIEEE(hash)}
+}
+
+type hashJournal struct {
+	file  *os.File
+	count int // Number of records since the last checkpoint
+}
+
+// Create a new (empty) journal for the database.
+func createHashJournal(dbPath string) (*hashJournal, error) {
+	f, err := os.OpenFile(journalPath(dbPath), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the hash table journal. %w", err)
+	}
+	return &hashJournal{file: f}, nil
+}
+
+// Append the record to the journal.
+// sync is called to write the database to disk before the journal is truncated at a checkpoint.
+// A nil sync function disables the checkpoints and thus the journal keeps growing.
+func (j *hashJournal) append(r journalRecord, sync func() error) error {
+	if (sync != nil) && (j.count >= journalCheckpointInterval) {
+		if err := sync(); err != nil {
+			return err
+		}
+		if err := j.file.Truncate(0); err != nil {
+			return fmt.Errorf("failed to truncate the hash table journal. %w", err)
+		}
+		if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to truncate the hash table journal. %w", err)
+		}
+		j.count = 0
+	}
+
+	// Unbuffered so that the record reaches the OS before the hash table entry is written
+	if err := binary.Write(j.file, binary.LittleEndian, r); err != nil {
+		return fmt.Errorf("failed to write to the hash table journal. %w", err)
+	}
+	j.count++
+	return nil
+}
+
+// Close and remove the journal.
+func (j *hashJournal) remove() error {
+	if err := j.file.Close(); err != nil {
+		return err
+	}
+	return os.Remove(j.file.Name())
+}
+
+// Read the records from the journal of the database, the last record of an index wins.
+// Returns nil when there is no journal. A record that was only partially written is ignored, since the
+// hash table entry is only written after the complete record.
+func readHashJournal(dbPath string) (map[uint32]uint32, error) {
+	f, err := os.Open(journalPath(dbPath))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open the hash table journal. %w", err)
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	result := make(map[uint32]uint32)
+	for {
+		r := journalRecord{}
+		if err := binary.Read(br, binary.LittleEndian, &r); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return result, nil
+			}
+			return nil, fmt.Errorf("failed to read the hash table journal. %w", err)
+		}
+		result[r.Index] = r.Checksum
+	}
+}
+
+// Remove the journal of the database if it exists.
+func removeHashJournal(dbPath string) error {
+	if err := os.Remove(journalPath(dbPath)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove the hash table journal. %w", err)
+	}
+	return nil
+}
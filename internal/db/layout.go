@@ -0,0 +1,95 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package db
+
+import (
+	"errors"
+	"fmt"
+)
+
+// LookupTableRevision identifies the format of the entries lookup table.
+type LookupTableRevision uint8
+
+const (
+	LookupTableNone      LookupTableRevision = iota // The database has no entries and thus no lookup table.
+	LookupTableRevision1                            // Sentinel, the identifier and file offset of each entry, sentinel.
+)
+
+// The marker that starts the entries lookup table of each revision.
+var lookupTableMarkers = map[[4]byte]LookupTableRevision{
+	sentinel: LookupTableRevision1,
+}
+
+// ErrUnknownLookupTableRevision is returned when the entries lookup table does not start with a known marker.
+var ErrUnknownLookupTableRevision = errors.New("unknown entries lookup table revision")
+
+// Stringer implementation.
+func (r LookupTableRevision) String() string {
+	switch r {
+	case LookupTableNone:
+		return "none"
+	case LookupTableRevision1:
+		return "revision 1 (identifier and offset of each entry)"
+	}
+	return fmt.Sprintf("revision %d", uint8(r))
+}
+
+// Determine the revision of the entries lookup table from the marker it starts with.
+func detectLookupTableRevision(marker [4]byte) (LookupTableRevision, error) {
+	if r, ok := lookupTableMarkers[marker]; ok {
+		return r, nil
+	}
+	return LookupTableNone, fmt.Errorf("%w (marker %q)", ErrUnknownLookupTableRevision, marker)
+}
+
+// Layout of the database file.
+type Layout struct {
+	Version             int                 // Version of the file format
+	LookupTableRevision LookupTableRevision // Format of the entries lookup table
+	Sections            []Section           // Sections in the order in which they are stored
+}
+
+// Return the layout of the database file.
+func (dbf *DatabaseFile) Layout() (Layout, error) {
+	sections, err := dbf.Sections()
+	if err != nil {
+		return Layout{}, err
+	}
+
+	result := Layout{
+		Version:  dbf.Version(),
+		Sections: sections,
+	}
+
+	if dbf.header.EntriesCount > 0 {
+		// ReadAt does not change the file offset shared by the other methods
+		var marker [4]byte
+		if _, err := dbf.file.File().ReadAt(marker[:], int64(dbf.header.EntriesLookupTableOffset)); err != nil {
+			return Layout{}, fmt.Errorf("failed to read the entry lookup table marker. path: %q. %w", dbf.path, err)
+		}
+		result.LookupTableRevision, err = detectLookupTableRevision(marker)
+		if err != nil {
+			return Layout{}, fmt.Errorf("failed to determine the layout of %q. %w", dbf.path, err)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayout(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test/", db.FeatureJustEntries)
+	require.NoError(t, err)
+	for i := range 10 {
+		filePath := fmt.Sprintf("some/path/%d.txt", i)
+		p := path.Info{
+			Id:   path.IdFromPath(filePath),
+			Path: filePath,
+			Size: 42,
+			Mode: 0740,
+		}
+		require.NoError(t, dbf.WriteEntry(&p))
+	}
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.Close())
+
+	expectLayout := func() {
+		dbf, err := db.OpenDatabase(tempFile)
+		require.NoError(t, err)
+		defer dbf.Close()
+
+		layout, err := dbf.Layout()
+		require.NoError(t, err)
+		assert.Equal(t, dbf.Version(), layout.Version)
+		assert.Equal(t, db.LookupTableRevision1, layout.LookupTableRevision)
+		assert.Equal(t, "revision 1 (identifier and offset of each entry)", layout.LookupTableRevision.String())
+
+		names := make([]string, 0, len(layout.Sections))
+		for _, s := range layout.Sections {
+			names = append(names, s.Name)
+		}
+		assert.Equal(t, []string{"header", "entries", "lookup table"}, names)
+	}
+	expectLayout()
+
+	// Rewriting the database writes the same revision
+	_, err = db.PruneDatabase(context.Background(), tempFile, func(idx int, pi path.Info, hash []byte) (bool, error) {
+		return pi.Path != "some/path/0.txt", nil
+	})
+	require.NoError(t, err)
+	expectLayout()
+
+	// An unknown revision is rejected
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	layout, err := dbf.Layout()
+	require.NoError(t, err)
+	require.NoError(t, dbf.Close())
+
+	f, err := os.OpenFile(tempFile, os.O_RDWR, 0)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte("AJ99"), int64(layout.Sections[2].Offset))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, err = db.OpenDatabase(tempFile)
+	assert.ErrorIs(t, err, db.ErrUnknownLookupTableRevision)
+}
+
+func TestLayoutWhenEmpty(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test/", db.FeatureJustEntries)
+	require.NoError(t, err)
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.Close())
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	layout, err := dbf.Layout()
+	require.NoError(t, err)
+	assert.Equal(t, db.LookupTableNone, layout.LookupTableRevision)
+	assert.Equal(t, "none", layout.LookupTableRevision.String())
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PathDisplay is the preferred way in which the paths of a database are displayed.
+type PathDisplay uint8
+
+const (
+	PathDisplayDefault  PathDisplay = iota // No preference has been stored.
+	PathDisplayFull                        // Display the paths prefixed with the root path.
+	PathDisplayRelative                    // Display the paths relative to the root path.
+)
+
+var pathDisplayNames = map[PathDisplay]string{
+	PathDisplayDefault:  "default",
+	PathDisplayFull:     "full",
+	PathDisplayRelative: "relative",
+}
+
+// Stringer implementation.
+func (p PathDisplay) String() string {
+	if name, ok := pathDisplayNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("pathDisplay(%d)", uint8(p))
+}
+
+// Parse the path display from its name, e.g. "full".
+func ParsePathDisplay(s string) (PathDisplay, error) {
+	for p, name := range pathDisplayNames {
+		if strings.EqualFold(s, name) {
+			return p, nil
+		}
+	}
+	return PathDisplayDefault, fmt.Errorf("invalid path display %q. valid values are full, relative and default", s)
+}
+
+// Store the preferred way in which the paths of the database are displayed.
+// Only the status flags in the header are changed, thus the checksum is not affected and a sealed database
+// can also be changed.
+func SetPathDisplay(dbPath string, p PathDisplay) error {
+	if _, exists := pathDisplayNames[p]; !exists {
+		return fmt.Errorf("invalid path display %d", p)
+	}
+
+	h, err := readHeader(dbPath)
+	if err != nil {
+		return err
+	}
+
+	if h.isDirty() {
+		return fmt.Errorf("%w. path: %q", ErrDirty, dbPath)
+	}
+
+	h.Status &^= statusFullPaths | statusRelativePaths
+	switch p {
+	case PathDisplayFull:
+		h.Status |= statusFullPaths
+	case PathDisplayRelative:
+		h.Status |= statusRelativePaths
+	}
+
+	if err = replaceHeader(h, dbPath); err != nil {
+		return fmt.Errorf("failed to update the ajfs header. path: %q. %w", dbPath, err)
+	}
+	return nil
+}
+
+// Returns the preferred way in which the paths of the database are displayed.
+// Only the headers are read which means this is cheap enough to be called before a command opens the database.
+func ReadPathDisplay(dbPath string) (PathDisplay, error) {
+	h, err := readHeader(dbPath)
+	if err != nil {
+		return PathDisplayDefault, err
+	}
+	return h.pathDisplay(), nil
+}
+
+// Returns the preferred way in which the paths of the database are displayed, see [SetPathDisplay].
+func (dbf *DatabaseFile) PathDisplay() PathDisplay {
+	return dbf.header.pathDisplay()
+}
+
+func (s *header) pathDisplay() PathDisplay {
+	switch {
+	case (s.Status & statusFullPaths) != 0:
+		return PathDisplayFull
+	case (s.Status & statusRelativePaths) != 0:
+		return PathDisplayRelative
+	default:
+		return PathDisplayDefault
+	}
+}
@@ -0,0 +1,279 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
+)
+
+// Change the root path stored in the database.
+// The root path is stored inside the checksummed section of the file and because its length can change
+// the database is rewritten (using the current file format version). The path entries, file signature
+// hashes (including the additional hash tables), meta entry and sealed status are kept the same. Any signature is removed.
+func SetRootPath(ctx context.Context, dbPath string, root string) error {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return fmt.Errorf("failed to get the absolute root path from %q. %w", root, err)
+	}
+
+	_, err = rewriteDatabase(ctx, dbPath, absRoot, nil)
+	return err
+}
+
+// KeepEntryFn will be called by PruneDatabase for each entry in the database.
+// idx Is the index of the entry.
+// pi Is the path info object.
+// hash Is the file signature hash or nil if it is not available.
+// Return true to keep the entry.
+type KeepEntryFn func(idx int, pi path.Info, hash []byte) (bool, error)
+
+// Remove the entries (and their file signature hashes) for which fn returns false.
+// The database is rewritten (using the current file format version) and the root path and meta entry are kept the same.
+// Any signature is removed.
+// Returns the number of entries that were removed.
+func PruneDatabase(ctx context.Context, dbPath string, fn KeepEntryFn) (int, error) {
+	return rewriteDatabase(ctx, dbPath, "", func(idx int, pi *path.Info, hash []byte) (bool, bool, error) {
+		keep, err := fn(idx, *pi, hash)
+		return keep, false, err
+	})
+}
+
+// RefreshEntryFn will be called by RefreshDatabase for each entry in the database.
+// idx Is the index of the entry.
+// pi Is the path info object which can be modified. The identifier and path must not be changed.
+// hash Is the file signature hash or nil if it is not available.
+// Return true to discard the file signature hash so that it will be calculated again (see ajfs resume).
+type RefreshEntryFn func(idx int, pi *path.Info, hash []byte) (bool, error)
+
+// Update the meta data (size, mode and last modification time) of the entries using fn.
+// The database is rewritten (using the current file format version) and the root path and meta entry are kept the same.
+// The directory statistics are calculated again. Any signature is removed.
+func RefreshDatabase(ctx context.Context, dbPath string, fn RefreshEntryFn) error {
+	_, err := rewriteDatabase(ctx, dbPath, "", func(idx int, pi *path.Info, hash []byte) (bool, bool, error) {
+		id, p := pi.Id, pi.Path
+		discardHash, err := fn(idx, pi, hash)
+		if (pi.Id != id) || (pi.Path != p) {
+			panic("the identifier and path of an entry can't be changed")
+		}
+		return true, discardHash, err
+	})
+	return err
+}
+
+// rewriteEntryFn is called by rewriteDatabase for each entry in the database and can modify pi.
+// Return whether the entry should be kept and whether its file signature hash should be discarded.
+type rewriteEntryFn func(idx int, pi *path.Info, hash []byte) (keep bool, discardHash bool, err error)
+
+// Rewrite the database to a temporary file which then replaces the original.
+// root is the new absolute root path, empty means the root path stays the same.
+// fn is used to determine which entries to keep, nil means all entries are kept.
+func rewriteDatabase(ctx context.Context, dbPath string, root string, fn rewriteEntryFn) (int, error) {
+	in, err := OpenDatabase(dbPath)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	if in.Limited() {
+		return 0, fmt.Errorf("can't rewrite %q because it can only be processed in a limited way", dbPath)
+	}
+
+	if root == "" {
+		root = in.RootPath()
+	} else if (root == in.RootPath()) && (fn == nil) {
+		return 0, nil
+	}
+
+	var hashTable HashTable
+	if in.Features().HasHashTable() {
+		hashTable, err = in.ReadHashTable(ctx)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var hashTimes HashTimes
+	if in.Features().HasHashTimes() {
+		hashTimes, err = in.ReadHashTimes(ctx)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	extraTables, err := in.readExtraHashTables(ctx, func(ajhash.Algo) bool { return true })
+	if err != nil {
+		return 0, err
+	}
+
+	annotations, err := in.ReadAnnotations()
+	if err != nil {
+		return 0, err
+	}
+	// Only the annotations of the entries that are kept will be copied
+	keptAnnotations := NewAnnotations()
+	keptAnnotations.Groups = annotations.Groups
+
+	tmpPath := dbPath + ".rewrite.tmp"
+	if err := os.Remove(tmpPath); err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("failed to remove the temporary file %q. %w", tmpPath, err)
+	}
+
+	// The content changes and thus an existing signature would no longer be valid.
+	// The additional hash tables and annotations are added once the database has been created.
+	features := in.Features() &^ (FeatureSignature | FeatureExtraHashes | FeatureAnnotations)
+
+	meta := in.Meta()
+	out, err := createDatabase(tmpPath, root, features, in.ChecksumAlgo(), &meta, currentVersion)
+	if err != nil {
+		return 0, err
+	}
+	if in.Archives() {
+		out.SetArchives()
+	}
+
+	// Called when an error happened and the incomplete database needs to be removed
+	errFn := func(rcvErr error) error {
+		if err := out.Interrupted(); err != nil {
+			return fmt.Errorf("failed to remove the temporary file %q with error (%w). original error: %w", tmpPath, err, rcvErr)
+		}
+		return rcvErr
+	}
+
+	// inIndices[outIdx] is the index of the same entry in the original database
+	inIndices := make([]int, 0, in.EntriesCount())
+
+	err = in.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		if fn != nil {
+			keep, discardHash, err := fn(idx, &pi, hashTable[idx])
+			if err != nil {
+				return err
+			}
+			if !keep {
+				return nil
+			}
+			if discardHash {
+				delete(hashTable, idx)
+				for _, t := range extraTables {
+					delete(t.table, idx)
+				}
+			}
+		}
+
+		if a, exists := annotations.Entries[pi.Id]; exists {
+			keptAnnotations.Entries[pi.Id] = a
+		}
+
+		inIndices = append(inIndices, idx)
+		return out.WriteEntry(&pi)
+	})
+	if err != nil {
+		return 0, errFn(fmt.Errorf("failed to copy the entries from %q. %w", dbPath, err))
+	}
+
+	if err = out.FinishEntries(); err != nil {
+		return 0, errFn(err)
+	}
+
+	if in.Features().HasHashTable() {
+		algo, err := in.HashTableAlgo()
+		if err != nil {
+			return 0, errFn(err)
+		}
+
+		if err = out.StartHashTable(algo); err != nil {
+			return 0, errFn(err)
+		}
+
+		if err = out.FinishHashTable(); err != nil {
+			return 0, errFn(err)
+		}
+
+		for outIdx, inIdx := range inIndices {
+			hash, exists := hashTable[inIdx]
+			if !exists {
+				continue
+			}
+
+			if err = out.WriteHashEntryAt(outIdx, hash, hashTimes[inIdx]); err != nil {
+				return 0, errFn(fmt.Errorf("failed to copy the file signature hashes from %q. %w", dbPath, err))
+			}
+		}
+	}
+
+	removed := in.EntriesCount() - len(inIndices)
+
+	if err = out.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return 0, err
+	}
+
+	for _, t := range extraTables {
+		table := make(HashTable, len(t.table))
+		for outIdx, inIdx := range inIndices {
+			if hash, exists := t.table[inIdx]; exists {
+				table[outIdx] = hash
+			}
+		}
+
+		if err = AddHashTable(ctx, tmpPath, t.algo, table); err != nil {
+			_ = os.Remove(tmpPath)
+			return 0, err
+		}
+	}
+
+	if keptAnnotations.Len() > 0 {
+		if err = SetAnnotations(tmpPath, keptAnnotations); err != nil {
+			_ = os.Remove(tmpPath)
+			return 0, err
+		}
+	}
+
+	if in.Sealed() {
+		if err = SetSealed(tmpPath, true); err != nil {
+			_ = os.Remove(tmpPath)
+			return 0, err
+		}
+	}
+
+	if pd := in.PathDisplay(); pd != PathDisplayDefault {
+		if err = SetPathDisplay(tmpPath, pd); err != nil {
+			_ = os.Remove(tmpPath)
+			return 0, err
+		}
+	}
+
+	if err = in.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return 0, err
+	}
+
+	if err = os.Rename(tmpPath, dbPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return 0, fmt.Errorf("failed to replace %q with %q. %w", dbPath, tmpPath, err)
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/andrejacobs/go-aj/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetRootPath(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	// Create new database with a hash table
+	dbf, err := db.CreateDatabaseWithChecksum(tempFile, "/test", db.FeatureHashTable, db.ChecksumSHA256)
+	require.NoError(t, err)
+
+	const count = 10
+	for i := range count {
+		p := fmt.Sprintf("some/path/%d.txt", i)
+		pi := path.Info{
+			Id:      path.IdFromPath(p),
+			Path:    p,
+			Size:    uint64(i),
+			Mode:    0740,
+			ModTime: time.Now().Add(-10 * time.Minute),
+		}
+		require.NoError(t, dbf.WriteEntry(&pi))
+	}
+	require.NoError(t, dbf.FinishEntries())
+
+	algo := ajhash.AlgoSHA1
+	require.NoError(t, dbf.StartHashTable(algo))
+	for _, idx := range []int{1, 5, 9} {
+		h := make([]byte, algo.Size())
+		require.NoError(t, random.SecureBytes(h))
+		require.NoError(t, dbf.WriteHashEntry(idx, h))
+	}
+	require.NoError(t, dbf.FinishHashTable())
+	require.NoError(t, dbf.Close())
+
+	// Capture what is expected to stay the same
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	expMeta := dbf.Meta()
+	expEntries, err := dbf.BuildIdToInfoMap(context.Background())
+	require.NoError(t, err)
+	expHashes, err := dbf.ReadHashTable(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, dbf.Close())
+
+	// Change the root path
+	require.NoError(t, db.SetRootPath(context.Background(), tempFile, "/mnt/new-root"))
+	assert.NoFileExists(t, tempFile+".rewrite.tmp")
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	expRoot, err := filepath.Abs("/mnt/new-root")
+	require.NoError(t, err)
+	assert.Equal(t, expRoot, dbf.RootPath())
+	assert.NoError(t, dbf.VerifyChecksums())
+	assert.Equal(t, db.ChecksumSHA256, dbf.ChecksumAlgo())
+	assert.True(t, expMeta.CreatedAt.Equal(dbf.Meta().CreatedAt))
+	assert.Equal(t, expMeta.Tool, dbf.Meta().Tool)
+
+	entries, err := dbf.BuildIdToInfoMap(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, entries, count)
+	for id, exp := range expEntries {
+		pi := entries[id]
+		assert.Equal(t, exp.Path, pi.Path)
+		assert.Equal(t, exp.Size, pi.Size)
+		assert.True(t, exp.ModTime.Equal(pi.ModTime))
+	}
+
+	hashes, err := dbf.ReadHashTable(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, expHashes, hashes)
+}
+
+func TestSetRootPathMissingDatabase(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "does-not-exist.ajfs")
+	assert.Error(t, db.SetRootPath(context.Background(), tempFile, "/mnt/new-root"))
+	assert.NoFileExists(t, tempFile+".rewrite.tmp")
+}
+
+func TestPruneDatabase(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test", db.FeatureHashTable)
+	require.NoError(t, err)
+	dbf.SetArchives()
+
+	const count = 10
+	for i := range count {
+		p := fmt.Sprintf("some/path/%d.txt", i)
+		pi := path.Info{
+			Id:      path.IdFromPath(p),
+			Path:    p,
+			Size:    uint64(i),
+			Mode:    0740,
+			ModTime: time.Now().Add(-10 * time.Minute),
+		}
+		require.NoError(t, dbf.WriteEntry(&pi))
+	}
+	require.NoError(t, dbf.FinishEntries())
+
+	algo := ajhash.AlgoSHA1
+	require.NoError(t, dbf.StartHashTable(algo))
+	expHashes := make(map[string][]byte)
+	for idx := range count {
+		h := make([]byte, algo.Size())
+		require.NoError(t, random.SecureBytes(h))
+		require.NoError(t, dbf.WriteHashEntry(idx, h))
+		expHashes[fmt.Sprintf("some/path/%d.txt", idx)] = h
+	}
+	require.NoError(t, dbf.FinishHashTable())
+	require.NoError(t, dbf.Close())
+
+	// Remove the odd entries
+	removed, err := db.PruneDatabase(context.Background(), tempFile, func(idx int, pi path.Info, hash []byte) (bool, error) {
+		assert.Equal(t, expHashes[pi.Path], hash)
+		return pi.Size%2 == 0, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, count/2, removed)
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	assert.Equal(t, "/test", dbf.RootPath())
+	assert.NoError(t, dbf.VerifyChecksums())
+	assert.Equal(t, count/2, dbf.EntriesCount())
+	assert.True(t, dbf.Archives())
+
+	err = dbf.ReadAllEntriesWithHashes(context.Background(), func(idx int, pi path.Info, hash []byte) error {
+		assert.Zero(t, pi.Size%2)
+		assert.Equal(t, path.IdFromPath(pi.Path), pi.Id)
+		assert.Equal(t, expHashes[pi.Path], hash)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Errors stop the process and leave the database untouched
+	_, err = db.PruneDatabase(context.Background(), tempFile, func(idx int, pi path.Info, hash []byte) (bool, error) {
+		return false, fmt.Errorf("simulated error")
+	})
+	assert.Error(t, err)
+	assert.NoFileExists(t, tempFile+".rewrite.tmp")
+}
+
+func TestRefreshDatabase(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test", db.FeatureHashTable)
+	require.NoError(t, err)
+
+	const count = 4
+	for i := range count {
+		p := fmt.Sprintf("some/path/%d.txt", i)
+		pi := path.Info{
+			Id:      path.IdFromPath(p),
+			Path:    p,
+			Size:    uint64(i),
+			Mode:    0740,
+			ModTime: time.Now().Add(-10 * time.Minute),
+		}
+		require.NoError(t, dbf.WriteEntry(&pi))
+	}
+	require.NoError(t, dbf.FinishEntries())
+
+	algo := ajhash.AlgoSHA1
+	require.NoError(t, dbf.StartHashTable(algo))
+	for idx := range count {
+		h := make([]byte, algo.Size())
+		require.NoError(t, random.SecureBytes(h))
+		require.NoError(t, dbf.WriteHashEntry(idx, h))
+	}
+	require.NoError(t, dbf.FinishHashTable())
+	require.NoError(t, dbf.Close())
+
+	// Change the mode of all entries and discard the hash of the even entries
+	err = db.RefreshDatabase(context.Background(), tempFile, func(idx int, pi *path.Info, hash []byte) (bool, error) {
+		pi.Mode = 0600
+		return pi.Size%2 == 0, nil
+	})
+	require.NoError(t, err)
+	assert.NoFileExists(t, tempFile+".rewrite.tmp")
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	assert.NoError(t, dbf.VerifyChecksums())
+	assert.Equal(t, count, dbf.EntriesCount())
+
+	hashes, err := dbf.ReadHashTable(context.Background())
+	require.NoError(t, err)
+	err = dbf.ReadAllEntries(context.Background(), func(idx int, pi path.Info) error {
+		assert.Equal(t, os.FileMode(0600), pi.Mode)
+		_, hashed := hashes[idx]
+		assert.Equal(t, pi.Size%2 != 0, hashed)
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,223 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package db
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/andrejacobs/go-aj/ajio/trackedoffset"
+)
+
+// SalvageDatabase copies all the path entries that can be read completely (and any complete file signature hashes)
+// from a damaged database into a new database. This is intended for databases that were truncated, e.g. a scan that
+// crashed while writing the entries, which can't be repaired by [FixDatabase].
+// out is used to display information to the user.
+// dbPath is the file path to the damaged database.
+// outPath is the file path of the new database to be created.
+func SalvageDatabase(out io.Writer, dbPath string, outPath string) error {
+	if _, err := os.Stat(outPath); err == nil {
+		return fmt.Errorf("failed to salvage the database because a file already exists at %q", outPath)
+	}
+
+	dbf := &DatabaseFile{
+		path: dbPath,
+	}
+
+	var err error
+	dbf.file, err = trackedoffset.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open the ajfs database file. path: %q. %w", dbPath, err)
+	}
+	defer dbf.file.Close()
+	resizeIOBuffers(dbf.file)
+
+	// The headers, root and meta are required to create the new database
+	if err := dbf.prefixHeader.read(dbf.file); err != nil {
+		return fmt.Errorf("error reading the ajfs prefix header. path: %q. %w", dbf.path, err)
+	}
+	if dbf.prefixHeader.Signature != signature {
+		return fmt.Errorf("not a valid ajfs file (invalid signature %q, expected %q). path: %q", dbf.prefixHeader.Signature, signature, dbf.path)
+	}
+	if dbf.prefixHeader.Version > currentVersion {
+		return fmt.Errorf("not a supported ajfs file (invalid version %d, expected <= %d). path: %q", dbf.prefixHeader.Version, currentVersion, dbf.path)
+	}
+	if err := dbf.header.read(dbf.file); err != nil {
+		return fmt.Errorf("failed to read the ajfs header. path: %q. %w", dbf.path, err)
+	}
+	if err := dbf.root.read(dbf.file); err != nil {
+		return fmt.Errorf("failed to read the ajfs root entry. path: %q. %w", dbf.path, err)
+	}
+	if err := dbf.meta.read(dbf.file, dbf.header.Features); err != nil {
+		return fmt.Errorf("failed to read the ajfs meta entry. path: %q. %w", dbf.path, err)
+	}
+
+	fmt.Fprintf(out, "Root: %q\n", dbf.root.path)
+
+	// Read the entries until one can't be read completely or the entry lookup table is reached
+	entries := make([]path.Info, 0, 1024)
+	complete := false
+	readCoder := newPathEntryCoder(dbf.prefixHeader.Version)
+
+	for {
+		entry := pathEntry{}
+		if err := readCoder.read(dbf.file, &entry); err != nil {
+			break
+		}
+		// Garbage (e.g. zeroes written by the file system after a crash) does not have a valid identifier
+		if entry.path == "" || entry.header.Id != path.IdFromPathInNamespace(dbf.meta.IdNamespace, entry.path) {
+			break
+		}
+		entries = append(entries, pathInfoFromPathEntry(&entry))
+
+		buf, err := dbf.file.Peek(4)
+		if err != nil {
+			break
+		}
+		if bytes.Equal(buf, sentinel[:]) {
+			complete = true
+			break
+		}
+	}
+
+	if complete {
+		fmt.Fprintf(out, "Entries: %d (complete)\n", len(entries))
+	} else {
+		fmt.Fprintf(out, "Entries: %d (truncated)\n", len(entries))
+	}
+
+	// The hash table is only written once all the entries have been written
+	hashes, algo := dbf.salvageHashes(entries, complete)
+	if hashes != nil {
+		fmt.Fprintf(out, "Hashes: %d\n", len(hashes))
+	}
+
+	// Create the new database
+	features := FeatureFlags(FeatureJustEntries)
+	if dbf.header.Features.HasDirStats() {
+		features |= FeatureDirStats
+	}
+	if hashes != nil {
+		features |= FeatureHashTable
+	}
+
+	checksumAlgo := dbf.header.ChecksumAlgo
+	if _, err := checksumAlgo.newHasher(); err != nil {
+		checksumAlgo = ChecksumCRC32
+	}
+
+	meta := dbf.meta
+	outDbf, err := createDatabase(outPath, dbf.root.path, features, checksumAlgo, &meta, currentVersion)
+	if err != nil {
+		return err
+	}
+
+	// Called when an error happened and the incomplete database needs to be removed
+	errFn := func(rcvErr error) error {
+		if err := outDbf.Interrupted(); err != nil {
+			return fmt.Errorf("failed to remove the incomplete database %q with error (%w). original error: %w", outPath, err, rcvErr)
+		}
+		return rcvErr
+	}
+
+	for i := range entries {
+		if err := outDbf.WriteEntry(&entries[i]); err != nil {
+			return errFn(err)
+		}
+	}
+
+	if err := outDbf.FinishEntries(); err != nil {
+		return errFn(err)
+	}
+
+	if hashes != nil {
+		if err := outDbf.StartHashTable(algo); err != nil {
+			return errFn(err)
+		}
+		if err := outDbf.FinishHashTable(); err != nil {
+			return errFn(err)
+		}
+
+		for idx, hash := range hashes {
+			if err := outDbf.WriteHashEntry(idx, hash); err != nil {
+				return errFn(err)
+			}
+		}
+	}
+
+	if err := outDbf.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Salvaged %d entries and %d hashes to %q\n", len(entries), len(hashes), outPath)
+	return nil
+}
+
+// Read the complete file signature hashes of the entries that were salvaged.
+// Returns nil when there is no usable hash table, which is always the case when the entries were truncated.
+func (dbf *DatabaseFile) salvageHashes(entries []path.Info, complete bool) (HashTable, ajhash.Algo) {
+	if !complete || (dbf.header.HashTableOffset == 0) {
+		return nil, 0
+	}
+
+	if _, err := dbf.file.Seek(int64(dbf.header.HashTableOffset), io.SeekStart); err != nil {
+		return nil, 0
+	}
+	dbf.file.ResetReadBuffer()
+
+	var s [4]byte
+	if _, err := io.ReadFull(dbf.file, s[:]); err != nil || s != hashTableSentinel {
+		return nil, 0
+	}
+
+	header := hashTableHeader{}
+	if err := header.read(dbf.file); err != nil || !validAlgo(header.Algo) {
+		return nil, 0
+	}
+
+	result := make(HashTable, header.EntriesCount)
+	zeroHash := AlgoZeroValue(header.Algo)
+
+	for range header.EntriesCount {
+		entry := hashEntry{
+			Hash: AlgoZeroValue(header.Algo),
+		}
+		if err := entry.read(dbf.file); err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, 0
+			}
+			break
+		}
+
+		idx := int(entry.Index)
+		if idx >= len(entries) || !entries[idx].IsFile() || bytes.Equal(entry.Hash, zeroHash) {
+			continue
+		}
+		result[idx] = entry.Hash
+	}
+
+	return result, header.Algo
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package db
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrSealed is returned when an attempt is made to modify a database that has been sealed.
+var ErrSealed = errors.New("the ajfs database is sealed (read-only), use --force to modify it anyway")
+
+// Seal (or unseal) the database.
+// A sealed database is marked as read-only and commands that modify it will refuse to do so unless forced.
+// Only the status flags in the header are changed, thus the checksum is not affected.
+func SetSealed(dbPath string, sealed bool) error {
+	h, err := readHeader(dbPath)
+	if err != nil {
+		return err
+	}
+
+	if h.isDirty() {
+		return fmt.Errorf("%w. path: %q", ErrDirty, dbPath)
+	}
+
+	if sealed {
+		h.Status |= statusSealed
+	} else {
+		h.Status &^= statusSealed
+	}
+
+	if err = replaceHeader(h, dbPath); err != nil {
+		return fmt.Errorf("failed to update the ajfs header. path: %q. %w", dbPath, err)
+	}
+	return nil
+}
+
+// Returns true if the database has been sealed.
+// Only the headers are read which means this also works for databases that need to be fixed.
+func IsSealed(dbPath string) (bool, error) {
+	h, err := readHeader(dbPath)
+	if err != nil {
+		return false, err
+	}
+	return h.isSealed(), nil
+}
+
+// Return [ErrSealed] if the database has been sealed and force is false.
+func EnsureNotSealed(dbPath string, force bool) error {
+	if force {
+		return nil
+	}
+
+	sealed, err := IsSealed(dbPath)
+	if err != nil {
+		return err
+	}
+	if sealed {
+		return fmt.Errorf("%w. path: %q", ErrSealed, dbPath)
+	}
+	return nil
+}
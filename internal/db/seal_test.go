@@ -0,0 +1,136 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package db_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetSealed(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test", db.FeatureJustEntries)
+	require.NoError(t, err)
+	for _, p := range []string{"a.txt", "b.txt"} {
+		pi := path.Info{Id: path.IdFromPath(p), Path: p, Size: 42}
+		require.NoError(t, dbf.WriteEntry(&pi))
+	}
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.Close())
+
+	sealed, err := db.IsSealed(tempFile)
+	require.NoError(t, err)
+	assert.False(t, sealed)
+	assert.NoError(t, db.EnsureNotSealed(tempFile, false))
+
+	require.NoError(t, db.SetSealed(tempFile, true))
+
+	sealed, err = db.IsSealed(tempFile)
+	require.NoError(t, err)
+	assert.True(t, sealed)
+	assert.ErrorIs(t, db.EnsureNotSealed(tempFile, false), db.ErrSealed)
+	assert.NoError(t, db.EnsureNotSealed(tempFile, true))
+
+	// The checksum is not affected
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	assert.True(t, dbf.Sealed())
+	assert.NoError(t, dbf.VerifyChecksums())
+	require.NoError(t, dbf.Close())
+
+	// Rewriting keeps the seal
+	removed, err := db.PruneDatabase(context.Background(), tempFile, func(idx int, pi path.Info, hash []byte) (bool, error) {
+		return pi.Path != "a.txt", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	assert.True(t, dbf.Sealed())
+	require.NoError(t, dbf.Close())
+
+	require.NoError(t, db.SetSealed(tempFile, false))
+	sealed, err = db.IsSealed(tempFile)
+	require.NoError(t, err)
+	assert.False(t, sealed)
+}
+
+func TestSetPathDisplay(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test", db.FeatureJustEntries)
+	require.NoError(t, err)
+	for _, p := range []string{"a.txt", "b.txt"} {
+		pi := path.Info{Id: path.IdFromPath(p), Path: p, Size: 42}
+		require.NoError(t, dbf.WriteEntry(&pi))
+	}
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.Close())
+
+	pd, err := db.ReadPathDisplay(tempFile)
+	require.NoError(t, err)
+	assert.Equal(t, db.PathDisplayDefault, pd)
+
+	require.NoError(t, db.SetSealed(tempFile, true))
+	require.NoError(t, db.SetPathDisplay(tempFile, db.PathDisplayFull))
+
+	pd, err = db.ReadPathDisplay(tempFile)
+	require.NoError(t, err)
+	assert.Equal(t, db.PathDisplayFull, pd)
+
+	// The checksum and seal are not affected
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	assert.Equal(t, db.PathDisplayFull, dbf.PathDisplay())
+	assert.True(t, dbf.Sealed())
+	assert.NoError(t, dbf.VerifyChecksums())
+	require.NoError(t, dbf.Close())
+
+	// Rewriting keeps the preference
+	require.NoError(t, db.SetPathDisplay(tempFile, db.PathDisplayRelative))
+	_, err = db.PruneDatabase(context.Background(), tempFile, func(idx int, pi path.Info, hash []byte) (bool, error) {
+		return pi.Path != "a.txt", nil
+	})
+	require.NoError(t, err)
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	assert.Equal(t, db.PathDisplayRelative, dbf.PathDisplay())
+	require.NoError(t, dbf.Close())
+
+	require.NoError(t, db.SetPathDisplay(tempFile, db.PathDisplayDefault))
+	pd, err = db.ReadPathDisplay(tempFile)
+	require.NoError(t, err)
+	assert.Equal(t, db.PathDisplayDefault, pd)
+
+	pd, err = db.ParsePathDisplay("Full")
+	require.NoError(t, err)
+	assert.Equal(t, db.PathDisplayFull, pd)
+	_, err = db.ParsePathDisplay("absolute")
+	assert.Error(t, err)
+}
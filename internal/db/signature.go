@@ -0,0 +1,269 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package db
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/andrejacobs/go-aj/ajmath/safe"
+)
+
+// file format
+// ... <all the other sections>
+// sentinel
+// signatureEntry
+// sentinel
+//
+// The signature must always be the last section in the file.
+// The message being signed is the SHA-256 digest of the whole file up to the signature section, where the status
+// flags in the header are treated as being zero (so that the database can still be sealed after it was signed).
+
+// ErrInvalidSignature is returned when the database does not match the stored signature.
+var ErrInvalidSignature = errors.New("ajfs database file does not match the stored signature")
+
+// Signature that was used to sign the database.
+type Signature struct {
+	PublicKey ed25519.PublicKey // The public key of the key pair that was used to sign the database.
+	Signature []byte            // The Ed25519 signature.
+}
+
+// Sign the database using the Ed25519 private key.
+// The signature is appended to the file as a new feature section. If the database was already signed then the
+// existing signature is replaced.
+func SignDatabase(dbPath string, key ed25519.PrivateKey) error {
+	dbf, err := OpenDatabase(dbPath)
+	if err != nil {
+		return err
+	}
+	limited := dbf.Limited()
+	h := dbf.header
+	if err = dbf.Close(); err != nil {
+		return err
+	}
+
+	if limited {
+		return fmt.Errorf("can't sign %q because it can only be processed in a limited way", dbPath)
+	}
+
+	f, err := os.OpenFile(dbPath, os.O_RDWR|os.O_EXCL, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open the ajfs database file. path: %q. %w", dbPath, err)
+	}
+	defer f.Close()
+
+	if h.Features.HasSignature() {
+		// Replace the existing signature
+		if err = f.Truncate(int64(h.SignatureOffset)); err != nil {
+			return fmt.Errorf("failed to remove the existing signature. path: %q. %w", dbPath, err)
+		}
+	} else {
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		h.SignatureOffset, err = safe.Uint64ToUint32(uint64(info.Size())) //nolint:gosec // disable G115
+		if err != nil {
+			return fmt.Errorf("failed to set the ajfs signature offset. %w", err)
+		}
+		h.Features |= FeatureSignature
+	}
+
+	digest, err := signatureDigest(f, h)
+	if err != nil {
+		return fmt.Errorf("failed to sign the database %q. %w", dbPath, err)
+	}
+
+	entry := signatureEntry{}
+	copy(entry.PublicKey[:], key.Public().(ed25519.PublicKey))
+	copy(entry.Signature[:], ed25519.Sign(key, digest))
+
+	if _, err = f.Seek(int64(h.SignatureOffset), io.SeekStart); err != nil {
+		return err
+	}
+
+	if err = writeSignature(f, &entry); err != nil {
+		return err
+	}
+
+	// The header is only updated once the signature has been written
+	if _, err = f.Seek(headerOffset(), io.SeekStart); err != nil {
+		return err
+	}
+	if err = h.write(f); err != nil {
+		return fmt.Errorf("failed to update the ajfs header. %w", err)
+	}
+
+	return f.Sync()
+}
+
+// Read the signature that was used to sign the database.
+func (dbf *DatabaseFile) ReadSignature() (Signature, error) {
+	if !dbf.Features().HasSignature() {
+		panic("database does not contain a signature")
+	}
+
+	_, err := dbf.file.Seek(int64(dbf.header.SignatureOffset), io.SeekStart)
+	if err != nil {
+		return Signature{}, fmt.Errorf("failed to read the signature. %w", err)
+	}
+	dbf.file.ResetReadBuffer()
+
+	entry, err := readSignatureFrom(dbf.file)
+	if err != nil {
+		return Signature{}, err
+	}
+
+	return Signature{
+		PublicKey: ed25519.PublicKey(entry.PublicKey[:]),
+		Signature: entry.Signature[:],
+	}, nil
+}
+
+// Verify that the database has not been modified since it was signed and return [ErrInvalidSignature] if it has.
+// If key is nil then the public key stored along with the signature is used, which only proves the integrity of the
+// database. Specify the expected public key to also prove who signed the database.
+func (dbf *DatabaseFile) VerifySignature(key ed25519.PublicKey) error {
+	sig, err := dbf.ReadSignature()
+	if err != nil {
+		return err
+	}
+
+	if (key != nil) && !key.Equal(sig.PublicKey) {
+		return fmt.Errorf("%w (signed using a different key)", ErrInvalidSignature)
+	}
+
+	f, err := os.Open(dbf.path)
+	if err != nil {
+		return fmt.Errorf("failed to verify the signature. %w", err)
+	}
+	defer f.Close()
+
+	digest, err := signatureDigest(f, dbf.header)
+	if err != nil {
+		return fmt.Errorf("failed to verify the signature. %w", err)
+	}
+
+	if !ed25519.Verify(sig.PublicKey, digest, sig.Signature) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// Calculate the digest to be signed.
+// h is the header as it will be stored once the database has been signed.
+func signatureDigest(r io.ReadSeeker, h header) ([]byte, error) {
+	hasher := sha256.New()
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(hasher, r, headerOffset()); err != nil {
+		return nil, err
+	}
+
+	h.Status = 0
+	if err := h.write(hasher); err != nil {
+		return nil, err
+	}
+
+	offset := headerOffset() + headerSize()
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(hasher, r, int64(h.SignatureOffset)-offset); err != nil {
+		return nil, err
+	}
+
+	return hasher.Sum(nil), nil
+}
+
+func writeSignature(w io.Writer, entry *signatureEntry) error {
+	// 1st sentinel
+	if _, err := w.Write(signatureSentinel[:]); err != nil {
+		return fmt.Errorf("failed to write the signature (1st sentinel). %w", err)
+	}
+
+	if err := entry.write(w); err != nil {
+		return fmt.Errorf("failed to write the signature. %w", err)
+	}
+
+	// 2nd sentinel
+	if _, err := w.Write(signatureSentinel[:]); err != nil {
+		return fmt.Errorf("failed to write the signature (2nd sentinel). %w", err)
+	}
+	return nil
+}
+
+// Read the signature section starting at the 1st sentinel.
+func readSignatureFrom(r io.Reader) (signatureEntry, error) {
+	// Check 1st sentinel
+	var s [4]byte
+	_, err := io.ReadFull(r, s[:])
+	if err != nil {
+		return signatureEntry{}, fmt.Errorf("failed to read the signature (1st sentinel). %w", err)
+	}
+	if s != signatureSentinel {
+		return signatureEntry{}, fmt.Errorf("failed to read the signature (1st sentinel %q does not match %q)", s, signatureSentinel)
+	}
+
+	entry := signatureEntry{}
+	if err := entry.read(r); err != nil {
+		return signatureEntry{}, fmt.Errorf("failed to read the signature. %w", err)
+	}
+
+	// Check 2nd sentinel
+	_, err = io.ReadFull(r, s[:])
+	if err != nil {
+		return signatureEntry{}, fmt.Errorf("failed to read the signature (2nd sentinel). %w", err)
+	}
+	if s != signatureSentinel {
+		return signatureEntry{}, fmt.Errorf("failed to read the signature (2nd sentinel %q does not match %q)", s, signatureSentinel)
+	}
+
+	return entry, nil
+}
+
+//-----------------------------------------------------------------------------
+
+type signatureEntry struct {
+	PublicKey [ed25519.PublicKeySize]byte
+	Signature [ed25519.SignatureSize]byte
+}
+
+func (s *signatureEntry) read(r io.Reader) error {
+	return binary.Read(r, binary.LittleEndian, s)
+}
+
+func (s *signatureEntry) write(w io.Writer) error {
+	return binary.Write(w, binary.LittleEndian, s)
+}
+
+var (
+	signatureSentinel = [4]byte{0x41, 0x4A, 0x53, 0x47} // AJSG
+)
@@ -0,0 +1,111 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package db_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignDatabase(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test", db.FeatureHashTable)
+	require.NoError(t, err)
+	for i := range 5 {
+		p := fmt.Sprintf("%d.txt", i)
+		pi := path.Info{Id: path.IdFromPath(p), Path: p, Size: uint64(i)}
+		require.NoError(t, dbf.WriteEntry(&pi))
+	}
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.StartHashTable(ajhash.AlgoSHA1))
+	require.NoError(t, dbf.FinishHashTable())
+	require.NoError(t, dbf.Close())
+
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherPub, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	require.NoError(t, db.SignDatabase(tempFile, key))
+
+	verify := func(key ed25519.PublicKey) error {
+		dbf, err := db.OpenDatabase(tempFile)
+		require.NoError(t, err)
+		defer dbf.Close()
+		require.True(t, dbf.Features().HasSignature())
+		require.NoError(t, dbf.VerifyChecksums())
+		return dbf.VerifySignature(key)
+	}
+
+	assert.NoError(t, verify(nil))
+	assert.NoError(t, verify(pub))
+	assert.ErrorIs(t, verify(otherPub), db.ErrInvalidSignature)
+
+	// The database is still valid
+	require.NoError(t, db.FixDatabase(io.Discard, tempFile, true, ""))
+
+	// Sealing does not affect the signature
+	require.NoError(t, db.SetSealed(tempFile, true))
+	assert.NoError(t, verify(pub))
+
+	// Signing again replaces the signature
+	size := fileSize(t, tempFile)
+	require.NoError(t, db.SignDatabase(tempFile, otherKey))
+	assert.Equal(t, size, fileSize(t, tempFile))
+	assert.NoError(t, verify(otherPub))
+	assert.ErrorIs(t, verify(pub), db.ErrInvalidSignature)
+
+	// Modifying the database invalidates the signature
+	dbf, err = db.ResumeDatabase(tempFile)
+	require.NoError(t, err)
+	require.NoError(t, dbf.WriteHashEntry(2, []byte("01234567890123456789")))
+	require.NoError(t, dbf.Close())
+	assert.ErrorIs(t, verify(otherPub), db.ErrInvalidSignature)
+
+	// Rewriting removes the signature
+	_, err = db.PruneDatabase(context.Background(), tempFile, func(idx int, pi path.Info, hash []byte) (bool, error) {
+		return true, nil
+	})
+	require.NoError(t, err)
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	assert.False(t, dbf.Features().HasSignature())
+	require.NoError(t, dbf.Close())
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	return info.Size()
+}
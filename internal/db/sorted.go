@@ -0,0 +1,199 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/andrejacobs/go-aj/ajhash"
+)
+
+// Sorted hash table layout
+// The hash table entries are rewritten in place in the order of the hash and then the path entry index. Each entry
+// keeps its index field, so readers that build a map from the index are not affected. The statusSorted flag in the
+// header records the layout and is cleared when the database is resumed, since writing hashes would break the order.
+
+// Returns true if the hash table entries are ordered by hash, see [SortHashTable].
+func (dbf *DatabaseFile) HashTableSorted() bool {
+	return dbf.header.isSorted()
+}
+
+// Sort the hash table by hash once the database being created or resumed has been closed, see [SortHashTable].
+func (dbf *DatabaseFile) SortHashTableOnClose() {
+	dbf.sortOnClose = true
+}
+
+// Sort the hash table entries by hash which allows hashes to be found using a binary search and duplicates to be
+// grouped without building a map of every hash in memory.
+// Signed databases are not sorted because it would invalidate the signature.
+func SortHashTable(ctx context.Context, dbPath string) error {
+	dbf, err := OpenDatabase(dbPath)
+	if err != nil {
+		return err
+	}
+	defer dbf.Close()
+
+	if !dbf.Features().HasHashTable() {
+		return fmt.Errorf("the database %q does not contain a hash table", dbPath)
+	}
+	if dbf.Limited() {
+		return fmt.Errorf("can't sort the hash table of %q because it can only be processed in a limited way", dbPath)
+	}
+	if dbf.Features().HasSignature() {
+		return fmt.Errorf("can't sort the hash table of %q because it would invalidate the signature", dbPath)
+	}
+	if dbf.HashTableSorted() {
+		return nil
+	}
+
+	f, err := os.OpenFile(dbPath, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open the ajfs database file. path: %q. %w", dbPath, err)
+	}
+	defer f.Close()
+
+	h := dbf.header
+	h.Status |= statusDirty
+	if err = replaceHeader(h, dbPath); err != nil {
+		return fmt.Errorf("failed to mark the ajfs database as dirty. path: %q. %w", dbPath, err)
+	}
+
+	if _, err = f.Seek(dbf.hashEntriesOffset(), io.SeekStart); err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+
+	// Stopping half way would leave the hash table with missing entries
+	err = dbf.sortHashEntries(context.WithoutCancel(ctx), false, func(hash []byte, idx int) error {
+		entry := hashEntry{Index: uint32(idx), Hash: hash} //nolint:gosec // disable G115
+		return entry.write(w)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sort the hash table. path: %q. %w", dbPath, err)
+	}
+
+	if err = w.Flush(); err != nil {
+		return fmt.Errorf("failed to write the sorted hash table. path: %q. %w", dbPath, err)
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+
+	h.Status &^= statusDirty
+	h.Status |= statusSorted
+	if err = replaceHeader(h, dbPath); err != nil {
+		return fmt.Errorf("failed to update the ajfs header. path: %q. %w", dbPath, err)
+	}
+	return nil
+}
+
+// Find the indices of the path entries that have the specified file signature hash.
+// A binary search is used when the hash table is sorted, otherwise all the hash table entries are read.
+func (dbf *DatabaseFile) FindHash(ctx context.Context, hash []byte) ([]int, error) {
+	if !dbf.Features().HasHashTable() {
+		panic("database does not contain the hash table")
+	}
+
+	if !dbf.HashTableSorted() {
+		var result []int
+		err := dbf.ReadHashTableEntries(ctx, func(idx int, h []byte) error {
+			if bytes.Equal(hash, h) {
+				result = append(result, idx)
+			}
+			return nil
+		})
+		slices.Sort(result)
+		return result, err
+	}
+
+	header, err := dbf.readHashTableHeader()
+	if err != nil {
+		return nil, err
+	}
+	if len(hash) != AlgoSize(header.Algo) {
+		return nil, nil
+	}
+
+	count := int(header.EntriesCount)
+	start := dbf.hashEntriesOffset()
+	entrySize := int64(4 + len(hash))
+	entry := hashEntry{Hash: AlgoZeroValue(header.Algo)}
+	r := dbf.file.File()
+
+	var readErr error
+	readAt := func(i int) []byte {
+		if readErr != nil {
+			return entry.Hash
+		}
+		readErr = entry.read(io.NewSectionReader(r, start+int64(i)*entrySize, entrySize))
+		return entry.Hash
+	}
+
+	first := sort.Search(count, func(i int) bool {
+		return bytes.Compare(readAt(i), hash) >= 0
+	})
+
+	var result []int
+	for i := first; i < count && readErr == nil; i++ {
+		if !bytes.Equal(readAt(i), hash) {
+			break
+		}
+		result = append(result, int(entry.Index))
+	}
+
+	if readErr != nil {
+		return nil, fmt.Errorf("failed to read the hash table entries. %w", readErr)
+	}
+	return result, nil
+}
+
+// Group the duplicates by streaming the sorted hash table.
+func (dbf *DatabaseFile) findDuplicatesSorted(ctx context.Context, fn FindDuplicatesFn) error {
+	g := duplicateGrouper{dbf: dbf, fn: fn}
+
+	err := dbf.ReadHashTableEntries(ctx, func(idx int, hash []byte) error {
+		if ajhash.AllZeroBytes(hash) {
+			return nil
+		}
+		return g.add(hash, idx)
+	})
+	if err == nil {
+		err = g.flush()
+	}
+
+	if err == SkipAll {
+		return nil
+	}
+	return err
+}
+
+// Offset of the first hash table entry.
+func (dbf *DatabaseFile) hashEntriesOffset() int64 {
+	return int64(dbf.header.HashTableOffset) + int64(len(hashTableSentinel)) + int64(binary.Size(hashTableHeader{}))
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package db_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/andrejacobs/go-aj/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortHashTable(t *testing.T) {
+	tempFile, hashes := createHashedDatabase(t, 50)
+
+	dbf, err := db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	assert.False(t, dbf.HashTableSorted())
+	expTable, err := dbf.ReadHashTable(context.Background())
+	require.NoError(t, err)
+	expDupes := collectDuplicates(t, dbf)
+	require.NotEmpty(t, expDupes)
+	require.NoError(t, dbf.Close())
+
+	require.NoError(t, db.SortHashTable(context.Background(), tempFile))
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+	assert.True(t, dbf.HashTableSorted())
+	require.NoError(t, dbf.VerifyChecksums())
+
+	// Entries are ordered by hash and still map to the same path entries
+	var prev []byte
+	err = dbf.ReadHashTableEntries(context.Background(), func(idx int, hash []byte) error {
+		assert.LessOrEqual(t, bytes.Compare(prev, hash), 0)
+		prev = hash
+		return nil
+	})
+	require.NoError(t, err)
+
+	table, err := dbf.ReadHashTable(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, expTable, table)
+	assert.Equal(t, expDupes, collectDuplicates(t, dbf))
+
+	// Binary search
+	for idx, hash := range hashes {
+		if hash == nil {
+			continue
+		}
+		indices, err := dbf.FindHash(context.Background(), hash)
+		require.NoError(t, err)
+		assert.Contains(t, indices, idx)
+		for _, i := range indices {
+			assert.Equal(t, hash, hashes[i])
+		}
+	}
+
+	notFound := ajhash.AlgoSHA1.Buffer()
+	require.NoError(t, random.SecureBytes(notFound))
+	indices, err := dbf.FindHash(context.Background(), notFound)
+	require.NoError(t, err)
+	assert.Empty(t, indices)
+
+	// The database is still valid
+	require.NoError(t, db.FixDatabase(io.Discard, tempFile, true, ""))
+
+	// Sorting again is a no-op
+	require.NoError(t, db.SortHashTable(context.Background(), tempFile))
+}
+
+func TestFindHashUnsorted(t *testing.T) {
+	tempFile, hashes := createHashedDatabase(t, 20)
+
+	dbf, err := db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	indices, err := dbf.FindHash(context.Background(), hashes[1])
+	require.NoError(t, err)
+	assert.Contains(t, indices, 1)
+}
+
+func TestSortHashTableClearedWhenResumed(t *testing.T) {
+	tempFile, _ := createHashedDatabase(t, 10)
+	require.NoError(t, db.SortHashTable(context.Background(), tempFile))
+
+	dbf, err := db.ResumeDatabase(tempFile)
+	require.NoError(t, err)
+	require.NoError(t, dbf.Close())
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+	assert.False(t, dbf.HashTableSorted())
+}
+
+func TestSortHashTableSigned(t *testing.T) {
+	tempFile, _ := createHashedDatabase(t, 10)
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.NoError(t, db.SignDatabase(tempFile, key))
+
+	assert.ErrorContains(t, db.SortHashTable(context.Background(), tempFile), "invalidate the signature")
+}
+
+// Create a database with count files of which most share a few hashes and some are not hashed.
+// Returns the hashes by path entry index (nil if not hashed).
+func createHashedDatabase(t *testing.T, count int) (string, [][]byte) {
+	t.Helper()
+	algo := ajhash.AlgoSHA1
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test", db.FeatureHashTable)
+	require.NoError(t, err)
+
+	for i := range count {
+		p := fmt.Sprintf("file-%d.txt", i)
+		pi := path.Info{
+			Id:      path.IdFromPath(p),
+			Path:    p,
+			Size:    uint64(i),
+			Mode:    0644,
+			ModTime: time.Now(),
+		}
+		require.NoError(t, dbf.WriteEntry(&pi))
+	}
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.StartHashTable(algo))
+	require.NoError(t, dbf.FinishHashTable())
+
+	pool := make([][]byte, 5)
+	for i := range pool {
+		pool[i] = algo.Buffer()
+		require.NoError(t, random.SecureBytes(pool[i]))
+	}
+
+	hashes := make([][]byte, count)
+	for i := range count {
+		switch {
+		case i%7 == 3:
+			continue
+		case i%3 == 0:
+			hashes[i] = algo.Buffer()
+			require.NoError(t, random.SecureBytes(hashes[i]))
+		default:
+			hashes[i] = pool[i%len(pool)]
+		}
+		require.NoError(t, dbf.WriteHashEntry(i, hashes[i]))
+	}
+	require.NoError(t, dbf.Close())
+
+	t.Cleanup(func() { _ = os.Remove(tempFile) })
+	return tempFile, hashes
+}
+
+func collectDuplicates(t *testing.T, dbf *db.DatabaseFile) []string {
+	t.Helper()
+	var result []string
+	err := dbf.FindDuplicates(context.Background(), func(group string, idx int, pi path.Info, hash string) error {
+		result = append(result, fmt.Sprintf("%s %d %s", group, idx, hash))
+		return nil
+	})
+	require.NoError(t, err)
+	return result
+}
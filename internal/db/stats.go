@@ -21,7 +21,10 @@
 package db
 
 import (
+	"cmp"
+	"context"
 	"fmt"
+	"slices"
 
 	"github.com/andrejacobs/ajfs/internal/path"
 	"github.com/andrejacobs/go-aj/ajhash"
@@ -37,13 +40,16 @@ type Stats struct {
 	AvgFileSize   uint64 // totalFileSize / fileCount
 
 	MaxFileSize uint64 // the biggest single file size
+
+	TotalPathSize uint64 // total length in bytes of all the path strings
 }
 
 // Calculate statistics on the database.
-func (dbf *DatabaseFile) CalculateStats() (Stats, error) {
+func (dbf *DatabaseFile) CalculateStats(ctx context.Context) (Stats, error) {
 	result := Stats{}
 
-	err := dbf.ReadAllEntries(func(idx int, pi path.Info) error {
+	err := dbf.ReadAllEntries(ctx, func(idx int, pi path.Info) error {
+		result.TotalPathSize += uint64(len(pi.Path))
 		if pi.IsDir() {
 			result.DirCount++
 		} else if pi.IsFile() {
@@ -64,6 +70,60 @@ func (dbf *DatabaseFile) CalculateStats() (Stats, error) {
 	return result, nil
 }
 
+// Section describes a part of the database file.
+type Section struct {
+	Name   string // Name of the section (e.g. entries)
+	Offset uint64 // Offset in bytes from the start of the file
+	Size   uint64 // Size in bytes
+}
+
+// Return the sections of the database file in the order in which they are stored.
+// The header section includes the prefix header, header, root path and meta entry.
+func (dbf *DatabaseFile) Sections() ([]Section, error) {
+	info, err := dbf.file.File().Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine the sections for %q. %w", dbf.Path(), err)
+	}
+	fileSize := uint64(info.Size()) //nolint:gosec // disable G115
+
+	h := dbf.header
+	offsets := []Section{
+		{Name: "header", Offset: 0},
+		{Name: "entries", Offset: uint64(h.EntriesOffset)},
+		{Name: "lookup table", Offset: uint64(h.EntriesLookupTableOffset)},
+		{Name: "checksum", Offset: uint64(h.ChecksumOffset)},
+		{Name: "dir stats", Offset: uint64(h.DirStatsOffset)},
+		{Name: "hash table", Offset: uint64(h.HashTableOffset)},
+		{Name: "hash times", Offset: uint64(h.HashTimesOffset)},
+		{Name: "extra hashes", Offset: uint64(h.ExtraHashTablesOffset)},
+		{Name: "annotations", Offset: uint64(h.AnnotationsOffset)},
+		{Name: "signature", Offset: uint64(h.SignatureOffset)},
+	}
+
+	// Sections that are not present have a zero offset
+	result := make([]Section, 0, len(offsets))
+	for i, s := range offsets {
+		if i == 0 || s.Offset > 0 {
+			result = append(result, s)
+		}
+	}
+	slices.SortStableFunc(result, func(a, b Section) int {
+		return cmp.Compare(a.Offset, b.Offset)
+	})
+
+	for i := range result {
+		end := fileSize
+		if i+1 < len(result) {
+			end = result[i+1].Offset
+		}
+		if end > result[i].Offset {
+			result[i].Size = end - result[i].Offset
+		}
+	}
+
+	return result, nil
+}
+
 // Stats used to calculate statistics on the hash table.
 type HashTableStats struct {
 	HashedCount  uint64 // number of entries that have a calculated hash
@@ -75,14 +135,14 @@ type HashTableStats struct {
 }
 
 // Calculate statistics for the hash table.
-func (dbf *DatabaseFile) CalculateHashTableStats() (HashTableStats, error) {
+func (dbf *DatabaseFile) CalculateHashTableStats(ctx context.Context) (HashTableStats, error) {
 	if !dbf.Features().HasHashTable() {
 		panic("database does not contain the hash table")
 	}
 
 	stats := HashTableStats{}
 
-	err := dbf.ReadHashTableEntries(func(idx int, hash []byte) error {
+	err := dbf.ReadHashTableEntries(ctx, func(idx int, hash []byte) error {
 		if ajhash.AllZeroBytes(hash) {
 			stats.PendingCount++
 		} else {
@@ -95,9 +155,9 @@ func (dbf *DatabaseFile) CalculateHashTableStats() (HashTableStats, error) {
 		return HashTableStats{}, err
 	}
 
-	singleSizes := make(map[int]uint64, 64)
+	singleSizes := make(map[string]uint64, 64)
 
-	err = dbf.FindDuplicates(func(group, idx int, pi path.Info, hash string) error {
+	err = dbf.FindDuplicates(ctx, func(group string, idx int, pi path.Info, hash string) error {
 		stats.DupesCount++
 
 		var err error
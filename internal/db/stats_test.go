@@ -21,6 +21,7 @@
 package db_test
 
 import (
+	"context"
 	"fmt"
 	"io/fs"
 	"os"
@@ -30,6 +31,7 @@ import (
 
 	"github.com/andrejacobs/ajfs/internal/db"
 	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
 	"github.com/andrejacobs/go-aj/random"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -50,7 +52,7 @@ func TestCalculateStats(t *testing.T) {
 	expStats := db.Stats{}
 
 	for i := range expCount {
-		filePath := fmt.Sprintf("/some/path/%d.txt", i)
+		filePath := fmt.Sprintf("some/path/%d.txt", i)
 		p := path.Info{
 			Id:      path.IdFromPath(filePath),
 			Path:    filePath,
@@ -58,6 +60,7 @@ func TestCalculateStats(t *testing.T) {
 			Mode:    0740,
 			ModTime: expTime,
 		}
+		expStats.TotalPathSize += uint64(len(filePath))
 		if i == 3 || i == 7 {
 			p.Mode |= fs.ModeDir
 			expStats.DirCount++
@@ -78,12 +81,54 @@ func TestCalculateStats(t *testing.T) {
 	require.NoError(t, err)
 	defer dbf.Close()
 
-	stats, err := dbf.CalculateStats()
+	stats, err := dbf.CalculateStats(context.Background())
 	require.NoError(t, err)
 
 	assert.Equal(t, expStats, stats)
 }
 
+func TestSections(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	dbf, err := db.CreateDatabase(tempFile, "/test/", db.FeatureHashTable)
+	require.NoError(t, err)
+	for i := range 10 {
+		filePath := fmt.Sprintf("some/path/%d.txt", i)
+		p := path.Info{
+			Id:   path.IdFromPath(filePath),
+			Path: filePath,
+			Size: 42,
+			Mode: 0740,
+		}
+		require.NoError(t, dbf.WriteEntry(&p))
+	}
+	require.NoError(t, dbf.FinishEntries())
+	require.NoError(t, dbf.StartHashTable(ajhash.AlgoSHA1))
+	require.NoError(t, dbf.FinishHashTable())
+	require.NoError(t, dbf.Close())
+
+	info, err := os.Stat(tempFile)
+	require.NoError(t, err)
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	sections, err := dbf.Sections()
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(sections))
+	total := uint64(0)
+	for i, s := range sections {
+		names = append(names, s.Name)
+		assert.Equal(t, total, s.Offset, "section %d", i)
+		assert.Positive(t, s.Size, s.Name)
+		total += s.Size
+	}
+	assert.Equal(t, []string{"header", "entries", "lookup table", "hash table"}, names)
+	assert.Equal(t, uint64(info.Size()), total)
+}
+
 func TestCalculateStatsWhenEmpty(t *testing.T) {
 	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
 	_ = os.Remove(tempFile)
@@ -99,7 +144,7 @@ func TestCalculateStatsWhenEmpty(t *testing.T) {
 	require.NoError(t, err)
 	defer dbf.Close()
 
-	stats, err := dbf.CalculateStats()
+	stats, err := dbf.CalculateStats(context.Background())
 	require.NoError(t, err)
 	require.Equal(t, db.Stats{}, stats)
 }
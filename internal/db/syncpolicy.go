@@ -0,0 +1,69 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package db
+
+import "fmt"
+
+// SyncPolicy controls how often the database file is committed to stable storage (fsync) while the
+// hash table entries are being written. The header is always synced when the dirty status changes.
+type SyncPolicy uint8
+
+const (
+	SyncPeriodic SyncPolicy = iota // Sync at every journal checkpoint and when the database is closed.
+	SyncAlways                     // Sync after every hash table entry (or batch of entries) has been written.
+	SyncOnClose                    // Only sync when the database is closed.
+)
+
+func (p SyncPolicy) String() string {
+	switch p {
+	case SyncPeriodic:
+		return "periodic"
+	case SyncAlways:
+		return "always"
+	case SyncOnClose:
+		return "close"
+	}
+	return fmt.Sprintf("unknown (%d)", uint8(p))
+}
+
+// Set how often the database file is synced to disk while writing the hash table entries.
+// The default is [SyncPeriodic].
+// With [SyncOnClose] the hash table journal is never truncated since the entries it covers are only
+// known to be on disk once the database has been closed.
+func (dbf *DatabaseFile) SetSyncPolicy(p SyncPolicy) {
+	dbf.syncPolicy = p
+}
+
+// Return the function the journal calls at a checkpoint, nil when no checkpoints should be made.
+func (dbf *DatabaseFile) journalCheckpointSync() func() error {
+	if dbf.syncPolicy == SyncOnClose {
+		return nil
+	}
+	return dbf.syncHashWrites
+}
+
+// Sync the hash table entries that have been written when every write must be durable.
+func (dbf *DatabaseFile) syncIfAlways() error {
+	if dbf.syncPolicy != SyncAlways {
+		return nil
+	}
+	return dbf.syncHashWrites()
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncPolicy(t *testing.T) {
+	// More hashes than a journal checkpoint interval (1024 records of 8 bytes each)
+	count := 1500
+
+	testCases := []struct {
+		policy         db.SyncPolicy
+		expJournalSize int64
+	}{
+		{policy: db.SyncPeriodic, expJournalSize: int64(count-1024) * 8},
+		{policy: db.SyncAlways, expJournalSize: int64(count-1024) * 8},
+		{policy: db.SyncOnClose, expJournalSize: int64(count) * 8},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.policy.String(), func(t *testing.T) {
+			tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+			dbf, err := db.CreateDatabase(tempFile, "/test/", db.FeatureHashTable)
+			require.NoError(t, err)
+			dbf.SetSyncPolicy(tC.policy)
+
+			for i := range count {
+				filePath := fmt.Sprintf("%d.txt", i)
+				pi := path.Info{Id: path.IdFromPath(filePath), Path: filePath, Mode: 0644}
+				require.NoError(t, dbf.WriteEntry(&pi))
+			}
+			require.NoError(t, dbf.FinishEntries())
+			require.NoError(t, dbf.StartHashTable(ajhash.AlgoSHA1))
+			require.NoError(t, dbf.FinishHashTable())
+
+			hash := ajhash.AlgoSHA1.ZeroValue()
+			hash[0] = 0x42
+			for i := range count {
+				require.NoError(t, dbf.WriteHashEntry(i, hash))
+			}
+
+			info, err := os.Stat(tempFile + ".journal")
+			require.NoError(t, err)
+			assert.Equal(t, tC.expJournalSize, info.Size())
+
+			require.NoError(t, dbf.Close())
+			_, err = os.Stat(tempFile + ".journal")
+			assert.ErrorIs(t, err, os.ErrNotExist)
+
+			dbf, err = db.OpenDatabase(tempFile)
+			require.NoError(t, err)
+			defer dbf.Close()
+			require.NoError(t, dbf.VerifyChecksums())
+
+			hashTable, err := dbf.ReadHashTable(context.Background())
+			require.NoError(t, err)
+			assert.Len(t, hashTable, count)
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package errlog is used to keep a durable record of the files that could not be processed.
+//
+// The log is stored as a sidecar file next to the database (e.g. db.ajfs.errors.jsonl) and
+// each line is a JSON encoded [Entry]. Lines are appended as errors happen so that the log
+// survives the application being interrupted. When the log is closed it is rewritten to only
+// contain the latest entry for each path that still has an error.
+package errlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"time"
+)
+
+// Entry describes a file that could not be processed.
+type Entry struct {
+	Path    string    `json:"path"`    // Path relative to the root path of the database.
+	Error   string    `json:"error"`   // The reason why it failed.
+	Time    time.Time `json:"time"`    // When the last attempt failed.
+	Attempt int       `json:"attempt"` // Number of attempts that have failed.
+}
+
+// Log keeps track of the files that could not be processed.
+type Log struct {
+	path    string
+	entries map[string]Entry
+	file    *os.File
+	enc     *json.Encoder
+	dirty   bool
+}
+
+// Return the path of the error log file for the database.
+func PathFor(dbPath string) string {
+	return dbPath + ".errors.jsonl"
+}
+
+// Open the error log file and load the existing entries.
+// The log file will only be created once the first error is added.
+func Open(path string) (*Log, error) {
+	l := &Log{
+		path:    path,
+		entries: make(map[string]Entry),
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return l, nil
+		}
+		return nil, fmt.Errorf("failed to open the error log %q. %w", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to read the error log %q (line %d). %w", path, line, err)
+		}
+		// Later entries are from later attempts
+		l.entries[entry.Path] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the error log %q. %w", path, err)
+	}
+
+	return l, nil
+}
+
+// Remove the error log file if it exists.
+func Remove(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove the error log %q. %w", path, err)
+	}
+	return nil
+}
+
+// Path of the error log file.
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Number of paths that have an error.
+func (l *Log) Count() int {
+	return len(l.entries)
+}
+
+// Return true if the path has an error.
+func (l *Log) Contains(p string) bool {
+	_, exists := l.entries[p]
+	return exists
+}
+
+// Return the entries sorted by path.
+func (l *Log) Entries() []Entry {
+	result := make([]Entry, 0, len(l.entries))
+	for _, k := range slices.Sorted(maps.Keys(l.entries)) {
+		result = append(result, l.entries[k])
+	}
+	return result
+}
+
+// Record that processing the path failed and append it to the log file.
+func (l *Log) Add(p string, rcvErr error) error {
+	prev, exists := l.entries[p]
+	entry := Entry{
+		Path:    p,
+		Error:   rcvErr.Error(),
+		Time:    time.Now().UTC(),
+		Attempt: prev.Attempt + 1,
+	}
+	l.entries[p] = entry
+	// Older attempts are removed when the log is closed
+	l.dirty = l.dirty || exists
+
+	if l.file == nil {
+		f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+		if err != nil {
+			return fmt.Errorf("failed to open the error log %q. %w", l.path, err)
+		}
+		l.file = f
+		l.enc = json.NewEncoder(f)
+	}
+
+	if err := l.enc.Encode(entry); err != nil {
+		return fmt.Errorf("failed to write to the error log %q. %w", l.path, err)
+	}
+	return nil
+}
+
+// Record that the path was processed successfully and thus no longer has an error.
+func (l *Log) Resolve(p string) {
+	if _, exists := l.entries[p]; exists {
+		delete(l.entries, p)
+		l.dirty = true
+	}
+}
+
+// Close the log file.
+// If any errors were resolved then the file is rewritten and if no errors remain the file is removed.
+func (l *Log) Close() error {
+	if l.file != nil {
+		if err := l.file.Close(); err != nil {
+			return fmt.Errorf("failed to close the error log %q. %w", l.path, err)
+		}
+		l.file = nil
+		l.enc = nil
+	}
+
+	if !l.dirty {
+		return nil
+	}
+	l.dirty = false
+
+	if len(l.entries) == 0 {
+		return Remove(l.path)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return fmt.Errorf("failed to rewrite the error log %q. %w", l.path, err)
+	}
+
+	enc := json.NewEncoder(f)
+	for _, entry := range l.Entries() {
+		if err := enc.Encode(entry); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("failed to rewrite the error log %q. %w", l.path, err)
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to rewrite the error log %q. %w", l.path, err)
+	}
+	return nil
+}
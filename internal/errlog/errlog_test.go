@@ -0,0 +1,93 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package errlog_test
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/errlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog(t *testing.T) {
+	logPath := errlog.PathFor(filepath.Join(t.TempDir(), "unit-test.ajfs"))
+
+	// Nothing is created until an error is added
+	l, err := errlog.Open(logPath)
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+	assert.NoFileExists(t, logPath)
+
+	l, err = errlog.Open(logPath)
+	require.NoError(t, err)
+	require.NoError(t, l.Add("a.txt", fmt.Errorf("permission denied")))
+	require.NoError(t, l.Add("b.txt", fmt.Errorf("input/output error")))
+	require.NoError(t, l.Close())
+	assert.Equal(t, 2, countLines(t, logPath))
+
+	// Attempts are counted across runs and older attempts are removed on close
+	l, err = errlog.Open(logPath)
+	require.NoError(t, err)
+	assert.Equal(t, 2, l.Count())
+	assert.True(t, l.Contains("a.txt"))
+	assert.False(t, l.Contains("c.txt"))
+
+	require.NoError(t, l.Add("a.txt", fmt.Errorf("still denied")))
+	assert.Equal(t, 3, countLines(t, logPath))
+	require.NoError(t, l.Close())
+	assert.Equal(t, 2, countLines(t, logPath))
+
+	l, err = errlog.Open(logPath)
+	require.NoError(t, err)
+	entries := l.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "a.txt", entries[0].Path)
+	assert.Equal(t, "still denied", entries[0].Error)
+	assert.Equal(t, 2, entries[0].Attempt)
+	assert.Equal(t, "b.txt", entries[1].Path)
+	assert.Equal(t, 1, entries[1].Attempt)
+
+	// The log is removed once all errors are resolved
+	l.Resolve("a.txt")
+	l.Resolve("b.txt")
+	require.NoError(t, l.Close())
+	assert.NoFileExists(t, logPath)
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		count++
+	}
+	require.NoError(t, scanner.Err())
+	return count
+}
@@ -22,7 +22,9 @@
 package filter
 
 import (
+	"io/fs"
 	"strings"
+	"time"
 
 	"github.com/andrejacobs/go-aj/file"
 )
@@ -100,3 +102,69 @@ func ParsePathRegexToMatchPathFn(input []string, include bool) (file.MatchPathFn
 
 	return fileFn, dirFn, nil
 }
+
+// FileLimits restricts which files are included based on their size and last modification time.
+// A zero value for any of the fields means that limit is not applied.
+type FileLimits struct {
+	MinSize   uint64    // Files smaller than this are excluded.
+	MaxSize   uint64    // Files larger than this are excluded.
+	NewerThan time.Time // Files last modified at or before this time are excluded.
+	OlderThan time.Time // Files last modified at or after this time are excluded.
+}
+
+// IsZero returns true if none of the limits have been set.
+func (l FileLimits) IsZero() bool {
+	return l.MinSize == 0 && l.MaxSize == 0 && l.NewerThan.IsZero() && l.OlderThan.IsZero()
+}
+
+// MatchOutsideLimits returns a middleware that will match (exclude) regular files that fall outside of the limits.
+// Directories and files that can't be stat'ed are passed on to the next matcher.
+func MatchOutsideLimits(limits FileLimits) file.MatchPathMiddleware {
+	return func(next file.MatchPathFn) file.MatchPathFn {
+		if limits.IsZero() {
+			return next
+		}
+
+		return func(path string, d fs.DirEntry) (bool, error) {
+			if d.IsDir() {
+				return next(path, d)
+			}
+
+			info, err := d.Info()
+			if err != nil || !info.Mode().IsRegular() {
+				return next(path, d)
+			}
+
+			size := uint64(info.Size()) //nolint:gosec // disable G115
+			if limits.MinSize > 0 && size < limits.MinSize {
+				return true, nil
+			}
+			if limits.MaxSize > 0 && size > limits.MaxSize {
+				return true, nil
+			}
+
+			modTime := info.ModTime()
+			if !limits.NewerThan.IsZero() && !modTime.After(limits.NewerThan) {
+				return true, nil
+			}
+			if !limits.OlderThan.IsZero() && !modTime.Before(limits.OlderThan) {
+				return true, nil
+			}
+
+			return next(path, d)
+		}
+	}
+}
+
+// The file types that are considered to be special files.
+const specialFileTypes = fs.ModeSymlink | fs.ModeNamedPipe | fs.ModeSocket | fs.ModeDevice | fs.ModeCharDevice | fs.ModeIrregular
+
+// MatchSpecialFiles middleware will match symlinks, named pipes (FIFOs), sockets and device files.
+func MatchSpecialFiles(next file.MatchPathFn) file.MatchPathFn {
+	return func(path string, d fs.DirEntry) (bool, error) {
+		if d.Type()&specialFileTypes != 0 {
+			return true, nil
+		}
+		return next(path, d)
+	}
+}
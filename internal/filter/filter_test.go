@@ -22,10 +22,13 @@ package filter_test
 
 import (
 	"io/fs"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/andrejacobs/ajfs/internal/filter"
+	"github.com/andrejacobs/go-aj/file"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -77,6 +80,89 @@ func TestParsePathRegexToMatchPathFn(t *testing.T) {
 	assert.False(t, r)
 }
 
+func TestMatchOutsideLimits(t *testing.T) {
+	tempDir := t.TempDir()
+	now := time.Now()
+
+	createFile := func(name string, size int, modTime time.Time) fs.DirEntry {
+		path := filepath.Join(tempDir, name)
+		require.NoError(t, os.WriteFile(path, make([]byte, size), 0o644))
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		return fs.FileInfoToDirEntry(info)
+	}
+
+	small := createFile("small", 10, now)
+	large := createFile("large", 1000, now)
+	old := createFile("old", 100, now.AddDate(-2, 0, 0))
+
+	limits := filter.FileLimits{
+		MinSize:   50,
+		MaxSize:   500,
+		NewerThan: now.AddDate(-1, 0, 0),
+	}
+	fn := filter.MatchOutsideLimits(limits)(file.MatchNever)
+
+	r, err := fn("small", small)
+	require.NoError(t, err)
+	assert.True(t, r)
+
+	r, err = fn("large", large)
+	require.NoError(t, err)
+	assert.True(t, r)
+
+	r, err = fn("old", old)
+	require.NoError(t, err)
+	assert.True(t, r)
+
+	limits = filter.FileLimits{OlderThan: now.AddDate(-1, 0, 0)}
+	fn = filter.MatchOutsideLimits(limits)(file.MatchNever)
+
+	r, err = fn("old", old)
+	require.NoError(t, err)
+	assert.False(t, r)
+
+	r, err = fn("small", small)
+	require.NoError(t, err)
+	assert.True(t, r)
+
+	// Directories are never excluded based on the limits
+	dirInfo, err := os.Stat(tempDir)
+	require.NoError(t, err)
+	r, err = fn("dir", fs.FileInfoToDirEntry(dirInfo))
+	require.NoError(t, err)
+	assert.False(t, r)
+
+	// No limits defers to the next matcher
+	fn = filter.MatchOutsideLimits(filter.FileLimits{})(file.MatchNever)
+	r, err = fn("large", large)
+	require.NoError(t, err)
+	assert.False(t, r)
+}
+
+func TestMatchSpecialFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	target := filepath.Join(tempDir, "target")
+	link := filepath.Join(tempDir, "link")
+	require.NoError(t, os.WriteFile(target, []byte("hello"), 0o644))
+	require.NoError(t, os.Symlink(target, link))
+
+	fn := filter.MatchSpecialFiles(file.MatchNever)
+
+	info, err := os.Lstat(link)
+	require.NoError(t, err)
+	r, err := fn(link, fs.FileInfoToDirEntry(info))
+	require.NoError(t, err)
+	assert.True(t, r)
+
+	info, err = os.Lstat(target)
+	require.NoError(t, err)
+	r, err = fn(target, fs.FileInfoToDirEntry(info))
+	require.NoError(t, err)
+	assert.False(t, r)
+}
+
 //-----------------------------------------------------------------------------
 
 // Pretend to be a fs.DirEntry
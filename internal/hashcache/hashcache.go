@@ -0,0 +1,448 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package hashcache is used to reuse previously calculated file signature hashes.
+//
+// The cache is a local file that is shared across databases and maps a file (absolute path, size, last modification
+// time and hashing algorithm) to its hash. Creating a new snapshot of a mostly unchanged file hierarchy can then
+// reuse the hashes instead of having to read all of the files again.
+//
+// Each line in the cache file is a JSON encoded [Entry]. Lines are appended as hashes are calculated so that the
+// cache survives the application being interrupted. A last line that was only partially written (e.g. due to a crash
+// or power loss) is skipped and removed the next time the cache is used.
+//
+// Multiple processes can share the cache file. Reading, appending and rewriting the file is done while holding a lock
+// on a separate lock file (<cache file>.lock) and a rewrite merges the entries that were added by other processes.
+package hashcache
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/andrejacobs/go-aj/ajhash"
+)
+
+// Entry describes the file signature hash of a file.
+type Entry struct {
+	Path    string      `json:"path"`     // Absolute path of the file.
+	Size    uint64      `json:"size"`     // Size of the file when the hash was calculated.
+	ModTime time.Time   `json:"mod_time"` // Last modification time of the file when the hash was calculated.
+	Algo    ajhash.Algo `json:"algo"`     // Algorithm used to calculate the hash.
+	Hash    string      `json:"hash"`     // Hex encoded file signature hash.
+}
+
+// Cache of file signature hashes.
+// A nil *Cache is valid and behaves as a cache that is disabled.
+type Cache struct {
+	path     string
+	entries  map[key]Entry
+	removed  map[key]Entry // Entries removed by Prune that are also removed from the cache file when it is rewritten.
+	dirty    bool          // The cache file needs to be rewritten when closed.
+	warnings []string      // Problems found while loading the cache that did not prevent it from being used.
+}
+
+// Entries read from the cache file.
+type contents struct {
+	entries     map[key]Entry
+	duplicates  bool  // Some files have more than one entry.
+	partialLine int   // Line number of the partially written last line (0 if there is none).
+	partialErr  error // Reason the partially written last line could not be read.
+}
+
+type key struct {
+	path string
+	algo ajhash.Algo
+}
+
+// Return the default path of the cache file, which is located in the user's cache directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine the user cache directory. %w", err)
+	}
+	return filepath.Join(dir, "ajfs", "hashes.jsonl"), nil
+}
+
+// Open the cache file and load the existing entries.
+// The cache file will only be created once the first hash is added.
+// An empty path returns a nil cache, which means the cache is disabled.
+// A last line that can't be read is treated as partially written, it is skipped and reported by [Cache.Warnings].
+func Open(path string) (*Cache, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	c := &Cache{
+		path:    path,
+		entries: make(map[key]Entry, 1024),
+		removed: make(map[key]Entry),
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return c, nil
+		}
+		return nil, fmt.Errorf("failed to open the hash cache %q. %w", path, err)
+	}
+
+	var cc contents
+	err := c.withLock(func() error {
+		var err error
+		cc, err = readContents(path)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.entries = cc.entries
+	// Duplicate entries and the partial line are removed when the cache is closed
+	c.dirty = cc.duplicates || (cc.partialLine > 0)
+	if cc.partialLine > 0 {
+		c.warnings = append(c.warnings, fmt.Sprintf("skipped the partially written line %d of the hash cache %q. %v", cc.partialLine, path, cc.partialErr))
+	}
+
+	return c, nil
+}
+
+// Read the entries from the cache file. A missing file has no entries.
+// Must be called while holding the lock.
+func readContents(path string) (contents, error) {
+	cc := contents{
+		entries: make(map[key]Entry, 1024),
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cc, nil
+		}
+		return cc, fmt.Errorf("failed to open the hash cache %q. %w", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	var badLine int
+	var badErr error
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		// Only the last line could have been partially written
+		if badErr != nil {
+			return cc, fmt.Errorf("failed to read the hash cache %q (line %d). %w", path, badLine, badErr)
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			badLine = line
+			badErr = err
+			continue
+		}
+
+		// Later entries replace earlier ones for the same file
+		k := key{path: entry.Path, algo: entry.Algo}
+		_, exists := cc.entries[k]
+		cc.duplicates = cc.duplicates || exists
+		cc.entries[k] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return cc, fmt.Errorf("failed to read the hash cache %q. %w", path, err)
+	}
+
+	if badErr != nil {
+		cc.partialLine = badLine
+		cc.partialErr = badErr
+	}
+
+	return cc, nil
+}
+
+// Return the problems found while loading the cache that did not prevent it from being used.
+func (c *Cache) Warnings() []string {
+	if c == nil {
+		return nil
+	}
+	return c.warnings
+}
+
+// Path of the cache file.
+func (c *Cache) Path() string {
+	if c == nil {
+		return ""
+	}
+	return c.path
+}
+
+// Number of hashes in the cache.
+func (c *Cache) Count() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.entries)
+}
+
+// Return the hash for the file if it is in the cache and the size and last modification time still match.
+// p must be the absolute path to the file.
+func (c *Cache) Lookup(p string, size uint64, modTime time.Time, algo ajhash.Algo) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	entry, exists := c.entries[key{path: p, algo: algo}]
+	if !exists || (entry.Size != size) || !entry.ModTime.Equal(modTime) {
+		return nil, false
+	}
+
+	hash, err := hex.DecodeString(entry.Hash)
+	if err != nil {
+		return nil, false
+	}
+	return hash, true
+}
+
+// Add the hash for the file and append it to the cache file.
+// p must be the absolute path to the file.
+func (c *Cache) Add(p string, size uint64, modTime time.Time, algo ajhash.Algo, hash []byte) error {
+	if c == nil {
+		return nil
+	}
+
+	entry := Entry{
+		Path:    p,
+		Size:    size,
+		ModTime: modTime,
+		Algo:    algo,
+		Hash:    hex.EncodeToString(hash),
+	}
+
+	k := key{path: p, algo: algo}
+	_, exists := c.entries[k]
+	c.entries[k] = entry
+	delete(c.removed, k)
+	// Replaced entries are removed when the cache is closed
+	c.dirty = c.dirty || exists
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to write to the hash cache %q. %w", c.path, err)
+	}
+	data = append(data, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create the directory for the hash cache %q. %w", c.path, err)
+	}
+
+	return c.withLock(func() error {
+		// The file is opened for each entry because another process could have rewritten it in the meantime
+		f, err := os.OpenFile(c.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+		if err != nil {
+			return fmt.Errorf("failed to open the hash cache %q. %w", c.path, err)
+		}
+
+		if err := removePartialLine(f); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("failed to remove the partially written line from the hash cache %q. %w", c.path, err)
+		}
+
+		if _, err := f.Write(data); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("failed to write to the hash cache %q. %w", c.path, err)
+		}
+
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write to the hash cache %q. %w", c.path, err)
+		}
+		return nil
+	})
+}
+
+// Remove the partially written last line (if any) so that a new entry does not get appended to it.
+// Entries are only written while holding the lock, so a partial line is left behind by a process that was interrupted.
+func removePartialLine(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	size := info.Size()
+	end := size
+	buf := make([]byte, 4096)
+	for end > 0 {
+		n := min(end, int64(len(buf)))
+		if _, err := f.ReadAt(buf[:n], end-n); err != nil {
+			return err
+		}
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			end = end - n + int64(i) + 1
+			break
+		}
+		end -= n
+	}
+
+	if end == size {
+		return nil
+	}
+	return f.Truncate(end)
+}
+
+// Remove the entries for files that no longer exist or that have changed since the hash was calculated.
+// Returns the number of entries that were removed.
+func (c *Cache) Prune(ctx context.Context) (int, error) {
+	if c == nil {
+		return 0, nil
+	}
+
+	removed := 0
+	for k, entry := range c.entries {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+
+		info, err := os.Stat(entry.Path)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return removed, fmt.Errorf("failed to check %q. %w", entry.Path, err)
+			}
+		} else if info.Mode().IsRegular() && (uint64(info.Size()) == entry.Size) && info.ModTime().Equal(entry.ModTime) { //nolint:gosec // disable G115
+			continue
+		}
+
+		delete(c.entries, k)
+		c.removed[k] = entry
+		removed++
+	}
+
+	c.dirty = c.dirty || (removed > 0)
+	return removed, nil
+}
+
+// Close the cache file.
+// If any entries were replaced or removed then the file is rewritten. Entries that were added by other processes
+// in the meantime are kept.
+func (c *Cache) Close() error {
+	if c == nil {
+		return nil
+	}
+
+	if !c.dirty {
+		return nil
+	}
+	c.dirty = false
+
+	return c.withLock(func() error {
+		// Merge with the entries that were added or replaced by other processes
+		cc, err := readContents(c.path)
+		if err != nil {
+			return err
+		}
+		for k, entry := range c.removed {
+			if current, exists := cc.entries[k]; exists && sameEntry(current, entry) {
+				delete(cc.entries, k)
+			}
+		}
+		c.entries = cc.entries
+		clear(c.removed)
+
+		if len(c.entries) == 0 {
+			return Remove(c.path)
+		}
+
+		// Write to a temporary file first so that the existing cache is not lost when the rewrite fails
+		f, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+		if err != nil {
+			return fmt.Errorf("failed to rewrite the hash cache %q. %w", c.path, err)
+		}
+		tmpPath := f.Name()
+
+		w := bufio.NewWriter(f)
+		enc := json.NewEncoder(w)
+		for _, k := range slices.SortedFunc(maps.Keys(c.entries), compareKeys) {
+			if err := enc.Encode(c.entries[k]); err != nil {
+				_ = f.Close()
+				_ = os.Remove(tmpPath)
+				return fmt.Errorf("failed to rewrite the hash cache %q. %w", c.path, err)
+			}
+		}
+
+		if err := w.Flush(); err != nil {
+			_ = f.Close()
+			_ = os.Remove(tmpPath)
+			return fmt.Errorf("failed to rewrite the hash cache %q. %w", c.path, err)
+		}
+
+		if err := f.Close(); err != nil {
+			_ = os.Remove(tmpPath)
+			return fmt.Errorf("failed to rewrite the hash cache %q. %w", c.path, err)
+		}
+
+		if err := os.Rename(tmpPath, c.path); err != nil {
+			_ = os.Remove(tmpPath)
+			return fmt.Errorf("failed to rewrite the hash cache %q. %w", c.path, err)
+		}
+		return nil
+	})
+}
+
+// Run fn while holding the lock on the cache file.
+func (c *Cache) withLock(fn func() error) error {
+	unlock, err := lockFile(c.path + ".lock")
+	if err != nil {
+		return fmt.Errorf("failed to lock the hash cache %q. %w", c.path, err)
+	}
+
+	err = fn()
+	if uerr := unlock(); uerr != nil && err == nil {
+		err = fmt.Errorf("failed to unlock the hash cache %q. %w", c.path, uerr)
+	}
+	return err
+}
+
+// Check if the entries describe the same hash of the same file.
+func sameEntry(l, r Entry) bool {
+	return (l.Size == r.Size) && l.ModTime.Equal(r.ModTime) && (l.Hash == r.Hash)
+}
+
+// Remove the cache file if it exists.
+// The lock file is kept because other processes could be waiting on it.
+func Remove(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove the hash cache %q. %w", path, err)
+	}
+	return nil
+}
+
+func compareKeys(l, r key) int {
+	return cmp.Or(cmp.Compare(l.path, r.path), cmp.Compare(l.algo, r.algo))
+}
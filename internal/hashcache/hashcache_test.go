@@ -0,0 +1,314 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package hashcache_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/hashcache"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	tempDir := t.TempDir()
+	cachePath := filepath.Join(tempDir, "ajfs", "hashes.jsonl")
+
+	c, err := hashcache.Open(cachePath)
+	require.NoError(t, err)
+	assert.Equal(t, 0, c.Count())
+	assert.NoFileExists(t, cachePath)
+
+	modTime := time.Now().Add(-time.Hour)
+	hash := []byte{1, 2, 3, 4}
+
+	require.NoError(t, c.Add("/a/1.txt", 42, modTime, ajhash.AlgoSHA1, hash))
+	require.NoError(t, c.Add("/a/2.txt", 43, modTime, ajhash.AlgoSHA1, []byte{5, 6}))
+	require.NoError(t, c.Close())
+	assert.FileExists(t, cachePath)
+
+	c, err = hashcache.Open(cachePath)
+	require.NoError(t, err)
+	assert.Equal(t, 2, c.Count())
+
+	found, ok := c.Lookup("/a/1.txt", 42, modTime, ajhash.AlgoSHA1)
+	assert.True(t, ok)
+	assert.Equal(t, hash, found)
+
+	// Size, modification time or algorithm changed
+	_, ok = c.Lookup("/a/1.txt", 41, modTime, ajhash.AlgoSHA1)
+	assert.False(t, ok)
+	_, ok = c.Lookup("/a/1.txt", 42, modTime.Add(time.Second), ajhash.AlgoSHA1)
+	assert.False(t, ok)
+	_, ok = c.Lookup("/a/1.txt", 42, modTime, ajhash.AlgoSHA256)
+	assert.False(t, ok)
+	_, ok = c.Lookup("/a/3.txt", 42, modTime, ajhash.AlgoSHA1)
+	assert.False(t, ok)
+
+	// Replacing an entry rewrites the file when closed
+	require.NoError(t, c.Add("/a/1.txt", 44, modTime, ajhash.AlgoSHA1, []byte{7}))
+	require.NoError(t, c.Close())
+	assert.Equal(t, 2, countLines(t, cachePath))
+
+	c, err = hashcache.Open(cachePath)
+	require.NoError(t, err)
+	found, ok = c.Lookup("/a/1.txt", 44, modTime, ajhash.AlgoSHA1)
+	assert.True(t, ok)
+	assert.Equal(t, []byte{7}, found)
+	require.NoError(t, c.Close())
+}
+
+func TestDisabledCache(t *testing.T) {
+	c, err := hashcache.Open("")
+	require.NoError(t, err)
+	assert.Nil(t, c)
+
+	require.NoError(t, c.Add("/a/1.txt", 42, time.Now(), ajhash.AlgoSHA1, []byte{1}))
+	_, ok := c.Lookup("/a/1.txt", 42, time.Now(), ajhash.AlgoSHA1)
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Count())
+	assert.NoError(t, c.Close())
+}
+
+func TestPrune(t *testing.T) {
+	tempDir := t.TempDir()
+	cachePath := filepath.Join(tempDir, "hashes.jsonl")
+
+	unchanged := filepath.Join(tempDir, "unchanged.txt")
+	changed := filepath.Join(tempDir, "changed.txt")
+	for _, p := range []string{unchanged, changed} {
+		require.NoError(t, os.WriteFile(p, []byte("The quick brown fox"), 0644))
+	}
+
+	c, err := hashcache.Open(cachePath)
+	require.NoError(t, err)
+
+	for _, p := range []string{unchanged, changed, filepath.Join(tempDir, "deleted.txt")} {
+		info, err := os.Stat(unchanged)
+		require.NoError(t, err)
+		require.NoError(t, c.Add(p, uint64(info.Size()), info.ModTime(), ajhash.AlgoSHA1, []byte{1}))
+	}
+
+	require.NoError(t, os.WriteFile(changed, []byte("jumped over the lazy dog"), 0644))
+
+	removed, err := c.Prune(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	require.NoError(t, c.Close())
+
+	c, err = hashcache.Open(cachePath)
+	require.NoError(t, err)
+	assert.Equal(t, 1, c.Count())
+	require.NoError(t, c.Close())
+
+	// Removing all entries removes the file
+	require.NoError(t, os.Remove(unchanged))
+	c, err = hashcache.Open(cachePath)
+	require.NoError(t, err)
+	removed, err = c.Prune(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	require.NoError(t, c.Close())
+	assert.NoFileExists(t, cachePath)
+}
+
+func TestSharedCache(t *testing.T) {
+	tempDir := t.TempDir()
+	cachePath := filepath.Join(tempDir, "hashes.jsonl")
+	modTime := time.Now().Add(-time.Hour)
+
+	c, err := hashcache.Open(cachePath)
+	require.NoError(t, err)
+	require.NoError(t, c.Add("/a/1.txt", 42, modTime, ajhash.AlgoSHA1, []byte{1}))
+	require.NoError(t, c.Add("/a/2.txt", 43, modTime, ajhash.AlgoSHA1, []byte{2}))
+	require.NoError(t, c.Close())
+
+	// Two processes using the cache at the same time
+	c1, err := hashcache.Open(cachePath)
+	require.NoError(t, err)
+	c2, err := hashcache.Open(cachePath)
+	require.NoError(t, err)
+
+	require.NoError(t, c1.Add("/a/1.txt", 44, modTime, ajhash.AlgoSHA1, []byte{3}))
+	require.NoError(t, c2.Add("/b/1.txt", 45, modTime, ajhash.AlgoSHA1, []byte{4}))
+	require.NoError(t, c2.Add("/b/1.txt", 46, modTime, ajhash.AlgoSHA1, []byte{5}))
+
+	// Rewriting the cache keeps the entries added by the other process
+	require.NoError(t, c1.Close())
+	require.NoError(t, c2.Add("/b/2.txt", 47, modTime, ajhash.AlgoSHA1, []byte{6}))
+	require.NoError(t, c2.Close())
+	assert.Equal(t, 4, countLines(t, cachePath))
+
+	c, err = hashcache.Open(cachePath)
+	require.NoError(t, err)
+	assert.Equal(t, 4, c.Count())
+	found, ok := c.Lookup("/a/1.txt", 44, modTime, ajhash.AlgoSHA1)
+	assert.True(t, ok)
+	assert.Equal(t, []byte{3}, found)
+	found, ok = c.Lookup("/b/1.txt", 46, modTime, ajhash.AlgoSHA1)
+	assert.True(t, ok)
+	assert.Equal(t, []byte{5}, found)
+	require.NoError(t, c.Close())
+
+	// Pruning only removes the entries that were not replaced by another process
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "1.txt"), []byte("The quick brown fox"), 0644))
+	info, err := os.Stat(filepath.Join(tempDir, "1.txt"))
+	require.NoError(t, err)
+
+	c1, err = hashcache.Open(cachePath)
+	require.NoError(t, err)
+	removed, err := c1.Prune(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 4, removed)
+
+	c2, err = hashcache.Open(cachePath)
+	require.NoError(t, err)
+	require.NoError(t, c2.Add("/a/1.txt", 48, modTime, ajhash.AlgoSHA1, []byte{7}))
+	require.NoError(t, c2.Add(filepath.Join(tempDir, "1.txt"), uint64(info.Size()), info.ModTime(), ajhash.AlgoSHA1, []byte{8}))
+	require.NoError(t, c1.Close())
+	require.NoError(t, c2.Close())
+
+	c, err = hashcache.Open(cachePath)
+	require.NoError(t, err)
+	assert.Equal(t, 2, c.Count())
+	_, ok = c.Lookup("/a/1.txt", 48, modTime, ajhash.AlgoSHA1)
+	assert.True(t, ok)
+	require.NoError(t, c.Close())
+
+	// No temporary files are left behind
+	matches, err := filepath.Glob(filepath.Join(tempDir, "*.tmp"))
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestConcurrentWriters(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "hashes.jsonl")
+	modTime := time.Now().Add(-time.Hour)
+
+	const writers = 4
+	const count = 50
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := hashcache.Open(cachePath)
+			if err != nil {
+				errs <- err
+				return
+			}
+			for i := range count {
+				p := fmt.Sprintf("/%d/%d.txt", w, i)
+				// Adding the same file twice forces the cache to be rewritten
+				for range 2 {
+					if err := c.Add(p, uint64(i), modTime, ajhash.AlgoSHA1, []byte{byte(w), byte(i)}); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}
+			errs <- c.Close()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	c, err := hashcache.Open(cachePath)
+	require.NoError(t, err)
+	assert.Equal(t, writers*count, c.Count())
+	assert.Empty(t, c.Warnings())
+	require.NoError(t, c.Close())
+}
+
+func countLines(t *testing.T, path string) int {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		count++
+	}
+	require.NoError(t, scanner.Err())
+	return count
+}
+
+func TestPartiallyWrittenLine(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "hashes.jsonl")
+
+	modTime := time.Now().Add(-time.Hour)
+	c, err := hashcache.Open(cachePath)
+	require.NoError(t, err)
+	require.NoError(t, c.Add("/a/1.txt", 42, modTime, ajhash.AlgoSHA1, []byte{1, 2}))
+	require.NoError(t, c.Add("/a/2.txt", 43, modTime, ajhash.AlgoSHA1, []byte{3, 4}))
+	require.NoError(t, c.Close())
+
+	// Simulate a crash while appending an entry
+	f, err := os.OpenFile(cachePath, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"path":"/a/3.txt","si`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	c, err = hashcache.Open(cachePath)
+	require.NoError(t, err)
+	assert.Equal(t, 2, c.Count())
+	require.Len(t, c.Warnings(), 1)
+	assert.Contains(t, c.Warnings()[0], "line 3")
+
+	// New entries are not appended to the partial line
+	require.NoError(t, c.Add("/a/4.txt", 44, modTime, ajhash.AlgoSHA1, []byte{5, 6}))
+	c2, err := hashcache.Open(cachePath)
+	require.NoError(t, err)
+	assert.Equal(t, 3, c2.Count())
+	assert.Empty(t, c2.Warnings())
+	require.NoError(t, c2.Close())
+
+	// The cache is rewritten without the partial line when closed
+	require.NoError(t, c.Close())
+	assert.Equal(t, 3, countLines(t, cachePath))
+
+	c, err = hashcache.Open(cachePath)
+	require.NoError(t, err)
+	assert.Equal(t, 3, c.Count())
+	assert.Empty(t, c.Warnings())
+	require.NoError(t, c.Close())
+
+	// A line that can't be read followed by other lines is not a partial write
+	data, err := os.ReadFile(cachePath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(cachePath, append([]byte("not json\n"), data...), 0644))
+	_, err = hashcache.Open(cachePath)
+	assert.ErrorContains(t, err, "line 1")
+}
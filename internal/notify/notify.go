@@ -0,0 +1,150 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package notify is used to let the outside world know that a long running command has finished.
+//
+// A JSON encoded [Summary] can be POSTed to a webhook and/or be passed to a shell command
+// (e.g. to send an email), which makes it possible to run unattended scans overnight.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// Status describes how the command finished.
+type Status string
+
+const (
+	StatusSuccess     Status = "success"     // The command finished successfully
+	StatusFailed      Status = "failed"      // The command finished with an error
+	StatusInterrupted Status = "interrupted" // The command was interrupted (e.g. Ctrl+C)
+)
+
+// Summary of a command that has finished.
+type Summary struct {
+	Command  string    `json:"command"`          // Name of the ajfs command (e.g. scan).
+	Database string    `json:"database"`         // Path to the database.
+	Status   Status    `json:"status"`           // How the command finished.
+	Error    string    `json:"error,omitempty"`  // The error when the command failed.
+	Host     string    `json:"host"`             // Name of the host on which the command ran.
+	Started  time.Time `json:"started"`          // When the command started.
+	Finished time.Time `json:"finished"`         // When the command finished.
+	Duration float64   `json:"duration_seconds"` // How long the command took in seconds.
+
+	EntriesScanned uint64 `json:"entries_scanned"` // Number of path entries scanned.
+	FilesHashed    uint64 `json:"files_hashed"`    // Number of files that were hashed.
+	BytesHashed    uint64 `json:"bytes_hashed"`    // Number of bytes that were hashed.
+	Errors         uint64 `json:"errors"`          // Number of paths that could not be read or hashed.
+}
+
+// Create a new summary of a command that started at the specified time and finished now.
+// The status is determined from the error and whether the context was cancelled.
+func NewSummary(ctx context.Context, command string, dbPath string, started time.Time, err error) Summary {
+	s := Summary{
+		Command:  command,
+		Database: dbPath,
+		Status:   StatusSuccess,
+		Started:  started,
+		Finished: time.Now(),
+	}
+	s.Duration = s.Finished.Sub(started).Seconds()
+	s.Host, _ = os.Hostname()
+
+	if err != nil {
+		s.Status = StatusFailed
+		s.Error = err.Error()
+	} else if ctx.Err() != nil {
+		s.Status = StatusInterrupted
+	}
+
+	return s
+}
+
+// Maximum amount of time to wait for a webhook to respond.
+const webhookTimeout = 30 * time.Second
+
+// POST the summary as JSON to the webhook URL.
+func Webhook(ctx context.Context, url string, s Summary) error {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode the summary. %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create the webhook request for %q. %w", url, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to notify the webhook %q. %w", url, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("the webhook %q responded with %q", url, resp.Status)
+	}
+	return nil
+}
+
+// Run the shell command with the JSON encoded summary as its standard input.
+// The summary is also available in the environment variables AJFS_COMMAND, AJFS_DATABASE,
+// AJFS_STATUS and AJFS_ERROR.
+func Command(ctx context.Context, command string, s Summary, stdout io.Writer, stderr io.Writer) error {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode the summary. %w", err)
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Env = append(os.Environ(),
+		"AJFS_COMMAND="+s.Command,
+		"AJFS_DATABASE="+s.Database,
+		"AJFS_STATUS="+string(s.Status),
+		"AJFS_ERROR="+s.Error,
+	)
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run the command %q. %w", command, err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package notify_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSummary(t *testing.T) {
+	started := time.Now().Add(-time.Minute)
+
+	s := notify.NewSummary(context.Background(), "scan", "db.ajfs", started, nil)
+	assert.Equal(t, "scan", s.Command)
+	assert.Equal(t, "db.ajfs", s.Database)
+	assert.Equal(t, notify.StatusSuccess, s.Status)
+	assert.Empty(t, s.Error)
+	assert.GreaterOrEqual(t, s.Duration, 60.0)
+
+	s = notify.NewSummary(context.Background(), "scan", "db.ajfs", started, errors.New("boom"))
+	assert.Equal(t, notify.StatusFailed, s.Status)
+	assert.Equal(t, "boom", s.Error)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s = notify.NewSummary(ctx, "scan", "db.ajfs", started, nil)
+	assert.Equal(t, notify.StatusInterrupted, s.Status)
+}
+
+func TestWebhook(t *testing.T) {
+	var received notify.Summary
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		if received.Status == notify.StatusFailed {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	s := notify.NewSummary(context.Background(), "resume", "db.ajfs", time.Now(), nil)
+	s.FilesHashed = 42
+	require.NoError(t, notify.Webhook(context.Background(), ts.URL, s))
+	assert.Equal(t, "resume", received.Command)
+	assert.Equal(t, notify.StatusSuccess, received.Status)
+	assert.Equal(t, uint64(42), received.FilesHashed)
+
+	s.Status = notify.StatusFailed
+	assert.ErrorContains(t, notify.Webhook(context.Background(), ts.URL, s), "500")
+}
+
+func TestCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test command requires a POSIX shell")
+	}
+
+	s := notify.NewSummary(context.Background(), "scan", "db.ajfs", time.Now(), errors.New("boom"))
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	require.NoError(t, notify.Command(context.Background(), `echo "$AJFS_COMMAND $AJFS_STATUS $AJFS_ERROR"; cat`, s, &stdout, &stderr))
+
+	out := stdout.String()
+	assert.Contains(t, out, "scan failed boom\n")
+	assert.Contains(t, out, `"database":"db.ajfs"`)
+
+	assert.Error(t, notify.Command(context.Background(), "exit 1", s, &stdout, &stderr))
+}
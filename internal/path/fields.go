@@ -0,0 +1,169 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package path
+
+import (
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Field is a property of a path info entry that is exported, displayed and compared.
+//
+// All the properties are described by the registry (see [Fields]) so that a property that is added to [Info]
+// only needs to be registered here to be included by the CSV, JSON and NDJSON exports, displayed by the verbose
+// output of list and search and compared by diff. The existing output formats keep their own layout of the fields
+// they already contain and the other fields are added after them.
+type Field struct {
+	Name   string // Unique name of the field, also used as the key by the JSON exports, e.g. "modTime"
+	Header string // Column header used by the CSV export, e.g. "ModTime". Empty if the field is not exported as CSV
+
+	Text  func(pi *Info, opts FieldOptions) string // Text used by the CSV export and displayed by list and search
+	Value func(pi *Info, opts FieldOptions) any    // Value encoded by the JSON exports. nil if not exported as JSON
+
+	// Reports if the property is different for the two entries. nil if the property is not compared.
+	Differs func(a *Info, b *Info) bool
+	Change  string // Class of change reported by diff when the property is different (e.g. "size")
+}
+
+// FieldOptions control how the values of the fields are formatted.
+type FieldOptions struct {
+	DisplayTime func(t time.Time) time.Time // Convert a time before it is formatted. nil keeps the recorded time zone
+}
+
+func (o FieldOptions) time(t time.Time) time.Time {
+	if o.DisplayTime == nil {
+		return t
+	}
+	return o.DisplayTime(t)
+}
+
+// The registry of all the fields in the order in which they are added by the output formats.
+var fields = []Field{
+	{
+		Name:   "id",
+		Header: "Id",
+		Text:   func(pi *Info, _ FieldOptions) string { return hex.EncodeToString(pi.Id[:]) },
+		Value:  func(pi *Info, _ FieldOptions) any { return hex.EncodeToString(pi.Id[:]) },
+	},
+	{
+		Name:   "path",
+		Header: "Path",
+		// The exports are text and can't contain paths that are not valid UTF-8
+		Text:  func(pi *Info, _ FieldOptions) string { return Escape(pi.Path) },
+		Value: func(pi *Info, _ FieldOptions) any { return Escape(pi.Path) },
+	},
+	{
+		Name:    "size",
+		Header:  "Size",
+		Text:    func(pi *Info, _ FieldOptions) string { return fmt.Sprintf("%d", pi.Size) },
+		Value:   func(pi *Info, _ FieldOptions) any { return pi.Size },
+		Differs: func(a *Info, b *Info) bool { return a.Size != b.Size },
+		Change:  "size",
+	},
+	{
+		Name:    "mode",
+		Header:  "Mode",
+		Text:    func(pi *Info, _ FieldOptions) string { return pi.Mode.String() },
+		Value:   func(pi *Info, _ FieldOptions) any { return pi.Mode },
+		Differs: func(a *Info, b *Info) bool { return a.Mode != b.Mode },
+		Change:  "mode",
+	},
+	{
+		Name:  "modeStr",
+		Text:  func(pi *Info, _ FieldOptions) string { return pi.Mode.String() },
+		Value: func(pi *Info, _ FieldOptions) any { return pi.Mode.String() },
+	},
+	{
+		Name:   "modTime",
+		Header: "ModTime",
+		Text:   func(pi *Info, opts FieldOptions) string { return opts.time(pi.ModTime).Format(time.RFC3339Nano) },
+		Value:  func(pi *Info, opts FieldOptions) any { return opts.time(pi.ModTime) },
+		// The same instant can be recorded in different time zones
+		Differs: func(a *Info, b *Info) bool { return !a.ModTime.Equal(b.ModTime) },
+		Change:  "mtime",
+	},
+	{
+		Name:   "isDir",
+		Header: "IsDir",
+		Text:   func(pi *Info, _ FieldOptions) string { return fmt.Sprintf("%t", pi.IsDir()) },
+	},
+}
+
+// Return all the registered fields.
+func Fields() []Field {
+	return slices.Clone(fields)
+}
+
+// Return the fields with the names in the same order.
+// Panics if a field has not been registered.
+func FieldsNamed(names ...string) []Field {
+	result := make([]Field, 0, len(names))
+	for _, name := range names {
+		idx := slices.IndexFunc(fields, func(f Field) bool { return f.Name == name })
+		if idx < 0 {
+			panic(fmt.Sprintf("the field %q has not been registered", name))
+		}
+		result = append(result, fields[idx])
+	}
+	return result
+}
+
+// Return the registered fields except those with the names.
+// Used by an output format to add the fields that are not part of its own layout.
+func FieldsExcept(names ...string) []Field {
+	result := make([]Field, 0, len(fields))
+	for _, f := range fields {
+		if !slices.Contains(names, f.Name) {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// The fields that are already displayed by Info.String() and Info.StringWithHash().
+// The other fields are displayed after them.
+var displayLayout = []string{"id", "path", "size", "mode", "modeStr", "modTime", "isDir"}
+
+// Return the column headers of the fields that are not part of the display layout, each preceded by a comma.
+func displayHeader() string {
+	var sb strings.Builder
+	for _, f := range FieldsExcept(displayLayout...) {
+		sb.WriteString(", ")
+		if f.Header != "" {
+			sb.WriteString(f.Header)
+		} else {
+			sb.WriteString(f.Name)
+		}
+	}
+	return sb.String()
+}
+
+// Return the text of the fields that are not part of the display layout, each preceded by a comma.
+func displayText(pi *Info, opts FieldOptions) string {
+	var sb strings.Builder
+	for _, f := range FieldsExcept(displayLayout...) {
+		sb.WriteString(", ")
+		sb.WriteString(f.Text(pi, opts))
+	}
+	return sb.String()
+}
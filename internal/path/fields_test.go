@@ -0,0 +1,83 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package path_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFields(t *testing.T) {
+	modTime := time.Date(2025, 4, 5, 10, 11, 12, 13, time.UTC)
+	pi := path.Info{
+		Id:      path.IdFromPath("a/b"),
+		Path:    "a/b",
+		Size:    42,
+		Mode:    0644,
+		ModTime: modTime,
+	}
+
+	fields := path.FieldsNamed("size", "id", "modTime")
+	require.Len(t, fields, 3)
+	assert.Equal(t, "size", fields[0].Name)
+	assert.Equal(t, "id", fields[1].Name)
+	assert.Equal(t, "modTime", fields[2].Name)
+
+	opts := path.FieldOptions{DisplayTime: func(t time.Time) time.Time { return t.Add(time.Hour) }}
+	assert.Equal(t, "42", fields[0].Text(&pi, opts))
+	assert.Equal(t, "3ec69c85a4ff96830024afeef2d4e512181c8f7b", fields[1].Text(&pi, opts))
+	assert.Equal(t, "2025-04-05T11:11:12.000000013Z", fields[2].Text(&pi, opts))
+	assert.Equal(t, modTime, fields[2].Value(&pi, path.FieldOptions{}))
+
+	assert.Panics(t, func() { path.FieldsNamed("unknown") })
+
+	for _, f := range path.FieldsExcept("id", "path") {
+		assert.NotEqual(t, "id", f.Name)
+		assert.NotEqual(t, "path", f.Name)
+	}
+	assert.Len(t, path.FieldsExcept("id", "path"), len(path.Fields())-2)
+}
+
+func TestFieldsDiffers(t *testing.T) {
+	a := path.Info{
+		Path:    "a",
+		Size:    1,
+		Mode:    0644,
+		ModTime: time.Date(2025, 4, 5, 10, 0, 0, 0, time.UTC),
+	}
+	b := a
+	b.ModTime = a.ModTime.In(time.FixedZone("test", 3600))
+
+	for _, f := range path.Fields() {
+		if f.Differs != nil {
+			assert.False(t, f.Differs(&a, &b), f.Name)
+			assert.NotEmpty(t, f.Change, f.Name)
+		}
+	}
+
+	b.Size = 2
+	f := path.FieldsNamed("size")[0]
+	assert.True(t, f.Differs(&a, &b))
+	assert.Equal(t, "size", f.Change)
+}
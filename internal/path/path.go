@@ -22,9 +22,14 @@
 package path
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/andrejacobs/go-aj/file"
 )
@@ -46,7 +51,15 @@ type Info struct {
 // Stringer implementation.
 func (p Info) String() string {
 	// See Header() to ensure these match if any changes are made
-	return fmt.Sprintf("{%x}, %v, %q, %v, %v", p.Id, p.Size, p.Path, p.Mode, p.ModTime.Format(time.RFC3339Nano))
+	return fmt.Sprintf("{%x}, %v, %q, %v, %v", p.Id, p.Size, p.Path, p.Mode, p.ModTime.Format(time.RFC3339Nano)) +
+		displayText(&p, FieldOptions{})
+}
+
+// Return the same columns as String() with the file signature hash after the identifier.
+// See HeaderWithHash().
+func (p *Info) StringWithHash(hash string, opts FieldOptions) string {
+	return fmt.Sprintf("{%x}, %s, %v, %q, %v, %v", p.Id, hash, p.Size, p.Path, p.Mode, opts.time(p.ModTime).Format(time.RFC3339Nano)) +
+		displayText(p, opts)
 }
 
 // Return true if the path is a directory.
@@ -73,6 +86,16 @@ func IdFromPath(path string) Id {
 	return Id(file.CalculatePathHash(path))
 }
 
+// Create a path identifier that is unique to the namespace.
+// Databases that use different namespaces don't share identifiers, even for the same relative path.
+// An empty namespace creates the same identifier as [IdFromPath].
+func IdFromPathInNamespace(namespace string, path string) Id {
+	if namespace == "" {
+		return IdFromPath(path)
+	}
+	return Id(file.CalculatePathHash(namespace + "\x00" + path))
+}
+
 // Create the path info from the results of a file system walk [filepath.WalkDir] or [file.Walker].
 func InfoFromWalk(path string, entry fs.DirEntry) (Info, error) {
 	fileInfo, err := entry.Info()
@@ -89,15 +112,116 @@ func InfoFromWalk(path string, entry fs.DirEntry) (Info, error) {
 	}, nil
 }
 
+// Return true if the path is equal to the prefix or is a descendant of the prefix.
+// Both paths are expected to be relative to the same root and prefix is expected to be cleaned [filepath.Clean].
+// A prefix of "." matches all paths.
+func IsUnder(path string, prefix string) bool {
+	if prefix == "." || path == prefix {
+		return true
+	}
+
+	rest, found := strings.CutPrefix(path, prefix)
+	return found && len(rest) > 0 && rest[0] == filepath.Separator
+}
+
+// ErrInvalidPath is returned when a path can't be recorded relative to the root of the file hierarchy.
+var ErrInvalidPath = errors.New("invalid path")
+
+// Normalize a path that is relative to the root of the file hierarchy.
+// Redundant separators, "." and ".." elements and trailing separators are removed (see [filepath.Clean]).
+// On Windows the forward slashes are also replaced with backslashes.
+// An [ErrInvalidPath] error is returned if the path is empty, absolute or escapes the root.
+func Normalize(p string) (string, error) {
+	if p == "" {
+		return "", fmt.Errorf("%w. the path is empty", ErrInvalidPath)
+	}
+
+	clean := filepath.Clean(p)
+	if filepath.IsAbs(clean) || filepath.VolumeName(clean) != "" || strings.HasPrefix(clean, string(filepath.Separator)) {
+		return "", fmt.Errorf("%w %q. the path must be relative to the root", ErrInvalidPath, p)
+	}
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w %q. the path is outside of the root", ErrInvalidPath, p)
+	}
+	return clean, nil
+}
+
+// Escape a path so that it can be displayed and exported as text.
+// Paths are recorded as the raw bytes returned by the file system, which on Linux are not required to be valid
+// UTF-8. A path that is valid UTF-8 is returned unchanged. Otherwise each byte that is not part of a valid UTF-8
+// sequence is replaced by \xNN and each backslash is replaced by \\, so that [Unescape] returns the original bytes.
+func Escape(p string) string {
+	if utf8.ValidString(p) {
+		return p
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(p) + 8)
+	for i := 0; i < len(p); {
+		r, size := utf8.DecodeRuneInString(p[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&sb, "\\x%02x", p[i])
+		case r == '\\':
+			sb.WriteString("\\\\")
+		default:
+			sb.WriteString(p[i : i+size])
+		}
+		i += size
+	}
+	return sb.String()
+}
+
+// Unescape a path that was escaped by [Escape].
+// Since a path that is valid UTF-8 is never escaped, s is returned unchanged when it does not contain valid escape
+// sequences or when unescaping it would result in valid UTF-8.
+// A valid UTF-8 path that contains an escape sequence like \xe9 itself is ambiguous and is unescaped, which is why
+// the JSON exports also contain the exact bytes.
+func Unescape(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			sb.WriteByte(s[i])
+			continue
+		}
+
+		rest := s[i+1:]
+		switch {
+		case strings.HasPrefix(rest, "\\"):
+			sb.WriteByte('\\')
+			i++
+		case len(rest) >= 3 && rest[0] == 'x':
+			b, err := strconv.ParseUint(rest[1:3], 16, 8)
+			if err != nil {
+				return s
+			}
+			sb.WriteByte(byte(b))
+			i += 3
+		default:
+			return s
+		}
+	}
+
+	if result := sb.String(); !utf8.ValidString(result) {
+		return result
+	}
+	return s
+}
+
 //-----------------------------------------------------------------------------
 
 // Header returns a comma separated list of the expected columns that will be outputted by Info.String().
 func Header() string {
 	// See Infor.String() to ensure they match if any changes are made
-	return "Id, Size, Path, Mode, Modification time"
+	return "Id, Size, Path, Mode, Modification time" + displayHeader()
 }
 
 // Header returns a comma separated list of the expected columns that will be outputted for paths with a file signature hash.
 func HeaderWithHash() string {
-	return "Id, Hash, Size, Path, Mode, Modification time"
+	return "Id, Hash, Size, Path, Mode, Modification time" + displayHeader()
 }
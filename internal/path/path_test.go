@@ -22,11 +22,13 @@ package path_test
 
 import (
 	"crypto/sha1"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/andrejacobs/ajfs/internal/path"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIdFromPath(t *testing.T) {
@@ -34,6 +36,13 @@ func TestIdFromPath(t *testing.T) {
 	assert.Equal(t, path.Id(sha1.Sum([]byte("/usr/bin"))), id)
 }
 
+func TestIdFromPathInNamespace(t *testing.T) {
+	assert.Equal(t, path.IdFromPath("a/b.txt"), path.IdFromPathInNamespace("", "a/b.txt"))
+	assert.NotEqual(t, path.IdFromPath("a/b.txt"), path.IdFromPathInNamespace("uuid:1", "a/b.txt"))
+	assert.NotEqual(t, path.IdFromPathInNamespace("uuid:1", "a/b.txt"), path.IdFromPathInNamespace("uuid:2", "a/b.txt"))
+	assert.Equal(t, path.IdFromPathInNamespace("uuid:1", "a/b.txt"), path.IdFromPathInNamespace("uuid:1", "a/b.txt"))
+}
+
 func TestPathInfoEquals(t *testing.T) {
 	p1 := path.Info{
 		Id:      path.IdFromPath("a/b/c"),
@@ -87,3 +96,56 @@ func TestPathInfoEquals(t *testing.T) {
 	}))
 
 }
+
+func TestIsUnder(t *testing.T) {
+	assert.True(t, path.IsUnder("a/b/c", "."))
+	assert.True(t, path.IsUnder(".", "."))
+	assert.True(t, path.IsUnder("a", "a"))
+	assert.True(t, path.IsUnder("a/b", "a"))
+	assert.True(t, path.IsUnder("a/b/c", "a/b"))
+
+	assert.False(t, path.IsUnder(".", "a"))
+	assert.False(t, path.IsUnder("ab", "a"))
+	assert.False(t, path.IsUnder("ab/c", "a"))
+	assert.False(t, path.IsUnder("a", "a/b"))
+}
+
+func TestNormalize(t *testing.T) {
+	valid := map[string]string{
+		".":             ".",
+		"./":            ".",
+		"a":             "a",
+		"a/":            "a",
+		"./a//b/":       "a/b",
+		"a/b/../c":      "a/c",
+		"a/..":          ".",
+		"..a/b":         "..a/b",
+		"a/b/c.txt":     "a/b/c.txt",
+		"a/./b/./c.txt": "a/b/c.txt",
+	}
+	for input, expected := range valid {
+		p, err := path.Normalize(filepath.FromSlash(input))
+		require.NoError(t, err, input)
+		assert.Equal(t, filepath.FromSlash(expected), p, input)
+	}
+
+	for _, input := range []string{"", "/", "/a/b", "..", "../a", "a/../../b"} {
+		_, err := path.Normalize(filepath.FromSlash(input))
+		assert.ErrorIs(t, err, path.ErrInvalidPath, input)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	assert.Equal(t, "a/b/c.txt", path.Escape("a/b/c.txt"))
+	assert.Equal(t, "a/café.txt", path.Escape("a/café.txt"))
+	assert.Equal(t, `a\b`, path.Escape(`a\b`))
+
+	// Latin-1 encoded café
+	assert.Equal(t, `a/caf\xe9.txt`, path.Escape("a/caf\xe9.txt"))
+	assert.Equal(t, `\xff\\x\xfe`, path.Escape("\xff\\x\xfe"))
+
+	for _, p := range []string{"a/b/c.txt", `a\b`, "a/caf\xe9.txt", "\xff\\x\xfe", "\xc3", `\\xff`} {
+		assert.Equal(t, p, path.Unescape(path.Escape(p)), p)
+	}
+	assert.Equal(t, `a\xzz`, path.Unescape(`a\xzz`))
+}
@@ -23,12 +23,18 @@ package scanner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/fs"
+	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strings"
 
+	"github.com/andrejacobs/ajfs/internal/archive"
 	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/errlog"
 	"github.com/andrejacobs/ajfs/internal/path"
 	"github.com/andrejacobs/go-aj/file"
 )
@@ -40,6 +46,27 @@ type Scanner struct {
 
 	DirExcluder  file.MatchPathFn // Determine which directories should not be walked
 	FileExcluder file.MatchPathFn // Determine which files should not be walked
+
+	// When set, paths that could not be read because of a permission error are recorded in
+	// this log and the scan continues instead of being aborted.
+	Unreadable *errlog.Log
+
+	// When set, this is called after each entry has been written to the database.
+	// idx is the index of the entry in the database and path is the full path that was walked.
+	OnEntry func(idx int, path string, pi path.Info) error
+
+	// Write the entries in lexicographic path order instead of the order in which they were walked.
+	// The entries are kept in memory until the walk has finished.
+	Sorted bool
+
+	// Descend into tar and zip archives and record their members as virtual entries (e.g. "backup.tar!/dir/file").
+	Archives bool
+}
+
+// An entry that was found while walking and is waiting to be written in sorted order.
+type pendingEntry struct {
+	fullPath string
+	info     path.Info
 }
 
 // Create a new scanner.
@@ -75,11 +102,20 @@ func (s Scanner) Scan(ctx context.Context, dbf *db.DatabaseFile) error {
 	w.FileExcluder = s.FileExcluder
 	w.DirExcluder = s.DirExcluder
 
-	fn := func(rcvPath string, d fs.DirEntry, rcvErr error) error {
-		if rcvErr != nil {
-			return rcvErr
+	write := func(fullPath string, info path.Info) error {
+		if err := dbf.WriteEntry(&info); err != nil {
+			return err
 		}
 
+		if s.OnEntry != nil {
+			return s.OnEntry(dbf.EntriesCount()-1, fullPath, info)
+		}
+		return nil
+	}
+
+	var pending []pendingEntry
+
+	fn := func(rcvPath string, d fs.DirEntry, rcvErr error) error {
 		if err := ctx.Err(); err != nil {
 			return err
 		}
@@ -89,17 +125,201 @@ func (s Scanner) Scan(ctx context.Context, dbf *db.DatabaseFile) error {
 			return err
 		}
 
+		if rcvErr != nil {
+			// The root path itself being unreadable is still fatal
+			if !s.canSkip(rcvErr) || (relPath == ".") {
+				return rcvErr
+			}
+			// The directory entry has already been written, only its children could not be read
+			if err = s.Unreadable.Add(relPath, rcvErr); err != nil {
+				return err
+			}
+			return fs.SkipDir
+		}
+
 		info, err := path.InfoFromWalk(relPath, d)
 		if err != nil {
-			return err
+			if !s.canSkip(err) {
+				return err
+			}
+			// Record a stub entry so that the path is still known to exist
+			if err = s.Unreadable.Add(relPath, err); err != nil {
+				return err
+			}
+			info = path.Info{
+				Id:   path.IdFromPath(relPath),
+				Path: relPath,
+				Mode: d.Type(),
+			}
+		}
+
+		entries := []pendingEntry{{fullPath: rcvPath, info: info}}
+		if s.Archives && info.IsFile() && archive.IsArchive(relPath) {
+			members, err := s.archiveMembers(ctx, rcvPath, relPath)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, members...)
 		}
 
-		return dbf.WriteEntry(&info)
+		if s.Sorted {
+			pending = append(pending, entries...)
+			return nil
+		}
+		for _, e := range entries {
+			if err := write(e.fullPath, e.info); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 
 	if err := w.Walk(dbf.RootPath(), fn); err != nil {
 		return fmt.Errorf("failed to scan %q and create ajfs database %q. %w", dbf.RootPath(), dbf.Path(), err)
 	}
 
+	if s.Sorted {
+		slices.SortFunc(pending, func(a, b pendingEntry) int {
+			return comparePaths(a.info.Path, b.info.Path)
+		})
+
+		for _, p := range pending {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := write(p.fullPath, p.info); err != nil {
+				return err
+			}
+		}
+	}
+
 	return dbf.FinishEntries()
 }
+
+// ScanImage writes the contents of a disk image or archive to the database as if it was a mounted file hierarchy.
+// The root path of dbf must be the path of the image followed by [archive.Marker] (see [ImageRoot]) so that the
+// entries can later be read again to calculate the file signature hashes.
+func (s Scanner) ScanImage(ctx context.Context, dbf *db.DatabaseFile) error {
+	imagePath := strings.TrimSuffix(dbf.RootPath(), archive.Marker)
+	stat, err := os.Stat(imagePath)
+	if err != nil {
+		return fmt.Errorf("failed to scan the image %q. %w", imagePath, err)
+	}
+	if !stat.Mode().IsRegular() || !archive.IsArchive(imagePath) {
+		return fmt.Errorf("failed to scan the image %q. unsupported image format", imagePath)
+	}
+
+	write := func(fullPath string, info path.Info) error {
+		if err := dbf.WriteEntry(&info); err != nil {
+			return err
+		}
+		if s.OnEntry != nil {
+			return s.OnEntry(dbf.EntriesCount()-1, fullPath, info)
+		}
+		return nil
+	}
+
+	// The image itself is the root directory
+	err = write(dbf.RootPath(), path.Info{
+		Id:      path.IdFromPath("."),
+		Path:    ".",
+		Mode:    fs.ModeDir | stat.Mode().Perm(),
+		ModTime: stat.ModTime(),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.Unreadable = nil // A damaged image can't be partially recorded
+	members, err := s.archiveMembers(ctx, imagePath, "")
+	if err != nil {
+		return fmt.Errorf("failed to scan the image %q. %w", imagePath, err)
+	}
+
+	if s.Sorted {
+		slices.SortFunc(members, func(a, b pendingEntry) int {
+			return comparePaths(a.info.Path, b.info.Path)
+		})
+	}
+	for _, m := range members {
+		if err := write(m.fullPath, m.info); err != nil {
+			return err
+		}
+	}
+
+	return dbf.FinishEntries()
+}
+
+// Return the root path to be stored in the database when the contents of the image at imagePath is scanned.
+func ImageRoot(imagePath string) string {
+	return imagePath + archive.Marker
+}
+
+// Return the members of the archive as entries to be written after the archive itself.
+// An empty relPath means the archive is the root of the database and the members are recorded relative to it.
+// An archive that can't be read is recorded as unreadable when possible, otherwise the scan is aborted.
+func (s Scanner) archiveMembers(ctx context.Context, fullPath string, relPath string) ([]pendingEntry, error) {
+	var members []pendingEntry
+	seen := make(map[string]bool)
+
+	virtualPath := func(p string, name string) string {
+		if p == "" {
+			return filepath.FromSlash(name)
+		}
+		return archive.VirtualPath(p, name)
+	}
+
+	err := archive.Walk(ctx, fullPath, func(m archive.Member) error {
+		// A tar archive can contain the same member more than once. Only the first one is recorded since that is
+		// also the one that will be found when the member is hashed.
+		if seen[m.Name] {
+			return nil
+		}
+		seen[m.Name] = true
+
+		memberPath := virtualPath(relPath, m.Name)
+		members = append(members, pendingEntry{
+			fullPath: archive.VirtualPath(fullPath, m.Name),
+			info: path.Info{
+				Id:      path.IdFromPath(memberPath),
+				Path:    memberPath,
+				Size:    m.Size,
+				Mode:    m.Mode,
+				ModTime: m.ModTime,
+			},
+		})
+		return nil
+	})
+
+	if err != nil {
+		if (s.Unreadable == nil) || (ctx.Err() != nil) {
+			return nil, err
+		}
+		// Skip the members of a damaged archive, the archive itself is still recorded
+		if err = s.Unreadable.Add(relPath, err); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	return members, nil
+}
+
+// comparePaths orders relative paths lexicographically using "/" as the separator on all platforms.
+// The root path "." is always ordered first.
+func comparePaths(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "." {
+		return -1
+	}
+	if b == "." {
+		return 1
+	}
+	return strings.Compare(filepath.ToSlash(a), filepath.ToSlash(b))
+}
+
+// Return true if the error can be recorded as an unreadable path and the scan continue.
+func (s Scanner) canSkip(err error) bool {
+	return (s.Unreadable != nil) && errors.Is(err, fs.ErrPermission)
+}
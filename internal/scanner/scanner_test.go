@@ -21,13 +21,16 @@
 package scanner_test
 
 import (
+	"archive/zip"
 	"context"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/andrejacobs/ajfs/internal/db"
+	"github.com/andrejacobs/ajfs/internal/errlog"
 	"github.com/andrejacobs/ajfs/internal/path"
 	"github.com/andrejacobs/ajfs/internal/scanner"
 	"github.com/andrejacobs/go-aj/file"
@@ -102,6 +105,187 @@ func TestScanCancelled(t *testing.T) {
 	require.ErrorIs(t, err, context.Canceled)
 }
 
+func TestScanSkipUnreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for the root user")
+	}
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "1.txt"), []byte("1"), 0644))
+	locked := filepath.Join(root, "locked")
+	require.NoError(t, os.Mkdir(locked, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(locked, "2.txt"), []byte("2"), 0644))
+	require.NoError(t, os.Chmod(locked, 0))
+	defer os.Chmod(locked, 0755) //nolint:errcheck
+
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	// Without an error log the scan is aborted
+	dbf, err := db.CreateDatabase(tempFile, root, db.FeatureJustEntries)
+	require.NoError(t, err)
+	s := scanner.NewScanner()
+	require.ErrorIs(t, s.Scan(context.Background(), dbf), fs.ErrPermission)
+	require.NoError(t, dbf.Interrupted())
+
+	// With an error log the unreadable directory is recorded and skipped
+	dbf, err = db.CreateDatabase(tempFile, root, db.FeatureJustEntries)
+	require.NoError(t, err)
+	s.Unreadable, err = errlog.Open(errlog.PathFor(tempFile))
+	require.NoError(t, err)
+	require.NoError(t, s.Scan(context.Background(), dbf))
+	require.NoError(t, s.Unreadable.Close())
+	require.NoError(t, dbf.Close())
+
+	assert.True(t, s.Unreadable.Contains("locked"))
+	assert.Equal(t, 1, s.Unreadable.Count())
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	paths := make([]string, 0)
+	require.NoError(t, dbf.ReadAllEntries(context.Background(), func(idx int, pi path.Info) error {
+		paths = append(paths, pi.Path)
+		return nil
+	}))
+	assert.Equal(t, []string{".", "1.txt", "locked"}, paths)
+}
+
+func TestScanSorted(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "a"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "b"), []byte("1"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a-b"), []byte("2"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "-c"), []byte("3"), 0644))
+
+	scanPaths := func(sorted bool) []string {
+		tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+		dbf, err := db.CreateDatabase(tempFile, root, db.FeatureJustEntries)
+		require.NoError(t, err)
+
+		s := scanner.NewScanner()
+		s.Sorted = sorted
+		require.NoError(t, s.Scan(context.Background(), dbf))
+		require.NoError(t, dbf.Close())
+
+		dbf, err = db.OpenDatabase(tempFile)
+		require.NoError(t, err)
+		defer dbf.Close()
+
+		paths := make([]string, 0)
+		require.NoError(t, dbf.ReadAllEntries(context.Background(), func(idx int, pi path.Info) error {
+			paths = append(paths, pi.Path)
+			return nil
+		}))
+		return paths
+	}
+
+	assert.Equal(t, []string{".", "-c", "a", "a/b", "a-b"}, scanPaths(false))
+	assert.Equal(t, []string{".", "-c", "a", "a-b", "a/b"}, scanPaths(true))
+}
+
+func TestScanArchives(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "1.txt"), []byte("1"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "damaged.zip"), []byte("not a zip file"), 0644))
+
+	f, err := os.Create(filepath.Join(root, "backup.zip"))
+	require.NoError(t, err)
+	zw := zip.NewWriter(f)
+	for _, name := range []string{"dir/", "dir/2.txt", "3.txt"} {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		if !strings.HasSuffix(name, "/") {
+			_, err = w.Write([]byte(name))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, f.Close())
+
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+
+	// Without an error log a damaged archive aborts the scan
+	dbf, err := db.CreateDatabase(tempFile, root, db.FeatureJustEntries)
+	require.NoError(t, err)
+	s := scanner.NewScanner()
+	s.Archives = true
+	require.ErrorContains(t, s.Scan(context.Background(), dbf), "damaged.zip")
+	require.NoError(t, dbf.Interrupted())
+
+	// With an error log the members of the damaged archive are skipped
+	dbf, err = db.CreateDatabase(tempFile, root, db.FeatureJustEntries)
+	require.NoError(t, err)
+	s.Sorted = true
+	s.Unreadable, err = errlog.Open(errlog.PathFor(tempFile))
+	require.NoError(t, err)
+	require.NoError(t, s.Scan(context.Background(), dbf))
+	require.NoError(t, s.Unreadable.Close())
+	require.NoError(t, dbf.Close())
+
+	assert.True(t, s.Unreadable.Contains("damaged.zip"))
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+
+	entries := make(map[string]path.Info)
+	paths := make([]string, 0)
+	require.NoError(t, dbf.ReadAllEntries(context.Background(), func(idx int, pi path.Info) error {
+		paths = append(paths, filepath.ToSlash(pi.Path))
+		entries[filepath.ToSlash(pi.Path)] = pi
+		return nil
+	}))
+	assert.Equal(t, []string{".", "1.txt", "backup.zip", "backup.zip!/3.txt", "backup.zip!/dir", "backup.zip!/dir/2.txt", "damaged.zip"}, paths)
+
+	member := entries["backup.zip!/dir/2.txt"]
+	assert.True(t, member.IsFile())
+	assert.Equal(t, uint64(len("dir/2.txt")), member.Size)
+	assert.True(t, entries["backup.zip!/dir"].Mode.IsDir())
+}
+
+func TestScanImage(t *testing.T) {
+	image := filepath.Join(t.TempDir(), "image.zip")
+	f, err := os.Create(image)
+	require.NoError(t, err)
+	zw := zip.NewWriter(f)
+	for _, name := range []string{"b.txt", "a/1.txt"} {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(name))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, f.Close())
+
+	tempFile := filepath.Join(t.TempDir(), "unit-test.ajfs")
+	dbf, err := db.CreateDatabase(tempFile, scanner.ImageRoot(image), db.FeatureJustEntries)
+	require.NoError(t, err)
+
+	s := scanner.NewScanner()
+	s.Sorted = true
+	require.NoError(t, s.ScanImage(context.Background(), dbf))
+	require.NoError(t, dbf.Close())
+
+	dbf, err = db.OpenDatabase(tempFile)
+	require.NoError(t, err)
+	defer dbf.Close()
+	assert.Equal(t, image+"!", dbf.RootPath())
+
+	paths := make([]string, 0)
+	require.NoError(t, dbf.ReadAllEntries(context.Background(), func(idx int, pi path.Info) error {
+		paths = append(paths, filepath.ToSlash(pi.Path))
+		return nil
+	}))
+	assert.Equal(t, []string{".", "a/1.txt", "b.txt"}, paths)
+
+	// Only archives and disk images can be scanned
+	dbf, err = db.CreateDatabase(tempFile+"2", scanner.ImageRoot(tempFile), db.FeatureJustEntries)
+	require.NoError(t, err)
+	require.ErrorContains(t, s.ScanImage(context.Background(), dbf), "unsupported image format")
+	require.NoError(t, dbf.Interrupted())
+}
+
 //-----------------------------------------------------------------------------
 
 // func TestLocalScan(t *testing.T) {
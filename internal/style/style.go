@@ -0,0 +1,77 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package style provides the presentation used when the output of a command is displayed on an interactive terminal.
+//
+// Styled output uses colors and aligned columns with human readable sizes. It is only used when standard out is a
+// terminal, the NO_COLOR environment variable (see https://no-color.org) is not set and --no-color was not specified.
+// Output that is redirected to a file or piped to another command is therefore never affected.
+package style
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// Color used to display text.
+type Color int
+
+const (
+	Default Color = iota
+	Red
+	Green
+	Yellow
+	Blue
+	Bold
+	Dim
+)
+
+// ANSI escape codes for each color.
+var escapeCodes = map[Color]string{
+	Red:    "\x1b[31m",
+	Green:  "\x1b[32m",
+	Yellow: "\x1b[33m",
+	Blue:   "\x1b[34m",
+	Bold:   "\x1b[1m",
+	Dim:    "\x1b[2m",
+}
+
+const resetCode = "\x1b[0m"
+
+// Wrap the text in the escape codes used to display it in the color.
+func Paint(c Color, s string) string {
+	code, ok := escapeCodes[c]
+	if !ok || s == "" {
+		return s
+	}
+	return code + s + resetCode
+}
+
+// Return true if styled output should be used for the file.
+// The file needs to be a terminal that is able to display colors and the NO_COLOR environment variable must not be set.
+func Supported(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return term.IsTerminal(int(f.Fd())) //nolint:gosec // disable G115
+}
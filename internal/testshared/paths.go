@@ -21,6 +21,7 @@
 package testshared
 
 import (
+	"context"
 	"io/fs"
 	"path/filepath"
 
@@ -79,7 +80,7 @@ func DatabasePaths(dbPath string) ([]path.Info, error) {
 
 	result := make([]path.Info, 0, 32)
 
-	err = dbf.ReadAllEntries(func(idx int, pi path.Info) error {
+	err = dbf.ReadAllEntries(context.Background(), func(idx int, pi path.Info) error {
 		result = append(result, pi)
 		return nil
 	})
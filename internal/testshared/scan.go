@@ -0,0 +1,55 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package testshared
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/app/config"
+	"github.com/andrejacobs/ajfs/internal/app/scan"
+	"github.com/andrejacobs/go-aj/ajhash"
+	"github.com/stretchr/testify/require"
+)
+
+// Return the config used to scan the file hierarchy at root into the database at dbPath.
+// When hashes is true the SHA-1 file signature hashes are calculated.
+func ScanConfig(dbPath string, root string, hashes bool) scan.Config {
+	return scan.Config{
+		CommonConfig: config.CommonConfig{
+			Stdout: io.Discard,
+			Stderr: io.Discard,
+			DbPath: dbPath,
+		},
+		Root:            root,
+		CalculateHashes: hashes,
+		Algo:            ajhash.AlgoSHA1,
+	}
+}
+
+// Scan the file hierarchy at root into a new database at dbPath and return dbPath.
+// When hashes is true the SHA-1 file signature hashes are calculated.
+func ScanDatabase(t *testing.T, dbPath string, root string, hashes bool) string {
+	t.Helper()
+	require.NoError(t, scan.Run(context.Background(), ScanConfig(dbPath, root, hashes)))
+	return dbPath
+}
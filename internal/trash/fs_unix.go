@@ -0,0 +1,57 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//go:build unix
+
+package trash
+
+import (
+	"os"
+	"syscall"
+)
+
+// Return an identifier for the file system the directory lives on and the candidate
+// directories for the trash, from the top of the file system down to dir itself.
+func fileSystem(dir string) (any, []string) {
+	dev, ok := device(dir)
+	if !ok {
+		return dir, []string{dir}
+	}
+
+	all := parents(dir)
+	// Skip the parents that live on another file system (i.e. above the mount point)
+	for i, p := range all {
+		if d, ok := device(p); ok && d == dev {
+			return dev, all[i:]
+		}
+	}
+	return dev, []string{dir}
+}
+
+func device(p string) (uint64, bool) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return 0, false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Dev), true //nolint:unconvert // Dev is not a uint64 on all platforms
+}
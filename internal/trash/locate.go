@@ -0,0 +1,299 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package trash
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Locator finds the files that were moved into a trash instead of being deleted.
+//
+// The following trash directories are checked:
+//   - The ajfs trash (see [Trash]) on the same file system.
+//   - The freedesktop.org trash used on Linux (~/.local/share/Trash and the .Trash directories at the top of each file system).
+//   - The Windows Recycle Bin ($Recycle.Bin at the top of each drive).
+//   - The macOS Trash (~/.Trash and the .Trashes directory at the top of each volume). The macOS Trash does not record
+//     where a file came from and thus a file is only matched by its name (and size).
+type Locator struct {
+	home string // Home directory of the user, empty if it could not be determined
+	uid  string // Identifier of the user as used in the names of the trash directories
+
+	homeTrash     []trashedItem            // Items in the trash inside of the home directory
+	homeTrashRead bool                     // Has the trash inside of the home directory been read
+	topTrash      map[string][]trashedItem // Top of a file system to the items in its trash directories
+}
+
+// An item in a trash directory for which the original path is known.
+type trashedItem struct {
+	original string // Absolute path from where the item was moved
+	trashed  string // Absolute path to where the item was moved
+}
+
+// Create a locator for the trash directories of the current user.
+func NewLocator() *Locator {
+	home, _ := os.UserHomeDir()
+	return &Locator{
+		home:     home,
+		uid:      strconv.Itoa(os.Getuid()),
+		topTrash: make(map[string][]trashedItem),
+	}
+}
+
+// Find the file (or directory) that used to exist at the absolute path p.
+// size is only used to match files in the trash directories that do not record where a file came from.
+// Returns the path inside of the trash where it was found.
+func (l *Locator) Find(p string, size uint64, isDir bool) (string, bool) {
+	dir := existingParent(filepath.Dir(p))
+	_, candidates := fileSystem(dir)
+	top := candidates[0]
+
+	// ajfs trash: root/.ajfs-trash/session/relative-path
+	for _, root := range candidates {
+		rel, err := filepath.Rel(root, p)
+		if err != nil || !isUnder(p, root) {
+			continue
+		}
+		sessions, _ := filepath.Glob(filepath.Join(root, DirName, "*"))
+		for _, session := range sessions {
+			if trashed := filepath.Join(session, rel); exists(trashed) {
+				return trashed, true
+			}
+		}
+	}
+
+	// Trash directories that record where each item came from
+	for _, items := range [][]trashedItem{l.homeItems(), l.topItems(top)} {
+		for _, item := range items {
+			if !isUnder(p, item.original) {
+				continue
+			}
+			// A file inside of a directory that was moved as a whole
+			rel, err := filepath.Rel(item.original, p)
+			if err != nil {
+				continue
+			}
+			if trashed := filepath.Join(item.trashed, rel); exists(trashed) {
+				return trashed, true
+			}
+		}
+	}
+
+	// macOS Trash
+	dirs := []string{filepath.Join(top, ".Trashes", l.uid)}
+	if l.home != "" {
+		dirs = append(dirs, filepath.Join(l.home, ".Trash"))
+	}
+	for _, dir := range dirs {
+		trashed := filepath.Join(dir, filepath.Base(p))
+		info, err := os.Lstat(trashed)
+		if err != nil || info.IsDir() != isDir {
+			continue
+		}
+		if isDir || uint64(info.Size()) == size { //nolint:gosec // disable G115
+			return trashed, true
+		}
+	}
+
+	return "", false
+}
+
+// Return the items in the freedesktop.org trash in the home directory.
+func (l *Locator) homeItems() []trashedItem {
+	if !l.homeTrashRead {
+		l.homeTrashRead = true
+
+		dataHome := os.Getenv("XDG_DATA_HOME")
+		if dataHome == "" && l.home != "" {
+			dataHome = filepath.Join(l.home, ".local", "share")
+		}
+		if dataHome != "" {
+			l.homeTrash = readFreedesktopTrash(filepath.Join(dataHome, "Trash"), "")
+		}
+	}
+	return l.homeTrash
+}
+
+// Return the items in the trash directories at the top of the file system.
+func (l *Locator) topItems(top string) []trashedItem {
+	if items, ok := l.topTrash[top]; ok {
+		return items
+	}
+
+	items := readFreedesktopTrash(filepath.Join(top, ".Trash", l.uid), top)
+	items = append(items, readFreedesktopTrash(filepath.Join(top, ".Trash-"+l.uid), top)...)
+	items = append(items, readRecycleBin(filepath.Join(top, "$Recycle.Bin"))...)
+
+	l.topTrash[top] = items
+	return items
+}
+
+//-----------------------------------------------------------------------------
+
+// Read the items from a freedesktop.org trash directory (see https://specifications.freedesktop.org/trash-spec/).
+// Each item in files has a matching .trashinfo file in info that records where it came from.
+// Relative paths are relative to top.
+func readFreedesktopTrash(dir string, top string) []trashedItem {
+	infos, _ := filepath.Glob(filepath.Join(dir, "info", "*.trashinfo"))
+
+	result := make([]trashedItem, 0, len(infos))
+	for _, info := range infos {
+		original, ok := readTrashInfo(info)
+		if !ok {
+			continue
+		}
+		if !filepath.IsAbs(original) {
+			if top == "" {
+				continue
+			}
+			original = filepath.Join(top, original)
+		}
+
+		name := strings.TrimSuffix(filepath.Base(info), ".trashinfo")
+		result = append(result, trashedItem{
+			original: filepath.Clean(original),
+			trashed:  filepath.Join(dir, "files", name),
+		})
+	}
+	return result
+}
+
+// Return the original path recorded in a .trashinfo file.
+func readTrashInfo(p string) (string, bool) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "Path=")
+		if !ok {
+			continue
+		}
+		original, err := url.PathUnescape(value)
+		if err != nil {
+			return "", false
+		}
+		return filepath.FromSlash(original), true
+	}
+	return "", false
+}
+
+// Read the items from the Windows Recycle Bin.
+// Each user has a directory in which an item $Rxxxxxx has a matching $Ixxxxxx file that records where it came from.
+func readRecycleBin(dir string) []trashedItem {
+	infos, _ := filepath.Glob(filepath.Join(dir, "*", "$I*"))
+
+	result := make([]trashedItem, 0, len(infos))
+	for _, info := range infos {
+		original, ok := readRecycleBinInfo(info)
+		if !ok {
+			continue
+		}
+
+		name := "$R" + strings.TrimPrefix(filepath.Base(info), "$I")
+		result = append(result, trashedItem{
+			original: filepath.Clean(original),
+			trashed:  filepath.Join(filepath.Dir(info), name),
+		})
+	}
+	return result
+}
+
+// Return the original path recorded in a $I file of the Windows Recycle Bin.
+//
+// Version 1 (Windows Vista to 8.1) and version 2 (Windows 10 and later) are supported:
+//
+//	version (int64), size (int64), deleted at (FILETIME)
+//	version 1: path (fixed size of 260 UTF-16 characters)
+//	version 2: path length in characters (int32), path (UTF-16)
+func readRecycleBinInfo(p string) (string, bool) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	var header struct {
+		Version   int64
+		Size      int64
+		DeletedAt int64
+	}
+	if err := binary.Read(f, binary.LittleEndian, &header); err != nil {
+		return "", false
+	}
+
+	var chars uint32
+	switch header.Version {
+	case 1:
+		chars = 260
+	case 2:
+		if err := binary.Read(f, binary.LittleEndian, &chars); err != nil || chars > 32*1024 {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+
+	buf := make([]uint16, chars)
+	if err := binary.Read(io.LimitReader(f, int64(chars)*2), binary.LittleEndian, buf); err != nil {
+		return "", false
+	}
+	// The path is terminated by a null character
+	for i, c := range buf {
+		if c == 0 {
+			buf = buf[:i]
+			break
+		}
+	}
+	if len(buf) == 0 {
+		return "", false
+	}
+
+	return filepath.FromSlash(strings.ReplaceAll(string(utf16.Decode(buf)), `\`, "/")), true
+}
+
+// Return the directory or its closest parent that exists.
+func existingParent(dir string) string {
+	for {
+		if exists(dir) {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+func exists(p string) bool {
+	_, err := os.Lstat(p)
+	return err == nil
+}
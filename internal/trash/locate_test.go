@@ -0,0 +1,95 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package trash_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/trash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocator(t *testing.T) {
+	tempDir := t.TempDir()
+	home := filepath.Join(tempDir, "home")
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", "")
+
+	root := filepath.Join(tempDir, "root")
+	files := createFiles(t, root, "desktop.txt", "photos/1.jpg", "photos/2.jpg", "ajfs.txt", "mac.txt", "gone.txt")
+	for _, f := range files {
+		require.NoError(t, os.Remove(f))
+	}
+	require.NoError(t, os.Remove(filepath.Join(root, "photos")))
+
+	// freedesktop.org trash in the home directory
+	desktopTrash := filepath.Join(home, ".local", "share", "Trash")
+	createFiles(t, filepath.Join(desktopTrash, "files"), "desktop.txt", "photos.2/1.jpg")
+	writeTrashInfo := func(name string, original string) {
+		require.NoError(t, os.MkdirAll(filepath.Join(desktopTrash, "info"), 0755))
+		info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=2025-01-02T03:04:05\n", original)
+		require.NoError(t, os.WriteFile(filepath.Join(desktopTrash, "info", name+".trashinfo"), []byte(info), 0644))
+	}
+	writeTrashInfo("desktop.txt", files[0])
+	// The complete directory was moved into the trash
+	writeTrashInfo("photos.2", filepath.Join(root, "photos"))
+
+	// ajfs trash
+	createFiles(t, filepath.Join(root, trash.DirName, "20250102-030405-1"), "ajfs.txt")
+
+	// macOS trash
+	createFiles(t, filepath.Join(home, ".Trash"), "mac.txt")
+
+	l := trash.NewLocator()
+
+	p, ok := l.Find(files[0], 0, false)
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join(desktopTrash, "files", "desktop.txt"), p)
+
+	p, ok = l.Find(files[1], 0, false)
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join(desktopTrash, "files", "photos.2", "1.jpg"), p)
+
+	p, ok = l.Find(filepath.Join(root, "photos"), 0, true)
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join(desktopTrash, "files", "photos.2"), p)
+
+	// Not every file of the directory is in the trash
+	_, ok = l.Find(files[2], 0, false)
+	assert.False(t, ok)
+
+	p, ok = l.Find(files[3], 0, false)
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join(root, trash.DirName, "20250102-030405-1", "ajfs.txt"), p)
+
+	// Matched by name and size
+	_, ok = l.Find(files[4], 1, false)
+	assert.False(t, ok)
+	p, ok = l.Find(files[4], uint64(len("a/mac.txt")), false)
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join(home, ".Trash", "mac.txt"), p)
+
+	_, ok = l.Find(files[5], 0, false)
+	assert.False(t, ok)
+}
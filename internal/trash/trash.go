@@ -0,0 +1,368 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+// Package trash is used by destructive actions to move files out of the way instead of deleting them.
+//
+// Files are moved into a trash directory (.ajfs-trash) on the same file system so that
+// the move is a cheap rename and no data has to be copied. Each move is appended to an
+// undo manifest where each line is a JSON encoded [Entry]. The manifest can be replayed
+// using [Restore] (see "ajfs undo") to move the files back to where they came from.
+//
+// [Locator] is used to find files that were moved into the ajfs trash or into the trash of the operating system.
+package trash
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Name of the trash directory created at the top of each file system.
+const DirName = ".ajfs-trash"
+
+// Entry describes a file that was moved into the trash.
+type Entry struct {
+	Original string    `json:"original"` // Absolute path from where the file was moved.
+	Trashed  string    `json:"trashed"`  // Absolute path to where the file was moved.
+	Session  string    `json:"session"`  // The trash directory used by this run (inside a .ajfs-trash directory).
+	Time     time.Time `json:"time"`     // When the file was moved.
+}
+
+// Trash moves files into the trash and records each move in the undo manifest.
+type Trash struct {
+	// [optional] Use this directory for all the files instead of a trash directory at the
+	// top of each file system. Files on another file system can't be moved into it.
+	Dir string
+
+	manifestPath string
+	file         *os.File
+	enc          *json.Encoder
+	sessions     map[any][]string // File system identifier to the session directories
+	count        int
+}
+
+// Return a default path for an undo manifest in the current directory.
+func DefaultManifestPath() string {
+	return fmt.Sprintf("ajfs-undo-%s.jsonl", time.Now().Format("20060102-150405"))
+}
+
+// Create a new undo manifest and return the trash used to move files.
+// An existing manifest will not be overwritten.
+func New(manifestPath string) (*Trash, error) {
+	f, err := os.OpenFile(manifestPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the undo manifest %q. %w", manifestPath, err)
+	}
+
+	return &Trash{
+		manifestPath: manifestPath,
+		file:         f,
+		enc:          json.NewEncoder(f),
+		sessions:     make(map[any][]string),
+	}, nil
+}
+
+// Path of the undo manifest.
+func (t *Trash) ManifestPath() string {
+	return t.manifestPath
+}
+
+// Number of files that have been moved into the trash.
+func (t *Trash) Count() int {
+	return t.count
+}
+
+// Move the file (or directory) into the trash of the file system it lives on.
+// Returns the path to where it was moved.
+func (t *Trash) Move(p string) (string, error) {
+	original, err := filepath.Abs(p)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the absolute path for %q. %w", p, err)
+	}
+
+	if _, err := os.Lstat(original); err != nil {
+		return "", fmt.Errorf("failed to move %q into the trash. %w", original, err)
+	}
+
+	root, session, err := t.session(filepath.Dir(original))
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(root, original)
+	if err != nil {
+		return "", fmt.Errorf("failed to move %q into the trash. %w", original, err)
+	}
+	trashed := filepath.Join(session, rel)
+
+	if err := os.MkdirAll(filepath.Dir(trashed), 0700); err != nil {
+		return "", fmt.Errorf("failed to create the trash directory for %q. %w", original, err)
+	}
+	if err := os.Rename(original, trashed); err != nil {
+		return "", fmt.Errorf("failed to move %q into the trash. %w", original, err)
+	}
+
+	entry := Entry{
+		Original: original,
+		Trashed:  trashed,
+		Session:  session,
+		Time:     time.Now().UTC(),
+	}
+	if err := t.enc.Encode(entry); err != nil {
+		// A move that can't be undone is not allowed
+		if renameErr := os.Rename(trashed, original); renameErr != nil {
+			return "", fmt.Errorf("failed to write to the undo manifest %q (%w) and failed to move %q back. %w", t.manifestPath, err, original, renameErr)
+		}
+		return "", fmt.Errorf("failed to write to the undo manifest %q. %w", t.manifestPath, err)
+	}
+
+	t.count++
+	return trashed, nil
+}
+
+// Close the undo manifest.
+// The manifest is removed when no files were moved into the trash.
+func (t *Trash) Close() error {
+	if t.file == nil {
+		return nil
+	}
+
+	err := t.file.Close()
+	t.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close the undo manifest %q. %w", t.manifestPath, err)
+	}
+
+	if t.count == 0 {
+		return os.Remove(t.manifestPath)
+	}
+	return nil
+}
+
+// Return the root of the file system (or the highest directory on it that can be written to)
+// and the session directory inside of its trash directory.
+func (t *Trash) session(dir string) (string, string, error) {
+	id, candidates := fileSystem(dir)
+	if t.Dir != "" {
+		id = t.Dir
+		candidates = nil
+	}
+
+	for _, session := range t.sessions[id] {
+		root := t.rootFor(session, dir)
+		if isUnder(dir, root) {
+			return root, session, nil
+		}
+	}
+
+	if t.Dir != "" {
+		if err := os.MkdirAll(t.Dir, 0700); err != nil {
+			return "", "", fmt.Errorf("failed to create the trash directory %q. %w", t.Dir, err)
+		}
+		session, err := os.MkdirTemp(t.Dir, time.Now().Format("20060102-150405")+"-")
+		if err != nil {
+			return "", "", fmt.Errorf("failed to create a session inside the trash directory %q. %w", t.Dir, err)
+		}
+		t.sessions[id] = append(t.sessions[id], session)
+		return t.rootFor(session, dir), session, nil
+	}
+
+	// Prefer the top of the file system, but fall back to lower directories when it is not writable
+	var lastErr error
+	for _, root := range candidates {
+		trashDir := filepath.Join(root, DirName)
+		if err := os.MkdirAll(trashDir, 0700); err != nil {
+			lastErr = err
+			continue
+		}
+
+		session, err := os.MkdirTemp(trashDir, time.Now().Format("20060102-150405")+"-")
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		t.sessions[id] = append(t.sessions[id], session)
+		return root, session, nil
+	}
+
+	return "", "", fmt.Errorf("failed to create a trash directory for %q. %w", dir, lastErr)
+}
+
+// Return the directory that the paths inside of the session are relative to.
+func (t *Trash) rootFor(session string, dir string) string {
+	if t.Dir != "" {
+		// The complete path is kept, e.g. /trash/session/home/user/file.txt
+		return filepath.VolumeName(dir) + string(filepath.Separator)
+	}
+	// Session is at root/.ajfs-trash/session
+	return filepath.Dir(filepath.Dir(session))
+}
+
+// Return true if the path is the same as or inside of dir.
+func isUnder(p string, dir string) bool {
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Return the parent directories of dir, from the top most down to dir itself.
+func parents(dir string) []string {
+	result := []string{dir}
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		result = append([]string{parent}, result...)
+		dir = parent
+	}
+	return result
+}
+
+//-----------------------------------------------------------------------------
+
+// Read all the entries from the undo manifest.
+func ReadManifest(manifestPath string) ([]Entry, error) {
+	f, err := os.Open(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the undo manifest %q. %w", manifestPath, err)
+	}
+	defer f.Close()
+
+	result := make([]Entry, 0, 64)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to read the undo manifest %q (line %d). %w", manifestPath, line, err)
+		}
+		result = append(result, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the undo manifest %q. %w", manifestPath, err)
+	}
+
+	return result, nil
+}
+
+// Called by Restore for each entry in the undo manifest.
+// err will be nil if the file was moved back to its original path.
+type RestoreFn func(entry Entry, err error)
+
+// Indicates that a file could not be restored because the original path exists again.
+var ErrExists = errors.New("original path already exists")
+
+// Move all the files in the undo manifest back to their original paths.
+// The files are restored in the reverse order in which they were moved.
+// Entries that could not be restored are kept in the manifest so that the undo can be
+// retried. The manifest is removed once all the entries have been restored.
+func Restore(manifestPath string, fn RestoreFn) error {
+	entries, err := ReadManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	remaining := make([]Entry, 0)
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		err := restore(entry)
+		if fn != nil {
+			fn(entry, err)
+		}
+		if err != nil {
+			remaining = append([]Entry{entry}, remaining...)
+		}
+	}
+
+	if len(remaining) == 0 {
+		if err := os.Remove(manifestPath); err != nil {
+			return fmt.Errorf("failed to remove the undo manifest %q. %w", manifestPath, err)
+		}
+		return nil
+	}
+
+	return rewriteManifest(manifestPath, remaining)
+}
+
+// Move the file back to its original path and clean up the empty trash directories.
+func restore(entry Entry) error {
+	if _, err := os.Lstat(entry.Original); err == nil {
+		return fmt.Errorf("failed to restore %q. %w", entry.Original, ErrExists)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to restore %q. %w", entry.Original, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(entry.Original), 0755); err != nil {
+		return fmt.Errorf("failed to recreate the parent directory for %q. %w", entry.Original, err)
+	}
+	if err := os.Rename(entry.Trashed, entry.Original); err != nil {
+		return fmt.Errorf("failed to restore %q. %w", entry.Original, err)
+	}
+
+	removeEmptyDirs(filepath.Dir(entry.Trashed), filepath.Dir(entry.Session))
+	return nil
+}
+
+// Remove the directory and its parents while they are empty, up to and including stop.
+func removeEmptyDirs(dir string, stop string) {
+	for isUnder(dir, stop) {
+		// Fails when the directory is not empty
+		if os.Remove(dir) != nil || dir == stop {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func rewriteManifest(manifestPath string, entries []Entry) error {
+	tempPath := manifestPath + ".tmp"
+	f, err := os.OpenFile(tempPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to rewrite the undo manifest %q. %w", manifestPath, err)
+	}
+
+	enc := json.NewEncoder(f)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("failed to rewrite the undo manifest %q. %w", manifestPath, err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to rewrite the undo manifest %q. %w", manifestPath, err)
+	}
+
+	if err := os.Rename(tempPath, manifestPath); err != nil {
+		return fmt.Errorf("failed to rewrite the undo manifest %q. %w", manifestPath, err)
+	}
+	return nil
+}
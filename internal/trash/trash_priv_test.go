@@ -0,0 +1,84 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package trash
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParents(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses unix paths")
+	}
+
+	assert.Equal(t, []string{"/", "/a", "/a/b"}, parents("/a/b"))
+	assert.Equal(t, []string{"/"}, parents("/"))
+}
+
+func TestIsUnder(t *testing.T) {
+	root := filepath.FromSlash("/a/b")
+	assert.True(t, isUnder(root, root))
+	assert.True(t, isUnder(filepath.Join(root, "c"), root))
+	assert.False(t, isUnder(filepath.FromSlash("/a"), root))
+	assert.False(t, isUnder(filepath.FromSlash("/a/bc"), root))
+	assert.False(t, isUnder(filepath.FromSlash("/x/y"), root))
+}
+
+func TestReadRecycleBin(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "$Recycle.Bin")
+	user := filepath.Join(dir, "S-1-5-21-1000")
+	require.NoError(t, os.MkdirAll(user, 0755))
+
+	original := `C:\Users\me\Documents\report.docx`
+	chars := utf16.Encode([]rune(original + "\x00"))
+
+	// Version 2
+	var buf bytes.Buffer
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, []int64{2, 1234, 133000000000000000}))
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, uint32(len(chars))))
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, chars))
+	require.NoError(t, os.WriteFile(filepath.Join(user, "$IABC123.docx"), buf.Bytes(), 0644))
+
+	// Version 1 has a fixed size path
+	buf.Reset()
+	fixed := make([]uint16, 260)
+	copy(fixed, chars)
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, []int64{1, 1234, 133000000000000000}))
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, fixed))
+	require.NoError(t, os.WriteFile(filepath.Join(user, "$IDEF456.docx"), buf.Bytes(), 0644))
+
+	// Unknown version
+	require.NoError(t, os.WriteFile(filepath.Join(user, "$IGHI789.docx"), make([]byte, 32), 0644))
+
+	expected := filepath.Clean(filepath.FromSlash("C:/Users/me/Documents/report.docx"))
+	items := readRecycleBin(dir)
+	require.Len(t, items, 2)
+	assert.Equal(t, trashedItem{original: expected, trashed: filepath.Join(user, "$RABC123.docx")}, items[0])
+	assert.Equal(t, trashedItem{original: expected, trashed: filepath.Join(user, "$RDEF456.docx")}, items[1])
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2025 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package trash_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andrejacobs/ajfs/internal/trash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoveAndRestore(t *testing.T) {
+	tempDir := t.TempDir()
+	files := createFiles(t, tempDir, "a/1.txt", "a/b/2.txt", "c/3.txt")
+	manifestPath := filepath.Join(tempDir, "undo.jsonl")
+	trashDir := filepath.Join(tempDir, "trash")
+
+	tr, err := trash.New(manifestPath)
+	require.NoError(t, err)
+	tr.Dir = trashDir
+
+	for _, f := range files[:2] {
+		trashed, err := tr.Move(f)
+		require.NoError(t, err)
+		assert.NoFileExists(t, f)
+		assert.FileExists(t, trashed)
+		assert.True(t, strings.HasPrefix(trashed, trashDir))
+		assert.True(t, strings.HasSuffix(trashed, f))
+	}
+
+	// Directories can also be moved
+	_, err = tr.Move(filepath.Join(tempDir, "c"))
+	require.NoError(t, err)
+	assert.NoDirExists(t, filepath.Join(tempDir, "c"))
+
+	_, err = tr.Move(filepath.Join(tempDir, "missing.txt"))
+	require.Error(t, err)
+
+	assert.Equal(t, 3, tr.Count())
+	require.NoError(t, tr.Close())
+
+	entries, err := trash.ReadManifest(manifestPath)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, files[0], entries[0].Original)
+
+	var restored []string
+	require.NoError(t, trash.Restore(manifestPath, func(entry trash.Entry, err error) {
+		assert.NoError(t, err)
+		restored = append(restored, entry.Original)
+	}))
+
+	// Reverse order
+	assert.Equal(t, []string{filepath.Join(tempDir, "c"), files[1], files[0]}, restored)
+	for _, f := range files {
+		assert.FileExists(t, f)
+	}
+	assert.NoFileExists(t, manifestPath)
+	assert.NoDirExists(t, trashDir)
+}
+
+func TestRestoreConflict(t *testing.T) {
+	tempDir := t.TempDir()
+	files := createFiles(t, tempDir, "1.txt", "2.txt")
+	manifestPath := filepath.Join(tempDir, "undo.jsonl")
+
+	tr, err := trash.New(manifestPath)
+	require.NoError(t, err)
+	tr.Dir = filepath.Join(tempDir, "trash")
+	for _, f := range files {
+		_, err := tr.Move(f)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tr.Close())
+
+	// Something new was created at the original path
+	require.NoError(t, os.WriteFile(files[0], []byte("new"), 0644))
+
+	failed := 0
+	require.NoError(t, trash.Restore(manifestPath, func(entry trash.Entry, err error) {
+		if err != nil {
+			assert.ErrorIs(t, err, trash.ErrExists)
+			assert.Equal(t, files[0], entry.Original)
+			failed++
+		}
+	}))
+	assert.Equal(t, 1, failed)
+	assert.FileExists(t, files[1])
+
+	entries, err := trash.ReadManifest(manifestPath)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, files[0], entries[0].Original)
+
+	// Resolve the conflict and retry
+	require.NoError(t, os.Remove(files[0]))
+	require.NoError(t, trash.Restore(manifestPath, nil))
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Equal(t, "a/1.txt", string(data))
+	assert.NoFileExists(t, manifestPath)
+}
+
+func TestNewManifest(t *testing.T) {
+	manifestPath := filepath.Join(t.TempDir(), "undo.jsonl")
+
+	tr, err := trash.New(manifestPath)
+	require.NoError(t, err)
+	assert.Equal(t, manifestPath, tr.ManifestPath())
+
+	// Never overwrite an existing manifest
+	_, err = trash.New(manifestPath)
+	require.Error(t, err)
+
+	// Removed when nothing was moved
+	require.NoError(t, tr.Close())
+	assert.NoFileExists(t, manifestPath)
+}
+
+func createFiles(t *testing.T, dir string, paths ...string) []string {
+	result := make([]string, 0, len(paths))
+	for _, p := range paths {
+		full := filepath.Join(dir, p)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+		require.NoError(t, os.WriteFile(full, []byte("a/"+filepath.Base(p)), 0644))
+		result = append(result, full)
+	}
+	return result
+}
@@ -104,20 +104,11 @@ type SignaturedNode struct {
 	NextSibling *SignaturedNode
 }
 
-// Insert the node as a child.
-func (n *SignaturedNode) insertChild(c *SignaturedNode) {
-	// Insert the child as the first child and thus we don't have to walk to the end of the list
-	c.NextSibling = n.FirstChild
-	n.FirstChild = c
-}
-
 // Recursively display the children nodes.
 func (n *SignaturedNode) printChildren(w io.Writer, st *stats, prefix string) {
 	// Based on kddnewton's implementation: https://github.com/kddnewton/tree/blob/main/tree.go
-	children := n.sortedChildren()
-	count := len(children)
-
-	for i, child := range children {
+	// The children are linked in the order of their names (see buildNodes)
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
 		// Filter out hidden paths
 		if len(child.Node.Name) > 0 && (child.Node.Name[0] == '.') {
 			continue
@@ -131,7 +122,7 @@ func (n *SignaturedNode) printChildren(w io.Writer, st *stats, prefix string) {
 
 		signature := fmt.Sprintf("%x", child.Signature)
 
-		if i == count-1 {
+		if child.NextSibling == nil {
 			fmt.Fprintln(w, prefix+"└──", child.Node.Name, "    ["+signature+"]")
 			child.printChildren(w, st, prefix+"    ")
 		} else {
@@ -142,31 +133,6 @@ func (n *SignaturedNode) printChildren(w io.Writer, st *stats, prefix string) {
 	}
 }
 
-// Return the children nodes.
-func (n *SignaturedNode) children() []*SignaturedNode {
-	result := make([]*SignaturedNode, 0, 8)
-
-	current := n.FirstChild
-	for {
-		if current == nil { //nolint:staticcheck // QF1006
-			break
-		}
-		result = append(result, current)
-		current = current.NextSibling
-	}
-
-	return result
-}
-
-// Return the children nodes sorted by name.
-func (n *SignaturedNode) sortedChildren() []*SignaturedNode {
-	result := n.children()
-	sort.Slice(result, func(i, j int) bool {
-		return result[i].Node.Name < result[j].Node.Name
-	})
-	return result
-}
-
 //-----------------------------------------------------------------------------
 
 // Display the map of duplicates.
@@ -204,13 +170,21 @@ func buildNodes(parent *Node, signaturedParent *SignaturedNode, hasher hash.Hash
 		return
 	}
 
-	children := parent.sortedChildren()
-	for _, child := range children {
+	// The signatures depend on the order and thus always use the raw bytes
+	var last *SignaturedNode
+	for child := range parent.Children(nil) {
 		signaturedChild := &SignaturedNode{
 			Node: child,
 		}
 
-		signaturedParent.insertChild(signaturedChild)
+		// Link the children in the same order
+		if last == nil {
+			signaturedParent.FirstChild = signaturedChild
+		} else {
+			last.NextSibling = signaturedChild
+		}
+		last = signaturedChild
+
 		buildNodes(child, signaturedChild, sha1.New()) // #nosec G401 -- SHA1 is not used for cryptography
 		hasher.Write(signaturedChild.Signature[:])
 	}
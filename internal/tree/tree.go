@@ -24,17 +24,20 @@ package tree
 import (
 	"fmt"
 	"io"
+	"iter"
 	"path/filepath"
-	"sort"
 	"strings"
 
 	"github.com/andrejacobs/ajfs/internal/path"
+	"github.com/andrejacobs/ajfs/internal/style"
+	"github.com/andrejacobs/go-aj/human"
 )
 
 // Tree represents a file hierarchy.
 type Tree struct {
 	rootPath string
 	root     *Node
+	ids      map[path.Id]*Node // Nodes by the identifier of the inserted path info
 }
 
 // Create a new Tree representing the file hierarchy at the specified root path.
@@ -43,6 +46,7 @@ func New(rootPath string) Tree {
 	return Tree{
 		rootPath: rootPath,
 		root:     newNode("."),
+		ids:      make(map[path.Id]*Node, 64),
 	}
 }
 
@@ -64,6 +68,7 @@ func (t *Tree) Insert(pi path.Info) *Node {
 
 	if pi.Path == "." {
 		t.root.Info = pi
+		t.indexId(t.root)
 		return t.root
 	}
 
@@ -81,9 +86,17 @@ func (t *Tree) Insert(pi path.Info) *Node {
 		n = existing
 	}
 	n.Info = pi
+	t.indexId(n)
 	return n
 }
 
+// Record the node by its identifier. Nodes without an identifier are not indexed.
+func (t *Tree) indexId(n *Node) {
+	if n.Info.Id != (path.Id{}) {
+		t.ids[n.Info.Id] = n
+	}
+}
+
 // Find the node for the specified path.
 func (t *Tree) Find(path string) *Node {
 	if path == "." {
@@ -96,6 +109,12 @@ func (t *Tree) Find(path string) *Node {
 	return t.findRecursive(t.root, head, tail)
 }
 
+// Find the node for the path info with the specified identifier.
+// Only the nodes of inserted path info objects can be found, not the parents that were created to hold them.
+func (t *Tree) FindById(id path.Id) *Node {
+	return t.ids[id]
+}
+
 // Display the entire tree.
 func (t *Tree) Print(w io.Writer) {
 	t.PrintWithLimit(w, 0)
@@ -103,12 +122,17 @@ func (t *Tree) Print(w io.Writer) {
 
 // Display the tree with a maximum specified depth.
 func (t *Tree) PrintWithLimit(w io.Writer, limit int) {
+	t.PrintWithOptions(w, PrintOptions{Limit: limit})
+}
+
+// Display the tree using the specified options.
+func (t *Tree) PrintWithOptions(w io.Writer, opts PrintOptions) {
 	if t.root != nil {
 		fmt.Fprintln(w, t.rootPath)
 		st := stats{
 			dirCount: 1,
 		}
-		t.root.printChildren(w, &st, "", 1, limit)
+		t.root.printChildren(w, &st, "", 1, opts)
 		fmt.Fprintln(w)
 		fmt.Fprintln(w, st.String())
 	}
@@ -159,14 +183,52 @@ func (t *Tree) findRecursive(parent *Node, head string, tail []string) *Node {
 
 //-----------------------------------------------------------------------------
 
+// Options used while displaying a tree.
+type PrintOptions struct {
+	Limit int    // Maximum depth to be displayed, 0 means no limit.
+	Sizes bool   // Display the size of each entry. See [Node.DirSize].
+	Order *Order // [optional] Used to sort the children by name. Nil compares the raw bytes.
+	Color bool   // Display the directories and sizes in color (see the style package).
+}
+
+// Order in which the children of a node are sorted by name.
+//
+// The children are only sorted when they are iterated for the first time in an order and they stay sorted until
+// a new child is inserted. Displaying the same tree again using the same order does not sort the children again.
+type Order struct {
+	compare func(a, b string) int
+}
+
+// Create an order that sorts the names using the compare function. Nil compares the raw bytes.
+func NewOrder(compare func(a, b string) int) *Order {
+	if compare == nil {
+		compare = strings.Compare
+	}
+	return &Order{compare: compare}
+}
+
+// The default order that compares the raw bytes of the names.
+var byteOrder = NewOrder(nil)
+
+//-----------------------------------------------------------------------------
+
 // Node in the tree describing a path entry.
 type Node struct {
 	Name        string
 	Info        path.Info
+	DirSize     uint64 // Combined size of the files inside the directory and all of its subdirectories.
 	FirstChild  *Node
 	NextSibling *Node
+
+	parent     *Node            // The parent node. Nil for the root.
+	childCount int              // Number of children.
+	index      map[string]*Node // Children by name. Only created for directories with many children.
+	sortedBy   *Order           // The order in which the children are currently linked. Nil if not sorted.
 }
 
+// The number of children a node needs before finding a child by name uses an index instead of walking the children.
+const indexThreshold = 32
+
 func newNode(name string) *Node {
 	return &Node{
 		Name: name,
@@ -176,12 +238,28 @@ func newNode(name string) *Node {
 // Insert the node as a child.
 func (n *Node) insertChild(c *Node) {
 	// Insert the child as the first child and thus we don't have to walk to the end of the list
+	c.parent = n
 	c.NextSibling = n.FirstChild
 	n.FirstChild = c
+	n.childCount++
+	n.sortedBy = nil
+
+	if n.index != nil {
+		n.index[c.Name] = c
+	} else if n.childCount > indexThreshold {
+		n.index = make(map[string]*Node, n.childCount)
+		for current := n.FirstChild; current != nil; current = current.NextSibling {
+			n.index[current.Name] = current
+		}
+	}
 }
 
 // Find the first child with the specified name.
 func (n *Node) findChild(named string) *Node {
+	if n.index != nil {
+		return n.index[named]
+	}
+
 	current := n.FirstChild
 	for {
 		if current == nil { //nolint:staticcheck // QF1006
@@ -196,32 +274,56 @@ func (n *Node) findChild(named string) *Node {
 	return nil
 }
 
+// Return the parent node or nil if this is the root node.
+func (n *Node) Parent() *Node {
+	return n.parent
+}
+
+// Return the nodes from the root node down to and including this node.
+// Used to display a node in the context of the tree.
+func (n *Node) Ancestry() []*Node {
+	depth := 0
+	for current := n; current != nil; current = current.parent {
+		depth++
+	}
+
+	result := make([]*Node, depth)
+	for current := n; current != nil; current = current.parent {
+		depth--
+		result[depth] = current
+	}
+	return result
+}
+
 // Recursively display this node and children.
 func (n *Node) Print(w io.Writer) {
 	n.PrintWithLimit(w, 0)
 }
 
+// Recursively display this node and children with a maximum specified depth.
 func (n *Node) PrintWithLimit(w io.Writer, limit int) {
+	n.PrintWithOptions(w, PrintOptions{Limit: limit})
+}
+
+// Recursively display this node and children using the specified options.
+func (n *Node) PrintWithOptions(w io.Writer, opts PrintOptions) {
 	st := stats{}
 	if n.Info.IsDir() {
 		st.dirCount = 1
 	}
 	fmt.Fprintln(w, n.Name)
-	n.printChildren(w, &st, "", 1, limit)
+	n.printChildren(w, &st, "", 1, opts)
 	fmt.Fprintln(w)
 	fmt.Fprintln(w, st.String())
 }
 
-func (n *Node) printChildren(w io.Writer, st *stats, prefix string, currentDepth int, maxDepth int) {
-	if (maxDepth > 0) && (currentDepth > maxDepth) {
+func (n *Node) printChildren(w io.Writer, st *stats, prefix string, currentDepth int, opts PrintOptions) {
+	if (opts.Limit > 0) && (currentDepth > opts.Limit) {
 		return
 	}
 
 	// Based on kddnewton's implementation: https://github.com/kddnewton/tree/blob/main/tree.go
-	children := n.sortedChildren()
-	count := len(children)
-
-	for i, child := range children {
+	for child := range n.Children(opts.Order) {
 		// Filter out hidden paths
 		if len(child.Name) > 0 && (child.Name[0] == '.') {
 			continue
@@ -233,39 +335,100 @@ func (n *Node) printChildren(w io.Writer, st *stats, prefix string, currentDepth
 			st.fileCount++
 		}
 
-		if i == count-1 {
-			fmt.Fprintln(w, prefix+"└──", child.Name)
-			child.printChildren(w, st, prefix+"    ", currentDepth+1, maxDepth)
+		name := child.Name
+		if opts.Color && child.Info.IsDir() {
+			name = style.Paint(style.Blue, name)
+		}
+		if opts.Sizes {
+			size := fmt.Sprintf("[%8s]", human.Bytes(child.size()))
+			if opts.Color {
+				size = style.Paint(style.Dim, size)
+			}
+			name = size + "  " + name
+		}
+
+		if child.NextSibling == nil {
+			fmt.Fprintln(w, prefix+"└──", name)
+			child.printChildren(w, st, prefix+"    ", currentDepth+1, opts)
 		} else {
-			fmt.Fprintln(w, prefix+"├──", child.Name)
-			child.printChildren(w, st, prefix+"│   ", currentDepth+1, maxDepth)
+			fmt.Fprintln(w, prefix+"├──", name)
+			child.printChildren(w, st, prefix+"│   ", currentDepth+1, opts)
 		}
 	}
 }
 
-// Return the children nodes.
-func (n *Node) children() []*Node {
-	result := make([]*Node, 0, 8)
+// Return the size of a file or the combined size of the files inside a directory.
+func (n *Node) size() uint64 {
+	if n.Info.IsDir() {
+		return n.DirSize
+	}
+	return n.Info.Size
+}
 
-	current := n.FirstChild
-	for {
-		if current == nil { //nolint:staticcheck // QF1006
-			break
+// Iterate the children sorted by name.
+// If order is nil then the names are sorted by their raw bytes.
+// The children are sorted in place and thus no new children may be inserted while iterating.
+func (n *Node) Children(order *Order) iter.Seq[*Node] {
+	if order == nil {
+		order = byteOrder
+	}
+	return func(yield func(*Node) bool) {
+		n.sortChildren(order)
+		for current := n.FirstChild; current != nil; current = current.NextSibling {
+			if !yield(current) {
+				return
+			}
 		}
-		result = append(result, current)
-		current = current.NextSibling
 	}
+}
 
-	return result
+// Sort the linked list of children by name, unless they are already sorted in this order.
+func (n *Node) sortChildren(order *Order) {
+	if n.sortedBy == order {
+		return
+	}
+	n.FirstChild = mergeSort(n.FirstChild, n.childCount, order.compare)
+	n.sortedBy = order
 }
 
-// Return the children nodes sorted by name.
-func (n *Node) sortedChildren() []*Node {
-	result := n.children()
-	sort.Slice(result, func(i, j int) bool {
-		return result[i].Name < result[j].Name
-	})
-	return result
+// Sort the first count nodes of the linked list by name and return the new head.
+// A merge sort is used so the list can be sorted without copying it into a slice.
+func mergeSort(head *Node, count int, compare func(a, b string) int) *Node {
+	if count < 2 {
+		if head != nil {
+			head.NextSibling = nil
+		}
+		return head
+	}
+
+	// Split the list in half
+	half := count / 2
+	right := head
+	for range half {
+		right = right.NextSibling
+	}
+	right = mergeSort(right, count-half, compare)
+	left := mergeSort(head, half, compare)
+
+	// Merge the two sorted halves. Equal names keep their original order.
+	var result Node
+	tail := &result
+	for left != nil && right != nil {
+		if compare(right.Name, left.Name) < 0 {
+			tail.NextSibling = right
+			right = right.NextSibling
+		} else {
+			tail.NextSibling = left
+			left = left.NextSibling
+		}
+		tail = tail.NextSibling
+	}
+	if left != nil {
+		tail.NextSibling = left
+	} else {
+		tail.NextSibling = right
+	}
+	return result.NextSibling
 }
 
 //-----------------------------------------------------------------------------
@@ -22,6 +22,7 @@ package tree_test
 
 import (
 	"bytes"
+	"fmt"
 	"io/fs"
 	"path/filepath"
 	"slices"
@@ -137,6 +138,73 @@ func TestPrint(t *testing.T) {
 
 }
 
+func TestChildren(t *testing.T) {
+	tr := tree.New("/test")
+	tr.Insert(path.Info{Path: ".", Mode: fs.ModeDir})
+
+	// Enough children for the node to index them by name
+	count := 1000
+	expected := make([]string, 0, count)
+	for i := range count {
+		name := fmt.Sprintf("file-%04d", (i*7919)%count)
+		expected = append(expected, name)
+		n := tr.Insert(path.Info{Path: filepath.Join("dir", name)})
+		assert.Equal(t, n, tr.Find(filepath.Join("dir", name)))
+	}
+	slices.Sort(expected)
+
+	dir := tr.Find("dir")
+	assert.NotNil(t, dir)
+	assert.Equal(t, expected, childNames(dir, nil))
+	assert.Nil(t, tr.Find("dir/file-9999"))
+
+	// Sorting in a different order
+	reverse := tree.NewOrder(func(a, b string) int { return strings.Compare(b, a) })
+	reversed := slices.Clone(expected)
+	slices.Reverse(reversed)
+	assert.Equal(t, reversed, childNames(dir, reverse))
+
+	// Inserting a child sorts the children again
+	tr.Insert(path.Info{Path: "dir/a"})
+	assert.Equal(t, append([]string{"a"}, expected...), childNames(dir, nil))
+
+	// Stop iterating early
+	for child := range dir.Children(nil) {
+		assert.Equal(t, "a", child.Name)
+		break
+	}
+}
+
+func TestFindById(t *testing.T) {
+	tr := tree.New("/test")
+	insert := func(p string, mode fs.FileMode) *tree.Node {
+		return tr.Insert(path.Info{Id: path.IdFromPath(p), Path: p, Mode: mode})
+	}
+
+	root := insert(".", fs.ModeDir)
+	c := insert("a/b/c", 0)
+	b := insert("a/b", fs.ModeDir)
+	d := insert("a/d", 0)
+
+	assert.Equal(t, root, tr.FindById(path.IdFromPath(".")))
+	assert.Equal(t, c, tr.FindById(path.IdFromPath("a/b/c")))
+	assert.Equal(t, b, tr.FindById(path.IdFromPath("a/b")))
+	assert.Equal(t, d, tr.FindById(path.IdFromPath("a/d")))
+	assert.Equal(t, tr.Find("a/b/c"), tr.FindById(path.IdFromPath("a/b/c")))
+
+	// Parents created to hold an inserted path are not indexed
+	assert.Nil(t, tr.FindById(path.IdFromPath("a")))
+	assert.Nil(t, tr.FindById(path.IdFromPath("a/z")))
+
+	a := tr.Find("a")
+	assert.Equal(t, a, c.Parent().Parent())
+	assert.Equal(t, root, a.Parent())
+	assert.Nil(t, root.Parent())
+
+	assert.Equal(t, []*tree.Node{root, a, b, c}, c.Ancestry())
+	assert.Equal(t, []*tree.Node{root}, root.Ancestry())
+}
+
 func TestTreePanics(t *testing.T) {
 	// Lol just imagine a big tree shaking with fear
 
@@ -171,6 +239,14 @@ func listRecursive(n *tree.Node, result []string) []string {
 	return result
 }
 
+func childNames(n *tree.Node, order *tree.Order) []string {
+	result := make([]string, 0, 100)
+	for child := range n.Children(order) {
+		result = append(result, child.Name)
+	}
+	return result
+}
+
 func makePaths(tr tree.Tree, p string) *tree.Node {
 	tr.Insert(path.Info{Path: ".", Mode: fs.ModeDir})
 